@@ -2,11 +2,19 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,8 +23,14 @@ import (
 	"github.com/TobiSchelling/AICrawler/internal/collect"
 	"github.com/TobiSchelling/AICrawler/internal/config"
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/deliver"
+	"github.com/TobiSchelling/AICrawler/internal/jobs"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
 	"github.com/TobiSchelling/AICrawler/internal/pipeline"
+	"github.com/TobiSchelling/AICrawler/internal/refine"
 	"github.com/TobiSchelling/AICrawler/internal/server"
+	"github.com/TobiSchelling/AICrawler/internal/storage"
+	"github.com/TobiSchelling/AICrawler/internal/triage"
 	"github.com/spf13/cobra"
 )
 
@@ -25,13 +39,49 @@ var version = "dev"
 var (
 	verbose    bool
 	configPath string
+	configFile string // the config file cfg was loaded from
 	cfg        *config.Config
 )
 
+// Exit codes, so scripts and schedulers can tell failures apart. Any other
+// failure exits with 1.
+const (
+	exitPeriodNotFound = 2 // no briefing for the period asked for
+	exitNoProvider     = 3 // no LLM provider available, or its API key rejected
+	exitRateLimited    = 4 // the provider rate limited the run; try again later
+	exitNoEmbedder     = 5 // the embedder could not be reached
+	exitUnhealthy      = 6 // healthcheck found a problem
+)
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		code, hint := classifyError(err)
+		if hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		os.Exit(code)
+	}
+}
+
+// classifyError returns the exit code for err and a hint on how to fix it.
+func classifyError(err error) (int, string) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return 1, ""
+	case errors.Is(err, llm.ErrNoProvider):
+		return exitNoProvider, "Start Ollama or set the API key variable of summarization.provider; see 'aicrawler init'."
+	case errors.Is(err, llm.ErrUnauthorized):
+		return exitNoProvider, "The provider rejected the API key; check the variable named by api_key_env."
+	case errors.Is(err, llm.ErrRateLimited):
+		return exitRateLimited, "The provider is rate limiting requests; lower summarization.max_concurrency or try again later."
+	case errors.Is(err, llm.ErrEmbedderUnavailable):
+		return exitNoEmbedder, "Check summarization.embedding_provider and that its embedding model is available."
+	case errors.Is(err, errUnhealthy):
+		return exitUnhealthy, ""
+	case errors.Is(err, database.ErrPeriodNotFound):
+		return exitPeriodNotFound, "Run 'aicrawler run' for that day first; 'aicrawler status' lists the stored briefings."
 	}
+	return 1, ""
 }
 
 var rootCmd = &cobra.Command{
@@ -59,6 +109,7 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
+		configFile = path
 		return nil
 	},
 }
@@ -69,11 +120,24 @@ func init() {
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(healthcheckCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(collectCmd)
+	rootCmd.AddCommand(fetchCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(deliverCmd)
+	rootCmd.AddCommand(renderCmd)
+	rootCmd.AddCommand(grepCmd)
 	rootCmd.AddCommand(prioritiesCmd)
+	rootCmd.AddCommand(profilesCmd)
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(feedsCmd)
+	rootCmd.AddCommand(llmCmd)
+	rootCmd.AddCommand(storageCmd)
+	rootCmd.AddCommand(auditCmd)
 }
 
 var versionCmd = &cobra.Command{
@@ -129,6 +193,20 @@ var statusCmd = &cobra.Command{
 		fmt.Printf("  Total collected: %d\n", stats.TotalArticles)
 		fmt.Printf("  Triaged: %d\n", stats.TriagedArticles)
 		fmt.Printf("  Relevant: %d\n", stats.RelevantArticles)
+		if stats.FailedTriage > 0 {
+			fmt.Printf("  Failing triage: %d\n", stats.FailedTriage)
+			failures, err := db.GetTriageFailures(5)
+			if err != nil {
+				return fmt.Errorf("getting triage failures: %w", err)
+			}
+			for _, f := range failures {
+				reason := f.LastError
+				if len(reason) > 80 {
+					reason = reason[:80] + "..."
+				}
+				fmt.Printf("    [%d] %s (%d attempts): %s\n", f.ArticleID, f.Title, f.Attempts, reason)
+			}
+		}
 		fmt.Println("\nOutput:")
 		fmt.Printf("  Storylines: %d\n", stats.Storylines)
 		fmt.Printf("  Briefings: %d\n", stats.Briefings)
@@ -136,12 +214,199 @@ var statusCmd = &cobra.Command{
 		fmt.Println("\nResearch Priorities:")
 		fmt.Printf("  Total: %d\n", stats.TotalPriorities)
 		fmt.Printf("  Active: %d\n", stats.ActivePriorities)
+
+		fmt.Println("\nLLM usage:")
+		last, err := db.GetLastRunUsage()
+		if err != nil {
+			return fmt.Errorf("getting LLM usage: %w", err)
+		}
+		if last == nil {
+			fmt.Println("  No LLM calls recorded yet")
+			return nil
+		}
+		fmt.Printf("  Last run (%s): %s\n", last.Label, formatUsageTotal(*last))
+		steps, err := db.GetRunUsage(last.Label)
+		if err != nil {
+			return fmt.Errorf("getting LLM usage: %w", err)
+		}
+		for _, s := range steps {
+			fmt.Printf("    %s: %s\n", s.Label, formatUsageTotal(s))
+		}
+		since := time.Now().UTC().AddDate(0, 0, -30).Format("2006-01-02 15:04:05")
+		month, err := db.GetUsageSince(since)
+		if err != nil {
+			return fmt.Errorf("getting LLM usage: %w", err)
+		}
+		fmt.Printf("  Last 30 days: %s\n", formatUsageTotal(month))
+		total, err := db.GetUsageSince("")
+		if err != nil {
+			return fmt.Errorf("getting LLM usage: %w", err)
+		}
+		fmt.Printf("  All time: %s\n", formatUsageTotal(total))
+		return nil
+	},
+}
+
+func formatUsageTotal(t database.UsageTotal) string {
+	return pipeline.FormatUsage(llm.Usage{
+		Calls:            t.Calls,
+		PromptTokens:     t.PromptTokens,
+		CompletionTokens: t.CompletionTokens,
+		CostUSD:          t.CostUSD,
+	})
+}
+
+// --- healthcheck command ---
+
+// errUnhealthy is returned by healthcheck when a check failed.
+var errUnhealthy = errors.New("unhealthy")
+
+var healthcheckPing string
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Exit non-zero with the reason when runs are overdue, feeds keep failing or the database is damaged",
+	Long: `Checks that the database is intact, that a briefing was composed within health.max_run_age_hours, and that no more than health.max_failing_feeds configured feeds failed their last health.feed_failures fetches. Prints one line per check and exits with 6 when one failed, for cron monitors and uptime checks.
+
+With --ping, the result is also sent to a healthchecks.io-style URL: the URL itself on success, and URL/fail with the reasons on failure.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems := healthProblems()
+		if healthcheckPing != "" {
+			if err := pingHealth(cmd.Context(), healthcheckPing, problems); err != nil {
+				fmt.Fprintf(os.Stderr, "Pinging %s: %v\n", healthcheckPing, err)
+			}
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%w: %s", errUnhealthy, strings.Join(problems, "; "))
+		}
 		return nil
 	},
 }
 
+func init() {
+	healthcheckCmd.Flags().StringVar(&healthcheckPing, "ping", "", "URL to report the result to, e.g. https://hc-ping.com/<uuid>")
+}
+
+// healthProblems runs the health checks, printing a line for each, and
+// returns why those that failed did.
+func healthProblems() []string {
+	var problems []string
+	check := func(name, problem, ok string) {
+		if problem != "" {
+			problems = append(problems, problem)
+			fmt.Printf("FAIL %s: %s\n", name, problem)
+		} else {
+			fmt.Printf("OK   %s: %s\n", name, ok)
+		}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		check("database", err.Error(), "")
+		return problems
+	}
+	defer db.Close()
+	if err := db.CheckIntegrity(); err != nil {
+		check("database", err.Error(), "")
+	} else {
+		check("database", "", "intact")
+	}
+
+	last, err := db.GetLastReportTime()
+	switch finished, parseErr := time.Parse("2006-01-02 15:04:05", last); {
+	case err != nil:
+		check("last run", fmt.Sprintf("reading run reports: %v", err), "")
+	case last == "":
+		check("last run", "no run has composed a briefing yet", "")
+	case parseErr != nil:
+		check("last run", fmt.Sprintf("unreadable run report time %q", last), "")
+	default:
+		age := time.Since(finished).Round(time.Minute)
+		if maxAge := cfg.Health.MaxRunAge(); maxAge > 0 && age > maxAge {
+			check("last run", fmt.Sprintf("last briefing composed %s ago, more than %s", age, maxAge), "")
+		} else {
+			check("last run", "", fmt.Sprintf("briefing composed %s ago", age))
+		}
+	}
+
+	if cfg.Health.FeedFailures == 0 {
+		return problems
+	}
+	configured := make(map[string]bool)
+	feeds := cfg.Sources.Feeds
+	if cfg.Policy.Enabled {
+		feeds = append(slices.Clone(feeds), cfg.Policy.Feeds...)
+	}
+	feeds = slices.DeleteFunc(slices.Clone(feeds), func(f config.Feed) bool { return f.Disabled })
+	for _, f := range feeds {
+		configured[f.URL] = true
+	}
+	failing, err := db.GetFailingFeeds(cfg.Health.FeedFailures)
+	if err != nil {
+		check("feeds", fmt.Sprintf("reading feed health: %v", err), "")
+		return problems
+	}
+	var names []string
+	for _, f := range failing {
+		if configured[f.FeedURL] {
+			reason := ""
+			if f.LastError != nil {
+				reason = ": " + *f.LastError
+			}
+			names = append(names, fmt.Sprintf("%s (%d failures%s)", f.FeedURL, f.Failures, reason))
+		}
+	}
+	if len(names) > cfg.Health.MaxFailingFeeds {
+		shown := names[:min(len(names), 5)]
+		more := ""
+		if len(names) > len(shown) {
+			more = fmt.Sprintf(" and %d more", len(names)-len(shown))
+		}
+		check("feeds", fmt.Sprintf("%d of %d feeds failing: %s%s", len(names), len(feeds), strings.Join(shown, ", "), more), "")
+	} else {
+		check("feeds", "", fmt.Sprintf("%d of %d feeds failing", len(names), len(feeds)))
+	}
+	return problems
+}
+
+// healthPingTimeout bounds reporting the health check's result.
+const healthPingTimeout = 10 * time.Second
+
+// pingHealth reports the health check's result to a healthchecks.io-style
+// URL: the URL itself when all is well, else URL/fail with the problems.
+// It goes through network.proxy, like every other request.
+func pingHealth(ctx context.Context, url string, problems []string) error {
+	body := "OK"
+	if len(problems) > 0 {
+		url = strings.TrimSuffix(url, "/") + "/fail"
+		body = strings.Join(problems, "\n")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", cfg.Fetch.Agent())
+	client := &http.Client{Transport: cfg.Network.Transport(), Timeout: healthPingTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // --- collect command ---
 
+var (
+	collectWatch    bool
+	collectInterval time.Duration
+)
+
 var collectCmd = &cobra.Command{
 	Use:   "collect",
 	Short: "Collect articles from configured sources",
@@ -152,6 +417,10 @@ var collectCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		if collectWatch {
+			return watchFeeds(db, collectInterval)
+		}
+
 		periodID := database.GetToday()
 		fmt.Println("Collecting articles from sources...")
 
@@ -162,6 +431,10 @@ var collectCmd = &cobra.Command{
 		fmt.Printf("  Total found: %d\n", result.TotalFound)
 		fmt.Printf("  New articles: %d\n", result.NewArticles)
 		fmt.Printf("  Duplicates skipped: %d\n", result.Duplicates)
+		fmt.Printf("  Filtered by keywords: %d\n", result.Filtered)
+		if result.Updated > 0 {
+			fmt.Printf("  Updated, to fetch again: %d\n", result.Updated)
+		}
 
 		if len(result.Sources) > 0 {
 			fmt.Println("\nArticles by source:")
@@ -183,16 +456,67 @@ var collectCmd = &cobra.Command{
 	},
 }
 
-// --- run command ---
+func init() {
+	collectCmd.Flags().BoolVar(&collectWatch, "watch", false, "Poll feeds until interrupted, pooling articles for the next run")
+	collectCmd.Flags().DurationVar(&collectInterval, "interval", 30*time.Minute, "Time between polls with --watch")
+}
+
+// watchFeeds polls the feeds every interval until interrupted. Articles are
+// pooled without a period; the next 'aicrawler run' adopts them.
+func watchFeeds(db *database.DB, interval time.Duration) error {
+	if err := checkPollInterval(interval); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	collector := collect.NewCollector(cfg, db, 1)
+	fmt.Printf("Polling feeds every %s. Press Ctrl+C to stop\n", interval)
+	pollUntilDone(ctx, interval, func() {
+		result := collector.PollFeeds()
+		fmt.Printf("%s  %d new articles, %d duplicates, %d filtered\n", time.Now().Format("15:04"), result.NewArticles, result.Duplicates, result.Filtered)
+	})
+	return nil
+}
+
+// checkPollInterval rejects an --interval that would poll the feeds more
+// often than once a minute.
+func checkPollInterval(interval time.Duration) error {
+	if interval < time.Minute {
+		return fmt.Errorf("--interval must be at least 1m, got %s", interval)
+	}
+	return nil
+}
+
+// pollUntilDone calls poll right away and then every interval after the
+// last call returned, until ctx is done. A poll under way is finished, not
+// cut short.
+func pollUntilDone(ctx context.Context, interval time.Duration, poll func()) {
+	for {
+		poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// --- fetch command ---
 
 var (
-	dryRun   bool
-	daysBack int
+	fetchRetryFailed bool
+	fetchPeriod      string
+	fetchAll         bool
+	fetchStatsDays   int
+	fetchStatsFail   bool
 )
 
-var runCmd = &cobra.Command{
-	Use:   "run",
-	Short: "Run the full pipeline: collect -> fetch -> triage -> cluster -> synthesize -> compose",
+var fetchCmd = &cobra.Command{
+	Use:   "fetch [--period X | --all]",
+	Short: "Fetch the content still missing for articles, outside a run",
+	Long:  "Fetches the pages of articles collected without content, like the fetch step of a run, to backfill content or retry fetches without running the pipeline. It covers every period unless --period names one, whose near-duplicates are then marked as in a run. A page that failed transiently (a refused connection, a timeout, a 429 or 5xx) is tried again later, up to performance.fetch_retries times; --retry-failed tries all of them again now, including those that ran out of retries.",
+	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		db, err := openDB()
 		if err != nil {
@@ -200,41 +524,268 @@ var runCmd = &cobra.Command{
 		}
 		defer db.Close()
 
-		today := database.GetToday()
-		periodID, effectiveDaysBack, err := resolvePeriod(db, today, daysBack)
+		periodID, err := fetchPeriodID(fetchPeriod, fetchAll, func(period string) (int, error) {
+			articles, err := db.GetArticlesForPeriod(period)
+			return len(articles), err
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		// Failed pages go first, so one failing again waits for its next
+		// retry rather than being tried twice.
+		fetcher := pipeline.NewFetcher(cfg, db)
+		if fetchRetryFailed {
+			fmt.Println("Retrying failed fetches: " + pipeline.FetchSummary(fetcher.RetryFailedContent(ctx, periodID)))
+		}
+		fmt.Println(pipeline.FetchSummary(fetcher.FetchMissingContent(ctx, periodID)))
+		if periodID != nil {
+			if n := pipeline.MarkNearDuplicates(cfg, db, *periodID); n > 0 {
+				fmt.Printf("Marked %d near-duplicates\n", n)
+			}
+		}
+		return nil
+	},
+}
+
+// fetchPeriodID returns the period fetch --period and --all select: the
+// one named, which must have articles by countArticles, or nil for every
+// period.
+func fetchPeriodID(period string, all bool, countArticles func(period string) (int, error)) (*string, error) {
+	if all && period != "" {
+		return nil, fmt.Errorf("--period and --all can't be combined")
+	}
+	if period == "" {
+		return nil, nil
+	}
+	n, err := countArticles(period)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no articles collected for %s", period)
+	}
+	return &period, nil
+}
+
+// minFailingAttempts is how many pages of a domain fetch stats --failing
+// wants to see before it calls the domain failing.
+const minFailingAttempts = 3
+
+var fetchStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how fetching the pages of each domain went lately",
+	Long:  "Lists every domain pages were fetched from in the last --days days: how many pages were tried, the share that failed (an HTTP or network error, or no readable text), how many were paywalled, rendered in the browser or read from the Wayback Machine, the average time a page took, fallbacks included, and the latest error. Domains failing most often come first; a domain that keeps failing with no readable text may need fetch.browser, one that keeps refusing may be better removed. Pages read from the cache aren't counted.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if fetchStatsDays < 1 {
+			return fmt.Errorf("--days must be at least 1")
+		}
+		db, err := openDB()
 		if err != nil {
 			return err
 		}
+		defer db.Close()
 
-		pipe := pipeline.New(cfg, db)
-		ctx := context.Background()
+		stats, err := db.GetFetchStats(fetchStatsDays)
+		if err != nil {
+			return err
+		}
+		if fetchStatsFail {
+			stats = slices.DeleteFunc(stats, func(s database.DomainFetchStats) bool {
+				return s.Attempts < minFailingAttempts || s.FailureRate() < 0.5
+			})
+		}
+		if len(stats) == 0 {
+			fmt.Printf("No pages fetched in the last %d days.\n", fetchStatsDays)
+			return nil
+		}
+		fmt.Printf("  %-32s %6s %7s %8s %8s %8s %7s  %s\n", "Domain", "Pages", "Failed", "Paywall", "Rendered", "Archived", "Avg", "Last error")
+		for _, s := range stats {
+			lastError := ""
+			if s.Failures > 0 && s.LastError != nil {
+				lastError = *s.LastError
+			}
+			line := fmt.Sprintf("  %-32s %6d %6.0f%% %8d %8d %8d %6.1fs  %s", s.Domain, s.Attempts, s.FailureRate()*100,
+				s.Paywalled, s.Rendered, s.Archived, s.AvgLatency.Seconds(), lastError)
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+		fmt.Printf("%d domains in the last %d days\n", len(stats), fetchStatsDays)
+		return nil
+	},
+}
 
-		var result *pipeline.Result
-		if dryRun {
-			result = pipe.DryRun(periodID)
-		} else {
-			result = pipe.Run(ctx, periodID, effectiveDaysBack)
+func init() {
+	fetchCmd.Flags().BoolVar(&fetchRetryFailed, "retry-failed", false, "Also fetch again now every page that failed transiently")
+	fetchCmd.Flags().StringVar(&fetchPeriod, "period", "", "Fetch only the articles of this period (e.g. 2026-02-06 or 2026-02-01..2026-02-06)")
+	fetchCmd.Flags().BoolVar(&fetchAll, "all", false, "Fetch the articles of every period (the default)")
+	fetchStatsCmd.Flags().IntVar(&fetchStatsDays, "days", 30, "Sum up the fetches of this many days, today included")
+	fetchStatsCmd.Flags().BoolVar(&fetchStatsFail, "failing", false, fmt.Sprintf("Only list domains of which at least half of %d or more pages failed", minFailingAttempts))
+	fetchCmd.AddCommand(fetchStatsCmd)
+}
+
+// --- run command ---
+
+var (
+	dryRun    bool
+	daysBack  int
+	edition   string
+	runDate   string
+	runRecord bool
+	runReplay bool
+	runTape   string
+	runUpdate bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the full pipeline: collect -> fetch -> triage -> cluster -> synthesize -> compose",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
 		}
+		defer db.Close()
 
-		for i, step := range result.Steps {
-			fmt.Printf("\nStep %d/6: %s\n", i+1, step.Name)
-			if step.Err != nil {
-				fmt.Printf("  Error: %v\n", step.Err)
-			} else {
-				fmt.Printf("  %s\n", step.Summary)
+		today := database.GetToday()
+		// Ctrl+C cancels the LLM calls in flight rather than waiting for
+		// them to time out.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		payload := pipeline.JobPayload{PeriodID: today, Edition: edition}
+		if (runRecord || runReplay) && edition != database.EditionMorning {
+			return fmt.Errorf("--record and --replay are not supported for the evening edition")
+		}
+		switch {
+		case runUpdate:
+			if edition != database.EditionMorning || dryRun || daysBack > 0 || runDate != "" || runRecord || runReplay {
+				return fmt.Errorf("--update cannot be combined with --edition, --dry-run, --days-back, --date, --record or --replay")
+			}
+			payload.Update = true
+			fmt.Printf("Updating the briefing for %s with articles collected since.\n", today)
+		case runReplay:
+			if runRecord || dryRun || daysBack > 0 {
+				return fmt.Errorf("--replay cannot be combined with --record, --dry-run or --days-back")
+			}
+			if _, err := resolveDate(today, runDate, daysBack); err != nil {
+				return err
+			}
+			payload.PeriodID = cmp.Or(runDate, today)
+			payload.Tape, payload.TapeMode = tapePath(payload.PeriodID), pipeline.TapeReplay
+			if _, err := os.Stat(payload.Tape); err != nil {
+				return fmt.Errorf("nothing to replay: %w", err)
+			}
+			fmt.Printf("Replaying the LLM calls recorded in %s for %s; collection, fetching and delivery are skipped.\n", payload.Tape, payload.PeriodID)
+		case edition == database.EditionMorning:
+			pastDate, err := resolveDate(today, runDate, daysBack)
+			if err != nil {
+				return err
+			}
+			if pastDate {
+				fmt.Printf("Running for %s: collecting from sources searchable by date and reprocessing the articles already collected for that day.\n", runDate)
+				if dryRun {
+					printSteps(pipeline.New(cfg, db).DryRun(runDate))
+					return nil
+				}
+				payload.PeriodID, payload.PastDate = runDate, true
+				break
+			}
+			periodID, effectiveDaysBack, err := resolvePeriod(db, today, daysBack)
+			if err != nil {
+				return err
 			}
+			if dryRun {
+				printSteps(pipeline.New(cfg, db).DryRun(periodID))
+				return nil
+			}
+			payload.PeriodID, payload.DaysBack = periodID, effectiveDaysBack
+		case edition == database.EditionEvening:
+			if dryRun || daysBack > 0 || runDate != "" {
+				return fmt.Errorf("--dry-run, --days-back and --date are not supported for the evening edition")
+			}
+			fmt.Printf("Evening edition for %s.\n", today)
+		default:
+			return fmt.Errorf("unknown edition %q (expected %q or %q)", edition, database.EditionMorning, database.EditionEvening)
+		}
+
+		if runRecord {
+			payload.Tape, payload.TapeMode = tapePath(payload.PeriodID), pipeline.TapeRecord
 		}
 
-		if !dryRun {
+		// The run goes through the job queue so a failure is retried by
+		// 'aicrawler jobs work' or 'aicrawler serve'.
+		job, err := runJob(ctx, db, pipeline.JobRun, payload)
+		if err != nil {
+			return err
+		}
+		if runRecord && job.Status == database.JobDone {
+			fmt.Printf("\nLLM calls recorded in %s; repeat them with 'aicrawler run --replay --date %s'.\n", payload.Tape, payload.PeriodID)
+		}
+		if job.Result != nil && *job.Result != "" {
+			fmt.Printf("\n%s\n", *job.Result)
+		}
+		switch job.Status {
+		case database.JobDone:
 			fmt.Println("\nPipeline complete! Run 'aicrawler serve' to view the briefing.")
+		case database.JobQueued:
+			fmt.Printf("\nRun failed: %s\nRetry %d/%d scheduled for %s UTC; 'aicrawler jobs work' or 'aicrawler serve' will pick it up.\n",
+				deref(job.LastError), job.Attempts+1, job.MaxAttempts, job.RunAfter)
+		default:
+			return fmt.Errorf("run failed: %w", job.Err)
 		}
 		return nil
 	},
 }
 
+func printSteps(result *pipeline.Result) {
+	for i, step := range result.Steps {
+		fmt.Printf("\nStep %d/%d: %s\n", i+1, len(result.Steps), step.Name)
+		if step.Err != nil {
+			fmt.Printf("  Error: %v\n", step.Err)
+		} else {
+			fmt.Printf("  %s\n", step.Summary)
+		}
+	}
+}
+
 func init() {
 	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without executing")
 	runCmd.Flags().IntVar(&daysBack, "days-back", 0, "Override lookback window (days)")
+	runCmd.Flags().StringVar(&runDate, "date", "", "Run for a past day (YYYY-MM-DD) instead of today")
+	runCmd.Flags().StringVar(&edition, "edition", database.EditionMorning, "Briefing edition: morning (full) or evening (delta since morning)")
+	runCmd.Flags().BoolVar(&runUpdate, "update", false, "Add new articles to today's briefing without recomposing it")
+	runCmd.Flags().BoolVar(&runRecord, "record", false, "Record LLM responses and embeddings on a tape for --replay")
+	runCmd.Flags().BoolVar(&runReplay, "replay", false, "Answer LLM calls from the tape recorded for the period; calls no API")
+	runCmd.Flags().StringVar(&runTape, "tape", "", "Tape file for --record or --replay (default: tapes/<period>.json in the data directory)")
+}
+
+// tapePath is the tape to record a period's run on or replay it from.
+func tapePath(periodID string) string {
+	if runTape != "" {
+		return runTape
+	}
+	return dataStore().Path(storage.Tapes, periodID+".json")
+}
+
+// resolveDate validates --date and reports whether it names a past day.
+// Today's date means a regular daily run.
+func resolveDate(today, date string, explicitDaysBack int) (bool, error) {
+	if date == "" || date == today {
+		return false, nil
+	}
+	if explicitDaysBack > 0 {
+		return false, fmt.Errorf("--date and --days-back cannot be combined")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return false, fmt.Errorf("invalid --date %q (expected YYYY-MM-DD)", date)
+	}
+	if date > today {
+		return false, fmt.Errorf("--date %s is in the future", date)
+	}
+	return true, nil
 }
 
 // resolvePeriod determines the period ID and effective days back based on
@@ -307,9 +858,41 @@ var serveCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		opts := server.Options{MaxIngestBytes: cfg.Performance.HTTP.MaxIngestBytes()}
+		if env := cfg.Server.IngestTokenEnv; env != "" {
+			opts.IngestToken = os.Getenv(env)
+		}
+		for _, u := range cfg.Server.Users {
+			opts.Users = append(opts.Users, server.User{Name: u.Name, Role: u.Role, Token: os.Getenv(u.TokenEnv)})
+		}
+		if provider := llm.CreateProvider(cfg.Summarization.ForStep(cfg.Summarization.Steps.Compose)); provider != nil {
+			opts.Refiner = refine.NewRefiner(db, provider, refine.Options{})
+		}
+
+		q := newQueue(db)
+		if cfg.Synthesize.ResynthesizeStale {
+			opts.OnStorylineChange = func(periodID string) {
+				if _, err := q.Enqueue(pipeline.JobResynthesize, pipeline.JobPayload{PeriodID: periodID}); err != nil {
+					log.Printf("Queueing resynthesis of %s: %v", periodID, err)
+				}
+			}
+		}
+
+		if n := deliver.NewDeliverer(cfg.Delivery, db).StartBots(context.Background()); n > 0 {
+			fmt.Printf("Answering chat commands on %d bot(s)\n", n)
+		}
+
+		go q.Work(context.Background(), jobPollInterval) //nolint: errcheck
+
 		fmt.Printf("Starting server at http://localhost:%d\n", servePort)
+		if opts.IngestToken != "" {
+			fmt.Println("Ingest API enabled at /api/v1/ingest")
+		}
+		if len(opts.Users) > 0 {
+			fmt.Printf("Multi-user mode: %d users must log in\n", len(opts.Users))
+		}
 		fmt.Println("Press Ctrl+C to stop")
-		return server.Serve(db, servePort)
+		return server.Serve(db, servePort, opts)
 	},
 }
 
@@ -317,16 +900,15 @@ func init() {
 	serveCmd.Flags().IntVarP(&servePort, "port", "p", 8000, "Port to run server on")
 }
 
-// --- priorities command ---
+// --- deliver command ---
 
-var prioritiesCmd = &cobra.Command{
-	Use:   "priorities",
-	Short: "Manage research priorities",
-}
+var deliverEdition string
 
-var prioritiesListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List all research priorities",
+var deliverCmd = &cobra.Command{
+	Use:   "deliver [period_id]",
+	Short: "Push a composed briefing to the configured delivery targets",
+	Long:  "Uploads a briefing to the S3/WebDAV targets and posts it to the Telegram/Matrix chats in the delivery config. Defaults to the latest briefing.",
+	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		db, err := openDB()
 		if err != nil {
@@ -334,66 +916,62 @@ var prioritiesListCmd = &cobra.Command{
 		}
 		defer db.Close()
 
-		items, err := db.GetAllPriorities()
-		if err != nil {
-			return err
-		}
-
-		if len(items) == 0 {
-			fmt.Println("No priorities defined. Add one with: aicrawler priorities add")
-			return nil
+		d := deliver.NewDeliverer(cfg.Delivery, db)
+		if !d.HasTargets() {
+			return fmt.Errorf("no delivery targets enabled; configure the delivery section")
 		}
 
-		fmt.Println("Research Priorities:")
-		fmt.Println()
-		for _, p := range items {
-			icon := " "
-			if p.IsActive {
-				icon = "*"
+		var periodID string
+		if len(args) > 0 {
+			periodID = args[0]
+		} else {
+			briefings, err := db.GetAllBriefings()
+			if err != nil {
+				return err
 			}
-			fmt.Printf("  [%d] %s %s\n", p.ID, icon, p.Title)
-			if p.Description != nil && *p.Description != "" {
-				desc := *p.Description
-				if len(desc) > 60 {
-					desc = desc[:60] + "..."
-				}
-				fmt.Printf("        %s\n", desc)
+			if len(briefings) == 0 {
+				return fmt.Errorf("no briefings yet; run 'aicrawler run' first")
 			}
+			periodID = briefings[0].PeriodID
 		}
-		return nil
-	},
-}
 
-var prioritiesAddCmd = &cobra.Command{
-	Use:   "add [title] [description]",
-	Short: "Add a new research priority",
-	Args:  cobra.RangeArgs(1, 2),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		db, err := openDB()
+		job, err := runJob(context.Background(), db, pipeline.JobDeliver,
+			pipeline.JobPayload{PeriodID: periodID, Edition: deliverEdition})
 		if err != nil {
 			return err
 		}
-		defer db.Close()
-
-		title := args[0]
-		description := ""
-		if len(args) > 1 {
-			description = args[1]
-		}
-
-		id, err := db.InsertPriority(title, description, nil)
-		if err != nil {
-			return err
+		fmt.Printf("Delivered %s (%s): %s\n", periodID, deliverEdition, deref(job.Result))
+		switch job.Status {
+		case database.JobDone:
+			return nil
+		case database.JobQueued:
+			fmt.Printf("Retry %d/%d scheduled for %s UTC\n", job.Attempts+1, job.MaxAttempts, job.RunAfter)
 		}
-		fmt.Printf("Added priority [%d]: %s\n", id, title)
-		return nil
+		return fmt.Errorf("delivery failed: %w", job.Err)
 	},
 }
 
-var prioritiesRemoveCmd = &cobra.Command{
-	Use:   "remove [id]",
-	Short: "Remove a research priority",
-	Args:  cobra.ExactArgs(1),
+func init() {
+	deliverCmd.Flags().StringVar(&deliverEdition, "edition", database.EditionMorning, "Briefing edition to deliver")
+}
+
+// --- render command ---
+
+// formatPage renders the web UI's briefing page rather than an export format.
+const formatPage = "page"
+
+var (
+	renderPeriod  string
+	renderEdition string
+	renderFormat  string
+	renderTo      string
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render a stored briefing for inspection",
+	Long:  "Renders a briefing edition from the database as delivery exports it (markdown, html or json) or as the web UI serves it (page), without calling an LLM. Defaults to the latest briefing.",
+	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		db, err := openDB()
 		if err != nil {
@@ -401,9 +979,445 @@ var prioritiesRemoveCmd = &cobra.Command{
 		}
 		defer db.Close()
 
-		id, err := strconv.ParseInt(args[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid priority ID: %s", args[0])
+		periodID := renderPeriod
+		if periodID == "" {
+			briefings, err := db.GetAllBriefings()
+			if err != nil {
+				return err
+			}
+			if len(briefings) == 0 {
+				return fmt.Errorf("no briefings yet; run 'aicrawler run' first")
+			}
+			periodID = briefings[0].PeriodID
+		}
+		doc, err := deliver.Load(db, periodID, renderEdition)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			return &database.BriefingNotFoundError{PeriodID: periodID, Edition: renderEdition}
+		}
+
+		var out bytes.Buffer
+		if renderFormat == formatPage {
+			srv, err := server.New(db, server.Options{})
+			if err != nil {
+				return err
+			}
+			if err := srv.RenderBriefing(&out, periodID, renderEdition); err != nil {
+				return err
+			}
+		} else {
+			body, _, _, err := doc.Render(renderFormat)
+			if err != nil {
+				return err
+			}
+			out.Write(body)
+		}
+
+		if renderTo == "stdout" {
+			_, err = os.Stdout.Write(out.Bytes())
+			return err
+		}
+		if err := os.WriteFile(renderTo, out.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("writing briefing: %w", err)
+		}
+		fmt.Printf("Rendered %s (%s) as %s to %s\n", periodID, renderEdition, renderFormat, renderTo)
+		return nil
+	},
+}
+
+func init() {
+	renderCmd.Flags().StringVar(&renderPeriod, "period", "", "Period to render (default: the latest briefing)")
+	renderCmd.Flags().StringVar(&renderEdition, "edition", database.EditionMorning, "Briefing edition to render")
+	renderCmd.Flags().StringVar(&renderFormat, "format", deliver.FormatMarkdown, "markdown, html, json or page")
+	renderCmd.Flags().StringVar(&renderTo, "to", "stdout", "File to write to, or stdout")
+}
+
+// --- grep command ---
+
+// Formats of grep.
+const (
+	grepMarkdown = "md"   // a Markdown digest for notes and chats
+	grepJSON     = "json" // the matches as one JSON document
+)
+
+// grepSnippet is how many characters of context a match is shown with.
+const grepSnippet = 160
+
+var (
+	grepSince  string
+	grepFormat string
+	grepLimit  int
+)
+
+type grepSource struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type grepNarrative struct {
+	PeriodID string       `json:"period_id"`
+	Title    string       `json:"title"`
+	Snippet  string       `json:"snippet"`
+	Link     string       `json:"link,omitempty"` // the briefing in the web UI, with delivery.public_url
+	Sources  []grepSource `json:"sources"`
+}
+
+type grepArticle struct {
+	Date    string `json:"date"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Source  string `json:"source,omitempty"`
+	Snippet string `json:"snippet"`
+}
+
+var grepCmd = &cobra.Command{
+	Use:   "grep [query]",
+	Short: "Search stored articles and narratives",
+	Long:  "Searches the titles and texts of stored articles and briefing narratives for a phrase (case-insensitive) and prints the matches with dates and links, as a compact Markdown digest (md) or JSON, for piping into notes or chats.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.TrimSpace(args[0])
+		if query == "" {
+			return fmt.Errorf("empty query")
+		}
+		if grepFormat != grepMarkdown && grepFormat != grepJSON {
+			return fmt.Errorf("unknown format %q (want %s or %s)", grepFormat, grepMarkdown, grepJSON)
+		}
+		since, err := parseSince(grepSince, time.Now())
+		if err != nil {
+			return err
+		}
+
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		narratives, err := db.SearchNarratives(query, since, grepLimit)
+		if err != nil {
+			return fmt.Errorf("searching narratives: %w", err)
+		}
+		articles, err := db.SearchArticlesSince(query, since, grepLimit)
+		if err != nil {
+			return fmt.Errorf("searching articles: %w", err)
+		}
+
+		publicURL := strings.TrimRight(cfg.Delivery.PublicURL, "/")
+		var result struct {
+			Query      string          `json:"query"`
+			Since      string          `json:"since,omitempty"`
+			Narratives []grepNarrative `json:"narratives"`
+			Articles   []grepArticle   `json:"articles"`
+		}
+		result.Query, result.Since = query, since
+		result.Narratives, result.Articles = []grepNarrative{}, []grepArticle{}
+		for _, n := range narratives {
+			gn := grepNarrative{PeriodID: n.PeriodID, Title: n.Title, Snippet: snippet(n.NarrativeText, query), Sources: []grepSource{}}
+			if publicURL != "" {
+				gn.Link = publicURL + "/briefing/" + n.PeriodID
+			}
+			for _, ref := range n.SourceReferences {
+				gn.Sources = append(gn.Sources, grepSource{Title: ref.Title, URL: ref.URL})
+			}
+			result.Narratives = append(result.Narratives, gn)
+		}
+		for _, a := range articles {
+			ga := grepArticle{Title: a.Title, URL: a.URL}
+			if a.PublishedDate != nil && *a.PublishedDate != "" {
+				ga.Date = *a.PublishedDate
+			} else if a.PeriodID != nil {
+				ga.Date = *a.PeriodID
+			}
+			if a.Source != nil {
+				ga.Source = *a.Source
+			}
+			if a.Content != nil {
+				ga.Snippet = snippet(*a.Content, query)
+			}
+			result.Articles = append(result.Articles, ga)
+		}
+
+		if grepFormat == grepJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+
+		if len(narratives)+len(articles) == 0 {
+			fmt.Fprintf(os.Stderr, "No articles or narratives match %q\n", query)
+			return nil
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "## %q", query)
+		if since != "" {
+			fmt.Fprintf(&b, " since %s", since)
+		}
+		b.WriteString("\n")
+		if len(result.Narratives) > 0 {
+			b.WriteString("\n### In briefings\n\n")
+			for _, n := range result.Narratives {
+				title := "**" + n.Title + "**"
+				if n.Link != "" {
+					title = markdownLink(n.Title, n.Link)
+				}
+				fmt.Fprintf(&b, "- %s — %s", n.PeriodID, title)
+				if n.Snippet != "" {
+					b.WriteString(": " + n.Snippet)
+				}
+				var links []string
+				for _, s := range n.Sources[:min(3, len(n.Sources))] {
+					links = append(links, markdownLink(cmp.Or(s.Title, s.URL), s.URL))
+				}
+				if len(links) > 0 {
+					b.WriteString(" (" + strings.Join(links, ", ") + ")")
+				}
+				b.WriteString("\n")
+			}
+		}
+		if len(result.Articles) > 0 {
+			b.WriteString("\n### Articles\n\n")
+			for _, a := range result.Articles {
+				fmt.Fprintf(&b, "- %s — %s", cmp.Or(a.Date, "undated"), markdownLink(a.Title, a.URL))
+				if a.Source != "" {
+					b.WriteString(" (" + a.Source + ")")
+				}
+				if a.Snippet != "" {
+					b.WriteString(": " + a.Snippet)
+				}
+				b.WriteString("\n")
+			}
+		}
+		_, err = os.Stdout.WriteString(b.String())
+		return err
+	},
+}
+
+func init() {
+	grepCmd.Flags().StringVar(&grepSince, "since", "", "Only periods from this date (YYYY-MM-DD) or this long ago (e.g. 90d, 12w)")
+	grepCmd.Flags().StringVar(&grepFormat, "format", grepMarkdown, "md (Markdown digest) or json")
+	grepCmd.Flags().IntVar(&grepLimit, "limit", 20, "Most matches listed of articles and of narratives each")
+}
+
+// parseSince turns a date or an age such as "90d" or "12w" into the first
+// period to include, relative to now; empty means every period.
+func parseSince(s string, now time.Time) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if _, err := time.Parse("2006-01-02", s); err == nil {
+		return s, nil
+	}
+	unit := map[byte]int{'d': 1, 'w': 7}[s[len(s)-1]]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if unit == 0 || err != nil || n < 0 {
+		return "", fmt.Errorf("invalid --since %q (want YYYY-MM-DD, or days or weeks such as 90d or 12w)", s)
+	}
+	return now.AddDate(0, 0, -n*unit).Format("2006-01-02"), nil
+}
+
+// snippet returns a line of text around the first match of query, cut at
+// word boundaries; without a match, or with an empty query, it is the
+// start of text.
+func snippet(text, query string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	start := 0
+	if i := strings.Index(strings.ToLower(text), strings.ToLower(query)); query != "" && i >= 0 {
+		start = max(0, i-grepSnippet/3)
+	}
+	end := min(len(text), start+grepSnippet)
+	if start > 0 {
+		if j := strings.IndexByte(text[start:], ' '); j >= 0 && start+j < end {
+			start += j + 1
+		}
+	}
+	if end < len(text) {
+		if j := strings.LastIndexByte(text[start:end], ' '); j > 0 {
+			end = start + j
+		}
+	}
+	s := strings.ToValidUTF8(text[start:end], "")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) {
+		s += "…"
+	}
+	return s
+}
+
+// markdownLink writes a Markdown link, escaping brackets in its text.
+func markdownLink(text, url string) string {
+	text = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+	return "[" + text + "](" + url + ")"
+}
+
+// --- priorities command ---
+
+var prioritiesCmd = &cobra.Command{
+	Use:   "priorities",
+	Short: "Manage research priorities",
+}
+
+var prioritiesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all research priorities",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		items, err := db.GetAllPriorities()
+		if err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			fmt.Println("No priorities defined. Add one with: aicrawler priorities add")
+			return nil
+		}
+
+		profiles, err := db.GetProfiles()
+		if err != nil {
+			return err
+		}
+		profileNames := make(map[int64]string, len(profiles))
+		for _, pr := range profiles {
+			profileNames[pr.ID] = pr.Name
+		}
+
+		fmt.Println("Research Priorities:")
+		fmt.Println()
+		for _, p := range items {
+			icon := " "
+			if p.IsActive {
+				icon = "*"
+			}
+			profile := ""
+			if p.ProfileID != nil {
+				profile = fmt.Sprintf(" (%s)", profileNames[*p.ProfileID])
+			}
+			fmt.Printf("  [%d] %s %s%s\n", p.ID, icon, p.Title, profile)
+			if p.Description != nil && *p.Description != "" {
+				desc := *p.Description
+				if len(desc) > 60 {
+					desc = desc[:60] + "..."
+				}
+				fmt.Printf("        %s\n", desc)
+			}
+		}
+		return nil
+	},
+}
+
+var prioritiesAddCmd = &cobra.Command{
+	Use:   "add [title] [description]",
+	Short: "Add a new research priority",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		title := args[0]
+		description := ""
+		if len(args) > 1 {
+			description = args[1]
+		}
+
+		var profile *database.ReaderProfile
+		if priorityProfile != "" {
+			profile, err = lookupProfile(db, priorityProfile)
+			if err != nil {
+				return err
+			}
+		}
+
+		id, err := db.InsertPriority(title, description, nil)
+		if err != nil {
+			return err
+		}
+		recordAudit(db, database.AuditPriorityAdd, id, title)
+		if profile != nil {
+			if err := db.SetPriorityProfile(id, &profile.ID); err != nil {
+				return err
+			}
+			recordAudit(db, database.AuditPriorityAssign, id, profile.Name)
+			fmt.Printf("Added priority [%d] for %s: %s\n", id, profile.Name, title)
+			return nil
+		}
+		fmt.Printf("Added priority [%d]: %s\n", id, title)
+		return nil
+	},
+}
+
+var prioritiesAssignCmd = &cobra.Command{
+	Use:   "assign [id] [profile]",
+	Short: "Assign a priority to a reader profile (omit profile to share it)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid priority ID: %s", args[0])
+		}
+
+		priority, err := db.GetPriority(id)
+		if err != nil {
+			return err
+		}
+		if priority == nil {
+			return fmt.Errorf("priority %d not found", id)
+		}
+
+		if len(args) == 1 {
+			if err := db.SetPriorityProfile(id, nil); err != nil {
+				return err
+			}
+			recordAudit(db, database.AuditPriorityAssign, id, "shared")
+			fmt.Printf("Priority [%d] %s: shared by all readers\n", id, priority.Title)
+			return nil
+		}
+
+		profile, err := lookupProfile(db, args[1])
+		if err != nil {
+			return err
+		}
+		if err := db.SetPriorityProfile(id, &profile.ID); err != nil {
+			return err
+		}
+		recordAudit(db, database.AuditPriorityAssign, id, profile.Name)
+		fmt.Printf("Priority [%d] %s: assigned to %s\n", id, priority.Title, profile.Name)
+		return nil
+	},
+}
+
+var prioritiesRemoveCmd = &cobra.Command{
+	Use:   "remove [id]",
+	Short: "Remove a research priority",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid priority ID: %s", args[0])
 		}
 
 		priority, err := db.GetPriority(id)
@@ -417,6 +1431,7 @@ var prioritiesRemoveCmd = &cobra.Command{
 		if err := db.DeletePriority(id); err != nil {
 			return err
 		}
+		recordAudit(db, database.AuditPriorityDelete, id, priority.Title)
 		fmt.Printf("Removed priority [%d]: %s\n", id, priority.Title)
 		return nil
 	},
@@ -453,23 +1468,1180 @@ var prioritiesToggleCmd = &cobra.Command{
 		if !priority.IsActive {
 			newState = "enabled"
 		}
+		recordAudit(db, database.AuditPriorityToggle, id, newState)
 		fmt.Printf("Priority [%d] %s: %s\n", id, priority.Title, newState)
 		return nil
 	},
 }
 
-func init() {
-	prioritiesCmd.AddCommand(prioritiesListCmd)
-	prioritiesCmd.AddCommand(prioritiesAddCmd)
-	prioritiesCmd.AddCommand(prioritiesRemoveCmd)
-	prioritiesCmd.AddCommand(prioritiesToggleCmd)
-}
+var (
+	refineDays int
+	muteSource bool
+)
 
-func openDB() (*database.DB, error) {
-	dataDir := cfg.GetDataDir()
-	if err := os.MkdirAll(dataDir, 0o755); err != nil {
-		return nil, fmt.Errorf("creating data directory: %w", err)
-	}
-	dbPath := filepath.Join(dataDir, "aicrawler.db")
-	return database.Open(dbPath)
+var prioritiesRefineCmd = &cobra.Command{
+	Use:   "refine",
+	Short: "Answer a few questions about recent briefings and apply the priority changes they suggest",
+	Long: `Looks at what you rated useful or noisy lately and at the storylines of recent
+briefings, asks a few questions about them, and proposes new priorities,
+keyword changes, priorities to disable and terms or sources to mute. Nothing
+changes until you pick the proposals to apply.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		provider := llm.CreateProvider(cfg.Summarization.ForStep(cfg.Summarization.Steps.Compose))
+		refiner := refine.NewRefiner(db, provider, refine.Options{Days: refineDays})
+		fmt.Printf("Looking at the last %d days of ratings and briefings...\n", cmp.Or(refineDays, refine.DefaultDays))
+		questions, err := refiner.Questions(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		stdin := bufio.NewReader(os.Stdin)
+		var answers []refine.Answer
+		for i, q := range questions {
+			fmt.Printf("\n%d. %s\n> ", i+1, q)
+			answer, _ := stdin.ReadString('\n')
+			answers = append(answers, refine.Answer{Question: q, Answer: strings.TrimSpace(answer)})
+		}
+
+		changes, err := refiner.Propose(cmd.Context(), answers)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Println("\nNo changes to propose.")
+			return nil
+		}
+		fmt.Println("\nProposed changes:")
+		for i, c := range changes {
+			fmt.Printf("  %d. %s\n", i+1, c.Summary())
+			if c.Reason != "" {
+				fmt.Printf("     %s\n", c.Reason)
+			}
+		}
+		fmt.Printf("Apply which? [numbers, all or none]: ")
+		answer, _ := stdin.ReadString('\n')
+		picks, err := parsePicks(answer, len(changes))
+		if err != nil {
+			return err
+		}
+		for _, i := range picks {
+			if err := refine.Apply(db, auditUser(), changes[i]); err != nil {
+				return fmt.Errorf("applying %q: %w", changes[i].Summary(), err)
+			}
+			fmt.Printf("Applied: %s\n", changes[i].Summary())
+		}
+		if len(picks) == 0 {
+			fmt.Println("Nothing changed.")
+		}
+		return nil
+	},
+}
+
+// parsePicks reads the 0-based indexes of the items a reader picked out of
+// n from an answer like "1, 3", "all" or "none" (or nothing).
+func parsePicks(answer string, n int) ([]int, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "", "none", "n", "no":
+		return nil, nil
+	case "all", "a":
+		picks := make([]int, n)
+		for i := range picks {
+			picks[i] = i
+		}
+		return picks, nil
+	}
+	var picks []int
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 1 || i > n {
+			return nil, fmt.Errorf("no proposal %q; pick numbers of 1-%d", field, n)
+		}
+		if !slices.Contains(picks, i-1) {
+			picks = append(picks, i-1)
+		}
+	}
+	return picks, nil
+}
+
+var prioritiesMuteCmd = &cobra.Command{
+	Use:   "mute [term]",
+	Short: "Skip articles whose title mentions a term (or, with --source, all articles of a source)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		kind := database.MuteTerm
+		if muteSource {
+			kind = database.MuteSource
+		}
+		id, err := db.InsertMuteRule(kind, args[0])
+		if err != nil {
+			return err
+		}
+		value := strings.TrimSpace(args[0])
+		recordAudit(db, database.AuditMuteAdd, id, kind+" "+value)
+		if muteSource {
+			fmt.Printf("Muted [%d] source %q\n", id, value)
+		} else {
+			fmt.Printf("Muted [%d] titles mentioning %q\n", id, value)
+		}
+		return nil
+	},
+}
+
+var prioritiesMutesCmd = &cobra.Command{
+	Use:   "mutes",
+	Short: "List the mute rules",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		rules, err := db.GetMuteRules()
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			fmt.Println("Nothing is muted. Mute a term with: aicrawler priorities mute")
+			return nil
+		}
+		fmt.Println("Mute rules:")
+		for _, m := range rules {
+			fmt.Printf("  [%d] %-6s %s\n", m.ID, m.Kind, m.Value)
+		}
+		return nil
+	},
+}
+
+var prioritiesUnmuteCmd = &cobra.Command{
+	Use:   "unmute [id]",
+	Short: "Remove a mute rule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid mute rule ID: %s", args[0])
+		}
+		rule, err := db.GetMuteRule(id)
+		if err != nil {
+			return err
+		}
+		if rule == nil {
+			return fmt.Errorf("mute rule %d not found", id)
+		}
+		if err := db.DeleteMuteRule(id); err != nil {
+			return err
+		}
+		recordAudit(db, database.AuditMuteDelete, id, rule.Kind+" "+rule.Value)
+		fmt.Printf("Unmuted [%d] %s %q\n", id, rule.Kind, rule.Value)
+		return nil
+	},
+}
+
+func init() {
+	prioritiesCmd.AddCommand(prioritiesListCmd)
+	prioritiesCmd.AddCommand(prioritiesAddCmd)
+	prioritiesCmd.AddCommand(prioritiesRemoveCmd)
+	prioritiesCmd.AddCommand(prioritiesToggleCmd)
+	prioritiesCmd.AddCommand(prioritiesAssignCmd)
+	prioritiesCmd.AddCommand(prioritiesRefineCmd)
+	prioritiesCmd.AddCommand(prioritiesMuteCmd)
+	prioritiesCmd.AddCommand(prioritiesMutesCmd)
+	prioritiesCmd.AddCommand(prioritiesUnmuteCmd)
+
+	prioritiesAddCmd.Flags().StringVar(&priorityProfile, "profile", "", "Reader profile this priority belongs to")
+	prioritiesRefineCmd.Flags().IntVar(&refineDays, "days", 0, "Days of ratings and briefings to look at (default 14)")
+	prioritiesMuteCmd.Flags().BoolVar(&muteSource, "source", false, "Mute a source by its name instead of a title term")
+}
+
+// --- profiles command ---
+
+var priorityProfile string
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage reader profiles for team digests",
+}
+
+var profilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List reader profiles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		profiles, err := db.GetProfiles()
+		if err != nil {
+			return err
+		}
+		if len(profiles) == 0 {
+			fmt.Println("No reader profiles defined. Add one with: aicrawler profiles add")
+			return nil
+		}
+
+		fmt.Println("Reader Profiles:")
+		fmt.Println()
+		for _, p := range profiles {
+			priorities, err := db.GetActiveProfilePriorities(p.ID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("  %s — %q (%d active priorities)\n", p.Name, p.Heading, len(priorities))
+		}
+		return nil
+	},
+}
+
+var profilesAddCmd = &cobra.Command{
+	Use:   "add [name] [heading]",
+	Short: "Add a reader profile, e.g. add qa \"For QA\"",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		name := args[0]
+		heading := "For " + name
+		if len(args) > 1 {
+			heading = args[1]
+		}
+
+		id, err := db.InsertProfile(name, heading)
+		if err != nil {
+			return err
+		}
+		recordAudit(db, database.AuditProfileAdd, id, name)
+		fmt.Printf("Added profile %s: %s\n", name, heading)
+		return nil
+	},
+}
+
+var profilesRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a reader profile; its priorities become shared",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		profile, err := lookupProfile(db, args[0])
+		if err != nil {
+			return err
+		}
+		if err := db.DeleteProfile(profile.ID); err != nil {
+			return err
+		}
+		recordAudit(db, database.AuditProfileDelete, profile.ID, profile.Name)
+		fmt.Printf("Removed profile %s\n", profile.Name)
+		return nil
+	},
+}
+
+func init() {
+	profilesCmd.AddCommand(profilesListCmd)
+	profilesCmd.AddCommand(profilesAddCmd)
+	profilesCmd.AddCommand(profilesRemoveCmd)
+}
+
+// --- jobs command ---
+
+// jobPollInterval is how often a worker checks the queue for due jobs.
+const jobPollInterval = 30 * time.Second
+
+var (
+	jobsEdition string
+	jobsUpdate  bool
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Inspect and run the job queue",
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent jobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		list, err := db.GetJobs(20)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No jobs yet.")
+			return nil
+		}
+		for _, j := range list {
+			fmt.Printf("  [%d] %-9s %-7s attempts %d/%d  %s\n", j.ID, j.Kind, j.Status, j.Attempts, j.MaxAttempts, j.Payload)
+			if j.Status != database.JobDone && j.LastError != nil {
+				fmt.Printf("        last error: %s\n", *j.LastError)
+			}
+		}
+		return nil
+	},
+}
+
+var jobsEnqueueCmd = &cobra.Command{
+	Use:   "enqueue [kind] [period_id]",
+	Short: "Queue a job: run, refetch, recluster, resynthesize or deliver (period defaults to today)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		payload := pipeline.JobPayload{PeriodID: database.GetToday(), Edition: jobsEdition, Update: jobsUpdate}
+		if len(args) > 1 {
+			payload.PeriodID = args[1]
+		}
+		id, err := newQueue(db).Enqueue(args[0], payload)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued job [%d] %s for %s\n", id, args[0], payload.PeriodID)
+		return nil
+	},
+}
+
+var jobsRetryCmd = &cobra.Command{
+	Use:   "retry [id]",
+	Short: "Queue a failed job again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid job ID: %s", args[0])
+		}
+		ok, err := db.RetryJob(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("job %d not found or not failed", id)
+		}
+		recordAudit(db, database.AuditJobRetry, id, "")
+		fmt.Printf("Job [%d] queued again\n", id)
+		return nil
+	},
+}
+
+var jobsWorkCmd = &cobra.Command{
+	Use:   "work",
+	Short: "Run queued jobs until interrupted",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		fmt.Println("Working on the job queue. Press Ctrl+C to stop")
+		return newQueue(db).Work(ctx, jobPollInterval)
+	},
+}
+
+func init() {
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsEnqueueCmd)
+	jobsCmd.AddCommand(jobsRetryCmd)
+	jobsCmd.AddCommand(jobsWorkCmd)
+
+	jobsEnqueueCmd.Flags().StringVar(&jobsEdition, "edition", database.EditionMorning, "Edition for run and deliver jobs")
+	jobsEnqueueCmd.Flags().BoolVar(&jobsUpdate, "update", false, "Make a run job update the morning briefing, like 'run --update'")
+}
+
+// --- export / import commands ---
+
+var exportOutput string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export profiles, priorities, mute rules and feedback as a JSON bundle",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		bundle, err := db.ExportBundle()
+		if err != nil {
+			return fmt.Errorf("exporting: %w", err)
+		}
+		data, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if exportOutput == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(exportOutput, data, 0o644); err != nil {
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+		fmt.Printf("Exported %d profiles, %d priorities, %d mute rules, %d article and %d storyline ratings to %s\n",
+			len(bundle.Profiles), len(bundle.Priorities), len(bundle.MuteRules), len(bundle.ArticleFeedback), len(bundle.StorylineFeedback), exportOutput)
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import a bundle written by export; existing data is kept",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		var bundle database.Bundle
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return fmt.Errorf("reading bundle: %w", err)
+		}
+
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		result, err := db.ImportBundle(&bundle, auditUser())
+		if err != nil {
+			return fmt.Errorf("importing: %w", err)
+		}
+		recordAudit(db, database.AuditImport, 0, filepath.Base(args[0]))
+		fmt.Printf("Imported %d profiles, %d priorities, %d mute rules, %d storyline ratings\n", result.Profiles, result.Priorities, result.MuteRules, result.StorylineFeedback)
+		fmt.Printf("Imported %d article ratings (%d waiting for their article to be collected)\n",
+			result.ArticleFeedback+result.PendingFeedback, result.PendingFeedback)
+		if result.Skipped > 0 {
+			fmt.Printf("Skipped %d items already present or not found\n", result.Skipped)
+		}
+		return nil
+	},
+}
+
+// Formats of export training.
+const (
+	trainingJSONL = "jsonl" // one TrainingRecord per line
+	trainingChat  = "chat"  // one chat fine-tuning example per line: the prompt, then the label
+)
+
+var (
+	trainingFormat   string
+	trainingOutput   string
+	trainingSince    string
+	trainingMaxChars int
+)
+
+var exportTrainingCmd = &cobra.Command{
+	Use:   "training",
+	Short: "Export triage decisions as prompt/label pairs for training a classifier",
+	Long: "Writes one JSON line per triaged article: a short classification prompt built from its title, source and content, and its label. " +
+		"A reader's rating overrides the triage verdict (positive: relevant, negative: skip). Articles from policy feeds, and those whose triage " +
+		"response couldn't be parsed, are left out. --format chat writes chat fine-tuning examples instead.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if trainingFormat != trainingJSONL && trainingFormat != trainingChat {
+			return fmt.Errorf("unknown format %q (want %s or %s)", trainingFormat, trainingJSONL, trainingChat)
+		}
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		examples, err := db.GetTrainingExamples(trainingSince)
+		if err != nil {
+			return fmt.Errorf("exporting: %w", err)
+		}
+		opts := triage.TrainingOptions{Audience: cfg.Persona.Audience, MaxChars: trainingMaxChars}
+		for _, f := range cfg.Policy.Feeds {
+			opts.PolicySources = append(opts.PolicySources, collect.FeedConfig{URL: f.URL, Name: f.Name}.SourceName())
+		}
+		records := triage.TrainingRecords(examples, opts)
+
+		var out bytes.Buffer
+		enc := json.NewEncoder(&out)
+		overridden := 0
+		for _, r := range records {
+			if r.LabelSource == triage.LabelFeedback && r.Label != r.TriageVerdict {
+				overridden++
+			}
+			var line any = r
+			if trainingFormat == trainingChat {
+				line = map[string]any{"messages": []map[string]string{
+					{"role": "user", "content": r.Prompt},
+					{"role": "assistant", "content": r.Label},
+				}}
+			}
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+		}
+
+		if trainingOutput == "" {
+			_, err = os.Stdout.Write(out.Bytes())
+			return err
+		}
+		if err := os.WriteFile(trainingOutput, out.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("writing training data: %w", err)
+		}
+		fmt.Printf("Exported %d examples (%d verdicts overridden by ratings) to %s\n", len(records), overridden, trainingOutput)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the bundle to this file instead of stdout")
+
+	exportCmd.AddCommand(exportTrainingCmd)
+	exportTrainingCmd.Flags().StringVar(&trainingFormat, "format", trainingJSONL, "jsonl (prompt/label records) or chat (fine-tuning messages)")
+	exportTrainingCmd.Flags().StringVarP(&trainingOutput, "output", "o", "", "Write to this file instead of stdout")
+	exportTrainingCmd.Flags().StringVar(&trainingSince, "since", "", "Only periods from this date on (YYYY-MM-DD)")
+	exportTrainingCmd.Flags().IntVar(&trainingMaxChars, "max-chars", 0, "Characters of article content per prompt (default 2000)")
+}
+
+// --- feeds commands ---
+
+var (
+	feedsExportOutput string
+	feedsAddName      string
+	feedsAddType      string
+	feedsAddPick      int
+	feedsTestDays     int
+)
+
+var feedsCmd = &cobra.Command{
+	Use:   "feeds",
+	Short: "List, add, remove and test feeds, or move subscriptions in and out as OPML",
+}
+
+var feedsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sources.feeds of the config, with the feeds that keep failing",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		feeds := cfg.Sources.Feeds
+		if len(feeds) == 0 {
+			fmt.Printf("No feeds in %s; add one with `aicrawler feeds add <site-url>`.\n", configFile)
+			return nil
+		}
+		// Without a database nothing was collected yet, so no feed failed.
+		failing := make(map[string]database.FeedHealth)
+		if _, err := os.Stat(dataStore().DatabasePath()); err == nil {
+			if db, err := openDB(); err == nil {
+				health, _ := db.GetFailingFeeds(1)
+				db.Close()
+				for _, h := range health {
+					failing[h.FeedURL] = h
+				}
+			}
+		}
+
+		for _, f := range feeds {
+			var notes []string
+			if f.Type != "" {
+				notes = append(notes, f.Type)
+			}
+			if f.Scrape != nil {
+				notes = append(notes, "scraped")
+			}
+			if f.Sitemap != nil {
+				notes = append(notes, "sitemap")
+			}
+			if f.Disabled {
+				notes = append(notes, "disabled")
+			}
+			if h, ok := failing[f.URL]; ok {
+				notes = append(notes, fmt.Sprintf("failing %dx", h.Failures))
+			}
+			line := fmt.Sprintf("  %-28s %s", cmp.Or(f.Name, collect.FeedConfig{URL: f.URL}.SourceName()), f.URL)
+			if len(notes) > 0 {
+				line += " (" + strings.Join(notes, ", ") + ")"
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("%d feeds in %s\n", len(feeds), configFile)
+		return nil
+	},
+}
+
+var feedsRemoveCmd = &cobra.Command{
+	Use:   "remove [feed-url]",
+	Short: "Remove a feed from sources.feeds of the config",
+	Long:  "Removes the feed from sources.feeds of the config, editing the file as text so the rest of it stays as it is. Articles already collected from the feed are kept; to stop collecting a feed for a while, set `disabled: true` instead.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		feed, err := config.RemoveFeed(configFile, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s (%s) from %s\n", cmp.Or(feed.Name, feed.URL), feed.URL, configFile)
+		return nil
+	},
+}
+
+var feedsTestCmd = &cobra.Command{
+	Use:   "test [feed-url]",
+	Short: "Check that a feed parses, and show the entries it would bring",
+	Long:  "Reads the feed the way collect does, and lists the entries within the lookback window. A feed from sources.feeds is read with its settings, so scraped pages and sitemaps are tested as configured; any other URL is read as a plain feed. Nothing is stored.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		feed, configured := cfg.Sources.FindFeed(args[0])
+		if !configured {
+			feed = config.Feed{URL: args[0]}
+		}
+
+		client := &http.Client{Transport: cfg.Network.Transport(), Timeout: cfg.Performance.HTTP.SourceTimeout()}
+		entries, err := collect.ReadFeed(client, cfg.Fetch.Agent(), feed, feedsTestDays)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", feed.URL, err)
+		}
+		if !configured {
+			fmt.Printf("%s is not in %s; read as a plain feed.\n", feed.URL, configFile)
+		}
+		fmt.Printf("%s parses: %d entries within %d days\n", collect.FeedConfig{URL: feed.URL, Name: feed.Name}.SourceName(), len(entries), feedsTestDays)
+		for _, e := range entries[:min(len(entries), 10)] {
+			fmt.Printf("  %-10s %s\n", cmp.Or(e.PublishedDate, "undated"), e.Title)
+		}
+		if len(entries) > 10 {
+			fmt.Printf("  ... and %d more\n", len(entries)-10)
+		}
+		return nil
+	},
+}
+
+var feedsImportCmd = &cobra.Command{
+	Use:   "import [file.opml]",
+	Short: "Add the feeds of an OPML file to sources.feeds of the config",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		feeds, err := config.ParseOPML(f, append(slices.Clone(database.SourceTypes), collect.AggregatorType))
+		if err != nil {
+			return err
+		}
+		added, err := config.AddFeeds(configFile, feeds, "Imported from "+filepath.Base(args[0]))
+		if err != nil {
+			return err
+		}
+		for _, feed := range added {
+			fmt.Printf("  + %s (%s)\n", cmp.Or(feed.Name, feed.URL), feed.URL)
+		}
+		fmt.Printf("Added %d feeds to %s", len(added), configFile)
+		if known := len(feeds) - len(added); known > 0 {
+			fmt.Printf(" (%d already configured)", known)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+var feedsAddCmd = &cobra.Command{
+	Use:   "add [site-url]",
+	Short: "Find the feeds a site offers and add one to sources.feeds",
+	Long:  "Fetches the page, finds the feeds it announces (or the page itself when it is a feed, or feeds at common paths such as /feed), and adds the one you pick to sources.feeds of the config.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		types := append(slices.Clone(database.SourceTypes), collect.AggregatorType)
+		if feedsAddType != "" && !slices.Contains(types, feedsAddType) {
+			return fmt.Errorf("unknown type %q (want one of %s)", feedsAddType, strings.Join(types, ", "))
+		}
+		client := &http.Client{Transport: cfg.Network.Transport(), Timeout: cfg.Performance.HTTP.SourceTimeout()}
+		feeds, err := collect.DiscoverFeeds(cmd.Context(), client, cfg.Fetch.Agent(), args[0])
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", args[0], err)
+		}
+		if len(feeds) == 0 {
+			return fmt.Errorf("no feed found at %s; pass the feed's URL if you know it", args[0])
+		}
+
+		pick := feedsAddPick
+		if pick == 0 && len(feeds) == 1 {
+			pick = 1
+		}
+		if pick == 0 {
+			fmt.Printf("%s offers %d feeds:\n", args[0], len(feeds))
+			for i, f := range feeds {
+				fmt.Printf("  %d. %s (%s, %d entries)\n", i+1, cmp.Or(f.Title, "untitled"), f.URL, f.Items)
+			}
+			fmt.Printf("Add which feed? [1-%d]: ", len(feeds))
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if pick, err = strconv.Atoi(strings.TrimSpace(answer)); err != nil {
+				return fmt.Errorf("aborted")
+			}
+		}
+		if pick < 1 || pick > len(feeds) {
+			return fmt.Errorf("no feed %d; pick one of 1-%d", pick, len(feeds))
+		}
+
+		found := feeds[pick-1]
+		feed := config.Feed{URL: found.URL, Name: cmp.Or(feedsAddName, found.Title), Type: feedsAddType}
+		added, err := config.AddFeeds(configFile, []config.Feed{feed}, "")
+		if err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			fmt.Printf("%s is already in %s\n", feed.URL, configFile)
+			return nil
+		}
+		fmt.Printf("Added %s (%s) to %s\n", cmp.Or(feed.Name, feed.URL), feed.URL, configFile)
+		return nil
+	},
+}
+
+var feedsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write sources.feeds of the config as an OPML file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var out bytes.Buffer
+		if err := config.WriteOPML(&out, "AICrawler feeds", cfg.Sources.Feeds); err != nil {
+			return err
+		}
+		if feedsExportOutput == "" {
+			_, err := os.Stdout.Write(out.Bytes())
+			return err
+		}
+		if err := os.WriteFile(feedsExportOutput, out.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("writing OPML: %w", err)
+		}
+		fmt.Printf("Exported %d feeds to %s\n", len(cfg.Sources.Feeds), feedsExportOutput)
+		return nil
+	},
+}
+
+func init() {
+	feedsCmd.AddCommand(feedsListCmd)
+	feedsCmd.AddCommand(feedsAddCmd)
+	feedsCmd.AddCommand(feedsImportCmd)
+	feedsAddCmd.Flags().StringVar(&feedsAddName, "name", "", "Source name (default: the feed's title)")
+	feedsAddCmd.Flags().StringVar(&feedsAddType, "type", "", "Source type: blog, vendor, news, academic or aggregator (default: inferred from the URL)")
+	feedsAddCmd.Flags().IntVar(&feedsAddPick, "pick", 0, "Add the nth feed found without asking")
+	feedsCmd.AddCommand(feedsRemoveCmd)
+	feedsCmd.AddCommand(feedsTestCmd)
+	feedsTestCmd.Flags().IntVar(&feedsTestDays, "days-back", 7, "Lookback window (days)")
+	feedsCmd.AddCommand(feedsExportCmd)
+	feedsExportCmd.Flags().StringVarP(&feedsExportOutput, "output", "o", "", "Write to this file instead of stdout")
+}
+
+// --- llm commands ---
+
+var llmLogFilter database.LLMCallFilter
+
+var llmCmd = &cobra.Command{
+	Use:   "llm",
+	Short: "Inspect the LLM call log",
+}
+
+var llmLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "List recent LLM calls, newest first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		calls, err := db.GetLLMCalls(llmLogFilter)
+		if err != nil {
+			return err
+		}
+		if len(calls) == 0 {
+			fmt.Println("No LLM calls logged.")
+			return nil
+		}
+		for _, c := range calls {
+			model := "cache"
+			if !c.Cached {
+				model = c.Provider + "/" + c.Model
+			}
+			var subject string
+			if c.ArticleID != nil {
+				subject = fmt.Sprintf("  article %d", *c.ArticleID)
+			}
+			if c.StorylineID != nil {
+				subject += fmt.Sprintf("  storyline %d", *c.StorylineID)
+			}
+			fmt.Printf("  [%d] %s %-10s %s  %dms  %d+%d tokens%s\n",
+				c.ID, c.CreatedAt, c.Step, model, c.LatencyMS, c.PromptTokens, c.CompletionTokens, subject)
+			if c.Error != nil {
+				fmt.Printf("        error: %s\n", *c.Error)
+			}
+			prompt, _, _ := strings.Cut(strings.TrimSpace(c.Prompt), "\n")
+			if len(prompt) > 100 {
+				prompt = prompt[:100] + "..."
+			}
+			fmt.Printf("        %s\n", prompt)
+		}
+		return nil
+	},
+}
+
+var llmShowCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: "Print the full prompt and response of a logged call",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid call ID: %s", args[0])
+		}
+		c, err := db.GetLLMCall(id)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return fmt.Errorf("LLM call %d not found", id)
+		}
+		fmt.Printf("Call [%d] %s, step %s, run %s\n", c.ID, c.CreatedAt, c.Step, c.RunID)
+		if c.Cached {
+			fmt.Println("Answered from the response cache")
+		} else {
+			fmt.Printf("Model %s/%s, %dms, %d prompt + %d completion tokens\n",
+				c.Provider, c.Model, c.LatencyMS, c.PromptTokens, c.CompletionTokens)
+		}
+		if c.Error != nil {
+			fmt.Printf("Error: %s\n", *c.Error)
+		}
+		fmt.Printf("\n--- prompt ---\n%s\n\n--- response ---\n%s\n", c.Prompt, c.Response)
+		return nil
+	},
+}
+
+func init() {
+	llmCmd.AddCommand(llmLogCmd)
+	llmCmd.AddCommand(llmShowCmd)
+
+	llmLogCmd.Flags().StringVar(&llmLogFilter.Step, "step", "", "Only calls of this pipeline step (e.g. Triage)")
+	llmLogCmd.Flags().Int64Var(&llmLogFilter.ArticleID, "article", 0, "Only calls about this article")
+	llmLogCmd.Flags().Int64Var(&llmLogFilter.StorylineID, "storyline", 0, "Only calls about this storyline")
+	llmLogCmd.Flags().StringVar(&llmLogFilter.RunID, "run", "", "Only calls of this pipeline run")
+	llmLogCmd.Flags().BoolVar(&llmLogFilter.ErrorsOnly, "errors", false, "Only failed calls")
+	llmLogCmd.Flags().IntVar(&llmLogFilter.Limit, "limit", 20, "Number of calls to list")
+}
+
+// --- storage commands ---
+
+var (
+	storageOlderThan string
+	storageDryRun    bool
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Report and clean up what the data directory holds",
+}
+
+var storageUsageCmd = &cobra.Command{
+	Use:     "usage",
+	Aliases: []string{"du"},
+	Short:   "Show how much disk space each area of the data directory uses",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := dataStore()
+		usage, err := store.Usage()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Data directory: %s\n\n", store.Dir())
+		if len(usage) == 0 {
+			fmt.Println("  Empty")
+			return nil
+		}
+		files, bytes := 0, int64(0)
+		for _, u := range usage {
+			name := u.Area
+			if u.Profile != "" {
+				name = fmt.Sprintf("%s (profile %s)", u.Area, u.Profile)
+			}
+			fmt.Printf("  %-28s %6d files %10s\n", name, u.Files, formatSize(u.Bytes))
+			files += u.Files
+			bytes += u.Bytes
+		}
+		fmt.Printf("\n  %-28s %6d files %10s\n", "Total", files, formatSize(bytes))
+		return nil
+	},
+}
+
+var storageCleanCmd = &cobra.Command{
+	Use:   "clean [area...]",
+	Short: "Remove old files from areas of the data directory (default: the HTML cache)",
+	Long: fmt.Sprintf("Removes the files of the given areas (%s) last modified more than --older-than ago, including those of each reader profile's directory. The database is never cleaned, and cleaning backups keeps the newest one.",
+		strings.Join(storage.Areas[1:], ", ")),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		age, err := parseAge(storageOlderThan)
+		if err != nil {
+			return err
+		}
+		areas := args
+		if len(areas) == 0 {
+			areas = []string{storage.Cache}
+		}
+		before := time.Now().Add(-age)
+		store := dataStore()
+		verb := "Removed"
+		if storageDryRun {
+			verb = "Would remove"
+		}
+		for _, area := range areas {
+			files, bytes, err := store.Clean(area, before, storageDryRun)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s %d files (%s) from %s\n", verb, files, formatSize(bytes), area)
+		}
+		return nil
+	},
+}
+
+var storageBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write a copy of the database to the backups directory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		path := dataStore().BackupPath(time.Now())
+		if err := db.BackupTo(path); err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Backed up the database to %s (%s)\n", path, formatSize(info.Size()))
+		return nil
+	},
+}
+
+func init() {
+	storageCleanCmd.Flags().StringVar(&storageOlderThan, "older-than", "30d", "Only remove files older than this, in days, weeks or a duration such as 30d, 2w or 12h")
+	storageCleanCmd.Flags().BoolVar(&storageDryRun, "dry-run", false, "Report what would be removed without removing it")
+	storageCmd.AddCommand(storageUsageCmd)
+	storageCmd.AddCommand(storageCleanCmd)
+	storageCmd.AddCommand(storageBackupCmd)
+}
+
+// parseAge parses an age given in days (30d), weeks (2w) or as a duration.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	if s != "" {
+		unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+		if n, err := strconv.Atoi(s[:len(s)-1]); unit != 0 && err == nil && n >= 0 {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid age %q (want days, weeks or a duration such as 30d, 2w or 12h)", s)
+}
+
+// formatSize formats a number of bytes for people, e.g. "1.5 MB".
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, prefix := float64(bytes)/unit, 0
+	for value >= unit && prefix < 3 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[prefix])
+}
+
+// --- audit command ---
+
+var (
+	auditFilter database.AuditFilter
+	auditLimit  int
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List manual edits from the web UI and CLI, newest first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		entries, total, err := db.ListAuditEntries(auditFilter, database.ListOptions{Limit: auditLimit})
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("No edits recorded.")
+			return nil
+		}
+		for _, e := range entries {
+			target := ""
+			if e.TargetID != nil {
+				target = fmt.Sprintf(" [%d]", *e.TargetID)
+			}
+			detail := ""
+			if e.Detail != "" {
+				detail = ": " + e.Detail
+			}
+			fmt.Printf("  %s  %-12s %s%s%s\n", e.CreatedAt, e.User, e.Action, target, detail)
+		}
+		if total > len(entries) {
+			fmt.Printf("\n%d of %d edits shown; raise --limit to see more\n", len(entries), total)
+		}
+		return nil
+	},
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditFilter.User, "user", "", "Only edits by this user (cli:<name> for the CLI)")
+	auditCmd.Flags().StringVar(&auditFilter.Action, "action", "", "Only edits of this action (e.g. priority_delete)")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 20, "Number of edits to list")
+}
+
+// auditUser names the CLI user in the audit log after the OS account, so
+// their edits stand apart from those made through the web UI.
+func auditUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	return "cli"
+}
+
+// recordAudit logs a manual edit made with the CLI. The edit is already
+// done, so a failure only warns.
+func recordAudit(db *database.DB, action string, targetID int64, detail string) {
+	if err := db.RecordAudit(auditUser(), action, targetID, detail); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: recording the edit in the audit log: %v\n", err)
+	}
+}
+
+// newQueue returns a job queue with the pipeline's job kinds registered.
+func newQueue(db *database.DB) *jobs.Queue {
+	q := jobs.NewQueue(db)
+	pipeline.RegisterJobs(q, cfg, db)
+	return q
+}
+
+// runJob queues a job and works the queue until nothing is due, returning
+// the job's final state.
+func runJob(ctx context.Context, db *database.DB, kind string, payload any) (*ranJob, error) {
+	q := newQueue(db)
+	id, err := q.Enqueue(kind, payload)
+	if err != nil {
+		return nil, err
+	}
+	var last error
+	q.Observe(func(jobID int64, err error) {
+		if jobID == id {
+			last = err
+		}
+	})
+	if _, err := q.RunPending(ctx); err != nil {
+		return nil, err
+	}
+	job, err := db.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil && job.LastError != nil {
+		last = errors.New(*job.LastError)
+	}
+	return &ranJob{Job: job, Err: last}, nil
+}
+
+// ranJob is a job run by runJob, with the error of its last attempt, which
+// the queue only keeps as text.
+type ranJob struct {
+	*database.Job
+	Err error
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func lookupProfile(db *database.DB, name string) (*database.ReaderProfile, error) {
+	profile, err := db.GetProfileByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return profile, nil
+}
+
+// dataStore returns the manager of the configured data directory.
+func dataStore() *storage.Manager {
+	return storage.New(cfg.GetDataDir())
+}
+
+func openDB() (*database.DB, error) {
+	return database.Open(dataStore().DatabasePath())
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveDate(t *testing.T) {
+	const today = "2026-03-10"
+	tests := []struct {
+		name      string
+		date      string
+		daysBack  int
+		wantPast  bool
+		wantError string
+	}{
+		{"no date", "", 0, false, ""},
+		{"today", today, 0, false, ""},
+		{"today with days back", today, 3, false, ""},
+		{"past day", "2026-03-02", 0, true, ""},
+		{"past day with days back", "2026-03-02", 3, false, "cannot be combined"},
+		{"not a date", "10.03.2026", 0, false, "invalid --date"},
+		{"not a day", "2026-02-30", 0, false, "invalid --date"},
+		{"future day", "2026-03-11", 0, false, "in the future"},
+	}
+	for _, tt := range tests {
+		past, err := resolveDate(today, tt.date, tt.daysBack)
+		if tt.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+		}
+		if past != tt.wantPast {
+			t.Errorf("%s: expected past day %v, got %v", tt.name, tt.wantPast, past)
+		}
+	}
+}
+
+func TestCheckPollInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		wantErr  bool
+	}{
+		{30 * time.Minute, false},
+		{time.Minute, false},
+		{59 * time.Second, true},
+		{0, true},
+		{-time.Hour, true},
+	}
+	for _, tt := range tests {
+		if err := checkPollInterval(tt.interval); (err != nil) != tt.wantErr {
+			t.Errorf("checkPollInterval(%s): expected error %v, got %v", tt.interval, tt.wantErr, err)
+		}
+	}
+}
+
+func TestPollUntilDone(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelled bool
+		stopAfter int
+		want      int
+	}{
+		// A poll runs even when an interrupt came first.
+		{"already interrupted", true, 0, 1},
+		{"interrupted during the first poll", false, 1, 1},
+		{"interrupted during the third poll", false, 3, 3},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.cancelled {
+			cancel()
+		}
+		polls := 0
+		pollUntilDone(ctx, time.Millisecond, func() {
+			polls++
+			if polls == tt.stopAfter {
+				cancel()
+			}
+		})
+		cancel()
+		if polls != tt.want {
+			t.Errorf("%s: expected %d polls, got %d", tt.name, tt.want, polls)
+		}
+	}
+}
+
+func TestFetchPeriodID(t *testing.T) {
+	counts := map[string]int{"2026-03-10": 4, "2026-03-09": 0}
+	countArticles := func(period string) (int, error) {
+		if period == "broken" {
+			return 0, errors.New("database is locked")
+		}
+		return counts[period], nil
+	}
+	tests := []struct {
+		name      string
+		period    string
+		all       bool
+		want      string
+		wantError string
+	}{
+		{"every period by default", "", false, "", ""},
+		{"every period with --all", "", true, "", ""},
+		{"period with articles", "2026-03-10", false, "2026-03-10", ""},
+		{"period and --all", "2026-03-10", true, "", "can't be combined"},
+		{"period without articles", "2026-03-09", false, "", "no articles collected for 2026-03-09"},
+		{"unknown period", "2026-01-01", false, "", "no articles collected"},
+		{"failed lookup", "broken", false, "", "database is locked"},
+	}
+	for _, tt := range tests {
+		got, err := fetchPeriodID(tt.period, tt.all, countArticles)
+		if tt.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+			continue
+		}
+		if tt.want == "" && got != nil {
+			t.Errorf("%s: expected every period, got %s", tt.name, *got)
+		}
+		if tt.want != "" && (got == nil || *got != tt.want) {
+			t.Errorf("%s: expected period %s, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package cluster
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"strings"
 
@@ -10,7 +13,7 @@ import (
 )
 
 const (
-	BrieflyNotedLabel         = "Briefly Noted"
+	BrieflyNotedLabel        = "Briefly Noted"
 	DefaultDistanceThreshold = 0.9
 )
 
@@ -19,24 +22,46 @@ type Result struct {
 	StorylineCount    int
 	ArticleCount      int
 	BrieflyNotedCount int
+	SpotlightCount    int  // of StorylineCount, storylines of one article from a too-small cluster
+	KeywordFallback   bool // embedding failed; articles were clustered with TF-IDF vectors
+}
+
+// Options controls what becomes of articles in small clusters.
+type Options struct {
+	// MinClusterSize is the fewest articles a cluster needs to become a
+	// storyline; the articles of smaller ones go to Briefly Noted. Below 2
+	// means 2.
+	MinClusterSize int
+
+	// SpotlightScore, when above 0, gives each article of a too-small cluster
+	// with at least this practical score a storyline of its own instead of a
+	// Briefly Noted bullet.
+	SpotlightScore int
 }
 
 // Clusterer clusters relevant articles into storylines using embeddings.
+// Embeddings are cached per article and embedding model, so re-clustering
+// a period only embeds articles that are new or whose text changed.
 type Clusterer struct {
 	db                *database.DB
 	embedder          llm.Embedder
+	embeddingModel    string // cache key of the embedder; empty disables the cache
 	distanceThreshold float64
+	opts              Options
 }
 
 // NewClusterer creates a new article clusterer.
-func NewClusterer(db *database.DB, embedder llm.Embedder, distanceThreshold float64) *Clusterer {
+func NewClusterer(db *database.DB, embedder llm.Embedder, distanceThreshold float64, opts Options) *Clusterer {
 	if distanceThreshold <= 0 {
 		distanceThreshold = DefaultDistanceThreshold
 	}
+	opts.MinClusterSize = max(opts.MinClusterSize, 2)
 	return &Clusterer{
 		db:                db,
 		embedder:          embedder,
+		embeddingModel:    llm.EmbedderName(embedder),
 		distanceThreshold: distanceThreshold,
+		opts:              opts,
 	}
 }
 
@@ -57,57 +82,66 @@ func (c *Clusterer) ClusterArticles(ctx context.Context, periodID string) (*Resu
 		return nil, err
 	}
 
-	if len(articles) < 2 {
-		// Single article -> Briefly Noted
-		ids := make([]int64, len(articles))
+	// A single article is a cluster of its own
+	clusterLabels := []int{0}
+	keywordFallback := false
+	if len(articles) >= 2 {
+		// Build text representations for embedding
+		texts := make([]string, len(articles))
 		for i, a := range articles {
-			ids[i] = a.ID
+			texts[i] = c.articleText(a)
 		}
-		c.db.InsertStoryline(periodID, BrieflyNotedLabel, ids)
-		return &Result{
-			StorylineCount:    1,
-			ArticleCount:      len(articles),
-			BrieflyNotedCount: len(articles),
-		}, nil
-	}
 
-	// Build text representations for embedding
-	texts := make([]string, len(articles))
-	for i, a := range articles {
-		texts[i] = c.articleText(a)
-	}
+		// Generate embeddings, falling back to the built-in TF-IDF embedder
+		// when the configured one is unavailable so the briefing still has
+		// storylines
+		log.Printf("Generating embeddings for %d articles...", len(articles))
+		embeddings, err := c.embed(ctx, articles, texts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("Embedding failed, clustering with TF-IDF vectors instead: %v", err)
+			embeddings, _ = (&llm.TFIDFEmbedder{StopWords: stopWords}).Embed(ctx, texts)
+			keywordFallback = true
+		}
 
-	// Generate embeddings
-	log.Printf("Generating embeddings for %d articles...", len(articles))
-	embeddings, err := c.embedder.Embed(ctx, texts)
-	if err != nil {
-		return nil, err
+		// Cluster using Ward's linkage
+		clusterLabels = c.clusterEmbeddings(embeddings)
 	}
 
-	// Cluster using Ward's linkage
-	clusterLabels := c.clusterEmbeddings(embeddings)
-
 	// Group articles by cluster
 	groups := make(map[int][]database.Article)
 	for i, label := range clusterLabels {
 		groups[label] = append(groups[label], articles[i])
 	}
 
-	// Separate real storylines from singletons
+	// Separate real storylines from small clusters, whose articles are
+	// spotlighted or briefly noted
 	var storylines [][]database.Article
 	var brieflyNoted []database.Article
+	spotlights := 0
 
 	for _, group := range groups {
-		if len(group) >= 2 {
+		if len(group) >= c.opts.MinClusterSize {
 			storylines = append(storylines, group)
-		} else {
-			brieflyNoted = append(brieflyNoted, group...)
+			continue
+		}
+		for _, a := range group {
+			if c.spotlighted(a) {
+				storylines = append(storylines, []database.Article{a})
+				spotlights++
+			} else {
+				brieflyNoted = append(brieflyNoted, a)
+			}
 		}
 	}
 
-	// Store storylines
+	// Store storylines, keeping labels distinct within the period
+	usedLabels := map[string]bool{database.FoldLabel(BrieflyNotedLabel): true}
 	for _, group := range storylines {
-		label := generateLabel(group)
+		label := generateLabel(group, usedLabels)
+		usedLabels[database.FoldLabel(label)] = true
 		ids := make([]int64, len(group))
 		for i, a := range group {
 			ids[i] = a.ID
@@ -131,16 +165,260 @@ func (c *Clusterer) ClusterArticles(ctx context.Context, periodID string) (*Resu
 		totalStorylines++
 	}
 
-	log.Printf("Clustering complete: %d storylines + %d briefly noted from %d articles",
-		len(storylines), brieflyNotedCount, len(articles))
+	log.Printf("Clustering complete: %d storylines (%d spotlighted) + %d briefly noted from %d articles",
+		len(storylines), spotlights, brieflyNotedCount, len(articles))
 
 	return &Result{
 		StorylineCount:    totalStorylines,
 		ArticleCount:      len(articles),
 		BrieflyNotedCount: brieflyNotedCount,
+		SpotlightCount:    spotlights,
+		KeywordFallback:   keywordFallback,
 	}, nil
 }
 
+// spotlighted reports whether an article of a too-small cluster gets a
+// storyline of its own.
+func (c *Clusterer) spotlighted(a database.Article) bool {
+	if c.opts.SpotlightScore <= 0 {
+		return false
+	}
+	triage, _ := c.db.GetTriage(a.ID)
+	return triage != nil && triage.PracticalScore >= c.opts.SpotlightScore
+}
+
+// AssignResult holds the results of assigning new articles to storylines.
+type AssignResult struct {
+	Assigned   int // articles added to a storyline
+	Unassigned int // articles no storyline was close enough to
+	Storylines int // storylines that got articles
+}
+
+// AssignArticles adds the period's relevant articles that are in no
+// storyline yet to the storyline they would have joined when clustered
+// together, leaving the other storylines as they are. An article joins the
+// storyline whose Ward merge distance to it is lowest, if that is within
+// the clustering threshold; Briefly Noted takes none. The narratives of
+// storylines that got articles become stale.
+func (c *Clusterer) AssignArticles(ctx context.Context, periodID string) (*AssignResult, error) {
+	pending, err := c.db.GetUnclusteredArticles(periodID)
+	if err != nil {
+		return nil, err
+	}
+	r := &AssignResult{Unassigned: len(pending)}
+	if len(pending) == 0 {
+		return r, nil
+	}
+	storylines, err := c.db.GetStorylinesForPeriod(periodID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Embed the articles of every storyline along with the pending ones, so
+	// all vectors come from one embedder; cached vectors make this cheap.
+	articles := pending
+	var sizes []int
+	var targets []database.Storyline
+	for _, s := range storylines {
+		if s.Label == BrieflyNotedLabel {
+			continue
+		}
+		members, err := c.db.GetStorylineArticles(s.ID, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(members) == 0 {
+			continue
+		}
+		articles = append(articles, members...)
+		sizes = append(sizes, len(members))
+		targets = append(targets, s)
+	}
+	if len(targets) == 0 {
+		return r, nil
+	}
+	texts := make([]string, len(articles))
+	for i, a := range articles {
+		texts[i] = c.articleText(a)
+	}
+	embeddings, err := c.embed(ctx, articles, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	centroids := make([][]float64, len(targets))
+	next := len(pending)
+	for i, size := range sizes {
+		centroids[i] = centroid(embeddings[next : next+size])
+		next += size
+	}
+
+	joined := map[int64]bool{}
+	for i, a := range pending {
+		best, bestDist := -1, c.distanceThreshold
+		for j := range targets {
+			if d := wardDistance(embeddings[i], centroids[j], sizes[j]); d <= bestDist {
+				best, bestDist = j, d
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		if _, err := c.db.AddStorylineArticle(targets[best].ID, a.ID); err != nil {
+			return nil, err
+		}
+		r.Assigned++
+		r.Unassigned--
+		joined[targets[best].ID] = true
+	}
+	r.Storylines = len(joined)
+	log.Printf("Assigned %d new articles to %d storylines, %d fit none", r.Assigned, r.Storylines, r.Unassigned)
+	return r, nil
+}
+
+// centroid returns the mean of vectors of one size.
+func centroid(vectors [][]float64) []float64 {
+	mean := make([]float64, len(vectors[0]))
+	for _, v := range vectors {
+		for k, x := range v {
+			mean[k] += x
+		}
+	}
+	for k := range mean {
+		mean[k] /= float64(len(vectors))
+	}
+	return mean
+}
+
+// dimensionProbe is embedded to learn the vector size of the embedder.
+const dimensionProbe = "embedding dimension check"
+
+// CheckEmbeddingCache validates the cached embeddings of the embedder
+// against the vectors it returns now, which change size when a model is
+// replaced under the same name or reconfigured. Cached vectors of another
+// size are deleted, so they are embedded again instead of being mixed with
+// new ones; it returns how many were. Without cached vectors the embedder
+// isn't called.
+func (c *Clusterer) CheckEmbeddingCache(ctx context.Context) (int64, error) {
+	if c.embedder == nil || c.embeddingModel == "" {
+		return 0, nil
+	}
+	dims, err := c.db.EmbeddingDimensions(c.embeddingModel)
+	if err != nil || len(dims) == 0 {
+		return 0, err
+	}
+	probe, err := c.embedTexts(ctx, []string{dimensionProbe})
+	if err != nil {
+		return 0, fmt.Errorf("checking embedding dimensions: %w", err)
+	}
+	want := len(probe[0])
+	if len(dims) == 1 && dims[0] == want {
+		return 0, nil
+	}
+	dropped, err := c.db.DeleteMismatchedEmbeddings(c.embeddingModel, want)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Dropped %d cached embeddings of %s: the embedder now returns %d dimensions, the cache held %v",
+		dropped, c.embeddingModel, want, dims)
+	return dropped, nil
+}
+
+// embed returns an embedding per article, of its text in texts. Cached
+// embeddings of an unchanged text are reused; the rest are embedded in one
+// call and cached.
+func (c *Clusterer) embed(ctx context.Context, articles []database.Article, texts []string) ([][]float64, error) {
+	if c.embedder == nil {
+		return nil, fmt.Errorf("%w: none configured", llm.ErrEmbedderUnavailable)
+	}
+	if c.embeddingModel == "" {
+		return c.embedTexts(ctx, texts)
+	}
+
+	ids := make([]int64, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+	cached, err := c.db.GetArticleEmbeddings(c.embeddingModel, ids)
+	if err != nil {
+		log.Printf("Error reading cached embeddings: %v", err)
+	}
+
+	embeddings := make([][]float64, len(articles))
+	hashes := make([]string, len(articles))
+	var missing []int
+	for i, a := range articles {
+		hashes[i] = textHash(texts[i])
+		if e, ok := cached[a.ID]; ok && e.TextHash == hashes[i] {
+			embeddings[i] = e.Vector
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) < len(articles) {
+		log.Printf("Reusing %d cached embeddings", len(articles)-len(missing))
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	missingTexts := make([]string, len(missing))
+	for j, i := range missing {
+		missingTexts[j] = texts[i]
+	}
+	vectors, err := c.embedTexts(ctx, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+	fresh := make([]database.ArticleEmbedding, len(missing))
+	for j, i := range missing {
+		embeddings[i] = vectors[j]
+		fresh[j] = database.ArticleEmbedding{
+			ArticleID: articles[i].ID,
+			Model:     c.embeddingModel,
+			TextHash:  hashes[i],
+			Vector:    vectors[j],
+		}
+	}
+	if err := c.db.PutArticleEmbeddings(fresh); err != nil {
+		log.Printf("Error caching embeddings: %v", err)
+	}
+	if err := sameDimensions(embeddings); err != nil {
+		return nil, fmt.Errorf("cached and new embeddings differ: %w", err)
+	}
+	return embeddings, nil
+}
+
+func (c *Clusterer) embedTexts(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings, err := c.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", llm.ErrEmbedderUnavailable, err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(embeddings), len(texts))
+	}
+	if err := sameDimensions(embeddings); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// sameDimensions checks that the embeddings are non-empty vectors of one
+// size, as the distance computation needs.
+func sameDimensions(embeddings [][]float64) error {
+	for _, e := range embeddings {
+		if len(e) == 0 || len(e) != len(embeddings[0]) {
+			return fmt.Errorf("embeddings of %d and %d dimensions", len(embeddings[0]), len(e))
+		}
+	}
+	return nil
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
 func (c *Clusterer) articleText(article database.Article) string {
 	parts := []string{article.Title}
 
@@ -166,39 +444,46 @@ func (c *Clusterer) clusterEmbeddings(embeddings [][]float64) []int {
 	return cutDendrogram(merges, len(embeddings), c.distanceThreshold)
 }
 
-func generateLabel(articles []database.Article) string {
-	stopWords := map[string]bool{
-		"the": true, "a": true, "an": true, "is": true, "are": true, "was": true,
-		"were": true, "be": true, "been": true, "being": true, "have": true, "has": true,
-		"had": true, "do": true, "does": true, "did": true, "will": true, "would": true,
-		"could": true, "should": true, "may": true, "might": true, "can": true, "shall": true,
-		"to": true, "of": true, "in": true, "for": true, "on": true, "with": true, "at": true,
-		"by": true, "from": true, "as": true, "into": true, "through": true, "during": true,
-		"before": true, "after": true, "above": true, "below": true, "and": true, "but": true,
-		"or": true, "nor": true, "not": true, "so": true, "yet": true, "both": true,
-		"either": true, "neither": true, "each": true, "every": true, "all": true, "any": true,
-		"few": true, "more": true, "most": true, "other": true, "some": true, "such": true,
-		"no": true, "only": true, "own": true, "same": true, "than": true, "too": true,
-		"very": true, "just": true, "how": true, "what": true, "which": true, "who": true,
-		"whom": true, "this": true, "that": true, "these": true, "those": true, "it": true,
-		"its": true, "new": true, "about": true, "up": true, "out": true, "one": true,
-		"two": true, "also": true, "like": true, "get": true, "use": true,
-	}
+// stopWords are skipped when building labels and TF-IDF fallback vectors.
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "is": true, "are": true, "was": true,
+	"were": true, "be": true, "been": true, "being": true, "have": true, "has": true,
+	"had": true, "do": true, "does": true, "did": true, "will": true, "would": true,
+	"could": true, "should": true, "may": true, "might": true, "can": true, "shall": true,
+	"to": true, "of": true, "in": true, "for": true, "on": true, "with": true, "at": true,
+	"by": true, "from": true, "as": true, "into": true, "through": true, "during": true,
+	"before": true, "after": true, "above": true, "below": true, "and": true, "but": true,
+	"or": true, "nor": true, "not": true, "so": true, "yet": true, "both": true,
+	"either": true, "neither": true, "each": true, "every": true, "all": true, "any": true,
+	"few": true, "more": true, "most": true, "other": true, "some": true, "such": true,
+	"no": true, "only": true, "own": true, "same": true, "than": true, "too": true,
+	"very": true, "just": true, "how": true, "what": true, "which": true, "who": true,
+	"whom": true, "this": true, "that": true, "these": true, "those": true, "it": true,
+	"its": true, "new": true, "about": true, "up": true, "out": true, "one": true,
+	"two": true, "also": true, "like": true, "get": true, "use": true,
+}
+
+// wordPunctuation is trimmed from words before they are counted.
+const wordPunctuation = ".,!?:;\"'()-[]"
 
+// generateLabel builds a label from the most frequent title words. If the
+// three-word label is already in use, further words are appended until it is
+// distinct; failing that, a numeric disambiguator is added.
+func generateLabel(articles []database.Article, used map[string]bool) string {
 	wordCounts := make(map[string]int)
 	for _, article := range articles {
 		words := strings.Fields(strings.ToLower(article.Title))
 		for _, word := range words {
-			word = strings.Trim(word, ".,!?:;\"'()-[]")
+			word = strings.Trim(word, wordPunctuation)
 			if len(word) > 2 && !stopWords[word] {
 				wordCounts[word]++
 			}
 		}
 	}
 
-	// Find top 3 words
+	// Rank up to 5 words; the first 3 form the default label
 	var topWords []string
-	for i := 0; i < 3; i++ {
+	for i := 0; i < 5; i++ {
 		maxCount := 0
 		maxWord := ""
 		for word, count := range wordCounts {
@@ -214,7 +499,13 @@ func generateLabel(articles []database.Article) string {
 	}
 
 	if len(topWords) > 0 {
-		return strings.Join(topWords, " ")
+		n := min(3, len(topWords))
+		label := strings.Join(topWords[:n], " ")
+		for used[database.FoldLabel(label)] && n < len(topWords) {
+			n++
+			label = strings.Join(topWords[:n], " ")
+		}
+		return database.UniqueLabel(label, used)
 	}
 
 	// Fallback: first article title truncated
@@ -222,5 +513,5 @@ func generateLabel(articles []database.Article) string {
 	if len(title) > 50 {
 		title = title[:50]
 	}
-	return title
+	return database.UniqueLabel(title, used)
 }
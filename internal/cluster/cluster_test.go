@@ -2,10 +2,16 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
 )
 
 // mockEmbedder implements llm.Embedder for testing.
@@ -17,6 +23,13 @@ func (m *mockEmbedder) Embed(_ context.Context, _ []string) ([][]float64, error)
 	return m.embeddings, nil
 }
 
+// failingEmbedder simulates an unreachable embedding service.
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(context.Context, []string) ([][]float64, error) {
+	return nil, errors.New("connection refused")
+}
+
 func openTestDB(t *testing.T) *database.DB {
 	t.Helper()
 	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
@@ -31,7 +44,7 @@ func ptr(s string) *string { return &s }
 
 func TestClusterNoArticles(t *testing.T) {
 	db := openTestDB(t)
-	clusterer := NewClusterer(db, nil, DefaultDistanceThreshold)
+	clusterer := NewClusterer(db, nil, DefaultDistanceThreshold, Options{})
 	result, err := clusterer.ClusterArticles(context.Background(), "2026-02-06")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -46,7 +59,7 @@ func TestClusterSingleArticleGoesToBrieflyNoted(t *testing.T) {
 	aid, _ := db.InsertArticle("https://a.com", "Solo Article", nil, nil, ptr("Content"), ptr("2026-02-06"))
 	db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
 
-	clusterer := NewClusterer(db, nil, DefaultDistanceThreshold)
+	clusterer := NewClusterer(db, nil, DefaultDistanceThreshold, Options{})
 	result, err := clusterer.ClusterArticles(context.Background(), "2026-02-06")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -84,7 +97,7 @@ func TestClusterSimilarArticlesGrouped(t *testing.T) {
 		{0.0, 0.0, 1.0},
 	}
 
-	clusterer := NewClusterer(db, &mockEmbedder{embeddings: embeddings}, 1.0)
+	clusterer := NewClusterer(db, &mockEmbedder{embeddings: embeddings}, 1.0, Options{})
 	result, err := clusterer.ClusterArticles(context.Background(), "2026-02-06")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -98,12 +111,116 @@ func TestClusterSimilarArticlesGrouped(t *testing.T) {
 	}
 }
 
+func TestClusterMinSizeAndSpotlight(t *testing.T) {
+	db := openTestDB(t)
+	for i, score := range []int{3, 3, 5, 2} {
+		aid, _ := db.InsertArticle("https://example.com/"+string(rune('a'+i)), "Article "+string(rune('A'+i)),
+			nil, nil, ptr("C"), ptr("2026-02-06"))
+		db.InsertTriage(aid, "relevant", nil, nil, nil, score)
+	}
+	// Articles come by score: the 5, the two 3s together, then the 2.
+	embeddings := [][]float64{
+		{0.0, 1.0, 0.0},
+		{1.0, 0.0, 0.0},
+		{0.95, 0.05, 0.0},
+		{0.0, 0.0, 1.0},
+	}
+
+	clusterer := NewClusterer(db, &mockEmbedder{embeddings: embeddings}, 1.0, Options{SpotlightScore: 4})
+	result, err := clusterer.ClusterArticles(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StorylineCount != 3 || result.SpotlightCount != 1 || result.BrieflyNotedCount != 1 {
+		t.Errorf("expected a pair, a spotlight and one briefly noted, got %+v", result)
+	}
+
+	clusterer = NewClusterer(db, &mockEmbedder{embeddings: embeddings}, 1.0, Options{MinClusterSize: 3})
+	if result, err = clusterer.ClusterArticles(context.Background(), "2026-02-06"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StorylineCount != 1 || result.BrieflyNotedCount != 4 {
+		t.Errorf("expected every article briefly noted below the minimum size, got %+v", result)
+	}
+}
+
+func TestAssignArticlesJoinsClosestStoryline(t *testing.T) {
+	db := openTestDB(t)
+	near, _ := db.InsertArticle("https://example.com/near", "AI testing, part three", nil, nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(near, "relevant", nil, nil, nil, 4)
+	far, _ := db.InsertArticle("https://example.com/far", "Cryptocurrency markets", nil, nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(far, "relevant", nil, nil, nil, 2)
+	var members []int64
+	for i := range 2 {
+		aid, _ := db.InsertArticle("https://example.com/testing-"+string(rune('0'+i)), "AI testing", nil, nil, ptr("C"), ptr("2026-02-06"))
+		db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
+		members = append(members, aid)
+	}
+	sid, _ := db.InsertStoryline("2026-02-06", "AI Testing", members)
+	db.InsertStorylineNarrative(sid, "2026-02-06", "AI Testing", "Narrative", nil)
+
+	// Pending articles come first, by score, then the storyline's members.
+	embeddings := [][]float64{
+		{1.0, 0.0, 0.0},
+		{0.0, 0.0, 1.0},
+		{0.95, 0.05, 0.0},
+		{0.9, 0.1, 0.0},
+	}
+	clusterer := NewClusterer(db, &mockEmbedder{embeddings: embeddings}, 1.0, Options{})
+	result, err := clusterer.AssignArticles(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Assigned != 1 || result.Unassigned != 1 || result.Storylines != 1 {
+		t.Errorf("expected 1 assigned to 1 storyline and 1 left, got %+v", result)
+	}
+
+	articles, _ := db.GetStorylineArticles(sid, "")
+	if len(articles) != 3 {
+		t.Errorf("expected the near article to join the storyline, got %d articles", len(articles))
+	}
+	left, _ := db.GetUnclusteredArticles("2026-02-06")
+	if len(left) != 1 || left[0].ID != far {
+		t.Errorf("expected only the far article to stay unclustered, got %v", left)
+	}
+	if n, _ := db.GetNarrativeForStoryline(sid); n == nil || !n.Stale {
+		t.Error("expected the storyline's narrative to be stale")
+	}
+}
+
+func TestClusterFallsBackToKeywords(t *testing.T) {
+	db := openTestDB(t)
+	titles := []string{
+		"Gemini release brings longer context windows",
+		"Gemini release: context windows grow again",
+		"Gemini release adds longer context windows for developers",
+		"Robotics startup raises funding round",
+	}
+	for i, title := range titles {
+		aid, _ := db.InsertArticle("https://example.com/"+string(rune('a'+i)), title, nil, nil, nil, ptr("2026-02-06"))
+		db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
+	}
+
+	clusterer := NewClusterer(db, &failingEmbedder{}, DefaultDistanceThreshold, Options{})
+	result, err := clusterer.ClusterArticles(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("expected keyword fallback instead of error: %v", err)
+	}
+	if !result.KeywordFallback {
+		t.Error("expected KeywordFallback to be reported")
+	}
+	if result.StorylineCount != 2 || result.BrieflyNotedCount != 1 {
+		t.Errorf("expected the Gemini storyline plus Briefly Noted, got %d storylines, %d briefly noted",
+			result.StorylineCount, result.BrieflyNotedCount)
+	}
+}
+
 func TestReClusteringClearsOldData(t *testing.T) {
 	db := openTestDB(t)
 	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("Content"), ptr("2026-02-06"))
 	db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
 
-	clusterer := NewClusterer(db, nil, DefaultDistanceThreshold)
+	clusterer := NewClusterer(db, nil, DefaultDistanceThreshold, Options{})
 	clusterer.ClusterArticles(context.Background(), "2026-02-06")
 
 	storylines, _ := db.GetStorylinesForPeriod("2026-02-06")
@@ -119,3 +236,126 @@ func TestReClusteringClearsOldData(t *testing.T) {
 		t.Errorf("expected 1 storyline after re-cluster, got %d", len(storylines))
 	}
 }
+
+func TestGenerateLabelAvoidsUsedLabels(t *testing.T) {
+	articles := []database.Article{
+		{Title: "Agents Agents Agents Agents Coding Coding Coding Testing Testing Review"},
+	}
+	used := map[string]bool{}
+
+	first := generateLabel(articles, used)
+	if first != "Agents Coding Testing" {
+		t.Fatalf("expected 'Agents Coding Testing', got %q", first)
+	}
+	used[strings.ToLower(first)] = true
+
+	second := generateLabel(articles, used)
+	if second == first {
+		t.Error("expected a distinct label when the first is taken")
+	}
+	if !strings.HasPrefix(second, first) {
+		t.Errorf("expected %q to extend %q", second, first)
+	}
+}
+
+func TestClusterReusesCachedEmbeddings(t *testing.T) {
+	db := openTestDB(t)
+	for i, title := range []string{"Agents ship", "Agents ship again", "Robots raise"} {
+		aid, _ := db.InsertArticle("https://example.com/"+string(rune('a'+i)), title, nil, nil, nil, ptr("2026-02-06"))
+		db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
+	}
+
+	var embedded [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		embedded = append(embedded, req.Input)
+		vectors := make([][]float64, len(req.Input))
+		for i, text := range req.Input {
+			vectors[i] = []float64{float64(len(text)), 1}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": vectors})
+	}))
+	defer srv.Close()
+
+	clusterer := NewClusterer(db, llm.NewOllamaEmbedder("test-embed", srv.URL), 1.0, Options{})
+	ctx := context.Background()
+	if _, err := clusterer.ClusterArticles(ctx, "2026-02-06"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clusterer.ClusterArticles(ctx, "2026-02-06"); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 1 || len(embedded[0]) != 3 {
+		t.Fatalf("expected one embedding call for 3 articles, got %v", embedded)
+	}
+
+	aid, _ := db.InsertArticle("https://example.com/d", "Robots raise more", nil, nil, nil, ptr("2026-02-06"))
+	db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
+	if _, err := clusterer.ClusterArticles(ctx, "2026-02-06"); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 2 || len(embedded[1]) != 1 || embedded[1][0] != "Robots raise more" {
+		t.Errorf("expected only the new article to be embedded, got %v", embedded)
+	}
+
+	db.UpdateArticleContent(aid, ptr("Fetched text"))
+	if _, err := clusterer.ClusterArticles(ctx, "2026-02-06"); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 3 || len(embedded[2]) != 1 || !strings.Contains(embedded[2][0], "Fetched text") {
+		t.Errorf("expected the article whose text changed to be embedded again, got %v", embedded)
+	}
+
+	other := NewClusterer(db, llm.NewOllamaEmbedder("other-embed", srv.URL), 1.0, Options{})
+	if _, err := other.ClusterArticles(ctx, "2026-02-06"); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 4 || len(embedded[3]) != 4 {
+		t.Errorf("expected another model to embed every article, got %v", embedded)
+	}
+}
+
+func TestCheckEmbeddingCacheDropsMismatchedVectors(t *testing.T) {
+	db := openTestDB(t)
+	a, _ := db.InsertArticle("https://example.com/a", "A", nil, nil, nil, ptr("2026-02-06"))
+	b, _ := db.InsertArticle("https://example.com/b", "B", nil, nil, nil, ptr("2026-02-06"))
+
+	dims := 2
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": [][]float64{make([]float64, dims)}})
+	}))
+	defer srv.Close()
+	clusterer := NewClusterer(db, llm.NewOllamaEmbedder("test-embed", srv.URL), 1.0, Options{})
+	ctx := context.Background()
+
+	if dropped, err := clusterer.CheckEmbeddingCache(ctx); err != nil || dropped != 0 || calls != 0 {
+		t.Fatalf("expected no check without cached vectors, got %d, %v after %d calls", dropped, err, calls)
+	}
+
+	model := llm.EmbedderName(llm.NewOllamaEmbedder("test-embed", srv.URL))
+	db.PutArticleEmbeddings([]database.ArticleEmbedding{
+		{ArticleID: a, Model: model, TextHash: "x", Vector: []float64{1, 0}},
+		{ArticleID: b, Model: model, TextHash: "y", Vector: []float64{1, 0, 0}},
+	})
+	dropped, err := clusterer.CheckEmbeddingCache(ctx)
+	if err != nil || dropped != 1 {
+		t.Fatalf("expected the 3-dimensional vector to be dropped, got %d, %v", dropped, err)
+	}
+	if cached, _ := db.GetArticleEmbeddings(model, []int64{a, b}); len(cached) != 1 || cached[a].Vector == nil {
+		t.Errorf("expected only the matching vector to stay cached, got %v", cached)
+	}
+
+	if dropped, _ := clusterer.CheckEmbeddingCache(ctx); dropped != 0 {
+		t.Errorf("expected a consistent cache to be kept, dropped %d", dropped)
+	}
+
+	dims = 3 // the model was replaced under the same name
+	if dropped, _ := clusterer.CheckEmbeddingCache(ctx); dropped != 1 {
+		t.Errorf("expected the old model's vector to be dropped, dropped %d", dropped)
+	}
+}
@@ -30,6 +30,17 @@ func pairwiseDistances(embeddings [][]float64) []float64 {
 	return dist
 }
 
+// wardDistance returns the merge distance wardLinkage reports for joining a
+// single point to a cluster of size points around centroid.
+func wardDistance(point, centroid []float64, size int) float64 {
+	var d float64
+	for k := range point {
+		diff := point[k] - centroid[k]
+		d += diff * diff
+	}
+	return math.Sqrt(2 * float64(size) / float64(size+1) * d)
+}
+
 // condensedIndex returns the index in the condensed distance array for pair (i, j) where i < j.
 func condensedIndex(n, i, j int) int {
 	if i > j {
@@ -0,0 +1,136 @@
+package collect
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/htmltext"
+	"golang.org/x/net/html"
+)
+
+// AggregatorType marks a feed as an aggregator: a link site such as a
+// lobste.rs tag or a curated newsletter whose entries point at articles
+// elsewhere. Its entries are stored under the articles they link to, with
+// the linked site as source, instead of under the aggregator page.
+const AggregatorType = "aggregator"
+
+// redirectParams are query parameters click-tracking links carry their
+// target in.
+var redirectParams = []string{"url", "u", "target", "redirect", "link", "dest"}
+
+// linkHosts are sites whose links in a newsletter are sharing buttons,
+// profiles or subscription management rather than articles.
+var linkHosts = []string{
+	"twitter.com", "x.com", "facebook.com", "linkedin.com", "reddit.com",
+	"bsky.app", "mastodon.social", "substack.com", "mailchimp.com", "list-manage.com",
+	"buttondown.email", "beehiiv.com",
+}
+
+// minLinkTitle is the shortest anchor text taken as an article title;
+// shorter ones are "comments", "link", "read more" and the like.
+const minLinkTitle = 12
+
+// IsAggregator reports whether the feed is configured as an aggregator.
+func (fc FeedConfig) IsAggregator() bool {
+	return fc.Type == AggregatorType
+}
+
+// resolveAggregated turns an aggregator entry into entries for the articles
+// it links to. An entry linking off-site, like a lobste.rs story, becomes
+// one entry for its target, through any click-tracking redirect. An entry
+// linking to the aggregator itself, like a newsletter issue, becomes one
+// entry per outbound link in its HTML; they take the issue's date and a
+// GUID of their own. An issue without outbound links is kept as it is.
+func resolveAggregated(entry FeedEntry, itemHTML, feedURL string) []FeedEntry {
+	home := siteHost(feedURL)
+	if target := unwrapRedirect(entry.URL); siteHost(target) != "" && siteHost(target) != home {
+		entry.URL = target
+		entry.Source = extractSourceName(target)
+		return []FeedEntry{entry}
+	}
+
+	var entries []FeedEntry
+	seen := make(map[string]bool)
+	for _, link := range outboundLinks(itemHTML, home) {
+		if seen[link.url] {
+			continue
+		}
+		seen[link.url] = true
+		e := entry
+		e.URL = link.url
+		e.Title = link.title
+		e.Content = ""
+		e.Source = extractSourceName(link.url)
+		if entry.GUID != "" {
+			e.GUID = entry.GUID + "#" + link.url
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return []FeedEntry{entry}
+	}
+	return entries
+}
+
+type outboundLink struct {
+	url, title string
+}
+
+// outboundLinks returns the links in fragment that lead off home to what
+// look like articles, with their anchor text as title.
+func outboundLinks(fragment, home string) []outboundLink {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return nil
+	}
+	var links []outboundLink
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				target := unwrapRedirect(strings.TrimSpace(attr.Val))
+				title := strings.Join(strings.Fields(htmltext.Text(n)), " ")
+				host := siteHost(target)
+				if host == "" || host == home || matchesHost(host, linkHosts) || len(title) < minLinkTitle {
+					continue
+				}
+				links = append(links, outboundLink{url: target, title: title})
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links
+}
+
+// unwrapRedirect returns the target of a click-tracking link, or rawURL
+// when it isn't one.
+func unwrapRedirect(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for _, p := range redirectParams {
+		if t := q.Get(p); strings.HasPrefix(t, "http://") || strings.HasPrefix(t, "https://") {
+			return t
+		}
+	}
+	return rawURL
+}
+
+// siteHost returns the host of an http(s) URL without "www.", or "" for
+// anything else.
+func siteHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,62 @@
+package collect
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+func TestReadAggregator(t *testing.T) {
+	client := fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(t, w, "aggregator_feed.xml", "application/rss+xml")
+	}))
+	entries, err := ReadFeed(client, "Test/1.0", config.Feed{URL: "https://lobste.rs/t/ai.rss", Name: "Lobsters", Type: AggregatorType}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct{ URL, Title, Source, GUID string }
+	var got []entry
+	for _, e := range entries {
+		got = append(got, entry{e.URL, e.Title, e.Source, e.GUID})
+		if e.PublishedDate != time.Now().UTC().Add(-2*time.Hour).Format("2006-01-02") {
+			t.Errorf("expected %s dated by its item, got %q", e.URL, e.PublishedDate)
+		}
+	}
+	want := []entry{
+		// A story linking off-site, under its target.
+		{"https://example.com/posts/agents", "Agents that write their own tests", "Example", "https://lobste.rs/s/abc123"},
+		// Through the click-tracking redirect.
+		{"https://evalsweekly.org/drift", "Why evals drift", "Evalsweekly", "https://lobste.rs/s/def456"},
+		// An issue, split into its outbound links: the duplicate, the links
+		// back to the aggregator, the short anchor, the social and mail links
+		// are left out.
+		{"https://paperswithnotes.net/long-context", "A paper on long context reasoning", "Paperswithnotes", "issue-12#https://paperswithnotes.net/long-context"},
+		{"https://toolsmith.dev/release-2", "Toolsmith 2 is out today", "Toolsmith", "issue-12#https://toolsmith.dev/release-2"},
+		// An issue without outbound links, kept as it is; the old story is
+		// outside the window.
+		{"https://lobste.rs/s/issue-11", "Issue 11", "Lobsters", "issue-11"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestUnwrapRedirect(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://news.example/click?url=https%3A%2F%2Fexample.com%2Fa", "https://example.com/a"},
+		{"https://t.example/r?id=7&dest=http://example.com/b", "http://example.com/b"},
+		{"https://example.com/search?link=not-a-url", "https://example.com/search?link=not-a-url"},
+		{"https://example.com/plain", "https://example.com/plain"},
+	}
+	for _, tt := range tests {
+		if got := unwrapRedirect(tt.url); got != tt.want {
+			t.Errorf("unwrapRedirect(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package collect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+const arxivBaseURL = "https://export.arxiv.org/api/query"
+
+// arxivVersion matches the version suffix of an arXiv abstract URL.
+var arxivVersion = regexp.MustCompile(`v\d+$`)
+
+// Paper represents a paper from the arXiv API.
+type Paper struct {
+	URL           string // abstract page, without version
+	Title         string
+	PublishedDate string
+	Abstract      string
+}
+
+// ArxivClient searches arXiv for recent papers in some categories.
+type ArxivClient struct {
+	categories []string
+	terms      []string
+	maxResults int
+	client     *http.Client
+	userAgent  string
+}
+
+// NewArxivClient creates an arXiv client for papers in any of categories
+// (e.g. "cs.AI") that mention any of terms; no terms means every paper in
+// the categories. Each search returns at most maxResults papers.
+func NewArxivClient(categories, terms []string, maxResults int) *ArxivClient {
+	return &ArxivClient{
+		categories: categories,
+		terms:      terms,
+		maxResults: max(maxResults, 1),
+		client:     &http.Client{Timeout: 30 * time.Second},
+		userAgent:  config.DefaultUserAgent,
+	}
+}
+
+// query builds the API search query for the categories, terms and dates.
+func (c *ArxivClient) query(dates DateRange) string {
+	var cats []string
+	for _, cat := range c.categories {
+		if cat = strings.TrimSpace(cat); cat != "" {
+			cats = append(cats, "cat:"+cat)
+		}
+	}
+	q := "(" + strings.Join(cats, " OR ") + ")"
+	var terms []string
+	for _, t := range c.terms {
+		if t = strings.TrimSpace(t); t != "" {
+			terms = append(terms, fmt.Sprintf("all:%q", t))
+		}
+	}
+	if len(terms) > 0 {
+		q += " AND (" + strings.Join(terms, " OR ") + ")"
+	}
+	from := strings.ReplaceAll(dates.From, "-", "")
+	to := strings.ReplaceAll(dates.To, "-", "")
+	return q + fmt.Sprintf(" AND submittedDate:[%s0000 TO %s2359]", from, to)
+}
+
+// Search returns the papers submitted within dates, newest first. Their
+// abstract is enough to triage them, so they are stored as content and the
+// fetch step leaves them alone.
+func (c *ArxivClient) Search(dates DateRange) []Paper {
+	if len(c.categories) == 0 {
+		log.Println("arXiv enabled but no categories configured, skipping")
+		return nil
+	}
+
+	params := url.Values{
+		"search_query": {c.query(dates)},
+		"sortBy":       {"submittedDate"},
+		"sortOrder":    {"descending"},
+		"max_results":  {fmt.Sprintf("%d", c.maxResults)},
+	}
+	req, err := http.NewRequest("GET", arxivBaseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		log.Printf("arXiv request error: %v", err)
+		return nil
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Printf("arXiv error: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("arXiv HTTP error: %d", resp.StatusCode)
+		return nil
+	}
+
+	var feed struct {
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Summary   string `xml:"summary"`
+			Published string `xml:"published"`
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		log.Printf("arXiv decode error: %v", err)
+		return nil
+	}
+
+	var papers []Paper
+	for _, e := range feed.Entries {
+		// The API answers a bad query with a single error entry.
+		_, id, ok := strings.Cut(e.ID, "/abs/")
+		if !ok {
+			continue
+		}
+		title := strings.Join(strings.Fields(e.Title), " ")
+		if title == "" {
+			continue
+		}
+		var pubDate string
+		if t, err := time.Parse(time.RFC3339, e.Published); err == nil {
+			pubDate = t.Format("2006-01-02")
+		}
+		papers = append(papers, Paper{
+			URL:           "https://arxiv.org/abs/" + arxivVersion.ReplaceAllString(id, ""),
+			Title:         title,
+			PublishedDate: pubDate,
+			Abstract:      strings.Join(strings.Fields(e.Summary), " "),
+		})
+	}
+
+	log.Printf("Fetched %d papers from arXiv", len(papers))
+	return papers
+}
@@ -0,0 +1,73 @@
+package collect
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestArxivSearch(t *testing.T) {
+	var query map[string][]string
+	c := NewArxivClient([]string{"cs.AI", " cs.SE "}, []string{"agents", " "}, 25)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "export.arxiv.org" || r.URL.Path != "/api/query" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		serveFixture(t, w, "arxiv_query.xml", "application/atom+xml")
+	}))
+
+	papers := c.Search(DateRange{From: "2026-02-04", To: "2026-02-06"})
+	want := []Paper{
+		// Without the version and with the whitespace of the feed collapsed.
+		{URL: "https://arxiv.org/abs/2602.01234", Title: "Tool Use at Scale: Agents That Call Thousands of APIs", PublishedDate: "2026-02-05",
+			Abstract: "We study agents that call thousands of APIs. Our results show that retrieval helps."},
+		// An unreadable date leaves it empty; the untitled entry is left out.
+		{URL: "https://arxiv.org/abs/2602.00042", Title: "Evaluating Code Models", Abstract: "A benchmark."},
+	}
+	if !reflect.DeepEqual(papers, want) {
+		t.Errorf("expected %+v, got %+v", want, papers)
+	}
+
+	wantQuery := map[string][]string{
+		"search_query": {`(cat:cs.AI OR cat:cs.SE) AND (all:"agents") AND submittedDate:[202602040000 TO 202602062359]`},
+		"sortBy":       {"submittedDate"},
+		"sortOrder":    {"descending"},
+		"max_results":  {"25"},
+	}
+	if !reflect.DeepEqual(query, wantQuery) {
+		t.Errorf("expected query %v, got %v", wantQuery, query)
+	}
+}
+
+func TestArxivErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"HTTP error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		}},
+		{"error entry", func(w http.ResponseWriter, r *http.Request) {
+			serveFixture(t, w, "arxiv_error.xml", "application/atom+xml")
+		}},
+		{"not a feed", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Rate exceeded."))
+		}},
+	}
+	for _, tt := range tests {
+		c := NewArxivClient([]string{"cs.AI"}, nil, 0)
+		c.client = fixtureClient(t, tt.handler)
+		if papers := c.Search(DateRange{From: "2026-02-06", To: "2026-02-06"}); papers != nil {
+			t.Errorf("%s: expected no papers, got %+v", tt.name, papers)
+		}
+	}
+
+	requested := false
+	c := NewArxivClient(nil, []string{"agents"}, 10)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requested = true }))
+	if papers := c.Search(DateRange{From: "2026-02-06", To: "2026-02-06"}); papers != nil || requested {
+		t.Errorf("expected no search without categories, got %+v", papers)
+	}
+}
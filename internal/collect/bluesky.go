@@ -0,0 +1,202 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+// blueskyBaseURL is Bluesky's public AppView, which serves feeds and author
+// timelines without an account.
+const blueskyBaseURL = "https://public.api.bsky.app/xrpc"
+
+// BlueskyClient fetches public posts of Bluesky accounts and feeds.
+type BlueskyClient struct {
+	client    *http.Client
+	userAgent string
+}
+
+// NewBlueskyClient creates a Bluesky client.
+func NewBlueskyClient() *BlueskyClient {
+	return &BlueskyClient{client: &http.Client{Timeout: 30 * time.Second}, userAgent: config.DefaultUserAgent}
+}
+
+type blueskyFeed struct {
+	Feed []struct {
+		Post struct {
+			URI    string `json:"uri"`
+			Author struct {
+				DID    string `json:"did"`
+				Handle string `json:"handle"`
+			} `json:"author"`
+			Record struct {
+				Text      string `json:"text"`
+				CreatedAt string `json:"createdAt"`
+				Reply     *struct {
+					Parent struct {
+						URI string `json:"uri"`
+					} `json:"parent"`
+				} `json:"reply"`
+				Facets []struct {
+					Features []struct {
+						Type string `json:"$type"`
+						URI  string `json:"uri"`
+					} `json:"features"`
+				} `json:"facets"`
+			} `json:"record"`
+			Embed *struct {
+				External *struct {
+					URI   string `json:"uri"`
+					Title string `json:"title"`
+				} `json:"external"`
+			} `json:"embed"`
+		} `json:"post"`
+		Reason json.RawMessage `json:"reason"`
+	} `json:"feed"`
+}
+
+// get decodes the JSON response to a GET of the XRPC method into v.
+func (c *BlueskyClient) get(method string, params url.Values, v any) error {
+	req, err := http.NewRequest("GET", blueskyBaseURL+"/"+method+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AccountPosts returns the threads the account (a handle such as
+// "simonwillison.net") started in the last daysBack days. Reposts are
+// skipped.
+func (c *BlueskyClient) AccountPosts(handle string, daysBack int) []SocialPost {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	if handle == "" {
+		return nil
+	}
+	name := "@" + handle
+
+	var feed blueskyFeed
+	params := url.Values{"actor": {handle}, "limit": {"100"}, "filter": {"posts_and_author_threads"}}
+	if err := c.get("app.bsky.feed.getAuthorFeed", params, &feed); err != nil {
+		log.Printf("Bluesky error for %s: %v", name, err)
+		return nil
+	}
+
+	posts := socialPosts(blueskyStatuses(feed), name, daysBack)
+	log.Printf("Fetched %d threads from %s", len(posts), name)
+	return posts
+}
+
+// FeedPosts returns the threads of a feed started in the last daysBack
+// days. The feed is its at:// URI or its page on bsky.app
+// (https://bsky.app/profile/<handle>/feed/<name>).
+func (c *BlueskyClient) FeedPosts(feedRef string, daysBack int) []SocialPost {
+	uri, err := c.feedURI(feedRef)
+	if err != nil {
+		log.Printf("Invalid Bluesky feed %q: %v, skipping", feedRef, err)
+		return nil
+	}
+	name := "Bluesky feed " + uri[strings.LastIndex(uri, "/")+1:]
+
+	var feed blueskyFeed
+	if err := c.get("app.bsky.feed.getFeed", url.Values{"feed": {uri}, "limit": {"100"}}, &feed); err != nil {
+		log.Printf("Bluesky error for %s: %v", name, err)
+		return nil
+	}
+
+	posts := socialPosts(blueskyStatuses(feed), name, daysBack)
+	log.Printf("Fetched %d threads from %s", len(posts), name)
+	return posts
+}
+
+// feedURI returns the at:// URI of a feed generator, resolving the handle
+// of a bsky.app feed page to the DID the API wants.
+func (c *BlueskyClient) feedURI(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "at://") {
+		return ref, nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if siteHost(ref) != "bsky.app" || len(parts) != 4 || parts[0] != "profile" || parts[2] != "feed" {
+		return "", fmt.Errorf("want an at:// URI or https://bsky.app/profile/<handle>/feed/<name>")
+	}
+	did := parts[1]
+	if !strings.HasPrefix(did, "did:") {
+		var resolved struct {
+			DID string `json:"did"`
+		}
+		if err := c.get("com.atproto.identity.resolveHandle", url.Values{"handle": {did}}, &resolved); err != nil {
+			return "", fmt.Errorf("resolving %s: %w", did, err)
+		}
+		did = resolved.DID
+	}
+	return "at://" + did + "/app.bsky.feed.generator/" + parts[3], nil
+}
+
+// blueskyStatuses converts the posts of a feed, leaving out reposts. The
+// link card, when there is one, is the post's first link.
+func blueskyStatuses(feed blueskyFeed) []socialStatus {
+	var out []socialStatus
+	for _, item := range feed.Feed {
+		if len(item.Reason) > 0 && string(item.Reason) != "null" {
+			continue
+		}
+		p := item.Post
+		created, err := time.Parse(time.RFC3339, p.Record.CreatedAt)
+		if err != nil {
+			continue
+		}
+		s := socialStatus{
+			id:      p.URI,
+			author:  p.Author.DID,
+			url:     blueskyPostURL(p.URI, p.Author.Handle),
+			text:    strings.TrimSpace(p.Record.Text),
+			created: created,
+		}
+		if p.Record.Reply != nil {
+			s.parentID = p.Record.Reply.Parent.URI
+		}
+		if p.Embed != nil && p.Embed.External != nil && outboundURL(p.Embed.External.URI, "bsky.app") {
+			s.links = append(s.links, p.Embed.External.URI)
+			s.cardTitle = strings.TrimSpace(p.Embed.External.Title)
+		}
+		for _, facet := range p.Record.Facets {
+			for _, f := range facet.Features {
+				if f.Type == "app.bsky.richtext.facet#link" && outboundURL(f.URI, "bsky.app") {
+					s.links = append(s.links, f.URI)
+				}
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// blueskyPostURL returns the bsky.app page of the post with the at:// URI.
+func blueskyPostURL(uri, handle string) string {
+	rkey := uri[strings.LastIndex(uri, "/")+1:]
+	if handle == "" || rkey == "" {
+		return ""
+	}
+	return "https://bsky.app/profile/" + handle + "/post/" + rkey
+}
@@ -0,0 +1,139 @@
+package collect
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+const braveNewsURL = "https://api.search.brave.com/res/v1/news/search"
+
+// BraveSource is the source name of Brave results without a site name.
+const BraveSource = "Brave Search"
+
+// braveMaxCount is the most results Brave returns for one news search.
+const braveMaxCount = 50
+
+// braveInterval spaces out requests; the free plan allows one per second.
+const braveInterval = 1100 * time.Millisecond
+
+// BraveClient searches news through the Brave Search API, an alternative to
+// NewsAPI with a free plan of 2,000 requests a month.
+type BraveClient struct {
+	apiKey    string
+	client    *http.Client
+	userAgent string
+	last      time.Time // of the previous request
+}
+
+// NewBraveClient creates a Brave Search client with the key in apiKeyEnv.
+func NewBraveClient(apiKeyEnv string) *BraveClient {
+	return &BraveClient{
+		apiKey:    os.Getenv(apiKeyEnv),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		userAgent: config.DefaultUserAgent,
+	}
+}
+
+// Name returns the name of the news API.
+func (c *BraveClient) Name() string { return BraveSource }
+
+// IsConfigured returns whether the API key is available.
+func (c *BraveClient) IsConfigured() bool {
+	return c.apiKey != ""
+}
+
+// Search searches news articles published within dates matching a query.
+// Brave returns at most 50 results per search.
+func (c *BraveClient) Search(query string, dates DateRange, pageSize int) []NewsArticle {
+	if c.apiKey == "" {
+		log.Println("Brave Search not configured, skipping search")
+		return nil
+	}
+
+	params := url.Values{
+		"q":           {query},
+		"count":       {fmt.Sprintf("%d", min(pageSize, braveMaxCount))},
+		"freshness":   {dates.From + "to" + dates.To},
+		"search_lang": {"en"},
+	}
+	req, err := http.NewRequest("GET", braveNewsURL+"?"+params.Encode(), nil)
+	if err != nil {
+		log.Printf("Brave Search request error: %v", err)
+		return nil
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", c.apiKey)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if wait := braveInterval - time.Since(c.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	c.last = time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Printf("Brave Search error: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Brave Search HTTP error: %d", resp.StatusCode)
+		return nil
+	}
+
+	var result struct {
+		Results []struct {
+			URL         string `json:"url"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			PageAge     string `json:"page_age"`
+			MetaURL     struct {
+				Hostname string `json:"hostname"`
+			} `json:"meta_url"`
+			Profile struct {
+				Name string `json:"name"`
+			} `json:"profile"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.Printf("Brave Search decode error: %v", err)
+		return nil
+	}
+
+	var articles []NewsArticle
+	for _, a := range result.Results {
+		title := strings.TrimSpace(stripHTML(a.Title))
+		if a.URL == "" || title == "" {
+			continue
+		}
+
+		// page_age is an ISO timestamp without a zone, e.g. 2026-02-06T09:30:00.
+		var pubDate string
+		if len(a.PageAge) >= 10 {
+			if _, err := time.Parse("2006-01-02", a.PageAge[:10]); err == nil {
+				pubDate = a.PageAge[:10]
+			}
+		}
+
+		source := cmp.Or(a.Profile.Name, strings.TrimPrefix(a.MetaURL.Hostname, "www."), BraveSource)
+		articles = append(articles, NewsArticle{
+			URL:           a.URL,
+			Title:         title,
+			PublishedDate: pubDate,
+			Content:       strings.TrimSpace(stripHTML(a.Description)),
+			Source:        source,
+		})
+	}
+
+	log.Printf("Fetched %d articles from Brave Search for query: %s", len(articles), query)
+	return articles
+}
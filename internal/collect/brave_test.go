@@ -0,0 +1,68 @@
+package collect
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBraveSearch(t *testing.T) {
+	var header http.Header
+	var query map[string][]string
+	t.Setenv("TEST_BRAVE_KEY", "secret")
+	c := NewBraveClient("TEST_BRAVE_KEY")
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.search.brave.com" || r.URL.Path != "/res/v1/news/search" {
+			http.NotFound(w, r)
+			return
+		}
+		header, query = r.Header, r.URL.Query()
+		serveFixture(t, w, "brave_news.json", "application/json")
+	}))
+
+	articles := c.Search("coding agents", DateRange{From: "2026-02-05", To: "2026-02-06"}, 100)
+	want := []NewsArticle{
+		{URL: "https://news.example.com/2026/02/agent", Title: "A new coding agent & its benchmarks", PublishedDate: "2026-02-06",
+			Content: "The agent beats the baseline.", Source: "Example News"},
+		// Without a profile, the site's host names the source.
+		{URL: "https://www.blog.example.org/agents", Title: "Agents in the enterprise", Source: "blog.example.org"},
+		{URL: "https://example.net/untraced", Title: "Untraced", PublishedDate: "2026-02-05", Content: "No site details.", Source: BraveSource},
+		// The result without a title is left out.
+	}
+	if !reflect.DeepEqual(articles, want) {
+		t.Errorf("expected %+v, got %+v", want, articles)
+	}
+	if got := header.Get("X-Subscription-Token"); got != "secret" {
+		t.Errorf("expected the API key sent, got %q", got)
+	}
+	wantQuery := map[string][]string{
+		"q":           {"coding agents"},
+		"count":       {"50"}, // Brave's most, though 100 were asked for
+		"freshness":   {"2026-02-05to2026-02-06"},
+		"search_lang": {"en"},
+	}
+	if !reflect.DeepEqual(query, wantQuery) {
+		t.Errorf("expected query %v, got %v", wantQuery, query)
+	}
+}
+
+func TestBraveErrors(t *testing.T) {
+	t.Setenv("TEST_BRAVE_KEY", "secret")
+	c := NewBraveClient("TEST_BRAVE_KEY")
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type": "ErrorResponse"}`, http.StatusUnauthorized)
+	}))
+	if articles := c.Search("agents", DateRange{From: "2026-02-06", To: "2026-02-06"}, 20); articles != nil {
+		t.Errorf("expected no articles on a 401, got %+v", articles)
+	}
+
+	requested := false
+	c = NewBraveClient("TEST_BRAVE_KEY_UNSET")
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requested = true }))
+	if c.IsConfigured() {
+		t.Error("expected a client without a key not configured")
+	}
+	if articles := c.Search("agents", DateRange{From: "2026-02-06", To: "2026-02-06"}, 20); articles != nil || requested {
+		t.Errorf("expected no search without a key, got %+v", articles)
+	}
+}
@@ -1,7 +1,10 @@
 package collect
 
 import (
+	"cmp"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/TobiSchelling/AICrawler/internal/config"
 	"github.com/TobiSchelling/AICrawler/internal/database"
@@ -12,101 +15,451 @@ type Result struct {
 	TotalFound  int
 	NewArticles int
 	Duplicates  int
+	Filtered    int // dropped by the keyword filters
+	Updated     int // articles collected before whose feed entry was updated, queued to be fetched again
 	Sources     map[string]int
+	ArticleIDs  []int64 // new and adopted articles
 }
 
-// Collector orchestrates article collection from RSS feeds and NewsAPI.
+// Collector orchestrates article collection from RSS feeds and news APIs.
 type Collector struct {
 	db         *database.DB
+	feeds      []FeedConfig
 	feedParser *FeedParser
-	newsClient *NewsAPIClient
-	newsQuery  string
+	news       []newsSearch
+	filters    sourceFilters
+	reddit     *RedditClient
+	subreddits []string
+	arxiv      *ArxivClient
+	github     *GitHubClient
+	repos      []string
+	topics     []string
+	mastodon   *MastodonClient
+	accounts   []string
+	hashtags   []string
+	bluesky    *BlueskyClient
+	handles    []string
+	skyFeeds   []string
 	daysBack   int
+	date       string // set by NewCollectorForDate
+
+	// refetchDays is how many days after they were collected articles are
+	// fetched again when their feed entry is updated; 0 fetches none again.
+	refetchDays int
+}
+
+// newsSearch is a news API with the query it is searched with.
+type newsSearch struct {
+	client NewsSearcher
+	query  string
+	filter keywordFilter
+}
+
+// sourceFilters are the keyword filters of each source, the global filter
+// combined with the source's own.
+type sourceFilters struct {
+	feeds                                    map[string]keywordFilter // by feed URL
+	reddit, arxiv, github, mastodon, bluesky keywordFilter
 }
 
+// defaultNewsQuery is searched by a news API configured without a query.
+const defaultNewsQuery = "artificial intelligence software development"
+
 // NewCollector creates a new article collector.
 func NewCollector(cfg *config.Config, db *database.DB, daysBack int) *Collector {
 	c := &Collector{
 		db:       db,
 		daysBack: daysBack,
 	}
+	if r := cfg.Fetch.Refetch; r.Enabled {
+		c.refetchDays = r.Days
+	}
+	// Every feed and source API request shares one timeout, the proxy and
+	// the User-Agent.
+	client := &http.Client{
+		Transport: cfg.Network.Transport(),
+		Timeout:   cmp.Or(cfg.Performance.HTTP.SourceTimeout(), 30*time.Second),
+	}
+	userAgent := cfg.Fetch.Agent()
 
-	// Set up feed parser
-	if len(cfg.Sources.Feeds) > 0 {
-		feeds := make([]FeedConfig, len(cfg.Sources.Feeds))
-		for i, f := range cfg.Sources.Feeds {
-			feeds[i] = FeedConfig{URL: f.URL, Name: f.Name}
+	// Set up feed parser; policy tracking brings its own feed bundle.
+	// Disabled feeds are left out.
+	sources := cfg.Sources.Feeds
+	if cfg.Policy.Enabled {
+		sources = append(append([]config.Feed(nil), sources...), cfg.Policy.Feeds...)
+	}
+	global := cfg.Sources.Filter
+	c.filters = sourceFilters{
+		feeds:    make(map[string]keywordFilter),
+		reddit:   newKeywordFilter(global, cfg.Sources.Reddit.Filter),
+		arxiv:    newKeywordFilter(global, cfg.Sources.Arxiv.Filter),
+		github:   newKeywordFilter(global, cfg.Sources.GitHub.Filter),
+		mastodon: newKeywordFilter(global, cfg.Sources.Mastodon.Filter),
+		bluesky:  newKeywordFilter(global, cfg.Sources.Bluesky.Filter),
+	}
+	var feeds []FeedConfig
+	for _, f := range sources {
+		if !f.Disabled {
+			c.filters.feeds[f.URL] = newKeywordFilter(global, f.Filter)
+			feeds = append(feeds, feedConfig(f))
 		}
+	}
+	if len(feeds) > 0 {
+		c.feeds = feeds
 		c.feedParser = NewFeedParser(feeds)
+		c.feedParser.client = client
+		c.feedParser.agent = userAgent
+		c.feedParser.workers = cfg.Performance.FeedWorkers
+		c.feedParser.perHost = cfg.Performance.FeedsPerHost
+		c.feedParser.updateDays = c.refetchDays
 	}
 
-	// Set up NewsAPI client
-	apiCfg := cfg.Sources.APIs.NewsAPI
-	if apiCfg.Enabled {
-		c.newsClient = NewNewsAPIClient(apiCfg.APIKeyEnv)
-		c.newsQuery = apiCfg.Query
-		if c.newsQuery == "" {
-			c.newsQuery = "artificial intelligence software development"
-		}
+	// Set up Reddit client
+	if len(cfg.Sources.Reddit.Subreddits) > 0 {
+		c.reddit = NewRedditClient(cfg.Sources.Reddit.MinUpvotes)
+		c.reddit.client = client
+		c.reddit.userAgent = userAgent
+		c.subreddits = cfg.Sources.Reddit.Subreddits
+	}
+
+	// Set up arXiv client
+	if ax := cfg.Sources.Arxiv; ax.Enabled {
+		c.arxiv = NewArxivClient(ax.Categories, ax.Terms, ax.MaxResults)
+		c.arxiv.client = client
+		c.arxiv.userAgent = userAgent
+	}
+
+	// Set up GitHub client
+	if gh := cfg.Sources.GitHub; len(gh.Repos) > 0 || len(gh.Topics) > 0 {
+		c.github = NewGitHubClient(gh.TokenEnv, gh.IncludePrereleases, gh.MinStars)
+		c.github.client = client
+		c.github.userAgent = userAgent
+		c.repos = gh.Repos
+		c.topics = gh.Topics
+	}
+
+	// Set up Mastodon and Bluesky clients
+	if m := cfg.Sources.Mastodon; len(m.Accounts) > 0 || len(m.Hashtags) > 0 {
+		c.mastodon = NewMastodonClient(m.Instance)
+		c.mastodon.client = client
+		c.mastodon.userAgent = userAgent
+		c.accounts = m.Accounts
+		c.hashtags = m.Hashtags
+	}
+	if b := cfg.Sources.Bluesky; len(b.Accounts) > 0 || len(b.Feeds) > 0 {
+		c.bluesky = NewBlueskyClient()
+		c.bluesky.client = client
+		c.bluesky.userAgent = userAgent
+		c.handles = b.Accounts
+		c.skyFeeds = b.Feeds
+	}
+
+	// Set up the news API clients
+	if apiCfg := cfg.Sources.APIs.NewsAPI; apiCfg.Enabled {
+		news := NewNewsAPIClient(apiCfg.APIKeyEnv)
+		news.client = client
+		news.userAgent = userAgent
+		c.news = append(c.news, newsSearch{news, cmp.Or(apiCfg.Query, defaultNewsQuery), newKeywordFilter(global, apiCfg.Filter)})
+	}
+	if apiCfg := cfg.Sources.APIs.Brave; apiCfg.Enabled {
+		brave := NewBraveClient(apiCfg.APIKeyEnv)
+		brave.client = client
+		brave.userAgent = userAgent
+		c.news = append(c.news, newsSearch{brave, cmp.Or(apiCfg.Query, defaultNewsQuery), newKeywordFilter(global, apiCfg.Filter)})
+	}
+	if apiCfg := cfg.Sources.APIs.GDELT; apiCfg.Enabled {
+		gdelt := NewGDELTClient()
+		gdelt.client = client
+		gdelt.userAgent = userAgent
+		c.news = append(c.news, newsSearch{gdelt, cmp.Or(apiCfg.Query, defaultNewsQuery), newKeywordFilter(global, apiCfg.Filter)})
 	}
 
 	return c
 }
 
+// NewCollectorForDate creates a collector for a past day. Only sources that
+// can be searched by publication date are collected, which are the news
+// APIs and arXiv: feeds, subreddit listings, GitHub releases and social timelines
+// only reach back from now, and pending articles (polled or pushed through
+// the ingest API) are left for the next regular run.
+func NewCollectorForDate(cfg *config.Config, db *database.DB, date string) *Collector {
+	c := NewCollector(cfg, db, 1)
+	c.date = date
+	c.feedParser = nil
+	c.reddit = nil
+	c.github = nil
+	c.mastodon = nil
+	c.bluesky = nil
+	return c
+}
+
 // Collect collects articles from all configured sources.
 func (c *Collector) Collect(periodID string) *Result {
 	r := &Result{Sources: make(map[string]int)}
 
+	// Adopt articles polled or pushed through the ingest API since the last run
+	if c.date == "" {
+		c.adoptPending(periodID, r)
+	}
+
+	c.typeFeeds()
+
 	// Collect from RSS feeds
-	if c.feedParser != nil {
-		log.Println("Collecting from RSS feeds...")
-		entries := c.feedParser.ParseAll(c.daysBack)
-		r.TotalFound += len(entries)
+	c.collectFeeds(&periodID, r)
 
-		for _, entry := range entries {
-			var source, pubDate, content *string
-			if entry.Source != "" {
-				source = &entry.Source
+	// Collect from subreddits, arXiv, GitHub, Mastodon and Bluesky
+	c.collectReddit(periodID, r)
+	c.collectArxiv(periodID, r)
+	c.collectGitHub(periodID, r)
+	c.collectSocial(periodID, r)
+
+	// Collect from the news APIs
+	c.collectNews(periodID, r)
+
+	c.typeRemainingSources()
+
+	log.Printf("Collection complete: %d found, %d new, %d duplicates, %d filtered", r.TotalFound, r.NewArticles, r.Duplicates, r.Filtered)
+	return r
+}
+
+// PollFeeds collects feed entries without a period, into the pool of
+// pending articles that the next pipeline run adopts. Polling often keeps
+// busy feeds from dropping entries before the daily run sees them. NewsAPI
+// is left to the run: it can search back by date, and polling it would use
+// up the request quota.
+func (c *Collector) PollFeeds() *Result {
+	r := &Result{Sources: make(map[string]int)}
+	c.typeFeeds()
+	c.collectFeeds(nil, r)
+	log.Printf("Poll complete: %d found, %d new, %d duplicates, %d filtered", r.TotalFound, r.NewArticles, r.Duplicates, r.Filtered)
+	return r
+}
+
+// adoptPending assigns articles that arrived without a period, from feed
+// polls or the ingest API, to periodID.
+func (c *Collector) adoptPending(periodID string, r *Result) {
+	ids, err := c.db.AssignPendingArticles(periodID)
+	if err != nil {
+		log.Printf("Error adopting pending articles: %v", err)
+		return
+	}
+	r.ArticleIDs = append(r.ArticleIDs, ids...)
+	if n := len(ids); n > 0 {
+		log.Printf("Adopted %d pending articles", n)
+		r.TotalFound += n
+		r.NewArticles += n
+		r.Sources["Polled or ingested earlier"] += n
+	}
+}
+
+// typeFeeds records the type and weight of every configured feed. Feeds
+// take them from the config (or their URL) on every run, so editing a
+// feed's type or weight takes effect immediately. Aggregators aren't sources themselves: their
+// entries are stored under the sites they link to, which are typed like
+// ingested articles' sources.
+func (c *Collector) typeFeeds() {
+	for _, fc := range c.feeds {
+		if fc.IsAggregator() {
+			continue
+		}
+		if err := c.db.SetSourceType(fc.SourceName(), fc.SourceType()); err != nil {
+			log.Printf("Error recording type of %s: %v", fc.SourceName(), err)
+		}
+		if err := c.db.SetSourceWeight(fc.SourceName(), fc.Weight); err != nil {
+			log.Printf("Error recording weight of %s: %v", fc.SourceName(), err)
+		}
+	}
+}
+
+// collectFeeds stores the feeds' entries within the lookback window under
+// periodID, or as pending when it is nil.
+func (c *Collector) collectFeeds(periodID *string, r *Result) {
+	if c.feedParser == nil {
+		return
+	}
+	log.Println("Collecting from RSS feeds...")
+	entries, failed := c.feedParser.ParseAll(c.daysBack)
+	entries = c.newSitemapPages(entries)
+	c.recordFeedHealth(failed)
+
+	for _, entry := range entries {
+		if entry.UpdateOnly {
+			c.checkUpdated(entry, r)
+			continue
+		}
+		r.TotalFound++
+		if !c.filters.feeds[entry.FeedURL].allows(entry.Title, entry.Content) {
+			// Remember its GUID, so a sitemap doesn't offer a filtered page
+			// as new again.
+			if entry.GUID != "" {
+				c.db.AddFeedItem(entry.FeedURL, entry.GUID, 0)
+			}
+			r.Filtered++
+			continue
+		}
+		// Feeds that rotate tracking parameters or republish an entry under
+		// a new URL keep its GUID, so check that first.
+		if entry.GUID != "" {
+			if seen, _ := c.db.HasFeedItem(entry.FeedURL, entry.GUID); seen {
+				c.checkUpdated(entry, r)
+				r.Duplicates++
+				continue
 			}
-			if entry.PublishedDate != "" {
-				pubDate = &entry.PublishedDate
+		}
+
+		var source, pubDate, content *string
+		if entry.Source != "" {
+			source = &entry.Source
+		}
+		if entry.PublishedDate != "" {
+			pubDate = &entry.PublishedDate
+		}
+		if entry.Content != "" {
+			content = &entry.Content
+		}
+
+		id, _ := c.db.InsertArticle(entry.URL, entry.Title, source, pubDate, content, periodID)
+		if entry.GUID != "" {
+			if err := c.db.AddFeedItem(entry.FeedURL, entry.GUID, id); err != nil {
+				log.Printf("Error recording feed item %s: %v", entry.GUID, err)
 			}
-			if entry.Content != "" {
-				content = &entry.Content
+			c.checkUpdated(entry, r)
+		}
+		if id > 0 {
+			r.NewArticles++
+			r.ArticleIDs = append(r.ArticleIDs, id)
+			r.Sources[entry.Source]++
+			if entry.Category != "" || entry.Weight != 1 {
+				var category *string
+				if entry.Category != "" {
+					category = &entry.Category
+				}
+				if err := c.db.SetArticleFeed(id, category, entry.Weight); err != nil {
+					log.Printf("Error recording feed of %s: %v", entry.URL, err)
+				}
+			}
+		} else {
+			r.Duplicates++
+		}
+	}
+}
+
+// checkUpdated records when a feed entry was last updated and, when that is
+// later than at the last collection, queues the article collected from it
+// to be fetched again, if it was collected in the last refetchDays. The
+// time is recorded even when nothing is fetched again, so updates are told
+// from it once fetch.refetch is enabled.
+func (c *Collector) checkUpdated(entry FeedEntry, r *Result) {
+	if entry.GUID == "" || entry.Updated == "" {
+		return
+	}
+	articleID, updated, err := c.db.UpdateFeedItem(entry.FeedURL, entry.GUID, entry.Updated)
+	if err != nil {
+		log.Printf("Error recording the update of feed item %s: %v", entry.GUID, err)
+		return
+	}
+	if !updated || articleID == 0 || c.refetchDays == 0 {
+		return
+	}
+	queued, err := c.db.QueueArticleRefetch(articleID, c.refetchDays)
+	if err != nil {
+		log.Printf("Error queueing %s to fetch again: %v", entry.URL, err)
+	} else if queued {
+		r.Updated++
+		log.Printf("Updated since it was collected, to fetch again: %s", entry.URL)
+	}
+}
+
+// recordFeedHealth notes for every feed whether fetching it failed, so the
+// healthcheck can tell feeds that keep failing from a one-off error.
+func (c *Collector) recordFeedHealth(failed map[string]error) {
+	for _, fc := range c.feeds {
+		var err error
+		if reason, ok := failed[fc.URL]; ok {
+			err = c.db.RecordFeedFailure(fc.URL, reason.Error())
+		} else {
+			err = c.db.RecordFeedSuccess(fc.URL)
+		}
+		if err != nil {
+			log.Printf("Error recording health of feed %s: %v", fc.URL, err)
+		}
+	}
+}
+
+// collectReddit stores the configured subreddits' top posts under periodID.
+// Subreddits are typed as blogs, like other practitioner communities.
+func (c *Collector) collectReddit(periodID string, r *Result) {
+	if c.reddit == nil {
+		return
+	}
+	log.Println("Collecting from Reddit...")
+	for _, sub := range c.subreddits {
+		posts := c.reddit.TopPosts(sub, c.daysBack)
+		r.TotalFound += len(posts)
+
+		for _, post := range posts {
+			if !c.filters.reddit.allows(post.Title, post.Content) {
+				r.Filtered++
+				continue
+			}
+			var pubDate, content *string
+			if post.PublishedDate != "" {
+				pubDate = &post.PublishedDate
+			}
+			if post.Content != "" {
+				content = &post.Content
 			}
 			pid := periodID
 
-			id, _ := c.db.InsertArticle(entry.URL, entry.Title, source, pubDate, content, &pid)
+			id, _ := c.db.InsertArticle(post.URL, post.Title, &post.Source, pubDate, content, &pid)
 			if id > 0 {
 				r.NewArticles++
-				r.Sources[entry.Source]++
+				r.ArticleIDs = append(r.ArticleIDs, id)
+				r.Sources[post.Source]++
+				c.db.AddSource(post.Source, database.SourceBlog)
 			} else {
 				r.Duplicates++
 			}
 		}
 	}
+}
 
-	// Collect from NewsAPI
-	if c.newsClient != nil && c.newsClient.IsConfigured() {
-		log.Println("Collecting from NewsAPI...")
-
+// collectNews searches each configured news API, with the active
+// priorities added to its query, and stores the results under periodID.
+func (c *Collector) collectNews(periodID string, r *Result) {
+	var priorityTitles []string
+	if len(c.news) > 0 {
 		priorities, _ := c.db.GetActivePriorities()
-		var priorityTitles []string
 		for _, p := range priorities {
 			priorityTitles = append(priorityTitles, p.Title)
 		}
+	}
+	dates := LastDays(c.daysBack)
+	if c.date != "" {
+		dates = DateRange{From: c.date, To: c.date}
+	}
+
+	for _, news := range c.news {
+		if !news.client.IsConfigured() {
+			continue
+		}
+		log.Printf("Collecting from %s...", news.client.Name())
 
 		var articles []NewsArticle
 		if len(priorityTitles) > 0 {
 			log.Printf("Using %d active priorities for search", len(priorityTitles))
-			articles = c.newsClient.SearchWithPriorities(c.newsQuery, priorityTitles, c.daysBack)
+			articles = SearchWithPriorities(news.client, news.query, priorityTitles, dates)
 		} else {
-			articles = c.newsClient.Search(c.newsQuery, c.daysBack, 100)
+			articles = news.client.Search(news.query, dates, 100)
 		}
 
 		r.TotalFound += len(articles)
 
 		for _, article := range articles {
+			if !news.filter.allows(article.Title, article.Content) {
+				r.Filtered++
+				continue
+			}
 			var source, pubDate, content *string
 			if article.Source != "" {
 				source = &article.Source
@@ -122,13 +475,175 @@ func (c *Collector) Collect(periodID string) *Result {
 			id, _ := c.db.InsertArticle(article.URL, article.Title, source, pubDate, content, &pid)
 			if id > 0 {
 				r.NewArticles++
+				r.ArticleIDs = append(r.ArticleIDs, id)
 				r.Sources[article.Source]++
+				if source != nil {
+					c.db.AddSource(article.Source, InferSourceType(article.URL, database.SourceNews))
+				}
 			} else {
 				r.Duplicates++
 			}
 		}
 	}
+}
 
-	log.Printf("Collection complete: %d found, %d new, %d duplicates", r.TotalFound, r.NewArticles, r.Duplicates)
-	return r
+// collectArxiv stores the papers submitted in the lookback window, or on the
+// collector's date, under periodID, with their abstract as content.
+func (c *Collector) collectArxiv(periodID string, r *Result) {
+	if c.arxiv == nil {
+		return
+	}
+	log.Println("Collecting from arXiv...")
+	dates := LastDays(c.daysBack)
+	if c.date != "" {
+		dates = DateRange{From: c.date, To: c.date}
+	}
+	papers := c.arxiv.Search(dates)
+	r.TotalFound += len(papers)
+
+	source := "arXiv"
+	for _, paper := range papers {
+		if !c.filters.arxiv.allows(paper.Title, paper.Abstract) {
+			r.Filtered++
+			continue
+		}
+		var pubDate, content *string
+		if paper.PublishedDate != "" {
+			pubDate = &paper.PublishedDate
+		}
+		if paper.Abstract != "" {
+			content = &paper.Abstract
+		}
+		pid := periodID
+
+		id, _ := c.db.InsertArticle(paper.URL, paper.Title, &source, pubDate, content, &pid)
+		if id > 0 {
+			r.NewArticles++
+			r.ArticleIDs = append(r.ArticleIDs, id)
+			r.Sources[source]++
+		} else {
+			r.Duplicates++
+		}
+	}
+	if r.Sources[source] > 0 {
+		c.db.AddSource(source, database.SourceAcademic)
+	}
+}
+
+// collectGitHub stores new releases of the followed repositories and
+// trending repositories of the followed topics under periodID. Projects
+// announce their own releases, so releases are typed as vendor sources.
+func (c *Collector) collectGitHub(periodID string, r *Result) {
+	if c.github == nil {
+		return
+	}
+	log.Println("Collecting from GitHub...")
+	var entries []GitHubEntry
+	for _, repo := range c.repos {
+		entries = append(entries, c.github.Releases(repo, c.daysBack)...)
+	}
+	for _, topic := range c.topics {
+		entries = append(entries, c.github.Trending(topic, c.daysBack)...)
+	}
+	r.TotalFound += len(entries)
+	log.Printf("Fetched %d releases and trending repositories from GitHub", len(entries))
+
+	for _, entry := range entries {
+		if !c.filters.github.allows(entry.Title, entry.Content) {
+			r.Filtered++
+			continue
+		}
+		var pubDate, content *string
+		if entry.PublishedDate != "" {
+			pubDate = &entry.PublishedDate
+		}
+		if entry.Content != "" {
+			content = &entry.Content
+		}
+		pid := periodID
+
+		id, _ := c.db.InsertArticle(entry.URL, entry.Title, &entry.Source, pubDate, content, &pid)
+		if id > 0 {
+			r.NewArticles++
+			r.ArticleIDs = append(r.ArticleIDs, id)
+			r.Sources[entry.Source]++
+		} else {
+			r.Duplicates++
+		}
+	}
+	if r.Sources[GitHubReleasesSource] > 0 {
+		c.db.AddSource(GitHubReleasesSource, database.SourceVendor)
+	}
+	if r.Sources[GitHubTrendingSource] > 0 {
+		c.db.AddSource(GitHubTrendingSource, database.SourceBlog)
+	}
+}
+
+// collectSocial stores the threads of the followed Mastodon and Bluesky
+// accounts, hashtags and feeds under periodID. Like subreddits, they are
+// typed as blogs.
+func (c *Collector) collectSocial(periodID string, r *Result) {
+	var posts []SocialPost
+	keep := func(filter keywordFilter, found []SocialPost) {
+		r.TotalFound += len(found)
+		for _, post := range found {
+			if filter.allows(post.Title, post.Content) {
+				posts = append(posts, post)
+			} else {
+				r.Filtered++
+			}
+		}
+	}
+	if c.mastodon != nil {
+		log.Println("Collecting from Mastodon...")
+		for _, account := range c.accounts {
+			keep(c.filters.mastodon, c.mastodon.AccountPosts(account, c.daysBack))
+		}
+		for _, tag := range c.hashtags {
+			keep(c.filters.mastodon, c.mastodon.HashtagPosts(tag, c.daysBack))
+		}
+	}
+	if c.bluesky != nil {
+		log.Println("Collecting from Bluesky...")
+		for _, handle := range c.handles {
+			keep(c.filters.bluesky, c.bluesky.AccountPosts(handle, c.daysBack))
+		}
+		for _, feed := range c.skyFeeds {
+			keep(c.filters.bluesky, c.bluesky.FeedPosts(feed, c.daysBack))
+		}
+	}
+
+	for _, post := range posts {
+		var pubDate, content *string
+		if post.PublishedDate != "" {
+			pubDate = &post.PublishedDate
+		}
+		if post.Content != "" {
+			content = &post.Content
+		}
+		pid := periodID
+
+		id, _ := c.db.InsertArticle(post.URL, post.Title, &post.Source, pubDate, content, &pid)
+		if id > 0 {
+			r.NewArticles++
+			r.ArticleIDs = append(r.ArticleIDs, id)
+			r.Sources[post.Source]++
+			c.db.AddSource(post.Source, database.SourceBlog)
+		} else {
+			r.Duplicates++
+		}
+	}
+}
+
+// typeRemainingSources infers a type for sources that articles arrived under
+// without one, such as those pushed through the ingest API.
+func (c *Collector) typeRemainingSources() {
+	untyped, err := c.db.GetUntypedSources()
+	if err != nil {
+		log.Printf("Error listing untyped sources: %v", err)
+		return
+	}
+	for name, articleURL := range untyped {
+		c.db.AddSource(name, InferSourceType(articleURL, database.SourceBlog))
+	}
 }
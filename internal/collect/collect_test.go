@@ -0,0 +1,76 @@
+package collect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fixtureClient returns an HTTP client that sends every request, whatever
+// its host and scheme, to handler, which sees the host asked for in r.Host.
+func fixtureClient(t *testing.T, handler http.Handler) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return &http.Client{Transport: redirectTransport{target: target}}
+}
+
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Host = req.URL.Host
+	out.URL.Scheme = rt.target.Scheme
+	out.URL.Host = rt.target.Host
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if resp != nil {
+		// Links in the response resolve against the URL asked for.
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// fixtureTimes are the placeholders of fixtures: {{recent}} is two hours
+// ago, {{old}} thirty days ago, and either may add minutes, as in
+// {{recent+5m}}, and end in :unix for seconds since the epoch, as in
+// {{old:unix}}.
+var fixtureTimes = regexp.MustCompile(`\{\{(recent|old)(?:\+(\d+)m)?(:unix)?\}\}`)
+
+// fixture returns the file of testdata with its time placeholders filled
+// in, as RFC 3339 times unless they ask for Unix ones.
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	return fixtureTimes.ReplaceAllFunc(data, func(m []byte) []byte {
+		parts := fixtureTimes.FindSubmatch(m)
+		at := now.Add(-2 * time.Hour)
+		if string(parts[1]) == "old" {
+			at = now.AddDate(0, 0, -30)
+		}
+		minutes, _ := strconv.Atoi(string(parts[2]))
+		at = at.Add(time.Duration(minutes) * time.Minute)
+		if len(parts[3]) > 0 {
+			return strconv.AppendInt(nil, at.Unix(), 10)
+		}
+		return []byte(at.Format(time.RFC3339))
+	})
+}
+
+// serveFixture answers with a testdata file of the given content type.
+func serveFixture(t *testing.T, w http.ResponseWriter, name, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Write(fixture(t, name))
+}
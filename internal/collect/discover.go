@@ -0,0 +1,139 @@
+package collect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+)
+
+// DiscoveredFeed is a feed a site offers.
+type DiscoveredFeed struct {
+	URL   string
+	Title string // the feed's own title, or the link's when it has none
+	Items int    // entries the feed lists now
+}
+
+// feedTypes are the link types sites announce feeds with.
+var feedTypes = []string{"application/rss+xml", "application/atom+xml", "application/feed+json", "text/xml", "application/xml"}
+
+// commonFeedPaths are tried on the site root when a page announces no feed.
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml"}
+
+// maxDiscoveryPage bounds how much of a page is read to find its feeds.
+const maxDiscoveryPage = 5 << 20
+
+// DiscoverFeeds returns the feeds offered by the page at pageURL: the page
+// itself when it is a feed, otherwise the feeds its <link rel="alternate">
+// tags announce or, without any, those found at common paths of the site.
+// Every feed is fetched and parsed, so only working ones are returned. All
+// requests go out with userAgent.
+func DiscoverFeeds(ctx context.Context, client *http.Client, userAgent, pageURL string) ([]DiscoveredFeed, error) {
+	if !strings.Contains(pageURL, "://") {
+		pageURL = "https://" + pageURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryPage))
+	if err != nil {
+		return nil, err
+	}
+	base := resp.Request.URL
+
+	parser := gofeed.NewParser()
+	parser.Client = client
+	parser.UserAgent = userAgent
+	if feed, err := parser.ParseString(string(body)); err == nil {
+		return []DiscoveredFeed{{URL: base.String(), Title: strings.TrimSpace(feed.Title), Items: len(feed.Items)}}, nil
+	}
+
+	candidates := feedLinks(string(body), base)
+	if len(candidates) == 0 {
+		for _, path := range commonFeedPaths {
+			candidates = append(candidates, DiscoveredFeed{URL: base.ResolveReference(&url.URL{Path: path}).String()})
+		}
+	}
+
+	var feeds []DiscoveredFeed
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		feed, err := parser.ParseURLWithContext(c.URL, ctx)
+		if err != nil {
+			continue
+		}
+		// A feed that points at itself under another URL is listed once.
+		self := c.URL
+		if feed.FeedLink != "" {
+			self = feed.FeedLink
+		}
+		if seen[c.URL] || seen[self] {
+			continue
+		}
+		seen[c.URL], seen[self] = true, true
+		if title := strings.TrimSpace(feed.Title); title != "" {
+			c.Title = title
+		}
+		c.Items = len(feed.Items)
+		feeds = append(feeds, c)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return feeds, nil
+}
+
+// feedLinks returns the feeds announced by the <link rel="alternate"> tags
+// of a page, resolved against base.
+func feedLinks(page string, base *url.URL) []DiscoveredFeed {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	var feeds []DiscoveredFeed
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, typ, href, title string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "type":
+					typ, _, _ = strings.Cut(strings.ToLower(attr.Val), ";")
+					typ = strings.TrimSpace(typ)
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				case "title":
+					title = strings.TrimSpace(attr.Val)
+				}
+			}
+			if href != "" && slices.Contains(strings.Fields(rel), "alternate") && slices.Contains(feedTypes, typ) {
+				if u, err := base.Parse(href); err == nil {
+					feeds = append(feeds, DiscoveredFeed{URL: u.String(), Title: title})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return feeds
+}
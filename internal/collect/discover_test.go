@@ -0,0 +1,77 @@
+package collect
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverFeeds(t *testing.T) {
+	var requested []string
+	client := fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Host+r.URL.Path)
+		if ua := r.Header.Get("User-Agent"); ua != "Test/1.0" {
+			t.Errorf("expected %s asked for with the User-Agent, got %q", r.URL, ua)
+		}
+		switch r.Host + r.URL.Path {
+		case "blog.example/":
+			serveFixture(t, w, "discover_page.html", "text/html")
+		case "blog.example/feed.xml", "feeds.example/rss":
+			serveFixture(t, w, "discover_feed.xml", "application/rss+xml")
+		case "blog.example/notes/atom.xml", "plain.example/index.xml":
+			serveFixture(t, w, "discover_atom.xml", "application/atom+xml")
+		case "plain.example/":
+			w.Write([]byte("<html><body><p>No feeds announced here.</p></body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	tests := []struct {
+		name    string
+		page    string
+		want    []DiscoveredFeed
+		wantErr bool
+	}{
+		{
+			// The second RSS link is the first feed under another URL, and
+			// the feed that fails to load is left out.
+			name: "announced feeds",
+			page: "blog.example",
+			want: []DiscoveredFeed{
+				{URL: "https://blog.example/feed.xml", Title: "Example blog", Items: 2},
+				{URL: "https://blog.example/notes/atom.xml", Title: "Notes", Items: 1},
+			},
+		},
+		{
+			name: "the page is a feed",
+			page: "https://feeds.example/rss",
+			want: []DiscoveredFeed{{URL: "https://feeds.example/rss", Title: "Example blog", Items: 2}},
+		},
+		{
+			name: "common paths",
+			page: "https://plain.example/",
+			want: []DiscoveredFeed{{URL: "https://plain.example/index.xml", Items: 1}},
+		},
+		{
+			name:    "missing page",
+			page:    "https://blog.example/gone",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		feeds, err := DiscoverFeeds(context.Background(), client, "Test/1.0", tt.page)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+		if !reflect.DeepEqual(feeds, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, feeds)
+		}
+	}
+	for _, path := range requested {
+		if path == "blog.example/de/" {
+			t.Error("expected links that aren't feeds left alone")
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package collect
 
 import (
+	"cmp"
 	"log"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/TobiSchelling/AICrawler/internal/config"
 	"github.com/mmcdole/gofeed"
 )
 
-const maxPerFeed = 20
+// defaultMaxItems is how many entries are read from a feed that doesn't
+// set max_items.
+const defaultMaxItems = 20
 
 // FeedEntry represents a parsed feed entry.
 type FeedEntry struct {
@@ -18,65 +24,207 @@ type FeedEntry struct {
 	PublishedDate string // YYYY-MM-DD or empty
 	Content       string
 	Source        string
+	FeedURL       string
+	GUID          string  // the item's guid or Atom id, if it has one
+	Updated       string  // when the item says it was last updated, RFC 3339 in UTC, or empty
+	UpdateOnly    bool    // older than the lookback window, read for its update only
+	Category      string  // of the feed, if it sets one
+	Weight        float64 // of the feed
 }
 
 // FeedConfig represents a single feed configuration.
 type FeedConfig struct {
-	URL  string
-	Name string
+	URL      string
+	Name     string
+	Type     string          // one of database.SourceTypes or AggregatorType, or "" to infer from URL
+	MaxItems int             // entries read per collection; 0 means defaultMaxItems
+	Category string          // stored with the feed's articles
+	Weight   float64         // stored with the feed's articles
+	Scrape   *config.Scrape  // set for a page scraped instead of parsed as a feed
+	Sitemap  *config.Sitemap // set for a sitemap whose new pages are collected
 }
 
 // FeedParser parses RSS/Atom feeds.
 type FeedParser struct {
-	feeds []FeedConfig
+	feeds   []FeedConfig
+	client  *http.Client // nil uses gofeed's default client
+	agent   string       // the User-Agent of feeds, scraped pages and sitemaps
+	workers int          // feeds parsed at once; fewer than one means one
+	perHost int          // of those from the same host; fewer than one means one
+
+	// updateDays is how many days back entries older than the lookback
+	// window are read with UpdateOnly, to notice updates of articles
+	// collected before; 0 reads none.
+	updateDays int
+}
+
+// SourceName is the name articles from the feed are stored under: the
+// configured name, or one derived from the feed's host.
+func (fc FeedConfig) SourceName() string {
+	if fc.Name != "" {
+		return fc.Name
+	}
+	return extractSourceName(fc.URL)
+}
+
+// feedConfig returns the parser's view of a configured feed.
+func feedConfig(f config.Feed) FeedConfig {
+	return FeedConfig{URL: f.URL, Name: f.Name, Type: f.Type,
+		MaxItems: f.MaxItems, Category: f.Category, Weight: f.SourceWeight(), Scrape: f.Scrape, Sitemap: f.Sitemap}
 }
 
 // NewFeedParser creates a new FeedParser.
 func NewFeedParser(feeds []FeedConfig) *FeedParser {
-	return &FeedParser{feeds: feeds}
+	return &FeedParser{feeds: feeds, agent: config.DefaultUserAgent}
 }
 
-// ParseAll parses all configured feeds and returns entries within daysBack.
-func (fp *FeedParser) ParseAll(daysBack int) []FeedEntry {
+// ParseAll parses all configured feeds and returns entries within daysBack,
+// and older ones within updateDays that say when they were updated, in the
+// order the feeds are configured, along with why each feed that
+// failed did, by URL. Up to workers feeds are parsed at once, but no more
+// than perHost from the same host, so a site serving many of the feeds
+// isn't hit with all of them together.
+func (fp *FeedParser) ParseAll(daysBack int) ([]FeedEntry, map[string]error) {
 	cutoff := time.Now().AddDate(0, 0, -daysBack)
-	var all []FeedEntry
+	updateCutoff := time.Now().AddDate(0, 0, -max(daysBack, fp.updateDays))
+	entries := make([][]FeedEntry, len(fp.feeds))
+	errs := make([]error, len(fp.feeds))
 
-	parser := gofeed.NewParser()
+	hosts := make(map[string]chan struct{}) // a semaphore per host
 	for _, fc := range fp.feeds {
-		name := fc.Name
-		if name == "" {
-			name = extractSourceName(fc.URL)
+		if host := feedHost(fc.URL); hosts[host] == nil {
+			hosts[host] = make(chan struct{}, max(fp.perHost, 1))
 		}
+	}
 
-		entries, err := parseFeed(parser, fc.URL, name, cutoff)
-		if err != nil {
-			log.Printf("Failed to parse feed %s: %v", fc.URL, err)
-			continue
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(fp.workers, 1), len(fp.feeds)) {
+		wg.Go(func() {
+			// gofeed parsers keep state while parsing, so each worker has its own.
+			parser := gofeed.NewParser()
+			parser.Client = fp.client
+			parser.UserAgent = fp.agent
+			for i := range next {
+				fc := fp.feeds[i]
+				sem := hosts[feedHost(fc.URL)]
+				sem <- struct{}{}
+				entries[i], errs[i] = parseFeed(parser, fc, cutoff, updateCutoff)
+				<-sem
+				if errs[i] != nil {
+					log.Printf("Failed to parse feed %s: %v", fc.URL, errs[i])
+				} else {
+					log.Printf("Parsed %d entries from %s (within %d days)", len(entries[i]), fc.SourceName(), daysBack)
+				}
+			}
+		})
+	}
+	for _, i := range interleaveHosts(fp.feeds) {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var all []FeedEntry
+	failed := make(map[string]error)
+	for i, fc := range fp.feeds {
+		if errs[i] != nil {
+			failed[fc.URL] = errs[i]
 		}
-		all = append(all, entries...)
-		log.Printf("Parsed %d entries from %s (within %d days)", len(entries), name, daysBack)
+		all = append(all, entries[i]...)
 	}
+	return all, failed
+}
 
-	return all
+// ReadFeed parses a single configured feed the way a collection does,
+// scraped or read as a sitemap when it is configured so, and returns its
+// entries within daysBack, asking with userAgent. Nothing is stored, and a
+// sitemap's pages are all returned, new or not.
+func ReadFeed(client *http.Client, userAgent string, f config.Feed, daysBack int) ([]FeedEntry, error) {
+	parser := gofeed.NewParser()
+	parser.Client = client
+	parser.UserAgent = userAgent
+	cutoff := time.Now().AddDate(0, 0, -daysBack)
+	return parseFeed(parser, feedConfig(f), cutoff, cutoff)
 }
 
-func parseFeed(parser *gofeed.Parser, feedURL, sourceName string, cutoff time.Time) ([]FeedEntry, error) {
-	feed, err := parser.ParseURL(feedURL)
+// interleaveHosts returns the indexes of feeds in the order they are
+// handed to workers: one feed of each host in turn, so workers waiting for
+// a busy host don't hold up the feeds of the others.
+func interleaveHosts(feeds []FeedConfig) []int {
+	var hosts []string
+	byHost := make(map[string][]int)
+	for i, fc := range feeds {
+		host := feedHost(fc.URL)
+		if byHost[host] == nil {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], i)
+	}
+	order := make([]int, 0, len(feeds))
+	for len(order) < len(feeds) {
+		for _, host := range hosts {
+			if queue := byHost[host]; len(queue) > 0 {
+				order = append(order, queue[0])
+				byHost[host] = queue[1:]
+			}
+		}
+	}
+	return order
+}
+
+// feedHost returns the host a feed is served from, in lower case.
+func feedHost(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return feedURL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// parseFeed reads the entries of a feed published after cutoff, and those
+// published after updateCutoff that say when they were updated, with
+// UpdateOnly.
+func parseFeed(parser *gofeed.Parser, fc FeedConfig, cutoff, updateCutoff time.Time) ([]FeedEntry, error) {
+	if fc.Scrape != nil {
+		return scrapeListing(parser.Client, parser.UserAgent, fc, cutoff)
+	}
+	if fc.Sitemap != nil {
+		return readSitemap(parser.Client, parser.UserAgent, fc)
+	}
+	feed, err := parser.ParseURL(fc.URL)
 	if err != nil {
 		return nil, err
 	}
 
 	var entries []FeedEntry
+	limit := cmp.Or(fc.MaxItems, defaultMaxItems)
 	for _, item := range feed.Items {
-		if len(entries) >= maxPerFeed {
+		if len(entries) >= limit {
 			break
 		}
 
-		entry := parseItem(item, sourceName)
+		entry := parseItem(item, fc.SourceName())
 		if entry == nil {
 			continue
 		}
-		if isWithinWindow(entry.PublishedDate, cutoff) {
+		entry.FeedURL = fc.URL
+		entry.Category = fc.Category
+		entry.Weight = fc.Weight
+		if fc.IsAggregator() {
+			// An aggregator updates its entries for their comments and votes,
+			// not for the articles they link to.
+			entry.Updated = ""
+		}
+		if !isWithinWindow(entry.PublishedDate, cutoff) {
+			if entry.GUID == "" || entry.Updated == "" || !isWithinWindow(entry.PublishedDate, updateCutoff) {
+				continue
+			}
+			entry.UpdateOnly = true
+		}
+		if fc.IsAggregator() {
+			entries = append(entries, resolveAggregated(*entry, cmp.Or(item.Content, item.Description), fc.URL)...)
+		} else {
 			entries = append(entries, *entry)
 		}
 	}
@@ -105,6 +253,11 @@ func parseItem(item *gofeed.Item, source string) *FeedEntry {
 		publishedDate = item.UpdatedParsed.Format("2006-01-02")
 	}
 
+	var updated string
+	if item.UpdatedParsed != nil {
+		updated = item.UpdatedParsed.UTC().Format(time.RFC3339)
+	}
+
 	var content string
 	if item.Content != "" {
 		content = stripHTML(item.Content)
@@ -118,6 +271,8 @@ func parseItem(item *gofeed.Item, source string) *FeedEntry {
 		PublishedDate: publishedDate,
 		Content:       content,
 		Source:        source,
+		GUID:          strings.TrimSpace(item.GUID),
+		Updated:       updated,
 	}
 }
 
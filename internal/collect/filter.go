@@ -0,0 +1,64 @@
+package collect
+
+import (
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+	"github.com/TobiSchelling/AICrawler/internal/textmatch"
+)
+
+// keywordFilter decides at collect time which items are stored at all, by
+// the keywords in their title and content, so items that are obviously off
+// topic never reach triage.
+type keywordFilter struct {
+	include []string // lower case; an item must contain one, unless empty
+	exclude []string // lower case; an item containing one is dropped
+}
+
+// newKeywordFilter combines the global filter with a source's own: the
+// exclude lists add up, and the source's include list replaces the global
+// one when it has one.
+func newKeywordFilter(global, own config.KeywordFilter) keywordFilter {
+	include := own.Include
+	if len(include) == 0 {
+		include = global.Include
+	}
+	return keywordFilter{
+		include: lowerTerms(include),
+		exclude: lowerTerms(append(append([]string(nil), global.Exclude...), own.Exclude...)),
+	}
+}
+
+func lowerTerms(terms []string) []string {
+	var lower []string
+	for _, t := range terms {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			lower = append(lower, t)
+		}
+	}
+	return lower
+}
+
+// allows reports whether an item with title and content passes the filter.
+// Keywords match whole words, case-insensitively, so "ai" doesn't match
+// "maintain".
+func (f keywordFilter) allows(title, content string) bool {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return true
+	}
+	text := strings.ToLower(title + "\n" + content)
+	for _, term := range f.exclude {
+		if textmatch.ContainsWords(text, term) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, term := range f.include {
+		if textmatch.ContainsWords(text, term) {
+			return true
+		}
+	}
+	return false
+}
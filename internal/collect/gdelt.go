@@ -0,0 +1,119 @@
+package collect
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+const gdeltDocURL = "https://api.gdeltproject.org/api/v2/doc/doc"
+
+// GDELTSource is the source name of GDELT results without a domain.
+const GDELTSource = "GDELT"
+
+// gdeltMaxRecords is the most articles GDELT lists for one search.
+const gdeltMaxRecords = 250
+
+// gdeltInterval spaces out requests; GDELT asks for one every five seconds.
+const gdeltInterval = 5 * time.Second
+
+// GDELTClient searches the GDELT 2.0 DOC API, which monitors news sites
+// worldwide and needs no API key. It lists titles and links only, so the
+// articles are left for the fetch step; URLs a feed already brought are
+// duplicates like any other.
+type GDELTClient struct {
+	client    *http.Client
+	userAgent string
+	last      time.Time // of the previous request
+}
+
+// NewGDELTClient creates a GDELT DOC API client.
+func NewGDELTClient() *GDELTClient {
+	return &GDELTClient{client: &http.Client{Timeout: 30 * time.Second}, userAgent: config.DefaultUserAgent}
+}
+
+// Name returns the name of the news API.
+func (c *GDELTClient) Name() string { return GDELTSource }
+
+// IsConfigured is always true: GDELT needs no key.
+func (c *GDELTClient) IsConfigured() bool { return true }
+
+// Search lists English articles matching a query that GDELT saw within
+// dates, most relevant first. The query takes GDELT's syntax: words are
+// all required, "quoted phrases" and (a OR b) work.
+func (c *GDELTClient) Search(query string, dates DateRange, pageSize int) []NewsArticle {
+	params := url.Values{
+		"query":         {query + " sourcelang:english"},
+		"mode":          {"artlist"},
+		"format":        {"json"},
+		"sort":          {"hybridrel"},
+		"maxrecords":    {fmt.Sprintf("%d", min(pageSize, gdeltMaxRecords))},
+		"startdatetime": {strings.ReplaceAll(dates.From, "-", "") + "000000"},
+		"enddatetime":   {strings.ReplaceAll(dates.To, "-", "") + "235959"},
+	}
+	req, err := http.NewRequest("GET", gdeltDocURL+"?"+params.Encode(), nil)
+	if err != nil {
+		log.Printf("GDELT request error: %v", err)
+		return nil
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if wait := gdeltInterval - time.Since(c.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	c.last = time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Printf("GDELT error: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("GDELT HTTP error: %d", resp.StatusCode)
+		return nil
+	}
+
+	// A query GDELT can't run is answered with a plain-text message instead
+	// of JSON, which shows up as a decode error.
+	var result struct {
+		Articles []struct {
+			URL      string `json:"url"`
+			Title    string `json:"title"`
+			SeenDate string `json:"seendate"` // e.g. 20260206T093000Z
+			Domain   string `json:"domain"`
+		} `json:"articles"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.Printf("GDELT decode error (query %q): %v", query, err)
+		return nil
+	}
+
+	var articles []NewsArticle
+	for _, a := range result.Articles {
+		title := strings.TrimSpace(a.Title)
+		if a.URL == "" || title == "" {
+			continue
+		}
+		var pubDate string
+		if t, err := time.Parse("20060102T150405Z", a.SeenDate); err == nil {
+			pubDate = t.Format("2006-01-02")
+		}
+		articles = append(articles, NewsArticle{
+			URL:           a.URL,
+			Title:         title,
+			PublishedDate: pubDate,
+			Source:        cmp.Or(strings.TrimPrefix(a.Domain, "www."), GDELTSource),
+		})
+	}
+
+	log.Printf("Fetched %d articles from GDELT for query: %s", len(articles), query)
+	return articles
+}
@@ -0,0 +1,65 @@
+package collect
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGDELTSearch(t *testing.T) {
+	var query map[string][]string
+	c := NewGDELTClient()
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.gdeltproject.org" || r.URL.Path != "/api/v2/doc/doc" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		serveFixture(t, w, "gdelt_artlist.json", "application/json")
+	}))
+
+	articles := c.Search(`"coding agents"`, DateRange{From: "2026-02-05", To: "2026-02-06"}, 500)
+	want := []NewsArticle{
+		{URL: "https://www.news.example.com/2026/02/06/agents", Title: "Coding agents reach the enterprise", PublishedDate: "2026-02-06", Source: "news.example.com"},
+		// An unreadable date leaves it empty, a missing domain names GDELT.
+		{URL: "https://example.org/benchmarks", Title: "New benchmark results", Source: GDELTSource},
+		// The result without a link is left out.
+	}
+	if !reflect.DeepEqual(articles, want) {
+		t.Errorf("expected %+v, got %+v", want, articles)
+	}
+	wantQuery := map[string][]string{
+		"query":         {`"coding agents" sourcelang:english`},
+		"mode":          {"artlist"},
+		"format":        {"json"},
+		"sort":          {"hybridrel"},
+		"maxrecords":    {"250"}, // GDELT's most, though 500 were asked for
+		"startdatetime": {"20260205000000"},
+		"enddatetime":   {"20260206235959"},
+	}
+	if !reflect.DeepEqual(query, wantQuery) {
+		t.Errorf("expected query %v, got %v", wantQuery, query)
+	}
+}
+
+func TestGDELTErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"HTTP error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		}},
+		// GDELT answers a query it can't run with a message, not JSON.
+		{"query rejected", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("The specified phrase is too short."))
+		}},
+	}
+	for _, tt := range tests {
+		c := NewGDELTClient()
+		c.client = fixtureClient(t, tt.handler)
+		if articles := c.Search("ai", DateRange{From: "2026-02-06", To: "2026-02-06"}, 20); articles != nil {
+			t.Errorf("%s: expected no articles, got %+v", tt.name, articles)
+		}
+	}
+}
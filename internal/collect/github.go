@@ -0,0 +1,182 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+const githubBaseURL = "https://api.github.com"
+
+// Source names of GitHub articles.
+const (
+	GitHubReleasesSource = "GitHub Releases"
+	GitHubTrendingSource = "GitHub Trending"
+)
+
+// GitHubEntry represents a release of a followed repository or a trending
+// repository of a followed topic.
+type GitHubEntry struct {
+	URL           string
+	Title         string
+	PublishedDate string
+	Content       string
+	Source        string
+}
+
+// GitHubClient fetches releases and trending repositories from the GitHub
+// REST API. A token is optional; without one GitHub allows 60 requests an
+// hour, which is plenty for a handful of repositories.
+type GitHubClient struct {
+	token       string
+	prereleases bool
+	minStars    int
+	client      *http.Client
+	userAgent   string
+}
+
+// NewGitHubClient creates a GitHub client authenticating with the token in
+// tokenEnv, if set. Trending repositories need at least minStars stars.
+func NewGitHubClient(tokenEnv string, prereleases bool, minStars int) *GitHubClient {
+	return &GitHubClient{
+		token:       os.Getenv(tokenEnv),
+		prereleases: prereleases,
+		minStars:    minStars,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		userAgent:   config.DefaultUserAgent,
+	}
+}
+
+// get decodes the JSON response to a GET of path into v.
+func (c *GitHubClient) get(path string, v any) error {
+	req, err := http.NewRequest("GET", githubBaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Releases returns the releases of repo ("owner/name") published in the
+// last daysBack days, with their release notes as content. Drafts are
+// skipped, and so are prereleases unless the client includes them.
+func (c *GitHubClient) Releases(repo string, daysBack int) []GitHubEntry {
+	repo = strings.Trim(strings.TrimPrefix(strings.TrimSpace(repo), "https://github.com/"), "/")
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		log.Printf("Invalid GitHub repository %q (want owner/name), skipping", repo)
+		return nil
+	}
+
+	var releases []struct {
+		TagName     string `json:"tag_name"`
+		Name        string `json:"name"`
+		HTMLURL     string `json:"html_url"`
+		Body        string `json:"body"`
+		Draft       bool   `json:"draft"`
+		Prerelease  bool   `json:"prerelease"`
+		PublishedAt string `json:"published_at"`
+	}
+	if err := c.get(fmt.Sprintf("/repos/%s/%s/releases?per_page=20", url.PathEscape(owner), url.PathEscape(name)), &releases); err != nil {
+		log.Printf("GitHub error for %s releases: %v", repo, err)
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -daysBack)
+	var entries []GitHubEntry
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && !c.prereleases) || r.HTMLURL == "" {
+			continue
+		}
+		published, err := time.Parse(time.RFC3339, r.PublishedAt)
+		if err != nil || published.Before(cutoff) {
+			continue
+		}
+		// Release names often repeat the tag or leave out the project.
+		title := repo + " " + r.TagName
+		if n := strings.TrimSpace(r.Name); n != "" && n != r.TagName {
+			title += ": " + n
+		}
+		entries = append(entries, GitHubEntry{
+			URL:           r.HTMLURL,
+			Title:         title,
+			PublishedDate: published.Format("2006-01-02"),
+			Content:       strings.TrimSpace(r.Body),
+			Source:        GitHubReleasesSource,
+		})
+	}
+	return entries
+}
+
+// Trending returns repositories on topic created in the last daysBack days
+// with at least the minimum number of stars, most starred first. Their
+// description is only a line, so the fetch step reads the README from the
+// repository page.
+func (c *GitHubClient) Trending(topic string, daysBack int) []GitHubEntry {
+	topic = strings.TrimSpace(topic)
+	if topic == "" {
+		return nil
+	}
+	since := time.Now().AddDate(0, 0, -daysBack).Format("2006-01-02")
+	params := url.Values{
+		"q":        {fmt.Sprintf("topic:%s created:>=%s stars:>=%d", topic, since, c.minStars)},
+		"sort":     {"stars"},
+		"order":    {"desc"},
+		"per_page": {"20"},
+	}
+
+	var result struct {
+		Items []struct {
+			FullName    string `json:"full_name"`
+			HTMLURL     string `json:"html_url"`
+			Description string `json:"description"`
+			CreatedAt   string `json:"created_at"`
+		} `json:"items"`
+	}
+	if err := c.get("/search/repositories?"+params.Encode(), &result); err != nil {
+		log.Printf("GitHub error for topic %s: %v", topic, err)
+		return nil
+	}
+
+	var entries []GitHubEntry
+	for _, item := range result.Items {
+		if item.HTMLURL == "" || item.FullName == "" {
+			continue
+		}
+		title := item.FullName
+		if d := strings.TrimSpace(item.Description); d != "" {
+			title += ": " + d
+		}
+		var pubDate string
+		if t, err := time.Parse(time.RFC3339, item.CreatedAt); err == nil {
+			pubDate = t.Format("2006-01-02")
+		}
+		entries = append(entries, GitHubEntry{
+			URL:           item.HTMLURL,
+			Title:         title,
+			PublishedDate: pubDate,
+			Source:        GitHubTrendingSource,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,103 @@
+package collect
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGitHubReleases(t *testing.T) {
+	var header http.Header
+	var query map[string][]string
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	c := NewGitHubClient("TEST_GITHUB_TOKEN", false, 0)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.github.com" || r.URL.Path != "/repos/acme/llm/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		header, query = r.Header, r.URL.Query()
+		serveFixture(t, w, "github_releases.json", "application/json")
+	}))
+
+	entries := c.Releases(" https://github.com/acme/llm/ ", 1)
+	day := time.Now().UTC().Add(-2 * time.Hour).Format("2006-01-02")
+	want := []GitHubEntry{
+		{URL: "https://github.com/acme/llm/releases/tag/v2.1.0", Title: "acme/llm v2.1.0: Faster inference", PublishedDate: day,
+			Content: "## Changes\n- Faster inference", Source: GitHubReleasesSource},
+		// A name repeating the tag isn't added to the title.
+		{URL: "https://github.com/acme/llm/releases/tag/v2.0.1", Title: "acme/llm v2.0.1", PublishedDate: day,
+			Content: "Bug fixes.", Source: GitHubReleasesSource},
+		// The prerelease, the draft and the old release are left out.
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected %+v, got %+v", want, entries)
+	}
+	if got := header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("expected the token sent, got %q", got)
+	}
+	if got := header.Get("Accept"); got != "application/vnd.github+json" {
+		t.Errorf("expected the GitHub media type accepted, got %q", got)
+	}
+	if got := query["per_page"]; !reflect.DeepEqual(got, []string{"20"}) {
+		t.Errorf("expected 20 releases asked for, got %v", got)
+	}
+
+	c.prereleases = true
+	if entries := c.Releases("acme/llm", 1); len(entries) != 3 || entries[2].Title != "acme/llm v2.2.0-rc1" {
+		t.Errorf("expected the prerelease included, got %+v", entries)
+	}
+	if entries := c.Releases("acme", 1); entries != nil {
+		t.Errorf("expected a repository without an owner skipped, got %+v", entries)
+	}
+}
+
+func TestGitHubTrending(t *testing.T) {
+	var query map[string][]string
+	c := NewGitHubClient("TEST_GITHUB_TOKEN_UNSET", false, 100)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/repositories" {
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no token sent without one, got %q", auth)
+		}
+		query = r.URL.Query()
+		serveFixture(t, w, "github_search.json", "application/json")
+	}))
+
+	entries := c.Trending("llm", 7)
+	want := []GitHubEntry{
+		{URL: "https://github.com/alice/agent-kit", Title: "alice/agent-kit: Tools for building agents", PublishedDate: "2026-02-05", Source: GitHubTrendingSource},
+		{URL: "https://github.com/bob/evals", Title: "bob/evals", Source: GitHubTrendingSource},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected %+v, got %+v", want, entries)
+	}
+	since := time.Now().AddDate(0, 0, -7).Format("2006-01-02")
+	wantQuery := map[string][]string{
+		"q":        {fmt.Sprintf("topic:llm created:>=%s stars:>=100", since)},
+		"sort":     {"stars"},
+		"order":    {"desc"},
+		"per_page": {"20"},
+	}
+	if !reflect.DeepEqual(query, wantQuery) {
+		t.Errorf("expected query %v, got %v", wantQuery, query)
+	}
+}
+
+func TestGitHubErrors(t *testing.T) {
+	c := NewGitHubClient("TEST_GITHUB_TOKEN_UNSET", false, 0)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "API rate limit exceeded"}`, http.StatusForbidden)
+	}))
+	if entries := c.Releases("acme/llm", 1); entries != nil {
+		t.Errorf("expected no releases on a 403, got %+v", entries)
+	}
+	if entries := c.Trending("llm", 1); entries != nil {
+		t.Errorf("expected no repositories on a 403, got %+v", entries)
+	}
+}
@@ -0,0 +1,202 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+	"golang.org/x/net/html"
+)
+
+// MastodonClient fetches public posts of Mastodon accounts and hashtags.
+// Accounts are read from their own server; hashtag timelines from the
+// configured instance, which sees the posts federated to it.
+type MastodonClient struct {
+	instance  string
+	client    *http.Client
+	userAgent string
+}
+
+// NewMastodonClient creates a Mastodon client reading hashtags from instance
+// (a host name such as "mastodon.social").
+func NewMastodonClient(instance string) *MastodonClient {
+	return &MastodonClient{
+		instance:  strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(instance), "https://"), "/"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		userAgent: config.DefaultUserAgent,
+	}
+}
+
+type mastodonStatus struct {
+	ID          string          `json:"id"`
+	CreatedAt   string          `json:"created_at"`
+	URL         string          `json:"url"`
+	Content     string          `json:"content"`
+	InReplyToID string          `json:"in_reply_to_id"`
+	Reblog      json.RawMessage `json:"reblog"`
+	Account     struct {
+		ID string `json:"id"`
+	} `json:"account"`
+	Card *struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	} `json:"card"`
+}
+
+// get decodes the JSON response to a GET of path on host into v.
+func (c *MastodonClient) get(host, path string, params url.Values, v any) error {
+	req, err := http.NewRequest("GET", "https://"+host+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AccountPosts returns the threads account ("@user@server", or "user" on
+// the configured instance) started in the last daysBack days. Boosts are
+// skipped: they are someone else's announcement.
+func (c *MastodonClient) AccountPosts(account string, daysBack int) []SocialPost {
+	user, host, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(account), "@"), "@")
+	if host == "" {
+		host = c.instance
+	}
+	if user == "" || host == "" {
+		log.Printf("Invalid Mastodon account %q (want @user@server), skipping", account)
+		return nil
+	}
+	name := "@" + user + "@" + host
+
+	var acct struct {
+		ID string `json:"id"`
+	}
+	if err := c.get(host, "/api/v1/accounts/lookup", url.Values{"acct": {user}}, &acct); err != nil || acct.ID == "" {
+		log.Printf("Mastodon error looking up %s: %v", name, err)
+		return nil
+	}
+	var statuses []mastodonStatus
+	params := url.Values{"limit": {"40"}, "exclude_reblogs": {"true"}}
+	if err := c.get(host, "/api/v1/accounts/"+url.PathEscape(acct.ID)+"/statuses", params, &statuses); err != nil {
+		log.Printf("Mastodon error for %s: %v", name, err)
+		return nil
+	}
+
+	posts := socialPosts(mastodonStatuses(statuses, host), name, daysBack)
+	log.Printf("Fetched %d threads from %s", len(posts), name)
+	return posts
+}
+
+// HashtagPosts returns the threads tagged with tag that the configured
+// instance saw in the last daysBack days.
+func (c *MastodonClient) HashtagPosts(tag string, daysBack int) []SocialPost {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	if tag == "" || c.instance == "" {
+		return nil
+	}
+	name := "#" + tag + " on Mastodon"
+
+	var statuses []mastodonStatus
+	if err := c.get(c.instance, "/api/v1/timelines/tag/"+url.PathEscape(tag), url.Values{"limit": {"40"}}, &statuses); err != nil {
+		log.Printf("Mastodon error for #%s on %s: %v", tag, c.instance, err)
+		return nil
+	}
+
+	posts := socialPosts(mastodonStatuses(statuses, c.instance), name, daysBack)
+	log.Printf("Fetched %d threads tagged #%s from %s", len(posts), tag, c.instance)
+	return posts
+}
+
+// mastodonStatuses converts API statuses read from host, leaving out boosts.
+// The link card, when there is one, is the post's first link.
+func mastodonStatuses(statuses []mastodonStatus, host string) []socialStatus {
+	var out []socialStatus
+	for _, st := range statuses {
+		if len(st.Reblog) > 0 && string(st.Reblog) != "null" {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, st.CreatedAt)
+		if err != nil {
+			continue
+		}
+		text, links := mastodonContent(st.Content, host)
+		s := socialStatus{
+			id:       st.ID,
+			parentID: st.InReplyToID,
+			author:   st.Account.ID,
+			url:      st.URL,
+			text:     text,
+			links:    links,
+			created:  created,
+		}
+		if st.Card != nil && outboundURL(st.Card.URL, host) {
+			s.links = append([]string{st.Card.URL}, links...)
+			s.cardTitle = strings.TrimSpace(st.Card.Title)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// mastodonContent returns the text of a status's HTML content, a line per
+// paragraph, and its outbound links. Mentions and hashtags are links too;
+// Mastodon marks them with a class, and they point at a Mastodon server.
+func mastodonContent(content, host string) (string, []string) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", nil
+	}
+	var b strings.Builder
+	var links []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteByte('\n')
+		case n.Type == html.ElementNode && n.Data == "a":
+			var href, class string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				case "class":
+					class = attr.Val
+				}
+			}
+			if !strings.Contains(class, "mention") && !strings.Contains(class, "hashtag") && outboundURL(href, host) {
+				links = append(links, href)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && n.Data == "p" {
+			b.WriteByte('\n')
+		}
+	}
+	walk(doc)
+
+	var lines []string
+	for line := range strings.SplitSeq(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), links
+}
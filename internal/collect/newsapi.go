@@ -9,6 +9,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
 )
 
 const newsAPIBaseURL = "https://newsapi.org/v2/everything"
@@ -22,43 +24,64 @@ type NewsArticle struct {
 	Source        string
 }
 
+// NewsSearcher is a news API that searches articles by query and
+// publication date: NewsAPI, Brave Search or GDELT, chosen in sources.apis.
+type NewsSearcher interface {
+	Name() string
+	IsConfigured() bool
+	Search(query string, dates DateRange, pageSize int) []NewsArticle
+}
+
 // NewsAPIClient fetches articles from NewsAPI.
 type NewsAPIClient struct {
-	apiKey string
-	client *http.Client
+	apiKey    string
+	client    *http.Client
+	userAgent string
 }
 
 // NewNewsAPIClient creates a new NewsAPI client.
 func NewNewsAPIClient(apiKeyEnv string) *NewsAPIClient {
 	return &NewsAPIClient{
-		apiKey: os.Getenv(apiKeyEnv),
-		client: &http.Client{Timeout: 30 * time.Second},
+		apiKey:    os.Getenv(apiKeyEnv),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		userAgent: config.DefaultUserAgent,
 	}
 }
 
+// Name returns the name of the news API.
+func (c *NewsAPIClient) Name() string { return "NewsAPI" }
+
 // IsConfigured returns whether the API key is available.
 func (c *NewsAPIClient) IsConfigured() bool {
 	return c.apiKey != ""
 }
 
+// DateRange is an inclusive range of publication dates (YYYY-MM-DD).
+type DateRange struct {
+	From, To string
+}
+
+// LastDays is the range from daysBack days ago until today.
+func LastDays(daysBack int) DateRange {
+	now := time.Now()
+	return DateRange{From: now.AddDate(0, 0, -daysBack).Format("2006-01-02"), To: now.Format("2006-01-02")}
+}
+
 // Search searches for articles matching a query.
-func (c *NewsAPIClient) Search(query string, daysBack, pageSize int) []NewsArticle {
+func (c *NewsAPIClient) Search(query string, dates DateRange, pageSize int) []NewsArticle {
 	if c.apiKey == "" {
 		log.Println("NewsAPI not configured, skipping search")
 		return nil
 	}
 
-	fromDate := time.Now().AddDate(0, 0, -daysBack).Format("2006-01-02")
-	toDate := time.Now().Format("2006-01-02")
-
 	if pageSize > 100 {
 		pageSize = 100
 	}
 
 	params := url.Values{
 		"q":        {query},
-		"from":     {fromDate},
-		"to":       {toDate},
+		"from":     {dates.From},
+		"to":       {dates.To},
 		"language": {"en"},
 		"pageSize": {fmt.Sprintf("%d", pageSize)},
 		"sortBy":   {"relevancy"},
@@ -70,6 +93,7 @@ func (c *NewsAPIClient) Search(query string, daysBack, pageSize int) []NewsArtic
 		return nil
 	}
 	req.Header.Set("X-Api-Key", c.apiKey)
+	req.Header.Set("User-Agent", c.userAgent)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -148,12 +172,12 @@ func (c *NewsAPIClient) Search(query string, daysBack, pageSize int) []NewsArtic
 	return articles
 }
 
-// SearchWithPriorities searches with base query and priority-enhanced queries.
-func (c *NewsAPIClient) SearchWithPriorities(baseQuery string, priorities []string, daysBack int) []NewsArticle {
+// SearchWithPriorities searches s with base query and priority-enhanced queries.
+func SearchWithPriorities(s NewsSearcher, baseQuery string, priorities []string, dates DateRange) []NewsArticle {
 	seen := make(map[string]struct{})
 	var all []NewsArticle
 
-	for _, a := range c.Search(baseQuery, daysBack, 100) {
+	for _, a := range s.Search(baseQuery, dates, 100) {
 		if _, ok := seen[a.URL]; !ok {
 			seen[a.URL] = struct{}{}
 			all = append(all, a)
@@ -162,7 +186,7 @@ func (c *NewsAPIClient) SearchWithPriorities(baseQuery string, priorities []stri
 
 	for _, priority := range priorities {
 		q := baseQuery + " " + priority
-		for _, a := range c.Search(q, daysBack, 50) {
+		for _, a := range s.Search(q, dates, 50) {
 			if _, ok := seen[a.URL]; !ok {
 				seen[a.URL] = struct{}{}
 				all = append(all, a)
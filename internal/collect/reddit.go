@@ -0,0 +1,141 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+const redditBaseURL = "https://www.reddit.com"
+
+// RedditPost represents a post from a subreddit listing.
+type RedditPost struct {
+	URL           string
+	Title         string
+	PublishedDate string
+	Content       string
+	Source        string // "r/<subreddit>"
+	Score         int
+}
+
+// RedditClient fetches top posts from subreddits through Reddit's public
+// JSON listings, which need no API key.
+type RedditClient struct {
+	minUpvotes int
+	client     *http.Client
+	userAgent  string
+}
+
+// NewRedditClient creates a Reddit client that drops posts with fewer than
+// minUpvotes upvotes.
+func NewRedditClient(minUpvotes int) *RedditClient {
+	return &RedditClient{
+		minUpvotes: minUpvotes,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		userAgent:  config.DefaultUserAgent,
+	}
+}
+
+// TopPosts returns the subreddit's top posts of the last daysBack days with
+// at least the minimum number of upvotes. Link posts point at the page they
+// link to, so an article also found in a feed is collected once; text posts
+// point at their Reddit thread and carry their text as content.
+func (c *RedditClient) TopPosts(subreddit string, daysBack int) []RedditPost {
+	subreddit = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(subreddit), "/"), "r/")
+	if subreddit == "" {
+		return nil
+	}
+
+	// The listing only offers fixed time windows; take the smallest that
+	// covers the lookback and filter by creation time below.
+	window := "month"
+	switch {
+	case daysBack <= 1:
+		window = "day"
+	case daysBack <= 7:
+		window = "week"
+	}
+	params := url.Values{"t": {window}, "limit": {"100"}, "raw_json": {"1"}}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/r/%s/top.json?%s", redditBaseURL, url.PathEscape(subreddit), params.Encode()), nil)
+	if err != nil {
+		log.Printf("Reddit request error: %v", err)
+		return nil
+	}
+	// Reddit throttles requests with generic user agents.
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Printf("Reddit error for r/%s: %v", subreddit, err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Reddit HTTP error for r/%s: %d", subreddit, resp.StatusCode)
+		return nil
+	}
+
+	var listing struct {
+		Data struct {
+			Children []struct {
+				Data struct {
+					Title      string  `json:"title"`
+					URL        string  `json:"url"`
+					Permalink  string  `json:"permalink"`
+					Selftext   string  `json:"selftext"`
+					IsSelf     bool    `json:"is_self"`
+					Stickied   bool    `json:"stickied"`
+					Over18     bool    `json:"over_18"`
+					Score      int     `json:"score"`
+					CreatedUTC float64 `json:"created_utc"`
+					Subreddit  string  `json:"subreddit"`
+				} `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		log.Printf("Reddit decode error for r/%s: %v", subreddit, err)
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -daysBack)
+	var posts []RedditPost
+	for _, child := range listing.Data.Children {
+		p := child.Data
+		if p.Title == "" || p.Stickied || p.Over18 || p.Score < c.minUpvotes {
+			continue
+		}
+		created := time.Unix(int64(p.CreatedUTC), 0)
+		if created.Before(cutoff) {
+			continue
+		}
+
+		postURL, content := p.URL, ""
+		if p.IsSelf || postURL == "" || strings.HasPrefix(postURL, "/") {
+			postURL, content = redditBaseURL+p.Permalink, strings.TrimSpace(p.Selftext)
+		}
+		name := p.Subreddit
+		if name == "" {
+			name = subreddit
+		}
+
+		posts = append(posts, RedditPost{
+			URL:           postURL,
+			Title:         strings.TrimSpace(p.Title),
+			PublishedDate: created.UTC().Format("2006-01-02"),
+			Content:       content,
+			Source:        "r/" + name,
+			Score:         p.Score,
+		})
+	}
+
+	log.Printf("Fetched %d posts from r/%s with at least %d upvotes", len(posts), subreddit, c.minUpvotes)
+	return posts
+}
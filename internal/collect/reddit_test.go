@@ -0,0 +1,70 @@
+package collect
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedditTopPosts(t *testing.T) {
+	var query map[string][]string
+	c := NewRedditClient(50)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "www.reddit.com" || r.URL.Path != "/r/LocalLLaMA/top.json" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		serveFixture(t, w, "reddit_top.json", "application/json")
+	}))
+
+	posts := c.TopPosts(" /r/LocalLLaMA", 1)
+	day := time.Now().UTC().Add(-2 * time.Hour).Format("2006-01-02")
+	want := []RedditPost{
+		// A link post points at the page it links to.
+		{URL: "https://example.com/blog/model", Title: "A new open-weights coding model", PublishedDate: day, Source: "r/LocalLLaMA", Score: 512},
+		// A text post, or one linking within Reddit, points at its thread.
+		{URL: "https://www.reddit.com/r/LocalLLaMA/comments/b2/which_quant/", Title: "Which quantization do you run?", PublishedDate: day,
+			Content: "I have 24 GB of VRAM.\n\nWhat works for you?", Source: "r/LocalLLaMA", Score: 120},
+		{URL: "https://www.reddit.com/r/LocalLLaMA/comments/c4/benchmarks/", Title: "Crossposted benchmark results", PublishedDate: day, Source: "r/LocalLLaMA", Score: 80},
+		// The stickied, NSFW, low-scoring, old and untitled posts are left out.
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("expected %+v, got %+v", want, posts)
+	}
+	if got := query["t"]; !reflect.DeepEqual(got, []string{"day"}) {
+		t.Errorf("expected the day's top posts, got t=%v", got)
+	}
+	if got := query["limit"]; !reflect.DeepEqual(got, []string{"100"}) {
+		t.Errorf("expected a limit of 100 posts, got %v", got)
+	}
+
+	tests := []struct {
+		daysBack int
+		want     string
+	}{
+		{1, "day"},
+		{7, "week"},
+		{14, "month"},
+	}
+	for _, tt := range tests {
+		c.TopPosts("LocalLLaMA", tt.daysBack)
+		if got := query["t"]; len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%d days back: expected t=%s, got %v", tt.daysBack, tt.want, got)
+		}
+	}
+}
+
+func TestRedditErrors(t *testing.T) {
+	c := NewRedditClient(0)
+	c.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	}))
+	if posts := c.TopPosts("LocalLLaMA", 1); posts != nil {
+		t.Errorf("expected no posts on a 429, got %+v", posts)
+	}
+	if posts := c.TopPosts(" r/ ", 1); posts != nil {
+		t.Errorf("expected an empty subreddit skipped, got %+v", posts)
+	}
+}
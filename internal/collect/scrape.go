@@ -0,0 +1,127 @@
+package collect
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/htmltext"
+	"github.com/andybalholm/cascadia"
+	"github.com/araddon/dateparse"
+	"golang.org/x/net/html"
+)
+
+// anchorSelector finds the first link inside an element.
+var anchorSelector = cascadia.MustCompile("a[href]")
+
+// scrapeListing collects a site without a feed from its listing page: every
+// element matching the scrape config's item selector is an entry, with the
+// link, title and date found inside it by the other selectors. Without a
+// link selector an entry's link is the item itself, when it is a link, or
+// its first link; without a title selector the title is the link's text.
+// Entries have no content, which the fetch step fills in.
+func scrapeListing(client *http.Client, userAgent string, fc FeedConfig, cutoff time.Time) ([]FeedEntry, error) {
+	s := fc.Scrape
+	item, err := cascadia.Compile(s.Item)
+	if err != nil {
+		return nil, fmt.Errorf("item selector: %w", err)
+	}
+	var link, title, date cascadia.Selector
+	for _, sel := range []struct {
+		value  string
+		target *cascadia.Selector
+	}{{s.Link, &link}, {s.Title, &title}, {s.Date, &date}} {
+		if sel.value == "" {
+			continue
+		}
+		if *sel.target, err = cascadia.Compile(sel.value); err != nil {
+			return nil, fmt.Errorf("selector %q: %w", sel.value, err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", fc.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := cmp.Or(client, http.DefaultClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	doc, err := html.Parse(io.LimitReader(resp.Body, maxDiscoveryPage))
+	if err != nil {
+		return nil, err
+	}
+	base := resp.Request.URL
+
+	var entries []FeedEntry
+	seen := make(map[string]bool) // listings often link an entry twice, from its image and its headline
+	limit := cmp.Or(fc.MaxItems, defaultMaxItems)
+	for _, n := range cascadia.QueryAll(doc, item) {
+		if len(entries) >= limit {
+			break
+		}
+
+		a := n
+		if link != nil {
+			a = cascadia.Query(n, link)
+		}
+		if a != nil && htmltext.Attr(a, "href") == "" {
+			a = cascadia.Query(a, anchorSelector)
+		}
+		if a == nil {
+			continue
+		}
+		u, err := base.Parse(strings.TrimSpace(htmltext.Attr(a, "href")))
+		if err != nil || siteHost(u.String()) == "" || seen[u.String()] {
+			continue
+		}
+
+		text := a
+		if title != nil {
+			text = cascadia.Query(n, title)
+		}
+		var entryTitle string
+		if text != nil {
+			entryTitle = strings.Join(strings.Fields(htmltext.Text(text)), " ")
+		}
+		if entryTitle == "" {
+			entryTitle = strings.TrimSpace(htmltext.Attr(a, "title"))
+		}
+		if entryTitle == "" {
+			continue
+		}
+
+		var published string
+		if date != nil {
+			if d := cascadia.Query(n, date); d != nil {
+				value := cmp.Or(htmltext.Attr(d, "datetime"), htmltext.Attr(d, "content"), strings.TrimSpace(htmltext.Text(d)))
+				if t, err := dateparse.ParseAny(strings.Join(strings.Fields(value), " ")); err == nil {
+					published = t.Format("2006-01-02")
+				}
+			}
+		}
+		if !isWithinWindow(published, cutoff) {
+			continue
+		}
+
+		seen[u.String()] = true
+		entries = append(entries, FeedEntry{
+			URL:           u.String(),
+			Title:         entryTitle,
+			PublishedDate: published,
+			Source:        fc.SourceName(),
+			FeedURL:       fc.URL,
+			Category:      fc.Category,
+			Weight:        fc.Weight,
+		})
+	}
+	return entries, nil
+}
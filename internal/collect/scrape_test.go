@@ -0,0 +1,91 @@
+package collect
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+func TestScrapeListing(t *testing.T) {
+	client := fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/blog/" {
+			http.NotFound(w, r)
+			return
+		}
+		serveFixture(t, w, "scrape_listing.html", "text/html")
+	}))
+
+	tests := []struct {
+		name     string
+		url      string
+		scrape   config.Scrape
+		maxItems int
+		want     []string // URL and title of each entry
+		wantErr  bool
+	}{
+		{
+			// The featured copy of the first post is collected once, the old
+			// post is outside the window and the post without a link is
+			// skipped; the undated image-only post takes its link's title.
+			name:   "all selectors",
+			url:    "https://news.example/blog/",
+			scrape: config.Scrape{Item: "article.post", Link: "h2 a, a.card", Title: "h2.title", Date: "time, [itemprop=datePublished]"},
+			want: []string{
+				"https://news.example/blog/agents", "Agents in production",
+				"https://other.example/evals", "Evals, revisited",
+				"https://news.example/blog/image-only", "Image only post",
+			},
+		},
+		{
+			name:   "first link of the item",
+			url:    "https://news.example/blog/",
+			scrape: config.Scrape{Item: "section.latest article.post"},
+			want: []string{
+				"https://news.example/blog/agents", "Agents in production",
+				"https://other.example/evals", "Evals, revisited",
+				"https://news.example/blog/archive-post", "From the archive",
+				"https://news.example/blog/image-only", "Image only post",
+			},
+		},
+		{
+			name:   "the item is the link",
+			url:    "https://news.example/blog/",
+			scrape: config.Scrape{Item: "h2.title a"},
+			want: []string{
+				"https://news.example/blog/agents", "Agents in production",
+				"https://other.example/evals", "Evals, revisited",
+			},
+			maxItems: 2,
+		},
+		{
+			name:    "invalid selector",
+			url:     "https://news.example/blog/",
+			scrape:  config.Scrape{Item: "article.post", Title: "h2["},
+			wantErr: true,
+		},
+		{
+			name:    "missing page",
+			url:     "https://news.example/gone",
+			scrape:  config.Scrape{Item: "article.post"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		entries, err := ReadFeed(client, "Test/1.0", config.Feed{URL: tt.url, Name: "Example news", MaxItems: tt.maxItems, Scrape: &tt.scrape}, 7)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.URL, e.Title)
+			if e.Source != "Example news" || e.FeedURL != tt.url {
+				t.Errorf("%s: expected %s under the listing, got %+v", tt.name, e.URL, e)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package collect
+
+import (
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/htmltext"
+	"golang.org/x/net/html"
+)
+
+// maxSitemaps bounds the sitemaps read from one sitemap index, newest first.
+const maxSitemaps = 50
+
+// maxSitemapSize bounds one sitemap, uncompressed; the protocol allows 50 MB.
+const maxSitemapSize = 50 << 20
+
+// maxTitlePage bounds how much of a page is read to find its title.
+const maxTitlePage = 1 << 20
+
+// sitemapDoc is a sitemap or a sitemap index, with the titles and dates of
+// Google News sitemaps.
+type sitemapDoc struct {
+	URLs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+		News    struct {
+			Title           string `xml:"title"`
+			PublicationDate string `xml:"publication_date"`
+		} `xml:"news"`
+	} `xml:"url"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+// sitemapRef is a sitemap listed in a sitemap index.
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// readSitemap returns the pages listed in the sitemap at fc.URL, following
+// a sitemap index to the sitemaps it lists, newest first; pages without a
+// date come last. Pages outside the configured path are left out. An
+// entry's GUID is its URL, which tells the collector which pages are new;
+// its title, unless a news sitemap gives one, is left for the collector.
+func readSitemap(client *http.Client, userAgent string, fc FeedConfig) ([]FeedEntry, error) {
+	client = cmp.Or(client, http.DefaultClient)
+	doc, err := fetchSitemap(client, userAgent, fc.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []*sitemapDoc{doc}
+	if len(doc.Sitemaps) > 0 {
+		children := doc.Sitemaps
+		slices.SortStableFunc(children, func(a, b sitemapRef) int {
+			return strings.Compare(b.LastMod, a.LastMod)
+		})
+		for _, s := range children[:min(len(children), maxSitemaps)] {
+			child, err := fetchSitemap(client, userAgent, strings.TrimSpace(s.Loc))
+			if err != nil {
+				log.Printf("Failed to read sitemap %s: %v", s.Loc, err)
+				continue
+			}
+			docs = append(docs, child)
+		}
+		if len(docs) == 1 {
+			return nil, fmt.Errorf("none of the %d sitemaps in the index could be read", len(children))
+		}
+	}
+
+	var prefix string
+	if fc.Sitemap != nil {
+		prefix = fc.Sitemap.Path
+	}
+	var entries []FeedEntry
+	seen := make(map[string]bool)
+	for _, d := range docs {
+		for _, u := range d.URLs {
+			loc := strings.TrimSpace(u.Loc)
+			parsed, err := url.Parse(loc)
+			if err != nil || siteHost(loc) == "" || seen[loc] || !strings.HasPrefix(parsed.Path, prefix) {
+				continue
+			}
+			seen[loc] = true
+			entries = append(entries, FeedEntry{
+				URL:           loc,
+				Title:         strings.TrimSpace(u.News.Title),
+				PublishedDate: sitemapDate(cmp.Or(u.News.PublicationDate, u.LastMod)),
+				Source:        fc.SourceName(),
+				FeedURL:       fc.URL,
+				GUID:          loc,
+				Category:      fc.Category,
+				Weight:        fc.Weight,
+			})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b FeedEntry) int {
+		return strings.Compare(b.PublishedDate, a.PublishedDate)
+	})
+	return entries, nil
+}
+
+// fetchSitemap reads the sitemap at rawURL, gzip-compressed or not.
+func fetchSitemap(client *http.Client, userAgent, rawURL string) (*sitemapDoc, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(r, maxSitemapSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing sitemap: %w", err)
+	}
+	return &doc, nil
+}
+
+// sitemapDate returns the day of a W3C datetime such as 2026-02-06 or
+// 2026-02-06T09:30:00+00:00, or "" when it isn't one.
+func sitemapDate(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < 10 {
+		return ""
+	}
+	if _, err := time.Parse("2006-01-02", value[:10]); err != nil {
+		return ""
+	}
+	return value[:10]
+}
+
+// newSitemapPages replaces the pages of each sitemap among entries by
+// those added since it was last read, newest first and up to the feed's
+// max_items; the others wait for the next run. The first time a sitemap is
+// read, its pages are recorded as seen and only those dated within the
+// lookback window are kept, so a site's back catalogue isn't collected.
+// Pages kept without a title get the one of their page.
+func (c *Collector) newSitemapPages(entries []FeedEntry) []FeedEntry {
+	sitemaps := make(map[string]FeedConfig)
+	for _, fc := range c.feeds {
+		if fc.Sitemap != nil {
+			sitemaps[fc.URL] = fc
+		}
+	}
+	if len(sitemaps) == 0 {
+		return entries
+	}
+
+	var kept []FeedEntry
+	var order []string
+	pages := make(map[string][]FeedEntry)
+	for _, e := range entries {
+		if _, ok := sitemaps[e.FeedURL]; !ok {
+			kept = append(kept, e)
+			continue
+		}
+		if pages[e.FeedURL] == nil {
+			order = append(order, e.FeedURL)
+		}
+		pages[e.FeedURL] = append(pages[e.FeedURL], e)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -c.daysBack)
+	for _, feedURL := range order {
+		fc := sitemaps[feedURL]
+		read, err := c.db.HasFeedItems(feedURL)
+		if err != nil {
+			log.Printf("Error checking sitemap %s: %v", feedURL, err)
+			continue
+		}
+
+		limit := cmp.Or(fc.MaxItems, defaultMaxItems)
+		var fresh []FeedEntry
+		var existing []string
+		for _, p := range pages[feedURL] {
+			switch {
+			case !read:
+				if p.PublishedDate == "" || !isWithinWindow(p.PublishedDate, cutoff) || len(fresh) >= limit {
+					existing = append(existing, p.GUID)
+					continue
+				}
+			case len(fresh) >= limit:
+				continue
+			default:
+				if seen, _ := c.db.HasFeedItem(feedURL, p.GUID); seen {
+					continue
+				}
+			}
+			if p.Title == "" {
+				p.Title = pageTitle(c.feedParser.client, c.feedParser.agent, p.URL)
+			}
+			fresh = append(fresh, p)
+		}
+
+		if !read {
+			if err := c.db.AddFeedItems(feedURL, existing); err != nil {
+				log.Printf("Error recording the pages of sitemap %s: %v", feedURL, err)
+				continue
+			}
+			log.Printf("First read of sitemap %s: %d existing pages recorded", fc.SourceName(), len(existing))
+		}
+		log.Printf("Found %d new pages in sitemap %s", len(fresh), fc.SourceName())
+		kept = append(kept, fresh...)
+	}
+	return kept
+}
+
+// pageTitle returns the title of the page at rawURL: its og:title, or its
+// <title>, or else one made from the last segment of its path.
+func pageTitle(client *http.Client, userAgent, rawURL string) string {
+	if title := fetchTitle(cmp.Or(client, http.DefaultClient), userAgent, rawURL); title != "" {
+		return title
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	slug := strings.TrimSuffix(path.Base(strings.TrimSuffix(u.Path, "/")), path.Ext(u.Path))
+	slug = strings.Join(strings.FieldsFunc(slug, func(r rune) bool { return r == '-' || r == '_' }), " ")
+	if slug == "" || slug == "." || slug == "/" {
+		return u.Hostname()
+	}
+	return strings.ToUpper(slug[:1]) + slug[1:]
+}
+
+// fetchTitle reads the title from the head of the page at rawURL, or
+// returns "" when it can't.
+func fetchTitle(client *http.Client, userAgent, rawURL string) string {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	doc, err := html.Parse(io.LimitReader(resp.Body, maxTitlePage))
+	if err != nil {
+		return ""
+	}
+
+	var og, title string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "meta" && htmltext.Attr(n, "property") == "og:title":
+				og = cmp.Or(og, strings.TrimSpace(htmltext.Attr(n, "content")))
+			case n.Data == "title":
+				title = cmp.Or(title, strings.Join(strings.Fields(htmltext.Text(n)), " "))
+			case n.Data == "body":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return cmp.Or(og, title)
+}
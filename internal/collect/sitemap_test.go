@@ -0,0 +1,112 @@
+package collect
+
+import (
+	"compress/gzip"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+func TestReadSitemapIndex(t *testing.T) {
+	var requested []string
+	client := fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if ua := r.Header.Get("User-Agent"); ua != "Test/1.0" {
+			t.Errorf("expected %s asked for with the User-Agent, got %q", r.URL, ua)
+		}
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			serveFixture(t, w, "sitemap_index.xml", "application/xml")
+		case "/sitemap-news.xml":
+			serveFixture(t, w, "sitemap_news.xml", "application/xml")
+		case "/sitemap-pages.xml.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write(fixture(t, "sitemap_pages.xml"))
+			gz.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	entries, err := ReadFeed(client, "Test/1.0", config.Feed{URL: "https://site.example/sitemap.xml", Sitemap: &config.Sitemap{Path: "/blog/"}}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sitemaps of the index are read newest first, and the one that
+	// fails is skipped.
+	if want := []string{"/sitemap.xml", "/sitemap-news.xml", "/sitemap-gone.xml", "/sitemap-pages.xml.gz"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("expected sitemaps read in order %v, got %v", want, requested)
+	}
+
+	type entry struct{ URL, Title, PublishedDate string }
+	var got []entry
+	for _, e := range entries {
+		got = append(got, entry{e.URL, e.Title, e.PublishedDate})
+		if e.GUID != e.URL || e.Source != "Site" {
+			t.Errorf("expected %s under its URL and the site's name, got %+v", e.URL, e)
+		}
+	}
+	// Newest first and undated last; the page listed twice keeps its news
+	// title and date, and pages outside /blog/ are left out.
+	want := []entry{
+		{"https://site.example/blog/tooling", "", "2026-02-08"},
+		{"https://site.example/blog/agents", "Agents in production", "2026-02-06"},
+		{"https://site.example/blog/evals-revisited", "", "2026-02-05"},
+		{"https://site.example/blog/about", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	if _, err := ReadFeed(client, "Test/1.0", config.Feed{URL: "https://site.example/missing.xml", Sitemap: &config.Sitemap{}}, 7); err == nil {
+		t.Error("expected a missing sitemap to fail")
+	}
+}
+
+func TestSitemapDate(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"2026-02-06", "2026-02-06"},
+		{" 2026-02-06T09:30:00+01:00 ", "2026-02-06"},
+		{"2026-13-01", ""},
+		{"Feb 6, 2026", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := sitemapDate(tt.value); got != tt.want {
+			t.Errorf("sitemapDate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	client := fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/og":
+			w.Write([]byte(`<html><head><title>Site | Post</title><meta property="og:title" content=" The post "></head><body></body></html>`))
+		case "/title":
+			w.Write([]byte(`<html><head><title>
+				A post
+				with a title </title></head><body><title>Not this one</title></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	tests := []struct {
+		url, want string
+	}{
+		{"https://site.example/og", "The post"},
+		{"https://site.example/title", "A post with a title"},
+		{"https://site.example/blog/evals-revisited_again.html", "Evals revisited again"},
+		{"https://site.example/", "site.example"},
+	}
+	for _, tt := range tests {
+		if got := pageTitle(client, "Test/1.0", tt.url); got != tt.want {
+			t.Errorf("pageTitle(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package collect
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// SocialPost is a thread from a Mastodon or Bluesky timeline, collapsed into
+// one article.
+type SocialPost struct {
+	URL           string
+	Title         string
+	PublishedDate string
+	Content       string
+	Source        string
+}
+
+// socialStatus is a single post of a timeline, reduced to what collapsing
+// threads and picking their link needs.
+type socialStatus struct {
+	id        string
+	parentID  string // post it replies to, if any
+	author    string
+	url       string
+	text      string
+	links     []string // outbound links, link card first
+	cardTitle string   // title of the link card, if any
+	created   time.Time
+}
+
+// maxSocialTitle is the longest title, in runes, taken from a post's text.
+const maxSocialTitle = 120
+
+// socialPosts collapses statuses into threads and turns the threads started
+// in the last daysBack days into posts under source. A thread is a post and
+// the replies its author made to it; replies to someone else, or to a post
+// outside the timeline, are conversations rather than announcements and are
+// dropped. A thread linking to a page is collected under the first link, so
+// it merges with the same article from a feed and the fetch step reads the
+// page; a thread without links is collected under its first post, with the
+// thread's text as content.
+func socialPosts(statuses []socialStatus, source string, daysBack int) []SocialPost {
+	slices.SortStableFunc(statuses, func(a, b socialStatus) int { return a.created.Compare(b.created) })
+
+	byID := make(map[string]socialStatus, len(statuses))
+	for _, s := range statuses {
+		byID[s.id] = s
+	}
+	rootOf := func(s socialStatus) (string, bool) {
+		for range len(statuses) {
+			if s.parentID == "" {
+				return s.id, true
+			}
+			parent, ok := byID[s.parentID]
+			if !ok || parent.author != s.author {
+				return "", false
+			}
+			s = parent
+		}
+		return "", false
+	}
+
+	var roots []string
+	threads := make(map[string][]socialStatus)
+	for _, s := range statuses {
+		root, ok := rootOf(s)
+		if !ok {
+			continue
+		}
+		if _, seen := threads[root]; !seen {
+			roots = append(roots, root)
+		}
+		threads[root] = append(threads[root], s)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -daysBack)
+	var posts []SocialPost
+	for _, root := range roots {
+		thread := threads[root]
+		first := thread[0]
+		if first.created.Before(cutoff) || first.url == "" {
+			continue
+		}
+
+		var texts []string
+		var link, title string
+		for _, s := range thread {
+			if s.text != "" {
+				texts = append(texts, s.text)
+			}
+			if link == "" && len(s.links) > 0 {
+				link, title = s.links[0], s.cardTitle
+			}
+		}
+		text := strings.Join(texts, "\n\n")
+		if text == "" && link == "" {
+			continue
+		}
+		if title == "" {
+			title = socialTitle(text)
+		}
+		if title == "" {
+			title = link
+		}
+
+		post := SocialPost{
+			URL:           link,
+			Title:         title,
+			PublishedDate: first.created.UTC().Format("2006-01-02"),
+			Source:        source,
+		}
+		if link == "" {
+			post.URL, post.Content = first.url, text
+		}
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+// socialTitle returns the first line of a post's text, cut at a word
+// boundary when it is longer than a title should be.
+func socialTitle(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	line = strings.Join(strings.Fields(line), " ")
+	runes := []rune(line)
+	if len(runes) <= maxSocialTitle {
+		return line
+	}
+	cut := string(runes[:maxSocialTitle])
+	if i := strings.LastIndex(cut, " "); i > maxSocialTitle/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+// outboundURL reports whether a link in a post leads to a page worth
+// collecting rather than a profile, a hashtag or another social site.
+func outboundURL(rawURL, home string) bool {
+	host := siteHost(rawURL)
+	return host != "" && host != home && !matchesHost(host, linkHosts)
+}
@@ -0,0 +1,219 @@
+package collect
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMastodonAccountPosts(t *testing.T) {
+	var statusQuery string
+	m := NewMastodonClient("mastodon.social")
+	m.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "mastodon.example" {
+			t.Errorf("expected the account read from its own server, got %s", r.Host)
+		}
+		switch r.URL.Path {
+		case "/api/v1/accounts/lookup":
+			if acct := r.URL.Query().Get("acct"); acct != "alice" {
+				t.Errorf("expected a lookup of alice, got %q", acct)
+			}
+			w.Write([]byte(`{"id": "A"}`))
+		case "/api/v1/accounts/A/statuses":
+			statusQuery = r.URL.RawQuery
+			serveFixture(t, w, "mastodon_statuses.json", "application/json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	posts := m.AccountPosts("@alice@mastodon.example", 1)
+	day := time.Now().UTC().Add(-2 * time.Hour).Format("2006-01-02")
+	want := []SocialPost{
+		// The thread of 101 and its author's reply 102, under the link card;
+		// Bob's reply, the boost, the old post and the reply to a post not
+		// in the timeline are left out.
+		{URL: "https://example.com/blog/model", Title: "Our new coding model", PublishedDate: day, Source: "@alice@mastodon.example"},
+		// Without outbound links, under the post itself with its text.
+		{URL: "https://mastodon.example/@alice/105", Title: "Thinking about how we evaluate agents.", PublishedDate: day,
+			Source:  "@alice@mastodon.example",
+			Content: "Thinking about how we evaluate agents.\nBenchmarks reward the wrong things.\nMore soon, see twitter.com/alice"},
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("expected %+v, got %+v", want, posts)
+	}
+	if !strings.Contains(statusQuery, "exclude_reblogs=true") {
+		t.Errorf("expected boosts excluded from the request, got %q", statusQuery)
+	}
+
+	if posts := m.AccountPosts("@", 1); posts != nil {
+		t.Errorf("expected an invalid account skipped, got %+v", posts)
+	}
+}
+
+func TestMastodonHashtagPosts(t *testing.T) {
+	m := NewMastodonClient("https://mastodon.example/")
+	m.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "mastodon.example" || r.URL.Path != "/api/v1/timelines/tag/llm" {
+			http.NotFound(w, r)
+			return
+		}
+		serveFixture(t, w, "mastodon_statuses.json", "application/json")
+	}))
+
+	posts := m.HashtagPosts("#llm", 1)
+	if len(posts) != 2 || posts[0].Source != "#llm on Mastodon" || posts[0].URL != "https://example.com/blog/model" {
+		t.Errorf("expected the tag's two threads from the instance, got %+v", posts)
+	}
+	if posts := NewMastodonClient("").HashtagPosts("llm", 1); posts != nil {
+		t.Errorf("expected no hashtags read without an instance, got %+v", posts)
+	}
+}
+
+func TestMastodonContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantText  string
+		wantLinks []string
+	}{
+		{
+			name:      "paragraphs and line breaks",
+			content:   "<p>First   line<br>second line</p><p>Third</p>",
+			wantText:  "First line\nsecond line\nThird",
+			wantLinks: nil,
+		},
+		{
+			name:      "outbound link",
+			content:   `<p>Read <a href=" https://example.com/post ">example.com/post</a></p>`,
+			wantText:  "Read example.com/post",
+			wantLinks: []string{"https://example.com/post"},
+		},
+		{
+			name:      "mentions and hashtags",
+			content:   `<p><a class="u-url mention" href="https://other.example/@bob">@bob</a> <a class="mention hashtag" href="https://other.example/tags/ai">#ai</a></p>`,
+			wantText:  "@bob #ai",
+			wantLinks: nil,
+		},
+		{
+			name:      "links to the server, social sites and mail",
+			content:   `<p><a href="https://mastodon.example/@carol/1">a post</a> <a href="https://www.linkedin.com/in/alice">me</a> <a href="mailto:a@example.com">mail</a></p>`,
+			wantText:  "a post me mail",
+			wantLinks: nil,
+		},
+	}
+	for _, tt := range tests {
+		text, links := mastodonContent(tt.content, "mastodon.example")
+		if text != tt.wantText {
+			t.Errorf("%s: expected text %q, got %q", tt.name, tt.wantText, text)
+		}
+		if !reflect.DeepEqual(links, tt.wantLinks) {
+			t.Errorf("%s: expected links %v, got %v", tt.name, tt.wantLinks, links)
+		}
+	}
+}
+
+func TestBlueskyAccountPosts(t *testing.T) {
+	b := NewBlueskyClient()
+	b.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "public.api.bsky.app" || r.URL.Path != "/xrpc/app.bsky.feed.getAuthorFeed" {
+			http.NotFound(w, r)
+			return
+		}
+		if actor := r.URL.Query().Get("actor"); actor != "alice.bsky.social" {
+			t.Errorf("expected alice's feed, got %q", actor)
+		}
+		serveFixture(t, w, "bluesky_feed.json", "application/json")
+	}))
+
+	posts := b.AccountPosts("@alice.bsky.social", 1)
+	day := time.Now().UTC().Add(-2 * time.Hour).Format("2006-01-02")
+	want := []SocialPost{
+		// The paper thread, collected under its link card; Bob's reply, the
+		// repost and the old post are left out.
+		{URL: "https://arxiv.org/abs/2602.01234", Title: "Tool Use at Scale", PublishedDate: day, Source: "@alice.bsky.social"},
+		// Its only link is a Bluesky profile, so it is collected as the post.
+		{URL: "https://bsky.app/profile/alice.bsky.social/post/3knote", Title: "Shipping evals before features, again",
+			PublishedDate: day, Source: "@alice.bsky.social", Content: "Shipping evals before features, again"},
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("expected %+v, got %+v", want, posts)
+	}
+}
+
+func TestBlueskyStatuses(t *testing.T) {
+	var feed blueskyFeed
+	if err := json.Unmarshal(fixture(t, "bluesky_feed.json"), &feed); err != nil {
+		t.Fatal(err)
+	}
+	statuses := blueskyStatuses(feed)
+	if len(statuses) != 5 {
+		t.Fatalf("expected the repost left out of 6 posts, got %d", len(statuses))
+	}
+	code := statuses[1]
+	if code.parentID != "at://did:plc:alice/app.bsky.feed.post/3kpaper" || code.author != "did:plc:alice" {
+		t.Errorf("expected the reply linked to its parent, got %+v", code)
+	}
+	if !reflect.DeepEqual(code.links, []string{"https://github.com/alice/tools"}) {
+		t.Errorf("expected only the link facet, got %v", code.links)
+	}
+	if paper := statuses[0]; !reflect.DeepEqual(paper.links, []string{"https://arxiv.org/abs/2602.01234"}) || paper.cardTitle != "Tool Use at Scale" {
+		t.Errorf("expected the link card first, got %+v", paper)
+	}
+	if note := statuses[3]; note.links != nil {
+		t.Errorf("expected links to Bluesky left out, got %v", note.links)
+	}
+}
+
+func TestBlueskyFeedPosts(t *testing.T) {
+	var feedParam string
+	b := NewBlueskyClient()
+	b.client = fixtureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.identity.resolveHandle":
+			w.Write([]byte(`{"did": "did:plc:alice"}`))
+		case "/xrpc/app.bsky.feed.getFeed":
+			feedParam = r.URL.Query().Get("feed")
+			serveFixture(t, w, "bluesky_feed.json", "application/json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	posts := b.FeedPosts("https://bsky.app/profile/alice.bsky.social/feed/ai-news", 1)
+	if feedParam != "at://did:plc:alice/app.bsky.feed.generator/ai-news" {
+		t.Errorf("expected the feed's handle resolved to its DID, got %q", feedParam)
+	}
+	if len(posts) != 2 || posts[0].Source != "Bluesky feed ai-news" {
+		t.Errorf("expected the feed's two threads, got %+v", posts)
+	}
+
+	for _, ref := range []string{"https://bsky.app/profile/alice.bsky.social", "https://example.com/profile/a/feed/b"} {
+		if _, err := b.feedURI(ref); err == nil {
+			t.Errorf("expected %s rejected as a feed", ref)
+		}
+	}
+	if uri, _ := b.feedURI("at://did:plc:x/app.bsky.feed.generator/y"); uri != "at://did:plc:x/app.bsky.feed.generator/y" {
+		t.Errorf("expected an at:// URI used as it is, got %q", uri)
+	}
+}
+
+func TestSocialTitle(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	tests := []struct {
+		text, want string
+	}{
+		{"  A short   announcement\nwith more below", "A short announcement"},
+		{long, strings.TrimSpace(strings.Repeat("word ", 24)) + "…"},
+		{strings.Repeat("x", 130), strings.Repeat("x", 120) + "…"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := socialTitle(tt.text); got != tt.want {
+			t.Errorf("socialTitle(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package collect
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// vendorHosts are sites where AI companies publish about their own products.
+var vendorHosts = []string{
+	"openai.com", "anthropic.com", "blog.google", "deepmind.google", "ai.google",
+	"research.google", "ai.meta.com", "engineering.fb.com", "microsoft.com",
+	"aws.amazon.com", "nvidia.com", "apple.com", "huggingface.co", "mistral.ai",
+	"cohere.com", "x.ai", "github.blog",
+}
+
+// academicHosts are preprint servers, publishers and proceedings.
+var academicHosts = []string{
+	"arxiv.org", "openreview.net", "aclanthology.org", "dl.acm.org", "ieeexplore.ieee.org",
+	"nature.com", "science.org", "paperswithcode.com", "proceedings.mlr.press",
+	"neurips.cc", "semanticscholar.org",
+}
+
+// newsHosts are newspapers, magazines and the tech press.
+var newsHosts = []string{
+	"theguardian.com", "arstechnica.com", "technologyreview.com", "theverge.com",
+	"techcrunch.com", "venturebeat.com", "wired.com", "bbc.co.uk", "bbc.com",
+	"nytimes.com", "washingtonpost.com", "nzz.ch", "indiatimes.com", "livemint.com",
+	"thehindubusinessline.com", "inc42.com", "reuters.com", "bloomberg.com",
+	"theregister.com", "zdnet.com", "cnbc.com", "ft.com", "wsj.com", "axios.com",
+	"theinformation.com", "harvardbusiness.org", "hbr.org",
+}
+
+// SourceType is the configured type of the feed, or one inferred from its
+// URL, with personal and unknown sites counted as blogs.
+func (fc FeedConfig) SourceType() string {
+	if fc.Type != "" && !fc.IsAggregator() {
+		for _, t := range database.SourceTypes {
+			if fc.Type == t {
+				return t
+			}
+		}
+		log.Printf("Unknown source type %q for feed %s; inferring it from the URL", fc.Type, fc.URL)
+	}
+	return InferSourceType(fc.URL, database.SourceBlog)
+}
+
+// InferSourceType guesses a source's type from the host of rawURL, returning
+// fallback when the host is not recognised.
+func InferSourceType(rawURL, fallback string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fallback
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch {
+	case matchesHost(host, vendorHosts):
+		return database.SourceVendor
+	case matchesHost(host, academicHosts), strings.HasSuffix(host, ".edu"), strings.Contains(host, ".ac."):
+		return database.SourceAcademic
+	case matchesHost(host, newsHosts):
+		return database.SourceNews
+	}
+	return fallback
+}
+
+func matchesHost(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
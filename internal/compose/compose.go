@@ -1,24 +1,31 @@
 package compose
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
 	"github.com/TobiSchelling/AICrawler/internal/llm"
 )
 
-const brieflyNotedLabel = "Briefly Noted"
+const (
+	brieflyNotedLabel      = "Briefly Noted"
+	otherDevelopmentsLabel = "Other developments"
+	laterTodayLabel        = "Later today"
+)
 
-const composePrompt = `You are writing the TL;DR for a daily AI news briefing aimed at software practitioners.
+const composePrompt = `You are writing the TL;DR for a daily AI news briefing aimed at %s.
 
 Here are today's storylines and their narratives:
 
 %s
-
-Write a TL;DR section (3-5 bullet points) that captures the most important takeaways from ALL storylines. Each bullet should be one sentence that tells the reader what happened and why it matters.
+%s
+Write a TL;DR section (3-5 bullet points) that captures the most important takeaways from ALL storylines. Each bullet should be one sentence that tells the reader what happened and why it matters.%s
 
 Respond with ONLY this JSON:
 {
@@ -29,19 +36,135 @@ Respond with ONLY this JSON:
     ]
 }`
 
+const eveningPrompt = `You are writing the TL;DR for the evening update of a daily AI news briefing aimed at %s. The morning edition already covered earlier news; these articles arrived since then:
+
+%s
+
+Write a TL;DR section (2-4 bullet points) that captures the most important new developments. Each bullet should be one sentence that tells the reader what happened and why it matters.%s
+
+Respond with ONLY this JSON:
+{
+    "tldr_bullets": [
+        "First key takeaway",
+        "Second key takeaway"
+    ]
+}`
+
+const highlightPrompt = `You are writing a short highlight section of a shared AI news briefing, addressed to one team of readers: "%s".
+
+This team cares about:
+%s
+
+These storylines from today's briefing match their interests:
+
+%s
+
+Write 2-3 bullet points telling this team what in today's briefing matters to them and why. Each bullet should be one sentence.%s
+
+Respond with ONLY this JSON:
+{
+    "highlight_bullets": [
+        "First highlight",
+        "Second highlight"
+    ]
+}`
+
+// tldrSchema and highlightSchema describe the responses the prompts above ask
+// for; the evening prompt shares the TL;DR shape.
+var (
+	tldrSchema      = llm.Schema{Name: "tldr", Definition: llm.Object(map[string]any{"tldr_bullets": llm.Array(llm.String())})}
+	highlightSchema = llm.Schema{Name: "highlights", Definition: llm.Object(map[string]any{"highlight_bullets": llm.Array(llm.String())})}
+)
+
+// maxHighlightStorylines caps how many storylines feed one profile's highlights.
+const maxHighlightStorylines = 3
+
+// Options controls optional composition behaviour.
+type Options struct {
+	// TeamDigest adds a highlight section per reader profile, selected from
+	// the shared storylines by each profile's priorities.
+	TeamDigest bool
+
+	// PolicyWatch appends a section with the latest known status of each
+	// regulation in Regulations, plus any other regulation the period's
+	// articles reported on.
+	PolicyWatch bool
+	Regulations []string
+
+	// Language, when set, is the language the TL;DR and highlights are
+	// written in; the fixed section headings are translated into it.
+	Language string
+
+	// MaxTokens limits the response to the TL;DR prompts of both editions;
+	// 0 means defaultMaxTokens.
+	MaxTokens int
+
+	// MaxStorylines caps the storylines that get a section of their own.
+	// Lower-ranked ones are listed in a compact "Other developments"
+	// section and left out of the TL;DR; 0 means no cap.
+	MaxStorylines int
+
+	// Audience is who the briefing is for; empty means llm.DefaultAudience.
+	Audience string
+}
+
+// defaultMaxTokens fits the TL;DR bullets.
+const defaultMaxTokens = 512
+
+// fixedHeadings are the section headings compose writes itself, as they
+// appear in the body markdown.
+var fixedHeadings = []struct{ text, format string }{
+	{brieflyNotedLabel, "## %s\n"},
+	{otherDevelopmentsLabel, "## %s\n"},
+	{"Upcoming", "## %s\n"},
+	{"Policy watch", "## %s\n"},
+	{"Since this morning", "## %s\n"},
+	{laterTodayLabel, "## %s\n"},
+	{"Sources", "**%s:**"},
+	{"Additional coverage", "**%s:**"},
+}
+
 // Composer composes the final briefing from storyline narratives.
 type Composer struct {
 	db       *database.DB
 	provider llm.Provider
+	opts     Options
 }
 
 // NewComposer creates a new briefing composer.
-func NewComposer(db *database.DB, provider llm.Provider) *Composer {
-	return &Composer{db: db, provider: provider}
+func NewComposer(db *database.DB, provider llm.Provider, opts Options) *Composer {
+	return &Composer{db: db, provider: provider, opts: opts}
 }
 
 // ComposeBriefing composes a complete briefing for a period.
 func (c *Composer) ComposeBriefing(ctx context.Context, periodID string) (*database.Briefing, error) {
+	return c.compose(ctx, periodID, nil)
+}
+
+// ComposeUpdate brings the morning briefing of a period up to date with its
+// current narratives and the relevant articles no storyline took, listed
+// under "Later today". The TL;DR, team highlights and quality note of the
+// briefing are kept; translating headings is the only LLM work left.
+func (c *Composer) ComposeUpdate(ctx context.Context, periodID string) (*database.Briefing, error) {
+	morning, err := c.db.GetBriefing(periodID)
+	if err != nil {
+		return nil, err
+	}
+	if morning == nil {
+		return nil, errNoMorning(periodID)
+	}
+	return c.compose(ctx, periodID, morning)
+}
+
+// errNoMorning is the error of the editions that build on a period's
+// morning briefing when it has none.
+func errNoMorning(periodID string) error {
+	return fmt.Errorf("%w; run the full pipeline first", &database.BriefingNotFoundError{PeriodID: periodID, Edition: database.EditionMorning})
+}
+
+// compose stores the morning briefing of a period. It writes a new TL;DR
+// and highlights unless it updates previous, the briefing composed before.
+func (c *Composer) compose(ctx context.Context, periodID string, previous *database.Briefing) (*database.Briefing, error) {
 	narratives, err := c.db.GetNarrativesForPeriod(periodID)
 	if err != nil {
 		return nil, err
@@ -51,22 +174,62 @@ func (c *Composer) ComposeBriefing(ctx context.Context, periodID string) (*datab
 		return nil, err
 	}
 
-	if len(narratives) == 0 {
+	if len(narratives) == 0 && previous == nil {
 		log.Printf("No narratives found for %s", periodID)
 		return c.storeEmptyBriefing(periodID)
 	}
 
-	tldr := c.generateTLDR(ctx, narratives)
-	body := assembleBody(narratives)
+	narratives, overflow := capStorylines(narratives, c.opts.MaxStorylines)
+	var tldr string
+	if previous != nil {
+		tldr = previous.TLDR
+	} else {
+		recent := c.previousTLDR(periodID)
+		tldr = dropRepeats(c.generateTLDR(ctx, narratives, recent), recent)
+	}
+	var sections []string
+	if body := assembleBody(narratives, overflow); body != "" {
+		sections = append(sections, body)
+	}
+	later, err := c.db.GetUnclusteredArticles(periodID)
+	if err != nil {
+		return nil, err
+	}
+	if len(later) > 0 {
+		sections = append(sections, c.laterTodaySection(later))
+	}
+	from, to := database.UpcomingWindow(periodID, database.UpcomingDays)
+	if events, _ := c.db.GetEventsBetween(from, to, database.MaxUpcomingEvents); len(events) > 0 {
+		sections = append(sections, upcomingSection(events))
+	}
+	if c.opts.PolicyWatch {
+		if watch, err := c.db.PolicyWatch(c.opts.Regulations, periodID); err != nil {
+			log.Printf("Error building policy watch: %v", err)
+		} else if len(watch) > 0 {
+			sections = append(sections, policyWatchSection(watch))
+		}
+	}
 
-	var articleCount int
+	body := c.localizeHeadings(ctx, strings.Join(sections, "\n\n---\n\n"))
+
+	articleCount := len(later)
 	for _, s := range storylines {
 		articleCount += s.ArticleCount
 	}
 
 	c.db.InsertBriefing(periodID, tldr, body, len(storylines), articleCount)
+	if previous != nil {
+		c.db.SetBriefingQualityNote(periodID, database.EditionMorning, previous.QualityNote)
+		log.Printf("Briefing updated for %s: %d storylines, %d articles later today", periodID, len(storylines), len(later))
+		return c.db.GetBriefing(periodID)
+	}
+
 	c.db.InsertReport(periodID, articleCount, len(storylines))
 
+	if c.opts.TeamDigest {
+		c.composeHighlights(ctx, periodID, narratives)
+	}
+
 	briefing, err := c.db.GetBriefing(periodID)
 	if err != nil {
 		return nil, err
@@ -75,7 +238,99 @@ func (c *Composer) ComposeBriefing(ctx context.Context, periodID string) (*datab
 	return briefing, nil
 }
 
-func (c *Composer) generateTLDR(ctx context.Context, narratives []database.StorylineNarrative) string {
+// ComposeEveningEdition composes the evening delta edition for a period,
+// covering only the relevant articles the morning edition doesn't.
+func (c *Composer) ComposeEveningEdition(ctx context.Context, periodID string) (*database.Briefing, error) {
+	morning, err := c.db.GetBriefing(periodID)
+	if err != nil {
+		return nil, err
+	}
+	if morning == nil {
+		return nil, errNoMorning(periodID)
+	}
+
+	articles, err := c.eveningArticles(morning)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(articles) == 0 {
+		log.Printf("No new articles since the morning edition of %s", periodID)
+		c.db.InsertBriefingEdition(periodID, database.EditionEvening,
+			"- Nothing new since the morning edition.", "No new articles were collected since this morning.", 0, 0)
+		return c.db.GetBriefingEdition(periodID, database.EditionEvening)
+	}
+
+	var bullets, promptParts, fallback []string
+	for _, a := range articles {
+		source := "Unknown"
+		if a.Source != nil {
+			source = *a.Source
+		}
+		point := ""
+		triage, _ := c.db.GetTriage(a.ID)
+		if triage != nil && len(triage.KeyPoints) > 0 {
+			point = triage.KeyPoints[0]
+		}
+
+		bullet := fmt.Sprintf("- [%s](%s) (%s)", a.Title, a.URL, source)
+		if point != "" {
+			bullet += ": " + point
+		}
+		bullets = append(bullets, bullet)
+		promptParts = append(promptParts, fmt.Sprintf("- %s (%s): %s", a.Title, source, point))
+		if len(fallback) < 4 {
+			fallback = append(fallback, "- "+a.Title)
+		}
+	}
+
+	tldr := strings.Join(fallback, "\n")
+	if c.provider != nil {
+		prompt := fmt.Sprintf(eveningPrompt, cmp.Or(c.opts.Audience, llm.DefaultAudience), strings.Join(promptParts, "\n"), llm.LanguageInstruction(c.opts.Language))
+		if responseText, err := c.provider.Generate(llm.WithSchema(ctx, tldrSchema), prompt, cmp.Or(c.opts.MaxTokens, defaultMaxTokens)); err == nil && responseText != "" {
+			tldr = dropRepeats(parseTLDR(responseText), morning.TLDR)
+		}
+	}
+
+	body := c.localizeHeadings(ctx, "## Since this morning\n\n"+strings.Join(bullets, "\n"))
+	c.db.InsertBriefingEdition(periodID, database.EditionEvening, tldr, body, 0, len(articles))
+
+	log.Printf("Evening edition composed for %s: %d new articles", periodID, len(articles))
+	return c.db.GetBriefingEdition(periodID, database.EditionEvening)
+}
+
+// EveningArticles returns the relevant articles of a period that its
+// evening edition covers, or nil when the period has no morning briefing.
+func (c *Composer) EveningArticles(periodID string) ([]database.Article, error) {
+	morning, err := c.db.GetBriefing(periodID)
+	if err != nil || morning == nil {
+		return nil, err
+	}
+	return c.eveningArticles(morning)
+}
+
+// eveningArticles returns the relevant articles of the morning briefing's
+// period in none of its storylines, which the highlights draw from too,
+// and not listed under "Later today" by an update. What the articles
+// referenced decides, not when they were collected: an article collected
+// before the morning edition but triaged after it is in neither.
+func (c *Composer) eveningArticles(morning *database.Briefing) ([]database.Article, error) {
+	unclustered, err := c.db.GetUnclusteredArticles(morning.PeriodID)
+	if err != nil {
+		return nil, err
+	}
+	var articles []database.Article
+	for _, a := range unclustered {
+		if !strings.Contains(morning.BodyMarkdown, "]("+a.URL+")") {
+			articles = append(articles, a)
+		}
+	}
+	return articles, nil
+}
+
+// generateTLDR writes the TL;DR of narratives, told what the previous
+// briefing's TL;DR said so it doesn't say it again.
+func (c *Composer) generateTLDR(ctx context.Context, narratives []database.StorylineNarrative, previous string) string {
 	if c.provider == nil {
 		return fallbackTLDR(narratives)
 	}
@@ -87,15 +342,126 @@ func (c *Composer) generateTLDR(ctx context.Context, narratives []database.Story
 		}
 	}
 
-	prompt := fmt.Sprintf(composePrompt, strings.Join(parts, "\n\n"))
-	responseText, err := c.provider.Generate(ctx, prompt, 512)
+	prompt := fmt.Sprintf(composePrompt, cmp.Or(c.opts.Audience, llm.DefaultAudience), strings.Join(parts, "\n\n"), previousSection(previous), llm.LanguageInstruction(c.opts.Language))
+	responseText, err := c.provider.Generate(llm.WithSchema(ctx, tldrSchema), prompt, cmp.Or(c.opts.MaxTokens, defaultMaxTokens))
 	if err != nil || responseText == "" {
 		return fallbackTLDR(narratives)
 	}
 
+	return parseTLDR(responseText)
+}
+
+// composeHighlights writes one highlight section per reader profile, picking
+// the shared storylines that best match the profile's own priorities.
+// Profiles with no priorities or no matching storylines get no section.
+func (c *Composer) composeHighlights(ctx context.Context, periodID string, narratives []database.StorylineNarrative) {
+	profiles, err := c.db.GetProfiles()
+	if err != nil {
+		log.Printf("Error loading reader profiles: %v", err)
+		return
+	}
+	c.db.DeleteBriefingHighlights(periodID)
+
+	for _, profile := range profiles {
+		priorities, _ := c.db.GetActiveProfilePriorities(profile.ID)
+		if len(priorities) == 0 {
+			continue
+		}
+		matched := matchNarratives(narratives, priorities)
+		if len(matched) == 0 {
+			continue
+		}
+
+		body := c.generateHighlights(ctx, profile, priorities, matched)
+		if err := c.db.InsertBriefingHighlight(periodID, profile.ID, profile.Heading, body); err != nil {
+			log.Printf("Error storing highlights for profile %s: %v", profile.Name, err)
+		}
+	}
+}
+
+func (c *Composer) generateHighlights(ctx context.Context, profile database.ReaderProfile, priorities []database.ResearchPriority, matched []database.StorylineNarrative) string {
+	var fallback []string
+	for _, n := range matched {
+		fallback = append(fallback, "- "+n.Title)
+	}
+	if c.provider == nil {
+		return strings.Join(fallback, "\n")
+	}
+
+	var interests, parts []string
+	for _, p := range priorities {
+		line := "- " + p.Title
+		if len(p.Keywords) > 0 {
+			line += " (" + strings.Join(p.Keywords, ", ") + ")"
+		}
+		interests = append(interests, line)
+	}
+	for _, n := range matched {
+		parts = append(parts, fmt.Sprintf("## %s\n%s", n.Title, n.NarrativeText))
+	}
+
+	prompt := fmt.Sprintf(highlightPrompt, profile.Heading, strings.Join(interests, "\n"), strings.Join(parts, "\n\n"), llm.LanguageInstruction(c.opts.Language))
+	responseText, err := c.provider.Generate(llm.WithSchema(ctx, highlightSchema), prompt, 384)
+	if err != nil || responseText == "" {
+		return strings.Join(fallback, "\n")
+	}
+	return parseBullets(responseText, "highlight_bullets")
+}
+
+// matchNarratives ranks storylines by how many of the priorities' titles and
+// keywords they mention, returning the best few with at least one match.
+func matchNarratives(narratives []database.StorylineNarrative, priorities []database.ResearchPriority) []database.StorylineNarrative {
+	var terms []string
+	for _, p := range priorities {
+		terms = append(terms, strings.ToLower(p.Title))
+		for _, kw := range p.Keywords {
+			terms = append(terms, strings.ToLower(kw))
+		}
+	}
+
+	type scored struct {
+		narrative database.StorylineNarrative
+		score     int
+	}
+	var candidates []scored
+	for _, n := range narratives {
+		if n.Title == brieflyNotedLabel {
+			continue
+		}
+		text := strings.ToLower(n.Title + " " + n.NarrativeText)
+		score := 0
+		for _, term := range terms {
+			if term != "" && strings.Contains(text, term) {
+				score++
+			}
+		}
+		if score > 0 {
+			candidates = append(candidates, scored{n, score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	var matched []database.StorylineNarrative
+	for i := 0; i < len(candidates) && i < maxHighlightStorylines; i++ {
+		matched = append(matched, candidates[i].narrative)
+	}
+	return matched
+}
+
+// parseTLDR turns an LLM tldr_bullets response into markdown bullets,
+// falling back to the raw text when it isn't the expected JSON.
+func parseTLDR(responseText string) string {
+	return parseBullets(responseText, "tldr_bullets")
+}
+
+// parseBullets turns a JSON list of strings under key into markdown bullets,
+// falling back to the raw text when it isn't the expected JSON.
+func parseBullets(responseText, key string) string {
 	parsed := llm.ParseJSONResponse(responseText)
 	if parsed != nil {
-		if bullets, ok := parsed["tldr_bullets"]; ok {
+		if bullets, ok := parsed[key]; ok {
 			if arr, ok := bullets.([]any); ok {
 				var lines []string
 				for _, b := range arr {
@@ -124,7 +490,30 @@ func fallbackTLDR(narratives []database.StorylineNarrative) string {
 	return strings.Join(bullets, "\n")
 }
 
-func assembleBody(narratives []database.StorylineNarrative) string {
+// capStorylines keeps the first max storylines, in their ranked order, and
+// returns the rest as overflow. Briefly Noted is always kept.
+func capStorylines(narratives []database.StorylineNarrative, max int) (kept, overflow []database.StorylineNarrative) {
+	if max <= 0 {
+		return narratives, nil
+	}
+	var sections int
+	for _, n := range narratives {
+		switch {
+		case n.Title == brieflyNotedLabel:
+			kept = append(kept, n)
+		case sections < max:
+			sections++
+			kept = append(kept, n)
+		default:
+			overflow = append(overflow, n)
+		}
+	}
+	return kept, overflow
+}
+
+// assembleBody writes a section per narrative, followed by the overflow
+// storylines as one "Other developments" section and by Briefly Noted.
+func assembleBody(narratives, overflow []database.StorylineNarrative) string {
 	var mainNarratives, brieflyNoted []database.StorylineNarrative
 	for _, n := range narratives {
 		if n.Title == brieflyNotedLabel {
@@ -137,20 +526,31 @@ func assembleBody(narratives []database.StorylineNarrative) string {
 	var sections []string
 	for _, n := range mainNarratives {
 		section := fmt.Sprintf("## %s\n\n%s", n.Title, n.NarrativeText)
-		if len(n.SourceReferences) > 0 {
-			var refs []string
-			for _, ref := range n.SourceReferences {
-				line := fmt.Sprintf("- [%s](%s)", ref.Title, ref.URL)
-				if ref.Contribution != "" {
-					line += " — " + ref.Contribution
-				}
-				refs = append(refs, line)
+		var refs, additional []string
+		for _, ref := range n.SourceReferences {
+			if ref.Additional {
+				additional = append(additional, fmt.Sprintf("[%s](%s)", ref.Title, ref.URL))
+				continue
 			}
+			line := fmt.Sprintf("- [%s](%s)", ref.Title, ref.URL)
+			if ref.Contribution != "" {
+				line += " — " + ref.Contribution
+			}
+			refs = append(refs, line)
+		}
+		if len(refs) > 0 {
 			section += "\n\n**Sources:**\n" + strings.Join(refs, "\n")
 		}
+		if len(additional) > 0 {
+			section += "\n\n**Additional coverage:** " + strings.Join(additional, ", ")
+		}
 		sections = append(sections, section)
 	}
 
+	if len(overflow) > 0 {
+		sections = append(sections, otherDevelopmentsSection(overflow))
+	}
+
 	for _, n := range brieflyNoted {
 		sections = append(sections, fmt.Sprintf("## %s\n\n%s", n.Title, n.NarrativeText))
 	}
@@ -158,6 +558,141 @@ func assembleBody(narratives []database.StorylineNarrative) string {
 	return strings.Join(sections, "\n\n---\n\n")
 }
 
+// otherDevelopmentsSection lists storylines as "## Other developments"
+// bullets: the title, the narrative's opening sentence and its sources.
+func otherDevelopmentsSection(narratives []database.StorylineNarrative) string {
+	lines := []string{"## " + otherDevelopmentsLabel, ""}
+	for _, n := range narratives {
+		line := "- **" + n.Title + "**"
+		if lead := leadSentence(n.NarrativeText); lead != "" {
+			line += ": " + lead
+		}
+		var links []string
+		for _, ref := range n.SourceReferences {
+			if !ref.Additional {
+				links = append(links, fmt.Sprintf("[%s](%s)", ref.Title, ref.URL))
+			}
+		}
+		if len(links) > 0 {
+			line += " (" + strings.Join(links, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leadSentence returns the first sentence of a narrative's first paragraph.
+func leadSentence(text string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	for _, end := range []string{". ", "! ", "? "} {
+		if i := strings.Index(paragraph, end); i >= 0 {
+			paragraph = paragraph[:i+1]
+		}
+	}
+	return strings.TrimSpace(paragraph)
+}
+
+// laterTodaySection lists articles as "## Later today" markdown bullets
+// with their first key point.
+func (c *Composer) laterTodaySection(articles []database.Article) string {
+	lines := []string{"## " + laterTodayLabel, ""}
+	for _, a := range articles {
+		source := "Unknown"
+		if a.Source != nil {
+			source = *a.Source
+		}
+		line := fmt.Sprintf("- [%s](%s) (%s)", a.Title, a.URL, source)
+		if triage, _ := c.db.GetTriage(a.ID); triage != nil && len(triage.KeyPoints) > 0 {
+			line += ": " + triage.KeyPoints[0]
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// upcomingSection lists events as "## Upcoming" markdown bullets.
+func upcomingSection(events []database.Event) string {
+	lines := []string{"## Upcoming", ""}
+	for _, e := range events {
+		date := e.EventDate
+		if d, err := time.Parse("2006-01-02", e.EventDate); err == nil {
+			date = d.Format("Jan 02")
+		}
+		line := fmt.Sprintf("- **%s** — %s", date, e.Title)
+		if e.Kind != database.EventOther {
+			line += " (" + e.Kind + ")"
+		}
+		if e.ArticleURL != nil {
+			line += fmt.Sprintf(" [source](%s)", *e.ArticleURL)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// policyWatchSection lists regulation statuses as "## Policy watch" markdown
+// bullets, marking the ones that changed in this period.
+func policyWatchSection(watch []database.PolicyStatus) string {
+	lines := []string{"## Policy watch", ""}
+	for _, w := range watch {
+		line := fmt.Sprintf("- **%s** — ", w.Regulation)
+		if w.Current == nil {
+			lines = append(lines, line+"no status reported yet")
+			continue
+		}
+		line += "*" + policyStatusLabel(w.Current.Status) + "*"
+		if w.Previous != "" {
+			line += " (was: " + policyStatusLabel(w.Previous) + ")"
+		}
+		if w.New {
+			if w.Current.Summary != nil && *w.Current.Summary != "" {
+				line += " · " + *w.Current.Summary
+			}
+			line += fmt.Sprintf(" [source](%s)", w.Current.ArticleURL)
+		} else {
+			line += " · no news since " + w.Current.ReportedDate
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// policyStatusLabel turns a policy status into reader-facing text.
+func policyStatusLabel(status string) string {
+	return strings.ReplaceAll(status, "_", " ")
+}
+
+// localizeHeadings translates the fixed section headings in body into the
+// configured language. Narrative sections are already in that language, so
+// only the headings compose adds itself need translating.
+func (c *Composer) localizeHeadings(ctx context.Context, body string) string {
+	if c.opts.Language == "" || c.provider == nil {
+		return body
+	}
+
+	var present []string
+	var formats []string
+	for _, h := range fixedHeadings {
+		if strings.Contains(body, fmt.Sprintf(h.format, h.text)) {
+			present = append(present, h.text)
+			formats = append(formats, h.format)
+		}
+	}
+	if len(present) == 0 {
+		return body
+	}
+
+	translated, err := llm.Translate(ctx, c.provider, c.opts.Language, present)
+	if err != nil {
+		log.Printf("Error translating section headings: %v", err)
+		return body
+	}
+	for i, text := range present {
+		body = strings.ReplaceAll(body, fmt.Sprintf(formats[i], text), fmt.Sprintf(formats[i], translated[i]))
+	}
+	return body
+}
+
 func (c *Composer) storeEmptyBriefing(periodID string) (*database.Briefing, error) {
 	c.db.InsertBriefing(periodID, "- No articles collected today.", "No briefing content available for this period.", 0, 0)
 	return c.db.GetBriefing(periodID)
@@ -3,18 +3,22 @@ package compose
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/golden"
 )
 
 type mockProvider struct {
 	response string
+	prompts  []string
 }
 
-func (m *mockProvider) Generate(_ context.Context, _ string, _ int) (string, error) {
+func (m *mockProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
 	return m.response, nil
 }
 
@@ -48,7 +52,7 @@ func TestComposeBriefing(t *testing.T) {
 		},
 	})
 
-	composer := NewComposer(db, &mockProvider{response: string(resp)})
+	composer := NewComposer(db, &mockProvider{response: string(resp)}, Options{})
 	briefing, err := composer.ComposeBriefing(context.Background(), "2026-02-06")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -74,9 +78,132 @@ func TestComposeBriefing(t *testing.T) {
 	}
 }
 
+func TestComposeDropsRepeatedTLDRBullets(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertBriefing("2026-02-05", "- OpenAI released GPT-5 with stronger reasoning\n- Test generators get a shared benchmark", "Body", 2, 4)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Models", []int64{a1})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "GPT-5 rollout", "A section.", nil)
+
+	mock := &mockProvider{response: `{"tldr_bullets": ["GPT-5, released by OpenAI, brings stronger reasoning", "GPT-5 reaches the free tier in Europe"]}`}
+	briefing, err := NewComposer(db, mock, Options{}).ComposeBriefing(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.prompts) != 1 || !strings.Contains(mock.prompts[0], "previous briefing's TL;DR already told the reader:\n\n- OpenAI released GPT-5") {
+		t.Errorf("expected the previous TL;DR in the prompt, got %q", mock.prompts)
+	}
+	if briefing.TLDR != "- GPT-5 reaches the free tier in Europe" {
+		t.Errorf("expected the repeated bullet dropped, got %q", briefing.TLDR)
+	}
+}
+
+func TestDropRepeatsKeepsATLDR(t *testing.T) {
+	previous := "- Coding agents now open their own pull requests"
+	if got := dropRepeats("- Coding agents open pull requests of their own", previous); got != "- Coding agents open pull requests of their own" {
+		t.Errorf("expected a TL;DR of only repeats kept, got %q", got)
+	}
+	if got := dropRepeats("- Agents arrive\n- Benchmarks arrive", previous); got != "- Agents arrive\n- Benchmarks arrive" {
+		t.Errorf("expected bullets sharing one word kept, got %q", got)
+	}
+}
+
+func TestComposeBriefingGolden(t *testing.T) {
+	db := openTestDB(t)
+	if err := golden.Seed(db); err != nil {
+		t.Fatalf("seeding fixture: %v", err)
+	}
+	resp := `{"tldr_bullets": ["Coding agents now open their own pull requests", "Test generators get a shared benchmark"]}`
+
+	composer := NewComposer(db, &mockProvider{response: resp}, Options{})
+	briefing, err := composer.ComposeBriefing(context.Background(), golden.PeriodID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	golden.Assert(t, "briefing", []byte(briefing.TLDR+"\n\n"+briefing.BodyMarkdown+"\n"))
+	if briefing.TLDR != golden.TLDR || briefing.BodyMarkdown != golden.Body {
+		t.Error("expected the fixture's briefing to be the one compose writes")
+	}
+}
+
+// germanProvider answers heading translations and TL;DR requests in German,
+// recording the prompts it receives.
+type germanProvider struct {
+	prompts []string
+}
+
+func (m *germanProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	if strings.HasPrefix(prompt, "Translate") {
+		return `{"translations": ["Kurz notiert", "Quellen"]}`, nil
+	}
+	return `{"tldr_bullets": ["Agenten erreichen die CI"]}`, nil
+}
+
+func (m *germanProvider) IsConfigured() bool { return true }
+
+func TestComposeWritesForAudience(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{a1})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Agents in CI", "A section.", nil)
+
+	mock := &germanProvider{}
+	if _, err := NewComposer(db, mock, Options{Audience: "security engineers"}).ComposeBriefing(context.Background(), "2026-02-06"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(mock.prompts[0], "aimed at security engineers.") {
+		t.Errorf("expected the TL;DR prompt to name the audience, got %q", mock.prompts[0])
+	}
+}
+
+func TestComposeListsAdditionalCoverage(t *testing.T) {
+	body := assembleBody([]database.StorylineNarrative{{
+		Title:         "Launch",
+		NarrativeText: "Text",
+		SourceReferences: []database.SourceReference{
+			{Title: "A", URL: "https://a.com", Contribution: "The announcement"},
+			{Title: "B", URL: "https://b.com", Additional: true},
+			{Title: "C", URL: "https://c.com", Additional: true},
+		},
+	}}, nil)
+	if !strings.Contains(body, "**Sources:**\n- [A](https://a.com) — The announcement\n\n") {
+		t.Errorf("expected only the narrative's sources under Sources, got %q", body)
+	}
+	if !strings.Contains(body, "**Additional coverage:** [B](https://b.com), [C](https://c.com)") {
+		t.Errorf("expected additional coverage line, got %q", body)
+	}
+}
+
+func TestComposeTranslatesHeadings(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{a1})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "Agenten in der CI", "Ein Abschnitt.",
+		[]database.SourceReference{{Title: "A", URL: "https://a.com"}})
+	s2, _ := db.InsertStoryline("2026-02-06", brieflyNotedLabel, []int64{a2})
+	db.InsertStorylineNarrative(s2, "2026-02-06", brieflyNotedLabel, "- **B**: Punkt", nil)
+
+	mock := &germanProvider{}
+	briefing, err := NewComposer(db, mock, Options{Language: "German"}).ComposeBriefing(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(briefing.BodyMarkdown, "## Kurz notiert\n") || !strings.Contains(briefing.BodyMarkdown, "**Quellen:**") {
+		t.Errorf("expected translated headings, got %q", briefing.BodyMarkdown)
+	}
+	if strings.Contains(briefing.BodyMarkdown, brieflyNotedLabel) {
+		t.Error("expected no English Briefly Noted heading left")
+	}
+	if !strings.Contains(mock.prompts[0], "in German") {
+		t.Errorf("expected TL;DR prompt to ask for German, got %q", mock.prompts[0])
+	}
+}
+
 func TestComposeEmptyPeriod(t *testing.T) {
 	db := openTestDB(t)
-	composer := NewComposer(db, &mockProvider{})
+	composer := NewComposer(db, &mockProvider{}, Options{})
 	briefing, err := composer.ComposeBriefing(context.Background(), "2026-02-06")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -96,7 +223,7 @@ func TestComposeFallbackWithoutProvider(t *testing.T) {
 	db.InsertStorylineNarrative(sid, "2026-02-06", "AI Testing Narrative", "Content here.", nil)
 
 	// Provider returns empty (simulates unavailable)
-	composer := NewComposer(db, &mockProvider{response: ""})
+	composer := NewComposer(db, &mockProvider{response: ""}, Options{})
 	briefing, err := composer.ComposeBriefing(context.Background(), "2026-02-06")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -108,3 +235,247 @@ func TestComposeFallbackWithoutProvider(t *testing.T) {
 		t.Errorf("expected fallback TL;DR with storyline title, got %q", briefing.TLDR)
 	}
 }
+
+func TestComposeCapsStorylines(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a3, _ := db.InsertArticle("https://c.com", "C", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{a1, a2})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "Agents Everywhere", "Agents took over.", nil)
+	s2, _ := db.InsertStoryline("2026-02-06", "Evals", []int64{a3})
+	db.InsertStorylineNarrative(s2, "2026-02-06", "New Eval Suite", "A lab released an eval suite. It covers coding.",
+		[]database.SourceReference{{Title: "C", URL: "https://c.com"}})
+
+	briefing, err := NewComposer(db, &mockProvider{}, Options{MaxStorylines: 1}).ComposeBriefing(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(briefing.BodyMarkdown, "## Agents Everywhere") {
+		t.Errorf("expected the top storyline to keep its section, got %q", briefing.BodyMarkdown)
+	}
+	if strings.Contains(briefing.BodyMarkdown, "## New Eval Suite") {
+		t.Errorf("expected the storyline beyond the cap to lose its section, got %q", briefing.BodyMarkdown)
+	}
+	want := "## Other developments\n\n- **New Eval Suite**: A lab released an eval suite. ([C](https://c.com))"
+	if !strings.Contains(briefing.BodyMarkdown, want) {
+		t.Errorf("expected %q in the body, got %q", want, briefing.BodyMarkdown)
+	}
+	if strings.Contains(briefing.TLDR, "New Eval Suite") {
+		t.Errorf("expected the TL;DR to leave out overflow storylines, got %q", briefing.TLDR)
+	}
+	if briefing.StorylineCount != 2 {
+		t.Errorf("expected both storylines counted, got %d", briefing.StorylineCount)
+	}
+}
+
+func TestComposeEveningEditionRequiresMorning(t *testing.T) {
+	db := openTestDB(t)
+	composer := NewComposer(db, &mockProvider{}, Options{})
+	if _, err := composer.ComposeEveningEdition(context.Background(), "2026-02-06"); !errors.Is(err, database.ErrPeriodNotFound) {
+		t.Errorf("expected ErrPeriodNotFound without a morning edition, got %v", err)
+	}
+}
+
+func TestComposeEveningEditionOnlyNewArticles(t *testing.T) {
+	db := openTestDB(t)
+	old, _ := db.InsertArticle("https://old.com", "Morning News", nil, nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(old, "relevant", nil, nil, nil, 3)
+	db.InsertStoryline("2026-02-06", "Morning News", []int64{old})
+	db.InsertBriefing("2026-02-06", "- Morning", "Body", 1, 1)
+
+	fresh, _ := db.InsertArticle("https://new.com", "Afternoon Release", ptr("Blog"), nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(fresh, "relevant", nil, []string{"Shipped a thing"}, nil, 4)
+
+	resp, _ := json.Marshal(map[string]any{"tldr_bullets": []string{"A release landed"}})
+	composer := NewComposer(db, &mockProvider{response: string(resp)}, Options{})
+	evening, err := composer.ComposeEveningEdition(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evening.ArticleCount != 1 {
+		t.Errorf("expected 1 new article, got %d", evening.ArticleCount)
+	}
+	if !strings.Contains(evening.BodyMarkdown, "Afternoon Release") || strings.Contains(evening.BodyMarkdown, "Morning News") {
+		t.Errorf("expected only the new article in body, got %q", evening.BodyMarkdown)
+	}
+	if !strings.Contains(evening.TLDR, "A release landed") {
+		t.Errorf("expected LLM TL;DR, got %q", evening.TLDR)
+	}
+}
+
+func TestComposeEveningEditionLateTriage(t *testing.T) {
+	db := openTestDB(t)
+	clustered, _ := db.InsertArticle("https://a.com", "Clustered", nil, nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(clustered, "relevant", nil, nil, nil, 3)
+	db.InsertStoryline("2026-02-06", "Agents", []int64{clustered})
+	// Collected before the morning edition, triaged only after it.
+	late, _ := db.InsertArticle("https://late.com", "Triaged Late", nil, nil, ptr("C"), ptr("2026-02-06"))
+	// Listed under "Later today" by an update of the morning edition.
+	listed, _ := db.InsertArticle("https://listed.com", "Listed Later Today", nil, nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(listed, "relevant", nil, nil, nil, 3)
+	db.InsertBriefing("2026-02-06", "- Morning", "## Later today\n\n- [Listed Later Today](https://listed.com) (Unknown)", 1, 2)
+	db.InsertTriage(late, "relevant", nil, nil, nil, 4)
+
+	composer := NewComposer(db, nil, Options{})
+	evening, err := composer.ComposeEveningEdition(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evening.ArticleCount != 1 || !strings.Contains(evening.BodyMarkdown, "Triaged Late") {
+		t.Errorf("expected only the late-triaged article, got %d: %q", evening.ArticleCount, evening.BodyMarkdown)
+	}
+
+	// Composing the morning edition again doesn't take it away.
+	db.InsertBriefing("2026-02-06", "- Morning", "## Later today\n\n- [Listed Later Today](https://listed.com) (Unknown)", 1, 2)
+	if articles, _ := composer.EveningArticles("2026-02-06"); len(articles) != 1 || articles[0].ID != late {
+		t.Errorf("expected the late-triaged article after recomposing the morning, got %+v", articles)
+	}
+}
+
+func TestComposeUpdateRequiresMorning(t *testing.T) {
+	db := openTestDB(t)
+	composer := NewComposer(db, &mockProvider{}, Options{})
+	if _, err := composer.ComposeUpdate(context.Background(), "2026-02-06"); !errors.Is(err, database.ErrPeriodNotFound) {
+		t.Errorf("expected ErrPeriodNotFound without a morning edition, got %v", err)
+	}
+}
+
+func TestComposeUpdateKeepsTLDRAndListsLaterToday(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "AI Testing", []int64{a1})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "AI Transforms Testing", "Narrative", nil)
+
+	resp, _ := json.Marshal(map[string]any{"tldr_bullets": []string{"Testing changed"}})
+	composer := NewComposer(db, &mockProvider{response: string(resp)}, Options{})
+	if _, err := composer.ComposeBriefing(context.Background(), "2026-02-06"); err != nil {
+		t.Fatal(err)
+	}
+	note := "1 articles missing full text"
+	db.SetBriefingQualityNote("2026-02-06", database.EditionMorning, &note)
+
+	later, _ := db.InsertArticle("https://later.com", "Afternoon Release", ptr("Blog"), nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(later, "relevant", nil, []string{"Shipped a thing"}, nil, 4)
+
+	resp, _ = json.Marshal(map[string]any{"tldr_bullets": []string{"A new TL;DR"}})
+	composer = NewComposer(db, &mockProvider{response: string(resp)}, Options{})
+	briefing, err := composer.ComposeUpdate(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(briefing.TLDR, "Testing changed") {
+		t.Errorf("expected the morning TL;DR to be kept, got %q", briefing.TLDR)
+	}
+	if !strings.Contains(briefing.BodyMarkdown, "AI Transforms Testing") {
+		t.Error("expected body to keep the storyline")
+	}
+	if !strings.Contains(briefing.BodyMarkdown, "## Later today\n\n- [Afternoon Release](https://later.com) (Blog): Shipped a thing") {
+		t.Errorf("expected the new article under Later today, got %q", briefing.BodyMarkdown)
+	}
+	if briefing.ArticleCount != 2 {
+		t.Errorf("expected 2 articles, got %d", briefing.ArticleCount)
+	}
+	if briefing.QualityNote == nil || *briefing.QualityNote != note {
+		t.Errorf("expected the quality note to be kept, got %v", briefing.QualityNote)
+	}
+}
+
+func TestComposeTeamDigestHighlights(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Flaky Tests", []int64{a1})
+	s2, _ := db.InsertStoryline("2026-02-06", "Kubernetes Operators", []int64{a2})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "LLMs Triage Flaky Tests", "Teams use models to quarantine flaky tests.", nil)
+	db.InsertStorylineNarrative(s2, "2026-02-06", "Agents Run Kubernetes", "Operators now ship with agents.", nil)
+
+	qa, _ := db.InsertProfile("qa", "For QA")
+	db.InsertProfile("security", "For Security")
+	pid, _ := db.InsertPriority("Test automation", "", []string{"flaky tests"})
+	db.SetPriorityProfile(pid, &qa)
+
+	// Empty LLM response exercises the title fallback.
+	composer := NewComposer(db, &mockProvider{response: ""}, Options{TeamDigest: true})
+	if _, err := composer.ComposeBriefing(context.Background(), "2026-02-06"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	highlights, _ := db.GetBriefingHighlights("2026-02-06")
+	if len(highlights) != 1 {
+		t.Fatalf("expected highlights only for the profile with matching priorities, got %d", len(highlights))
+	}
+	if highlights[0].Heading != "For QA" {
+		t.Errorf("expected heading 'For QA', got %q", highlights[0].Heading)
+	}
+	if !strings.Contains(highlights[0].BodyMarkdown, "LLMs Triage Flaky Tests") ||
+		strings.Contains(highlights[0].BodyMarkdown, "Kubernetes") {
+		t.Errorf("expected only the matching storyline, got %q", highlights[0].BodyMarkdown)
+	}
+}
+
+func TestComposeWithoutTeamDigestSkipsHighlights(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Flaky Tests", []int64{a1})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Flaky Tests", "About flaky tests.", nil)
+	qa, _ := db.InsertProfile("qa", "For QA")
+	pid, _ := db.InsertPriority("Flaky tests", "", nil)
+	db.SetPriorityProfile(pid, &qa)
+
+	NewComposer(db, &mockProvider{}, Options{}).ComposeBriefing(context.Background(), "2026-02-06")
+
+	highlights, _ := db.GetBriefingHighlights("2026-02-06")
+	if len(highlights) != 0 {
+		t.Errorf("expected no highlights without team digest, got %d", len(highlights))
+	}
+}
+
+func TestComposeListsUpcomingEvents(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Releases", []int64{a1})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Releases", "Text", nil)
+	db.InsertEvent(a1, "Model v5 release", database.EventRelease, "2026-02-20")
+	db.InsertEvent(a1, "Too far out", database.EventOther, "2026-06-01")
+
+	briefing, err := NewComposer(db, &mockProvider{}, Options{}).ComposeBriefing(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(briefing.BodyMarkdown, "## Upcoming\n\n- **Feb 20** — Model v5 release (release) [source](https://a.com)") {
+		t.Errorf("expected upcoming section, got %q", briefing.BodyMarkdown)
+	}
+	if strings.Contains(briefing.BodyMarkdown, "Too far out") {
+		t.Error("expected events outside the window to be omitted")
+	}
+}
+
+func TestComposePolicyWatch(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Policy", []int64{a1})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Policy", "Text", nil)
+	old, _ := db.InsertArticle("https://old.com", "Old", nil, nil, nil, ptr("2026-01-10"))
+	db.InsertPolicyUpdate(old, "EU AI Act", database.PolicyAdopted, nil, "2026-01-10")
+	db.InsertPolicyUpdate(a1, "EU AI Act", database.PolicyInForce, ptr("Obligations apply"), "2026-02-06")
+	db.InsertPolicyUpdate(old, "Colorado AI Act", database.PolicyDelayed, nil, "2026-01-10")
+
+	opts := Options{PolicyWatch: true, Regulations: []string{"EU AI Act", "Colorado AI Act", "UK AI Bill"}}
+	briefing, err := NewComposer(db, &mockProvider{}, opts).ComposeBriefing(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "## Policy watch\n\n" +
+		"- **EU AI Act** — *in force* (was: adopted) · Obligations apply [source](https://a.com)\n" +
+		"- **Colorado AI Act** — *delayed* · no news since 2026-01-10\n" +
+		"- **UK AI Bill** — no status reported yet"
+	if !strings.Contains(briefing.BodyMarkdown, want) {
+		t.Errorf("expected policy watch section, got %q", briefing.BodyMarkdown)
+	}
+
+	briefing, _ = NewComposer(db, &mockProvider{}, Options{}).ComposeBriefing(context.Background(), "2026-02-06")
+	if strings.Contains(briefing.BodyMarkdown, "Policy watch") {
+		t.Error("expected no policy watch section when disabled")
+	}
+}
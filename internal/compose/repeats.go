@@ -0,0 +1,116 @@
+package compose
+
+import (
+	"log"
+	"strings"
+	"unicode"
+)
+
+// repeatOverlap is the share of the shorter bullet's words another bullet
+// must share for the two to say the same thing.
+const repeatOverlap = 0.6
+
+// minRepeatWords is how many words two bullets must share at least, so
+// short bullets aren't taken as repeats for one common word.
+const minRepeatWords = 2
+
+// repeatStopWords are left out when comparing bullets, besides words of
+// two or fewer letters.
+var repeatStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"from": true, "its": true, "are": true, "was": true, "has": true, "have": true,
+	"into": true, "now": true, "new": true, "more": true, "than": true, "their": true,
+}
+
+// previousTLDR returns the TL;DR of the last morning briefing before
+// periodID, or "" when there is none.
+func (c *Composer) previousTLDR(periodID string) string {
+	previous, _, err := c.db.GetAdjacentBriefingPeriods(periodID)
+	if err != nil || previous == "" {
+		return ""
+	}
+	b, err := c.db.GetBriefing(previous)
+	if err != nil || b == nil {
+		return ""
+	}
+	return b.TLDR
+}
+
+// previousSection tells the TL;DR prompt what the reader was told last
+// time, or is empty without a previous TL;DR.
+func previousSection(previous string) string {
+	if strings.TrimSpace(previous) == "" {
+		return ""
+	}
+	return "\nThe previous briefing's TL;DR already told the reader:\n\n" + strings.TrimSpace(previous) +
+		"\n\nDon't repeat these points. Leave out a storyline with nothing new since then; when one has moved on, say what changed.\n"
+}
+
+// dropRepeats removes the bullets of tldr that say about the same as a
+// bullet of previous, by the share of words they have in common. Lines that
+// aren't bullets are kept, and so is tldr when every bullet repeats, since a
+// TL;DR is never empty.
+func dropRepeats(tldr, previous string) string {
+	var earlier []map[string]bool
+	for line := range strings.Lines(previous) {
+		if text, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+			earlier = append(earlier, bulletWords(text))
+		}
+	}
+	if len(earlier) == 0 {
+		return tldr
+	}
+
+	var kept []string
+	bullets, dropped := 0, 0
+	for line := range strings.Lines(tldr) {
+		line = strings.TrimRight(line, "\n")
+		text, ok := strings.CutPrefix(strings.TrimSpace(line), "- ")
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		bullets++
+		words := bulletWords(text)
+		if isRepeat(words, earlier) {
+			log.Printf("Dropping TL;DR bullet already in the previous briefing: %s", text)
+			dropped++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if dropped == 0 || dropped == bullets {
+		return tldr
+	}
+	return strings.Join(kept, "\n")
+}
+
+// isRepeat reports whether words cover about the same as one of earlier.
+func isRepeat(words map[string]bool, earlier []map[string]bool) bool {
+	for _, e := range earlier {
+		shared := 0
+		for w := range words {
+			if e[w] {
+				shared++
+			}
+		}
+		if shared >= minRepeatWords && float64(shared) >= repeatOverlap*float64(min(len(words), len(e))) {
+			return true
+		}
+	}
+	return false
+}
+
+// bulletWords returns the lower-cased words of a bullet that count when
+// comparing it, without markdown punctuation or stop words.
+func bulletWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) > 2 && !repeatStopWords[w] {
+			words[w] = true
+		}
+	}
+	return words
+}
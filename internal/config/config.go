@@ -1,11 +1,19 @@
 package config
 
 import (
+	"cmp"
 	_ "embed"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/andybalholm/cascadia"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,49 +22,742 @@ var DefaultConfigYAML []byte
 
 type Config struct {
 	Sources       Sources       `yaml:"sources"`
+	Fetch         Fetch         `yaml:"fetch"`
 	Keywords      []string      `yaml:"keywords"`
 	Summarization Summarization `yaml:"summarization"`
+	Persona       Persona       `yaml:"persona"`
+	Triage        Triage        `yaml:"triage"`
+	Cluster       Cluster       `yaml:"cluster"`
+	Synthesize    Synthesize    `yaml:"synthesize"`
+	Compose       Compose       `yaml:"compose"`
+	Policy        Policy        `yaml:"policy"`
+	Delivery      Delivery      `yaml:"delivery"`
 	Output        Output        `yaml:"output"`
 	Server        Server        `yaml:"server"`
+	Performance   Performance   `yaml:"performance"`
+	Network       Network       `yaml:"network"`
+	Health        Health        `yaml:"health"`
 	Logging       Logging       `yaml:"logging"`
 }
 
 type Sources struct {
-	Feeds []Feed     `yaml:"feeds"`
-	APIs  APIsConfig `yaml:"apis"`
+	Feeds    []Feed        `yaml:"feeds"`
+	Reddit   Reddit        `yaml:"reddit"`
+	Arxiv    Arxiv         `yaml:"arxiv"`
+	GitHub   GitHub        `yaml:"github"`
+	Mastodon Mastodon      `yaml:"mastodon"`
+	Bluesky  Bluesky       `yaml:"bluesky"`
+	APIs     APIsConfig    `yaml:"apis"`
+	Filter   KeywordFilter `yaml:"filter"`
+}
+
+type KeywordFilter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
 }
 
 type Feed struct {
-	URL  string `yaml:"url"`
-	Name string `yaml:"name"`
+	URL      string        `yaml:"url"`
+	Name     string        `yaml:"name"`
+	Type     string        `yaml:"type"`
+	MaxItems int           `yaml:"max_items"`
+	Category string        `yaml:"category"`
+	Weight   *float64      `yaml:"weight"`
+	Disabled bool          `yaml:"disabled"`
+	Scrape   *Scrape       `yaml:"scrape"`
+	Sitemap  *Sitemap      `yaml:"sitemap"`
+	Filter   KeywordFilter `yaml:"filter"`
+}
+
+type Sitemap struct {
+	Path string `yaml:"path"`
+}
+
+type Scrape struct {
+	Item  string `yaml:"item"`
+	Link  string `yaml:"link"`
+	Title string `yaml:"title"`
+	Date  string `yaml:"date"`
+}
+
+// SourceWeight is how much the feed's articles count compared to others;
+// 1 unless the feed sets a weight.
+func (f Feed) SourceWeight() float64 {
+	if f.Weight != nil {
+		return *f.Weight
+	}
+	return 1
+}
+
+// validate rejects per-feed settings no collection could work with.
+func (s Sources) validate() error {
+	for i, f := range s.Feeds {
+		if f.MaxItems < 0 {
+			return fmt.Errorf("sources.feeds[%d].max_items must not be negative", i)
+		}
+		if f.SourceWeight() < 0 {
+			return fmt.Errorf("sources.feeds[%d].weight must not be negative", i)
+		}
+		if f.Scrape != nil && f.Sitemap != nil {
+			return fmt.Errorf("sources.feeds[%d] sets both scrape and sitemap", i)
+		}
+		if f.Scrape != nil {
+			if err := f.Scrape.validate(); err != nil {
+				return fmt.Errorf("sources.feeds[%d].scrape.%w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks that the listing's entries are selected and that every
+// selector is valid CSS.
+func (s Scrape) validate() error {
+	if strings.TrimSpace(s.Item) == "" {
+		return fmt.Errorf("item is required")
+	}
+	for _, sel := range []struct{ name, value string }{{"item", s.Item}, {"link", s.Link}, {"title", s.Title}, {"date", s.Date}} {
+		if sel.value == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(sel.value); err != nil {
+			return fmt.Errorf("%s: invalid selector %q: %v", sel.name, sel.value, err)
+		}
+	}
+	return nil
+}
+
+type Reddit struct {
+	Subreddits []string      `yaml:"subreddits"`
+	MinUpvotes int           `yaml:"min_upvotes"`
+	Filter     KeywordFilter `yaml:"filter"`
+}
+
+type Arxiv struct {
+	Enabled    bool          `yaml:"enabled"`
+	Categories []string      `yaml:"categories"`
+	Terms      []string      `yaml:"terms"`
+	MaxResults int           `yaml:"max_results"`
+	Filter     KeywordFilter `yaml:"filter"`
+}
+
+type GitHub struct {
+	Repos              []string      `yaml:"repos"`
+	Topics             []string      `yaml:"topics"`
+	TokenEnv           string        `yaml:"token_env"`
+	IncludePrereleases bool          `yaml:"include_prereleases"`
+	MinStars           int           `yaml:"min_stars"`
+	Filter             KeywordFilter `yaml:"filter"`
+}
+
+type Mastodon struct {
+	Instance string        `yaml:"instance"`
+	Accounts []string      `yaml:"accounts"`
+	Hashtags []string      `yaml:"hashtags"`
+	Filter   KeywordFilter `yaml:"filter"`
+}
+
+type Bluesky struct {
+	Accounts []string      `yaml:"accounts"`
+	Feeds    []string      `yaml:"feeds"`
+	Filter   KeywordFilter `yaml:"filter"`
 }
 
 type APIsConfig struct {
 	NewsAPI NewsAPIConfig `yaml:"newsapi"`
+	Brave   NewsAPIConfig `yaml:"brave"`
+	GDELT   NewsAPIConfig `yaml:"gdelt"` // needs no key
 }
 
 type NewsAPIConfig struct {
-	Enabled   bool   `yaml:"enabled"`
-	APIKeyEnv string `yaml:"api_key_env"`
-	Query     string `yaml:"query"`
+	Enabled   bool          `yaml:"enabled"`
+	APIKeyEnv string        `yaml:"api_key_env"`
+	Query     string        `yaml:"query"`
+	Filter    KeywordFilter `yaml:"filter"`
 }
 
 type Summarization struct {
-	Provider       string `yaml:"provider"`
+	Provider             string                `yaml:"provider"`
+	Model                string                `yaml:"model"`
+	OllamaURL            string                `yaml:"ollama_url"`
+	EmbeddingProvider    string                `yaml:"embedding_provider"`
+	EmbeddingModel       string                `yaml:"embedding_model"`
+	OpenAIModel          string                `yaml:"openai_model"`
+	OpenAIEmbeddingModel string                `yaml:"openai_embedding_model"`
+	OpenAIBaseURL        string                `yaml:"openai_base_url"`
+	APIKeyEnv            string                `yaml:"api_key_env"`
+	Temperature          float64               `yaml:"temperature"`
+	MaxConcurrency       int                   `yaml:"max_concurrency"`
+	Claude               ClaudeConfig          `yaml:"claude"`
+	Gemini               GeminiConfig          `yaml:"gemini"`
+	Voyage               VoyageConfig          `yaml:"voyage"`
+	Azure                AzureConfig           `yaml:"azure"`
+	Retry                RetryConfig           `yaml:"retry"`
+	Timeouts             TimeoutsConfig        `yaml:"timeouts"`
+	Pricing              map[string]ModelPrice `yaml:"pricing"`
+	Cache                CacheConfig           `yaml:"cache"`
+	Steps                StepsConfig           `yaml:"steps"`
+	Fallback             []FallbackConfig      `yaml:"fallback"`
+	Audit                AuditConfig           `yaml:"audit"`
+}
+
+type AuditConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	KeepDays int  `yaml:"keep_days"`
+}
+
+type FallbackConfig struct {
+	Provider  string `yaml:"provider"`
+	Model     string `yaml:"model"`
+	BaseURL   string `yaml:"base_url"`
+	APIKeyEnv string `yaml:"api_key_env"`
+}
+
+type StepsConfig struct {
+	Triage     StepConfig `yaml:"triage"`
+	Releases   StepConfig `yaml:"releases"`
+	Synthesize StepConfig `yaml:"synthesize"`
+	Compose    StepConfig `yaml:"compose"`
+}
+
+type StepConfig struct {
+	Provider    string   `yaml:"provider"`
+	Model       string   `yaml:"model"`
+	Temperature *float64 `yaml:"temperature"`
+	MaxTokens   int      `yaml:"max_tokens"`
+}
+
+// OwnProvider reports whether the step needs a provider of its own rather
+// than the shared one.
+func (step StepConfig) OwnProvider() bool {
+	return step.Provider != "" || step.Model != ""
+}
+
+// StepTemperature returns the sampling temperature of a pipeline step: its
+// own where it sets one, the shared temperature otherwise.
+func (s Summarization) StepTemperature(step StepConfig) float64 {
+	if step.Temperature != nil {
+		return *step.Temperature
+	}
+	return s.Temperature
+}
+
+// ForStep returns the settings a pipeline step's provider is built from: s
+// with the step's provider, and its model in place of that provider's
+// model, where the step sets them.
+func (s Summarization) ForStep(step StepConfig) Summarization {
+	if step.Provider != "" {
+		s.Provider = step.Provider
+	}
+	if step.Model == "" {
+		return s
+	}
+	switch strings.ToLower(s.Provider) {
+	case "ollama":
+		s.Model = step.Model
+	case "claude", "anthropic":
+		s.Claude.Model = step.Model
+	case "gemini":
+		s.Gemini.Model = step.Model
+	case "azure":
+		s.Azure.Deployment = step.Model
+	default:
+		s.OpenAIModel = step.Model
+	}
+	return s
+}
+
+// ForFallback returns the settings a fallback provider is built from: s
+// switched to f's provider, with f's model, base URL and API key variable
+// in place of that provider's where f sets them. The base URL applies to
+// Ollama, OpenAI-compatible servers and Azure (as its endpoint).
+func (s Summarization) ForFallback(f FallbackConfig) Summarization {
+	s = s.ForStep(StepConfig{Provider: f.Provider, Model: f.Model})
+	s.Fallback = nil
+	switch strings.ToLower(s.Provider) {
+	case "ollama":
+		s.OllamaURL = cmp.Or(f.BaseURL, s.OllamaURL)
+	case "claude", "anthropic":
+		s.Claude.APIKeyEnv = cmp.Or(f.APIKeyEnv, s.Claude.APIKeyEnv)
+	case "gemini":
+		s.Gemini.APIKeyEnv = cmp.Or(f.APIKeyEnv, s.Gemini.APIKeyEnv)
+	case "azure":
+		s.Azure.Endpoint = cmp.Or(f.BaseURL, s.Azure.Endpoint)
+		s.Azure.APIKeyEnv = cmp.Or(f.APIKeyEnv, s.Azure.APIKeyEnv)
+	default:
+		s.OpenAIBaseURL = cmp.Or(f.BaseURL, s.OpenAIBaseURL)
+		s.APIKeyEnv = cmp.Or(f.APIKeyEnv, s.APIKeyEnv)
+	}
+	return s
+}
+
+type CacheConfig struct {
+	Enabled  bool    `yaml:"enabled"`
+	TTLHours float64 `yaml:"ttl_hours"`
+}
+
+type RetryConfig struct {
+	MaxAttempts           int     `yaml:"max_attempts"`
+	InitialBackoffSeconds float64 `yaml:"initial_backoff_seconds"`
+	MaxBackoffSeconds     float64 `yaml:"max_backoff_seconds"`
+}
+
+type TimeoutsConfig struct {
+	DefaultSeconds float64 `yaml:"default_seconds"`
+	OllamaSeconds  float64 `yaml:"ollama_seconds"`
+	OpenAISeconds  float64 `yaml:"openai_seconds"`
+	ClaudeSeconds  float64 `yaml:"claude_seconds"`
+	GeminiSeconds  float64 `yaml:"gemini_seconds"`
+	AzureSeconds   float64 `yaml:"azure_seconds"`
+	VoyageSeconds  float64 `yaml:"voyage_seconds"`
+}
+
+// For returns how long a request to provider waits for a response: the
+// provider's own timeout where one is set, DefaultSeconds otherwise. Any
+// name other than the other providers' means OpenAI, as in CreateProvider.
+func (t TimeoutsConfig) For(provider string) time.Duration {
+	var own float64
+	switch strings.ToLower(provider) {
+	case "ollama":
+		own = t.OllamaSeconds
+	case "claude", "anthropic":
+		own = t.ClaudeSeconds
+	case "gemini":
+		own = t.GeminiSeconds
+	case "azure":
+		own = t.AzureSeconds
+	case "voyage":
+		own = t.VoyageSeconds
+	default:
+		own = t.OpenAISeconds
+	}
+	return time.Duration(cmp.Or(own, t.DefaultSeconds) * float64(time.Second))
+}
+
+type ModelPrice struct {
+	InputPerMillion  float64 `yaml:"input_per_million"`
+	OutputPerMillion float64 `yaml:"output_per_million"`
+}
+
+type ClaudeConfig struct {
+	Model     string `yaml:"model"`
+	APIKeyEnv string `yaml:"api_key_env"`
+}
+
+type AzureConfig struct {
+	Endpoint   string `yaml:"endpoint"`
+	Deployment string `yaml:"deployment"`
+	APIVersion string `yaml:"api_version"`
+	APIKeyEnv  string `yaml:"api_key_env"`
+}
+
+type GeminiConfig struct {
 	Model          string `yaml:"model"`
-	OllamaURL      string `yaml:"ollama_url"`
 	EmbeddingModel string `yaml:"embedding_model"`
-	OpenAIModel    string `yaml:"openai_model"`
 	APIKeyEnv      string `yaml:"api_key_env"`
-	MaxTokens      int    `yaml:"max_tokens"`
+}
+
+type VoyageConfig struct {
+	EmbeddingModel string `yaml:"embedding_model"`
+	APIKeyEnv      string `yaml:"api_key_env"`
+}
+
+type Persona struct {
+	Audience     string `yaml:"audience"`
+	SystemPrompt string `yaml:"system_prompt"`
+}
+
+type Fetch struct {
+	UserAgent   string                       `yaml:"user_agent"`
+	Headers     map[string]map[string]string `yaml:"headers"` // by domain
+	SecretsFile string                       `yaml:"secrets_file"`
+	Extractors  []string                     `yaml:"extractors"`
+	Browser     Browser                      `yaml:"browser"`
+	Wayback     Wayback                      `yaml:"wayback"`
+	Cache       CacheConfig                  `yaml:"cache"`
+	Dedup       Dedup                        `yaml:"dedup"`
+	Refetch     Refetch                      `yaml:"refetch"`
+}
+
+// DefaultUserAgent is the User-Agent requests go out with unless
+// fetch.user_agent replaces it: those for pages, feeds, source APIs and
+// webhooks.
+const DefaultUserAgent = "AICrawler/1.0 (news aggregator)"
+
+// Agent returns the User-Agent requests go out with: fetch.user_agent, or
+// DefaultUserAgent when it is empty.
+func (f Fetch) Agent() string {
+	return cmp.Or(f.UserAgent, DefaultUserAgent)
+}
+
+// extractorNames are the extractors fetch.extractors may name.
+var extractorNames = []string{"readability", "heuristic", "meta"}
+
+func (f Fetch) validate() error {
+	for domain, headers := range f.Headers {
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			return fmt.Errorf("fetch.headers: %q is not a domain, such as example.com", domain)
+		}
+		for name, value := range headers {
+			if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+				return fmt.Errorf("fetch.headers.%s: %q is not a header name", domain, name)
+			}
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("fetch.headers.%s.%s must be a single line", domain, name)
+			}
+		}
+	}
+	if len(f.Extractors) == 0 {
+		return fmt.Errorf("fetch.extractors must name at least one of %s", strings.Join(extractorNames, ", "))
+	}
+	for _, name := range f.Extractors {
+		if !slices.Contains(extractorNames, name) {
+			return fmt.Errorf("fetch.extractors: unknown extractor %q, expected one of %s", name, strings.Join(extractorNames, ", "))
+		}
+	}
+	if err := f.Dedup.validate(); err != nil {
+		return err
+	}
+	if f.Refetch.Days < 1 {
+		return fmt.Errorf("fetch.refetch.days must be at least 1, got %d", f.Refetch.Days)
+	}
+	return f.Browser.validate()
+}
+
+type Browser struct {
+	Enabled        bool    `yaml:"enabled"`
+	Path           string  `yaml:"path"`
+	TimeoutSeconds float64 `yaml:"timeout_seconds"`
+}
+
+// Timeout returns how long rendering one page may take.
+func (b Browser) Timeout() time.Duration {
+	return time.Duration(b.TimeoutSeconds * float64(time.Second))
+}
+
+func (b Browser) validate() error {
+	if b.TimeoutSeconds <= 0 {
+		return fmt.Errorf("fetch.browser.timeout_seconds must be positive, got %g", b.TimeoutSeconds)
+	}
+	return nil
+}
+
+type Wayback struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type Dedup struct {
+	Enabled     bool `yaml:"enabled"`
+	MaxDistance int  `yaml:"max_distance"`
+	Days        int  `yaml:"days"`
+}
+
+// validate rejects a distance that would make unrelated articles
+// duplicates: two random simhashes differ in about 32 of their 64 bits.
+func (d Dedup) validate() error {
+	if d.MaxDistance < 1 || d.MaxDistance > 16 {
+		return fmt.Errorf("fetch.dedup.max_distance must be between 1 and 16, got %d", d.MaxDistance)
+	}
+	if d.Days < 1 {
+		return fmt.Errorf("fetch.dedup.days must be at least 1, got %d", d.Days)
+	}
+	return nil
+}
+
+type Refetch struct {
+	Enabled bool `yaml:"enabled"`
+	Days    int  `yaml:"days"`
+}
+
+type Triage struct {
+	RetryPasses         int        `yaml:"retry_passes"`
+	RetryBackoffSeconds float64    `yaml:"retry_backoff_seconds"`
+	ErrorBudget         int        `yaml:"error_budget"`
+	Classifier          Classifier `yaml:"classifier"`
+	Languages           []string   `yaml:"languages"`
+	Translate           bool       `yaml:"translate"`
+}
+
+// languageCode matches an ISO 639-1 code, as language detection stores.
+var languageCode = regexp.MustCompile(`^[a-z]{2}$`)
+
+// validate rejects languages given by name rather than by code, which no
+// article would ever match.
+func (t Triage) validate() error {
+	for _, l := range t.Languages {
+		if !languageCode.MatchString(l) {
+			return fmt.Errorf("triage.languages must be two-letter ISO 639-1 codes like en or de, got %q", l)
+		}
+	}
+	return t.Classifier.validate()
+}
+
+type Classifier struct {
+	Enabled     bool    `yaml:"enabled"`
+	MinExamples int     `yaml:"min_examples"`
+	AcceptAbove float64 `yaml:"accept_above"`
+	RejectBelow float64 `yaml:"reject_below"`
+	MinAccuracy float64 `yaml:"min_accuracy"`
+}
+
+// validate rejects thresholds that would let the classifier decide every
+// article both ways.
+func (c Classifier) validate() error {
+	if c.RejectBelow >= c.AcceptAbove {
+		return fmt.Errorf("triage.classifier.reject_below (%g) must be below accept_above (%g)", c.RejectBelow, c.AcceptAbove)
+	}
+	return nil
+}
+
+type Cluster struct {
+	MinClusterSize    int    `yaml:"min_cluster_size"`
+	Singletons        string `yaml:"singletons"`
+	SpotlightMinScore int    `yaml:"spotlight_min_score"`
+}
+
+type Synthesize struct {
+	ResynthesizeStale bool   `yaml:"resynthesize_stale"`
+	ExcerptLookups    int    `yaml:"excerpt_lookups"`
+	Memory            Memory `yaml:"memory"`
+}
+
+type Memory struct {
+	Enabled  bool `yaml:"enabled"`
+	KeepDays int  `yaml:"keep_days"`
+}
+
+type Compose struct {
+	TeamDigest    bool `yaml:"team_digest"`
+	MaxStorylines int  `yaml:"max_storylines"`
+}
+
+type Policy struct {
+	Enabled     bool     `yaml:"enabled"`
+	Regulations []string `yaml:"regulations"`
+	Feeds       []Feed   `yaml:"feeds"`
+}
+
+type Delivery struct {
+	PublicURL string          `yaml:"public_url"`
+	Formats   []string        `yaml:"formats"`
+	S3        S3Config        `yaml:"s3"`
+	WebDAV    WebDAVConfig    `yaml:"webdav"`
+	Telegram  TelegramConfig  `yaml:"telegram"`
+	Matrix    MatrixConfig    `yaml:"matrix"`
+	Webhooks  []WebhookConfig `yaml:"webhooks"`
+}
+
+type S3Config struct {
+	Enabled         bool   `yaml:"enabled"`
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`
+	PathStyle       bool   `yaml:"path_style"`
+	AccessKeyEnv    string `yaml:"access_key_env"`
+	SecretKeyEnv    string `yaml:"secret_key_env"`
+	SessionTokenEnv string `yaml:"session_token_env"`
+}
+
+type WebDAVConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	URL         string `yaml:"url"`
+	Username    string `yaml:"username"`
+	PasswordEnv string `yaml:"password_env"`
+}
+
+type TelegramConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	BotTokenEnv string `yaml:"bot_token_env"`
+	ChatID      string `yaml:"chat_id"`
+	Interactive bool   `yaml:"interactive"`
+}
+
+type MatrixConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Homeserver     string `yaml:"homeserver"`
+	AccessTokenEnv string `yaml:"access_token_env"`
+	RoomID         string `yaml:"room_id"`
+	Interactive    bool   `yaml:"interactive"`
+}
+
+type WebhookConfig struct {
+	URL    string   `yaml:"url"`
+	Events []string `yaml:"events"`
 }
 
 type Output struct {
-	DataDir string `yaml:"data_dir"`
+	DataDir  string `yaml:"data_dir"`
+	Language string `yaml:"language"`
 }
 
 type Server struct {
-	Port int `yaml:"port"`
+	Port           int          `yaml:"port"`
+	IngestTokenEnv string       `yaml:"ingest_token_env"`
+	Users          []UserConfig `yaml:"users"`
+}
+
+type UserConfig struct {
+	Name     string `yaml:"name"`
+	Role     string `yaml:"role"`
+	TokenEnv string `yaml:"token_env"`
+}
+
+type Performance struct {
+	FetchWorkers        int                `yaml:"fetch_workers"`
+	FetchesPerHost      int                `yaml:"fetches_per_host"`
+	FetchRetries        int                `yaml:"fetch_retries"`
+	FeedWorkers         int                `yaml:"feed_workers"`
+	FeedsPerHost        int                `yaml:"feeds_per_host"`
+	TriageConcurrency   int                `yaml:"triage_concurrency"`
+	EmbedBatchSize      int                `yaml:"embed_batch_size"`
+	StepTimeoutsMinutes map[string]float64 `yaml:"step_timeouts_minutes"`
+	HTTP                HTTPLimits         `yaml:"http"`
+}
+
+type HTTPLimits struct {
+	FetchTimeoutSeconds  float64 `yaml:"fetch_timeout_seconds"`
+	SourceTimeoutSeconds float64 `yaml:"source_timeout_seconds"`
+	MaxRedirects         int     `yaml:"max_redirects"`
+	MaxPageMB            float64 `yaml:"max_page_mb"`
+	MaxIngestMB          float64 `yaml:"max_ingest_mb"`
+}
+
+// TimedSteps are the pipeline steps step_timeouts_minutes can limit;
+// "cluster" covers assigning articles to existing storylines too.
+var TimedSteps = []string{"fetch", "triage", "releases", "cluster", "synthesize", "compose", "memory"}
+
+// StepTimeout returns how long a pipeline step may run, or 0 for no limit.
+func (p Performance) StepTimeout(step string) time.Duration {
+	return time.Duration(p.StepTimeoutsMinutes[step] * float64(time.Minute))
+}
+
+// FetchTimeout returns how long fetching one page may take.
+func (h HTTPLimits) FetchTimeout() time.Duration {
+	return time.Duration(h.FetchTimeoutSeconds * float64(time.Second))
+}
+
+// SourceTimeout returns how long a request to a feed or source API may take.
+func (h HTTPLimits) SourceTimeout() time.Duration {
+	return time.Duration(h.SourceTimeoutSeconds * float64(time.Second))
+}
+
+// MaxPageBytes returns the most of a page or snapshot fetching reads.
+func (h HTTPLimits) MaxPageBytes() int64 {
+	return int64(h.MaxPageMB * (1 << 20))
+}
+
+// MaxIngestBytes returns the largest request body the ingest API accepts.
+func (h HTTPLimits) MaxIngestBytes() int64 {
+	return int64(h.MaxIngestMB * (1 << 20))
+}
+
+// validate rejects settings no run could work with, naming the setting.
+func (p Performance) validate() error {
+	for _, s := range []struct {
+		name     string
+		value    float64
+		positive bool // zero isn't allowed either
+	}{
+		{"fetch_workers", float64(p.FetchWorkers), true},
+		{"fetches_per_host", float64(p.FetchesPerHost), true},
+		{"fetch_retries", float64(p.FetchRetries), false},
+		{"feed_workers", float64(p.FeedWorkers), true},
+		{"feeds_per_host", float64(p.FeedsPerHost), true},
+		{"triage_concurrency", float64(p.TriageConcurrency), false},
+		{"embed_batch_size", float64(p.EmbedBatchSize), false},
+		{"http.fetch_timeout_seconds", p.HTTP.FetchTimeoutSeconds, true},
+		{"http.source_timeout_seconds", p.HTTP.SourceTimeoutSeconds, true},
+		{"http.max_redirects", float64(p.HTTP.MaxRedirects), false},
+		{"http.max_page_mb", p.HTTP.MaxPageMB, true},
+		{"http.max_ingest_mb", p.HTTP.MaxIngestMB, true},
+	} {
+		switch {
+		case s.positive && s.value <= 0:
+			return fmt.Errorf("performance.%s must be positive, got %g", s.name, s.value)
+		case s.value < 0:
+			return fmt.Errorf("performance.%s must not be negative, got %g", s.name, s.value)
+		}
+	}
+	for step, minutes := range p.StepTimeoutsMinutes {
+		if !slices.Contains(TimedSteps, step) {
+			return fmt.Errorf("performance.step_timeouts_minutes: unknown step %q (want one of %s)", step, strings.Join(TimedSteps, ", "))
+		}
+		if minutes < 0 {
+			return fmt.Errorf("performance.step_timeouts_minutes.%s must not be negative, got %g", step, minutes)
+		}
+	}
+	return nil
+}
+
+type Network struct {
+	Proxy string `yaml:"proxy"`
+}
+
+// proxySchemes are the kinds of proxy net/http can go through.
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ProxyURL returns the configured proxy, or nil when there is none.
+func (n Network) ProxyURL() *url.URL {
+	if n.Proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(n.Proxy)
+	if err != nil {
+		return nil // rejected by validate
+	}
+	return u
+}
+
+// Transport returns the transport of requests to sites and source APIs:
+// through the configured proxy, or else the default transport, which
+// follows the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables.
+func (n Network) Transport() http.RoundTripper {
+	proxy := n.ProxyURL()
+	if proxy == nil {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return transport
+}
+
+func (n Network) validate() error {
+	if n.Proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(n.Proxy)
+	if err != nil || u.Host == "" || !slices.Contains(proxySchemes, u.Scheme) {
+		return fmt.Errorf("network.proxy must be a URL like http://proxy:3128 or socks5://proxy:1080, got %q", n.Proxy)
+	}
+	return nil
+}
+
+type Health struct {
+	MaxRunAgeHours  float64 `yaml:"max_run_age_hours"`
+	FeedFailures    int     `yaml:"feed_failures"`
+	MaxFailingFeeds int     `yaml:"max_failing_feeds"`
+}
+
+// MaxRunAge is how long ago the last run may have finished; 0 means any age.
+func (h Health) MaxRunAge() time.Duration {
+	return time.Duration(h.MaxRunAgeHours * float64(time.Hour))
+}
+
+func (h Health) validate() error {
+	for _, s := range []struct {
+		name  string
+		value float64
+	}{
+		{"max_run_age_hours", h.MaxRunAgeHours},
+		{"feed_failures", float64(h.FeedFailures)},
+		{"max_failing_feeds", float64(h.MaxFailingFeeds)},
+	} {
+		if s.value < 0 {
+			return fmt.Errorf("health.%s must not be negative", s.name)
+		}
+	}
+	return nil
 }
 
 type Logging struct {
@@ -112,30 +813,164 @@ func Load(path string) (*Config, error) {
 func parse(data []byte) (*Config, error) {
 	cfg := &Config{
 		Sources: Sources{
+			Reddit:   Reddit{MinUpvotes: 50},
+			Arxiv:    Arxiv{Categories: []string{"cs.AI", "cs.SE"}, MaxResults: 100},
+			GitHub:   GitHub{TokenEnv: "GITHUB_TOKEN", MinStars: 50},
+			Mastodon: Mastodon{Instance: "mastodon.social"},
 			APIs: APIsConfig{
 				NewsAPI: NewsAPIConfig{
 					Enabled:   true,
 					APIKeyEnv: "NEWSAPI_KEY",
 					Query:     "artificial intelligence software development",
 				},
+				Brave: NewsAPIConfig{
+					APIKeyEnv: "BRAVE_API_KEY",
+					Query:     "artificial intelligence software development",
+				},
+				GDELT: NewsAPIConfig{
+					Query: `"artificial intelligence" software`,
+				},
 			},
 		},
+		Fetch: Fetch{
+			UserAgent:  DefaultUserAgent,
+			Extractors: []string{"readability", "heuristic", "meta"},
+			Browser:    Browser{TimeoutSeconds: 30},
+			Wayback:    Wayback{Enabled: true},
+			Cache:      CacheConfig{Enabled: true, TTLHours: 24},
+			Dedup:      Dedup{Enabled: true, MaxDistance: 6, Days: 7},
+			Refetch:    Refetch{Days: 7},
+		},
 		Summarization: Summarization{
-			Provider:       "ollama",
-			Model:          "qwen2.5:7b",
-			OllamaURL:      "http://localhost:11434",
-			EmbeddingModel: "nomic-embed-text",
-			OpenAIModel:    "gpt-4o-mini",
-			APIKeyEnv:      "OPENAI_API_KEY",
-			MaxTokens:      512,
+			Provider:             "ollama",
+			Model:                "qwen2.5:7b",
+			OllamaURL:            "http://localhost:11434",
+			EmbeddingModel:       "nomic-embed-text",
+			OpenAIModel:          "gpt-4o-mini",
+			OpenAIEmbeddingModel: "text-embedding-3-small",
+			OpenAIBaseURL:        "https://api.openai.com/v1",
+			APIKeyEnv:            "OPENAI_API_KEY",
+			Temperature:          0.3,
+			MaxConcurrency:       1,
+			Claude: ClaudeConfig{
+				Model:     "claude-sonnet-4-5",
+				APIKeyEnv: "ANTHROPIC_API_KEY",
+			},
+			Gemini: GeminiConfig{
+				Model:          "gemini-2.5-flash",
+				EmbeddingModel: "gemini-embedding-001",
+				APIKeyEnv:      "GEMINI_API_KEY",
+			},
+			Voyage: VoyageConfig{
+				EmbeddingModel: "voyage-3.5",
+				APIKeyEnv:      "VOYAGE_API_KEY",
+			},
+			Azure: AzureConfig{
+				APIVersion: "2024-10-21",
+				APIKeyEnv:  "AZURE_OPENAI_API_KEY",
+			},
+			Retry: RetryConfig{
+				MaxAttempts:           4,
+				InitialBackoffSeconds: 2,
+				MaxBackoffSeconds:     60,
+			},
+			Timeouts: TimeoutsConfig{DefaultSeconds: 120, OllamaSeconds: 600},
+			Pricing: map[string]ModelPrice{
+				"gpt-4o-mini":            {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+				"gpt-4o":                 {InputPerMillion: 2.50, OutputPerMillion: 10},
+				"claude-sonnet-4-5":      {InputPerMillion: 3, OutputPerMillion: 15},
+				"claude-haiku-4-5":       {InputPerMillion: 1, OutputPerMillion: 5},
+				"gemini-2.5-flash":       {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+				"gemini-2.5-pro":         {InputPerMillion: 1.25, OutputPerMillion: 10},
+				"text-embedding-3-small": {InputPerMillion: 0.02},
+				"text-embedding-3-large": {InputPerMillion: 0.13},
+				"voyage-3.5":             {InputPerMillion: 0.06},
+				"voyage-3.5-lite":        {InputPerMillion: 0.02},
+			},
+			Cache: CacheConfig{Enabled: true, TTLHours: 72},
+			Audit: AuditConfig{Enabled: true, KeepDays: 30},
+		},
+		Persona: Persona{Audience: "software practitioners"},
+		Triage: Triage{
+			RetryPasses:         1,
+			RetryBackoffSeconds: 30,
+			ErrorBudget:         20,
+			Classifier: Classifier{
+				MinExamples: 300,
+				AcceptAbove: 0.97,
+				RejectBelow: 0.05,
+				MinAccuracy: 0.95,
+			},
 		},
-		Server: Server{Port: 8000},
+		Cluster: Cluster{
+			MinClusterSize:    2,
+			Singletons:        "briefly_noted",
+			SpotlightMinScore: 4,
+		},
+		Synthesize: Synthesize{Memory: Memory{Enabled: true, KeepDays: 90}},
+		Compose:    Compose{MaxStorylines: 10},
+		Policy: Policy{
+			Regulations: []string{"EU AI Act", "US AI Executive Order", "Colorado AI Act", "UK AI Bill"},
+			Feeds: []Feed{
+				{URL: "https://artificialintelligenceact.substack.com/feed", Name: "EU AI Act Newsletter"},
+				{URL: "https://www.techpolicy.press/rss/", Name: "Tech Policy Press"},
+				{URL: "https://iapp.org/rss/daily-dashboard/", Name: "IAPP Daily Dashboard"},
+			},
+		},
+		Delivery: Delivery{
+			Formats: []string{"markdown", "html", "json"},
+			S3: S3Config{
+				Endpoint:        "https://s3.amazonaws.com",
+				Region:          "us-east-1",
+				Prefix:          "briefings/",
+				AccessKeyEnv:    "AWS_ACCESS_KEY_ID",
+				SecretKeyEnv:    "AWS_SECRET_ACCESS_KEY",
+				SessionTokenEnv: "AWS_SESSION_TOKEN",
+			},
+			WebDAV:   WebDAVConfig{PasswordEnv: "WEBDAV_PASSWORD"},
+			Telegram: TelegramConfig{BotTokenEnv: "TELEGRAM_BOT_TOKEN"},
+			Matrix:   MatrixConfig{Homeserver: "https://matrix.org", AccessTokenEnv: "MATRIX_ACCESS_TOKEN"},
+		},
+		Server: Server{Port: 8000, IngestTokenEnv: "AICRAWLER_INGEST_TOKEN"},
+		Performance: Performance{
+			FetchWorkers:   4,
+			FetchesPerHost: 1,
+			FetchRetries:   3,
+			FeedWorkers:    8,
+			FeedsPerHost:   2,
+			HTTP: HTTPLimits{
+				FetchTimeoutSeconds:  15,
+				SourceTimeoutSeconds: 30,
+				MaxRedirects:         10,
+				MaxPageMB:            10,
+				MaxIngestMB:          5,
+			},
+		},
+		Health:  Health{MaxRunAgeHours: 26, FeedFailures: 3},
 		Logging: Logging{Level: "INFO"},
 	}
 
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
+	if err := cfg.Sources.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Fetch.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Performance.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Network.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Triage.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Health.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return cfg, nil
 }
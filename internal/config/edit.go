@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddFeeds appends to sources.feeds of the config file at path the feeds
+// it doesn't list yet, under a comment line, and returns those it added.
+// The file is edited as text, so its comments, blank lines and quoting stay
+// as they are; the result is parsed again before it is written.
+func AddFeeds(path string, feeds []Feed, comment string) ([]Feed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	cfg, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, f := range cfg.Sources.Feeds {
+		known[feedKey(f.URL)] = true
+	}
+	var added []Feed
+	for _, f := range feeds {
+		if f.URL != "" && !known[feedKey(f.URL)] {
+			known[feedKey(f.URL)] = true
+			added = append(added, f)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	edited, err := insertFeeds(data, added, comment)
+	if err != nil {
+		return nil, err
+	}
+	check, err := parse(edited)
+	if err != nil {
+		return nil, fmt.Errorf("adding feeds would break the config: %w", err)
+	}
+	if len(check.Sources.Feeds) != len(cfg.Sources.Feeds)+len(added) {
+		return nil, fmt.Errorf("adding feeds to %s: sources.feeds could not be edited safely", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, edited, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("writing config: %w", err)
+	}
+	return added, nil
+}
+
+// insertFeeds returns data with feeds added to the end of sources.feeds,
+// found through the line and column numbers of the parsed document.
+func insertFeeds(data []byte, feeds []Feed, comment string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+
+	var root *yaml.Node
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		root = doc.Content[0]
+	}
+	sourcesKey, sources := mappingValue(root, "sources")
+	feedsKey, list := mappingValue(sources, "feeds")
+
+	var at int          // index of the line the feeds are inserted before
+	var indent string   // of the "- " starting each feed
+	var header []string // lines needed before the feeds
+	switch {
+	case list != nil && list.Kind == yaml.SequenceNode && list.Style&yaml.FlowStyle == 0 && len(list.Content) > 0:
+		last := list.Content[len(list.Content)-1]
+		at = lastLine(last)
+		indent = strings.Repeat(" ", list.Content[0].Column-3)
+	case feedsKey != nil:
+		// An empty list, written "feeds: []" or "feeds:", becomes a block.
+		if list.Kind == yaml.SequenceNode && len(list.Content) > 0 {
+			return nil, fmt.Errorf("sources.feeds is a flow list; write it as a block list to add feeds")
+		}
+		i := feedsKey.Line - 1
+		line := strings.TrimRight(lines[i], "\n")
+		key := line[:feedsKey.Column-1] + "feeds:"
+		rest := strings.TrimSpace(line[len(key):])
+		if c := strings.Index(rest, "#"); c >= 0 {
+			key += " " + rest[c:]
+			rest = strings.TrimSpace(rest[:c])
+		}
+		if rest != "" && rest != "[]" && rest != "~" && rest != "null" {
+			return nil, fmt.Errorf("sources.feeds is not a list")
+		}
+		lines[i] = key + "\n"
+		at = i + 1
+		indent = line[:feedsKey.Column-1] + "  "
+	case sourcesKey != nil:
+		if sources.Kind != yaml.MappingNode || sources.Style&yaml.FlowStyle != 0 || len(sources.Content) == 0 {
+			return nil, fmt.Errorf("sources is not a block mapping; add sources.feeds by hand")
+		}
+		at = sourcesKey.Line
+		pad := strings.Repeat(" ", sources.Content[0].Column-1)
+		header = []string{pad + "feeds:\n"}
+		indent = pad + "  "
+	default:
+		at = len(lines)
+		header = []string{"sources:\n", "  feeds:\n"}
+		indent = "    "
+	}
+
+	var insert []string
+	insert = append(insert, header...)
+	if comment != "" {
+		insert = append(insert, indent+"# "+comment+"\n")
+	}
+	for _, f := range feeds {
+		insert = append(insert, indent+"- url: "+strconv.Quote(f.URL)+"\n")
+		if f.Name != "" {
+			insert = append(insert, indent+"  name: "+strconv.Quote(f.Name)+"\n")
+		}
+		if f.Type != "" {
+			insert = append(insert, indent+"  type: "+strconv.Quote(f.Type)+"\n")
+		}
+	}
+
+	var out bytes.Buffer
+	for _, l := range lines[:at] {
+		out.WriteString(l)
+	}
+	for _, l := range insert {
+		out.WriteString(l)
+	}
+	for _, l := range lines[at:] {
+		out.WriteString(l)
+	}
+	return out.Bytes(), nil
+}
+
+// RemoveFeed deletes the feed with url from sources.feeds of the config
+// file at path and returns it. Like AddFeeds it edits the file as text:
+// the lines of the feed's list item go, and everything else stays.
+func RemoveFeed(path, url string) (Feed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Feed{}, fmt.Errorf("reading config: %w", err)
+	}
+	cfg, err := parse(data)
+	if err != nil {
+		return Feed{}, err
+	}
+	removed, ok := cfg.Sources.FindFeed(url)
+	if !ok {
+		return Feed{}, fmt.Errorf("%s is not in sources.feeds of %s", url, path)
+	}
+	i := slices.IndexFunc(cfg.Sources.Feeds, func(f Feed) bool { return f.URL == removed.URL })
+
+	edited, err := deleteFeed(data, i)
+	if err != nil {
+		return Feed{}, err
+	}
+	check, err := parse(edited)
+	if err != nil {
+		return Feed{}, fmt.Errorf("removing the feed would break the config: %w", err)
+	}
+	want := slices.Delete(slices.Clone(cfg.Sources.Feeds), i, i+1)
+	if !slices.EqualFunc(check.Sources.Feeds, want, func(a, b Feed) bool { return a.URL == b.URL }) {
+		return Feed{}, fmt.Errorf("removing %s from %s: sources.feeds could not be edited safely", url, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Feed{}, err
+	}
+	if err := os.WriteFile(path, edited, info.Mode().Perm()); err != nil {
+		return Feed{}, fmt.Errorf("writing config: %w", err)
+	}
+	return removed, nil
+}
+
+// deleteFeed returns data without the ith item of sources.feeds: from its
+// "- " through the last line indented further, which takes in continued
+// values and the item's own comments.
+func deleteFeed(data []byte, i int) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	var root *yaml.Node
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		root = doc.Content[0]
+	}
+	_, sources := mappingValue(root, "sources")
+	_, list := mappingValue(sources, "feeds")
+	if list == nil || list.Kind != yaml.SequenceNode || i >= len(list.Content) {
+		return nil, fmt.Errorf("sources.feeds is not a list")
+	}
+	if list.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("sources.feeds is a flow list; write it as a block list to remove feeds")
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	item := list.Content[i]
+	start := item.Line - 1 // the item's first key, on the line of its "- " or the next
+	if !strings.HasPrefix(strings.TrimSpace(lines[start]), "-") {
+		start--
+	}
+	dash := strings.Index(lines[start], "-")
+	end := lastLine(item) // index of the first line after the item
+	for end < len(lines) {
+		line := strings.TrimRight(lines[end], "\n")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || len(line)-len(trimmed) <= dash {
+			break
+		}
+		end++
+	}
+	return []byte(strings.Join(slices.Delete(lines, start, end), "")), nil
+}
+
+// mappingValue returns the key and value nodes of key in a mapping node.
+func mappingValue(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// lastLine returns the line number of the last scalar under n, which is
+// where a list item of single-line values ends.
+func lastLine(n *yaml.Node) int {
+	line := n.Line
+	for _, c := range n.Content {
+		line = max(line, lastLine(c))
+	}
+	return line
+}
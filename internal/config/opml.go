@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// opml is the subset of an OPML subscription list that feeds need.
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ParseOPML returns the feeds of an OPML subscription list, as exported by
+// RSS readers, in the order they are listed; a URL listed twice is returned
+// once. Readers file feeds in folders: a feed in a folder named after one
+// of types (case-insensitively) gets that type, as WriteOPML files them.
+func ParseOPML(r io.Reader, types []string) ([]Feed, error) {
+	var doc opml
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing OPML: %w", err)
+	}
+
+	var feeds []Feed
+	seen := make(map[string]bool)
+	var walk func(outlines []opmlOutline, folder string)
+	walk = func(outlines []opmlOutline, folder string) {
+		for _, o := range outlines {
+			url := strings.TrimSpace(o.XMLURL)
+			if url == "" {
+				walk(o.Outlines, strings.TrimSpace(strings.ToLower(o.firstTitle())))
+				continue
+			}
+			if seen[feedKey(url)] {
+				continue
+			}
+			seen[feedKey(url)] = true
+			f := Feed{URL: url, Name: strings.TrimSpace(o.firstTitle())}
+			if f.Name == url {
+				f.Name = "" // WriteOPML's stand-in for a feed without a name
+			}
+			if slices.Contains(types, folder) {
+				f.Type = folder
+			}
+			feeds = append(feeds, f)
+		}
+	}
+	walk(doc.Body, "")
+	return feeds, nil
+}
+
+func (o opmlOutline) firstTitle() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// WriteOPML writes feeds as an OPML subscription list titled title. Feeds
+// with a type are filed in a folder named after it, so ParseOPML reads the
+// type back; the others are listed at the top level. Scraped pages and
+// sitemaps aren't feeds an RSS reader could follow and are left out.
+func WriteOPML(w io.Writer, title string, feeds []Feed) error {
+	doc := opml{Version: "2.0", Title: title}
+	folders := make(map[string]int) // type → index of its folder in doc.Body
+	for _, f := range feeds {
+		if f.Scrape != nil || f.Sitemap != nil {
+			continue
+		}
+		o := opmlOutline{Text: f.Name, Title: f.Name, Type: "rss", XMLURL: f.URL}
+		if o.Text == "" {
+			o.Text = f.URL
+		}
+		if f.Type == "" {
+			doc.Body = append(doc.Body, o)
+			continue
+		}
+		i, ok := folders[f.Type]
+		if !ok {
+			i = len(doc.Body)
+			folders[f.Type] = i
+			doc.Body = append(doc.Body, opmlOutline{Text: f.Type, Title: f.Type})
+		}
+		doc.Body[i].Outlines = append(doc.Body[i].Outlines, o)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// FindFeed returns the feed of sources.feeds with url, matched like
+// AddFeeds matches them.
+func (s Sources) FindFeed(url string) (Feed, bool) {
+	i := slices.IndexFunc(s.Feeds, func(f Feed) bool { return feedKey(f.URL) == feedKey(url) })
+	if i < 0 {
+		return Feed{}, false
+	}
+	return s.Feeds[i], true
+}
+
+// feedKey identifies a feed URL for deduplication, ignoring a trailing
+// slash and the case of the scheme and host.
+func feedKey(url string) string {
+	url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok {
+		return url
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	return strings.ToLower(scheme) + "://" + strings.ToLower(host) + "/" + path
+}
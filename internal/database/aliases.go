@@ -0,0 +1,152 @@
+package database
+
+import "database/sql"
+
+// ResolveCanonicalURL records that the article with articleID is a copy of
+// canonicalURL, as declared by the page's <link rel="canonical">. If another
+// article already has that URL (or an alias of it), the copy is merged into
+// it: triage, feedback, storyline membership and extracted facts move to the
+// canonical row where it has none of its own, and the copy is deleted.
+// Otherwise the article itself becomes canonical and takes the URL. Either
+// way the article's old URL is kept as an alias, so collecting it again
+// counts as a duplicate. It returns the ID of the canonical article.
+func (db *DB) ResolveCanonicalURL(articleID int64, canonicalURL string) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var oldURL string
+	if err := tx.QueryRow(`SELECT url FROM articles WHERE id = ?`, articleID).Scan(&oldURL); err != nil {
+		return 0, err
+	}
+	if oldURL == canonicalURL {
+		return articleID, nil
+	}
+
+	canonicalID, err := articleIDForURL(tx, canonicalURL)
+	if err != nil {
+		return 0, err
+	}
+	if canonicalID == 0 || canonicalID == articleID {
+		if _, err := tx.Exec(`UPDATE articles SET url = ? WHERE id = ?`, canonicalURL, articleID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM article_aliases WHERE url = ?`, canonicalURL); err != nil {
+			return 0, err
+		}
+		canonicalID = articleID
+	} else if err := mergeArticle(tx, articleID, canonicalID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT OR REPLACE INTO article_aliases (url, article_id) VALUES (?, ?)`, oldURL, canonicalID,
+	); err != nil {
+		return 0, err
+	}
+	return canonicalID, tx.Commit()
+}
+
+// GetArticleAliases returns the other URLs an article was collected under.
+func (db *DB) GetArticleAliases(articleID int64) ([]string, error) {
+	rows, err := db.conn.Query(`SELECT url FROM article_aliases WHERE article_id = ? ORDER BY url`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, rows.Err()
+}
+
+// articleIDForURL returns the article stored under url or aliased to it, or
+// 0 if there is none.
+func articleIDForURL(tx *sql.Tx, url string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		`SELECT id FROM articles WHERE url = ?
+		UNION ALL SELECT article_id FROM article_aliases WHERE url = ?
+		LIMIT 1`, url, url,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// mergeArticle folds the article dupID into canonicalID and deletes it. Rows
+// keyed by article (triage, feedback, release scans) move only when the
+// canonical article has none; the canonical article's own judgement wins.
+func mergeArticle(tx *sql.Tx, dupID, canonicalID int64) error {
+	// Storylines of the copy get the canonical article in its place or lose
+	// it, so what was written about them no longer matches.
+	if _, err := tx.Exec(markStale+articleStorylines, dupID); err != nil {
+		return err
+	}
+	stmts := []string{
+		`UPDATE OR IGNORE article_triage SET article_id = ? WHERE article_id = ?`,
+		`UPDATE OR IGNORE article_feedback SET article_id = ? WHERE article_id = ?`,
+		`UPDATE OR IGNORE storyline_articles SET article_id = ? WHERE article_id = ?`,
+		`UPDATE OR IGNORE model_release_scans SET article_id = ? WHERE article_id = ?`,
+		`UPDATE OR IGNORE policy_updates SET article_id = ? WHERE article_id = ?`,
+		`UPDATE events SET article_id = ? WHERE article_id = ?`,
+		`UPDATE benchmark_results SET article_id = ? WHERE article_id = ?`,
+		`UPDATE model_releases SET article_id = ? WHERE article_id = ?`,
+		`UPDATE article_aliases SET article_id = ? WHERE article_id = ?`,
+		`UPDATE feed_items SET article_id = ? WHERE article_id = ?`,
+		`UPDATE articles SET duplicate_of = ? WHERE duplicate_of = ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, canonicalID, dupID); err != nil {
+			return err
+		}
+	}
+
+	// The copy's fetched text stands in for a canonical article that has none.
+	if _, err := tx.Exec(
+		`UPDATE articles SET content = (SELECT content FROM articles WHERE id = ?), content_fetched = 1
+		WHERE id = ? AND (content IS NULL OR content = '')
+		AND (SELECT content FROM articles WHERE id = ?) <> ''`, dupID, canonicalID, dupID,
+	); err != nil {
+		return err
+	}
+	// So does its lead image.
+	if _, err := tx.Exec(
+		`UPDATE articles SET image_url = (SELECT image_url FROM articles WHERE id = ?) WHERE id = ? AND image_url IS NULL`,
+		dupID, canonicalID,
+	); err != nil {
+		return err
+	}
+
+	// Whatever could not move (the copy's triage or feedback when the canonical
+	// article has its own, or its place in a storyline the canonical article
+	// shares) is dropped before the copy itself.
+	for _, table := range []string{"article_triage", "article_feedback", "storyline_articles"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE article_id = ?`, dupID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM articles WHERE id = ?`, dupID); err != nil {
+		return err
+	}
+	// A canonical article found to be a near-duplicate of its copy is now
+	// the one kept.
+	if _, err := tx.Exec(`UPDATE articles SET duplicate_of = NULL WHERE id = ? AND duplicate_of = id`, canonicalID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		`UPDATE storylines SET article_count =
+			(SELECT COUNT(*) FROM storyline_articles WHERE storyline_id = storylines.id)
+		WHERE id IN (SELECT storyline_id FROM storyline_articles WHERE article_id = ?)`, canonicalID,
+	)
+	return err
+}
@@ -2,10 +2,20 @@ package database
 
 import (
 	"database/sql"
+	"strings"
+	"time"
 )
 
 // InsertArticle inserts an article. Returns the ID on success, 0 if duplicate.
+// A URL recorded as an alias of a canonical article counts as a duplicate.
+// Feedback imported for the URL before the article was collected is moved
+// onto it.
 func (db *DB) InsertArticle(url, title string, source, publishedDate, content, periodID *string) (int64, error) {
+	var aliased int
+	db.conn.QueryRow(`SELECT COUNT(*) FROM article_aliases WHERE url = ?`, url).Scan(&aliased)
+	if aliased > 0 {
+		return 0, nil
+	}
 	result, err := db.conn.Exec(
 		`INSERT INTO articles (url, title, source, published_date, content, period_id)
 		VALUES (?, ?, ?, ?, ?, ?)`,
@@ -15,13 +25,54 @@ func (db *DB) InsertArticle(url, title string, source, publishedDate, content, p
 		// Duplicate URL constraint
 		return 0, nil //nolint: nilerr
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO article_feedback (article_id, rating, created_at)
+		SELECT ?, rating, created_at FROM imported_feedback WHERE url = ?`, id, url,
+	); err == nil {
+		db.conn.Exec(`DELETE FROM imported_feedback WHERE url = ?`, url)
+	}
+	return id, nil
+}
+
+// SetArticleFeed records the category and weight of the feed an article
+// was collected from.
+func (db *DB) SetArticleFeed(articleID int64, category *string, weight float64) error {
+	_, err := db.conn.Exec(
+		"UPDATE articles SET category = ?, source_weight = ? WHERE id = ?",
+		category, weight, articleID,
+	)
+	return err
+}
+
+// AssignPendingArticles moves articles that arrived without a period (via
+// feed polling or the ingest API) into periodID, returning the IDs of the
+// adopted articles.
+func (db *DB) AssignPendingArticles(periodID string) ([]int64, error) {
+	rows, err := db.conn.Query(`UPDATE articles SET period_id = ? WHERE period_id IS NULL RETURNING id`, periodID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
 // GetArticlesForPeriod returns articles for a given period, ordered by collected_at DESC.
 func (db *DB) GetArticlesForPeriod(periodID string) ([]Article, error) {
 	rows, err := db.conn.Query(
-		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at
+		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at, category, source_weight, paywalled, language, image_url
 		FROM articles WHERE period_id = ? ORDER BY collected_at DESC`, periodID,
 	)
 	if err != nil {
@@ -31,10 +82,34 @@ func (db *DB) GetArticlesForPeriod(periodID string) ([]Article, error) {
 	return scanArticles(rows)
 }
 
-// GetArticlesNeedingFetch returns articles with empty content that haven't been fetched.
+// GetArticlesNeedingFetch returns articles with empty content that haven't
+// been fetched, and those queued to be fetched again, leaving out those
+// waiting to retry a failed fetch.
 func (db *DB) GetArticlesNeedingFetch(periodID *string) ([]Article, error) {
-	query := `SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at
-		FROM articles WHERE (content IS NULL OR content = '') AND content_fetched = 0`
+	return db.articlesToFetch(periodID, `(content_fetched = 0 OR refetch_requested = 1)
+		AND (fetch_retry_at IS NULL OR fetch_retry_at <= datetime('now'))`)
+}
+
+// GetFailedFetches returns articles with empty content, or queued to be
+// fetched again, whose last fetch failed transiently, whether they wait to
+// be retried or were given up on.
+func (db *DB) GetFailedFetches(periodID *string) ([]Article, error) {
+	return db.articlesToFetch(periodID, "fetch_retries > 0")
+}
+
+// GetArticlesToRefetch returns the articles of every period queued to be
+// fetched again, leaving out those waiting to retry a failed fetch.
+func (db *DB) GetArticlesToRefetch() ([]Article, error) {
+	return db.articlesToFetch(nil, `refetch_requested = 1
+		AND (fetch_retry_at IS NULL OR fetch_retry_at <= datetime('now'))`)
+}
+
+// articlesToFetch returns the articles with empty content, or queued to be
+// fetched again, that match the condition, of a period or of all when
+// periodID is nil, newest first.
+func (db *DB) articlesToFetch(periodID *string, condition string) ([]Article, error) {
+	query := `SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at, category, source_weight, paywalled, language, image_url
+		FROM articles WHERE (content IS NULL OR content = '' OR refetch_requested = 1) AND ` + condition
 	var args []any
 	if periodID != nil {
 		query += " AND period_id = ?"
@@ -50,29 +125,112 @@ func (db *DB) GetArticlesNeedingFetch(periodID *string) ([]Article, error) {
 	return scanArticles(rows)
 }
 
-// UpdateArticleContent updates article content after fetching.
+// UpdateArticleContent updates article content after fetching, undoing any
+// translation and near-duplicate mark so the new text is detected,
+// translated and compared again. Narratives already written from the
+// article without it become stale.
 func (db *DB) UpdateArticleContent(articleID int64, content *string) error {
-	_, err := db.conn.Exec(
-		"UPDATE articles SET content = ?, content_fetched = 1 WHERE id = ?",
+	if _, err := db.conn.Exec(
+		`UPDATE articles SET content = ?, content_fetched = 1, paywalled = 0, fetch_retry_at = NULL, fetch_error = NULL,
+		refetch_requested = 0, language = NULL, title = COALESCE(original_title, title), original_title = NULL, original_content = NULL,
+		simhash = NULL, duplicate_of = NULL WHERE id = ?`,
 		content, articleID,
+	); err != nil || content == nil {
+		return err
+	}
+	_, err := db.conn.Exec(markStale+articleStorylines, articleID)
+	return err
+}
+
+// RefreshArticleContent stores the content an article queued to be fetched
+// again was fetched with, like UpdateArticleContent, when it differs from
+// the content fetched before, and reports whether it did. When the text is
+// the same the article is only taken off the queue, so its translation,
+// fingerprint and narratives stand.
+func (db *DB) RefreshArticleContent(articleID int64, content string) (bool, error) {
+	res, err := db.conn.Exec(
+		`UPDATE articles SET refetch_requested = 0, fetch_retries = 0, fetch_retry_at = NULL, fetch_error = NULL
+		WHERE id = ? AND COALESCE(original_content, content) = ?`,
+		articleID, content,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return false, err
+	}
+	return true, db.UpdateArticleContent(articleID, &content)
+}
+
+// QueueArticleRefetch queues an article collected in the last days to be
+// fetched again, as when its feed entry was updated, and reports whether it
+// did. Content fetched before is kept until the new page is read.
+func (db *DB) QueueArticleRefetch(articleID int64, days int) (bool, error) {
+	res, err := db.conn.Exec(
+		`UPDATE articles SET refetch_requested = 1, fetch_retries = 0, fetch_retry_at = NULL
+		WHERE id = ? AND collected_at >= datetime('now', '-' || ? || ' days')`,
+		articleID, days,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SetArticleImage stores the URL of the lead image of an article's page.
+func (db *DB) SetArticleImage(articleID int64, imageURL string) error {
+	_, err := db.conn.Exec("UPDATE articles SET image_url = ? WHERE id = ?", imageURL, articleID)
+	return err
+}
+
+// MarkArticleFetchFailed records a fetch that failed for good, such as a
+// page that is gone, so the article isn't fetched again. Content fetched
+// before is kept.
+func (db *DB) MarkArticleFetchFailed(articleID int64, reason string) error {
+	_, err := db.conn.Exec(
+		"UPDATE articles SET content_fetched = 1, refetch_requested = 0, fetch_retries = 0, fetch_retry_at = NULL, fetch_error = ? WHERE id = ?",
+		reason, articleID,
 	)
 	return err
 }
 
-// MarkArticleFetchAttempted marks that we tried to fetch content.
-func (db *DB) MarkArticleFetchAttempted(articleID int64) error {
+// MarkArticlePaywalled records that an article's page is behind a paywall.
+// Its teaser isn't stored as content, which would pass for the article, and
+// it isn't fetched again.
+func (db *DB) MarkArticlePaywalled(articleID int64) error {
 	_, err := db.conn.Exec(
-		"UPDATE articles SET content_fetched = 1 WHERE id = ?", articleID,
+		"UPDATE articles SET content_fetched = 1, paywalled = 1, refetch_requested = 0, fetch_retries = 0, fetch_retry_at = NULL, fetch_error = 'paywalled' WHERE id = ?",
+		articleID,
 	)
 	return err
 }
 
-// GetUntriagedArticles returns articles that haven't been triaged yet.
+// ScheduleFetchRetry records a fetch that failed transiently. The article
+// is fetched again once backoff has passed, doubled for every earlier
+// retry, until it has been retried maxRetries times; after that it is given
+// up on like a permanent failure, and taken off the queue of articles to
+// fetch again. It reports whether a retry is scheduled.
+func (db *DB) ScheduleFetchRetry(articleID int64, reason string, maxRetries int, backoff time.Duration) (bool, error) {
+	var retries int
+	err := db.conn.QueryRow(
+		`UPDATE articles SET fetch_retries = fetch_retries + 1, fetch_error = ?,
+			content_fetched = fetch_retries + 1 > ?,
+			refetch_requested = refetch_requested AND fetch_retries + 1 <= ?,
+			fetch_retry_at = datetime('now', '+' || (? << fetch_retries) || ' seconds')
+		WHERE id = ? RETURNING fetch_retries`,
+		reason, maxRetries, maxRetries, int64(backoff.Seconds()), articleID,
+	).Scan(&retries)
+	return retries <= maxRetries, err
+}
+
+// GetUntriagedArticles returns articles that haven't been triaged yet,
+// leaving out near-duplicates.
 func (db *DB) GetUntriagedArticles(periodID *string) ([]Article, error) {
 	query := `SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
-		a.content_fetched, a.period_id, a.collected_at
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url
 		FROM articles a LEFT JOIN article_triage t ON a.id = t.article_id
-		WHERE t.article_id IS NULL`
+		WHERE t.article_id IS NULL AND a.duplicate_of IS NULL`
 	var args []any
 	if periodID != nil {
 		query += " AND a.period_id = ?"
@@ -88,13 +246,14 @@ func (db *DB) GetUntriagedArticles(periodID *string) ([]Article, error) {
 	return scanArticles(rows)
 }
 
-// GetRelevantArticles returns articles triaged as relevant for a period.
+// GetRelevantArticles returns articles triaged as relevant for a period,
+// leaving out those found to be near-duplicates since.
 func (db *DB) GetRelevantArticles(periodID string) ([]Article, error) {
 	rows, err := db.conn.Query(
 		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
-		a.content_fetched, a.period_id, a.collected_at
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url
 		FROM articles a JOIN article_triage t ON a.id = t.article_id
-		WHERE a.period_id = ? AND t.verdict = 'relevant'
+		WHERE a.period_id = ? AND t.verdict = 'relevant' AND a.duplicate_of IS NULL
 		ORDER BY t.practical_score DESC`, periodID,
 	)
 	if err != nil {
@@ -104,10 +263,29 @@ func (db *DB) GetRelevantArticles(periodID string) ([]Article, error) {
 	return scanArticles(rows)
 }
 
+// GetUnclusteredArticles returns the relevant articles of a period that are
+// in none of its storylines, ordered by practical score.
+func (db *DB) GetUnclusteredArticles(periodID string) ([]Article, error) {
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url
+		FROM articles a JOIN article_triage t ON a.id = t.article_id
+		WHERE a.period_id = ? AND t.verdict = 'relevant' AND a.duplicate_of IS NULL
+		AND a.id NOT IN (SELECT sa.article_id FROM storyline_articles sa
+			JOIN storylines s ON s.id = sa.storyline_id WHERE s.period_id = ?)
+		ORDER BY t.practical_score DESC`, periodID, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
+
 // GetArticleByID returns a single article by ID.
 func (db *DB) GetArticleByID(articleID int64) (*Article, error) {
 	row := db.conn.QueryRow(
-		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at
+		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at, category, source_weight, paywalled, language, image_url
 		FROM articles WHERE id = ?`, articleID,
 	)
 	a, err := scanArticle(row)
@@ -120,13 +298,40 @@ func (db *DB) GetArticleByID(articleID int64) (*Article, error) {
 	return a, nil
 }
 
+// SearchArticles returns the most recent articles whose title or content
+// contains query (case-insensitive), up to limit.
+func (db *DB) SearchArticles(query string, limit int) ([]Article, error) {
+	return db.SearchArticlesSince(query, "", limit)
+}
+
+// SearchArticlesSince is SearchArticles for the articles of periods from
+// since on; an empty since searches every article.
+func (db *DB) SearchArticlesSince(query, since string, limit int) ([]Article, error) {
+	pattern := "%" + escapeLike(query) + "%"
+	rows, err := db.conn.Query(
+		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at, category, source_weight, paywalled, language, image_url
+		FROM articles WHERE (title LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\')
+		AND (? = '' OR period_id >= ?)
+		ORDER BY collected_at DESC, id DESC LIMIT ?`, pattern, pattern, since, since, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func scanArticles(rows *sql.Rows) ([]Article, error) {
 	var articles []Article
 	for rows.Next() {
 		var a Article
 		var fetched int
 		if err := rows.Scan(&a.ID, &a.URL, &a.Title, &a.Source, &a.PublishedDate,
-			&a.Content, &fetched, &a.PeriodID, &a.CollectedAt); err != nil {
+			&a.Content, &fetched, &a.PeriodID, &a.CollectedAt, &a.Category, &a.SourceWeight, &a.Paywalled, &a.Language, &a.ImageURL); err != nil {
 			return nil, err
 		}
 		a.ContentFetched = fetched != 0
@@ -139,7 +344,7 @@ func scanArticle(row *sql.Row) (*Article, error) {
 	var a Article
 	var fetched int
 	if err := row.Scan(&a.ID, &a.URL, &a.Title, &a.Source, &a.PublishedDate,
-		&a.Content, &fetched, &a.PeriodID, &a.CollectedAt); err != nil {
+		&a.Content, &fetched, &a.PeriodID, &a.CollectedAt, &a.Category, &a.SourceWeight, &a.Paywalled, &a.Language, &a.ImageURL); err != nil {
 		return nil, err
 	}
 	a.ContentFetched = fetched != 0
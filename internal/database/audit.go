@@ -0,0 +1,75 @@
+package database
+
+// Actions of the audit log. The target of an entry is the storyline,
+// article, priority, mute rule, profile or job its action names.
+const (
+	AuditStorylineFeedback = "storyline_feedback"
+	AuditArticleFeedback   = "article_feedback"
+	AuditStorylineAdd      = "storyline_add"
+	AuditStorylineRemove   = "storyline_remove"
+	AuditPriorityAdd       = "priority_add"
+	AuditPriorityEdit      = "priority_edit"
+	AuditPriorityToggle    = "priority_toggle"
+	AuditPriorityAssign    = "priority_assign"
+	AuditPriorityDelete    = "priority_delete"
+	AuditMuteAdd           = "mute_add"
+	AuditMuteDelete        = "mute_delete"
+	AuditProfileAdd        = "profile_add"
+	AuditProfileDelete     = "profile_delete"
+	AuditJobRetry          = "job_retry"
+	AuditImport            = "import"
+)
+
+// RecordAudit logs a manual edit by user. A targetID of 0 records no target.
+func (db *DB) RecordAudit(user, action string, targetID int64, detail string) error {
+	var target *int64
+	if targetID != 0 {
+		target = &targetID
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO audit_log (user, action, target_id, detail) VALUES (?, ?, ?, ?)`,
+		user, action, target, detail,
+	)
+	return err
+}
+
+var auditSorts = map[string]string{
+	"created": "created_at",
+	"user":    "user COLLATE NOCASE",
+	"action":  "action",
+}
+
+// ListAuditEntries returns a page of the audit entries matching f and the
+// number of such entries across all pages. Sort keys are "created" (the
+// default, newest first), "user" and "action".
+func (db *DB) ListAuditEntries(f AuditFilter, opts ListOptions) ([]AuditEntry, int, error) {
+	where := ` WHERE (? = '' OR user = ? COLLATE NOCASE) AND (? = '' OR action = ?)`
+	args := []any{f.User, f.User, f.Action, f.Action}
+
+	clause, pageArgs, err := listClause(opts, auditSorts, ListOptions{Sort: "created", Desc: true}, "id")
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.conn.Query(
+		`SELECT id, user, action, target_id, detail, COALESCE(created_at, '') FROM audit_log`+where+clause,
+		append(args, pageArgs...)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.User, &e.Action, &e.TargetID, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,86 @@
+package database
+
+// InsertBenchmarkResult records a reported benchmark score. Scores are
+// assumed to be higher-is-better. A result is marked SOTA when the article
+// claims state of the art or when it beats every score recorded for the
+// benchmark on or before reportedDate. Repeats of the same model, benchmark
+// and score are stored once; returns 0 then.
+func (db *DB) InsertBenchmarkResult(articleID int64, model, benchmark string, score float64, reportedDate string, claimedSOTA bool) (int64, error) {
+	sota := claimedSOTA
+	if !sota {
+		var best *float64
+		err := db.conn.QueryRow(
+			`SELECT MAX(score) FROM benchmark_results
+			WHERE benchmark = ? COLLATE NOCASE AND reported_date <= ?`, benchmark, reportedDate,
+		).Scan(&best)
+		if err != nil {
+			return 0, err
+		}
+		sota = best != nil && score > *best
+	}
+
+	result, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO benchmark_results
+		(article_id, model, benchmark, score, reported_date, sota)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		articleID, model, benchmark, score, reportedDate, sota,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+	return result.LastInsertId()
+}
+
+// GetBenchmarkResults returns all recorded results grouped by benchmark,
+// oldest first within each benchmark.
+func (db *DB) GetBenchmarkResults() ([]BenchmarkResult, error) {
+	rows, err := db.conn.Query(
+		`SELECT b.id, b.article_id, b.model, b.benchmark, b.score, b.reported_date, b.sota, a.url, b.created_at
+		FROM benchmark_results b LEFT JOIN articles a ON a.id = b.article_id
+		ORDER BY b.benchmark COLLATE NOCASE, b.reported_date, b.score`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []BenchmarkResult
+	for rows.Next() {
+		var r BenchmarkResult
+		if err := rows.Scan(&r.ID, &r.ArticleID, &r.Model, &r.Benchmark, &r.Score,
+			&r.ReportedDate, &r.SOTA, &r.ArticleURL, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetSOTAStorylines returns the IDs of storylines in a period that contain an
+// article with a state-of-the-art benchmark result.
+func (db *DB) GetSOTAStorylines(periodID string) (map[int64]bool, error) {
+	rows, err := db.conn.Query(
+		`SELECT DISTINCT sa.storyline_id
+		FROM storyline_articles sa
+		JOIN storylines s ON s.id = sa.storyline_id
+		JOIN benchmark_results b ON b.article_id = sa.article_id
+		WHERE s.period_id = ? AND b.sota = 1`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
@@ -2,16 +2,42 @@ package database
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 )
 
-// InsertBriefing inserts or replaces a briefing for a period.
+// ErrPeriodNotFound is matched by errors about a period without the
+// briefing they need, such as a BriefingNotFoundError.
+var ErrPeriodNotFound = errors.New("period not found")
+
+// BriefingNotFoundError reports that a period has no briefing of an edition.
+type BriefingNotFoundError struct {
+	PeriodID string
+	Edition  string
+}
+
+func (e *BriefingNotFoundError) Error() string {
+	return fmt.Sprintf("no %s briefing for %s", e.Edition, e.PeriodID)
+}
+
+// Is makes a BriefingNotFoundError match ErrPeriodNotFound.
+func (e *BriefingNotFoundError) Is(target error) bool { return target == ErrPeriodNotFound }
+
+const briefingColumns = "id, period_id, edition, tldr, body_markdown, storyline_count, article_count, generated_at, quality_note"
+
+// InsertBriefing inserts or replaces the morning (full) briefing for a period.
 func (db *DB) InsertBriefing(periodID, tldr, bodyMarkdown string, storylineCount, articleCount int) (int64, error) {
+	return db.InsertBriefingEdition(periodID, EditionMorning, tldr, bodyMarkdown, storylineCount, articleCount)
+}
+
+// InsertBriefingEdition inserts or replaces a specific edition of a period's briefing.
+func (db *DB) InsertBriefingEdition(periodID, edition, tldr, bodyMarkdown string, storylineCount, articleCount int) (int64, error) {
 	result, err := db.conn.Exec(
 		`INSERT OR REPLACE INTO briefings
-		(period_id, tldr, body_markdown, storyline_count, article_count)
-		VALUES (?, ?, ?, ?, ?)`,
-		periodID, tldr, bodyMarkdown, storylineCount, articleCount,
+		(period_id, edition, tldr, body_markdown, storyline_count, article_count)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		periodID, edition, tldr, bodyMarkdown, storylineCount, articleCount,
 	)
 	if err != nil {
 		return 0, err
@@ -19,16 +45,31 @@ func (db *DB) InsertBriefing(periodID, tldr, bodyMarkdown string, storylineCount
 	return result.LastInsertId()
 }
 
-// GetBriefing returns the briefing for a period.
+// SetBriefingQualityNote records how a briefing edition was degraded by
+// partial failures; nil clears the note. Recomposing an edition clears it too.
+func (db *DB) SetBriefingQualityNote(periodID, edition string, note *string) error {
+	_, err := db.conn.Exec(
+		"UPDATE briefings SET quality_note = ? WHERE period_id = ? AND edition = ?",
+		note, periodID, edition,
+	)
+	return err
+}
+
+// GetBriefing returns the morning (full) briefing for a period.
 func (db *DB) GetBriefing(periodID string) (*Briefing, error) {
+	return db.GetBriefingEdition(periodID, EditionMorning)
+}
+
+// GetBriefingEdition returns a specific edition of a period's briefing.
+func (db *DB) GetBriefingEdition(periodID, edition string) (*Briefing, error) {
 	row := db.conn.QueryRow(
-		`SELECT id, period_id, tldr, body_markdown, storyline_count, article_count, generated_at
-		FROM briefings WHERE period_id = ?`, periodID,
+		"SELECT "+briefingColumns+" FROM briefings WHERE period_id = ? AND edition = ?",
+		periodID, edition,
 	)
 
 	var b Briefing
-	if err := row.Scan(&b.ID, &b.PeriodID, &b.TLDR, &b.BodyMarkdown,
-		&b.StorylineCount, &b.ArticleCount, &b.GeneratedAt); err != nil {
+	if err := row.Scan(&b.ID, &b.PeriodID, &b.Edition, &b.TLDR, &b.BodyMarkdown,
+		&b.StorylineCount, &b.ArticleCount, &b.GeneratedAt, &b.QualityNote); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -37,10 +78,33 @@ func (db *DB) GetBriefing(periodID string) (*Briefing, error) {
 	return &b, nil
 }
 
-// GetAllBriefings returns all briefings ordered by period_id DESC.
+// GetBriefingEditions returns the editions available for a period, in
+// publication order.
+func (db *DB) GetBriefingEditions(periodID string) ([]string, error) {
+	rows, err := db.conn.Query(
+		"SELECT edition FROM briefings WHERE period_id = ? ORDER BY generated_at, id", periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var editions []string
+	for rows.Next() {
+		var e string
+		if err := rows.Scan(&e); err != nil {
+			return nil, err
+		}
+		editions = append(editions, e)
+	}
+	return editions, rows.Err()
+}
+
+// GetAllBriefings returns all briefings ordered by period_id DESC, latest
+// edition first within a period.
 func (db *DB) GetAllBriefings() ([]Briefing, error) {
 	rows, err := db.conn.Query(
-		"SELECT id, period_id, tldr, body_markdown, storyline_count, article_count, generated_at FROM briefings ORDER BY period_id DESC",
+		"SELECT " + briefingColumns + " FROM briefings ORDER BY period_id DESC, generated_at DESC, id DESC",
 	)
 	if err != nil {
 		return nil, err
@@ -50,8 +114,8 @@ func (db *DB) GetAllBriefings() ([]Briefing, error) {
 	var briefings []Briefing
 	for rows.Next() {
 		var b Briefing
-		if err := rows.Scan(&b.ID, &b.PeriodID, &b.TLDR, &b.BodyMarkdown,
-			&b.StorylineCount, &b.ArticleCount, &b.GeneratedAt); err != nil {
+		if err := rows.Scan(&b.ID, &b.PeriodID, &b.Edition, &b.TLDR, &b.BodyMarkdown,
+			&b.StorylineCount, &b.ArticleCount, &b.GeneratedAt, &b.QualityNote); err != nil {
 			return nil, err
 		}
 		briefings = append(briefings, b)
@@ -59,6 +123,18 @@ func (db *DB) GetAllBriefings() ([]Briefing, error) {
 	return briefings, rows.Err()
 }
 
+// GetAdjacentBriefingPeriods returns the periods of the briefings
+// immediately before and after periodID, or empty strings at either end of
+// the archive.
+func (db *DB) GetAdjacentBriefingPeriods(periodID string) (previous, next string, err error) {
+	err = db.conn.QueryRow(
+		`SELECT COALESCE((SELECT MAX(period_id) FROM briefings WHERE period_id < ?), ''),
+		COALESCE((SELECT MIN(period_id) FROM briefings WHERE period_id > ?), '')`,
+		periodID, periodID,
+	).Scan(&previous, &next)
+	return previous, next, err
+}
+
 // InsertReport inserts or replaces a run report.
 func (db *DB) InsertReport(periodID string, articleCount, storylineCount int) (int64, error) {
 	result, err := db.conn.Exec(
@@ -72,6 +148,16 @@ func (db *DB) InsertReport(periodID string, articleCount, storylineCount int) (i
 	return result.LastInsertId()
 }
 
+// GetLastReportTime returns when the most recent run report was written,
+// in SQLite's UTC "YYYY-MM-DD HH:MM:SS" format, or "" before the first run.
+func (db *DB) GetLastReportTime() (string, error) {
+	var at sql.NullString
+	if err := db.conn.QueryRow("SELECT MAX(generated_at) FROM run_reports").Scan(&at); err != nil {
+		return "", err
+	}
+	return at.String, nil
+}
+
 // GetLastRunDate returns the end date from the most recent run report.
 // Returns empty string if no runs exist.
 func (db *DB) GetLastRunDate() (string, error) {
@@ -113,6 +199,7 @@ func (db *DB) GetStats() (*Stats, error) {
 		{"SELECT COUNT(*) FROM storylines", &s.Storylines},
 		{"SELECT COUNT(*) FROM research_priorities", &s.TotalPriorities},
 		{"SELECT COUNT(*) FROM research_priorities WHERE is_active = 1", &s.ActivePriorities},
+		{"SELECT COUNT(*) FROM triage_failures", &s.FailedTriage},
 	}
 
 	for _, q := range queries {
@@ -0,0 +1,338 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BundleVersion is the format version written by ExportBundle. Version 2
+// added mute rules; version 1 bundles, without them, still import.
+const BundleVersion = 2
+
+// Bundle is a portable copy of a reader's personalization: reader profiles,
+// research priorities, mute rules and feedback. Articles and storylines are referred to
+// by URL and label rather than by ID, so a bundle can be imported into
+// another database.
+type Bundle struct {
+	Version           int                       `json:"version"`
+	ExportedAt        string                    `json:"exported_at"`
+	Profiles          []BundleProfile           `json:"profiles"`
+	Priorities        []BundlePriority          `json:"priorities"`
+	MuteRules         []BundleMuteRule          `json:"mute_rules"`
+	ArticleFeedback   []BundleArticleFeedback   `json:"article_feedback"`
+	StorylineFeedback []BundleStorylineFeedback `json:"storyline_feedback"`
+}
+
+// BundleProfile is a reader profile in a bundle.
+type BundleProfile struct {
+	Name    string `json:"name"`
+	Heading string `json:"heading"`
+}
+
+// BundlePriority is a research priority in a bundle; Profile names its
+// reader profile, if any.
+type BundlePriority struct {
+	Title       string   `json:"title"`
+	Description *string  `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Active      bool     `json:"active"`
+	Profile     string   `json:"profile,omitempty"`
+}
+
+// BundleMuteRule is a mute rule in a bundle.
+type BundleMuteRule struct {
+	Kind  string `json:"kind"` // MuteTerm or MuteSource
+	Value string `json:"value"`
+}
+
+// BundleArticleFeedback is an article rating in a bundle. Source and
+// ArticleType carry what triage learns from the rating, so it counts even
+// before the article is collected again.
+type BundleArticleFeedback struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Source      string `json:"source,omitempty"`
+	ArticleType string `json:"article_type,omitempty"`
+	Rating      string `json:"rating"`
+	RatedAt     string `json:"rated_at,omitempty"`
+}
+
+// BundleStorylineFeedback is a storyline rating in a bundle, identified by
+// period and storyline label.
+type BundleStorylineFeedback struct {
+	PeriodID  string `json:"period_id"`
+	Storyline string `json:"storyline"`
+	Rating    string `json:"rating"`
+}
+
+// ImportResult counts what ImportBundle added. Items already present locally
+// are kept as they are and counted as skipped.
+type ImportResult struct {
+	Profiles          int
+	Priorities        int
+	MuteRules         int
+	ArticleFeedback   int // attached to collected articles
+	PendingFeedback   int // kept until the article is collected
+	StorylineFeedback int
+	Skipped           int
+}
+
+// ExportBundle collects the database's profiles, priorities, mute rules
+// and feedback.
+func (db *DB) ExportBundle() (*Bundle, error) {
+	b := &Bundle{Version: BundleVersion, ExportedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	profiles, err := db.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+	profileNames := make(map[int64]string, len(profiles))
+	for _, p := range profiles {
+		profileNames[p.ID] = p.Name
+		b.Profiles = append(b.Profiles, BundleProfile{Name: p.Name, Heading: p.Heading})
+	}
+
+	priorities, err := db.GetAllPriorities()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range priorities {
+		bp := BundlePriority{Title: p.Title, Description: p.Description, Keywords: p.Keywords, Active: p.IsActive}
+		if p.ProfileID != nil {
+			bp.Profile = profileNames[*p.ProfileID]
+		}
+		b.Priorities = append(b.Priorities, bp)
+	}
+
+	rules, err := db.GetMuteRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range rules {
+		b.MuteRules = append(b.MuteRules, BundleMuteRule{Kind: m.Kind, Value: m.Value})
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT a.url, a.title, COALESCE(a.source, ''), COALESCE(t.article_type, ''), f.rating, COALESCE(f.created_at, '')
+		FROM article_feedback f
+		JOIN articles a ON a.id = f.article_id
+		LEFT JOIN article_triage t ON t.article_id = f.article_id
+		UNION ALL
+		SELECT url, title, COALESCE(source, ''), COALESCE(article_type, ''), rating, COALESCE(created_at, '')
+		FROM imported_feedback
+		ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f BundleArticleFeedback
+		if err := rows.Scan(&f.URL, &f.Title, &f.Source, &f.ArticleType, &f.Rating, &f.RatedAt); err != nil {
+			return nil, err
+		}
+		b.ArticleFeedback = append(b.ArticleFeedback, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	srows, err := db.conn.Query(`
+		SELECT f.period_id, s.label, f.rating
+		FROM storyline_feedback f JOIN storylines s ON s.id = f.storyline_id
+		ORDER BY f.period_id, s.label`)
+	if err != nil {
+		return nil, err
+	}
+	defer srows.Close()
+	for srows.Next() {
+		var f BundleStorylineFeedback
+		if err := srows.Scan(&f.PeriodID, &f.Storyline, &f.Rating); err != nil {
+			return nil, err
+		}
+		b.StorylineFeedback = append(b.StorylineFeedback, f)
+	}
+	return b, srows.Err()
+}
+
+// ImportBundle merges a bundle into the database in one transaction. Local
+// data wins: profiles with an existing name, priorities with an existing
+// title and articles or storylines already rated are left alone. Feedback on
+// articles not collected here is kept in imported_feedback, where it already
+// informs triage, and moves onto the article once it is collected.
+// Storyline feedback only applies to storylines that exist here. Mute
+// rules not muted here already are added after the transaction, with
+// InsertMuteRule, and recorded in the audit log as added by user.
+func (db *DB) ImportBundle(b *Bundle, user string) (*ImportResult, error) {
+	if b.Version < 1 || b.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	r := &ImportResult{}
+	profileIDs := make(map[string]int64)
+	for _, p := range b.Profiles {
+		result, err := tx.Exec(`INSERT OR IGNORE INTO reader_profiles (name, heading) VALUES (?, ?)`, p.Name, p.Heading)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			r.Profiles++
+		} else {
+			r.Skipped++
+		}
+	}
+	profileID := func(name string) (*int64, error) {
+		if name == "" {
+			return nil, nil
+		}
+		if id, ok := profileIDs[name]; ok {
+			return &id, nil
+		}
+		var id int64
+		err := tx.QueryRow(`SELECT id FROM reader_profiles WHERE name = ?`, name).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		profileIDs[name] = id
+		return &id, nil
+	}
+
+	for _, p := range b.Priorities {
+		var exists int
+		if err := tx.QueryRow(
+			`SELECT COUNT(*) FROM research_priorities WHERE lower(title) = ?`, strings.ToLower(p.Title),
+		).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists > 0 {
+			r.Skipped++
+			continue
+		}
+		var kwJSON *string
+		if p.Keywords != nil {
+			data, err := json.Marshal(p.Keywords)
+			if err != nil {
+				return nil, err
+			}
+			s := string(data)
+			kwJSON = &s
+		}
+		pid, err := profileID(p.Profile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO research_priorities (title, description, keywords, is_active, profile_id) VALUES (?, ?, ?, ?, ?)`,
+			p.Title, p.Description, kwJSON, p.Active, pid,
+		); err != nil {
+			return nil, err
+		}
+		r.Priorities++
+	}
+
+	for _, f := range b.ArticleFeedback {
+		if f.Rating != "positive" && f.Rating != "negative" {
+			r.Skipped++
+			continue
+		}
+		articleID, err := articleIDForURL(tx, f.URL)
+		if err != nil {
+			return nil, err
+		}
+		var result sql.Result
+		if articleID != 0 {
+			result, err = tx.Exec(
+				`INSERT OR IGNORE INTO article_feedback (article_id, rating, created_at) VALUES (?, ?, COALESCE(NULLIF(?, ''), datetime('now')))`,
+				articleID, f.Rating, f.RatedAt,
+			)
+		} else {
+			result, err = tx.Exec(
+				`INSERT OR IGNORE INTO imported_feedback (url, title, source, article_type, rating, created_at)
+				VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, COALESCE(NULLIF(?, ''), datetime('now')))`,
+				f.URL, f.Title, f.Source, f.ArticleType, f.Rating, f.RatedAt,
+			)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch n, _ := result.RowsAffected(); {
+		case n == 0:
+			r.Skipped++
+		case articleID != 0:
+			r.ArticleFeedback++
+		default:
+			r.PendingFeedback++
+		}
+	}
+
+	for _, f := range b.StorylineFeedback {
+		if f.Rating != "useful" && f.Rating != "not_useful" {
+			r.Skipped++
+			continue
+		}
+		result, err := tx.Exec(
+			`INSERT OR IGNORE INTO storyline_feedback (storyline_id, period_id, rating)
+			SELECT id, period_id, ? FROM storylines WHERE period_id = ? AND label = ? LIMIT 1`,
+			f.Rating, f.PeriodID, f.Storyline,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			r.StorylineFeedback++
+		} else {
+			r.Skipped++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r, db.importMuteRules(b.MuteRules, user, r)
+}
+
+// importMuteRules adds the mute rules of a bundle that aren't muted yet,
+// in any letter case, and records each in the audit log. Rules of an
+// unknown kind or without a value are skipped.
+func (db *DB) importMuteRules(rules []BundleMuteRule, user string, r *ImportResult) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	existing, err := db.GetMuteRules()
+	if err != nil {
+		return err
+	}
+	muted := make(map[string]bool, len(existing))
+	key := func(kind, value string) string { return kind + "\x00" + strings.ToLower(strings.TrimSpace(value)) }
+	for _, m := range existing {
+		muted[key(m.Kind, m.Value)] = true
+	}
+	for _, m := range rules {
+		k := key(m.Kind, m.Value)
+		if muted[k] || (m.Kind != MuteTerm && m.Kind != MuteSource) || strings.TrimSpace(m.Value) == "" {
+			r.Skipped++
+			continue
+		}
+		id, err := db.InsertMuteRule(m.Kind, m.Value)
+		if err != nil {
+			return err
+		}
+		muted[k] = true
+		r.MuteRules++
+		if err := db.RecordAudit(user, AuditMuteAdd, id, m.Kind+" "+strings.TrimSpace(m.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
@@ -22,7 +23,12 @@ func Open(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("creating data directory: %w", err)
 	}
 
-	conn, err := sql.Open("sqlite", dbPath)
+	// The busy timeout and foreign keys are set per connection, so they go
+	// in the DSN to reach every connection of the pool: writes from parallel
+	// pipeline workers and server handlers wait for each other instead of
+	// failing with SQLITE_BUSY, and ON DELETE clauses apply whichever
+	// connection a statement runs on.
+	conn, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -31,10 +37,6 @@ func Open(dbPath string) (*DB, error) {
 		conn.Close()
 		return nil, fmt.Errorf("setting journal mode: %w", err)
 	}
-	if _, err := conn.Exec("PRAGMA foreign_keys=ON"); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("enabling foreign keys: %w", err)
-	}
 
 	if err := migrate(conn); err != nil {
 		conn.Close()
@@ -53,3 +55,42 @@ func (db *DB) Close() error {
 func (db *DB) Path() string {
 	return db.path
 }
+
+// CheckIntegrity runs SQLite's quick check over the whole database file and
+// returns what it found as an error when the file is damaged.
+func (db *DB) CheckIntegrity() error {
+	rows, err := db.conn.Query("PRAGMA quick_check")
+	if err != nil {
+		return fmt.Errorf("checking database integrity: %w", err)
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("checking database integrity: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("checking database integrity: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("database is damaged: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// BackupTo writes a consistent copy of the database to path, which must not
+// exist yet, while it stays open for reading and writing.
+func (db *DB) BackupTo(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+	if _, err := db.conn.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("backing up database: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,15 @@
 package database
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func openTestDB(t *testing.T) *DB {
@@ -28,6 +35,22 @@ func TestInsertArticle(t *testing.T) {
 	}
 }
 
+func TestSetArticleFeed(t *testing.T) {
+	db := openTestDB(t)
+	plain, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	weighted, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, ptr("2026-02-06"))
+	if err := db.SetArticleFeed(weighted, ptr("research"), 2.5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if a, _ := db.GetArticleByID(plain); a.Category != nil || a.SourceWeight != 1 {
+		t.Errorf("expected no category and a weight of 1 by default, got %v, %g", a.Category, a.SourceWeight)
+	}
+	if a, _ := db.GetArticleByID(weighted); a.Category == nil || *a.Category != "research" || a.SourceWeight != 2.5 {
+		t.Errorf("expected the feed's category and weight, got %v, %g", a.Category, a.SourceWeight)
+	}
+}
+
 func TestInsertDuplicateArticle(t *testing.T) {
 	db := openTestDB(t)
 	_, _ = db.InsertArticle("https://example.com/dup", "First", nil, nil, nil, ptr("2026-02-06"))
@@ -73,6 +96,64 @@ func TestArticlesNeedingFetch(t *testing.T) {
 	}
 }
 
+func TestFetchRetryQueue(t *testing.T) {
+	db := openTestDB(t)
+	period := "2026-02-06"
+	waiting, _ := db.InsertArticle("https://a.com", "Waiting", nil, nil, nil, &period)
+	due, _ := db.InsertArticle("https://b.com", "Due", nil, nil, nil, &period)
+	gone, _ := db.InsertArticle("https://c.com", "Gone", nil, nil, nil, &period)
+
+	titles := func(articles []Article, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, a := range articles {
+			names = append(names, a.Title)
+		}
+		slices.Sort(names)
+		return strings.Join(names, ",")
+	}
+
+	if retry, err := db.ScheduleFetchRetry(waiting, "HTTP 503", 2, time.Hour); err != nil || !retry {
+		t.Fatalf("expected a retry to be scheduled, got %v, %v", retry, err)
+	}
+	db.ScheduleFetchRetry(due, "connection refused", 2, 0)
+	db.MarkArticleFetchFailed(gone, "HTTP 404")
+	if got := titles(db.GetArticlesNeedingFetch(&period)); got != "Due" {
+		t.Errorf("expected only the due retry to need fetching, got %q", got)
+	}
+	if got := titles(db.GetFailedFetches(nil)); got != "Due,Waiting" {
+		t.Errorf("expected the transient failures to be retryable, got %q", got)
+	}
+
+	// The third failure of an article allowed two retries gives up on it.
+	db.ScheduleFetchRetry(due, "connection refused", 2, 0)
+	if retry, _ := db.ScheduleFetchRetry(due, "connection refused", 2, 0); retry {
+		t.Error("expected no retry after the last one")
+	}
+	if got := titles(db.GetArticlesNeedingFetch(&period)); got != "" {
+		t.Errorf("expected the given-up article to be left alone, got %q", got)
+	}
+	if got := titles(db.GetFailedFetches(&period)); got != "Due,Waiting" {
+		t.Errorf("expected the given-up article to stay retryable by hand, got %q", got)
+	}
+
+	content := "Fetched at last"
+	db.UpdateArticleContent(waiting, &content)
+	if got := titles(db.GetFailedFetches(&period)); got != "Due" {
+		t.Errorf("expected a fetched article to leave the retry queue, got %q", got)
+	}
+
+	db.MarkArticlePaywalled(due)
+	if got := titles(db.GetFailedFetches(&period)); got != "" {
+		t.Errorf("expected a paywalled article to leave the retry queue, got %q", got)
+	}
+	if a, _ := db.GetArticleByID(due); !a.Paywalled || !a.ContentFetched || a.Content != nil {
+		t.Errorf("expected the article flagged as paywalled without content, got %+v", a)
+	}
+}
+
 func TestUpdateArticleContent(t *testing.T) {
 	db := openTestDB(t)
 	id, _ := db.InsertArticle("https://a.com", "Test", nil, nil, nil, ptr("2026-02-06"))
@@ -93,6 +174,37 @@ func TestUpdateArticleContent(t *testing.T) {
 	}
 }
 
+func TestArticleTranslation(t *testing.T) {
+	db := openTestDB(t)
+	id, _ := db.InsertArticle("https://a.com", "Ein Modell", nil, nil, ptr("Der Inhalt"), ptr("2026-02-06"))
+	db.SetArticleLanguage(id, "de")
+	if got, _ := db.GetUntranslatedArticles("2026-02-06"); len(got) != 1 {
+		t.Fatalf("expected the German article untranslated, got %+v", got)
+	}
+
+	db.SetArticleTranslation(id, "A model", ptr("The content"))
+	db.SetArticleTranslation(id, "Translated twice", nil)
+	var originalTitle, originalContent string
+	db.conn.QueryRow("SELECT original_title, original_content FROM articles WHERE id = ?", id).Scan(&originalTitle, &originalContent)
+	if originalTitle != "Ein Modell" || originalContent != "Der Inhalt" {
+		t.Errorf("expected the originals kept, got %q and %q", originalTitle, originalContent)
+	}
+	a, _ := db.GetArticleByID(id)
+	if a.Title != "A model" || *a.Content != "The content" || *a.Language != "de" {
+		t.Errorf("expected the first translation stored, got %+v", a)
+	}
+	if got, _ := db.GetUntranslatedArticles("2026-02-06"); len(got) != 0 {
+		t.Errorf("expected no article left to translate, got %+v", got)
+	}
+
+	// Fetched content is new text, whose language is detected again, under
+	// the original title.
+	db.UpdateArticleContent(id, ptr("Neuer Inhalt"))
+	if got, _ := db.GetArticlesWithoutLanguage("2026-02-06"); len(got) != 1 || got[0].Title != "Ein Modell" {
+		t.Errorf("expected the refetched article to need detecting, got %+v", got)
+	}
+}
+
 func TestTriageLifecycle(t *testing.T) {
 	db := openTestDB(t)
 	id, _ := db.InsertArticle("https://a.com", "Test", nil, nil, nil, ptr("2026-02-06"))
@@ -181,12 +293,111 @@ func TestStorylineLifecycle(t *testing.T) {
 		t.Errorf("expected article_count 2, got %d", storylines[0].ArticleCount)
 	}
 
-	articles, _ := db.GetStorylineArticles(sid)
+	articles, _ := db.GetStorylineArticles(sid, "")
 	if len(articles) != 2 {
 		t.Errorf("expected 2 storyline articles, got %d", len(articles))
 	}
 }
 
+func TestStorylineChangesMarkNarrativesStale(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, ptr("2026-02-06"))
+	a3, _ := db.InsertArticle("https://c.com", "C", nil, nil, nil, ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "One", []int64{a1, a2})
+	s2, _ := db.InsertStoryline("2026-02-06", "Two", []int64{a3})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "One", "Text", nil)
+	db.InsertStorylineNarrative(s2, "2026-02-06", "Two", "Text", nil)
+	stale := func(id int64) bool {
+		n, _ := db.GetNarrativeForStoryline(id)
+		return n != nil && n.Stale
+	}
+
+	// Fetch attempts without content don't change what was written.
+	db.UpdateArticleContent(a3, nil)
+	if stale(s2) {
+		t.Error("expected a failed fetch to leave the narrative fresh")
+	}
+	db.UpdateArticleContent(a3, ptr("Late content"))
+	if !stale(s2) || stale(s1) {
+		t.Errorf("expected late content to stale only its storyline, got %v %v", stale(s1), stale(s2))
+	}
+
+	// Rewriting a narrative replaces it fresh.
+	db.InsertStorylineNarrative(s2, "2026-02-06", "Two", "Rewritten", nil)
+	if n, _ := db.CountStaleNarratives("2026-02-06"); n != 0 {
+		t.Errorf("expected no stale narratives after rewriting, got %d", n)
+	}
+	narratives, _ := db.GetNarrativesForPeriod("2026-02-06")
+	if len(narratives) != 2 {
+		t.Errorf("expected one narrative per storyline, got %d", len(narratives))
+	}
+
+	changed, err := db.AddStorylineArticle(s2, a2)
+	if err != nil || len(changed) != 2 {
+		t.Fatalf("expected a move between two storylines, got %v (%v)", changed, err)
+	}
+	if !stale(s1) || !stale(s2) {
+		t.Error("expected both storylines of a move to be stale")
+	}
+	ids, _ := db.GetStorylineArticleIDs(s1)
+	if len(ids) != 1 || ids[0] != a1 {
+		t.Errorf("expected the article to leave its storyline, got %v", ids)
+	}
+	if st, _ := db.GetStoryline(s2); st == nil || st.ArticleCount != 2 {
+		t.Errorf("expected the article count to follow, got %+v", st)
+	}
+	if changed, _ := db.AddStorylineArticle(s2, a2); len(changed) != 0 {
+		t.Errorf("expected no change moving an article where it is, got %v", changed)
+	}
+
+	db.InsertStorylineNarrative(s1, "2026-02-06", "One", "Rewritten", nil)
+	if ok, _ := db.RemoveStorylineArticle(s1, a3); ok {
+		t.Error("expected removing an article not in the storyline to report false")
+	}
+	if ok, _ := db.RemoveStorylineArticle(s1, a1); !ok || !stale(s1) {
+		t.Error("expected removing an article to stale the narrative")
+	}
+	if n, _ := db.CountStaleNarratives("2026-02-06"); n != 2 {
+		t.Errorf("expected 2 stale narratives, got %d", n)
+	}
+}
+
+func TestStorylineArticleOrders(t *testing.T) {
+	db := openTestDB(t)
+	old, _ := db.InsertArticle("https://a.com/old", "Old", ptr("Trusted"), ptr("2026-02-01"), nil, ptr("2026-02-06"))
+	useful, _ := db.InsertArticle("https://b.com/useful", "Useful", ptr("Noisy"), ptr("2026-02-03"), nil, ptr("2026-02-06"))
+	undated, _ := db.InsertArticle("https://c.com/undated", "Undated", nil, nil, nil, ptr("2026-02-06"))
+	other, _ := db.InsertArticle("https://a.com/other", "Other", ptr("Trusted"), nil, nil, ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{old, useful, undated})
+	db.InsertTriage(old, "relevant", nil, nil, nil, 2)
+	db.InsertTriage(useful, "relevant", nil, nil, nil, 5)
+	db.UpsertArticleFeedback(other, "positive")
+	db.UpsertArticleFeedback(useful, "negative")
+
+	for order, want := range map[string][]int64{
+		"":                     {old, useful, undated},
+		ArticleOrderScore:      {useful, old, undated},
+		ArticleOrderPublished:  {useful, old, undated},
+		ArticleOrderReputation: {old, undated, useful},
+	} {
+		articles, err := db.GetStorylineArticles(sid, order)
+		if err != nil {
+			t.Fatalf("order %q: %v", order, err)
+		}
+		var got []int64
+		for _, a := range articles {
+			got = append(got, a.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("order %q: expected %v, got %v", order, want, got)
+		}
+	}
+	if _, err := db.GetStorylineArticles(sid, "title"); !errors.Is(err, ErrUnknownSort) {
+		t.Errorf("expected ErrUnknownSort, got %v", err)
+	}
+}
+
 func TestClearStorylines(t *testing.T) {
 	db := openTestDB(t)
 	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
@@ -229,6 +440,25 @@ func TestBriefingLifecycle(t *testing.T) {
 	if len(all) != 1 {
 		t.Errorf("expected 1 briefing, got %d", len(all))
 	}
+	if briefing.QualityNote != nil {
+		t.Errorf("expected no quality note, got %q", *briefing.QualityNote)
+	}
+
+	note := "clustering degraded: keyword fallback used"
+	if err := db.SetBriefingQualityNote("2026-02-06", EditionMorning, &note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	briefing, _ = db.GetBriefing("2026-02-06")
+	if briefing.QualityNote == nil || *briefing.QualityNote != note {
+		t.Errorf("expected quality note %q, got %v", note, briefing.QualityNote)
+	}
+
+	// Recomposing replaces the row and clears the note
+	db.InsertBriefing("2026-02-06", "- Key point", "Body", 3, 15)
+	briefing, _ = db.GetBriefing("2026-02-06")
+	if briefing.QualityNote != nil {
+		t.Errorf("expected recomposed briefing without note, got %q", *briefing.QualityNote)
+	}
 }
 
 func TestPriorityLifecycle(t *testing.T) {
@@ -370,6 +600,103 @@ func TestGetLastRunDateRange(t *testing.T) {
 	}
 }
 
+func TestGetLastReportTime(t *testing.T) {
+	db := openTestDB(t)
+	if at, err := db.GetLastReportTime(); err != nil || at != "" {
+		t.Fatalf("expected no report time before a run, got %q, %v", at, err)
+	}
+	db.InsertReport("2026-02-05", 10, 3)
+	at, err := db.GetLastReportTime()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if finished, err := time.Parse("2006-01-02 15:04:05", at); err != nil || time.Since(finished) > time.Minute {
+		t.Errorf("expected the report time to be now, got %q", at)
+	}
+}
+
+func TestFeedHealth(t *testing.T) {
+	db := openTestDB(t)
+	for range 3 {
+		db.RecordFeedFailure("https://a.example/feed", "HTTP 404")
+	}
+	db.RecordFeedFailure("https://b.example/feed", "timeout")
+	db.RecordFeedSuccess("https://c.example/feed")
+
+	failing, err := db.GetFailingFeeds(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(failing) != 1 || failing[0].FeedURL != "https://a.example/feed" || failing[0].Failures != 3 || *failing[0].LastError != "HTTP 404" {
+		t.Fatalf("expected the feed failing 3 times, got %+v", failing)
+	}
+
+	// A success ends the streak.
+	db.RecordFeedSuccess("https://a.example/feed")
+	if failing, _ = db.GetFailingFeeds(1); len(failing) != 1 || failing[0].FeedURL != "https://b.example/feed" {
+		t.Errorf("expected only the feed still failing, got %+v", failing)
+	}
+}
+
+func TestFetchStats(t *testing.T) {
+	db := openTestDB(t)
+	db.RecordFetchAttempt(FetchAttempt{Domain: "a.example", Duration: 200 * time.Millisecond})
+	db.RecordFetchAttempt(FetchAttempt{Domain: "a.example", Rendered: true, Duration: 400 * time.Millisecond})
+	db.RecordFetchAttempt(FetchAttempt{Domain: "b.example", Error: "HTTP 403 Forbidden", Duration: 100 * time.Millisecond})
+	db.RecordFetchAttempt(FetchAttempt{Domain: "b.example", Paywalled: true})
+	// A week ago, b.example timed out.
+	db.conn.Exec("INSERT INTO fetch_stats (domain, day, attempts, failures, last_error) VALUES ('b.example', date('now', '-7 days'), 1, 1, 'timeout')")
+
+	stats, err := db.GetFetchStats(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Domain != "b.example" || stats[1].Domain != "a.example" {
+		t.Fatalf("expected the failing domain first, got %+v", stats)
+	}
+	if b := stats[0]; b.Attempts != 2 || b.Failures != 1 || b.Paywalled != 1 || b.FailureRate() != 0.5 ||
+		b.LastError == nil || *b.LastError != "HTTP 403 Forbidden" {
+		t.Errorf("expected one of b.example's two recent attempts failed, got %+v", b)
+	}
+	if a := stats[1]; a.Attempts != 2 || a.Failures != 0 || a.Rendered != 1 || a.AvgLatency != 300*time.Millisecond || a.LastError != nil {
+		t.Errorf("expected a.example's two successes averaging 300ms, got %+v", a)
+	}
+	if stats, _ := db.GetFetchStats(30); stats[0].Attempts != 3 || stats[0].Failures != 2 {
+		t.Errorf("expected the older failure within 30 days, got %+v", stats[0])
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.CheckIntegrity(); err != nil {
+		t.Errorf("expected a fresh database to be intact, got %v", err)
+	}
+}
+
+func TestMuteRules(t *testing.T) {
+	db := openTestDB(t)
+	id, err := db.InsertMuteRule(MuteTerm, " crypto ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.InsertMuteRule(MuteSource, "Hype Daily")
+	if again, _ := db.InsertMuteRule(MuteTerm, "Crypto"); again != id {
+		t.Errorf("expected muting again in another case to return rule %d, got %d", id, again)
+	}
+	if _, err := db.InsertMuteRule("domain", "example.com"); err == nil {
+		t.Error("expected an unknown kind to be rejected")
+	}
+
+	rules, _ := db.GetMuteRules()
+	if len(rules) != 2 || rules[0].Kind != MuteTerm || rules[0].Value != "crypto" || rules[1].Value != "Hype Daily" {
+		t.Fatalf("expected both rules, oldest first, got %+v", rules)
+	}
+	db.DeleteMuteRule(id)
+	if rule, err := db.GetMuteRule(id); err != nil || rule != nil {
+		t.Errorf("expected the rule deleted, got %+v, %v", rule, err)
+	}
+}
+
 func TestStorylineFeedbackLifecycle(t *testing.T) {
 	db := openTestDB(t)
 	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
@@ -510,3 +837,1062 @@ func containsStr(s, substr string) bool {
 	}
 	return false
 }
+
+func TestInsertStorylineDisambiguatesLabel(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, ptr("2026-02-06"))
+	a3, _ := db.InsertArticle("https://c.com", "C", nil, nil, nil, ptr("2026-02-07"))
+
+	db.InsertStoryline("2026-02-06", "AI Agents", []int64{a1})
+	if _, err := db.InsertStoryline("2026-02-06", "ai agents", []int64{a2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.InsertStoryline("2026-02-07", "AI Agents", []int64{a3})
+
+	storylines, _ := db.GetStorylinesForPeriod("2026-02-06")
+	labels := map[string]bool{}
+	for _, s := range storylines {
+		labels[s.Label] = true
+	}
+	if !labels["AI Agents"] || !labels["ai agents (2)"] {
+		t.Errorf("expected disambiguated labels, got %v", labels)
+	}
+
+	other, _ := db.GetStorylinesForPeriod("2026-02-07")
+	if len(other) != 1 || other[0].Label != "AI Agents" {
+		t.Error("expected labels in other periods to be unaffected")
+	}
+}
+
+func TestUniqueLabel(t *testing.T) {
+	used := map[string]bool{"agents": true, "agents (2)": true}
+	if got := UniqueLabel("Agents", used); got != "Agents (3)" {
+		t.Errorf("expected 'Agents (3)', got %q", got)
+	}
+	if got := UniqueLabel("Testing", used); got != "Testing" {
+		t.Errorf("expected 'Testing', got %q", got)
+	}
+}
+
+func TestFoldLabel(t *testing.T) {
+	tests := []struct {
+		label, want string
+	}{
+		{"AI Agents", "ai agents"},
+		{"Über KI", "Über ki"},
+		{"ÉMILE", "Émile"},
+	}
+	for _, tt := range tests {
+		if got := FoldLabel(tt.label); got != tt.want {
+			t.Errorf("FoldLabel(%q): expected %q, got %q", tt.label, tt.want, got)
+		}
+	}
+}
+
+func TestStorylineLabelsFoldLikeTheIndex(t *testing.T) {
+	db := openTestDB(t)
+	// The NOCASE index tells these apart, so neither needs a disambiguator;
+	// it only folds ASCII letters, so the last one does.
+	db.InsertStoryline("2026-02-06", "Über KI", nil)
+	db.InsertStoryline("2026-02-06", "über KI", nil)
+	if _, err := db.InsertStoryline("2026-02-06", "Über ki", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var labels []string
+	storylines, _ := db.GetStorylinesForPeriod("2026-02-06")
+	for _, s := range storylines {
+		labels = append(labels, s.Label)
+	}
+	slices.Sort(labels)
+	want := []string{"Über KI", "Über ki (2)", "über KI"}
+	slices.Sort(want)
+	if !slices.Equal(labels, want) {
+		t.Errorf("expected labels %v, got %v", want, labels)
+	}
+}
+
+func TestBriefingEditions(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertBriefing("2026-02-06", "- Morning", "Morning body", 2, 5)
+	if _, err := db.InsertBriefingEdition("2026-02-06", EditionEvening, "- Evening", "Evening body", 0, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	morning, _ := db.GetBriefing("2026-02-06")
+	if morning == nil || morning.TLDR != "- Morning" || morning.Edition != EditionMorning {
+		t.Errorf("expected morning edition, got %+v", morning)
+	}
+	evening, _ := db.GetBriefingEdition("2026-02-06", EditionEvening)
+	if evening == nil || evening.TLDR != "- Evening" {
+		t.Errorf("expected evening edition, got %+v", evening)
+	}
+
+	editions, _ := db.GetBriefingEditions("2026-02-06")
+	if len(editions) != 2 || editions[0] != EditionMorning {
+		t.Errorf("expected [morning evening], got %v", editions)
+	}
+
+	all, _ := db.GetAllBriefings()
+	if len(all) != 2 {
+		t.Errorf("expected 2 briefings in archive, got %d", len(all))
+	}
+}
+
+func TestReaderProfiles(t *testing.T) {
+	db := openTestDB(t)
+	qa, err := db.InsertProfile("qa", "For QA")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.InsertProfile("platform", "For the platform team")
+
+	shared, _ := db.InsertPriority("LLM evaluation", "", nil)
+	automation, _ := db.InsertPriority("Test automation", "", []string{"flaky tests"})
+	db.SetPriorityProfile(automation, &qa)
+
+	profiles, _ := db.GetProfiles()
+	if len(profiles) != 2 || profiles[0].Name != "platform" {
+		t.Errorf("expected profiles ordered by name, got %+v", profiles)
+	}
+
+	qaPriorities, _ := db.GetActiveProfilePriorities(qa)
+	if len(qaPriorities) != 1 || qaPriorities[0].ID != automation {
+		t.Errorf("expected only the QA priority, got %+v", qaPriorities)
+	}
+	active, _ := db.GetActivePriorities()
+	if len(active) != 2 {
+		t.Errorf("expected profile priorities to stay in shared triage, got %d", len(active))
+	}
+
+	p, _ := db.GetPriority(shared)
+	if p.ProfileID != nil {
+		t.Errorf("expected shared priority, got profile %d", *p.ProfileID)
+	}
+
+	db.DeleteProfile(qa)
+	p, _ = db.GetPriority(automation)
+	if p == nil || p.ProfileID != nil {
+		t.Errorf("expected priority to become shared after profile removal, got %+v", p)
+	}
+	if missing, _ := db.GetProfileByName("qa"); missing != nil {
+		t.Error("expected profile to be deleted")
+	}
+}
+
+func TestForeignKeysOnEveryConnection(t *testing.T) {
+	db := openTestDB(t)
+	qa, _ := db.InsertProfile("qa", "For QA")
+	automation, _ := db.InsertPriority("Test automation", "", nil)
+	db.SetPriorityProfile(automation, &qa)
+
+	// Hold the connection Open set up, so the delete runs on another one.
+	held, err := db.conn.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+	if err := db.DeleteProfile(qa); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, _ := db.GetPriority(automation); p == nil || p.ProfileID != nil {
+		t.Errorf("expected the priority shared after deleting on a second connection, got %+v", p)
+	}
+}
+
+func TestBriefingHighlights(t *testing.T) {
+	db := openTestDB(t)
+	qa, _ := db.InsertProfile("qa", "For QA")
+	db.InsertBriefingHighlight("2026-02-06", qa, "For QA", "- Old")
+	db.InsertBriefingHighlight("2026-02-06", qa, "For QA", "- New")
+
+	highlights, _ := db.GetBriefingHighlights("2026-02-06")
+	if len(highlights) != 1 || highlights[0].BodyMarkdown != "- New" {
+		t.Errorf("expected one replaced highlight, got %+v", highlights)
+	}
+
+	db.DeleteBriefingHighlights("2026-02-06")
+	highlights, _ = db.GetBriefingHighlights("2026-02-06")
+	if len(highlights) != 0 {
+		t.Errorf("expected highlights cleared, got %d", len(highlights))
+	}
+}
+
+func TestAssignPendingArticles(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertArticle("https://assigned.com", "Assigned", nil, nil, nil, ptr("2026-02-05"))
+
+	pending, _ := db.InsertArticle("https://pending.com", "Pending", nil, nil, nil, nil)
+	ids, err := db.AssignPendingArticles("2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != pending {
+		t.Errorf("expected the pending article adopted, got %v", ids)
+	}
+	articles, _ := db.GetArticlesForPeriod("2026-02-06")
+	if len(articles) != 1 || articles[0].URL != "https://pending.com" {
+		t.Errorf("expected pending article in new period, got %+v", articles)
+	}
+}
+
+func TestSearchArticles(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertArticle("https://a.com", "Playwright adds AI locators", nil, nil, ptr("Browser testing"), nil)
+	db.InsertArticle("https://b.com", "Unrelated", nil, nil, ptr("Mentions playwright in passing"), nil)
+	db.InsertArticle("https://c.com", "100% coverage", nil, nil, nil, nil)
+
+	results, _ := db.SearchArticles("PLAYWRIGHT", 10)
+	if len(results) != 2 {
+		t.Errorf("expected title and content matches, got %d", len(results))
+	}
+	results, _ = db.SearchArticles("%", 10)
+	if len(results) != 1 || results[0].URL != "https://c.com" {
+		t.Errorf("expected literal %% match only, got %+v", results)
+	}
+
+	db.InsertArticle("https://d.com", "Playwright 2.0", nil, nil, nil, ptr("2026-02-06"))
+	results, _ = db.SearchArticlesSince("playwright", "2026-02-01", 10)
+	if len(results) != 1 || results[0].URL != "https://d.com" {
+		t.Errorf("expected only the article of a later period, got %+v", results)
+	}
+}
+
+func TestSearchNarratives(t *testing.T) {
+	db := openTestDB(t)
+	old, _ := db.InsertStoryline("2026-01-10", "Caching", nil)
+	recent, _ := db.InsertStoryline("2026-02-06", "Caching again", nil)
+	other, _ := db.InsertStoryline("2026-02-06", "Agents", nil)
+	db.InsertStorylineNarrative(old, "2026-01-10", "Prompt Caching Arrives", "Cheaper repeats.", nil)
+	db.InsertStorylineNarrative(recent, "2026-02-06", "Cheaper Tokens", "Prompt caching spreads to more providers.", nil)
+	db.InsertStorylineNarrative(other, "2026-02-06", "Agents Everywhere", "Nothing about caches.", nil)
+
+	narratives, err := db.SearchNarratives("prompt caching", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(narratives) != 2 || narratives[0].Title != "Cheaper Tokens" || narratives[1].Title != "Prompt Caching Arrives" {
+		t.Errorf("expected title and text matches, newest first, got %+v", narratives)
+	}
+	if narratives, _ = db.SearchNarratives("prompt caching", "2026-02-01", 10); len(narratives) != 1 {
+		t.Errorf("expected only the later period, got %+v", narratives)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, nil)
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, nil)
+
+	id, _ := db.InsertEvent(a1, "DevConf 2026", EventConference, "2026-03-10")
+	if id == 0 {
+		t.Fatal("expected event to be stored")
+	}
+	if dup, _ := db.InsertEvent(a2, "devconf 2026", EventConference, "2026-03-10"); dup != 0 {
+		t.Error("expected duplicate event to be ignored")
+	}
+	db.InsertEvent(a2, "API deadline", EventDeadline, "2026-02-20")
+	db.InsertEvent(a2, "Far away", EventOther, "2026-09-01")
+
+	from, to := UpcomingWindow("2026-02-01..2026-02-14", UpcomingDays)
+	if from != "2026-02-14" || to != "2026-03-16" {
+		t.Errorf("unexpected window %s..%s", from, to)
+	}
+	events, _ := db.GetEventsBetween(from, to, 0)
+	if len(events) != 2 || events[0].Title != "API deadline" {
+		t.Fatalf("expected 2 events soonest first, got %+v", events)
+	}
+	if events[1].ArticleURL == nil || *events[1].ArticleURL != "https://a.com" {
+		t.Errorf("expected source URL of first mention, got %v", events[1].ArticleURL)
+	}
+	if limited, _ := db.GetEventsBetween(from, to, 1); len(limited) != 1 {
+		t.Errorf("expected limit to apply, got %d", len(limited))
+	}
+}
+
+func TestBenchmarkResults(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-01"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, ptr("2026-02-06"))
+
+	db.InsertBenchmarkResult(a1, "Model A", "SWE-bench Verified", 61.2, "2026-02-01", false)
+	if id, _ := db.InsertBenchmarkResult(a2, "Model A", "swe-bench verified", 61.2, "2026-02-06", false); id != 0 {
+		t.Error("expected repeated result to be ignored")
+	}
+	db.InsertBenchmarkResult(a2, "Model B", "SWE-bench Verified", 58.0, "2026-02-06", false)
+	db.InsertBenchmarkResult(a2, "Model C", "SWE-bench Verified", 64.5, "2026-02-06", false)
+	db.InsertBenchmarkResult(a2, "Model C", "MMLU", 90.1, "2026-02-06", true)
+
+	results, _ := db.GetBenchmarkResults()
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if results[0].Benchmark != "MMLU" || !results[0].SOTA {
+		t.Errorf("expected claimed MMLU result first and SOTA, got %+v", results[0])
+	}
+	sota := map[string]bool{}
+	for _, r := range results[1:] {
+		sota[r.Model] = r.SOTA
+	}
+	if sota["Model A"] || sota["Model B"] || !sota["Model C"] {
+		t.Errorf("expected only the score beating earlier results to be SOTA, got %v", sota)
+	}
+
+	sid, _ := db.InsertStoryline("2026-02-06", "Coding agents", []int64{a2})
+	other, _ := db.InsertStoryline("2026-02-06", "Other", []int64{a1})
+	ids, _ := db.GetSOTAStorylines("2026-02-06")
+	if !ids[sid] || ids[other] {
+		t.Errorf("expected only storyline %d flagged, got %v", sid, ids)
+	}
+}
+
+func TestModelReleases(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, nil)
+	vendor, license := "Lab", "MIT"
+	var ctx int64 = 128000
+
+	if isNew, _ := db.UpsertModelRelease(aid, "Model Z", nil, "2026-02-10", nil, nil); !isNew {
+		t.Fatal("expected first mention to be new")
+	}
+	if isNew, _ := db.UpsertModelRelease(aid, "model z", &vendor, "2026-02-08", &license, &ctx); isNew {
+		t.Error("expected repeat mention not to be new")
+	}
+	db.UpsertModelRelease(aid, "Older", nil, "2026-01-20", nil, nil)
+
+	all, _ := db.GetModelReleases("", "")
+	if len(all) != 2 || all[0].Name != "Model Z" {
+		t.Fatalf("expected 2 releases newest first, got %+v", all)
+	}
+	z := all[0]
+	if z.ReleaseDate != "2026-02-08" || z.Vendor == nil || *z.Vendor != "Lab" || z.ContextWindow == nil || *z.ContextWindow != ctx {
+		t.Errorf("expected details merged and earliest date kept, got %+v", z)
+	}
+	if feb, _ := db.GetModelReleases("2026-02-01", "2026-02-28"); len(feb) != 1 {
+		t.Errorf("expected 1 release in February, got %d", len(feb))
+	}
+}
+
+func TestPolicyWatch(t *testing.T) {
+	db := openTestDB(t)
+	jan, feb := "2026-01-15", "2026-02-06"
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, &jan)
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, &feb)
+	summary := "Obligations for general-purpose models apply"
+
+	db.InsertPolicyUpdate(a1, "EU AI Act", PolicyAdopted, nil, "2026-01-15")
+	db.InsertPolicyUpdate(a2, "eu ai act", PolicyInForce, &summary, "2026-02-06")
+	db.InsertPolicyUpdate(a2, "EU AI Act", PolicyDelayed, nil, "2026-02-06")
+	db.InsertPolicyUpdate(a2, "Colorado AI Act", PolicyDelayed, nil, "2026-02-06")
+
+	watch, err := db.PolicyWatch([]string{"EU AI Act", "UK AI Bill"}, "2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(watch) != 3 || watch[2].Regulation != "Colorado AI Act" {
+		t.Fatalf("expected tracked regulations then reported ones, got %+v", watch)
+	}
+	eu := watch[0]
+	if eu.Current == nil || eu.Current.Status != PolicyInForce || eu.Previous != PolicyAdopted || !eu.New {
+		t.Errorf("expected EU AI Act in force (was adopted) this period, got %+v", eu)
+	}
+	if watch[1].Current != nil {
+		t.Errorf("expected no status for UK AI Bill, got %+v", watch[1].Current)
+	}
+
+	watch, _ = db.PolicyWatch([]string{"EU AI Act"}, "2026-01-15")
+	if watch[0].Current == nil || watch[0].Current.Status != PolicyAdopted || watch[0].Previous != "" {
+		t.Errorf("expected status as of the earlier period, got %+v", watch[0])
+	}
+}
+
+func TestLLMUsage(t *testing.T) {
+	db := openTestDB(t)
+	if last, err := db.GetLastRunUsage(); err != nil || last != nil {
+		t.Fatalf("expected no usage on an empty database, got %+v, %v", last, err)
+	}
+
+	period := "2026-02-06"
+	db.InsertLLMUsage([]LLMUsage{
+		{RunID: "run1", PeriodID: &period, Step: "Triage", Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 100, CompletionTokens: 10, CostUSD: 0.01},
+	})
+	db.InsertLLMUsage([]LLMUsage{
+		{RunID: "run2", PeriodID: &period, Step: "Triage", Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 200, CompletionTokens: 20, CostUSD: 0.02},
+		{RunID: "run2", PeriodID: &period, Step: "Synthesize", Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 300, CompletionTokens: 30, CostUSD: 0.03},
+		{RunID: "run2", PeriodID: &period, Step: "Triage", Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 50, CompletionTokens: 5, CostUSD: 0.005},
+	})
+
+	last, err := db.GetLastRunUsage()
+	if err != nil || last == nil {
+		t.Fatalf("expected last run usage, got %+v, %v", last, err)
+	}
+	if last.Label != "run2" || last.Calls != 3 || last.PromptTokens != 550 || last.CompletionTokens != 55 {
+		t.Errorf("unexpected last run totals: %+v", last)
+	}
+
+	steps, _ := db.GetRunUsage("run2")
+	if len(steps) != 2 || steps[0].Label != "Triage" || steps[0].Calls != 2 || steps[1].Label != "Synthesize" {
+		t.Errorf("expected per-step totals in run order, got %+v", steps)
+	}
+
+	all, _ := db.GetUsageSince("")
+	if all.Calls != 4 || all.PromptTokens != 650 {
+		t.Errorf("unexpected all-time totals: %+v", all)
+	}
+	future, _ := db.GetUsageSince("2999-01-01 00:00:00")
+	if future.Calls != 0 || future.CostUSD != 0 {
+		t.Errorf("expected no usage in the future, got %+v", future)
+	}
+}
+
+func TestResolveCanonicalURLRewritesURL(t *testing.T) {
+	db := openTestDB(t)
+	id, _ := db.InsertArticle("https://feeds.example.com/story?utm_source=rss", "Story", nil, nil, nil, ptr("2026-02-06"))
+
+	got, err := db.ResolveCanonicalURL(id, "https://example.com/story")
+	if err != nil || got != id {
+		t.Fatalf("expected the article to become canonical, got %d, %v", got, err)
+	}
+	a, _ := db.GetArticleByID(id)
+	if a.URL != "https://example.com/story" {
+		t.Errorf("expected canonical URL, got %q", a.URL)
+	}
+	aliases, _ := db.GetArticleAliases(id)
+	if len(aliases) != 1 || aliases[0] != "https://feeds.example.com/story?utm_source=rss" {
+		t.Errorf("expected old URL as alias, got %v", aliases)
+	}
+	if dup, _ := db.InsertArticle("https://feeds.example.com/story?utm_source=rss", "Story", nil, nil, nil, ptr("2026-02-07")); dup != 0 {
+		t.Error("expected an aliased URL to count as a duplicate")
+	}
+}
+
+func TestResolveCanonicalURLMergesCopy(t *testing.T) {
+	db := openTestDB(t)
+	canon, _ := db.InsertArticle("https://origin.com/post", "Post", nil, nil, nil, ptr("2026-02-06"))
+	copyID, _ := db.InsertArticle("https://syndicator.com/post", "Post (syndicated)", nil, nil, ptr("Full text of the copy"), ptr("2026-02-06"))
+	db.InsertTriage(copyID, "relevant", nil, []string{"Point"}, nil, 4)
+	db.UpsertArticleFeedback(copyID, "positive")
+	db.SetArticleImage(copyID, "https://syndicator.com/lead.jpg")
+	sid, _ := db.InsertStoryline("2026-02-06", "Posts", []int64{canon, copyID})
+
+	got, err := db.ResolveCanonicalURL(copyID, "https://origin.com/post")
+	if err != nil || got != canon {
+		t.Fatalf("expected merge into %d, got %d, %v", canon, got, err)
+	}
+	if a, _ := db.GetArticleByID(copyID); a != nil {
+		t.Error("expected the copy to be deleted")
+	}
+	a, _ := db.GetArticleByID(canon)
+	if a.Content == nil || *a.Content != "Full text of the copy" {
+		t.Errorf("expected the copy's content on the canonical article, got %v", a.Content)
+	}
+	if a.ImageURL == nil || *a.ImageURL != "https://syndicator.com/lead.jpg" {
+		t.Errorf("expected the copy's lead image on the canonical article, got %v", a.ImageURL)
+	}
+	if tr, _ := db.GetTriage(canon); tr == nil || tr.PracticalScore != 4 {
+		t.Errorf("expected the copy's triage to move, got %+v", tr)
+	}
+	if f, _ := db.GetArticleFeedback(canon); f == nil || f.Rating != "positive" {
+		t.Errorf("expected the copy's feedback to move, got %+v", f)
+	}
+	articles, _ := db.GetStorylineArticles(sid, "")
+	storylines, _ := db.GetStorylinesForPeriod("2026-02-06")
+	if len(articles) != 1 || storylines[0].ArticleCount != 1 {
+		t.Errorf("expected one article left in the storyline, got %d (count %d)", len(articles), storylines[0].ArticleCount)
+	}
+	if aliases, _ := db.GetArticleAliases(canon); len(aliases) != 1 || aliases[0] != "https://syndicator.com/post" {
+		t.Errorf("expected the copy's URL as alias, got %v", aliases)
+	}
+}
+
+func TestResolveCanonicalURLMovesNearDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	canon, _ := db.InsertArticle("https://origin.com/post", "Post", nil, nil, nil, ptr("2026-02-06"))
+	copyID, _ := db.InsertArticle("https://syndicator.com/post", "Post (syndicated)", nil, nil, ptr("Full text"), ptr("2026-02-06"))
+	near, _ := db.InsertArticle("https://other.com/post", "Post (edited)", nil, nil, ptr("Full text, edited"), ptr("2026-02-06"))
+	db.SetArticleSimhash(copyID, 1, nil)
+	db.SetArticleSimhash(near, 3, &copyID)
+	db.SetArticleSimhash(canon, 1, &copyID)
+
+	db.ResolveCanonicalURL(copyID, "https://origin.com/post")
+	duplicateOf := func(id int64) *int64 {
+		var d *int64
+		db.conn.QueryRow("SELECT duplicate_of FROM articles WHERE id = ?", id).Scan(&d)
+		return d
+	}
+	if d := duplicateOf(near); d == nil || *d != canon {
+		t.Errorf("expected the near-duplicate of the copy to point to the canonical article, got %v", d)
+	}
+	if d := duplicateOf(canon); d != nil {
+		t.Errorf("expected the canonical article kept, got duplicate of %d", *d)
+	}
+	if fingerprints, _ := db.GetFingerprints("2026-02-01"); len(fingerprints) != 1 || fingerprints[0].ArticleID != canon {
+		t.Errorf("expected only the canonical article compared with, got %+v", fingerprints)
+	}
+}
+
+func TestResolveCanonicalURLKeepsCanonicalJudgement(t *testing.T) {
+	db := openTestDB(t)
+	canon, _ := db.InsertArticle("https://origin.com/post", "Post", nil, nil, ptr("Original"), ptr("2026-02-06"))
+	copyID, _ := db.InsertArticle("https://syndicator.com/post", "Post", nil, nil, ptr("Copy"), ptr("2026-02-06"))
+	db.InsertTriage(canon, "skip", nil, nil, nil, 1)
+	db.InsertTriage(copyID, "relevant", nil, nil, nil, 5)
+	db.UpsertArticleFeedback(canon, "negative")
+	db.UpsertArticleFeedback(copyID, "positive")
+
+	if _, err := db.ResolveCanonicalURL(copyID, "https://origin.com/post"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, _ := db.GetArticleByID(canon)
+	tr, _ := db.GetTriage(canon)
+	f, _ := db.GetArticleFeedback(canon)
+	if *a.Content != "Original" || tr.Verdict != "skip" || f.Rating != "negative" {
+		t.Errorf("expected the canonical article's own data to win, got %q, %q, %q", *a.Content, tr.Verdict, f.Rating)
+	}
+}
+
+func TestLLMCache(t *testing.T) {
+	db := openTestDB(t)
+	if r, err := db.GetCachedResponse("m", "h", time.Hour); err != nil || r != nil {
+		t.Fatalf("expected a miss, got %v, %v", r, err)
+	}
+	db.PutCachedResponse("m", "h", "first")
+	db.PutCachedResponse("m", "h", "second")
+	if r, _ := db.GetCachedResponse("m", "h", time.Hour); r == nil || *r != "second" {
+		t.Errorf("expected the latest response, got %v", r)
+	}
+	if r, _ := db.GetCachedResponse("other", "h", time.Hour); r != nil {
+		t.Errorf("expected responses to be keyed by model, got %v", *r)
+	}
+
+	db.conn.Exec(`UPDATE llm_cache SET created_at = datetime('now', '-2 hours')`)
+	if r, _ := db.GetCachedResponse("m", "h", time.Hour); r != nil {
+		t.Errorf("expected an expired response to miss, got %v", *r)
+	}
+	db.PruneCachedResponses(time.Hour)
+	var n int
+	db.conn.QueryRow(`SELECT COUNT(*) FROM llm_cache`).Scan(&n)
+	if n != 0 {
+		t.Errorf("expected expired responses to be pruned, %d left", n)
+	}
+}
+
+func TestFeedItems(t *testing.T) {
+	db := openTestDB(t)
+	feed := "https://example.com/feed.xml"
+	id, _ := db.InsertArticle("https://example.com/post?utm_source=rss", "Post", nil, nil, nil, ptr("2026-02-06"))
+
+	if seen, err := db.HasFeedItem(feed, "post-1"); err != nil || seen {
+		t.Fatalf("expected an unseen GUID, got %v, %v", seen, err)
+	}
+	if has, err := db.HasFeedItems(feed); err != nil || has {
+		t.Fatalf("expected no items of a new feed, got %v, %v", has, err)
+	}
+	db.AddFeedItem(feed, "post-1", id)
+	db.AddFeedItem(feed, "post-1", 0)
+	db.AddFeedItem(feed, "post-2", 0)
+	if seen, _ := db.HasFeedItem(feed, "post-1"); !seen {
+		t.Error("expected the GUID to be remembered")
+	}
+	if seen, _ := db.HasFeedItem("https://other.com/feed.xml", "post-1"); seen {
+		t.Error("expected GUIDs to be scoped to their feed")
+	}
+	var articleID *int64
+	db.conn.QueryRow(`SELECT article_id FROM feed_items WHERE guid = 'post-1'`).Scan(&articleID)
+	if articleID == nil || *articleID != id {
+		t.Errorf("expected the first article to be kept, got %v", articleID)
+	}
+
+	sitemap := "https://example.com/sitemap.xml"
+	if err := db.AddFeedItems(sitemap, []string{"https://example.com/a", "https://example.com/b", "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := db.HasFeedItems(sitemap); !has {
+		t.Error("expected the sitemap's items to be recorded")
+	}
+	if seen, _ := db.HasFeedItem(sitemap, "https://example.com/b"); !seen {
+		t.Error("expected every recorded page to count as seen")
+	}
+}
+
+func TestRefetchUpdatedArticles(t *testing.T) {
+	db := openTestDB(t)
+	feed := "https://example.com/feed.xml"
+	id, _ := db.InsertArticle("https://example.com/model-card", "Model card", nil, nil, nil, ptr("2026-02-06"))
+	db.UpdateArticleContent(id, ptr("The first version of the card."))
+	db.AddFeedItem(feed, "card", id)
+
+	if _, updated, err := db.UpdateFeedItem(feed, "card", "2026-02-06T08:00:00Z"); err != nil || updated {
+		t.Fatalf("expected the first time recorded not to be an update, got %v, %v", updated, err)
+	}
+	if _, updated, _ := db.UpdateFeedItem(feed, "card", "2026-02-06T08:00:00Z"); updated {
+		t.Error("expected the same time not to be an update")
+	}
+	if _, updated, _ := db.UpdateFeedItem(feed, "unseen", "2026-02-07T08:00:00Z"); updated {
+		t.Error("expected an entry never collected not to be updated")
+	}
+	articleID, updated, _ := db.UpdateFeedItem(feed, "card", "2026-02-07T08:00:00Z")
+	if !updated || articleID != id {
+		t.Fatalf("expected a later time to update article %d, got %d, %v", id, articleID, updated)
+	}
+
+	if queued, err := db.QueueArticleRefetch(id, 7); err != nil || !queued {
+		t.Fatalf("expected a recent article to be queued, got %v, %v", queued, err)
+	}
+	refetch, _ := db.GetArticlesToRefetch()
+	if len(refetch) != 1 || refetch[0].ID != id || *refetch[0].Content != "The first version of the card." {
+		t.Fatalf("expected the article queued with its content, got %+v", refetch)
+	}
+	if needing, _ := db.GetArticlesNeedingFetch(nil); len(needing) != 1 {
+		t.Errorf("expected a fetch to include the queued article, got %+v", needing)
+	}
+
+	// The same text only takes the article off the queue.
+	if changed, err := db.RefreshArticleContent(id, "The first version of the card."); err != nil || changed {
+		t.Errorf("expected unchanged content, got %v, %v", changed, err)
+	}
+	if refetch, _ := db.GetArticlesToRefetch(); len(refetch) != 0 {
+		t.Errorf("expected the queue empty, got %+v", refetch)
+	}
+	db.QueueArticleRefetch(id, 7)
+	if changed, _ := db.RefreshArticleContent(id, "The second version of the card."); !changed {
+		t.Error("expected the new content stored")
+	}
+	if a, _ := db.GetArticleByID(id); *a.Content != "The second version of the card." {
+		t.Errorf("expected the new content, got %q", *a.Content)
+	}
+
+	// A failed fetch keeps the content and ends the refetch.
+	db.QueueArticleRefetch(id, 7)
+	db.MarkArticleFetchFailed(id, "HTTP 404")
+	if refetch, _ := db.GetArticlesToRefetch(); len(refetch) != 0 {
+		t.Errorf("expected a failure to end the refetch, got %+v", refetch)
+	}
+	if a, _ := db.GetArticleByID(id); *a.Content != "The second version of the card." {
+		t.Errorf("expected the content kept, got %q", *a.Content)
+	}
+
+	// Articles collected before the window aren't fetched again.
+	db.conn.Exec(`UPDATE articles SET collected_at = datetime('now', '-30 days') WHERE id = ?`, id)
+	if queued, _ := db.QueueArticleRefetch(id, 7); queued {
+		t.Error("expected an old article not to be queued")
+	}
+}
+
+func TestTriageFailures(t *testing.T) {
+	db := openTestDB(t)
+	id, _ := db.InsertArticle("https://example.com/a", "A", nil, nil, nil, ptr("2026-02-06"))
+
+	db.RecordTriageFailure(id, "timeout")
+	db.RecordTriageFailure(id, "HTTP 429")
+	failures, err := db.GetTriageFailures(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].Attempts != 2 || failures[0].LastError != "HTTP 429" || failures[0].Title != "A" {
+		t.Fatalf("expected one failure with 2 attempts and the latest reason, got %+v", failures)
+	}
+	if stats, _ := db.GetStats(); stats.FailedTriage != 1 {
+		t.Errorf("expected 1 failing article in stats, got %d", stats.FailedTriage)
+	}
+
+	db.InsertTriage(id, "relevant", nil, nil, nil, 3)
+	if failures, _ := db.GetTriageFailures(10); len(failures) != 0 {
+		t.Errorf("expected triage to clear the failure, got %+v", failures)
+	}
+}
+
+func TestListArticles(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "Alpha", ptr("Blog"), ptr("2026-02-03"), nil, ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "Beta", ptr("News"), ptr("2026-02-05"), nil, ptr("2026-02-06"))
+	db.InsertArticle("https://c.com", "Gamma", ptr("Blog"), ptr("2026-02-04"), nil, ptr("2026-02-06"))
+	db.InsertArticle("https://d.com", "Delta", ptr("Blog"), nil, nil, ptr("2026-01-30"))
+	db.InsertTriage(a1, "relevant", nil, nil, nil, 2)
+	db.InsertTriage(a2, "relevant", nil, nil, nil, 5)
+
+	got, total, err := db.ListArticles(ArticleFilter{PeriodID: "2026-02-06"}, ListOptions{Sort: "published", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(got) != 2 || got[0].Title != "Alpha" || got[1].Title != "Gamma" {
+		t.Fatalf("expected the first 2 of 3 articles by publication date, got %d: %+v", total, got)
+	}
+	if got, _, _ := db.ListArticles(ArticleFilter{PeriodID: "2026-02-06"}, ListOptions{Sort: "published", Limit: 2, Offset: 2}); len(got) != 1 || got[0].Title != "Beta" {
+		t.Errorf("expected the last article on the second page, got %+v", got)
+	}
+
+	got, total, _ = db.ListArticles(ArticleFilter{Verdict: "relevant"}, ListOptions{Sort: "score", Desc: true})
+	if total != 2 || got[0].Title != "Beta" || *got[0].PracticalScore != 5 || *got[1].Verdict != "relevant" {
+		t.Errorf("expected relevant articles by score, got %+v", got)
+	}
+	if _, total, _ := db.ListArticles(ArticleFilter{Source: "blog", Verdict: VerdictUntriaged}, ListOptions{}); total != 2 {
+		t.Errorf("expected 2 untriaged blog articles, got %d", total)
+	}
+	if _, _, err := db.ListArticles(ArticleFilter{}, ListOptions{Sort: "content"}); !errors.Is(err, ErrUnknownSort) {
+		t.Errorf("expected ErrUnknownSort, got %v", err)
+	}
+}
+
+func TestLLMCalls(t *testing.T) {
+	db := openTestDB(t)
+	period := "2026-02-06"
+	article, storyline := int64(7), int64(3)
+	failed := "HTTP 502"
+	err := db.InsertLLMCalls([]LLMCall{
+		{RunID: "r1", PeriodID: &period, Step: "Triage", ArticleID: &article, Provider: "openai", Model: "gpt-4o-mini",
+			Prompt: "Triage this", Response: "{}", LatencyMS: 120, PromptTokens: 50, CompletionTokens: 10},
+		{RunID: "r1", PeriodID: &period, Step: "Synthesize", StorylineID: &storyline, Provider: "openai", Model: "gpt-4o",
+			Prompt: "Write", Error: &failed},
+		{RunID: "r2", PeriodID: &period, Step: "Triage", ArticleID: &article, Prompt: "Triage this", Response: "{}", Cached: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ := db.GetLLMCalls(LLMCallFilter{})
+	if len(all) != 3 || all[0].RunID != "r2" || !all[0].Cached || all[0].CreatedAt == "" {
+		t.Fatalf("expected all calls newest first, got %+v", all)
+	}
+	if calls, _ := db.GetLLMCalls(LLMCallFilter{Step: "triage", RunID: "r1"}); len(calls) != 1 || *calls[0].ArticleID != article {
+		t.Errorf("expected the run's triage call, got %+v", calls)
+	}
+	if calls, _ := db.GetLLMCalls(LLMCallFilter{ErrorsOnly: true}); len(calls) != 1 || *calls[0].StorylineID != storyline {
+		t.Errorf("expected the failed call, got %+v", calls)
+	}
+	if calls, _ := db.GetLLMCalls(LLMCallFilter{ArticleID: article, Limit: 1}); len(calls) != 1 {
+		t.Errorf("expected the limit to apply, got %d calls", len(calls))
+	}
+	if c, _ := db.GetLLMCall(all[2].ID); c == nil || c.Prompt != "Triage this" || c.LatencyMS != 120 {
+		t.Errorf("unexpected call: %+v", c)
+	}
+	if c, _ := db.GetLLMCall(999); c != nil {
+		t.Errorf("expected a missing call to be nil, got %+v", c)
+	}
+
+	db.conn.Exec(`UPDATE llm_calls SET created_at = datetime('now', '-2 hours') WHERE run_id = 'r1'`)
+	db.PruneLLMCalls(time.Hour)
+	if calls, _ := db.GetLLMCalls(LLMCallFilter{}); len(calls) != 1 {
+		t.Errorf("expected old calls to be pruned, %d left", len(calls))
+	}
+}
+
+func TestSourceTypes(t *testing.T) {
+	db := openTestDB(t)
+	period := "2026-02-06"
+	a1, _ := db.InsertArticle("https://openai.com/a", "A", ptr("OpenAI Blog"), nil, nil, &period)
+	a2, _ := db.InsertArticle("https://example.com/b", "B", ptr("Example"), nil, nil, &period)
+	a3, _ := db.InsertArticle("https://arxiv.org/abs/1", "C", ptr("arXiv"), nil, nil, &period)
+
+	db.SetSourceType("OpenAI Blog", SourceBlog)
+	db.SetSourceType("OpenAI Blog", SourceVendor)
+	db.AddSource("Example", SourceNews)
+	db.AddSource("Example", SourceBlog)
+	types, err := db.GetSourceTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types["OpenAI Blog"] != SourceVendor {
+		t.Errorf("expected SetSourceType to replace the type, got %q", types["OpenAI Blog"])
+	}
+	if types["Example"] != SourceNews {
+		t.Errorf("expected AddSource to keep the first type, got %q", types["Example"])
+	}
+
+	untyped, _ := db.GetUntypedSources()
+	if len(untyped) != 1 || untyped["arXiv"] != "https://arxiv.org/abs/1" {
+		t.Errorf("expected only arXiv to be untyped, got %v", untyped)
+	}
+
+	sid, _ := db.InsertStoryline(period, "S", []int64{a1, a2, a3})
+	counts, err := db.GetStorylineSourceTypes(period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := counts[sid]; c[SourceVendor] != 1 || c[SourceNews] != 1 || len(c) != 2 {
+		t.Errorf("unexpected storyline source types: %v", c)
+	}
+}
+
+func TestNarrativesKeepVendorOnlyStorylinesOutOfLead(t *testing.T) {
+	db := openTestDB(t)
+	period := "2026-02-06"
+	db.SetSourceType("Vendor", SourceVendor)
+	db.SetSourceType("Press", SourceNews)
+
+	// Storylines by size: a vendor-only one leads, then four with press coverage.
+	var urls int
+	storyline := func(label, source string, size int) {
+		var ids []int64
+		for i := 0; i < size; i++ {
+			urls++
+			id, _ := db.InsertArticle(fmt.Sprintf("https://example.com/%d", urls), label, ptr(source), nil, nil, &period)
+			ids = append(ids, id)
+		}
+		sid, _ := db.InsertStoryline(period, label, ids)
+		db.InsertStorylineNarrative(sid, period, label, "text", nil)
+	}
+	storyline("V1", "Vendor", 9)
+	storyline("N1", "Press", 8)
+	storyline("N2", "Press", 7)
+	storyline("N3", "Press", 6)
+	storyline("N4", "Press", 5)
+
+	narratives, err := db.GetNarrativesForPeriod(period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, n := range narratives {
+		titles = append(titles, n.Title)
+	}
+	if got := strings.Join(titles, ","); got != "N1,N2,N3,V1,N4" {
+		t.Errorf("unexpected briefing order %s", got)
+	}
+}
+
+func TestSourceWeights(t *testing.T) {
+	db := openTestDB(t)
+	period := "2026-02-06"
+	db.SetSourceType("Trusted", SourceNews)
+	db.SetSourceWeight("Trusted", 2)
+	db.SetSourceType("Trusted", SourceBlog) // retyping keeps the weight
+	db.SetSourceType("Plain", SourceNews)
+
+	var urls int
+	storyline := func(label, source string, size int) []int64 {
+		var ids []int64
+		for range size {
+			urls++
+			id, _ := db.InsertArticle(fmt.Sprintf("https://example.com/%d", urls), label, ptr(source), nil, nil, &period)
+			ids = append(ids, id)
+		}
+		sid, _ := db.InsertStoryline(period, label, ids)
+		db.InsertStorylineNarrative(sid, period, label, "text", nil)
+		return ids
+	}
+	storyline("Plain", "Plain", 5)
+	storyline("Trusted", "Trusted", 3)
+	rated := storyline("Rated", "Rated", 4)
+	for _, id := range rated {
+		db.UpsertArticleFeedback(id, "positive")
+	}
+
+	weights, err := db.GetSourceWeights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := weights["Trusted"]; w.Configured != 2 || w.Weight != 2 {
+		t.Errorf("expected the configured weight of Trusted, got %+v", w)
+	}
+	if w := weights["Rated"]; w.Configured != 1 || w.Positive != 4 || w.Weight != AdjustedWeight(1, 4, 0) || w.Weight <= 1 {
+		t.Errorf("expected positive ratings to raise the weight of Rated, got %+v", w)
+	}
+	if _, ok := weights["Plain"]; ok {
+		t.Error("expected no entry for an unweighted, unrated source")
+	}
+	if got := WeightOf(weights, ptr("Plain")); got != 1 {
+		t.Errorf("expected a weight of 1 for Plain, got %g", got)
+	}
+	if got := AdjustedWeight(2, 0, 1000); got <= 1 || got >= 1.01 {
+		t.Errorf("expected negative ratings to halve a weight at most, got %g", got)
+	}
+
+	// 3 articles weighted 2 outrank 5 of weight 1; 4 rated ones count about 4.9.
+	narratives, err := db.GetNarrativesForPeriod(period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, n := range narratives {
+		titles = append(titles, n.Title)
+	}
+	if got := strings.Join(titles, ","); got != "Trusted,Plain,Rated" {
+		t.Errorf("unexpected briefing order %s", got)
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	src := openTestDB(t)
+	qa, _ := src.InsertProfile("qa", "For QA")
+	pid, _ := src.InsertPriority("Test Automation", "AI in testing", []string{"playwright"})
+	src.SetPriorityProfile(pid, &qa)
+	a1, _ := src.InsertArticle("https://a.com", "A", ptr("TechBlog"), nil, nil, ptr("2026-02-06"))
+	a2, _ := src.InsertArticle("https://b.com", "B", ptr("NewsSite"), nil, nil, ptr("2026-02-06"))
+	at := "experience_report"
+	src.InsertTriage(a1, "relevant", &at, nil, nil, 4)
+	src.UpsertArticleFeedback(a1, "positive")
+	src.UpsertArticleFeedback(a2, "negative")
+	sid, _ := src.InsertStoryline("2026-02-06", "AI Testing", []int64{a1})
+	src.UpsertStorylineFeedback(sid, "2026-02-06", "useful")
+	src.InsertMuteRule(MuteTerm, "crypto")
+	src.InsertMuteRule(MuteSource, "SpamBlog")
+
+	exported, err := src.ExportBundle()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := json.Marshal(exported)
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The destination already has b.com and its own rating of it, plus the
+	// storyline; a.com hasn't been collected there yet.
+	dst := openTestDB(t)
+	b2, _ := dst.InsertArticle("https://b.com", "B", ptr("NewsSite"), nil, nil, ptr("2026-02-06"))
+	dst.UpsertArticleFeedback(b2, "positive")
+	dsid, _ := dst.InsertStoryline("2026-02-06", "AI Testing", []int64{b2})
+	dst.InsertMuteRule(MuteTerm, "Crypto")
+
+	result, err := dst.ImportBundle(&bundle, "cli:ada")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := ImportResult{Profiles: 1, Priorities: 1, MuteRules: 1, PendingFeedback: 1, StorylineFeedback: 1, Skipped: 2}
+	if *result != want {
+		t.Errorf("result = %+v, want %+v", *result, want)
+	}
+
+	priorities, _ := dst.GetAllPriorities()
+	profile, _ := dst.GetProfileByName("qa")
+	if len(priorities) != 1 || profile == nil || priorities[0].ProfileID == nil || *priorities[0].ProfileID != profile.ID {
+		t.Errorf("expected the priority to keep its profile, got %+v", priorities)
+	}
+	if fb, _ := dst.GetArticleFeedback(b2); fb == nil || fb.Rating != "positive" {
+		t.Error("expected the local rating to win")
+	}
+	if fb, _ := dst.GetStorylineFeedback(dsid); fb == nil || fb.Rating != "useful" {
+		t.Error("expected storyline feedback matched by label")
+	}
+	rules, _ := dst.GetMuteRules()
+	if len(rules) != 2 || rules[0].Value != "Crypto" || rules[1].Kind != MuteSource || rules[1].Value != "SpamBlog" {
+		t.Errorf("expected the local term kept and the source muted, got %+v", rules)
+	}
+	entries, _, _ := dst.ListAuditEntries(AuditFilter{Action: AuditMuteAdd}, ListOptions{})
+	if len(entries) != 1 || entries[0].User != "cli:ada" || entries[0].TargetID == nil || *entries[0].TargetID != rules[1].ID {
+		t.Errorf("expected the imported rule in the audit log, got %+v", entries)
+	}
+
+	// Pending feedback counts towards triage before the article arrives...
+	summary, _ := dst.GetFeedbackSummary()
+	if len(summary.Types) != 1 || summary.Types[0].ArticleType != "experience_report" {
+		t.Errorf("expected imported type feedback, got %+v", summary.Types)
+	}
+	// ...and is attached to it once collected.
+	a1dst, _ := dst.InsertArticle("https://a.com", "A", ptr("TechBlog"), nil, nil, ptr("2026-02-07"))
+	if fb, _ := dst.GetArticleFeedback(a1dst); fb == nil || fb.Rating != "positive" {
+		t.Error("expected imported feedback on the collected article")
+	}
+	if again, _ := dst.ExportBundle(); len(again.ArticleFeedback) != 2 {
+		t.Errorf("expected 2 article ratings after collection, got %d", len(again.ArticleFeedback))
+	}
+
+	// Importing again adds nothing.
+	result, _ = dst.ImportBundle(&bundle, "cli:ada")
+	if result.Profiles+result.Priorities+result.MuteRules+result.ArticleFeedback+result.PendingFeedback+result.StorylineFeedback != 0 {
+		t.Errorf("expected a repeated import to add nothing, got %+v", *result)
+	}
+
+	if _, err := dst.ImportBundle(&Bundle{Version: BundleVersion + 1}, "cli:ada"); err == nil {
+		t.Error("expected an error for a newer bundle version")
+	}
+
+	// A version 1 bundle, from before mute rules, still imports.
+	var v1 Bundle
+	json.Unmarshal([]byte(`{"version": 1, "profiles": [{"name": "ops", "heading": "For ops"}], "priorities": [],
+		"article_feedback": [], "storyline_feedback": []}`), &v1)
+	if result, err := dst.ImportBundle(&v1, "cli:ada"); err != nil || result.Profiles != 1 || result.MuteRules != 0 {
+		t.Errorf("expected the version 1 bundle imported, got %+v, %v", result, err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	db := openTestDB(t)
+
+	db.RecordAudit("ada", AuditPriorityAdd, 3, "Agents")
+	db.RecordAudit("bob", AuditStorylineFeedback, 7, "useful")
+	db.RecordAudit("cli:ada", AuditImport, 0, "bundle.json")
+
+	entries, total, err := db.ListAuditEntries(AuditFilter{}, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d of %d", len(entries), total)
+	}
+	if entries[0].Action != AuditImport || entries[0].TargetID != nil {
+		t.Errorf("expected newest entry first without a target, got %+v", entries[0])
+	}
+
+	entries, total, _ = db.ListAuditEntries(AuditFilter{User: "BOB"}, ListOptions{})
+	if total != 1 || entries[0].Detail != "useful" || entries[0].TargetID == nil || *entries[0].TargetID != 7 {
+		t.Errorf("expected bob's feedback entry, got %+v", entries)
+	}
+	if _, total, _ := db.ListAuditEntries(AuditFilter{Action: AuditPriorityAdd}, ListOptions{}); total != 1 {
+		t.Errorf("expected 1 priority addition, got %d", total)
+	}
+}
+
+func TestTopicMemories(t *testing.T) {
+	db := openTestDB(t)
+
+	db.ReplaceTopicMemories("2026-02-01", []TopicMemory{{Topic: "Vendor X outages", Summary: "Feb 1: API down", Keywords: []string{"Vendor X"}}})
+	db.ReplaceTopicMemories("2026-02-03", []TopicMemory{
+		{Topic: "Vendor X outages", Summary: "Feb 1 and 3: API down", Keywords: []string{"Vendor X"}, Periods: 2},
+		{Topic: "Agent benchmarks", Summary: "Feb 3: new benchmark"},
+	})
+
+	// A period recalls what earlier periods wrote, not what it wrote itself.
+	memories, err := db.GetTopicMemories("2026-02-03", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(memories) != 1 || memories[0].PeriodID != "2026-02-01" || memories[0].Periods != 1 || memories[0].Keywords[0] != "Vendor X" {
+		t.Fatalf("expected the Feb 1 memory only, got %+v", memories)
+	}
+	memories, _ = db.GetTopicMemories("2026-02-04", "")
+	if len(memories) != 2 || memories[0].Topic != "Agent benchmarks" || memories[1].Summary != "Feb 1 and 3: API down" || memories[1].Periods != 2 {
+		t.Fatalf("expected the latest memory of both topics, got %+v", memories)
+	}
+	if memories, _ := db.GetTopicMemories("2026-02-03", "2026-02-02"); len(memories) != 0 {
+		t.Errorf("expected topics last updated before since to be left out, got %+v", memories)
+	}
+
+	// Updating a period again replaces what it wrote.
+	db.ReplaceTopicMemories("2026-02-03", []TopicMemory{{Topic: "Vendor X outages", Summary: "Rewritten", Periods: 2}})
+	memories, _ = db.GetTopicMemories("2026-02-04", "")
+	if len(memories) != 1 || memories[0].Summary != "Rewritten" {
+		t.Errorf("expected the rewritten memory only, got %+v", memories)
+	}
+}
+
+func TestBackupTo(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.InsertArticle("https://example.com/backup", "Backed Up", nil, nil, nil, ptr("2026-02-06")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backups", "copy.db")
+	if err := db.BackupTo(path); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	copied, err := Open(path)
+	if err != nil {
+		t.Fatalf("opening backup: %v", err)
+	}
+	defer copied.Close()
+	articles, err := copied.GetArticlesForPeriod("2026-02-06")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(articles) != 1 || articles[0].Title != "Backed Up" {
+		t.Errorf("backup holds %+v, want the backed up article", articles)
+	}
+
+	if err := db.BackupTo(path); err == nil {
+		t.Error("expected an error backing up over an existing file")
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+// ArticleFingerprint is the simhash of an article's content.
+type ArticleFingerprint struct {
+	ArticleID int64
+	Simhash   uint64
+}
+
+// GetArticlesWithoutSimhash returns the articles of a period that have
+// content but no simhash yet, oldest first. Paywalled articles have only a
+// teaser and are left out.
+func (db *DB) GetArticlesWithoutSimhash(periodID string) ([]Article, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at, category, source_weight, paywalled, language, image_url
+		FROM articles WHERE period_id = ? AND simhash IS NULL AND content <> '' AND paywalled = 0
+		ORDER BY collected_at, id`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
+
+// GetFingerprints returns the simhashes of the articles of the periods
+// starting on or after since (a YYYY-MM-DD date) that aren't
+// near-duplicates themselves, oldest first.
+func (db *DB) GetFingerprints(since string) ([]ArticleFingerprint, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, simhash FROM articles
+		WHERE simhash IS NOT NULL AND duplicate_of IS NULL AND period_id >= ?
+		ORDER BY collected_at, id`, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fingerprints []ArticleFingerprint
+	for rows.Next() {
+		var f ArticleFingerprint
+		var hash int64
+		if err := rows.Scan(&f.ArticleID, &hash); err != nil {
+			return nil, err
+		}
+		f.Simhash = uint64(hash)
+		fingerprints = append(fingerprints, f)
+	}
+	return fingerprints, rows.Err()
+}
+
+// SetArticleSimhash stores the simhash of an article's content and, when
+// it is a near-duplicate, the article it duplicates. Triage and clustering
+// leave near-duplicates out.
+func (db *DB) SetArticleSimhash(articleID int64, simhash uint64, duplicateOf *int64) error {
+	_, err := db.conn.Exec(
+		"UPDATE articles SET simhash = ?, duplicate_of = ? WHERE id = ?",
+		int64(simhash), duplicateOf, articleID,
+	)
+	return err
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// GetArticleEmbeddings returns the embeddings model cached for the given
+// articles, by article ID. Articles without one are missing from the map.
+func (db *DB) GetArticleEmbeddings(model string, articleIDs []int64) (map[int64]ArticleEmbedding, error) {
+	embeddings := make(map[int64]ArticleEmbedding)
+	if len(articleIDs) == 0 {
+		return embeddings, nil
+	}
+	args := []any{model}
+	for _, id := range articleIDs {
+		args = append(args, id)
+	}
+	rows, err := db.conn.Query(
+		`SELECT article_id, text_hash, vector FROM article_embeddings
+		WHERE model = ? AND article_id IN (?`+strings.Repeat(", ?", len(articleIDs)-1)+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := ArticleEmbedding{Model: model}
+		var vector string
+		if err := rows.Scan(&e.ArticleID, &e.TextHash, &vector); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(vector), &e.Vector); err != nil {
+			return nil, err
+		}
+		embeddings[e.ArticleID] = e
+	}
+	return embeddings, rows.Err()
+}
+
+// PutArticleEmbeddings caches embeddings, replacing those the same models
+// stored earlier for the same articles.
+func (db *DB) PutArticleEmbeddings(embeddings []ArticleEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range embeddings {
+		vector, err := json.Marshal(e.Vector)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO article_embeddings (article_id, model, text_hash, vector) VALUES (?, ?, ?, ?)`,
+			e.ArticleID, e.Model, e.TextHash, string(vector),
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// EmbeddingDimensions returns the distinct vector sizes cached for model,
+// smallest first.
+func (db *DB) EmbeddingDimensions(model string) ([]int, error) {
+	rows, err := db.conn.Query(
+		`SELECT DISTINCT json_array_length(vector) AS dims FROM article_embeddings WHERE model = ? ORDER BY dims`, model,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dims []int
+	for rows.Next() {
+		var d int
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dims = append(dims, d)
+	}
+	return dims, rows.Err()
+}
+
+// DeleteMismatchedEmbeddings deletes the embeddings cached for model whose
+// size isn't dimensions and returns how many were deleted.
+func (db *DB) DeleteMismatchedEmbeddings(model string, dimensions int) (int64, error) {
+	res, err := db.conn.Exec(
+		`DELETE FROM article_embeddings WHERE model = ? AND json_array_length(vector) != ?`, model, dimensions,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
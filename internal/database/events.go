@@ -0,0 +1,65 @@
+package database
+
+import "time"
+
+// Briefings list events from the days following their period.
+const (
+	UpcomingDays      = 30
+	MaxUpcomingEvents = 8
+)
+
+// UpcomingWindow returns the date range, as YYYY-MM-DD, covering the given
+// number of days after a period ends.
+func UpcomingWindow(periodID string, days int) (from, to string) {
+	from = PeriodEndDate(periodID)
+	end, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		end = time.Now()
+		from = end.Format("2006-01-02")
+	}
+	return from, end.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// InsertEvent records an upcoming event. Events with the same title and date
+// are stored once, however many articles mention them; returns 0 then.
+func (db *DB) InsertEvent(articleID int64, title, kind, eventDate string) (int64, error) {
+	result, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO events (article_id, title, kind, event_date) VALUES (?, ?, ?, ?)`,
+		articleID, title, kind, eventDate,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+	return result.LastInsertId()
+}
+
+// GetEventsBetween returns events dated from..to inclusive (YYYY-MM-DD),
+// soonest first, up to limit (0 for no limit).
+func (db *DB) GetEventsBetween(from, to string, limit int) ([]Event, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := db.conn.Query(
+		`SELECT e.id, e.article_id, e.title, e.kind, e.event_date, a.url, e.created_at
+		FROM events e LEFT JOIN articles a ON a.id = e.article_id
+		WHERE e.event_date >= ? AND e.event_date <= ?
+		ORDER BY e.event_date, e.id LIMIT ?`, from, to, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.ArticleID, &e.Title, &e.Kind, &e.EventDate, &e.ArticleURL, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -119,44 +119,28 @@ func (db *DB) GetArticleFeedbackMap(articleIDs []int64) (map[int64]string, error
 	return m, rows.Err()
 }
 
-// GetFeedbackSummary aggregates all feedback for triage prompt injection.
+// GetFeedbackSummary aggregates all feedback for triage prompt injection,
+// including imported feedback for articles not collected here.
 func (db *DB) GetFeedbackSummary() (*FeedbackSummary, error) {
-	summary := &FeedbackSummary{}
-
-	// Source feedback: join article_feedback with articles to group by source
-	sourceRows, err := db.conn.Query(`
-		SELECT COALESCE(a.source, 'Unknown') as source,
-			SUM(CASE WHEN af.rating = 'positive' THEN 1 ELSE 0 END) as positive,
-			SUM(CASE WHEN af.rating = 'negative' THEN 1 ELSE 0 END) as negative
-		FROM article_feedback af
-		JOIN articles a ON a.id = af.article_id
-		GROUP BY COALESCE(a.source, 'Unknown')
-		HAVING positive > 0 OR negative > 0
-		ORDER BY (positive - negative) DESC`)
+	sources, err := db.sourceFeedback()
 	if err != nil {
 		return nil, err
 	}
-	defer sourceRows.Close()
-
-	for sourceRows.Next() {
-		var sf SourceFeedback
-		if err := sourceRows.Scan(&sf.Source, &sf.Positive, &sf.Negative); err != nil {
-			return nil, err
-		}
-		summary.Sources = append(summary.Sources, sf)
-	}
-	if err := sourceRows.Err(); err != nil {
-		return nil, err
-	}
+	summary := &FeedbackSummary{Sources: sources}
 
 	// Type feedback: join article_feedback with article_triage to group by article_type
 	typeRows, err := db.conn.Query(`
-		SELECT COALESCE(at.article_type, 'other') as article_type,
-			SUM(CASE WHEN af.rating = 'positive' THEN 1 ELSE 0 END) as positive,
-			SUM(CASE WHEN af.rating = 'negative' THEN 1 ELSE 0 END) as negative
-		FROM article_feedback af
-		JOIN article_triage at ON at.article_id = af.article_id
-		GROUP BY COALESCE(at.article_type, 'other')
+		SELECT article_type,
+			SUM(CASE WHEN rating = 'positive' THEN 1 ELSE 0 END) as positive,
+			SUM(CASE WHEN rating = 'negative' THEN 1 ELSE 0 END) as negative
+		FROM (
+			SELECT COALESCE(at.article_type, 'other') as article_type, af.rating
+			FROM article_feedback af
+			JOIN article_triage at ON at.article_id = af.article_id
+			UNION ALL
+			SELECT article_type, rating FROM imported_feedback WHERE article_type IS NOT NULL
+		)
+		GROUP BY article_type
 		HAVING positive > 0 OR negative > 0
 		ORDER BY (positive - negative) DESC`)
 	if err != nil {
@@ -174,6 +158,39 @@ func (db *DB) GetFeedbackSummary() (*FeedbackSummary, error) {
 	return summary, typeRows.Err()
 }
 
+// sourceFeedback counts the positive and negative ratings of the articles
+// of each rated source, imported feedback included, best rated first.
+func (db *DB) sourceFeedback() ([]SourceFeedback, error) {
+	rows, err := db.conn.Query(`
+		SELECT source,
+			SUM(CASE WHEN rating = 'positive' THEN 1 ELSE 0 END) as positive,
+			SUM(CASE WHEN rating = 'negative' THEN 1 ELSE 0 END) as negative
+		FROM (
+			SELECT COALESCE(a.source, 'Unknown') as source, af.rating
+			FROM article_feedback af
+			JOIN articles a ON a.id = af.article_id
+			UNION ALL
+			SELECT COALESCE(source, 'Unknown'), rating FROM imported_feedback
+		)
+		GROUP BY source
+		HAVING positive > 0 OR negative > 0
+		ORDER BY (positive - negative) DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []SourceFeedback
+	for rows.Next() {
+		var sf SourceFeedback
+		if err := rows.Scan(&sf.Source, &sf.Positive, &sf.Negative); err != nil {
+			return nil, err
+		}
+		sources = append(sources, sf)
+	}
+	return sources, rows.Err()
+}
+
 func repeatString(s string, n int) string {
 	result := ""
 	for i := 0; i < n; i++ {
@@ -181,3 +198,36 @@ func repeatString(s string, n int) string {
 	}
 	return result
 }
+
+// GetRecentRatings returns the articles and storylines rated on or after
+// since (YYYY-MM-DD), newest first, up to limit of each.
+func (db *DB) GetRecentRatings(since string, limit int) ([]Rating, error) {
+	rows, err := db.conn.Query(`
+		SELECT * FROM (
+			SELECT a.title, COALESCE(a.source, ''), 0, af.rating = 'positive', af.created_at
+			FROM article_feedback af JOIN articles a ON a.id = af.article_id
+			WHERE af.created_at >= ? ORDER BY af.created_at DESC LIMIT ?
+		)
+		UNION ALL
+		SELECT * FROM (
+			SELECT COALESCE((SELECT n.title FROM storyline_narratives n WHERE n.storyline_id = s.id ORDER BY n.id DESC LIMIT 1), s.label),
+				'', 1, sf.rating = 'useful', sf.created_at
+			FROM storyline_feedback sf JOIN storylines s ON s.id = sf.storyline_id
+			WHERE sf.created_at >= ? ORDER BY sf.created_at DESC LIMIT ?
+		)
+		ORDER BY 5 DESC`, since, limit, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ratings []Rating
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.Title, &r.Source, &r.Storyline, &r.Positive, &r.RatedAt); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
@@ -0,0 +1,48 @@
+package database
+
+// RecordFeedSuccess notes that a feed was fetched and parsed, which resets
+// its count of consecutive failures.
+func (db *DB) RecordFeedSuccess(feedURL string) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO feed_health (feed_url, failures, last_success_at) VALUES (?, 0, datetime('now'))
+		ON CONFLICT(feed_url) DO UPDATE SET
+			failures = 0, last_error = NULL, last_success_at = datetime('now'), last_checked_at = datetime('now')`,
+		feedURL,
+	)
+	return err
+}
+
+// RecordFeedFailure notes a failed fetch of a feed and why it failed.
+func (db *DB) RecordFeedFailure(feedURL, reason string) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO feed_health (feed_url, failures, last_error) VALUES (?, 1, ?)
+		ON CONFLICT(feed_url) DO UPDATE SET
+			failures = failures + 1, last_error = excluded.last_error, last_checked_at = datetime('now')`,
+		feedURL, reason,
+	)
+	return err
+}
+
+// GetFailingFeeds returns the feeds whose last minFailures fetches or more
+// all failed, most failures first.
+func (db *DB) GetFailingFeeds(minFailures int) ([]FeedHealth, error) {
+	rows, err := db.conn.Query(
+		`SELECT feed_url, failures, last_error, last_success_at, last_checked_at
+		FROM feed_health WHERE failures >= ? AND failures > 0
+		ORDER BY failures DESC, feed_url`, minFailures,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []FeedHealth
+	for rows.Next() {
+		var f FeedHealth
+		if err := rows.Scan(&f.FeedURL, &f.Failures, &f.LastError, &f.LastSuccessAt, &f.LastCheckedAt); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
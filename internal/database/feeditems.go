@@ -0,0 +1,81 @@
+package database
+
+import "database/sql"
+
+// HasFeedItem reports whether the entry with guid was already collected from
+// the feed at feedURL, whatever URL it was listed under then.
+func (db *DB) HasFeedItem(feedURL, guid string) (bool, error) {
+	var n int
+	err := db.conn.QueryRow(
+		`SELECT COUNT(*) FROM feed_items WHERE feed_url = ? AND guid = ?`, feedURL, guid,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// AddFeedItem remembers that the entry with guid was collected from the feed
+// at feedURL, as the article with articleID (0 when the entry was a duplicate
+// of an article collected before GUIDs were recorded).
+func (db *DB) AddFeedItem(feedURL, guid string, articleID int64) error {
+	var id *int64
+	if articleID > 0 {
+		id = &articleID
+	}
+	_, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO feed_items (feed_url, guid, article_id) VALUES (?, ?, ?)`, feedURL, guid, id,
+	)
+	return err
+}
+
+// HasFeedItems reports whether any entry was collected from the feed at
+// feedURL yet, which a sitemap read for the first time hasn't.
+func (db *DB) HasFeedItems(feedURL string) (bool, error) {
+	var n int
+	err := db.conn.QueryRow(
+		`SELECT COUNT(*) FROM (SELECT 1 FROM feed_items WHERE feed_url = ? LIMIT 1)`, feedURL,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// AddFeedItems remembers the entries with guids as seen in the feed at
+// feedURL without an article, in one transaction; a sitemap's existing
+// pages are recorded this way so only pages added later are collected.
+func (db *DB) AddFeedItems(feedURL string, guids []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, guid := range guids {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO feed_items (feed_url, guid) VALUES (?, ?)`, feedURL, guid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateFeedItem records when the entry with guid of the feed at feedURL was
+// last updated, as an RFC 3339 time in UTC. It reports whether the entry
+// was updated since the time recorded before, and if so returns the article
+// it was collected as (0 when none); the first time recorded is no update.
+func (db *DB) UpdateFeedItem(feedURL, guid, updated string) (int64, bool, error) {
+	var articleID sql.NullInt64
+	var previous sql.NullString
+	err := db.conn.QueryRow(
+		`SELECT article_id, updated_at FROM feed_items WHERE feed_url = ? AND guid = ?`, feedURL, guid,
+	).Scan(&articleID, &previous)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil || updated <= previous.String {
+		return 0, false, err
+	}
+	if _, err := db.conn.Exec(
+		`UPDATE feed_items SET updated_at = ? WHERE feed_url = ? AND guid = ?`, updated, feedURL, guid,
+	); err != nil {
+		return 0, false, err
+	}
+	if !previous.Valid {
+		return 0, false, nil
+	}
+	return articleID.Int64, true, nil
+}
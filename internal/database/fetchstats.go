@@ -0,0 +1,54 @@
+package database
+
+import "time"
+
+// RecordFetchAttempt adds a fetch attempt to today's stats of its domain.
+func (db *DB) RecordFetchAttempt(a FetchAttempt) error {
+	failed := a.Error != ""
+	_, err := db.conn.Exec(
+		`INSERT INTO fetch_stats (domain, day, attempts, failures, paywalled, rendered, archived, total_ms, last_error)
+		VALUES (?, date('now'), 1, ?, ?, ?, ?, ?, NULLIF(?, ''))
+		ON CONFLICT(domain, day) DO UPDATE SET
+			attempts = attempts + 1,
+			failures = failures + excluded.failures,
+			paywalled = paywalled + excluded.paywalled,
+			rendered = rendered + excluded.rendered,
+			archived = archived + excluded.archived,
+			total_ms = total_ms + excluded.total_ms,
+			last_error = COALESCE(excluded.last_error, last_error)`,
+		a.Domain, failed, a.Paywalled, a.Rendered, a.Archived, a.Duration.Milliseconds(), a.Error,
+	)
+	return err
+}
+
+// GetFetchStats returns the fetch stats of every domain attempted in the
+// last days, the domains failing most often first.
+func (db *DB) GetFetchStats(days int) ([]DomainFetchStats, error) {
+	rows, err := db.conn.Query(
+		`SELECT domain, SUM(attempts), SUM(failures), SUM(paywalled), SUM(rendered), SUM(archived), SUM(total_ms),
+			(SELECT last_error FROM fetch_stats l WHERE l.domain = s.domain AND l.last_error IS NOT NULL AND l.day >= ?1
+			ORDER BY l.day DESC LIMIT 1)
+		FROM fetch_stats s WHERE day >= ?1
+		GROUP BY domain
+		ORDER BY CAST(SUM(failures) AS REAL) / SUM(attempts) DESC, SUM(attempts) DESC, domain`,
+		time.Now().UTC().AddDate(0, 0, 1-days).Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []DomainFetchStats
+	for rows.Next() {
+		var s DomainFetchStats
+		var totalMS int64
+		if err := rows.Scan(&s.Domain, &s.Attempts, &s.Failures, &s.Paywalled, &s.Rendered, &s.Archived, &totalMS, &s.LastError); err != nil {
+			return nil, err
+		}
+		if s.Attempts > 0 {
+			s.AvgLatency = time.Duration(totalMS/int64(s.Attempts)) * time.Millisecond
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// jobTimeLayout matches SQLite's datetime('now'), so stored and generated
+// timestamps compare as strings.
+const jobTimeLayout = "2006-01-02 15:04:05"
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_after,
+	result, last_error, created_at, started_at, finished_at`
+
+// EnqueueJob adds a job, due at runAfter, that may run up to maxAttempts
+// times.
+func (db *DB) EnqueueJob(kind, payload string, maxAttempts int, runAfter time.Time) (int64, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	result, err := db.conn.Exec(
+		`INSERT INTO jobs (kind, payload, max_attempts, run_after) VALUES (?, ?, ?, ?)`,
+		kind, payload, maxAttempts, runAfter.UTC().Format(jobTimeLayout),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ClaimNextJob marks the oldest queued job that is due at now as running and
+// returns it, or nil when none is due.
+func (db *DB) ClaimNextJob(now time.Time) (*Job, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stamp := now.UTC().Format(jobTimeLayout)
+	var id int64
+	err = tx.QueryRow(
+		`SELECT id FROM jobs WHERE status = ? AND run_after <= ? ORDER BY run_after, id LIMIT 1`,
+		JobQueued, stamp,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE jobs SET status = ?, attempts = attempts + 1, started_at = ?, finished_at = NULL WHERE id = ?`,
+		JobRunning, stamp, id,
+	); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetJob(id)
+}
+
+// CompleteJob marks a job as done with a short result summary.
+func (db *DB) CompleteJob(id int64, result string) error {
+	_, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, result = ?, last_error = NULL, finished_at = datetime('now') WHERE id = ?`,
+		JobDone, result, id,
+	)
+	return err
+}
+
+// FailJob records a failed attempt and whatever result it produced. With a
+// retryAt the job is queued again for that time; without one it is marked
+// failed.
+func (db *DB) FailJob(id int64, result, errMsg string, retryAt *time.Time) error {
+	if retryAt != nil {
+		_, err := db.conn.Exec(
+			`UPDATE jobs SET status = ?, result = ?, last_error = ?, run_after = ? WHERE id = ?`,
+			JobQueued, result, errMsg, retryAt.UTC().Format(jobTimeLayout), id,
+		)
+		return err
+	}
+	_, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, result = ?, last_error = ?, finished_at = datetime('now') WHERE id = ?`,
+		JobFailed, result, errMsg, id,
+	)
+	return err
+}
+
+// RetryJob queues a failed job again with a fresh set of attempts. It
+// reports whether the job was failed.
+func (db *DB) RetryJob(id int64) (bool, error) {
+	result, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, attempts = 0, run_after = datetime('now'), finished_at = NULL
+		WHERE id = ? AND status = ?`,
+		JobQueued, id, JobFailed,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RequeueRunningJobs puts jobs left running by an interrupted worker back in
+// the queue, returning how many were requeued.
+func (db *DB) RequeueRunningJobs() (int64, error) {
+	result, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, run_after = datetime('now') WHERE status = ?`,
+		JobQueued, JobRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetJob returns a job by ID, or nil if it does not exist.
+func (db *DB) GetJob(id int64) (*Job, error) {
+	jobs, err := db.queryJobs(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// GetJobs returns the most recent jobs, newest first.
+func (db *DB) GetJobs(limit int) ([]Job, error) {
+	return db.queryJobs(`SELECT `+jobColumns+` FROM jobs ORDER BY id DESC LIMIT ?`, limit)
+}
+
+func (db *DB) queryJobs(query string, args ...any) ([]Job, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var j Job
+		if err := rows.Scan(&j.ID, &j.Kind, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAfter,
+			&j.Result, &j.LastError, &j.CreatedAt, &j.StartedAt, &j.FinishedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
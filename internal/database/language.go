@@ -0,0 +1,55 @@
+package database
+
+// GetArticlesWithoutLanguage returns the articles of a period whose
+// language hasn't been detected yet, oldest first.
+func (db *DB) GetArticlesWithoutLanguage(periodID string) ([]Article, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, url, title, source, published_date, content, content_fetched, period_id, collected_at, category, source_weight, paywalled, language, image_url
+		FROM articles WHERE period_id = ? AND language IS NULL
+		ORDER BY collected_at, id`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
+
+// SetArticleLanguage records the language an article is written in, as an
+// ISO 639-1 code, or "" when it couldn't be told.
+func (db *DB) SetArticleLanguage(articleID int64, language string) error {
+	_, err := db.conn.Exec("UPDATE articles SET language = ? WHERE id = ?", language, articleID)
+	return err
+}
+
+// GetUntranslatedArticles returns the untriaged articles of a period that
+// are written in a known language other than English and weren't
+// translated yet, oldest first. Near-duplicates aren't translated.
+func (db *DB) GetUntranslatedArticles(periodID string) ([]Article, error) {
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url
+		FROM articles a LEFT JOIN article_triage t ON a.id = t.article_id
+		WHERE t.article_id IS NULL AND a.period_id = ? AND a.duplicate_of IS NULL
+		AND a.language NOT IN ('', 'en') AND a.original_title IS NULL
+		ORDER BY a.collected_at, a.id`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
+
+// SetArticleTranslation replaces an article's title and content by their
+// translation, keeping the originals in original_title and
+// original_content. An article is translated once; its language stays the
+// one it was written in.
+func (db *DB) SetArticleTranslation(articleID int64, title string, content *string) error {
+	_, err := db.conn.Exec(
+		`UPDATE articles SET original_title = title, original_content = content, title = ?, content = ?
+		WHERE id = ? AND original_title IS NULL`,
+		title, content, articleID,
+	)
+	return err
+}
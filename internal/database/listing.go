@@ -0,0 +1,142 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownSort is returned by list queries asked to sort by a key they
+// don't offer.
+var ErrUnknownSort = errors.New("unknown sort key")
+
+// VerdictUntriaged filters ArticleFilter for articles without a triage.
+const VerdictUntriaged = "untriaged"
+
+// listClause returns the ORDER BY, LIMIT and OFFSET clause of opts. sorts
+// maps the sort keys a query offers to their column; an empty Sort uses
+// fallback. tiebreak orders rows with equal sort values, so pages don't
+// overlap.
+func listClause(opts ListOptions, sorts map[string]string, fallback ListOptions, tiebreak string) (string, []any, error) {
+	if opts.Sort == "" {
+		opts.Sort, opts.Desc = fallback.Sort, fallback.Desc
+	}
+	column, ok := sorts[opts.Sort]
+	if !ok {
+		keys := slices.Sorted(maps.Keys(sorts))
+		return "", nil, fmt.Errorf("%w %q (one of: %s)", ErrUnknownSort, opts.Sort, strings.Join(keys, ", "))
+	}
+
+	direction := " ASC"
+	if opts.Desc {
+		direction = " DESC"
+	}
+	clause := " ORDER BY " + column + direction + ", " + tiebreak + direction
+	var args []any
+	if opts.Limit > 0 {
+		clause += " LIMIT ? OFFSET ?"
+		args = append(args, opts.Limit, opts.Offset)
+	} else if opts.Offset > 0 {
+		clause += " LIMIT -1 OFFSET ?"
+		args = append(args, opts.Offset)
+	}
+	return clause, args, nil
+}
+
+var articleSorts = map[string]string{
+	"collected": "a.collected_at",
+	"published": "a.published_date",
+	"score":     "t.practical_score",
+	"title":     "a.title COLLATE NOCASE",
+}
+
+// ListArticles returns a page of the articles matching f, with their triage,
+// and the number of matching articles across all pages. Sort keys are
+// "collected" (the default, newest first), "published", "score" and
+// "title".
+func (db *DB) ListArticles(f ArticleFilter, opts ListOptions) ([]ArticleListing, int, error) {
+	var where []string
+	var args []any
+	if f.PeriodID != "" {
+		where, args = append(where, "a.period_id = ?"), append(args, f.PeriodID)
+	}
+	if f.Source != "" {
+		where, args = append(where, "a.source = ? COLLATE NOCASE"), append(args, f.Source)
+	}
+	switch f.Verdict {
+	case "":
+	case VerdictUntriaged:
+		where = append(where, "t.article_id IS NULL")
+	default:
+		where, args = append(where, "t.verdict = ?"), append(args, f.Verdict)
+	}
+	from := ` FROM articles a LEFT JOIN article_triage t ON t.article_id = a.id`
+	if len(where) > 0 {
+		from += ` WHERE ` + strings.Join(where, " AND ")
+	}
+
+	clause, pageArgs, err := listClause(opts, articleSorts, ListOptions{Sort: "collected", Desc: true}, "a.id")
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content_fetched, a.period_id, a.collected_at,
+		t.verdict, t.article_type, t.practical_score`+from+clause, append(args, pageArgs...)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var articles []ArticleListing
+	for rows.Next() {
+		var a ArticleListing
+		var fetched int
+		if err := rows.Scan(&a.ID, &a.URL, &a.Title, &a.Source, &a.PublishedDate, &fetched, &a.PeriodID, &a.CollectedAt,
+			&a.Verdict, &a.ArticleType, &a.PracticalScore); err != nil {
+			return nil, 0, err
+		}
+		a.ContentFetched = fetched != 0
+		articles = append(articles, a)
+	}
+	return articles, total, rows.Err()
+}
+
+var modelReleaseSorts = map[string]string{
+	"release_date": "m.release_date",
+	"name":         "m.name COLLATE NOCASE",
+	"vendor":       "m.vendor COLLATE NOCASE",
+}
+
+// ListModelReleases returns a page of the releases dated from..to inclusive
+// (YYYY-MM-DD; empty for open-ended) and the number of such releases across
+// all pages. Sort keys are "release_date" (the default, newest first),
+// "name" and "vendor".
+func (db *DB) ListModelReleases(from, to string, opts ListOptions) ([]ModelRelease, int, error) {
+	if to == "" {
+		to = "9999-12-31"
+	}
+	clause, pageArgs, err := listClause(opts, modelReleaseSorts, ListOptions{Sort: "release_date", Desc: true}, "m.id")
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := db.conn.QueryRow(
+		`SELECT COUNT(*) FROM model_releases WHERE release_date >= ? AND release_date <= ?`, from, to,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	releases, err := db.queryModelReleases(
+		`SELECT m.id, m.name, m.vendor, m.release_date, m.license, m.context_window,
+		m.article_id, a.url, m.created_at
+		FROM model_releases m LEFT JOIN articles a ON a.id = m.article_id
+		WHERE m.release_date >= ? AND m.release_date <= ?`+clause, append([]any{from, to}, pageArgs...)...,
+	)
+	return releases, total, err
+}
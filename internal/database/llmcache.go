@@ -0,0 +1,46 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetCachedResponse returns the LLM response cached for model and promptHash
+// if it is younger than maxAge, or nil.
+func (db *DB) GetCachedResponse(model, promptHash string, maxAge time.Duration) (*string, error) {
+	var response string
+	err := db.conn.QueryRow(
+		`SELECT response FROM llm_cache
+		WHERE model = ? AND prompt_hash = ? AND created_at >= datetime('now', ?)`,
+		model, promptHash, ageModifier(maxAge),
+	).Scan(&response)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// PutCachedResponse caches an LLM response, replacing any earlier one.
+func (db *DB) PutCachedResponse(model, promptHash, response string) error {
+	_, err := db.conn.Exec(
+		`INSERT OR REPLACE INTO llm_cache (model, prompt_hash, response) VALUES (?, ?, ?)`,
+		model, promptHash, response,
+	)
+	return err
+}
+
+// PruneCachedResponses deletes cached LLM responses older than maxAge.
+func (db *DB) PruneCachedResponses(maxAge time.Duration) error {
+	_, err := db.conn.Exec(`DELETE FROM llm_cache WHERE created_at < datetime('now', ?)`, ageModifier(maxAge))
+	return err
+}
+
+// ageModifier turns maxAge into an SQLite datetime modifier such as
+// "-259200 seconds".
+func ageModifier(maxAge time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(maxAge.Seconds()))
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// InsertLLMCalls stores the audited calls of a pipeline step.
+func (db *DB) InsertLLMCalls(calls []LLMCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range calls {
+		if _, err := tx.Exec(
+			`INSERT INTO llm_calls (run_id, period_id, step, article_id, storyline_id, provider, model,
+				prompt, response, error, latency_ms, prompt_tokens, completion_tokens, cached)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.RunID, c.PeriodID, c.Step, c.ArticleID, c.StorylineID, c.Provider, c.Model,
+			c.Prompt, c.Response, c.Error, c.LatencyMS, c.PromptTokens, c.CompletionTokens, c.Cached,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const llmCallColumns = `id, run_id, period_id, step, article_id, storyline_id, provider, model,
+	prompt, response, error, latency_ms, prompt_tokens, completion_tokens, cached, COALESCE(created_at, '')`
+
+// GetLLMCalls returns the audited calls matching f, newest first.
+func (db *DB) GetLLMCalls(f LLMCallFilter) ([]LLMCall, error) {
+	var where []string
+	var args []any
+	if f.RunID != "" {
+		where, args = append(where, "run_id = ?"), append(args, f.RunID)
+	}
+	if f.Step != "" {
+		where, args = append(where, "lower(step) = lower(?)"), append(args, f.Step)
+	}
+	if f.ArticleID != 0 {
+		where, args = append(where, "article_id = ?"), append(args, f.ArticleID)
+	}
+	if f.StorylineID != 0 {
+		where, args = append(where, "storyline_id = ?"), append(args, f.StorylineID)
+	}
+	if f.ErrorsOnly {
+		where = append(where, "error IS NOT NULL")
+	}
+	query := `SELECT ` + llmCallColumns + ` FROM llm_calls`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	return db.queryLLMCalls(query, args...)
+}
+
+// GetLLMCall returns an audited call by ID, or nil.
+func (db *DB) GetLLMCall(id int64) (*LLMCall, error) {
+	calls, err := db.queryLLMCalls(`SELECT `+llmCallColumns+` FROM llm_calls WHERE id = ?`, id)
+	if err != nil || len(calls) == 0 {
+		return nil, err
+	}
+	return &calls[0], nil
+}
+
+// PruneLLMCalls deletes audited calls older than maxAge.
+func (db *DB) PruneLLMCalls(maxAge time.Duration) error {
+	_, err := db.conn.Exec(`DELETE FROM llm_calls WHERE created_at < datetime('now', ?)`, ageModifier(maxAge))
+	return err
+}
+
+func (db *DB) queryLLMCalls(query string, args ...any) ([]LLMCall, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []LLMCall
+	for rows.Next() {
+		var c LLMCall
+		if err := rows.Scan(&c.ID, &c.RunID, &c.PeriodID, &c.Step, &c.ArticleID, &c.StorylineID, &c.Provider, &c.Model,
+			&c.Prompt, &c.Response, &c.Error, &c.LatencyMS, &c.PromptTokens, &c.CompletionTokens, &c.Cached, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		calls = append(calls, c)
+	}
+	return calls, rows.Err()
+}
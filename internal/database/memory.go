@@ -0,0 +1,63 @@
+package database
+
+import "encoding/json"
+
+// GetTopicMemories returns the latest memory of every topic written for a
+// period before periodID, leaving out topics last updated before since,
+// most recently updated first. Memories written for periodID itself are
+// what the period added, so they are never returned for it.
+func (db *DB) GetTopicMemories(periodID, since string) ([]TopicMemory, error) {
+	rows, err := db.conn.Query(
+		`SELECT m.topic, m.period_id, m.summary, m.keywords, m.periods, m.updated_at
+		FROM topic_memories m
+		WHERE m.period_id >= ? AND m.period_id = (
+			SELECT MAX(period_id) FROM topic_memories WHERE topic = m.topic AND period_id < ?
+		)
+		ORDER BY m.period_id DESC, m.topic`, since, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memories []TopicMemory
+	for rows.Next() {
+		var m TopicMemory
+		var keywords string
+		if err := rows.Scan(&m.Topic, &m.PeriodID, &m.Summary, &keywords, &m.Periods, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(keywords), &m.Keywords); err != nil {
+			m.Keywords = nil
+		}
+		memories = append(memories, m)
+	}
+	return memories, rows.Err()
+}
+
+// ReplaceTopicMemories stores the memories written for periodID, replacing
+// all that an earlier run wrote for it.
+func (db *DB) ReplaceTopicMemories(periodID string, memories []TopicMemory) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM topic_memories WHERE period_id = ?", periodID); err != nil {
+		return err
+	}
+	for _, m := range memories {
+		keywords, err := json.Marshal(m.Keywords)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO topic_memories (topic, period_id, summary, keywords, periods) VALUES (?, ?, ?, ?, ?)`,
+			m.Topic, periodID, m.Summary, string(keywords), max(m.Periods, 1),
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
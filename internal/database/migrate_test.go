@@ -111,3 +111,33 @@ func TestIsLegacyDBFalseOnNew(t *testing.T) {
 		t.Error("expected isLegacyDB=false on empty database")
 	}
 }
+
+func TestMigrateDeduplicatesStorylineLabels(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "dupes.db")
+
+	// Build a version-1 database containing duplicate labels.
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	tx, _ := raw.Begin()
+	if err := migrations[0].Up(tx); err != nil {
+		t.Fatalf("migration 1: %v", err)
+	}
+	tx.Commit()
+	raw.Exec("PRAGMA user_version = 1")
+	raw.Exec(`INSERT INTO storylines (period_id, label) VALUES
+		('2026-02-06', 'AI Agents'), ('2026-02-06', 'AI Agents'), ('2026-02-07', 'AI Agents')`)
+	raw.Close()
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	storylines, _ := db.GetStorylinesForPeriod("2026-02-06")
+	if len(storylines) != 2 || storylines[0].Label == storylines[1].Label {
+		t.Errorf("expected distinct labels after migration, got %+v", storylines)
+	}
+}
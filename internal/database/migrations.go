@@ -114,6 +114,628 @@ CREATE INDEX IF NOT EXISTS idx_storyline_feedback_period ON storyline_feedback(p
 			return err
 		},
 	},
+	{
+		Version:     2,
+		Description: "unique storyline labels per period",
+		Up: func(tx *sql.Tx) error {
+			if ok, err := tableExists(tx, "storylines"); err != nil || !ok {
+				return err
+			}
+
+			// Disambiguate any duplicates left by older versions before
+			// the unique index can be created.
+			rows, err := tx.Query("SELECT id, period_id, label FROM storylines ORDER BY id")
+			if err != nil {
+				return err
+			}
+			type renamed struct {
+				id    int64
+				label string
+			}
+			seen := make(map[string]map[string]bool)
+			var renames []renamed
+			for rows.Next() {
+				var id int64
+				var periodID, label string
+				if err := rows.Scan(&id, &periodID, &label); err != nil {
+					rows.Close()
+					return err
+				}
+				used := seen[periodID]
+				if used == nil {
+					used = make(map[string]bool)
+					seen[periodID] = used
+				}
+				unique := UniqueLabel(label, used)
+				used[FoldLabel(unique)] = true
+				if unique != label {
+					renames = append(renames, renamed{id: id, label: unique})
+				}
+			}
+			if err := rows.Err(); err != nil {
+				rows.Close()
+				return err
+			}
+			rows.Close()
+
+			for _, r := range renames {
+				if _, err := tx.Exec("UPDATE storylines SET label = ? WHERE id = ?", r.label, r.id); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_storylines_period_label
+ON storylines(period_id, label COLLATE NOCASE)`)
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "briefing editions",
+		Up: func(tx *sql.Tx) error {
+			if ok, err := tableExists(tx, "briefings"); err != nil || !ok {
+				return err
+			}
+			// SQLite cannot drop the UNIQUE(period_id) constraint in place,
+			// so the table is rebuilt keyed by (period_id, edition).
+			_, err := tx.Exec(`
+CREATE TABLE briefings_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    period_id TEXT NOT NULL,
+    edition TEXT NOT NULL DEFAULT 'morning',
+    tldr TEXT NOT NULL,
+    body_markdown TEXT NOT NULL,
+    storyline_count INTEGER DEFAULT 0,
+    article_count INTEGER DEFAULT 0,
+    generated_at TEXT DEFAULT (datetime('now')),
+    UNIQUE (period_id, edition)
+);
+
+INSERT INTO briefings_new
+    (id, period_id, edition, tldr, body_markdown, storyline_count, article_count, generated_at)
+SELECT id, period_id, 'morning', tldr, body_markdown, storyline_count, article_count, generated_at
+FROM briefings;
+
+DROP TABLE briefings;
+ALTER TABLE briefings_new RENAME TO briefings;
+CREATE INDEX IF NOT EXISTS idx_briefings_period ON briefings(period_id);
+`)
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "reader profiles and briefing highlights",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS reader_profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    heading TEXT NOT NULL,
+    created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE TABLE IF NOT EXISTS briefing_highlights (
+    period_id TEXT NOT NULL,
+    profile_id INTEGER NOT NULL REFERENCES reader_profiles(id) ON DELETE CASCADE,
+    heading TEXT NOT NULL,
+    body_markdown TEXT NOT NULL,
+    generated_at TEXT DEFAULT (datetime('now')),
+    PRIMARY KEY (period_id, profile_id)
+);
+`)
+			if err != nil {
+				return err
+			}
+			if ok, err := tableExists(tx, "research_priorities"); err != nil || !ok {
+				return err
+			}
+			_, err = tx.Exec(`ALTER TABLE research_priorities
+ADD COLUMN profile_id INTEGER REFERENCES reader_profiles(id) ON DELETE SET NULL`)
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "upcoming events extracted from articles",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER REFERENCES articles(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'other',
+    event_date TEXT NOT NULL,
+    created_at TEXT DEFAULT (datetime('now')),
+    UNIQUE(title COLLATE NOCASE, event_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_date ON events(event_date);
+`)
+			return err
+		},
+	},
+	{
+		Version:     6,
+		Description: "narrative hype score",
+		Up: func(tx *sql.Tx) error {
+			if ok, err := tableExists(tx, "storyline_narratives"); err != nil || !ok {
+				return err
+			}
+			_, err := tx.Exec("ALTER TABLE storyline_narratives ADD COLUMN hype_score REAL")
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "benchmark results extracted from articles",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS benchmark_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER REFERENCES articles(id) ON DELETE SET NULL,
+    model TEXT NOT NULL,
+    benchmark TEXT NOT NULL,
+    score REAL NOT NULL,
+    reported_date TEXT NOT NULL,
+    sota INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT DEFAULT (datetime('now')),
+    UNIQUE(model COLLATE NOCASE, benchmark COLLATE NOCASE, score)
+);
+
+CREATE INDEX IF NOT EXISTS idx_benchmark_results_benchmark ON benchmark_results(benchmark COLLATE NOCASE);
+`)
+			return err
+		},
+	},
+	{
+		Version:     8,
+		Description: "model release registry",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS model_releases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    vendor TEXT,
+    release_date TEXT NOT NULL,
+    license TEXT,
+    context_window INTEGER,
+    article_id INTEGER REFERENCES articles(id) ON DELETE SET NULL,
+    created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_releases_date ON model_releases(release_date);
+
+CREATE TABLE IF NOT EXISTS model_release_scans (
+    article_id INTEGER PRIMARY KEY REFERENCES articles(id) ON DELETE CASCADE,
+    scanned_at TEXT DEFAULT (datetime('now'))
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     9,
+		Description: "policy watch updates",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS policy_updates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    regulation TEXT NOT NULL COLLATE NOCASE,
+    status TEXT NOT NULL,
+    summary TEXT,
+    reported_date TEXT NOT NULL,
+    created_at TEXT DEFAULT (datetime('now')),
+    UNIQUE(article_id, regulation)
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_updates_regulation ON policy_updates(regulation, reported_date);
+`)
+			return err
+		},
+	},
+	{
+		Version:     10,
+		Description: "job queue",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'queued',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 1,
+    run_after TEXT NOT NULL DEFAULT (datetime('now')),
+    result TEXT,
+    last_error TEXT,
+    created_at TEXT DEFAULT (datetime('now')),
+    started_at TEXT,
+    finished_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, run_after);
+`)
+			return err
+		},
+	},
+	{
+		Version:     11,
+		Description: "briefing quality notes",
+		Up: func(tx *sql.Tx) error {
+			if ok, err := tableExists(tx, "briefings"); err != nil || !ok {
+				return err
+			}
+			_, err := tx.Exec(`ALTER TABLE briefings ADD COLUMN quality_note TEXT`)
+			return err
+		},
+	},
+	{
+		Version:     12,
+		Description: "LLM token usage",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT NOT NULL,
+    period_id TEXT,
+    step TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_run ON llm_usage(run_id);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_created ON llm_usage(created_at);
+`)
+			return err
+		},
+	},
+	{
+		Version:     13,
+		Description: "article URL aliases",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS article_aliases (
+    url TEXT PRIMARY KEY,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_aliases_article ON article_aliases(article_id);
+`)
+			return err
+		},
+	},
+	{
+		Version:     14,
+		Description: "LLM response cache",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS llm_cache (
+    model TEXT NOT NULL,
+    prompt_hash TEXT NOT NULL,
+    response TEXT NOT NULL,
+    created_at TEXT DEFAULT (datetime('now')),
+    PRIMARY KEY (model, prompt_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_cache_created ON llm_cache(created_at);
+`)
+			return err
+		},
+	},
+	{
+		Version:     15,
+		Description: "source types",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS sources (
+    name TEXT PRIMARY KEY,
+    source_type TEXT NOT NULL,
+    updated_at TEXT DEFAULT (datetime('now'))
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     16,
+		Description: "imported feedback",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS imported_feedback (
+    url TEXT PRIMARY KEY,
+    title TEXT NOT NULL,
+    source TEXT,
+    article_type TEXT,
+    rating TEXT NOT NULL CHECK(rating IN ('positive', 'negative')),
+    created_at TEXT DEFAULT (datetime('now'))
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     17,
+		Description: "LLM call audit log",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS llm_calls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT NOT NULL,
+    period_id TEXT,
+    step TEXT NOT NULL,
+    article_id INTEGER,
+    storyline_id INTEGER,
+    provider TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL DEFAULT '',
+    prompt TEXT NOT NULL,
+    response TEXT NOT NULL DEFAULT '',
+    error TEXT,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cached INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_calls_run ON llm_calls(run_id);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_article ON llm_calls(article_id);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_storyline ON llm_calls(storyline_id);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_created ON llm_calls(created_at);
+`)
+			return err
+		},
+	},
+	{
+		Version:     18,
+		Description: "feed item GUIDs",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS feed_items (
+    feed_url TEXT NOT NULL,
+    guid TEXT NOT NULL,
+    article_id INTEGER REFERENCES articles(id) ON DELETE SET NULL,
+    created_at TEXT DEFAULT (datetime('now')),
+    PRIMARY KEY (feed_url, guid)
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     19,
+		Description: "triage failures",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS triage_failures (
+    article_id INTEGER PRIMARY KEY REFERENCES articles(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL,
+    first_failed_at TEXT DEFAULT (datetime('now')),
+    last_failed_at TEXT DEFAULT (datetime('now'))
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     20,
+		Description: "audit log of manual edits",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_id INTEGER,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log(user);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+`)
+			return err
+		},
+	},
+	{
+		Version:     21,
+		Description: "article embedding cache",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS article_embeddings (
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    model TEXT NOT NULL,
+    text_hash TEXT NOT NULL,
+    vector TEXT NOT NULL,
+    created_at TEXT DEFAULT (datetime('now')),
+    PRIMARY KEY (article_id, model)
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     22,
+		Description: "stale storyline narratives",
+		Up: func(tx *sql.Tx) error {
+			if ok, err := tableExists(tx, "storyline_narratives"); err != nil || !ok {
+				return err
+			}
+			_, err := tx.Exec("ALTER TABLE storyline_narratives ADD COLUMN stale INTEGER NOT NULL DEFAULT 0")
+			return err
+		},
+	},
+	{
+		Version:     23,
+		Description: "topic memory",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS topic_memories (
+    topic TEXT NOT NULL,
+    period_id TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    keywords TEXT NOT NULL DEFAULT '[]',
+    periods INTEGER NOT NULL DEFAULT 1,
+    updated_at TEXT DEFAULT (datetime('now')),
+    PRIMARY KEY (topic, period_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_topic_memories_period ON topic_memories(period_id);
+`)
+			return err
+		},
+	},
+	{
+		Version:     24,
+		Description: "feed health",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS feed_health (
+    feed_url TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_success_at TEXT,
+    last_checked_at TEXT DEFAULT (datetime('now'))
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     25,
+		Description: "mute rules",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS mute_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL CHECK(kind IN ('term', 'source')),
+    value TEXT NOT NULL COLLATE NOCASE,
+    created_at TEXT DEFAULT (datetime('now')),
+    UNIQUE (kind, value)
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     26,
+		Description: "feed category and weight of articles",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+ALTER TABLE articles ADD COLUMN category TEXT;
+ALTER TABLE articles ADD COLUMN source_weight REAL NOT NULL DEFAULT 1;
+`)
+			return err
+		},
+	},
+	{
+		Version:     27,
+		Description: "configured source weights",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`ALTER TABLE sources ADD COLUMN weight REAL NOT NULL DEFAULT 1`)
+			return err
+		},
+	},
+	{
+		Version:     28,
+		Description: "fetch retry queue",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+ALTER TABLE articles ADD COLUMN fetch_retries INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE articles ADD COLUMN fetch_retry_at TEXT;
+ALTER TABLE articles ADD COLUMN fetch_error TEXT;
+`)
+			return err
+		},
+	},
+	{
+		Version:     29,
+		Description: "paywalled articles",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`ALTER TABLE articles ADD COLUMN paywalled INTEGER NOT NULL DEFAULT 0`)
+			return err
+		},
+	},
+	{
+		Version:     30,
+		Description: "article language and translations",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+ALTER TABLE articles ADD COLUMN language TEXT;
+ALTER TABLE articles ADD COLUMN original_title TEXT;
+ALTER TABLE articles ADD COLUMN original_content TEXT;
+`)
+			return err
+		},
+	},
+	{
+		Version:     31,
+		Description: "near-duplicate articles",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+ALTER TABLE articles ADD COLUMN simhash INTEGER;
+ALTER TABLE articles ADD COLUMN duplicate_of INTEGER REFERENCES articles(id) ON DELETE SET NULL;
+`)
+			return err
+		},
+	},
+	{
+		Version:     32,
+		Description: "article lead images",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`ALTER TABLE articles ADD COLUMN image_url TEXT`)
+			return err
+		},
+	},
+	{
+		Version:     33,
+		Description: "fetch stats per domain",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS fetch_stats (
+    domain TEXT NOT NULL,
+    day TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    failures INTEGER NOT NULL DEFAULT 0,
+    paywalled INTEGER NOT NULL DEFAULT 0,
+    rendered INTEGER NOT NULL DEFAULT 0,
+    archived INTEGER NOT NULL DEFAULT 0,
+    total_ms INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    PRIMARY KEY (domain, day)
+);
+`)
+			return err
+		},
+	},
+	{
+		Version:     34,
+		Description: "re-fetching updated feed entries",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+ALTER TABLE feed_items ADD COLUMN updated_at TEXT;
+ALTER TABLE articles ADD COLUMN refetch_requested INTEGER NOT NULL DEFAULT 0;
+`)
+			return err
+		},
+	},
+}
+
+// tableExists reports whether a table is present. Legacy databases stamped
+// as version 1 are not guaranteed to contain every table.
+func tableExists(tx *sql.Tx, name string) (bool, error) {
+	var count int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", name,
+	).Scan(&count)
+	return count > 0, err
 }
 
 // latestVersion returns the highest migration version number.
@@ -1,5 +1,7 @@
 package database
 
+import "time"
+
 // Article represents a collected article.
 type Article struct {
 	ID             int64
@@ -11,6 +13,11 @@ type Article struct {
 	ContentFetched bool
 	PeriodID       *string
 	CollectedAt    *string
+	Category       *string // of the feed it was collected from, if it sets one
+	SourceWeight   float64 // of the feed it was collected from; 1 otherwise
+	Paywalled      bool    // its page showed only a teaser to subscribe for
+	Language       *string // ISO 639-1 code it was written in; "" when unknown, nil until detected
+	ImageURL       *string // the lead image its page declares, if any
 }
 
 // ArticleTriage holds triage results for an article.
@@ -41,7 +48,42 @@ type StorylineNarrative struct {
 	Title            string
 	NarrativeText    string
 	SourceReferences []SourceReference
+	HypeScore        *float64 // 0 = substantive, 1 = promotional; nil for Briefly Noted
 	GeneratedAt      *string
+	Stale            bool // the storyline's articles changed after it was written
+}
+
+// TopicMemory is the rolling summary of a recurring topic as of a period,
+// so later narratives can note continuity with what came before.
+type TopicMemory struct {
+	Topic     string
+	PeriodID  string // period the summary was written for
+	Summary   string
+	Keywords  []string // names and terms that identify the topic's storylines
+	Periods   int      // periods the topic came up in, up to PeriodID
+	UpdatedAt *string
+}
+
+// Hype levels bucket a narrative's hype score for display.
+const (
+	HypeSubstantive = "substantive"
+	HypeMixed       = "mixed"
+	HypePromotional = "promotional"
+)
+
+// HypeLevel returns the display bucket of the hype score, or "" when the
+// narrative has none.
+func (n StorylineNarrative) HypeLevel() string {
+	switch {
+	case n.HypeScore == nil:
+		return ""
+	case *n.HypeScore < 0.34:
+		return HypeSubstantive
+	case *n.HypeScore < 0.67:
+		return HypeMixed
+	default:
+		return HypePromotional
+	}
 }
 
 // SourceReference is a reference to an article in a narrative.
@@ -49,17 +91,34 @@ type SourceReference struct {
 	Title        string `json:"title"`
 	URL          string `json:"url"`
 	Contribution string `json:"contribution,omitempty"`
+	Role         string `json:"role,omitempty"`       // RolePrimary or RoleFollowUp
+	Additional   bool   `json:"additional,omitempty"` // not given to the LLM; listed as additional coverage
 }
 
+// Roles of an article in its storyline's narrative.
+const (
+	RolePrimary  = "primary"   // where the news came from
+	RoleFollowUp = "follow_up" // coverage, analysis or tests of it
+)
+
+// Briefing editions. The morning edition is the full daily briefing; the
+// evening edition covers only articles collected after the morning run.
+const (
+	EditionMorning = "morning"
+	EditionEvening = "evening"
+)
+
 // Briefing represents a complete briefing for a period.
 type Briefing struct {
 	ID             int64
 	PeriodID       string
+	Edition        string
 	TLDR           string
 	BodyMarkdown   string
 	StorylineCount int
 	ArticleCount   int
 	GeneratedAt    *string
+	QualityNote    *string // what went wrong while generating it; nil for a clean run
 }
 
 // ResearchPriority is a user-defined research priority.
@@ -69,10 +128,48 @@ type ResearchPriority struct {
 	Description *string
 	Keywords    []string
 	IsActive    bool
+	ProfileID   *int64 // nil for priorities shared by all readers
 	CreatedAt   *string
 	UpdatedAt   *string
 }
 
+// ReaderProfile is a named group of readers with its own priorities,
+// e.g. "qa" with heading "For QA".
+type ReaderProfile struct {
+	ID        int64
+	Name      string
+	Heading   string
+	CreatedAt *string
+}
+
+// Event kinds extracted during triage.
+const (
+	EventRelease    = "release"
+	EventConference = "conference"
+	EventDeadline   = "deadline"
+	EventOther      = "other"
+)
+
+// Event is a future-dated event mentioned in an article.
+type Event struct {
+	ID         int64
+	ArticleID  *int64
+	Title      string
+	Kind       string
+	EventDate  string // YYYY-MM-DD
+	ArticleURL *string
+	CreatedAt  *string
+}
+
+// BriefingHighlight is a per-profile highlight section of a team digest.
+type BriefingHighlight struct {
+	PeriodID     string
+	ProfileID    int64
+	Heading      string
+	BodyMarkdown string
+	GeneratedAt  *string
+}
+
 // RunReport holds metadata about a pipeline run.
 type RunReport struct {
 	ID             int64
@@ -84,14 +181,87 @@ type RunReport struct {
 
 // Stats contains aggregate database statistics.
 type Stats struct {
-	TotalArticles      int
-	TriagedArticles    int
-	RelevantArticles   int
+	TotalArticles       int
+	TriagedArticles     int
+	RelevantArticles    int
 	PeriodsWithArticles int
-	Briefings          int
-	Storylines         int
-	TotalPriorities    int
-	ActivePriorities   int
+	Briefings           int
+	Storylines          int
+	TotalPriorities     int
+	ActivePriorities    int
+	FailedTriage        int
+}
+
+// TriageFailure is an article whose triage calls have been failing.
+type TriageFailure struct {
+	ArticleID    int64
+	Title        string
+	Attempts     int
+	LastError    string
+	LastFailedAt *string
+}
+
+// Kinds of mute rules.
+const (
+	MuteTerm   = "term"   // articles whose title mentions the term
+	MuteSource = "source" // articles from the source
+)
+
+// MuteRule makes triage skip matching articles without asking the LLM.
+type MuteRule struct {
+	ID        int64
+	Kind      string // MuteTerm or MuteSource
+	Value     string
+	CreatedAt *string
+}
+
+// Rating is a reader's rating of an article or a storyline.
+type Rating struct {
+	Title     string // the article's title or the storyline's label
+	Source    string // the article's source; empty for storylines
+	Storyline bool
+	Positive  bool
+	RatedAt   string
+}
+
+// FeedHealth is how fetching a feed went lately.
+type FeedHealth struct {
+	FeedURL       string
+	Failures      int // consecutive failed fetches, 0 after a success
+	LastError     *string
+	LastSuccessAt *string
+	LastCheckedAt *string
+}
+
+// FetchAttempt is how fetching one article page went, for the fetch stats
+// of its domain.
+type FetchAttempt struct {
+	Domain    string
+	Error     string // why no text was read; "" when it was
+	Paywalled bool
+	Rendered  bool // the text was read from the page rendered in the browser
+	Archived  bool // the text was read from a Wayback Machine snapshot
+	Duration  time.Duration
+}
+
+// DomainFetchStats sums up the fetch attempts of a domain over some days.
+type DomainFetchStats struct {
+	Domain     string
+	Attempts   int
+	Failures   int
+	Paywalled  int
+	Rendered   int
+	Archived   int
+	AvgLatency time.Duration
+	LastError  *string // of the latest day with a failure
+}
+
+// FailureRate returns the share of the attempts that failed.
+func (s DomainFetchStats) FailureRate() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Attempts)
 }
 
 // TriageStats contains triage statistics for a period.
@@ -116,6 +286,14 @@ type ArticleFeedback struct {
 	CreatedAt *string
 }
 
+// TrainingExample is a triaged article with the reader's rating of it, if
+// any, from which the training export derives a label.
+type TrainingExample struct {
+	Article Article
+	Triage  ArticleTriage
+	Rating  *string // "positive" or "negative"
+}
+
 // SourceFeedback aggregates feedback counts for a source.
 type SourceFeedback struct {
 	Source   string
@@ -123,6 +301,17 @@ type SourceFeedback struct {
 	Negative int
 }
 
+// SourceWeight is how much a source's articles count when ranking
+// storylines and how triage regards it: its configured weight, adjusted by
+// the reader's ratings of its articles.
+type SourceWeight struct {
+	Source     string
+	Configured float64 // of the feed in the config; 1 for other sources
+	Positive   int
+	Negative   int
+	Weight     float64 // Configured, adjusted by the ratings
+}
+
 // TypeFeedback aggregates feedback counts for an article type.
 type TypeFeedback struct {
 	ArticleType string
@@ -135,3 +324,205 @@ type FeedbackSummary struct {
 	Sources []SourceFeedback
 	Types   []TypeFeedback
 }
+
+// BenchmarkResult is a model's score on a benchmark as reported in an article.
+type BenchmarkResult struct {
+	ID           int64
+	ArticleID    *int64
+	Model        string
+	Benchmark    string
+	Score        float64
+	ReportedDate string
+	SOTA         bool // claimed as state of the art, or beat every earlier score
+	ArticleURL   *string
+	CreatedAt    *string
+}
+
+// ModelRelease is a model launch detected in an article.
+type ModelRelease struct {
+	ID            int64
+	Name          string
+	Vendor        *string
+	ReleaseDate   string
+	License       *string
+	ContextWindow *int64 // tokens
+	ArticleID     *int64
+	ArticleURL    *string
+	CreatedAt     *string
+}
+
+// Policy statuses describe where a regulation stands.
+const (
+	PolicyProposed  = "proposed"
+	PolicyDebated   = "under_debate"
+	PolicyAdopted   = "adopted"
+	PolicyInForce   = "in_force"
+	PolicyAmended   = "amended"
+	PolicyDelayed   = "delayed"
+	PolicyWithdrawn = "withdrawn"
+	PolicyOther     = "other"
+)
+
+// Source types classify where an article comes from.
+const (
+	SourceBlog     = "blog"     // independent writers and practitioners
+	SourceVendor   = "vendor"   // AI companies announcing their own work
+	SourceNews     = "news"     // newspapers, magazines and tech press
+	SourceAcademic = "academic" // papers, preprints and university labs
+)
+
+// SourceTypes lists the valid source types.
+var SourceTypes = []string{SourceBlog, SourceVendor, SourceNews, SourceAcademic}
+
+// PolicyUpdate is a reported change in the status of a regulation.
+type PolicyUpdate struct {
+	ID           int64
+	ArticleID    int64
+	Regulation   string
+	Status       string
+	Summary      *string
+	ReportedDate string
+	ArticleURL   string
+	PeriodID     *string // period of the reporting article
+	CreatedAt    *string
+}
+
+// PolicyStatus is where a tracked regulation stood at the end of a period.
+type PolicyStatus struct {
+	Regulation string
+	Current    *PolicyUpdate // nil when nothing has been reported yet
+	Previous   string        // last different status before Current, if any
+	New        bool          // Current was reported in this period
+}
+
+// Job statuses. Failed attempts go back to queued until max_attempts is
+// reached.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is a unit of long-running work in the job queue.
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     string // JSON
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAfter    string
+	Result      *string
+	LastError   *string
+	CreatedAt   *string
+	StartedAt   *string
+	FinishedAt  *string
+}
+
+// LLMUsage is the token usage of one LLM call made during a pipeline run.
+type LLMUsage struct {
+	ID               int64
+	RunID            string
+	PeriodID         *string
+	Step             string
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+	CreatedAt        *string
+}
+
+// LLMCall is an audited LLM exchange: the prompt as sent and the raw
+// response, or the error. Cached responses have no provider or model.
+type LLMCall struct {
+	ID               int64
+	RunID            string
+	PeriodID         *string
+	Step             string
+	ArticleID        *int64
+	StorylineID      *int64
+	Provider         string
+	Model            string
+	Prompt           string
+	Response         string
+	Error            *string
+	LatencyMS        int64
+	PromptTokens     int
+	CompletionTokens int
+	Cached           bool
+	CreatedAt        string
+}
+
+// ListOptions pages and sorts a list query: Limit rows (0 for all) after
+// skipping Offset, ordered by Sort, one of the keys the query offers, or by
+// its default order when Sort is empty.
+type ListOptions struct {
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+}
+
+// ArticleFilter selects articles for ListArticles; zero fields match
+// everything.
+type ArticleFilter struct {
+	PeriodID string
+	Source   string
+	Verdict  string // "relevant", "skip" or VerdictUntriaged
+}
+
+// ArticleListing is a listed article with its triage, if any. Content is
+// left out.
+type ArticleListing struct {
+	Article
+	Verdict        *string
+	ArticleType    *string
+	PracticalScore *int
+}
+
+// LLMCallFilter selects audited calls; zero fields match everything.
+type LLMCallFilter struct {
+	RunID       string
+	Step        string
+	ArticleID   int64
+	StorylineID int64
+	ErrorsOnly  bool
+	Limit       int
+}
+
+// ArticleEmbedding is the cached embedding of an article by one model.
+// TextHash identifies the text that was embedded, so an embedding of an
+// article whose text has since changed can be told apart.
+type ArticleEmbedding struct {
+	ArticleID int64
+	Model     string
+	TextHash  string
+	Vector    []float64
+}
+
+// AuditEntry is a recorded manual edit: who did what to which target.
+type AuditEntry struct {
+	ID        int64
+	User      string
+	Action    string
+	TargetID  *int64 // storyline, article, priority, profile or job, by action
+	Detail    string
+	CreatedAt string
+}
+
+// AuditFilter selects audit entries; zero fields match everything.
+type AuditFilter struct {
+	User   string
+	Action string
+}
+
+// UsageTotal aggregates LLM usage, e.g. for one step of a run.
+type UsageTotal struct {
+	Label            string // step name, run ID or period, depending on the query
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// InsertMuteRule adds a mute rule and returns its ID. Muting what is muted
+// already, in any letter case, returns the existing rule's ID.
+func (db *DB) InsertMuteRule(kind, value string) (int64, error) {
+	if kind != MuteTerm && kind != MuteSource {
+		return 0, fmt.Errorf("unknown mute rule kind %q", kind)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("mute rule without a value")
+	}
+	if _, err := db.conn.Exec(`INSERT OR IGNORE INTO mute_rules (kind, value) VALUES (?, ?)`, kind, value); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := db.conn.QueryRow(`SELECT id FROM mute_rules WHERE kind = ? AND value = ?`, kind, value).Scan(&id)
+	return id, err
+}
+
+// GetMuteRules returns every mute rule, oldest first.
+func (db *DB) GetMuteRules() ([]MuteRule, error) {
+	rows, err := db.conn.Query(`SELECT id, kind, value, created_at FROM mute_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []MuteRule
+	for rows.Next() {
+		var m MuteRule
+		if err := rows.Scan(&m.ID, &m.Kind, &m.Value, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, m)
+	}
+	return rules, rows.Err()
+}
+
+// GetMuteRule returns a mute rule, or nil if there is none with the ID.
+func (db *DB) GetMuteRule(id int64) (*MuteRule, error) {
+	var m MuteRule
+	err := db.conn.QueryRow(`SELECT id, kind, value, created_at FROM mute_rules WHERE id = ?`, id).
+		Scan(&m.ID, &m.Kind, &m.Value, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// DeleteMuteRule removes a mute rule.
+func (db *DB) DeleteMuteRule(id int64) error {
+	_, err := db.conn.Exec(`DELETE FROM mute_rules WHERE id = ?`, id)
+	return err
+}
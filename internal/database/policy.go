@@ -0,0 +1,94 @@
+package database
+
+import "strings"
+
+// InsertPolicyUpdate records a regulation status reported by an article.
+// An article reports each regulation at most once.
+func (db *DB) InsertPolicyUpdate(articleID int64, regulation, status string, summary *string, reportedDate string) error {
+	_, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO policy_updates (article_id, regulation, status, summary, reported_date)
+		VALUES (?, ?, ?, ?, ?)`,
+		articleID, regulation, status, summary, reportedDate,
+	)
+	return err
+}
+
+// GetPolicyUpdatesForPeriod returns the policy updates reported by a
+// period's articles, oldest first.
+func (db *DB) GetPolicyUpdatesForPeriod(periodID string) ([]PolicyUpdate, error) {
+	return db.queryPolicyUpdates(
+		`WHERE a.period_id = ? ORDER BY p.reported_date, p.id`, periodID)
+}
+
+// GetPolicyHistory returns the updates for a regulation reported on or
+// before until (YYYY-MM-DD), newest first.
+func (db *DB) GetPolicyHistory(regulation, until string) ([]PolicyUpdate, error) {
+	return db.queryPolicyUpdates(
+		`WHERE p.regulation = ? AND p.reported_date <= ?
+		ORDER BY p.reported_date DESC, p.id DESC`, regulation, until)
+}
+
+// PolicyWatch reports the status of each regulation at the end of a period.
+// Regulations are the tracked ones followed by any others the period's
+// articles reported on, without duplicates.
+func (db *DB) PolicyWatch(tracked []string, periodID string) ([]PolicyStatus, error) {
+	names := append([]string(nil), tracked...)
+	updates, err := db.GetPolicyUpdatesForPeriod(periodID)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		names = append(names, u.Regulation)
+	}
+
+	until := PeriodEndDate(periodID)
+	seen := make(map[string]bool)
+	var watch []PolicyStatus
+	for _, name := range names {
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		history, err := db.GetPolicyHistory(name, until)
+		if err != nil {
+			return nil, err
+		}
+		status := PolicyStatus{Regulation: name}
+		if len(history) > 0 {
+			status.Current = &history[0]
+			status.New = history[0].PeriodID != nil && *history[0].PeriodID == periodID
+			for _, h := range history[1:] {
+				if h.Status != history[0].Status {
+					status.Previous = h.Status
+					break
+				}
+			}
+		}
+		watch = append(watch, status)
+	}
+	return watch, nil
+}
+
+func (db *DB) queryPolicyUpdates(where string, args ...any) ([]PolicyUpdate, error) {
+	rows, err := db.conn.Query(
+		`SELECT p.id, p.article_id, p.regulation, p.status, p.summary, p.reported_date,
+		a.url, a.period_id, p.created_at
+		FROM policy_updates p JOIN articles a ON a.id = p.article_id `+where, args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var updates []PolicyUpdate
+	for rows.Next() {
+		var u PolicyUpdate
+		if err := rows.Scan(&u.ID, &u.ArticleID, &u.Regulation, &u.Status, &u.Summary,
+			&u.ReportedDate, &u.ArticleURL, &u.PeriodID, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
@@ -29,20 +29,40 @@ func (db *DB) InsertPriority(title, description string, keywords []string) (int6
 	return result.LastInsertId()
 }
 
+const priorityColumns = "id, title, description, keywords, is_active, profile_id, created_at, updated_at"
+
 // GetAllPriorities returns all research priorities.
 func (db *DB) GetAllPriorities() ([]ResearchPriority, error) {
-	return db.queryPriorities("SELECT * FROM research_priorities ORDER BY created_at DESC")
+	return db.queryPriorities("SELECT " + priorityColumns + " FROM research_priorities ORDER BY created_at DESC")
 }
 
 // GetActivePriorities returns only active research priorities.
 func (db *DB) GetActivePriorities() ([]ResearchPriority, error) {
-	return db.queryPriorities("SELECT * FROM research_priorities WHERE is_active = 1 ORDER BY created_at DESC")
+	return db.queryPriorities("SELECT " + priorityColumns + " FROM research_priorities WHERE is_active = 1 ORDER BY created_at DESC")
+}
+
+// GetActiveProfilePriorities returns active priorities assigned to a reader profile.
+func (db *DB) GetActiveProfilePriorities(profileID int64) ([]ResearchPriority, error) {
+	return db.queryPriorities(
+		"SELECT "+priorityColumns+" FROM research_priorities WHERE is_active = 1 AND profile_id = ? ORDER BY created_at DESC",
+		profileID,
+	)
+}
+
+// SetPriorityProfile assigns a priority to a reader profile, or back to all
+// readers when profileID is nil.
+func (db *DB) SetPriorityProfile(priorityID int64, profileID *int64) error {
+	_, err := db.conn.Exec(
+		`UPDATE research_priorities SET profile_id = ?, updated_at = datetime('now') WHERE id = ?`,
+		profileID, priorityID,
+	)
+	return err
 }
 
 // GetPriority returns a single priority by ID.
 func (db *DB) GetPriority(priorityID int64) (*ResearchPriority, error) {
 	row := db.conn.QueryRow(
-		"SELECT id, title, description, keywords, is_active, created_at, updated_at FROM research_priorities WHERE id = ?",
+		"SELECT "+priorityColumns+" FROM research_priorities WHERE id = ?",
 		priorityID,
 	)
 	p, err := scanPriority(row)
@@ -115,7 +135,7 @@ func (db *DB) queryPriorities(query string, args ...any) ([]ResearchPriority, er
 		var p ResearchPriority
 		var kwJSON, desc *string
 		var active int
-		if err := rows.Scan(&p.ID, &p.Title, &desc, &kwJSON, &active, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.Title, &desc, &kwJSON, &active, &p.ProfileID, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, err
 		}
 		p.Description = desc
@@ -134,7 +154,7 @@ func scanPriority(row *sql.Row) (*ResearchPriority, error) {
 	var p ResearchPriority
 	var kwJSON, desc *string
 	var active int
-	if err := row.Scan(&p.ID, &p.Title, &desc, &kwJSON, &active, &p.CreatedAt, &p.UpdatedAt); err != nil {
+	if err := row.Scan(&p.ID, &p.Title, &desc, &kwJSON, &active, &p.ProfileID, &p.CreatedAt, &p.UpdatedAt); err != nil {
 		return nil, err
 	}
 	p.Description = desc
@@ -0,0 +1,94 @@
+package database
+
+import "database/sql"
+
+// InsertProfile creates a new reader profile.
+func (db *DB) InsertProfile(name, heading string) (int64, error) {
+	result, err := db.conn.Exec(
+		`INSERT INTO reader_profiles (name, heading) VALUES (?, ?)`, name, heading,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetProfiles returns all reader profiles ordered by name.
+func (db *DB) GetProfiles() ([]ReaderProfile, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, name, heading, created_at FROM reader_profiles ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []ReaderProfile
+	for rows.Next() {
+		var p ReaderProfile
+		if err := rows.Scan(&p.ID, &p.Name, &p.Heading, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// GetProfileByName returns a reader profile by name.
+func (db *DB) GetProfileByName(name string) (*ReaderProfile, error) {
+	row := db.conn.QueryRow(
+		`SELECT id, name, heading, created_at FROM reader_profiles WHERE name = ?`, name,
+	)
+	var p ReaderProfile
+	if err := row.Scan(&p.ID, &p.Name, &p.Heading, &p.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DeleteProfile removes a reader profile. Its priorities become shared.
+func (db *DB) DeleteProfile(profileID int64) error {
+	_, err := db.conn.Exec("DELETE FROM reader_profiles WHERE id = ?", profileID)
+	return err
+}
+
+// InsertBriefingHighlight stores a profile's highlight section for a period.
+func (db *DB) InsertBriefingHighlight(periodID string, profileID int64, heading, bodyMarkdown string) error {
+	_, err := db.conn.Exec(
+		`INSERT OR REPLACE INTO briefing_highlights (period_id, profile_id, heading, body_markdown)
+		VALUES (?, ?, ?, ?)`,
+		periodID, profileID, heading, bodyMarkdown,
+	)
+	return err
+}
+
+// DeleteBriefingHighlights removes all highlight sections for a period.
+func (db *DB) DeleteBriefingHighlights(periodID string) error {
+	_, err := db.conn.Exec("DELETE FROM briefing_highlights WHERE period_id = ?", periodID)
+	return err
+}
+
+// GetBriefingHighlights returns the highlight sections for a period, ordered by heading.
+func (db *DB) GetBriefingHighlights(periodID string) ([]BriefingHighlight, error) {
+	rows, err := db.conn.Query(
+		`SELECT period_id, profile_id, heading, body_markdown, generated_at
+		FROM briefing_highlights WHERE period_id = ? ORDER BY heading`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var highlights []BriefingHighlight
+	for rows.Next() {
+		var h BriefingHighlight
+		if err := rows.Scan(&h.PeriodID, &h.ProfileID, &h.Heading, &h.BodyMarkdown, &h.GeneratedAt); err != nil {
+			return nil, err
+		}
+		highlights = append(highlights, h)
+	}
+	return highlights, rows.Err()
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// GetReleaseCandidates returns relevant articles of a period whose triage
+// type is one of articleTypes and that haven't been scanned for model
+// releases yet.
+func (db *DB) GetReleaseCandidates(periodID string, articleTypes []string) ([]Article, error) {
+	if len(articleTypes) == 0 {
+		return nil, nil
+	}
+	args := []any{periodID}
+	for _, t := range articleTypes {
+		args = append(args, t)
+	}
+	placeholders := strings.Repeat("?,", len(articleTypes))
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url
+		FROM articles a JOIN article_triage t ON a.id = t.article_id
+		WHERE a.period_id = ? AND t.verdict = 'relevant'
+		AND t.article_type IN (`+placeholders[:len(placeholders)-1]+`)
+		AND a.id NOT IN (SELECT article_id FROM model_release_scans)
+		ORDER BY a.id`, args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
+
+// MarkReleaseScanned records that an article was scanned for model releases.
+func (db *DB) MarkReleaseScanned(articleID int64) error {
+	_, err := db.conn.Exec("INSERT OR IGNORE INTO model_release_scans (article_id) VALUES (?)", articleID)
+	return err
+}
+
+// UpsertModelRelease records a model release. When the model is already
+// known (by case-insensitive name), missing details are filled in and the
+// earliest release date is kept. Returns true when the model is new.
+func (db *DB) UpsertModelRelease(articleID int64, name string, vendor *string, releaseDate string, license *string, contextWindow *int64) (bool, error) {
+	var existing int64
+	err := db.conn.QueryRow("SELECT id FROM model_releases WHERE name = ?", name).Scan(&existing)
+	if err == sql.ErrNoRows {
+		_, err = db.conn.Exec(
+			`INSERT INTO model_releases (name, vendor, release_date, license, context_window, article_id)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			name, vendor, releaseDate, license, contextWindow, articleID,
+		)
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = db.conn.Exec(
+		`UPDATE model_releases SET
+			vendor = COALESCE(vendor, ?),
+			license = COALESCE(license, ?),
+			context_window = COALESCE(context_window, ?),
+			release_date = MIN(release_date, ?)
+		WHERE id = ?`,
+		vendor, license, contextWindow, releaseDate, existing,
+	)
+	return false, err
+}
+
+// GetModelReleases returns releases dated from..to inclusive (YYYY-MM-DD;
+// empty for open-ended), newest first.
+func (db *DB) GetModelReleases(from, to string) ([]ModelRelease, error) {
+	if to == "" {
+		to = "9999-12-31"
+	}
+	return db.queryModelReleases(
+		`SELECT m.id, m.name, m.vendor, m.release_date, m.license, m.context_window,
+		m.article_id, a.url, m.created_at
+		FROM model_releases m LEFT JOIN articles a ON a.id = m.article_id
+		WHERE m.release_date >= ? AND m.release_date <= ?
+		ORDER BY m.release_date DESC, m.name COLLATE NOCASE`, from, to,
+	)
+}
+
+func (db *DB) queryModelReleases(query string, args ...any) ([]ModelRelease, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releases []ModelRelease
+	for rows.Next() {
+		var m ModelRelease
+		if err := rows.Scan(&m.ID, &m.Name, &m.Vendor, &m.ReleaseDate, &m.License, &m.ContextWindow,
+			&m.ArticleID, &m.ArticleURL, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		releases = append(releases, m)
+	}
+	return releases, rows.Err()
+}
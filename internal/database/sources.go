@@ -0,0 +1,233 @@
+package database
+
+// IndependentLead is how many storylines at the top of a briefing must have
+// at least one source that isn't a vendor, when the period has enough.
+const IndependentLead = 3
+
+// feedbackAdjust is the most the reader's ratings of a source's articles
+// move its weight, as a fraction of the configured weight, either way.
+const feedbackAdjust = 0.5
+
+// feedbackPrior is how many neutral ratings a source's ratings are weighed
+// against, so a handful of them moves its weight only a little.
+const feedbackPrior = 5
+
+// SetSourceType records the type of a source, replacing any earlier one.
+// Configured feeds are typed this way on every collection run.
+func (db *DB) SetSourceType(name, sourceType string) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO sources (name, source_type) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET source_type = excluded.source_type, updated_at = datetime('now')`,
+		name, sourceType,
+	)
+	return err
+}
+
+// SetSourceWeight records the configured weight of a known source.
+// Configured feeds are weighted this way on every collection run, after
+// they are typed; other sources keep a weight of 1.
+func (db *DB) SetSourceWeight(name string, weight float64) error {
+	_, err := db.conn.Exec(`UPDATE sources SET weight = ? WHERE name = ?`, weight, name)
+	return err
+}
+
+// GetSourceWeights returns the weight of every source that has a configured
+// weight other than 1 or rated articles, keyed by name. Sources missing
+// from it have a weight of 1.
+func (db *DB) GetSourceWeights() (map[string]SourceWeight, error) {
+	rows, err := db.conn.Query(`SELECT name, weight FROM sources WHERE weight <> 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weights := make(map[string]SourceWeight)
+	for rows.Next() {
+		w := SourceWeight{}
+		if err := rows.Scan(&w.Source, &w.Configured); err != nil {
+			return nil, err
+		}
+		weights[w.Source] = w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	feedback, err := db.sourceFeedback()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range feedback {
+		w, ok := weights[f.Source]
+		if !ok {
+			w = SourceWeight{Source: f.Source, Configured: 1}
+		}
+		w.Positive, w.Negative = f.Positive, f.Negative
+		weights[f.Source] = w
+	}
+	for name, w := range weights {
+		w.Weight = AdjustedWeight(w.Configured, w.Positive, w.Negative)
+		weights[name] = w
+	}
+	return weights, nil
+}
+
+// AdjustedWeight returns a configured source weight moved by the reader's
+// ratings of the source's articles: up by at most half for sources only
+// ever rated positive, down by at most half for those only rated negative.
+func AdjustedWeight(configured float64, positive, negative int) float64 {
+	n := float64(positive + negative + feedbackPrior)
+	return configured * (1 + feedbackAdjust*float64(positive-negative)/n)
+}
+
+// WeightOf returns the weight of a source in weights, 1 when it has none.
+func WeightOf(weights map[string]SourceWeight, source *string) float64 {
+	if source == nil {
+		return 1
+	}
+	if w, ok := weights[*source]; ok {
+		return w.Weight
+	}
+	return 1
+}
+
+// AddSource records the type of a source unless it already has one, so an
+// inferred type never overrides a configured one.
+func (db *DB) AddSource(name, sourceType string) error {
+	_, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO sources (name, source_type) VALUES (?, ?)`, name, sourceType,
+	)
+	return err
+}
+
+// GetSourceTypes returns the type of every known source, keyed by name.
+func (db *DB) GetSourceTypes() (map[string]string, error) {
+	rows, err := db.conn.Query(`SELECT name, source_type FROM sources`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, sourceType string
+		if err := rows.Scan(&name, &sourceType); err != nil {
+			return nil, err
+		}
+		types[name] = sourceType
+	}
+	return types, rows.Err()
+}
+
+// GetUntypedSources returns sources that articles were stored under but that
+// have no type yet, each with the URL of one of its articles.
+func (db *DB) GetUntypedSources() (map[string]string, error) {
+	rows, err := db.conn.Query(
+		`SELECT a.source, MIN(a.url) FROM articles a
+		LEFT JOIN sources s ON s.name = a.source
+		WHERE a.source IS NOT NULL AND a.source <> '' AND s.name IS NULL
+		GROUP BY a.source`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := make(map[string]string)
+	for rows.Next() {
+		var name, url string
+		if err := rows.Scan(&name, &url); err != nil {
+			return nil, err
+		}
+		sources[name] = url
+	}
+	return sources, rows.Err()
+}
+
+// GetStorylineSourceTypes counts the articles of each storyline in a period
+// by source type. Articles from untyped sources are not counted.
+func (db *DB) GetStorylineSourceTypes(periodID string) (map[int64]map[string]int, error) {
+	rows, err := db.conn.Query(
+		`SELECT sa.storyline_id, src.source_type, COUNT(*)
+		FROM storyline_articles sa
+		JOIN storylines s ON s.id = sa.storyline_id
+		JOIN articles a ON a.id = sa.article_id
+		JOIN sources src ON src.name = a.source
+		WHERE s.period_id = ?
+		GROUP BY sa.storyline_id, src.source_type`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]map[string]int)
+	for rows.Next() {
+		var id int64
+		var sourceType string
+		var n int
+		if err := rows.Scan(&id, &sourceType, &n); err != nil {
+			return nil, err
+		}
+		if counts[id] == nil {
+			counts[id] = make(map[string]int)
+		}
+		counts[id][sourceType] = n
+	}
+	return counts, rows.Err()
+}
+
+// VendorOnly reports whether a storyline's typed sources are all vendors.
+// Storylines without typed sources are not vendor-only.
+func VendorOnly(counts map[string]int) bool {
+	for sourceType, n := range counts {
+		if sourceType != SourceVendor && n > 0 {
+			return false
+		}
+	}
+	return counts[SourceVendor] > 0
+}
+
+// storylineWeights sums the weights of the sources of each storyline's
+// articles in a period, so a storyline counts an article of a source
+// weighted 2 as two.
+func (db *DB) storylineWeights(periodID string, weights map[string]SourceWeight) (map[int64]float64, error) {
+	rows, err := db.conn.Query(
+		`SELECT sa.storyline_id, a.source, COUNT(*)
+		FROM storyline_articles sa
+		JOIN storylines s ON s.id = sa.storyline_id
+		JOIN articles a ON a.id = sa.article_id
+		WHERE s.period_id = ?
+		GROUP BY sa.storyline_id, a.source`, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[int64]float64)
+	for rows.Next() {
+		var id int64
+		var source *string
+		var n int
+		if err := rows.Scan(&id, &source, &n); err != nil {
+			return nil, err
+		}
+		sums[id] += float64(n) * WeightOf(weights, source)
+	}
+	return sums, rows.Err()
+}
+
+// leadWithIndependent moves vendor-only storylines below the first
+// IndependentLead other storylines, keeping the order otherwise.
+func leadWithIndependent(narratives []StorylineNarrative, types map[int64]map[string]int) []StorylineNarrative {
+	var lead, rest []StorylineNarrative
+	for _, n := range narratives {
+		if len(lead) < IndependentLead && !VendorOnly(types[n.StorylineID]) {
+			lead = append(lead, n)
+		} else {
+			rest = append(rest, n)
+		}
+	}
+	return append(lead, rest...)
+}
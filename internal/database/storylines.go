@@ -1,11 +1,44 @@
 package database
 
 import (
+	"cmp"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
 )
 
+// UniqueLabel returns label unchanged if it is not in used, otherwise appends
+// the lowest numeric disambiguator (" (2)", " (3)", ...) that is free.
+// Keys in used are compared case-insensitively and must be folded with
+// FoldLabel.
+func UniqueLabel(label string, used map[string]bool) string {
+	if !used[FoldLabel(label)] {
+		return label
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", label, n)
+		if !used[FoldLabel(candidate)] {
+			return candidate
+		}
+	}
+}
+
+// FoldLabel folds the case of a label the way SQLite's NOCASE collation
+// does, which the unique index on storyline labels uses: only ASCII letters
+// are folded, so "Émile" and "émile" are different labels.
+func FoldLabel(label string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, label)
+}
+
 // InsertStoryline creates a storyline and links it to articles.
+// If the label is already taken within the period, a disambiguator is appended.
 func (db *DB) InsertStoryline(periodID, label string, articleIDs []int64) (int64, error) {
 	tx, err := db.conn.Begin()
 	if err != nil {
@@ -13,6 +46,12 @@ func (db *DB) InsertStoryline(periodID, label string, articleIDs []int64) (int64
 	}
 	defer tx.Rollback()
 
+	used, err := storylineLabels(tx, periodID)
+	if err != nil {
+		return 0, err
+	}
+	label = UniqueLabel(label, used)
+
 	result, err := tx.Exec(
 		`INSERT INTO storylines (period_id, label, article_count) VALUES (?, ?, ?)`,
 		periodID, label, len(articleIDs),
@@ -38,6 +77,24 @@ func (db *DB) InsertStoryline(periodID, label string, articleIDs []int64) (int64
 	return storylineID, tx.Commit()
 }
 
+func storylineLabels(tx *sql.Tx, periodID string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT label FROM storylines WHERE period_id = ?", periodID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	used := make(map[string]bool)
+	for rows.Next() {
+		var label string
+		if err := rows.Scan(&label); err != nil {
+			return nil, err
+		}
+		used[FoldLabel(label)] = true
+	}
+	return used, rows.Err()
+}
+
 // GetStorylinesForPeriod returns storylines ordered by article_count DESC.
 func (db *DB) GetStorylinesForPeriod(periodID string) ([]Storyline, error) {
 	rows, err := db.conn.Query(
@@ -60,6 +117,21 @@ func (db *DB) GetStorylinesForPeriod(periodID string) ([]Storyline, error) {
 	return storylines, rows.Err()
 }
 
+// GetStoryline returns a storyline by ID, or nil if it doesn't exist.
+func (db *DB) GetStoryline(id int64) (*Storyline, error) {
+	var s Storyline
+	err := db.conn.QueryRow(
+		"SELECT id, period_id, label, article_count, created_at FROM storylines WHERE id = ?", id,
+	).Scan(&s.ID, &s.PeriodID, &s.Label, &s.ArticleCount, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
 // GetStorylineArticleIDs returns the article IDs linked to a storyline.
 func (db *DB) GetStorylineArticleIDs(storylineID int64) ([]int64, error) {
 	rows, err := db.conn.Query(
@@ -81,13 +153,56 @@ func (db *DB) GetStorylineArticleIDs(storylineID int64) ([]int64, error) {
 	return ids, rows.Err()
 }
 
-// GetStorylineArticles returns the full articles linked to a storyline.
-func (db *DB) GetStorylineArticles(storylineID int64) ([]Article, error) {
+// Orders of a storyline's articles, for GetStorylineArticles.
+const (
+	ArticleOrderCollected  = "collected"  // as collected; the default
+	ArticleOrderScore      = "score"      // highest practical score first
+	ArticleOrderPublished  = "published"  // newest first
+	ArticleOrderReputation = "reputation" // sources readers rated best first
+)
+
+// ArticleOrders lists the valid article orders.
+var ArticleOrders = []string{ArticleOrderCollected, ArticleOrderScore, ArticleOrderPublished, ArticleOrderReputation}
+
+// articleOrderClauses are the ORDER BY clauses of the article orders. Ties,
+// and articles without a score or date, fall back to collection order.
+var articleOrderClauses = map[string]string{
+	ArticleOrderCollected:  "a.id",
+	ArticleOrderScore:      "COALESCE(t.practical_score, -1) DESC, a.id",
+	ArticleOrderPublished:  "a.published_date IS NULL, a.published_date DESC, a.id",
+	ArticleOrderReputation: "COALESCE(r.net, 0) DESC, a.id",
+}
+
+// sourceReputation is the net article feedback (positive minus negative
+// ratings) per source, imported feedback included, as in
+// GetFeedbackSummary.
+const sourceReputation = `SELECT source, SUM(CASE rating WHEN 'positive' THEN 1 WHEN 'negative' THEN -1 ELSE 0 END) AS net
+	FROM (
+		SELECT COALESCE(a.source, 'Unknown') AS source, af.rating
+		FROM article_feedback af JOIN articles a ON a.id = af.article_id
+		UNION ALL
+		SELECT COALESCE(source, 'Unknown'), rating FROM imported_feedback
+	)
+	GROUP BY source`
+
+// GetStorylineArticles returns the full articles linked to a storyline in
+// the given order, one of ArticleOrders; "" means ArticleOrderCollected. An
+// unknown order is ErrUnknownSort.
+func (db *DB) GetStorylineArticles(storylineID int64, order string) ([]Article, error) {
+	clause, ok := articleOrderClauses[cmp.Or(order, ArticleOrderCollected)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (one of: %s)", ErrUnknownSort, order, strings.Join(ArticleOrders, ", "))
+	}
+	joins := ` LEFT JOIN article_triage t ON t.article_id = a.id`
+	if order == ArticleOrderReputation {
+		joins += ` LEFT JOIN (` + sourceReputation + `) r ON r.source = COALESCE(a.source, 'Unknown')`
+	}
 	rows, err := db.conn.Query(
 		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
-		a.content_fetched, a.period_id, a.collected_at
-		FROM articles a JOIN storyline_articles sa ON a.id = sa.article_id
-		WHERE sa.storyline_id = ?`, storylineID,
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url
+		FROM articles a JOIN storyline_articles sa ON a.id = sa.article_id`+joins+`
+		WHERE sa.storyline_id = ?
+		ORDER BY `+clause, storylineID,
 	)
 	if err != nil {
 		return nil, err
@@ -136,7 +251,8 @@ func (db *DB) ClearStorylinesForPeriod(periodID string) error {
 	return tx.Commit()
 }
 
-// InsertStorylineNarrative inserts a narrative for a storyline.
+// InsertStorylineNarrative inserts a narrative for a storyline, replacing
+// the one it had.
 func (db *DB) InsertStorylineNarrative(storylineID int64, periodID, title, narrativeText string, sourceRefs []SourceReference) (int64, error) {
 	var refsJSON *string
 	if sourceRefs != nil {
@@ -148,7 +264,16 @@ func (db *DB) InsertStorylineNarrative(storylineID int64, periodID, title, narra
 		refsJSON = &s
 	}
 
-	result, err := db.conn.Exec(
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM storyline_narratives WHERE storyline_id = ?", storylineID); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(
 		`INSERT INTO storyline_narratives
 		(storyline_id, period_id, title, narrative_text, source_references)
 		VALUES (?, ?, ?, ?, ?)`,
@@ -157,14 +282,147 @@ func (db *DB) InsertStorylineNarrative(storylineID int64, periodID, title, narra
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-// GetNarrativesForPeriod returns narratives ordered by storyline article_count DESC.
+// AddStorylineArticle moves an article into a storyline, out of any other
+// storyline of the same period, and marks the narratives of the storylines
+// it left and joined stale. It returns the IDs of those storylines, none
+// when the article is in the storyline already.
+func (db *DB) AddStorylineArticle(storylineID, articleID int64) ([]int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var periodID string
+	if err := tx.QueryRow("SELECT period_id FROM storylines WHERE id = ?", storylineID).Scan(&periodID); err != nil {
+		return nil, fmt.Errorf("storyline %d: %w", storylineID, err)
+	}
+
+	rows, err := tx.Query(
+		`SELECT sa.storyline_id FROM storyline_articles sa
+		JOIN storylines s ON s.id = sa.storyline_id
+		WHERE sa.article_id = ? AND s.period_id = ?`, articleID, periodID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var changed []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if id == storylineID {
+			rows.Close()
+			return nil, nil
+		}
+		changed = append(changed, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range changed {
+		if _, err := tx.Exec("DELETE FROM storyline_articles WHERE storyline_id = ? AND article_id = ?", id, articleID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO storyline_articles (storyline_id, article_id) VALUES (?, ?)", storylineID, articleID); err != nil {
+		return nil, err
+	}
+	changed = append(changed, storylineID)
+	if err := storylinesChanged(tx, changed); err != nil {
+		return nil, err
+	}
+	return changed, tx.Commit()
+}
+
+// RemoveStorylineArticle takes an article out of a storyline and marks the
+// storyline's narrative stale. It reports whether the article was in it.
+func (db *DB) RemoveStorylineArticle(storylineID, articleID int64) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM storyline_articles WHERE storyline_id = ? AND article_id = ?", storylineID, articleID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := storylinesChanged(tx, []int64{storylineID}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// markStale marks narratives stale, those its WHERE clause selects.
+const markStale = "UPDATE storyline_narratives SET stale = 1"
+
+// articleStorylines selects the narratives of the storylines containing an
+// article, which it takes as the only argument.
+const articleStorylines = " WHERE storyline_id IN (SELECT storyline_id FROM storyline_articles WHERE article_id = ?)"
+
+// storylinesChanged recounts the articles of storylines whose article lists
+// changed and marks their narratives stale.
+func storylinesChanged(tx *sql.Tx, storylineIDs []int64) error {
+	for _, id := range storylineIDs {
+		if _, err := tx.Exec(
+			`UPDATE storylines SET article_count =
+				(SELECT COUNT(*) FROM storyline_articles WHERE storyline_id = ?)
+			WHERE id = ?`, id, id,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(markStale+" WHERE storyline_id = ?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountStaleNarratives returns how many narratives of a period are stale.
+func (db *DB) CountStaleNarratives(periodID string) (int, error) {
+	var n int
+	err := db.conn.QueryRow(
+		"SELECT COUNT(*) FROM storyline_narratives WHERE period_id = ? AND stale = 1", periodID,
+	).Scan(&n)
+	return n, err
+}
+
+// DeleteStorylineNarrative deletes the narrative of a storyline.
+func (db *DB) DeleteStorylineNarrative(storylineID int64) error {
+	_, err := db.conn.Exec("DELETE FROM storyline_narratives WHERE storyline_id = ?", storylineID)
+	return err
+}
+
+// SetNarrativeHypeScore records how promotional a narrative's coverage is.
+func (db *DB) SetNarrativeHypeScore(narrativeID int64, score float64) error {
+	_, err := db.conn.Exec("UPDATE storyline_narratives SET hype_score = ? WHERE id = ?", score, narrativeID)
+	return err
+}
+
+// GetNarrativesForPeriod returns narratives in briefing order: by the summed
+// source weights of their storylines' articles, which is the article count
+// when no source is weighted, largest first, except that storylines covered
+// only by vendor sources are moved below the first IndependentLead that are
+// not.
 func (db *DB) GetNarrativesForPeriod(periodID string) ([]StorylineNarrative, error) {
 	rows, err := db.conn.Query(
 		`SELECT sn.id, sn.storyline_id, sn.period_id, sn.title, sn.narrative_text,
-		sn.source_references, sn.generated_at
+		sn.source_references, sn.hype_score, sn.generated_at, sn.stale
 		FROM storyline_narratives sn
 		JOIN storylines s ON s.id = sn.storyline_id
 		WHERE sn.period_id = ?
@@ -174,20 +432,59 @@ func (db *DB) GetNarrativesForPeriod(periodID string) ([]StorylineNarrative, err
 		return nil, err
 	}
 	defer rows.Close()
+	narratives, err := scanNarratives(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	weights, err := db.GetSourceWeights()
+	if err != nil {
+		return nil, err
+	}
+	sums, err := db.storylineWeights(periodID, weights)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(narratives, func(a, b StorylineNarrative) int {
+		return cmp.Compare(sums[b.StorylineID], sums[a.StorylineID])
+	})
+
+	types, err := db.GetStorylineSourceTypes(periodID)
+	if err != nil {
+		return nil, err
+	}
+	return leadWithIndependent(narratives, types), nil
+}
+
+// SearchNarratives returns the narratives of periods from since on (all
+// periods when since is empty) whose title or text contains query
+// (case-insensitive), the most recent first, up to limit.
+func (db *DB) SearchNarratives(query, since string, limit int) ([]StorylineNarrative, error) {
+	pattern := "%" + escapeLike(query) + "%"
+	rows, err := db.conn.Query(
+		`SELECT id, storyline_id, period_id, title, narrative_text, source_references, hype_score, generated_at, stale
+		FROM storyline_narratives
+		WHERE (title LIKE ? ESCAPE '\' OR narrative_text LIKE ? ESCAPE '\') AND period_id >= ?
+		ORDER BY period_id DESC, id LIMIT ?`, pattern, pattern, since, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	return scanNarratives(rows)
 }
 
 // GetNarrativeForStoryline returns the narrative for a specific storyline.
 func (db *DB) GetNarrativeForStoryline(storylineID int64) (*StorylineNarrative, error) {
 	row := db.conn.QueryRow(
-		`SELECT id, storyline_id, period_id, title, narrative_text, source_references, generated_at
+		`SELECT id, storyline_id, period_id, title, narrative_text, source_references, hype_score, generated_at, stale
 		FROM storyline_narratives WHERE storyline_id = ?`, storylineID,
 	)
 
 	var n StorylineNarrative
 	var refsJSON *string
 	if err := row.Scan(&n.ID, &n.StorylineID, &n.PeriodID, &n.Title,
-		&n.NarrativeText, &refsJSON, &n.GeneratedAt); err != nil {
+		&n.NarrativeText, &refsJSON, &n.HypeScore, &n.GeneratedAt, &n.Stale); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -203,13 +500,33 @@ func (db *DB) GetNarrativeForStoryline(storylineID int64) (*StorylineNarrative,
 	return &n, nil
 }
 
+// GetNarrativeTitlesForPeriod returns the lowercased titles of all narratives
+// in a period, for checking new titles against.
+func (db *DB) GetNarrativeTitlesForPeriod(periodID string) (map[string]bool, error) {
+	rows, err := db.conn.Query("SELECT title FROM storyline_narratives WHERE period_id = ?", periodID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	titles := make(map[string]bool)
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles[FoldLabel(title)] = true
+	}
+	return titles, rows.Err()
+}
+
 func scanNarratives(rows *sql.Rows) ([]StorylineNarrative, error) {
 	var narratives []StorylineNarrative
 	for rows.Next() {
 		var n StorylineNarrative
 		var refsJSON *string
 		if err := rows.Scan(&n.ID, &n.StorylineID, &n.PeriodID, &n.Title,
-			&n.NarrativeText, &refsJSON, &n.GeneratedAt); err != nil {
+			&n.NarrativeText, &refsJSON, &n.HypeScore, &n.GeneratedAt, &n.Stale); err != nil {
 			return nil, err
 		}
 		if refsJSON != nil {
@@ -221,3 +538,27 @@ func scanNarratives(rows *sql.Rows) ([]StorylineNarrative, error) {
 	}
 	return narratives, rows.Err()
 }
+
+// GetStorylineTitlesSince returns the titles of the storylines of periods
+// starting on or after since (YYYY-MM-DD), the latest first, up to limit.
+// Storylines without a narrative are listed by their label.
+func (db *DB) GetStorylineTitlesSince(since string, limit int) ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT COALESCE((SELECT n.title FROM storyline_narratives n WHERE n.storyline_id = s.id ORDER BY n.id DESC LIMIT 1), s.label)
+		FROM storylines s WHERE s.period_id >= ?
+		ORDER BY s.period_id DESC, s.id LIMIT ?`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
@@ -0,0 +1,45 @@
+package database
+
+import "encoding/json"
+
+// GetTrainingExamples returns every triaged article of a period from since
+// on (all periods when since is empty), with its triage and rating, oldest
+// first.
+func (db *DB) GetTrainingExamples(since string) ([]TrainingExample, error) {
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.url, a.title, a.source, a.published_date, a.content,
+		a.content_fetched, a.period_id, a.collected_at, a.category, a.source_weight, a.paywalled, a.language, a.image_url,
+		t.verdict, t.article_type, t.key_points, t.relevance_reason, t.practical_score, t.triaged_at,
+		f.rating
+		FROM articles a
+		JOIN article_triage t ON t.article_id = a.id
+		LEFT JOIN article_feedback f ON f.article_id = a.id
+		WHERE a.period_id IS NOT NULL AND a.period_id >= ?
+		ORDER BY a.collected_at, a.id`, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var examples []TrainingExample
+	for rows.Next() {
+		var e TrainingExample
+		a, t := &e.Article, &e.Triage
+		var kpJSON *string
+		if err := rows.Scan(&a.ID, &a.URL, &a.Title, &a.Source, &a.PublishedDate, &a.Content,
+			&a.ContentFetched, &a.PeriodID, &a.CollectedAt, &a.Category, &a.SourceWeight, &a.Paywalled, &a.Language, &a.ImageURL,
+			&t.Verdict, &t.ArticleType, &kpJSON, &t.RelevanceReason, &t.PracticalScore, &t.TriagedAt,
+			&e.Rating); err != nil {
+			return nil, err
+		}
+		t.ArticleID = a.ID
+		if kpJSON != nil {
+			if err := json.Unmarshal([]byte(*kpJSON), &t.KeyPoints); err != nil {
+				t.KeyPoints = nil
+			}
+		}
+		examples = append(examples, e)
+	}
+	return examples, rows.Err()
+}
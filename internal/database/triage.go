@@ -23,9 +23,49 @@ func (db *DB) InsertTriage(articleID int64, verdict string, articleType *string,
 		VALUES (?, ?, ?, ?, ?, ?)`,
 		articleID, verdict, articleType, kpJSON, relevanceReason, practicalScore,
 	)
+	if err != nil {
+		return err
+	}
+	_, err = db.conn.Exec(`DELETE FROM triage_failures WHERE article_id = ?`, articleID)
 	return err
 }
 
+// RecordTriageFailure notes a failed triage attempt for an article and why
+// it failed. The article stays untriaged, so the next run tries it again.
+func (db *DB) RecordTriageFailure(articleID int64, reason string) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO triage_failures (article_id, last_error) VALUES (?, ?)
+		ON CONFLICT(article_id) DO UPDATE SET
+			attempts = attempts + 1, last_error = excluded.last_error, last_failed_at = datetime('now')`,
+		articleID, reason,
+	)
+	return err
+}
+
+// GetTriageFailures returns the articles whose triage is failing, most
+// attempts first.
+func (db *DB) GetTriageFailures(limit int) ([]TriageFailure, error) {
+	rows, err := db.conn.Query(
+		`SELECT f.article_id, a.title, f.attempts, f.last_error, f.last_failed_at
+		FROM triage_failures f JOIN articles a ON a.id = f.article_id
+		ORDER BY f.attempts DESC, f.last_failed_at DESC LIMIT ?`, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []TriageFailure
+	for rows.Next() {
+		var f TriageFailure
+		if err := rows.Scan(&f.ArticleID, &f.Title, &f.Attempts, &f.LastError, &f.LastFailedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
 // GetTriage returns the triage result for an article.
 func (db *DB) GetTriage(articleID int64) (*ArticleTriage, error) {
 	row := db.conn.QueryRow(
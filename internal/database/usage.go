@@ -0,0 +1,77 @@
+package database
+
+import "database/sql"
+
+// InsertLLMUsage stores the usage records of a pipeline step.
+func (db *DB) InsertLLMUsage(records []LLMUsage) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range records {
+		if _, err := tx.Exec(
+			`INSERT INTO llm_usage (run_id, period_id, step, provider, model, prompt_tokens, completion_tokens, cost_usd)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.RunID, r.PeriodID, r.Step, r.Provider, r.Model, r.PromptTokens, r.CompletionTokens, r.CostUSD,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const usageTotals = `COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost_usd), 0)`
+
+// GetRunUsage returns the usage of a run per step, in the order the steps ran.
+func (db *DB) GetRunUsage(runID string) ([]UsageTotal, error) {
+	rows, err := db.conn.Query(
+		`SELECT step, `+usageTotals+` FROM llm_usage WHERE run_id = ?
+		GROUP BY step ORDER BY MIN(id)`, runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		if err := rows.Scan(&t.Label, &t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CostUSD); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// GetLastRunUsage returns the total usage of the most recent run that made
+// LLM calls, labelled with its run ID, or nil if none has.
+func (db *DB) GetLastRunUsage() (*UsageTotal, error) {
+	var runID string
+	err := db.conn.QueryRow(`SELECT run_id FROM llm_usage ORDER BY id DESC LIMIT 1`).Scan(&runID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := UsageTotal{Label: runID}
+	err = db.conn.QueryRow(`SELECT `+usageTotals+` FROM llm_usage WHERE run_id = ?`, runID).
+		Scan(&t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CostUSD)
+	return &t, err
+}
+
+// GetUsageSince returns the total usage of calls made at or after since
+// ("YYYY-MM-DD HH:MM:SS", UTC); an empty since covers all calls.
+func (db *DB) GetUsageSince(since string) (UsageTotal, error) {
+	var t UsageTotal
+	err := db.conn.QueryRow(`SELECT `+usageTotals+` FROM llm_usage WHERE created_at >= ?`, since).
+		Scan(&t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CostUSD)
+	return t, err
+}
@@ -0,0 +1,153 @@
+// Package dedup finds articles whose content is a near-duplicate of an
+// earlier article's: syndicated copies and lightly edited press releases
+// that carry no canonical link to collapse them by. Each article's content
+// gets a 64-bit simhash, and two articles whose simhashes differ in few bits
+// say nearly the same thing.
+package dedup
+
+import (
+	"cmp"
+	"hash/fnv"
+	"log"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// Defaults for Options.
+const (
+	DefaultMaxDistance = 6
+	DefaultDays        = 7
+)
+
+// shingleWords is how many consecutive words are hashed together, so
+// reordered sentences count as different text but a changed word only
+// touches a few shingles.
+const shingleWords = 3
+
+// minWords is the least content worth fingerprinting; shorter texts, such
+// as summaries from a feed, are too alike to tell copies from similar news.
+const minWords = 50
+
+// Simhash returns the simhash of text's word shingles, or false when text
+// is too short to fingerprint.
+func Simhash(text string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < minWords {
+		return 0, false
+	}
+	var votes [64]int
+	for i := 0; i+shingleWords <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleWords], " ")))
+		sum := mix(h.Sum64())
+		for bit := range votes {
+			if sum&(1<<bit) != 0 {
+				votes[bit]++
+			} else {
+				votes[bit]--
+			}
+		}
+	}
+	var simhash uint64
+	for bit, v := range votes {
+		if v > 0 {
+			simhash |= 1 << bit
+		}
+	}
+	return simhash, true
+}
+
+// mix spreads the bits of an FNV hash, whose high bits change little
+// between short inputs, so every bit casts a fair vote (SplitMix64's
+// finalizer).
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+// Distance returns how many bits two simhashes differ in.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Options configures near-duplicate detection.
+type Options struct {
+	// MaxDistance is the most bits a near-duplicate's simhash may differ
+	// in; 0 means DefaultMaxDistance.
+	MaxDistance int
+
+	// Days is how long before the period articles are compared with;
+	// 0 means DefaultDays.
+	Days int
+}
+
+// Result holds the results of a near-duplicate check.
+type Result struct {
+	Fingerprinted int
+	Duplicates    int // articles marked as near-duplicates of another
+}
+
+// MarkPeriod fingerprints the content of the period's articles that have
+// none yet and marks those close to an article fingerprinted before them,
+// in this period or the Days before it, as its near-duplicate.
+func MarkPeriod(db *database.DB, periodID string, opts Options) (*Result, error) {
+	articles, err := db.GetArticlesWithoutSimhash(periodID)
+	if err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 {
+		return &Result{}, nil
+	}
+	known, err := db.GetFingerprints(since(periodID, cmp.Or(opts.Days, DefaultDays)))
+	if err != nil {
+		return nil, err
+	}
+	maxDistance := cmp.Or(opts.MaxDistance, DefaultMaxDistance)
+
+	r := &Result{}
+	for _, a := range articles {
+		simhash, ok := Simhash(*a.Content)
+		if !ok {
+			continue
+		}
+		var duplicateOf *int64
+		for _, k := range known {
+			if Distance(simhash, k.Simhash) <= maxDistance {
+				duplicateOf = &k.ArticleID
+				break
+			}
+		}
+		if err := db.SetArticleSimhash(a.ID, simhash, duplicateOf); err != nil {
+			log.Printf("Error storing the simhash of article %d: %v", a.ID, err)
+			continue
+		}
+		r.Fingerprinted++
+		if duplicateOf != nil {
+			r.Duplicates++
+			log.Printf("Near-duplicate of article %d: %s", *duplicateOf, a.URL)
+			continue
+		}
+		known = append(known, database.ArticleFingerprint{ArticleID: a.ID, Simhash: simhash})
+	}
+	return r, nil
+}
+
+// since returns the first day articles compared with periodID's may have
+// been collected on, days before it starts.
+func since(periodID string, days int) string {
+	start, _, _ := strings.Cut(periodID, "..")
+	day, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return ""
+	}
+	return day.AddDate(0, 0, -days).Format("2006-01-02")
+}
@@ -0,0 +1,99 @@
+package dedup
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+const release = `The company released a new open weights model on Tuesday that it says matches the coding performance of much larger systems.
+The model comes in three sizes and is licensed for commercial use. Developers can download it from the usual model hubs or run it through
+the company's hosted API, which now supports longer context windows and structured output. Early benchmark results show strong results on
+code generation and tool use, though independent testers have yet to confirm them. The company also published a technical report describing
+the training data mix and the reinforcement learning recipe used to improve its reasoning on multi-step programming tasks.
+Pricing for the hosted version starts below that of its previous flagship, and the smallest size is meant to run on a single laptop GPU.
+The launch follows months of competition between labs releasing open models, and analysts expect rivals to answer within weeks.
+Several editor plugins announced support on the same day, and the company said fine-tuning tools would follow later this quarter.`
+
+const other = `A survey of engineering teams found that most now use AI assistants for code review, but few trust them to approve changes on their own.
+Respondents said the tools catch style issues and simple bugs well while missing design problems that need context about the wider system.
+Teams that got the most out of them wrote down their conventions and fed them to the assistant, and kept a person responsible for every merge.
+The authors expect agents that run tests and open pull requests to spread next year, and warn that review habits will have to change with them.`
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func ptr(s string) *string { return &s }
+
+func TestSimhash(t *testing.T) {
+	a, ok := Simhash(release)
+	if !ok {
+		t.Fatal("expected the release to be long enough to fingerprint")
+	}
+	// A syndicated copy with a new byline and one word changed.
+	copied, _ := Simhash("By Staff Writer. " + strings.Replace(release, "Tuesday", "Wednesday", 1))
+	if d := Distance(a, copied); d > DefaultMaxDistance {
+		t.Errorf("expected the copy within %d bits, got %d", DefaultMaxDistance, d)
+	}
+	b, _ := Simhash(other)
+	if d := Distance(a, b); d <= DefaultMaxDistance*3 {
+		t.Errorf("expected unrelated articles far apart, got %d bits", d)
+	}
+	if _, ok := Simhash("A short summary from a feed."); ok {
+		t.Error("expected a short text not to be fingerprinted")
+	}
+}
+
+func TestMarkPeriod(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertArticle("https://a.com/model", "A new model", nil, nil, ptr(release), ptr("2026-02-05"))
+	copied, _ := db.InsertArticle("https://b.com/model", "Company releases model", nil, nil,
+		ptr(strings.Replace(release, "Tuesday", "Wednesday", 1)), ptr("2026-02-06"))
+	unrelated, _ := db.InsertArticle("https://c.com/survey", "Survey", nil, nil, ptr(other), ptr("2026-02-06"))
+	db.InsertArticle("https://d.com/short", "Short", nil, nil, ptr("A short summary."), ptr("2026-02-06"))
+
+	if _, err := MarkPeriod(db, "2026-02-05", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := MarkPeriod(db, "2026-02-06", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Fingerprinted != 2 || result.Duplicates != 1 {
+		t.Errorf("expected two articles fingerprinted and the copy marked, got %+v", result)
+	}
+
+	untriaged, _ := db.GetUntriagedArticles(ptr("2026-02-06"))
+	for _, a := range untriaged {
+		if a.ID == copied {
+			t.Errorf("expected the near-duplicate left out of triage, got %+v", untriaged)
+		}
+	}
+	if len(untriaged) != 2 || (untriaged[0].ID != unrelated && untriaged[1].ID != unrelated) {
+		t.Errorf("expected the unrelated and the short article to triage, got %+v", untriaged)
+	}
+
+	// Nothing is fingerprinted twice, and new content is compared again.
+	if result, _ := MarkPeriod(db, "2026-02-06", Options{}); result.Fingerprinted != 0 {
+		t.Errorf("expected nothing left to fingerprint, got %+v", result)
+	}
+	db.UpdateArticleContent(copied, ptr(release))
+	if result, _ := MarkPeriod(db, "2026-02-06", Options{}); result.Fingerprinted != 1 || result.Duplicates != 1 {
+		t.Errorf("expected the refetched article compared again, got %+v", result)
+	}
+
+	// Articles older than the window aren't compared with.
+	db.InsertArticle("https://e.com/model", "The model again", nil, nil, ptr(release), ptr("2026-03-01"))
+	if result, _ := MarkPeriod(db, "2026-03-01", Options{Days: 1}); result.Duplicates != 0 {
+		t.Errorf("expected articles outside the window not compared with, got %+v", result)
+	}
+}
@@ -0,0 +1,166 @@
+package deliver
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+const requestTimeout = 60 * time.Second
+
+// Target is a remote destination that composed briefings are pushed to.
+type Target interface {
+	Name() string
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+// Result holds the results of a delivery run.
+type Result struct {
+	Uploaded int
+	Notified int
+	Errors   int
+}
+
+// Deliverer renders composed briefings, pushes them to every configured
+// storage target and announces them on every configured chat.
+type Deliverer struct {
+	db        *database.DB
+	targets   []Target
+	notifiers []Notifier
+	bots      []Bot
+	formats   []string
+	publicURL string
+}
+
+// NewDeliverer creates a deliverer from the delivery config section.
+// Targets that are disabled or missing required settings are skipped.
+func NewDeliverer(cfg config.Delivery, db *database.DB) *Deliverer {
+	d := &Deliverer{db: db, formats: cfg.Formats, publicURL: cfg.PublicURL}
+	if len(d.formats) == 0 {
+		d.formats = []string{FormatMarkdown, FormatHTML, FormatJSON}
+	}
+
+	if s3 := cfg.S3; s3.Enabled {
+		if s3.Bucket == "" {
+			log.Println("S3 delivery enabled but no bucket configured, skipping")
+		} else {
+			d.targets = append(d.targets, NewS3Target(
+				s3.Endpoint, s3.Region, s3.Bucket, s3.Prefix,
+				os.Getenv(s3.AccessKeyEnv), os.Getenv(s3.SecretKeyEnv), os.Getenv(s3.SessionTokenEnv),
+				s3.PathStyle,
+			))
+		}
+	}
+
+	if dav := cfg.WebDAV; dav.Enabled {
+		if dav.URL == "" {
+			log.Println("WebDAV delivery enabled but no url configured, skipping")
+		} else {
+			d.targets = append(d.targets, NewWebDAVTarget(dav.URL, dav.Username, os.Getenv(dav.PasswordEnv)))
+		}
+	}
+
+	if tg := cfg.Telegram; tg.Enabled {
+		token := os.Getenv(tg.BotTokenEnv)
+		if token == "" || tg.ChatID == "" {
+			log.Printf("Telegram delivery enabled but %s or chat_id not set, skipping", tg.BotTokenEnv)
+		} else {
+			n := NewTelegramNotifier(token, tg.ChatID)
+			d.notifiers = append(d.notifiers, n)
+			if tg.Interactive {
+				d.bots = append(d.bots, n)
+			}
+		}
+	}
+
+	if mx := cfg.Matrix; mx.Enabled {
+		token := os.Getenv(mx.AccessTokenEnv)
+		if token == "" || mx.RoomID == "" || mx.Homeserver == "" {
+			log.Printf("Matrix delivery enabled but %s, homeserver or room_id not set, skipping", mx.AccessTokenEnv)
+		} else {
+			n := NewMatrixNotifier(mx.Homeserver, token, mx.RoomID)
+			d.notifiers = append(d.notifiers, n)
+			if mx.Interactive {
+				d.bots = append(d.bots, n)
+			}
+		}
+	}
+
+	return d
+}
+
+// HasTargets reports whether any delivery target or notifier is configured.
+func (d *Deliverer) HasTargets() bool {
+	return len(d.targets) > 0 || len(d.notifiers) > 0
+}
+
+// StartBots runs the interactive chat bots in the background until ctx is
+// done, returning how many were started.
+func (d *Deliverer) StartBots(ctx context.Context) int {
+	cmds := NewCommands(d.db, d.publicURL)
+	for _, b := range d.bots {
+		go b.Run(ctx, cmds)
+	}
+	return len(d.bots)
+}
+
+// Deliver renders one edition of a period's briefing in every configured
+// format, uploads each file to every target and posts the TL;DR to every
+// notifier.
+func (d *Deliverer) Deliver(ctx context.Context, periodID, edition string) (*Result, error) {
+	doc, err := Load(d.db, periodID, edition)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, &database.BriefingNotFoundError{PeriodID: periodID, Edition: edition}
+	}
+
+	r := &Result{}
+	for _, format := range d.formats {
+		if len(d.targets) == 0 {
+			break
+		}
+		body, contentType, ext, err := doc.Render(format)
+		if err != nil {
+			return nil, err
+		}
+		key := doc.BaseName() + ext
+
+		for _, t := range d.targets {
+			if err := t.Put(ctx, key, body, contentType); err != nil {
+				log.Printf("Error delivering %s to %s: %v", key, t.Name(), err)
+				r.Errors++
+				continue
+			}
+			r.Uploaded++
+		}
+	}
+
+	msg := NewMessage(doc.Briefing, d.publicURL)
+	for _, n := range d.notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			log.Printf("Error notifying %s: %v", n.Name(), err)
+			r.Errors++
+			continue
+		}
+		r.Notified++
+	}
+
+	log.Printf("Delivery complete: %d uploaded, %d notified, %d errors", r.Uploaded, r.Notified, r.Errors)
+	return r, nil
+}
+
+// joinKey joins a key prefix and a file name with exactly one slash.
+func joinKey(prefix, name string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
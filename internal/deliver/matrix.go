@@ -0,0 +1,154 @@
+package deliver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// matrixPollTimeout is the long-polling timeout for /sync, in milliseconds.
+const matrixPollTimeout = 30000
+
+// MatrixNotifier posts to a Matrix room through the client-server API.
+type MatrixNotifier struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	client      *http.Client
+	since       string
+	txn         atomic.Int64
+}
+
+// NewMatrixNotifier creates a Matrix notifier for one room. The access token
+// must belong to a user that has joined the room.
+func NewMatrixNotifier(homeserver, accessToken, roomID string) *MatrixNotifier {
+	return &MatrixNotifier{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		accessToken: accessToken,
+		roomID:      roomID,
+		client:      &http.Client{Timeout: requestTimeout + matrixPollTimeout*time.Millisecond},
+	}
+}
+
+// Name identifies the notifier in logs.
+func (m *MatrixNotifier) Name() string {
+	return "matrix:" + m.roomID
+}
+
+// Notify posts the briefing announcement to the room.
+func (m *MatrixNotifier) Notify(ctx context.Context, msg Message) error {
+	return m.send(ctx, msg.Text(), msg.HTML())
+}
+
+func (m *MatrixNotifier) send(ctx context.Context, text, html string) error {
+	content := map[string]string{"msgtype": "m.notice", "body": text}
+	if html != "" {
+		content["format"] = "org.matrix.custom.html"
+		content["formatted_body"] = html
+	}
+	payload, _ := json.Marshal(content)
+
+	txnID := fmt.Sprintf("aicrawler-%d-%d", time.Now().UnixNano(), m.txn.Add(1))
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		url.PathEscape(m.roomID), url.PathEscape(txnID))
+	return m.call(ctx, http.MethodPut, path, payload, nil)
+}
+
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []struct {
+					Type    string `json:"type"`
+					Content struct {
+						MsgType string `json:"msgtype"`
+						Body    string `json:"body"`
+					} `json:"content"`
+				} `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Run answers commands posted in the configured room until ctx is done.
+func (m *MatrixNotifier) Run(ctx context.Context, cmds *Commands) {
+	runBot(ctx, "Matrix", func(ctx context.Context) error { return m.poll(ctx, cmds) })
+}
+
+// poll runs one /sync and replies to commands in the room. The first sync
+// only records the position, so old room history is never answered.
+func (m *MatrixNotifier) poll(ctx context.Context, cmds *Commands) error {
+	filter, _ := json.Marshal(map[string]any{
+		"room": map[string]any{
+			"rooms":    []string{m.roomID},
+			"timeline": map[string]any{"types": []string{"m.room.message"}},
+		},
+		"presence":     map[string]any{"types": []string{}},
+		"account_data": map[string]any{"types": []string{}},
+	})
+	query := url.Values{"filter": {string(filter)}}
+	if m.since != "" {
+		query.Set("since", m.since)
+		query.Set("timeout", fmt.Sprint(matrixPollTimeout))
+	}
+
+	var out matrixSync
+	if err := m.call(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &out); err != nil {
+		return err
+	}
+	first := m.since == ""
+	m.since = out.NextBatch
+	if first {
+		return nil
+	}
+
+	room, ok := out.Rooms.Join[m.roomID]
+	if !ok {
+		return nil
+	}
+	for _, ev := range room.Timeline.Events {
+		// The bot's own replies are m.notice, so only m.text can trigger commands.
+		if ev.Type != "m.room.message" || ev.Content.MsgType != "m.text" {
+			continue
+		}
+		if reply, ok := cmds.Handle(ev.Content.Body); ok {
+			if err := m.send(ctx, reply, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MatrixNotifier) call(ctx context.Context, method, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, m.homeserver+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e) //nolint: errcheck
+		return fmt.Errorf("matrix %s %s: %s %s", method, strings.SplitN(path, "?", 2)[0], resp.Status, e.Error)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package deliver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// maxSearchResults caps how many articles a /search reply lists.
+const maxSearchResults = 5
+
+// botRetryDelay is how long a bot waits after a failed poll.
+const botRetryDelay = 5 * time.Second
+
+// Message is a short chat announcement of a composed briefing.
+type Message struct {
+	Title string
+	TLDR  string
+	Link  string // empty when no public URL is configured
+}
+
+// Text renders the message as plain text.
+func (m Message) Text() string {
+	parts := []string{m.Title}
+	if m.TLDR != "" {
+		parts = append(parts, m.TLDR)
+	}
+	if m.Link != "" {
+		parts = append(parts, m.Link)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// HTML renders the message as an HTML fragment for clients that support it.
+func (m Message) HTML() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<p><strong>%s</strong></p>", html.EscapeString(m.Title))
+	if m.TLDR != "" {
+		md.Convert([]byte(m.TLDR), &b) //nolint: errcheck
+	}
+	if m.Link != "" {
+		fmt.Fprintf(&b, `<p><a href="%s">Read the full briefing</a></p>`, html.EscapeString(m.Link))
+	}
+	return b.String()
+}
+
+// Notifier posts briefing announcements to a chat service.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Bot answers chat commands in the configured room or chat until ctx is done.
+type Bot interface {
+	Name() string
+	Run(ctx context.Context, cmds *Commands)
+}
+
+// Commands answers interactive chat commands from the database.
+type Commands struct {
+	db        *database.DB
+	publicURL string
+}
+
+// NewCommands creates a command handler. publicURL is used to link briefings.
+func NewCommands(db *database.DB, publicURL string) *Commands {
+	return &Commands{db: db, publicURL: publicURL}
+}
+
+// Handle answers one chat message. It returns ok=false for messages that
+// aren't commands, which bots ignore.
+func (c *Commands) Handle(text string) (reply string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", false
+	}
+	command, arg, _ := strings.Cut(text, " ")
+	// Telegram group commands look like /briefing@MyBot.
+	command, _, _ = strings.Cut(command, "@")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "/briefing":
+		return c.briefing(arg), true
+	case "/search":
+		return c.search(arg), true
+	case "/help", "/start":
+		return "Commands:\n/briefing [today|latest|YYYY-MM-DD] - show a briefing's TL;DR\n/search <query> - find collected articles", true
+	default:
+		return "", false
+	}
+}
+
+func (c *Commands) briefing(arg string) string {
+	var briefing *database.Briefing
+	var err error
+	switch arg {
+	case "", "latest":
+		var all []database.Briefing
+		all, err = c.db.GetAllBriefings()
+		if len(all) > 0 {
+			briefing = &all[0]
+		}
+	case "today":
+		briefing, err = c.db.GetBriefing(database.GetToday())
+	default:
+		briefing, err = c.db.GetBriefing(arg)
+	}
+	if err != nil {
+		log.Printf("Error loading briefing for chat command: %v", err)
+		return "Sorry, the briefing could not be loaded."
+	}
+	if briefing == nil {
+		if arg == "" || arg == "latest" {
+			return "No briefings yet."
+		}
+		return fmt.Sprintf("No briefing for %s.", arg)
+	}
+	return NewMessage(*briefing, c.publicURL).Text()
+}
+
+func (c *Commands) search(query string) string {
+	if query == "" {
+		return "Usage: /search <query>"
+	}
+	articles, err := c.db.SearchArticles(query, maxSearchResults)
+	if err != nil {
+		log.Printf("Error searching articles for chat command: %v", err)
+		return "Sorry, the search failed."
+	}
+	if len(articles) == 0 {
+		return fmt.Sprintf("No articles found for %q.", query)
+	}
+
+	lines := []string{fmt.Sprintf("Articles matching %q:", query)}
+	for _, a := range articles {
+		line := "- " + a.Title
+		if a.Source != nil && *a.Source != "" {
+			line += " (" + *a.Source + ")"
+		}
+		lines = append(lines, line+"\n  "+a.URL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewMessage builds the chat announcement for a briefing, linking to the web
+// UI under publicURL when set.
+func NewMessage(b database.Briefing, publicURL string) Message {
+	doc := &Document{Briefing: b}
+	return Message{Title: doc.Title(), TLDR: b.TLDR, Link: briefingLink(publicURL, b)}
+}
+
+// briefingLink returns the web UI URL of a briefing, or "" without a public URL.
+func briefingLink(publicURL string, b database.Briefing) string {
+	if publicURL == "" {
+		return ""
+	}
+	link := strings.TrimRight(publicURL, "/") + "/briefing/" + b.PeriodID
+	if b.Edition != "" && b.Edition != database.EditionMorning {
+		link += "?edition=" + b.Edition
+	}
+	return link
+}
+
+// runBot drives a long-polling bot loop, backing off after errors.
+func runBot(ctx context.Context, name string, poll func(context.Context) error) {
+	log.Printf("%s bot listening for commands", name)
+	for ctx.Err() == nil {
+		if err := poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("%s bot: %v", name, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(botRetryDelay):
+			}
+		}
+	}
+}
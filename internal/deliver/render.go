@@ -0,0 +1,201 @@
+package deliver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// Export formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+var md = goldmark.New()
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 760px; margin: 2rem auto; padding: 0 1rem; line-height: 1.7; color: #212529; }
+h1 { margin-bottom: 0.25rem; }
+.meta { color: #6c757d; margin-top: 0; }
+a { color: #0d6efd; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Meta}}</p>
+{{.Content}}
+</body>
+</html>
+`))
+
+// Document is one briefing edition with everything needed to export it.
+type Document struct {
+	Briefing   database.Briefing
+	Narratives []database.StorylineNarrative
+	Highlights []database.BriefingHighlight
+}
+
+// Load gathers a briefing edition and its storylines for export.
+// It returns nil when the edition doesn't exist.
+func Load(db *database.DB, periodID, edition string) (*Document, error) {
+	briefing, err := db.GetBriefingEdition(periodID, edition)
+	if err != nil || briefing == nil {
+		return nil, err
+	}
+
+	doc := &Document{Briefing: *briefing}
+	// Storylines and highlights belong to the morning edition only.
+	if edition == database.EditionMorning {
+		if doc.Narratives, err = db.GetNarrativesForPeriod(periodID); err != nil {
+			return nil, err
+		}
+		if doc.Highlights, err = db.GetBriefingHighlights(periodID); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// BaseName returns the file name, without extension, for this edition,
+// e.g. "2026-02-06" or "2026-02-06-evening".
+func (d *Document) BaseName() string {
+	if d.Briefing.Edition == "" || d.Briefing.Edition == database.EditionMorning {
+		return d.Briefing.PeriodID
+	}
+	return d.Briefing.PeriodID + "-" + d.Briefing.Edition
+}
+
+// Title returns the human-readable briefing title.
+func (d *Document) Title() string {
+	title := "AI Briefing: " + database.FormatPeriodDisplay(d.Briefing.PeriodID)
+	if d.Briefing.Edition != "" && d.Briefing.Edition != database.EditionMorning {
+		title += " (" + d.Briefing.Edition + " edition)"
+	}
+	return title
+}
+
+// Render encodes the document in the given format, returning the body,
+// its content type and the file extension to use.
+func (d *Document) Render(format string) ([]byte, string, string, error) {
+	switch format {
+	case FormatMarkdown:
+		return []byte(d.Markdown()), "text/markdown; charset=utf-8", ".md", nil
+	case FormatHTML:
+		body, err := d.HTML()
+		return body, "text/html; charset=utf-8", ".html", err
+	case FormatJSON:
+		body, err := d.JSON()
+		return body, "application/json", ".json", err
+	default:
+		return nil, "", "", fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Markdown renders the briefing as a single markdown document.
+func (d *Document) Markdown() string {
+	return fmt.Sprintf("# %s\n\n%s\n\n%s", d.Title(), d.meta(), d.content())
+}
+
+// content renders everything below the title and metadata line.
+func (d *Document) content() string {
+	var b strings.Builder
+	if d.Briefing.QualityNote != nil {
+		fmt.Fprintf(&b, "> **Degraded run:** %s\n\n", *d.Briefing.QualityNote)
+	}
+	if d.Briefing.TLDR != "" {
+		fmt.Fprintf(&b, "## TL;DR\n\n%s\n\n", d.Briefing.TLDR)
+	}
+	for _, h := range d.Highlights {
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", h.Heading, h.BodyMarkdown)
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(d.Briefing.BodyMarkdown)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// HTML renders the briefing as a standalone HTML page.
+func (d *Document) HTML() ([]byte, error) {
+	var content bytes.Buffer
+	if err := md.Convert([]byte(d.content()), &content); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	err := htmlPage.Execute(&out, map[string]any{
+		"Title":   d.Title(),
+		"Meta":    d.meta(),
+		"Content": template.HTML(content.String()), //nolint: gosec
+	})
+	return out.Bytes(), err
+}
+
+type jsonStoryline struct {
+	Title     string                     `json:"title"`
+	Narrative string                     `json:"narrative"`
+	HypeScore *float64                   `json:"hype_score,omitempty"`
+	Sources   []database.SourceReference `json:"sources"`
+}
+
+type jsonHighlight struct {
+	Heading string `json:"heading"`
+	Body    string `json:"body"`
+}
+
+type jsonBriefing struct {
+	PeriodID       string          `json:"period_id"`
+	Edition        string          `json:"edition"`
+	GeneratedAt    string          `json:"generated_at,omitempty"`
+	StorylineCount int             `json:"storyline_count"`
+	ArticleCount   int             `json:"article_count"`
+	QualityNote    *string         `json:"quality_note,omitempty"`
+	TLDR           string          `json:"tldr"`
+	Highlights     []jsonHighlight `json:"highlights,omitempty"`
+	Storylines     []jsonStoryline `json:"storylines,omitempty"`
+	BodyMarkdown   string          `json:"body_markdown"`
+}
+
+// JSON renders the briefing as structured JSON.
+func (d *Document) JSON() ([]byte, error) {
+	out := jsonBriefing{
+		PeriodID:       d.Briefing.PeriodID,
+		Edition:        d.Briefing.Edition,
+		StorylineCount: d.Briefing.StorylineCount,
+		ArticleCount:   d.Briefing.ArticleCount,
+		QualityNote:    d.Briefing.QualityNote,
+		TLDR:           d.Briefing.TLDR,
+		BodyMarkdown:   d.Briefing.BodyMarkdown,
+	}
+	if d.Briefing.GeneratedAt != nil {
+		out.GeneratedAt = *d.Briefing.GeneratedAt
+	}
+	for _, h := range d.Highlights {
+		out.Highlights = append(out.Highlights, jsonHighlight{Heading: h.Heading, Body: h.BodyMarkdown})
+	}
+	for _, n := range d.Narratives {
+		sources := n.SourceReferences
+		if sources == nil {
+			sources = []database.SourceReference{}
+		}
+		out.Storylines = append(out.Storylines, jsonStoryline{Title: n.Title, Narrative: n.NarrativeText, HypeScore: n.HypeScore, Sources: sources})
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func (d *Document) meta() string {
+	return fmt.Sprintf("%d storylines · %d articles", d.Briefing.StorylineCount, d.Briefing.ArticleCount)
+}
@@ -0,0 +1,199 @@
+package deliver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// S3Target uploads files to an S3-compatible bucket (AWS, MinIO, R2, ...)
+// using AWS Signature Version 4.
+type S3Target struct {
+	endpoint     *url.URL
+	region       string
+	bucket       string
+	prefix       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	pathStyle    bool
+	client       *http.Client
+	now          func() time.Time
+}
+
+// NewS3Target creates an S3 target. With pathStyle the bucket goes in the
+// path (endpoint/bucket/key), as most self-hosted S3 servers expect;
+// otherwise it is used as a virtual host (bucket.endpoint/key).
+func NewS3Target(endpoint, region, bucket, prefix, accessKey, secretKey, sessionToken string, pathStyle bool) *S3Target {
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		u = &url.URL{Scheme: "https", Host: endpoint}
+	}
+	return &S3Target{
+		endpoint:     u,
+		region:       region,
+		bucket:       bucket,
+		prefix:       prefix,
+		accessKey:    accessKey,
+		secretKey:    secretKey,
+		sessionToken: sessionToken,
+		pathStyle:    pathStyle,
+		client:       &http.Client{Timeout: requestTimeout},
+		now:          time.Now,
+	}
+}
+
+// Name identifies the target in logs.
+func (t *S3Target) Name() string {
+	return "s3://" + t.bucket
+}
+
+// Put uploads body under the configured prefix.
+func (t *S3Target) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if t.accessKey == "" || t.secretKey == "" {
+		return fmt.Errorf("S3 credentials not set")
+	}
+
+	objectKey := joinKey(t.prefix, key)
+	u := *t.endpoint
+	if t.pathStyle {
+		u.Path = "/" + t.bucket + "/" + objectKey
+	} else {
+		u.Host = t.bucket + "." + u.Host
+		u.Path = "/" + objectKey
+	}
+	// Send the path encoded as it is signed.
+	u.RawPath = canonicalURI(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	t.sign(req, body)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("S3 PUT %s: %s: %s", objectKey, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sign adds SigV4 authentication headers to req.
+func (t *S3Target) sign(req *http.Request, body []byte) {
+	now := t.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if t.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", t.sessionToken)
+	}
+
+	// Every header set on req is signed, along with the host.
+	signed := []string{"host"}
+	for h := range req.Header {
+		if h := strings.ToLower(h); h != "authorization" && h != "user-agent" {
+			signed = append(signed, h)
+		}
+	}
+	slices.Sort(signed)
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		value := strings.Join(req.Header.Values(h), ",")
+		if h == "host" {
+			value = req.URL.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", h, strings.Join(strings.Fields(value), " "))
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + t.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+t.secretKey), day)
+	key = hmacSHA256(key, t.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalURI encodes each segment of path the way SigV4 expects, which
+// escapes more than URL.EscapedPath does: everything but letters, digits
+// and "-_.~".
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode percent-encodes every byte of s outside SigV4's unreserved set.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
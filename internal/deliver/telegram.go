@@ -0,0 +1,140 @@
+package deliver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const telegramAPIBase = "https://api.telegram.org"
+
+// telegramPollTimeout is the long-polling timeout for getUpdates, in seconds.
+const telegramPollTimeout = 30
+
+// TelegramNotifier posts to a Telegram chat through the Bot API.
+type TelegramNotifier struct {
+	apiBase string
+	token   string
+	chatID  string
+	client  *http.Client
+	offset  int64
+}
+
+// NewTelegramNotifier creates a Telegram notifier for one chat.
+func NewTelegramNotifier(token, chatID string) *TelegramNotifier {
+	return &TelegramNotifier{
+		apiBase: telegramAPIBase,
+		token:   token,
+		chatID:  chatID,
+		// Long polls hold the connection open for telegramPollTimeout.
+		client: &http.Client{Timeout: requestTimeout + telegramPollTimeout*time.Second},
+	}
+}
+
+// Name identifies the notifier in logs.
+func (t *TelegramNotifier) Name() string {
+	return "telegram:" + t.chatID
+}
+
+// Notify posts the briefing announcement to the chat.
+func (t *TelegramNotifier) Notify(ctx context.Context, msg Message) error {
+	return t.send(ctx, msg.Text())
+}
+
+func (t *TelegramNotifier) send(ctx context.Context, text string) error {
+	payload, _ := json.Marshal(map[string]any{
+		"chat_id":                  t.chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	return t.call(ctx, http.MethodPost, "sendMessage", payload, nil)
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// Run answers commands sent to the configured chat until ctx is done.
+func (t *TelegramNotifier) Run(ctx context.Context, cmds *Commands) {
+	runBot(ctx, "Telegram", func(ctx context.Context) error { return t.poll(ctx, cmds) })
+}
+
+// poll fetches one batch of updates and replies to commands from the configured chat.
+func (t *TelegramNotifier) poll(ctx context.Context, cmds *Commands) error {
+	query := url.Values{
+		"timeout":         {strconv.Itoa(telegramPollTimeout)},
+		"offset":          {strconv.FormatInt(t.offset, 10)},
+		"allowed_updates": {`["message"]`},
+	}
+	var updates []telegramUpdate
+	if err := t.call(ctx, http.MethodGet, "getUpdates?"+query.Encode(), nil, &updates); err != nil {
+		return err
+	}
+
+	for _, u := range updates {
+		t.offset = u.UpdateID + 1
+		if u.Message == nil || strconv.FormatInt(u.Message.Chat.ID, 10) != t.chatID {
+			continue
+		}
+		if reply, ok := cmds.Handle(u.Message.Text); ok {
+			if err := t.send(ctx, reply); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// call invokes a Bot API method and decodes its result into out, if non-nil.
+func (t *TelegramNotifier) call(ctx context.Context, httpMethod, method string, payload []byte, out any) error {
+	endpoint := fmt.Sprintf("%s/bot%s/%s", t.apiBase, t.token, method)
+	req, err := http.NewRequestWithContext(ctx, httpMethod, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// The URL embeds the bot token; don't let it reach the logs.
+		return fmt.Errorf("telegram request failed: %w", redactURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram: %s: %w", resp.Status, err)
+	}
+	if !envelope.OK {
+		name, _, _ := strings.Cut(method, "?")
+		return fmt.Errorf("telegram %s: %s", name, envelope.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Result, out)
+	}
+	return nil
+}
+
+// redactURLError strips the request URL from net/http client errors.
+func redactURLError(err error) error {
+	if ue, ok := err.(*url.Error); ok {
+		return ue.Err
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package deliver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WebDAVTarget uploads files to a WebDAV collection such as a Nextcloud folder.
+type WebDAVTarget struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+	ensured  bool
+}
+
+// NewWebDAVTarget creates a WebDAV target rooted at baseURL, e.g.
+// https://cloud.example.com/remote.php/dav/files/me/Briefings/.
+func NewWebDAVTarget(baseURL, username, password string) *WebDAVTarget {
+	return &WebDAVTarget{
+		baseURL:  strings.TrimRight(baseURL, "/") + "/",
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Name identifies the target in logs.
+func (t *WebDAVTarget) Name() string {
+	return t.baseURL
+}
+
+// Put uploads body to baseURL/key, creating the collection on first use.
+func (t *WebDAVTarget) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if !t.ensured {
+		if err := t.mkcol(ctx); err != nil {
+			return err
+		}
+		t.ensured = true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, t.baseURL+key, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := t.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("WebDAV PUT %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+// mkcol creates the target collection. 405 means it already exists.
+func (t *WebDAVTarget) mkcol(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "MKCOL", t.baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("WebDAV MKCOL %s: %s", t.baseURL, resp.Status)
+	}
+	return nil
+}
+
+func (t *WebDAVTarget) do(req *http.Request) (*http.Response, error) {
+	if t.username != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	return t.client.Do(req)
+}
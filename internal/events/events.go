@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Kinds of events published during a pipeline run.
+const (
+	ArticleCollected = "article_collected" // a collect step stored new articles
+	TriageCompleted  = "triage_completed"  // a triage step finished
+	BriefingComposed = "briefing_composed" // an edition of a briefing was composed
+)
+
+// Kinds lists the event kinds.
+var Kinds = []string{ArticleCollected, TriageCompleted, BriefingComposed}
+
+// Event is something that happened during a pipeline run. Events carry
+// enough to find what changed in the database; handlers load the rest.
+type Event struct {
+	Kind       string    `json:"kind"`
+	PeriodID   string    `json:"period_id"`
+	Edition    string    `json:"edition,omitempty"`     // BriefingComposed
+	ArticleIDs []int64   `json:"article_ids,omitempty"` // ArticleCollected: the new articles, one event per step
+	Summary    string    `json:"summary,omitempty"`     // what the step reported, e.g. triage counts
+	Time       time.Time `json:"time"`
+}
+
+// Handler reacts to an event. The summary it returns, if any, is reported
+// with the run's steps; an error fails the run like a failing step, so
+// handlers should count partial failures in the summary instead.
+type Handler func(ctx context.Context, e Event) (summary string, err error)
+
+// Outcome is what one handler reported for an event.
+type Outcome struct {
+	Name    string
+	Summary string
+	Err     error
+}
+
+type subscription struct {
+	name   string
+	handle Handler
+}
+
+// Bus passes published events to the handlers subscribed to their kind.
+// It lets delivery, webhooks and other integrations react to the pipeline
+// without the pipeline knowing about them. A nil Bus drops every event.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]subscription
+}
+
+// NewBus creates a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string][]subscription)}
+}
+
+// Subscribe registers handler, named for logs and run reports, for events
+// of kind. Handlers run in the order they subscribed.
+func (b *Bus) Subscribe(kind, name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[kind] = append(b.subs[kind], subscription{name: name, handle: handler})
+}
+
+// Publish runs the handlers of e's kind one after another and returns the
+// outcomes of those that reported a summary or an error. A failing handler
+// doesn't keep the others from running. e.Time defaults to now.
+func (b *Bus) Publish(ctx context.Context, e Event) []Outcome {
+	if b == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b.mu.RLock()
+	subs := b.subs[e.Kind]
+	b.mu.RUnlock()
+
+	var outcomes []Outcome
+	for _, s := range subs {
+		summary, err := s.call(ctx, e)
+		if err != nil {
+			log.Printf("Error handling %s event in %s: %v", e.Kind, s.name, err)
+		}
+		if summary != "" || err != nil {
+			outcomes = append(outcomes, Outcome{Name: s.name, Summary: summary, Err: err})
+		}
+	}
+	return outcomes
+}
+
+// call runs the handler, turning a panic into an error so one broken
+// integration can't take the run down.
+func (s subscription) call(ctx context.Context, e Event) (summary string, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("handler panicked: %v", v)
+		}
+	}()
+	return s.handle(ctx, e)
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublishRunsSubscribersOfKind(t *testing.T) {
+	b := NewBus()
+	var calls []string
+	b.Subscribe(BriefingComposed, "first", func(_ context.Context, e Event) (string, error) {
+		calls = append(calls, "first "+e.Edition)
+		return "", nil
+	})
+	b.Subscribe(BriefingComposed, "second", func(context.Context, Event) (string, error) {
+		calls = append(calls, "second")
+		return "", errors.New("target down")
+	})
+	b.Subscribe(BriefingComposed, "third", func(context.Context, Event) (string, error) {
+		panic("broken")
+	})
+	b.Subscribe(TriageCompleted, "other", func(context.Context, Event) (string, error) {
+		calls = append(calls, "other")
+		return "", nil
+	})
+
+	outcomes := b.Publish(context.Background(), Event{Kind: BriefingComposed, PeriodID: "2026-02-06", Edition: "morning"})
+	if len(calls) != 2 || calls[0] != "first morning" || calls[1] != "second" {
+		t.Errorf("expected the briefing subscribers in order, got %v", calls)
+	}
+	// Handlers without a summary or error report nothing.
+	if len(outcomes) != 2 || outcomes[0].Name != "second" || outcomes[1].Name != "third" || outcomes[1].Err == nil {
+		t.Errorf("expected outcomes of the failing handlers, got %+v", outcomes)
+	}
+
+	var nilBus *Bus
+	if nilBus.Publish(context.Background(), Event{Kind: BriefingComposed}) != nil {
+		t.Error("expected a nil bus to drop events")
+	}
+}
+
+func TestWebhookPostsEvent(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "Test/1.0" {
+			t.Errorf("expected the configured User-Agent, got %q", ua)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	e := Event{Kind: ArticleCollected, PeriodID: "2026-02-06", ArticleIDs: []int64{3, 4}}
+	if err := NewWebhook(srv.URL, "Test/1.0").Post(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Kind != ArticleCollected || got.PeriodID != "2026-02-06" || len(got.ArticleIDs) != 2 {
+		t.Errorf("expected the event as JSON, got %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := NewWebhook(failing.URL, "Test/1.0").Post(context.Background(), e); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+}
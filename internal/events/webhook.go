@@ -0,0 +1,47 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	URL       string
+	client    *http.Client
+	userAgent string
+}
+
+// NewWebhook creates a webhook posting to url with userAgent.
+func NewWebhook(url, userAgent string) *Webhook {
+	return &Webhook{URL: url, client: &http.Client{Timeout: 15 * time.Second}, userAgent: userAgent}
+}
+
+// Post sends e as the JSON body of a POST request. Any 2xx response counts
+// as delivered.
+func (w *Webhook) Post(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", w.userAgent)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: HTTP %d", w.URL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package fetch
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is how pages of a domain are fetched signed in, for sites the
+// reader subscribes to: request headers, such as an Authorization token,
+// and the cookies of a cookie file exported from a signed-in browser.
+type Secret struct {
+	Headers    map[string]string `yaml:"headers"`
+	CookieFile string            `yaml:"cookie_file"` // Netscape cookies.txt; relative to the secrets file
+
+	cookies []fileCookie
+}
+
+// LoadSecrets reads a secrets file: the Secret of each domain, keyed by
+// the domain, and applying to its subdomains as well. The cookie files it
+// names are read with it. A file others may read is loaded with a warning.
+func LoadSecrets(path string) (map[string]Secret, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		log.Printf("Warning: %s can be read by other users; chmod 600 it", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var secrets map[string]Secret
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for domain, s := range secrets {
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			return nil, fmt.Errorf("%s: %q is not a domain, such as example.com", path, domain)
+		}
+		for name, value := range s.Headers {
+			if name == "" || strings.ContainsAny(name, ": \t\r\n") || strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("%s: invalid header %q of %s", path, name, domain)
+			}
+		}
+		if s.CookieFile == "" {
+			continue
+		}
+		cookiePath := s.CookieFile
+		if !filepath.IsAbs(cookiePath) {
+			cookiePath = filepath.Join(filepath.Dir(path), cookiePath)
+		}
+		if s.cookies, err = readCookieFile(cookiePath); err != nil {
+			return nil, fmt.Errorf("reading the cookie file of %s: %w", domain, err)
+		}
+		secrets[domain] = s
+	}
+	return secrets, nil
+}
+
+// fileCookie is a cookie of a cookie file.
+type fileCookie struct {
+	domain     string // lower case, without a leading dot
+	subdomains bool   // sent to the domain's subdomains too
+	path       string
+	secure     bool      // sent over https only
+	expires    time.Time // zero for a session cookie
+	name       string
+	value      string
+}
+
+// readCookieFile reads a cookie file in the Netscape cookies.txt format
+// browser extensions and curl export: one cookie per line, its domain,
+// whether subdomains get it, its path, whether it is secure, when it
+// expires (a Unix time, 0 for the session), its name and its value,
+// separated by tabs.
+func readCookieFile(path string) ([]fileCookie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cookies []fileCookie
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		// curl marks HttpOnly cookies with a prefix on an otherwise
+		// commented line.
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab-separated fields, got %d", path, line, len(fields))
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry %q", path, line, fields[4])
+		}
+		c := fileCookie{
+			domain:     strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			subdomains: strings.EqualFold(fields[1], "TRUE") || strings.HasPrefix(fields[0], "."),
+			path:       fields[2],
+			secure:     strings.EqualFold(fields[3], "TRUE"),
+			name:       fields[5],
+			value:      fields[6],
+		}
+		if expiry > 0 {
+			c.expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+// matches reports whether c is sent with a request to u at now, by the
+// rules a browser follows.
+func (c fileCookie) matches(u *url.URL, now time.Time) bool {
+	if !c.expires.IsZero() && !now.Before(c.expires) {
+		return false
+	}
+	if c.secure && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != c.domain && !(c.subdomains && strings.HasSuffix(host, "."+c.domain)) {
+		return false
+	}
+	path := cmp.Or(u.EscapedPath(), "/")
+	return c.path == "" || path == c.path || strings.HasPrefix(path, strings.TrimSuffix(c.path, "/")+"/")
+}
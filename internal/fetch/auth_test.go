@@ -0,0 +1,151 @@
+package fetch
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCookieFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []fileCookie
+		wantErr string
+	}{
+		{
+			name: "cookies and comments",
+			file: "# Netscape HTTP Cookie File\n\n.example.com\tTRUE\t/\tTRUE\t1900000000\tsession\tabc\r\n",
+			want: []fileCookie{{domain: "example.com", subdomains: true, path: "/", secure: true,
+				expires: time.Unix(1900000000, 0), name: "session", value: "abc"}},
+		},
+		{
+			name: "HttpOnly prefix",
+			file: "#HttpOnly_news.example.com\tFALSE\t/members\tFALSE\t0\ttoken\txyz\n",
+			want: []fileCookie{{domain: "news.example.com", path: "/members", name: "token", value: "xyz"}},
+		},
+		{
+			name: "leading dot means subdomains",
+			file: ".Example.com\tFALSE\t/\tFALSE\t0\ta\tb\n",
+			want: []fileCookie{{domain: "example.com", subdomains: true, path: "/", name: "a", value: "b"}},
+		},
+		{name: "too few fields", file: "example.com\tTRUE\t/\tTRUE\t0\tsession\n", wantErr: ":1: expected 7 tab-separated fields, got 6"},
+		{name: "spaces, not tabs", file: "# ok\nexample.com TRUE / TRUE 0 session abc\n", wantErr: ":2: expected 7 tab-separated fields, got 1"},
+		{name: "bad expiry", file: "example.com\tTRUE\t/\tTRUE\tnever\tsession\tabc\n", wantErr: `:1: invalid expiry "never"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cookies.txt")
+			os.WriteFile(path, []byte(tt.file), 0o600)
+			got, err := readCookieFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d cookies, got %+v", len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %+v, got %+v", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCookieMatches(t *testing.T) {
+	now := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	base := fileCookie{domain: "example.com", path: "/", name: "session", value: "abc"}
+	with := func(change func(*fileCookie)) fileCookie {
+		c := base
+		change(&c)
+		return c
+	}
+	tests := []struct {
+		name   string
+		cookie fileCookie
+		url    string
+		want   bool
+	}{
+		{"same domain", base, "https://example.com/post", true},
+		{"host case", base, "https://EXAMPLE.com/post", true},
+		{"subdomain not included", base, "https://news.example.com/post", false},
+		{"subdomain included", with(func(c *fileCookie) { c.subdomains = true }), "https://news.example.com/post", true},
+		{"lookalike domain", with(func(c *fileCookie) { c.subdomains = true }), "https://badexample.com/post", false},
+		{"parent of the domain", with(func(c *fileCookie) { c.domain = "news.example.com" }), "https://example.com/post", false},
+		{"secure over https", with(func(c *fileCookie) { c.secure = true }), "https://example.com/", true},
+		{"secure over http", with(func(c *fileCookie) { c.secure = true }), "http://example.com/", false},
+		{"session cookie", base, "http://example.com/", true},
+		{"not expired", with(func(c *fileCookie) { c.expires = now.Add(time.Hour) }), "https://example.com/", true},
+		{"expired", with(func(c *fileCookie) { c.expires = now.Add(-time.Hour) }), "https://example.com/", false},
+		{"expiring now", with(func(c *fileCookie) { c.expires = now }), "https://example.com/", false},
+		{"path itself", with(func(c *fileCookie) { c.path = "/members" }), "https://example.com/members", true},
+		{"below the path", with(func(c *fileCookie) { c.path = "/members" }), "https://example.com/members/post", true},
+		{"path with a slash", with(func(c *fileCookie) { c.path = "/members/" }), "https://example.com/members/post", true},
+		{"path prefix only", with(func(c *fileCookie) { c.path = "/members" }), "https://example.com/membership", false},
+		{"outside the path", with(func(c *fileCookie) { c.path = "/members" }), "https://example.com/", false},
+		{"empty path", with(func(c *fileCookie) { c.path = "" }), "https://example.com/any", true},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := tt.cookie.matches(u, now); got != tt.want {
+			t.Errorf("%s: expected %v for %s, got %v", tt.name, tt.want, tt.url, got)
+		}
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "news.txt"), []byte("#HttpOnly_.news.example\tTRUE\t/\tTRUE\t0\tsession\tabc\n"), 0o600)
+	path := filepath.Join(dir, "secrets.yaml")
+	os.WriteFile(path, []byte(`
+news.example:
+  cookie_file: news.txt
+api.example:
+  headers:
+    X-Api-Key: secret
+`), 0o600)
+	secrets, err := LoadSecrets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := secrets["news.example"].cookies; len(c) != 1 || c[0].name != "session" || !c[0].subdomains {
+		t.Errorf("expected the cookie file read relative to the secrets file, got %+v", c)
+	}
+	if h := secrets["api.example"].Headers["X-Api-Key"]; h != "secret" {
+		t.Errorf("expected the header, got %q", h)
+	}
+
+	tests := []struct {
+		name, file, wantErr string
+	}{
+		{"header with a colon", "a.example:\n  headers:\n    \"X-Key:\": v\n", `invalid header "X-Key:" of a.example`},
+		{"header with a space", "a.example:\n  headers:\n    \"X Key\": v\n", `invalid header "X Key"`},
+		{"empty header name", "a.example:\n  headers:\n    \"\": v\n", `invalid header ""`},
+		{"multi-line value", "a.example:\n  headers:\n    X-Key: \"a\\r\\nInjected: b\"\n", `invalid header "X-Key"`},
+		{"URL for a domain", "https://a.example:\n  headers:\n    X-Key: v\n", `"https://a.example" is not a domain`},
+		{"missing cookie file", "a.example:\n  cookie_file: gone.txt\n", "reading the cookie file of a.example"},
+		{"malformed cookie file", "a.example:\n  cookie_file: bad.txt\n", "expected 7 tab-separated fields"},
+		{"not YAML", "a.example: [\n", "parsing"},
+	}
+	os.WriteFile(filepath.Join(dir, "bad.txt"), []byte("a.example\tTRUE\n"), 0o600)
+	for _, tt := range tests {
+		p := filepath.Join(dir, "invalid.yaml")
+		os.WriteFile(p, []byte(tt.file), 0o600)
+		if _, err := LoadSecrets(p); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if _, err := LoadSecrets(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected a missing secrets file to fail")
+	}
+}
@@ -0,0 +1,101 @@
+package fetch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// browserNames are the Chrome and Chromium binaries FindBrowser looks for.
+var browserNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// browserPaths are where Chrome is installed outside the PATH.
+var browserPaths = []string{
+	"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	"/Applications/Chromium.app/Contents/MacOS/Chromium",
+}
+
+// maxRenders bounds the pages rendered at once; every one runs a browser.
+const maxRenders = 2
+
+// FindBrowser returns the path of a Chrome or Chromium binary on the PATH
+// or in its usual place, or "" when there is none.
+func FindBrowser() string {
+	for _, name := range browserNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	for _, path := range browserPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// browser renders pages in headless Chrome, for sites that build their
+// articles with JavaScript.
+type browser struct {
+	path      string
+	timeout   time.Duration
+	proxy     string // --proxy-server, if any
+	userAgent string
+	slots     chan struct{}
+}
+
+func newBrowser(path string, timeout time.Duration, proxy *url.URL, userAgent string) *browser {
+	b := &browser{path: path, timeout: cmp.Or(timeout, 30*time.Second), userAgent: userAgent, slots: make(chan struct{}, maxRenders)}
+	if proxy != nil {
+		// Chrome takes no credentials on the command line; a proxy that
+		// needs them only works for the plain fetches.
+		b.proxy = proxy.Scheme + "://" + proxy.Host
+	}
+	return b
+}
+
+// render returns the DOM of the page at pageURL once its scripts ran, as
+// HTML.
+func (b *browser) render(ctx context.Context, pageURL string) ([]byte, error) {
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-b.slots }()
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	args := []string{
+		"--headless=new", "--disable-gpu", "--hide-scrollbars", "--mute-audio",
+		"--no-first-run", "--disable-extensions", "--disable-dev-shm-usage",
+		// Lets scripts run for up to 10s of page time, less when they finish.
+		"--virtual-time-budget=10000",
+		"--user-agent=" + b.userAgent,
+	}
+	if b.proxy != "" {
+		args = append(args, "--proxy-server="+b.proxy)
+	}
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox") // Chrome refuses to run as root otherwise
+	}
+	args = append(args, "--dump-dom", pageURL)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.path, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("rendering %s: %w", pageURL, ctx.Err())
+		}
+		msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		return nil, fmt.Errorf("rendering %s: %v %s", pageURL, err, msg)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pageCache keeps the pages fetched on disk, one file per URL, so fetching
+// an article again within the TTL doesn't download it again. A file holds
+// the URL the page was served from, after redirects, on its first line and
+// the page below it. A nil cache keeps nothing.
+type pageCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func newPageCache(dir string, ttl time.Duration) *pageCache {
+	if dir == "" || ttl <= 0 {
+		return nil
+	}
+	return &pageCache{dir: dir, ttl: ttl}
+}
+
+// path returns the file of pageURL, in a subdirectory named after the first
+// two digits of its hash so no directory grows too large.
+func (c *pageCache) path(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+".html")
+}
+
+// get returns the cached page of pageURL and the URL it was served from,
+// unless it isn't cached or was cached longer than the TTL ago.
+func (c *pageCache) get(pageURL string) ([]byte, *url.URL, bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+	path := c.path(pageURL)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, false
+	}
+	line, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return nil, nil, false
+	}
+	served, err := url.Parse(string(line))
+	if err != nil {
+		return nil, nil, false
+	}
+	return body, served, true
+}
+
+// put caches the page of pageURL, served from served. A page that can't be
+// cached is only logged; it is fetched again next time.
+func (c *pageCache) put(pageURL string, served *url.URL, body []byte) {
+	if c == nil {
+		return
+	}
+	path := c.path(pageURL)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("Error caching %s: %v", pageURL, err)
+		return
+	}
+	// Written next to its place and renamed, so a reader never sees half
+	// a page.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".page-*")
+	if err != nil {
+		log.Printf("Error caching %s: %v", pageURL, err)
+		return
+	}
+	_, err = tmp.Write(append([]byte(served.String()+"\n"), body...))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Error caching %s: %v", pageURL, err)
+	}
+}
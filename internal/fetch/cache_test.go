@@ -0,0 +1,91 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPageCache(t *testing.T) {
+	if c := newPageCache("", time.Hour); c != nil {
+		t.Error("expected no cache without a directory")
+	}
+	if c := newPageCache(t.TempDir(), 0); c != nil {
+		t.Error("expected no cache without a TTL")
+	}
+	var none *pageCache
+	none.put("https://example.com/a", &url.URL{}, []byte("page"))
+	if _, _, ok := none.get("https://example.com/a"); ok {
+		t.Error("expected a nil cache to keep nothing")
+	}
+
+	c := newPageCache(t.TempDir(), time.Hour)
+	served, _ := url.Parse("https://example.com/a?from=feed")
+	c.put("https://example.com/a", served, []byte("first line\nsecond line"))
+
+	body, gotServed, ok := c.get("https://example.com/a")
+	if !ok || string(body) != "first line\nsecond line" || gotServed.String() != served.String() {
+		t.Errorf("expected the page and the URL it was served from, got %q from %v (%v)", body, gotServed, ok)
+	}
+	if _, _, ok := c.get("https://example.com/b"); ok {
+		t.Error("expected a page not cached to miss")
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(c.path("https://example.com/a"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := c.get("https://example.com/a"); ok {
+		t.Error("expected a page cached longer than the TTL ago to miss")
+	}
+}
+
+func TestFetchFromCache(t *testing.T) {
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+			return
+		}
+		downloads++
+		fmt.Fprint(w, articlePage)
+	}))
+	defer srv.Close()
+	f := NewContentFetcher(nil, Options{Cache: t.TempDir(), CacheTTL: time.Hour})
+
+	tests := []struct {
+		name       string
+		fresh      bool
+		wantCached bool
+		downloads  int
+	}{
+		{"first fetch", false, false, 1},
+		{"within the TTL", false, true, 1},
+		// A page fetched again after its entry was updated is downloaded, and
+		// the cache refreshed.
+		{"fresh", true, false, 2},
+		{"after a fresh fetch", false, true, 2},
+	}
+	for _, tt := range tests {
+		p, err := f.fetchArticleContent(context.Background(), srv.URL+"/old", tt.fresh)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err.reason)
+		}
+		if p.cached != tt.wantCached || downloads != tt.downloads {
+			t.Errorf("%s: expected cached %v after %d downloads, got %v after %d", tt.name, tt.wantCached, tt.downloads, p.cached, downloads)
+		}
+		if !p.usable() {
+			t.Errorf("%s: expected the article's text, got %+v", tt.name, p)
+		}
+	}
+
+	// The cached page keeps the URL it was served from, after the redirect.
+	if _, served, ok := f.cache.get(srv.URL + "/old"); !ok || served.String() != srv.URL+"/article" {
+		t.Errorf("expected the page cached as served from /article, got %v", served)
+	}
+}
@@ -0,0 +1,321 @@
+package fetch
+
+import (
+	"cmp"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/TobiSchelling/AICrawler/internal/htmltext"
+	readability "github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
+)
+
+// Extractor pulls the text of an article out of its parsed page. Every
+// extractor of a fetcher reads each page, and the text that scores best is
+// kept, so a site one of them mangles is read by another.
+type Extractor interface {
+	Name() string
+
+	// Extract returns the article's text, or "" when it finds none. The
+	// extractors of a page share doc, so Extract must not change it.
+	Extract(doc *html.Node, pageURL *url.URL) string
+}
+
+// Extractors are the extractors Options.Extractors may name, by name.
+var Extractors = map[string]Extractor{
+	"readability": readabilityExtractor{},
+	"heuristic":   heuristicExtractor{},
+	"meta":        metaExtractor{},
+}
+
+// DefaultExtractors are used when Options.Extractors names none, in order
+// of preference.
+var DefaultExtractors = []string{"readability", "heuristic", "meta"}
+
+// minText is the most text that counts as none: shorter text is a caption
+// or a cookie notice, not an article.
+const minText = 100
+
+// minScore is the score below which a text is thin, such as a page's
+// description or a few sentences of a page built by scripts: it is kept
+// when nothing reads better, but the browser and the archive are tried
+// first.
+const minScore = 4
+
+// preference is how much better than the text of an extractor earlier in
+// the list a later one's must score to replace it, so that extra words of
+// boilerplate don't win over a clean extraction.
+const preference = 1.1
+
+// extraction is the text one extractor read from a page and its score.
+type extraction struct {
+	extractor string
+	text      string
+	score     float64
+}
+
+// extract runs extractors over doc and returns the text that scores best,
+// or no text when none finds any.
+func extract(doc *html.Node, pageURL *url.URL, extractors []Extractor) extraction {
+	var best extraction
+	for _, e := range extractors {
+		text := strings.TrimSpace(e.Extract(doc, pageURL))
+		if text == "" {
+			continue
+		}
+		if s := score(text); best.text == "" || s > best.score*preference {
+			best = extraction{extractor: e.Name(), text: text, score: s}
+		}
+	}
+	return best
+}
+
+// score rates how much a text reads like an article: more words score
+// higher, on a log scale, and sentences of prose length (5 to 40 words)
+// score higher than menus, link lists and fragments, however the text is
+// laid out.
+func score(text string) float64 {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return 0
+	}
+	// A sentence ends at a full stop before a space, a capital or the end of
+	// the text: readability runs paragraphs together without a space.
+	sentences := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || unicode.IsUpper(runes[i+1]) {
+			sentences++
+		}
+	}
+	prose := 0.3
+	if sentences > 0 {
+		switch perSentence := float64(len(words)) / float64(sentences); {
+		case perSentence < 5:
+			prose = 0.5
+		case perSentence <= 40:
+			prose = 1
+		default:
+			prose = max(0.3, 40/perSentence)
+		}
+	}
+	return math.Log1p(float64(len(words))) * prose
+}
+
+// readabilityExtractor reads a page with go-readability, a port of
+// Firefox's reader view. It works on a copy of the page.
+type readabilityExtractor struct{}
+
+func (readabilityExtractor) Name() string { return "readability" }
+
+func (readabilityExtractor) Extract(doc *html.Node, pageURL *url.URL) string {
+	article, err := readability.FromDocument(doc, pageURL)
+	if err != nil {
+		return ""
+	}
+	return article.TextContent
+}
+
+// boilerplateTags hold no article text.
+var boilerplateTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "nav": true,
+	"header": true, "footer": true, "aside": true, "form": true, "button": true,
+	"select": true, "iframe": true, "svg": true, "figure": true,
+}
+
+// boilerplateNames are the words of classes and IDs that mark navigation,
+// sharing buttons, comments and ads.
+var boilerplateNames = map[string]bool{
+	"nav": true, "navbar": true, "navigation": true, "menu": true, "header": true, "footer": true,
+	"sidebar": true, "comment": true, "comments": true, "share": true, "sharing": true, "social": true,
+	"related": true, "recommended": true, "cookie": true, "cookies": true, "consent": true,
+	"banner": true, "promo": true, "newsletter": true, "subscribe": true, "breadcrumb": true,
+	"breadcrumbs": true, "ad": true, "ads": true, "advert": true, "advertisement": true, "sponsored": true,
+}
+
+// textBlocks are the elements whose text is a paragraph of an article.
+var textBlocks = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "blockquote": true, "pre": true,
+}
+
+// maxLinkShare is the most of a paragraph's text that may be link text;
+// more is a list of links.
+const maxLinkShare = 0.5
+
+// heuristicExtractor reads the paragraphs of a page's <article> (or
+// <main>, or else its body) that aren't boilerplate: outside navigation,
+// footers, sidebars and the like, and mostly not links.
+type heuristicExtractor struct{}
+
+func (heuristicExtractor) Name() string { return "heuristic" }
+
+func (heuristicExtractor) Extract(doc *html.Node, _ *url.URL) string {
+	root := largest(doc, "article")
+	if root == nil {
+		root = largest(doc, "main")
+	}
+	if root == nil {
+		root = largest(doc, "body")
+	}
+	if root == nil {
+		return ""
+	}
+	var paragraphs []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		if n != root && isBoilerplate(n) {
+			return
+		}
+		if textBlocks[n.Data] {
+			text, links := blockText(n)
+			if text != "" && float64(links) <= maxLinkShare*float64(len(text)) {
+				paragraphs = append(paragraphs, text)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// largest returns the element named tag with the most text, or nil.
+func largest(doc *html.Node, tag string) *html.Node {
+	var best *html.Node
+	bestLen := -1
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == tag {
+			if l := len(htmltext.Text(n)); l > bestLen {
+				best, bestLen = n, l
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return best
+}
+
+// isBoilerplate reports whether n holds no article text, by its tag or by
+// a word of its class or ID.
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateTags[n.Data] || htmltext.Attr(n, "aria-hidden") == "true" || htmltext.Attr(n, "role") == "navigation" {
+		return true
+	}
+	names := strings.FieldsFunc(strings.ToLower(htmltext.Attr(n, "class")+" "+htmltext.Attr(n, "id")), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, name := range names {
+		if boilerplateNames[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// blockText returns the text of a paragraph with its whitespace collapsed,
+// and how many bytes of it are link text.
+func blockText(n *html.Node) (string, int) {
+	var b strings.Builder
+	links := 0
+	var walk func(n *html.Node, inLink bool)
+	walk = func(n *html.Node, inLink bool) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			if inLink {
+				links += len(strings.TrimSpace(n.Data))
+			}
+			return
+		case n.Type == html.ElementNode && boilerplateTags[n.Data]:
+			return
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteString(" ")
+		}
+		inLink = inLink || n.Type == html.ElementNode && n.Data == "a"
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inLink)
+		}
+	}
+	walk(n, false)
+	return strings.Join(strings.Fields(b.String()), " "), links
+}
+
+// metaExtractor reads the text a page gives search engines and link
+// previews: the articleBody of its schema.org data, which many news sites
+// include in full, or else its description.
+type metaExtractor struct{}
+
+func (metaExtractor) Name() string { return "meta" }
+
+func (metaExtractor) Extract(doc *html.Node, _ *url.URL) string {
+	var body, description string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "script" && htmltext.Attr(n, "type") == "application/ld+json" && n.FirstChild != nil:
+				var data any
+				if json.Unmarshal([]byte(n.FirstChild.Data), &data) == nil {
+					if b := articleBody(data); len(b) > len(body) {
+						body = b
+					}
+				}
+			case n.Data == "meta" && description == "":
+				switch strings.ToLower(cmp.Or(htmltext.Attr(n, "property"), htmltext.Attr(n, "name"))) {
+				case "og:description", "description", "twitter:description":
+					description = strings.TrimSpace(htmltext.Attr(n, "content"))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if body != "" {
+		return body
+	}
+	return description
+}
+
+// articleBody returns the longest articleBody in schema.org data, looking
+// through @graph lists and nested objects.
+func articleBody(data any) string {
+	var longest string
+	switch v := data.(type) {
+	case map[string]any:
+		if s, ok := v["articleBody"].(string); ok {
+			longest = strings.TrimSpace(s)
+		}
+		for key, child := range v {
+			if key == "articleBody" {
+				continue
+			}
+			if s := articleBody(child); len(s) > len(longest) {
+				longest = s
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if s := articleBody(child); len(s) > len(longest) {
+				longest = s
+			}
+		}
+	}
+	return longest
+}
@@ -0,0 +1,110 @@
+package fetch
+
+import (
+	"math"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const sentence = "The model was trained on a large corpus of public code and evaluated on several benchmarks. "
+
+// Pages are small fixtures, each read best by a different extractor.
+var (
+	articlePage = "<html><head><title>A model</title></head><body><nav><a href=\"/\">Home</a></nav><article><h1>A model</h1><p>" +
+		strings.Repeat(sentence, 4) + "</p><p>" + strings.Repeat(sentence, 3) + "</p></article><footer>Copyright</footer></body></html>"
+	// The text is in a collapsed element that readability drops.
+	hiddenPage = "<html><body><div style=\"display:none\"><p>" + strings.Repeat(sentence, 6) + "</p></div></body></html>"
+	// A page built by scripts, with the article in its schema.org data.
+	schemaPage = `<html><head><meta name="description" content="A summary."><script type="application/ld+json">{"@graph": [{"@type": "NewsArticle", "articleBody": "` +
+		strings.Repeat(sentence, 5) + `"}]}</script></head><body><div id="app"></div></body></html>`
+	descriptionPage = `<html><head><meta property="og:description" content="A short summary of the article."></head><body><div id="app">Loading</div></body></html>`
+	emptyPage       = `<html><body><div id="app"></div></body></html>`
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want float64
+	}{
+		{"empty", "  ", 0},
+		{"prose", strings.Repeat(sentence, 3), math.Log1p(48)},
+		{"menu without sentences", "Home News Sport Weather Business Culture", math.Log1p(6) * 0.3},
+		{"fragments", "Yes. No. Maybe so. Read more!", math.Log1p(6) * 0.5},
+		{"paragraphs run together", strings.Repeat("This sentence has exactly eight words in it.", 8), math.Log1p(57)},
+		{"one long sentence", strings.Repeat("word ", 80) + "end.", math.Log1p(81) * 40 / 81},
+	}
+	for _, tt := range tests {
+		if got := score(tt.text); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected score %.3f, got %.3f", tt.name, tt.want, got)
+		}
+	}
+	if score(strings.Repeat(sentence, 10)) <= score(strings.Repeat(sentence, 2)) {
+		t.Error("expected more prose to score higher")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name       string
+		page       string
+		extractors []string
+		want       string
+		wantText   string
+	}{
+		{"article", articlePage, nil, "readability", "The model was trained"},
+		{"readability misses the text", hiddenPage, nil, "heuristic", "The model was trained"},
+		{"only schema.org data", schemaPage, nil, "meta", "The model was trained"},
+		{"only a description", descriptionPage, nil, "meta", "A short summary of the article."},
+		{"no text", emptyPage, nil, "", ""},
+		// Readability and the heuristic score the article within preference
+		// of each other, so the one named first is kept.
+		{"heuristic first", articlePage, []string{"heuristic", "readability"}, "heuristic", "A model\n\nThe model"},
+		{"unknown names skipped", articlePage, []string{"browser", "heuristic"}, "heuristic", "A model"},
+	}
+	pageURL, _ := url.Parse("https://example.com/news/a-model")
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := extract(doc, pageURL, namedExtractors(tt.extractors))
+		if got.extractor != tt.want {
+			t.Errorf("%s: expected the text of %q, got %q (%q)", tt.name, tt.want, got.extractor, got.text)
+		}
+		if !strings.HasPrefix(got.text, tt.wantText) {
+			t.Errorf("%s: expected text starting %q, got %q", tt.name, tt.wantText, got.text)
+		}
+	}
+}
+
+// fixedExtractor returns the same text from every page.
+type fixedExtractor struct {
+	name, text string
+}
+
+func (e fixedExtractor) Name() string                        { return e.name }
+func (e fixedExtractor) Extract(*html.Node, *url.URL) string { return e.text }
+
+func TestExtractPreference(t *testing.T) {
+	prose := strings.Repeat(sentence, 4)
+	tests := []struct {
+		name        string
+		first, then string
+		want        string
+	}{
+		{"later text within preference", prose, prose + "Share this article.", "first"},
+		{"later text much better", "Read more. Subscribe now.", prose, "then"},
+		{"later text worse", prose, "Home News Sport", "first"},
+		{"first finds nothing", "  \n", "Home News Sport", "then"},
+	}
+	for _, tt := range tests {
+		got := extract(nil, nil, []Extractor{fixedExtractor{"first", tt.first}, fixedExtractor{"then", tt.then}})
+		if got.extractor != tt.want {
+			t.Errorf("%s: expected the text of %s, got %s", tt.name, tt.want, got.extractor)
+		}
+	}
+}
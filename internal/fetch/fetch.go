@@ -1,52 +1,262 @@
 package fetch
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	readability "github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
 
+	"github.com/TobiSchelling/AICrawler/internal/config"
 	"github.com/TobiSchelling/AICrawler/internal/database"
 )
 
 // Result holds the results of a content fetch run.
 type Result struct {
-	Fetched          int
+	Fetched           int
 	AlreadyHadContent int
-	Failed           int
+	Failed            int
+	Merged            int // syndicated copies collapsed onto their canonical article
+	Retrying          int // failed transiently, to be fetched again later
+	Rendered          int // of those fetched, by the headless browser
+	Archived          int // of those fetched, from the Wayback Machine
+	Paywalled         int // showing only a teaser, not stored
+	Cached            int // of the pages read, from the cache
+	Refreshed         int // of those fetched again after their feed entry was updated, with new content
 }
 
-// ContentFetcher fetches full article text via HTTP + readability extraction.
+// Options controls how pages are fetched.
+type Options struct {
+	Timeout      time.Duration // per page; 0 means 15s
+	MaxRedirects int           // followed per page; 0 means 10
+	Workers      int           // pages fetched at once; fewer than one means one
+	PerHost      int           // of those from the same host; fewer than one means one
+	Retries      int           // times a transient failure is retried; 0 means never
+	MaxBodyBytes int64         // read of a page or snapshot; 0 means 10 MB
+
+	// Browser is the path of a Chrome or Chromium binary that renders pages
+	// whose HTML has no readable text, for sites that build their articles
+	// with JavaScript; "" renders none. BrowserTimeout bounds each render;
+	// 0 means 30s.
+	Browser        string
+	BrowserTimeout time.Duration
+
+	// Wayback reads pages that are gone, refused or without readable text
+	// from their Wayback Machine snapshots.
+	Wayback bool
+
+	// Cache is the directory fetched pages are kept in for CacheTTL, so
+	// fetching an article again within it doesn't download it again; ""
+	// or no TTL caches nothing.
+	Cache    string
+	CacheTTL time.Duration
+
+	// UserAgent is sent with every request; "" means
+	// config.DefaultUserAgent. Headers
+	// are sent with the requests to a domain, keyed by the domain, and to
+	// its subdomains; the browser and the Wayback Machine don't get them.
+	UserAgent string
+	Headers   map[string]map[string]string
+
+	// Secrets are the headers and cookies pages of a domain, keyed by the
+	// domain, and of its subdomains are fetched with to sign in, as from
+	// LoadSecrets. Their headers override the Headers of the same name.
+	Secrets map[string]Secret
+
+	// Proxy is the proxy pages, snapshots and rendered pages are fetched
+	// through; nil means the one of the HTTP_PROXY and HTTPS_PROXY
+	// variables, if any.
+	Proxy *url.URL
+
+	// Extractors names the Extractors that read each page, in order of
+	// preference; nil means DefaultExtractors.
+	Extractors []string
+}
+
+// retryBackoff is how long a page that failed transiently waits before its
+// first retry; the wait doubles for each further one.
+const retryBackoff = 30 * time.Minute
+
+// ContentFetcher fetches full article text via HTTP and the best of its
+// extractors.
 type ContentFetcher struct {
-	db     *database.DB
-	client *http.Client
+	db      *database.DB
+	client  *http.Client
+	workers int
+	perHost int
+	retries int
+	maxBody int64
+	browser *browser // nil renders no pages
+	wayback *wayback // nil reads no snapshots
+	cache   *pageCache
+
+	userAgent  string
+	headers    []domainHeaders // most specific domain last
+	extractors []Extractor
+}
+
+// domainHeaders are the extra request headers and cookies of a domain.
+type domainHeaders struct {
+	domain  string // lower case
+	headers map[string]string
+	cookies []fileCookie
 }
 
 // NewContentFetcher creates a new content fetcher.
-func NewContentFetcher(db *database.DB, timeout time.Duration) *ContentFetcher {
-	if timeout == 0 {
-		timeout = 15 * time.Second
-	}
-	return &ContentFetcher{
-		db: db,
-		client: &http.Client{
-			Timeout: timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return http.ErrUseLastResponse
-				}
-				return nil
-			},
+func NewContentFetcher(db *database.DB, opts Options) *ContentFetcher {
+	timeout := cmp.Or(opts.Timeout, 15*time.Second)
+	maxRedirects := cmp.Or(opts.MaxRedirects, 10)
+	userAgent := cmp.Or(opts.UserAgent, config.DefaultUserAgent)
+	maxBody := cmp.Or(opts.MaxBodyBytes, 10<<20)
+	var b *browser
+	if opts.Browser != "" {
+		b = newBrowser(opts.Browser, opts.BrowserTimeout, opts.Proxy, userAgent)
+	}
+	transport := http.DefaultTransport
+	if opts.Proxy != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = http.ProxyURL(opts.Proxy)
+		transport = t
+	}
+	limitRedirects := func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	var w *wayback
+	if opts.Wayback {
+		w = newWayback(&http.Client{Transport: transport, Timeout: timeout, CheckRedirect: limitRedirects}, userAgent, maxBody)
+	}
+	f := &ContentFetcher{
+		db:      db,
+		workers: max(opts.Workers, 1),
+		perHost: max(opts.PerHost, 1),
+		retries: max(opts.Retries, 0),
+		maxBody: maxBody,
+		browser: b,
+		wayback: w,
+		cache:   newPageCache(opts.Cache, opts.CacheTTL),
+
+		userAgent:  userAgent,
+		headers:    sortedHeaders(opts.Headers, opts.Secrets),
+		extractors: namedExtractors(opts.Extractors),
+	}
+	// Go forwards every header but Authorization and Cookie to wherever a
+	// page redirects, so each redirect gets the headers of its own host.
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if err := limitRedirects(req, via); err != nil {
+				return err
+			}
+			f.setHeaders(req)
+			return nil
 		},
 	}
+	return f
+}
+
+// namedExtractors returns the Extractors names names, or the
+// DefaultExtractors when it is empty. Unknown names are skipped.
+func namedExtractors(names []string) []Extractor {
+	if len(names) == 0 {
+		names = DefaultExtractors
+	}
+	var extractors []Extractor
+	for _, name := range names {
+		e, ok := Extractors[name]
+		if !ok {
+			log.Printf("Unknown extractor %q — skipping", name)
+			continue
+		}
+		extractors = append(extractors, e)
+	}
+	return extractors
+}
+
+// sortedHeaders merges the headers and secrets of domains and orders them
+// so that a domain comes after the domains it is a subdomain of, and its
+// headers override theirs.
+func sortedHeaders(byDomain map[string]map[string]string, secrets map[string]Secret) []domainHeaders {
+	merged := map[string]*domainHeaders{}
+	of := func(domain string) *domainHeaders {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "www."))
+		if merged[domain] == nil {
+			merged[domain] = &domainHeaders{domain: domain, headers: map[string]string{}}
+		}
+		return merged[domain]
+	}
+	for domain, headers := range byDomain {
+		d := of(domain)
+		for name, value := range headers {
+			d.headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	for domain, s := range secrets {
+		d := of(domain)
+		for name, value := range s.Headers {
+			d.headers[http.CanonicalHeaderKey(name)] = value
+		}
+		d.cookies = append(d.cookies, s.cookies...)
+	}
+	var sorted []domainHeaders
+	for _, d := range merged {
+		sorted = append(sorted, *d)
+	}
+	slices.SortFunc(sorted, func(a, b domainHeaders) int {
+		return cmp.Or(cmp.Compare(len(a.domain), len(b.domain)), strings.Compare(a.domain, b.domain))
+	})
+	return sorted
+}
+
+// setHeaders sets the User-Agent and the configured headers of the
+// request's host on req, and adds the cookies of its cookie files that
+// apply to the request. The configured headers of other domains, and
+// cookies, are removed first, for a redirect that carries those of the
+// page it came from.
+func (f *ContentFetcher) setHeaders(req *http.Request) {
+	req.Header.Del("Cookie")
+	for _, d := range f.headers {
+		for name := range d.headers {
+			req.Header.Del(name)
+		}
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	host := strings.ToLower(req.URL.Hostname())
+	now := time.Now()
+	for _, d := range f.headers {
+		if host == d.domain || strings.HasSuffix(host, "."+d.domain) {
+			for name, value := range d.headers {
+				req.Header.Set(name, value)
+			}
+			for _, c := range d.cookies {
+				if c.matches(req.URL, now) {
+					req.AddCookie(&http.Cookie{Name: c.name, Value: c.value})
+				}
+			}
+		}
+	}
 }
 
-// FetchMissingContent fetches content for articles that have empty content.
-func (f *ContentFetcher) FetchMissingContent(periodID *string) *Result {
+// FetchMissingContent fetches content for articles that have empty content,
+// on up to Workers pages at once but no more than PerHost from the same
+// host, so a site with many new articles isn't hit with all of them
+// together. Articles waiting for a retry are left out until it is due, and
+// articles not started when ctx is done are left for the next run.
+func (f *ContentFetcher) FetchMissingContent(ctx context.Context, periodID *string) *Result {
 	articles, err := f.db.GetArticlesNeedingFetch(periodID)
 	if err != nil {
 		log.Printf("Error getting articles needing fetch: %v", err)
@@ -57,88 +267,520 @@ func (f *ContentFetcher) FetchMissingContent(periodID *string) *Result {
 		log.Println("No articles need content fetching")
 		return &Result{}
 	}
+	return f.fetchAll(ctx, articles)
+}
+
+// RetryFailedContent fetches again the articles whose fetch failed
+// transiently, without waiting for their retry to be due and including
+// those that ran out of retries.
+func (f *ContentFetcher) RetryFailedContent(ctx context.Context, periodID *string) *Result {
+	articles, err := f.db.GetFailedFetches(periodID)
+	if err != nil {
+		log.Printf("Error getting failed fetches: %v", err)
+		return &Result{}
+	}
+
+	if len(articles) == 0 {
+		log.Println("No failed fetches to retry")
+		return &Result{}
+	}
+	return f.fetchAll(ctx, articles)
+}
+
+// RefetchUpdatedContent fetches again the articles of every period queued
+// because their feed entry was updated since they were collected, leaving
+// out those waiting for a retry. Content that changed replaces what was
+// fetched before; paywalls and failures keep it.
+func (f *ContentFetcher) RefetchUpdatedContent(ctx context.Context) *Result {
+	articles, err := f.db.GetArticlesToRefetch()
+	if err != nil {
+		log.Printf("Error getting updated articles to fetch again: %v", err)
+		return &Result{}
+	}
+
+	if len(articles) == 0 {
+		log.Println("No updated articles to fetch again")
+		return &Result{}
+	}
+	return f.fetchAll(ctx, articles)
+}
 
+// fetchAll fetches the content of articles on the worker pool. Articles
+// that have content are being fetched again after an update, and keep it
+// unless the new page has readable text.
+func (f *ContentFetcher) fetchAll(ctx context.Context, articles []database.Article) *Result {
 	result := &Result{}
-	failedDomains := make(map[string]struct{})
+	var mu sync.Mutex // guards result and failedDomains
+	failedDomains := make(map[string]*fetchError)
 
+	hosts := make(map[string]chan struct{}) // a semaphore per host
 	for _, article := range articles {
-		u, _ := url.Parse(article.URL)
-		domain := ""
-		if u != nil {
-			domain = strings.ToLower(u.Host)
+		if domain := articleDomain(article.URL); hosts[domain] == nil {
+			hosts[domain] = make(chan struct{}, f.perHost)
 		}
+	}
 
-		if _, failed := failedDomains[domain]; failed {
-			f.db.MarkArticleFetchAttempted(article.ID)
-			result.Failed++
-			continue
+	// fail records a failed fetch, to be retried when it is transient.
+	fail := func(article database.Article, err *fetchError) {
+		result.Failed++
+		if !err.transient {
+			f.db.MarkArticleFetchFailed(article.ID, err.reason)
+			return
+		}
+		retry, dbErr := f.db.ScheduleFetchRetry(article.ID, err.reason, f.retries, retryBackoff)
+		if dbErr != nil {
+			log.Printf("Error scheduling a retry of %s: %v", article.URL, dbErr)
+		} else if retry {
+			result.Retrying++
 		}
+	}
 
-		content, httpErr := f.fetchArticleContent(article.URL)
-		if httpErr != nil {
-			f.db.MarkArticleFetchAttempted(article.ID)
-			result.Failed++
-			if domain != "" {
-				failedDomains[domain] = struct{}{}
+	fetchOne := func(article database.Article) {
+		domain := articleDomain(article.URL)
+		sem := hosts[domain]
+		sem <- struct{}{}
+
+		// Checked once it is this page's turn, as the page fetched before it
+		// from the same host may just have failed. The page itself wasn't
+		// tried, so it is retried later whatever the host's error was.
+		mu.Lock()
+		hostErr := failedDomains[domain]
+		mu.Unlock()
+		if hostErr != nil {
+			<-sem
+			mu.Lock()
+			fail(article, &fetchError{reason: "not tried after " + domain + " failed: " + hostErr.reason, transient: true})
+			mu.Unlock()
+			return
+		}
+
+		refetch := article.Content != nil && *article.Content != ""
+		start := time.Now()
+		p, err := f.fetchArticleContent(ctx, article.URL, refetch)
+		took := time.Since(start)
+		<-sem
+		mu.Lock()
+		defer mu.Unlock()
+		if p.cached {
+			result.Cached++
+		}
+		if ctx.Err() != nil && !p.usable() {
+			return // cut short; left for the next run
+		}
+		if !p.cached && domain != "" {
+			f.recordAttempt(domain, p, err, took)
+		}
+		if err != nil {
+			fail(article, err)
+			if err.status != 0 && domain != "" {
+				failedDomains[domain] = err
+				log.Printf("%s for %s — skipping remaining from %s", err.reason, article.URL, domain)
+			} else {
+				log.Printf("Failed to fetch %s: %s", article.URL, err.reason)
 			}
-			log.Printf("HTTP error for %s — skipping remaining from %s", article.URL, domain)
-			continue
+			return
 		}
 
-		if content != "" {
-			f.db.UpdateArticleContent(article.ID, &content)
+		switch {
+		case p.paywalled && refetch:
+			f.db.MarkArticleFetchFailed(article.ID, "paywalled")
+			result.Paywalled++
+			log.Printf("Paywalled now, keeping the content fetched before: %s", article.URL)
+		case p.paywalled:
+			f.db.MarkArticlePaywalled(article.ID)
+			result.Paywalled++
+			log.Printf("Paywalled: %s", article.URL)
+		case p.text != "" && refetch:
+			changed, err := f.db.RefreshArticleContent(article.ID, p.text)
+			if err != nil {
+				log.Printf("Error storing the new content of %s: %v", article.URL, err)
+				break
+			}
+			result.Fetched++
+			if changed {
+				result.Refreshed++
+				log.Printf("Refreshed content for: %s (%s)", article.Title, p.extractor)
+			} else {
+				log.Printf("Content unchanged since the last fetch: %s", article.URL)
+			}
+		case p.text != "":
+			f.db.UpdateArticleContent(article.ID, &p.text)
 			result.Fetched++
-			log.Printf("Fetched content for: %s", article.Title)
-		} else {
-			f.db.MarkArticleFetchAttempted(article.ID)
-			result.Failed++
+			switch p.from {
+			case fromBrowser:
+				result.Rendered++
+			case fromArchive:
+				result.Archived++
+			}
+			log.Printf("Fetched content for: %s (%s)", article.Title, p.extractor)
+		default:
+			fail(article, &fetchError{reason: "no extractable content"})
 			log.Printf("No extractable content from: %s", article.URL)
 		}
+
+		if p.image != "" {
+			if err := f.db.SetArticleImage(article.ID, p.image); err != nil {
+				log.Printf("Error storing the lead image of %s: %v", article.URL, err)
+			}
+		}
+		if canonical := p.canonical; canonical != "" && canonical != article.URL {
+			id, err := f.db.ResolveCanonicalURL(article.ID, canonical)
+			if err != nil {
+				log.Printf("Error resolving canonical URL of %s: %v", article.URL, err)
+			} else if id != article.ID {
+				result.Merged++
+				log.Printf("Merged syndicated copy %s into %s", article.URL, canonical)
+			}
+		}
 	}
 
-	log.Printf("Content fetch complete: %d fetched, %d failed", result.Fetched, result.Failed)
+	next := make(chan database.Article)
+	var wg sync.WaitGroup
+	for range min(f.workers, len(articles)) {
+		wg.Go(func() {
+			for article := range next {
+				fetchOne(article)
+			}
+		})
+	}
+	for _, article := range interleaveDomains(articles) {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case next <- article:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		log.Printf("Content fetch stopped early: %v", err)
+	}
+
+	log.Printf("Content fetch complete: %d fetched, %d failed (%d to retry), %d merged", result.Fetched, result.Failed, result.Retrying, result.Merged)
 	return result
 }
 
-func (f *ContentFetcher) fetchArticleContent(articleURL string) (string, error) {
-	req, err := http.NewRequest("GET", articleURL, nil)
+// recordAttempt adds how reading a page of domain went, and how long it
+// took with its fallbacks, to the domain's fetch stats. Pages read from the
+// cache say nothing about the site and aren't recorded.
+func (f *ContentFetcher) recordAttempt(domain string, p page, err *fetchError, took time.Duration) {
+	attempt := database.FetchAttempt{
+		Domain:    strings.TrimPrefix(domain, "www."),
+		Paywalled: p.paywalled,
+		Rendered:  p.from == fromBrowser,
+		Archived:  p.from == fromArchive,
+		Duration:  took,
+	}
+	switch {
+	case err != nil:
+		attempt.Error = err.reason
+	case !p.paywalled && p.text == "":
+		attempt.Error = "no extractable content"
+	}
+	if dbErr := f.db.RecordFetchAttempt(attempt); dbErr != nil {
+		log.Printf("Error recording the fetch stats of %s: %v", domain, dbErr)
+	}
+}
+
+// articleDomain returns the host of an article's URL in lower case, or ""
+// when it has none.
+func articleDomain(articleURL string) string {
+	u, err := url.Parse(articleURL)
 	if err != nil {
-		return "", err
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// interleaveDomains returns articles in the order they are handed to
+// workers: one article of each domain in turn, so workers waiting for a
+// busy host don't hold up the articles of the others.
+func interleaveDomains(articles []database.Article) []database.Article {
+	var domains []string
+	byDomain := make(map[string][]database.Article)
+	for _, a := range articles {
+		domain := articleDomain(a.URL)
+		if byDomain[domain] == nil {
+			domains = append(domains, domain)
+		}
+		byDomain[domain] = append(byDomain[domain], a)
+	}
+	order := make([]database.Article, 0, len(articles))
+	for len(order) < len(articles) {
+		for _, domain := range domains {
+			if queue := byDomain[domain]; len(queue) > 0 {
+				order = append(order, queue[0])
+				byDomain[domain] = queue[1:]
+			}
+		}
+	}
+	return order
+}
+
+// origin is where the text of a page came from.
+type origin int
+
+const (
+	fromPage    origin = iota
+	fromBrowser        // the page rendered in the headless browser
+	fromArchive        // a Wayback Machine snapshot of the page
+)
+
+// page is what is read from an article's page.
+type page struct {
+	text      string // readable text; "" when there is too little
+	extractor string // the name of the extractor that read text
+	score     float64
+	canonical string // the canonical URL the page declares, if any
+	image     string // the URL of its lead image, if any
+	paywalled bool   // the text is only the teaser of a paywalled article
+	from      origin
+	cached    bool // the page came from the cache rather than the site
+}
+
+// usable reports whether the page has an article's text. Thin text, such
+// as a description, is kept only when the browser and the archive don't
+// read better.
+func (p page) usable() bool { return p.text != "" && !p.paywalled && p.score >= minScore }
+
+// fetchArticleContent reads an article's page. A page without readable
+// text, or with only thin text, is no error, but is rendered in the
+// browser, when there is one, and read again. A page still without text,
+// paywalled, or gone or refused, is
+// read from the Wayback Machine, when that is enabled; an HTTP error is
+// only returned when the archive has no text of the page either. A fresh
+// page, of an article fetched again after an update, is downloaded even
+// when it is cached and never read from the archive, whose snapshot may
+// predate the update.
+func (f *ContentFetcher) fetchArticleContent(ctx context.Context, articleURL string, fresh bool) (page, *fetchError) {
+	var body []byte
+	var served *url.URL
+	var cached bool
+	if !fresh {
+		body, served, cached = f.cache.get(articleURL)
+	}
+	if !cached {
+		var fetchErr *fetchError
+		body, served, fetchErr = f.download(ctx, articleURL)
+		if fetchErr != nil {
+			if archivableStatus(fetchErr.status) && !fresh {
+				if archived := f.archivedPage(ctx, articleURL); archived.usable() {
+					return archived, nil
+				}
+			}
+			return page{}, fetchErr
+		}
+		f.cache.put(articleURL, served, body)
+	}
+
+	p := f.readPage(body, served)
+	p.cached = cached
+	if p.usable() {
+		return p, nil
+	}
+
+	// A paywall isn't lifted by running the page's scripts.
+	if p.text == "" && !p.paywalled && f.browser != nil && ctx.Err() == nil {
+		// Render the page we ended up on, so redirects aren't followed twice.
+		dom, err := f.browser.render(ctx, served.String())
+		if err != nil {
+			log.Printf("Browser fallback failed: %v", err)
+		} else if rendered := f.readPage(dom, served); rendered.text != "" && rendered.score > p.score {
+			rendered.canonical = cmp.Or(p.canonical, rendered.canonical)
+			rendered.image = cmp.Or(p.image, rendered.image)
+			rendered.from = fromBrowser
+			if rendered.usable() {
+				return rendered, nil
+			}
+			p = rendered
+		}
 	}
-	req.Header.Set("User-Agent", "AICrawler/1.0 (news aggregator)")
+
+	if fresh {
+		return p, nil
+	}
+	if archived := f.archivedPage(ctx, articleURL); archived.usable() {
+		archived.canonical = cmp.Or(p.canonical, archived.canonical)
+		archived.image = cmp.Or(p.image, archived.image)
+		return archived, nil
+	}
+	return p, nil
+}
+
+// download returns the page at articleURL and the URL it was served from,
+// after redirects.
+func (f *ContentFetcher) download(ctx context.Context, articleURL string) ([]byte, *url.URL, *fetchError) {
+	req, err := http.NewRequestWithContext(ctx, "GET", articleURL, nil)
+	if err != nil {
+		return nil, nil, &fetchError{reason: err.Error()}
+	}
+	f.setHeaders(req)
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return "", nil // connection error, not HTTP error
+		return nil, nil, &fetchError{reason: err.Error(), transient: true} // connection error, not HTTP error
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return "", &httpError{code: resp.StatusCode}
+		return nil, nil, &fetchError{
+			reason:    fmt.Sprintf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			transient: transientStatus(resp.StatusCode),
+			status:    resp.StatusCode,
+		}
+	}
+	if contentType := resp.Header.Get("Content-Type"); !isPage(contentType) {
+		return nil, nil, &fetchError{reason: "not a web page: " + contentType}
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, f.maxBody)
+	if errors.Is(err, errTooLarge) {
+		return nil, nil, &fetchError{reason: fmt.Sprintf("page larger than %.3g MB", float64(f.maxBody)/(1<<20))}
+	}
 	if err != nil {
-		return "", nil
+		return nil, nil, &fetchError{reason: err.Error(), transient: true}
 	}
+	return body, resp.Request.URL, nil
+}
+
+// errTooLarge is returned by readBody for a body over its limit.
+var errTooLarge = errors.New("body too large")
 
-	parsedURL, _ := url.Parse(articleURL)
-	article, err := readability.FromReader(strings.NewReader(string(bodyBytes)), parsedURL)
+// readBody reads the body of resp, up to maxBytes of it: a video or a
+// download linked as an article isn't pulled into memory. A body declared
+// larger isn't read at all.
+func readBody(resp *http.Response, maxBytes int64) ([]byte, error) {
+	if resp.ContentLength > maxBytes {
+		return nil, errTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
-		return "", nil
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, errTooLarge
 	}
+	return body, nil
+}
+
+// isPage reports whether a response of contentType may hold an article's
+// text: HTML, XML or plain text, or no declared type, which is read as
+// HTML.
+func isPage(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true // a malformed header says nothing about the body
+	}
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/xhtml+xml" ||
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
 
-	text := strings.TrimSpace(article.TextContent)
-	if len(text) > 100 {
-		return text, nil
+// archivedPage reads the Wayback Machine's latest snapshot of a page, or
+// returns no page when the fallback is off or fails.
+func (f *ContentFetcher) archivedPage(ctx context.Context, articleURL string) page {
+	if f.wayback == nil || ctx.Err() != nil {
+		return page{}
+	}
+	body, err := f.wayback.page(ctx, articleURL)
+	if err != nil {
+		log.Printf("Wayback Machine fallback failed: %v", err)
+	}
+	// The snapshot is the page as it was served, so its relative links
+	// resolve against the page's own URL.
+	base, err := url.Parse(articleURL)
+	if body == nil || err != nil {
+		return page{}
+	}
+	p := f.readPage(body, base)
+	p.from = fromArchive
+	return p
+}
+
+// readPage reads an article's page at pageURL: the text of the extractor
+// that reads it best, too little of which counts as none, the canonical
+// URL and lead image it declares and whether the text is a paywall's
+// teaser. Relative links resolve against the page we ended up on.
+func (f *ContentFetcher) readPage(body []byte, pageURL *url.URL) page {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return page{}
+	}
+	p := page{canonical: canonicalURL(doc, pageURL), image: leadImage(doc, pageURL)}
+
+	best := extract(doc, pageURL, f.extractors)
+	p.paywalled = isTeaser(best.text, hasPaywallMarkup(doc))
+	if len(best.text) > minText {
+		p.text, p.extractor, p.score = best.text, best.extractor, best.score
+	}
+	return p
+}
+
+// canonicalURL returns the absolute http(s) URL of the first
+// <link rel="canonical"> in doc, or "" if there is none. Canonical links to a
+// site's front page are ignored: misconfigured sites declare them on every
+// article.
+func canonicalURL(doc *html.Node, base *url.URL) string {
+	var href string
+	var find func(*html.Node) bool
+	find = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, h string
+			for _, a := range n.Attr {
+				switch strings.ToLower(a.Key) {
+				case "rel":
+					rel = a.Val
+				case "href":
+					h = a.Val
+				}
+			}
+			for _, r := range strings.Fields(strings.ToLower(rel)) {
+				if r == "canonical" && strings.TrimSpace(h) != "" {
+					href = strings.TrimSpace(h)
+					return true
+				}
+			}
+		}
+		if n.Type == html.ElementNode && n.Data == "body" {
+			return false // canonical links belong in <head>
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if find(c) {
+				return true
+			}
+		}
+		return false
+	}
+	if !find(doc) {
+		return ""
+	}
+
+	u, err := base.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	if u.Path == "" || u.Path == "/" {
+		return ""
 	}
-	return "", nil
+	u.Fragment = ""
+	return u.String()
 }
 
-type httpError struct {
-	code int
+// fetchError is why a page couldn't be fetched. A transient one, such as
+// a refused connection or a 503, is worth trying again later.
+type fetchError struct {
+	reason    string
+	transient bool
+	status    int // of the HTTP response, or 0 without one
 }
 
-func (e *httpError) Error() string {
-	return http.StatusText(e.code)
+// transientStatus reports whether an HTTP error status may clear up by
+// itself: a timeout, rate limiting or a server error.
+func transientStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooEarly ||
+		code == http.StatusTooManyRequests || code >= 500
 }
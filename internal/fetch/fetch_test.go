@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectHeaders(t *testing.T) {
+	// One server, reached as two domains: /start redirects to /end on the
+	// other one, which records the headers it gets.
+	var got http.Header
+	var port string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			to := "localhost"
+			if strings.HasPrefix(r.Host, "localhost") {
+				to = "127.0.0.1"
+			}
+			http.Redirect(w, r, "http://"+to+":"+port+"/end", http.StatusFound)
+			return
+		}
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><p>Signed in.</p></body></html>")
+	}))
+	defer srv.Close()
+	port = srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	f := NewContentFetcher(nil, Options{
+		UserAgent: "Test/1.0",
+		Headers:   map[string]map[string]string{"localhost": {"X-Team": "news"}},
+		Secrets:   map[string]Secret{"127.0.0.1": {Headers: map[string]string{"x-api-key": "secret"}}},
+	})
+	tests := []struct {
+		from         string
+		want, absent string
+	}{
+		{"127.0.0.1", "X-Team", "X-Api-Key"},
+		{"localhost", "X-Api-Key", "X-Team"},
+	}
+	for _, tt := range tests {
+		got = nil
+		if _, _, err := f.download(context.Background(), "http://"+tt.from+":"+port+"/start"); err != nil {
+			t.Fatalf("from %s: %v", tt.from, err.reason)
+		}
+		if got.Get(tt.absent) != "" {
+			t.Errorf("from %s: expected %s not forwarded across the redirect, got %q", tt.from, tt.absent, got.Get(tt.absent))
+		}
+		if got.Get(tt.want) == "" {
+			t.Errorf("from %s: expected the %s of the domain redirected to, got %v", tt.from, tt.want, got)
+		}
+		if ua := got.Get("User-Agent"); ua != "Test/1.0" {
+			t.Errorf("from %s: expected the User-Agent kept, got %q", tt.from, ua)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package fetch
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/htmltext"
+	"golang.org/x/net/html"
+)
+
+// imageMeta are the meta tags a page declares its lead image in for link
+// previews, in order of preference.
+var imageMeta = []string{"og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"}
+
+// imageBoilerplate are the elements whose images aren't the article's.
+// Unlike boilerplateTags, headers and figures often hold the lead image.
+var imageBoilerplate = map[string]bool{"nav": true, "footer": true, "aside": true, "form": true, "noscript": true, "template": true}
+
+// minImageSide is the smallest width or height, where an <img> declares
+// one, of an image that may lead an article; smaller ones are icons,
+// avatars and tracking pixels.
+const minImageSide = 200
+
+// decorativeImages are words in the URL of an image that is part of the
+// site rather than the article.
+var decorativeImages = []string{"logo", "icon", "avatar", "sprite", "pixel", "spacer", "badge", "placeholder"}
+
+// leadImage returns the absolute http(s) URL of the image a page leads
+// with: the one its link preview meta tags or schema.org data declare, or
+// else the first large enough <img> of its article, or "" when there is
+// none.
+func leadImage(doc *html.Node, pageURL *url.URL) string {
+	meta := map[string]string{}
+	var ldImage, firstImg string
+	var walk func(n *html.Node, inArticle bool)
+	walk = func(n *html.Node, inArticle bool) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				name := strings.ToLower(cmp.Or(htmltext.Attr(n, "property"), htmltext.Attr(n, "name")))
+				if _, ok := meta[name]; !ok {
+					meta[name] = strings.TrimSpace(htmltext.Attr(n, "content"))
+				}
+			case "script":
+				if ldImage == "" && htmltext.Attr(n, "type") == "application/ld+json" && n.FirstChild != nil {
+					var data any
+					if json.Unmarshal([]byte(n.FirstChild.Data), &data) == nil {
+						ldImage = schemaImage(data)
+					}
+				}
+			case "article", "main":
+				inArticle = true
+			case "img":
+				if firstImg == "" && inArticle && contentImage(n) {
+					firstImg = cmp.Or(htmltext.Attr(n, "src"), htmltext.Attr(n, "data-src"))
+				}
+			}
+			if imageBoilerplate[n.Data] {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inArticle)
+		}
+	}
+	walk(doc, false)
+
+	candidates := make([]string, 0, len(imageMeta)+2)
+	for _, name := range imageMeta {
+		candidates = append(candidates, meta[name])
+	}
+	for _, c := range append(candidates, ldImage, firstImg) {
+		if u := imageURL(c, pageURL); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// contentImage reports whether an <img> may be an article's picture rather
+// than an icon, by the size it declares and its URL.
+func contentImage(n *html.Node) bool {
+	for _, side := range []string{"width", "height"} {
+		if v, err := strconv.Atoi(htmltext.Attr(n, side)); err == nil && v < minImageSide {
+			return false
+		}
+	}
+	src := strings.ToLower(cmp.Or(htmltext.Attr(n, "src"), htmltext.Attr(n, "data-src")))
+	for _, word := range decorativeImages {
+		if strings.Contains(src, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaImage returns the first image URL of schema.org data: its image,
+// given as a URL, an ImageObject or a list of either, looking through
+// @graph lists.
+func schemaImage(data any) string {
+	switch v := data.(type) {
+	case string:
+		return v
+	case []any:
+		for _, child := range v {
+			if s := schemaImage(child); s != "" {
+				return s
+			}
+		}
+	case map[string]any:
+		if s, ok := v["url"].(string); ok && v["@type"] == "ImageObject" {
+			return s
+		}
+		if image, ok := v["image"]; ok {
+			if s := schemaImage(image); s != "" {
+				return s
+			}
+		}
+		if graph, ok := v["@graph"]; ok {
+			return schemaImage(graph)
+		}
+	}
+	return ""
+}
+
+// imageURL resolves ref against the page and returns it when it is an
+// http(s) URL, or "".
+func imageURL(ref string, pageURL *url.URL) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := pageURL.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
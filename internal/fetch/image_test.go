@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestLeadImage(t *testing.T) {
+	ld := func(data string) string {
+		return `<script type="application/ld+json">` + data + `</script>`
+	}
+	tests := []struct {
+		name       string
+		head, body string
+		want       string
+	}{
+		{"og:image first",
+			`<meta name="twitter:image" content="https://cdn.example.com/card.jpg"><meta property="og:image" content="https://cdn.example.com/lead.jpg">` + ld(`{"image": "https://cdn.example.com/schema.jpg"}`),
+			`<article><img src="/photo.jpg"></article>`,
+			"https://cdn.example.com/lead.jpg"},
+		{"twitter:image without og:image",
+			`<meta name="twitter:image" content="https://cdn.example.com/card.jpg">`, "",
+			"https://cdn.example.com/card.jpg"},
+		{"schema.org image as a URL",
+			ld(`{"@type": "NewsArticle", "image": "https://cdn.example.com/schema.jpg"}`), "",
+			"https://cdn.example.com/schema.jpg"},
+		{"schema.org ImageObject",
+			ld(`{"@type": "NewsArticle", "image": {"@type": "ImageObject", "url": "https://cdn.example.com/object.jpg", "width": 1200}}`), "",
+			"https://cdn.example.com/object.jpg"},
+		{"schema.org list of images",
+			ld(`{"@type": "NewsArticle", "image": ["https://cdn.example.com/wide.jpg", "https://cdn.example.com/square.jpg"]}`), "",
+			"https://cdn.example.com/wide.jpg"},
+		{"schema.org image in @graph",
+			ld(`{"@graph": [{"@type": "WebSite", "name": "Example"}, {"@type": "NewsArticle", "image": {"@type": "ImageObject", "url": "https://cdn.example.com/graph.jpg"}}]}`), "",
+			"https://cdn.example.com/graph.jpg"},
+		{"first content image of the article",
+			"", `<nav><img src="/menu.jpg"></nav><article><img src="/static/logo.png"><img src="/pixel.gif"><img src="/small.jpg" width="32" height="32"><img src="/photo.jpg" width="800"></article>`,
+			"https://example.com/photo.jpg"},
+		{"image outside the article",
+			"", `<div><img src="/photo.jpg"></div>`,
+			""},
+		{"relative meta image",
+			`<meta property="og:image" content="../images/lead.jpg">`, "",
+			"https://example.com/images/lead.jpg"},
+		{"protocol-relative image",
+			"", `<main><img data-src="//cdn.example.com/lazy.jpg"></main>`,
+			"https://cdn.example.com/lazy.jpg"},
+		{"data URI skipped",
+			`<meta property="og:image" content="data:image/png;base64,iVBORw0KGgo=">`, `<article><img src="/photo.jpg"></article>`,
+			"https://example.com/photo.jpg"},
+		{"no image", "", "<article><p>Text only.</p></article>", ""},
+	}
+	pageURL, _ := url.Parse("https://example.com/news/a-model")
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader("<html><head>" + tt.head + "</head><body>" + tt.body + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := leadImage(doc, pageURL); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestContentImage(t *testing.T) {
+	tests := []struct {
+		img  string
+		want bool
+	}{
+		{`<img src="/photo.jpg">`, true},
+		{`<img src="/photo.jpg" width="1200" height="630">`, true},
+		{`<img src="/photo.jpg" width="1" height="1">`, false},
+		{`<img src="/photo.jpg" height="150">`, false},
+		{`<img src="/photo.jpg" width="auto">`, true},
+		{`<img src="/assets/Site-Logo.svg">`, false},
+		{`<img data-src="/authors/avatar-42.jpg">`, false},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.img))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := findElement(doc, "img")
+		if got := contentImage(img); got != tt.want {
+			t.Errorf("contentImage(%s): expected %v, got %v", tt.img, tt.want, got)
+		}
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/news/a-model")
+	tests := []struct {
+		ref, want string
+	}{
+		{"https://cdn.example.com/a.jpg", "https://cdn.example.com/a.jpg"},
+		{"/images/a.jpg", "https://example.com/images/a.jpg"},
+		{"a.jpg", "https://example.com/news/a.jpg"},
+		{"//cdn.example.com/a.jpg", "https://cdn.example.com/a.jpg"},
+		{"data:image/gif;base64,R0lGODlhAQABAAAAACw=", ""},
+		{"javascript:void(0)", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := imageURL(tt.ref, pageURL); got != tt.want {
+			t.Errorf("imageURL(%q): expected %q, got %q", tt.ref, tt.want, got)
+		}
+	}
+}
+
+// findElement returns the first element named tag below n, or nil.
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
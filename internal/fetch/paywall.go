@@ -0,0 +1,110 @@
+package fetch
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TobiSchelling/AICrawler/internal/htmltext"
+	"golang.org/x/net/html"
+)
+
+// maxTeaser is the most characters of readable text a paywalled page may
+// show; a page with more shows the article, whatever it says about
+// subscribing.
+const maxTeaser = 2000
+
+// notFree matches schema.org's way of marking an article as paid for.
+var notFree = regexp.MustCompile(`(?i)"isAccessibleForFree"\s*:\s*"?false`)
+
+// paywallClasses are the classes and IDs that paywall scripts put on the
+// box that replaces an article, matched as whole class names.
+var paywallClasses = []string{"paywall", "subscriber-only", "subscribers-only", "premium-content", "meteredcontent", "piano-offer", "tp-modal"}
+
+// teaserPhrases end the teaser of a paywalled article.
+var teaserPhrases = []string{
+	"subscribe to continue reading",
+	"subscribe to read",
+	"subscribe to unlock",
+	"this article is for subscribers",
+	"this article is only available to subscribers",
+	"this content is for paid subscribers",
+	"already a subscriber",
+	"sign in to continue reading",
+	"log in to continue reading",
+	"register to continue reading",
+	"become a member to read",
+	"to continue reading, please",
+	"keep reading with a",
+}
+
+// hasPaywallMarkup reports whether a page says its article is for
+// subscribers: in its structured data, its content tier or the box a
+// paywall script fills in. Many sites ship that box on every page, hidden
+// or empty until the script decides to show it, so only a visible box with
+// text in it counts.
+func hasPaywallMarkup(doc *html.Node) bool {
+	var found bool
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if found {
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "script" && htmltext.Attr(n, "type") == "application/ld+json":
+				if n.FirstChild != nil && notFree.MatchString(n.FirstChild.Data) {
+					found = true
+				}
+			case n.Data == "meta" && htmltext.Attr(n, "property") == "article:content_tier":
+				tier := strings.ToLower(htmltext.Attr(n, "content"))
+				found = tier == "locked" || tier == "metered"
+			case isHidden(n):
+				return
+			default:
+				names := strings.Fields(strings.ToLower(htmltext.Attr(n, "class") + " " + htmltext.Attr(n, "id")))
+				if slices.ContainsFunc(names, func(name string) bool { return slices.Contains(paywallClasses, name) }) {
+					found = strings.TrimSpace(htmltext.Text(n)) != ""
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return found
+}
+
+// isHidden reports whether n is kept from view by its own attributes.
+func isHidden(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "hidden" {
+			return true
+		}
+	}
+	if htmltext.Attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(htmltext.Attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// isTeaser reports whether text is the teaser of a paywalled article: short,
+// and on a page with paywall markup or ending in an invitation to subscribe.
+func isTeaser(text string, markup bool) bool {
+	if utf8.RuneCountInString(text) > maxTeaser {
+		return false
+	}
+	if markup {
+		return true
+	}
+	lower := strings.ToLower(text)
+	for _, phrase := range teaserPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package fetch
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestPaywalledPages(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    bool
+	}{
+		// A teaser of 1,200 characters in 3,600 bytes, under a paywall box.
+		{"paywall_teaser.html", true},
+		// The same box under a whole article.
+		{"paywall_long.html", false},
+		// A short free article with the hidden and empty boxes of a paywall
+		// script that shows them on other pages.
+		{"paywall_free.html", false},
+	}
+	f := NewContentFetcher(nil, Options{})
+	pageURL, _ := url.Parse("https://example.com/news/a-model")
+	for _, tt := range tests {
+		body, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.readPage(body, pageURL).paywalled; got != tt.want {
+			t.Errorf("%s: expected paywalled %v, got %v", tt.fixture, tt.want, got)
+		}
+	}
+}
+
+func TestHasPaywallMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"paywall box", `<div class="Paywall"><p>Subscribe now.</p></div>`, true},
+		{"paywall id", `<section id="tp-modal">Subscribe now.</section>`, true},
+		{"class containing paywall", `<div class="no-paywall">Free to read.</div>`, false},
+		{"empty box", `<div class="piano-offer"> </div>`, false},
+		{"hidden box", `<div class="paywall" hidden>Subscribe now.</div>`, false},
+		{"box inside a hidden element", `<div aria-hidden="true"><div class="paywall">Subscribe now.</div></div>`, false},
+		{"invisible box", `<div class="paywall" style="visibility: hidden">Subscribe now.</div>`, false},
+		{"not free", `<script type="application/ld+json">{"isAccessibleForFree": "False"}</script>`, true},
+		{"metered tier", `<meta property="article:content_tier" content="metered">`, true},
+		{"free tier", `<meta property="article:content_tier" content="free">`, false},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader("<html><head></head><body>" + tt.body + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hasPaywallMarkup(doc); got != tt.want {
+			t.Errorf("%s: expected paywall markup %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestIsTeaser(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		markup bool
+		want   bool
+	}{
+		{"short with markup", "A short intro.", true, true},
+		{"short without markup", "A short intro.", false, false},
+		{"invitation to subscribe", "A short intro. Subscribe to continue reading.", false, true},
+		{"long with markup", strings.Repeat("a", maxTeaser+1), true, false},
+		{"short in characters, long in bytes", strings.Repeat("é", maxTeaser), true, true},
+	}
+	for _, tt := range tests {
+		if got := isTeaser(tt.text, tt.markup); got != tt.want {
+			t.Errorf("%s: expected teaser %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
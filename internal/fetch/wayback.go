@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const waybackAPI = "https://archive.org/wayback/available"
+
+// archivableStatus reports whether a page answered with status may still be
+// read from an archived snapshot: it is gone or refused to us, perhaps by a
+// paywall, rather than broken for the moment.
+func archivableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden,
+		http.StatusNotFound, http.StatusGone, http.StatusUnavailableForLegalReasons:
+		return true
+	}
+	return false
+}
+
+// wayback reads pages from their snapshots in the Internet Archive's
+// Wayback Machine. It looks up one page at a time, as the archive limits
+// how fast it may be asked.
+type wayback struct {
+	client    *http.Client
+	userAgent string
+	maxBody   int64
+	slot      chan struct{}
+}
+
+func newWayback(client *http.Client, userAgent string, maxBody int64) *wayback {
+	return &wayback{client: client, userAgent: userAgent, maxBody: maxBody, slot: make(chan struct{}, 1)}
+}
+
+// page returns the latest snapshot of pageURL, or nil when the archive has
+// no snapshot of it.
+func (w *wayback) page(ctx context.Context, pageURL string) ([]byte, error) {
+	select {
+	case w.slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-w.slot }()
+
+	snapshot, err := w.snapshot(ctx, pageURL)
+	if err != nil || snapshot == "" {
+		return nil, err
+	}
+	body, contentType, err := w.get(ctx, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %w", snapshot, err)
+	}
+	if !isPage(contentType) {
+		return nil, fmt.Errorf("snapshot %s is not a web page: %s", snapshot, contentType)
+	}
+	return body, nil
+}
+
+// snapshot returns the URL of the latest snapshot of pageURL that was
+// archived with a 200, or "" when there is none. The URL asks for the page
+// as archived, without the links rewritten and the archive's toolbar.
+func (w *wayback) snapshot(ctx context.Context, pageURL string) (string, error) {
+	body, _, err := w.get(ctx, waybackAPI+"?"+url.Values{"url": {pageURL}}.Encode())
+	if err != nil {
+		return "", fmt.Errorf("looking up %s in the Wayback Machine: %w", pageURL, err)
+	}
+	var available struct {
+		ArchivedSnapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				Status    string `json:"status"`
+				Timestamp string `json:"timestamp"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.Unmarshal(body, &available); err != nil {
+		return "", fmt.Errorf("decoding the Wayback Machine's answer for %s: %w", pageURL, err)
+	}
+	closest := available.ArchivedSnapshots.Closest
+	if !closest.Available || closest.Status != "200" || closest.Timestamp == "" {
+		return "", nil
+	}
+	return "https://web.archive.org/web/" + closest.Timestamp + "id_/" + pageURL, nil
+}
+
+// get returns the body at rawURL and its content type.
+func (w *wayback) get(ctx context.Context, rawURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", w.userAgent)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	body, err := readBody(resp, w.maxBody)
+	return body, resp.Header.Get("Content-Type"), err
+}
@@ -0,0 +1,154 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// archiveTransport sends the requests for archive.org and web.archive.org
+// to a test server, and the others where they are going.
+type archiveTransport struct {
+	target *url.URL
+}
+
+func (rt archiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "archive.org" && req.URL.Host != "web.archive.org" {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	out := req.Clone(req.Context())
+	out.Host = req.URL.Host
+	out.URL.Scheme = rt.target.Scheme
+	out.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(out)
+}
+
+// fakeArchive serves the Wayback Machine's availability API from
+// snapshots, by page URL, as the closest snapshot's status, and the
+// snapshots themselves from pages, by page URL.
+func fakeArchive(t *testing.T, snapshots map[string]string, pages map[string]string) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "Test/1.0" {
+			t.Errorf("expected the archive asked with the User-Agent, got %q", ua)
+		}
+		if r.Host == "archive.org" && r.URL.Path == "/wayback/available" {
+			pageURL := r.URL.Query().Get("url")
+			status, ok := snapshots[pageURL]
+			switch {
+			case status == "garbled":
+				fmt.Fprint(w, "<html>")
+			case ok:
+				fmt.Fprintf(w, `{"archived_snapshots": {"closest": {"available": true, "status": %q, "timestamp": "20260101120000"}}}`, status)
+			default:
+				fmt.Fprint(w, `{"archived_snapshots": {}}`)
+			}
+			return
+		}
+		pageURL, ok := strings.CutPrefix(r.URL.Path, "/web/20260101120000id_/")
+		body, found := pages[pageURL]
+		if r.Host != "web.archive.org" || !ok || !found {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(pageURL, ".png") {
+			w.Header().Set("Content-Type", "image/png")
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return &http.Client{Transport: archiveTransport{target: target}}
+}
+
+func TestWaybackPage(t *testing.T) {
+	client := fakeArchive(t,
+		map[string]string{
+			"https://example.com/a":        "200",
+			"https://example.com/logo.png": "200",
+			"https://example.com/removed":  "404",
+			"https://example.com/lost":     "200",
+			"https://example.com/garbled":  "garbled",
+		},
+		map[string]string{
+			"https://example.com/a":        "<html><body>Archived</body></html>",
+			"https://example.com/logo.png": "PNG",
+		})
+	w := newWayback(client, "Test/1.0", 1<<20)
+
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "https://example.com/a", want: "<html><body>Archived</body></html>"},
+		// Archived with an error, or not at all: no snapshot, and no error.
+		{url: "https://example.com/removed"},
+		{url: "https://example.com/never"},
+		{url: "https://example.com/lost", wantErr: true},
+		{url: "https://example.com/logo.png", wantErr: true},
+		{url: "https://example.com/garbled", wantErr: true},
+	}
+	for _, tt := range tests {
+		body, err := w.page(context.Background(), tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.url, tt.wantErr, err)
+		}
+		if string(body) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.url, tt.want, body)
+		}
+	}
+}
+
+func TestArchiveFallback(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			http.NotFound(w, r)
+		case "/broken":
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		case "/empty":
+			fmt.Fprint(w, emptyPage)
+		}
+	}))
+	defer site.Close()
+
+	archived := map[string]string{}
+	for _, path := range []string{"/gone", "/broken", "/empty"} {
+		archived[site.URL+path] = articlePage
+	}
+	snapshots := map[string]string{}
+	for pageURL := range archived {
+		snapshots[pageURL] = "200"
+	}
+	f := NewContentFetcher(nil, Options{UserAgent: "Test/1.0", Wayback: true})
+	f.wayback.client = fakeArchive(t, snapshots, archived)
+
+	tests := []struct {
+		path    string
+		fresh   bool
+		want    origin
+		wantErr bool
+	}{
+		{path: "/gone", want: fromArchive},
+		{path: "/empty", want: fromArchive},
+		// A server error is no reason to think the page is gone, and a page
+		// fetched again for an update isn't read from an older snapshot.
+		{path: "/broken", wantErr: true},
+		{path: "/gone", fresh: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := f.fetchArticleContent(context.Background(), site.URL+tt.path, tt.fresh)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.path, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && (p.from != tt.want || !p.usable()) {
+			t.Errorf("%s: expected the text of the snapshot, got %+v", tt.path, p)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package golden
+
+import "github.com/TobiSchelling/AICrawler/internal/database"
+
+// PeriodID is the period Seed fills.
+const PeriodID = "2026-02-06"
+
+// Morning briefing of the fixture, as compose writes it.
+const (
+	TLDR = "- Coding agents now open their own pull requests\n- Test generators get a shared benchmark"
+	Body = "## Agents Open Pull Requests\n\nTwo vendors shipped agents that open pull requests on their own.\n\n" +
+		"**Sources:**\n- [Agent mode ships](https://vendor.example/agent-mode) — The announcement\n" +
+		"- [Hands-on with agent mode](https://news.example/agent-review)\n\n---\n\n" +
+		"## Measuring LLM Test Generators\n\nA new benchmark scores generated tests by the mutants they kill.\n\n" +
+		"**Sources:**\n- [Benchmarking LLM test generators](https://arxiv.org/abs/2602.01234)\n\n---\n\n" +
+		"## Later today\n\n- [Flaky-test triage with LLMs](https://blog.example/flaky) (Practitioner Blog): Rerun history is the best signal\n\n---\n\n" +
+		"## Upcoming\n\n- **Feb 20** — Agent mode general availability (release) [source](https://vendor.example/agent-mode)"
+)
+
+type fixtureArticle struct {
+	url, title, source, published, articleType string
+	score                                      int
+	keyPoint                                   string
+}
+
+var fixtureArticles = []fixtureArticle{
+	{"https://vendor.example/agent-mode", "Agent mode ships", "Vendor Blog", "2026-02-05", "tool_release", 4, "The agent opens a pull request per task"},
+	{"https://news.example/agent-review", "Hands-on with agent mode", "Tech News", "2026-02-06", "experience_report", 5, "Reviews still need a human"},
+	{"https://arxiv.org/abs/2602.01234", "Benchmarking LLM test generators", "arXiv", "2026-02-04", "technique", 3, "Mutation score beats coverage as a metric"},
+	{"https://blog.example/flaky", "Flaky-test triage with LLMs", "Practitioner Blog", "2026-02-06", "experience_report", 4, "Rerun history is the best signal"},
+}
+
+var fixtureSourceTypes = map[string]string{
+	"Vendor Blog":       database.SourceVendor,
+	"Tech News":         database.SourceNews,
+	"arXiv":             database.SourceAcademic,
+	"Practitioner Blog": database.SourceBlog,
+}
+
+// Seed fills db with a fixed period: typed sources, triaged articles, two
+// storylines with narratives, a relevant article no storyline took, an
+// upcoming event and a team highlight, plus the morning briefing composed
+// from them. Every value is fixed, so whatever is rendered from it is too.
+func Seed(db *database.DB) error {
+	for name, t := range fixtureSourceTypes {
+		if err := db.SetSourceType(name, t); err != nil {
+			return err
+		}
+	}
+
+	period := PeriodID
+	ids := make([]int64, len(fixtureArticles))
+	for i, a := range fixtureArticles {
+		id, err := db.InsertArticle(a.url, a.title, &a.source, &a.published, nil, &period)
+		if err != nil {
+			return err
+		}
+		reason := "Relevant to " + a.articleType
+		if err := db.InsertTriage(id, "relevant", &a.articleType, []string{a.keyPoint}, &reason, a.score); err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	agents, err := db.InsertStoryline(period, "Coding agents", ids[:2])
+	if err != nil {
+		return err
+	}
+	narrativeID, err := db.InsertStorylineNarrative(agents, period, "Agents Open Pull Requests",
+		"Two vendors shipped agents that open pull requests on their own.",
+		[]database.SourceReference{
+			{Title: "Agent mode ships", URL: fixtureArticles[0].url, Contribution: "The announcement", Role: database.RolePrimary},
+			{Title: "Hands-on with agent mode", URL: fixtureArticles[1].url, Role: database.RoleFollowUp},
+		})
+	if err != nil {
+		return err
+	}
+	if err := db.SetNarrativeHypeScore(narrativeID, 0.5); err != nil {
+		return err
+	}
+	evals, err := db.InsertStoryline(period, "Test generator evaluation", ids[2:3])
+	if err != nil {
+		return err
+	}
+	if _, err := db.InsertStorylineNarrative(evals, period, "Measuring LLM Test Generators",
+		"A new benchmark scores generated tests by the mutants they kill.",
+		[]database.SourceReference{{Title: "Benchmarking LLM test generators", URL: fixtureArticles[2].url, Role: database.RolePrimary}}); err != nil {
+		return err
+	}
+
+	if _, err := db.InsertEvent(ids[0], "Agent mode general availability", database.EventRelease, "2026-02-20"); err != nil {
+		return err
+	}
+	profile, err := db.InsertProfile("qa", "For the QA team")
+	if err != nil {
+		return err
+	}
+	if err := db.InsertBriefingHighlight(period, profile, "For the QA team", "- The benchmark gives test generators a comparable score"); err != nil {
+		return err
+	}
+	_, err = db.InsertBriefing(period, TLDR, Body, 2, len(fixtureArticles))
+	return err
+}
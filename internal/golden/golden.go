@@ -0,0 +1,56 @@
+// Package golden compares rendered briefings with golden files kept in the
+// testdata directory of the package under test, so a change to a template
+// or to how a briefing is assembled shows up as a reviewable diff. After an
+// intended change, rewrite the files of the packages that use it with
+//
+//	go test ./internal/compose ./internal/deliver ./internal/server -update
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Assert fails t unless got matches testdata/<name>.golden, reporting the
+// first line that differs. With -update it writes got to the file instead.
+func Assert(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatalf("creating testdata: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("writing %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v (run the test with -update to create it)", path, err)
+	}
+	if string(want) == string(got) {
+		return
+	}
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := range max(len(wantLines), len(gotLines)) {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			t.Errorf("%s differs at line %d (run the test with -update if the change is intended)\nwant: %q\ngot:  %q", path, i+1, w, g)
+			return
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package htmltext
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		page, want string
+	}{
+		{`<p>One <b>bold</b> word</p>`, "One bold word"},
+		{`<ul><li>First</li><li>Second</li></ul>`, "First Second"},
+		{`<div>Text<script>var x = 1;</script><style>p {}</style> after</div>`, "Text after"},
+		{`<a href="/"><img src="x.png"></a>`, ""},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(strings.Fields(Text(doc)), " "); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<a href="/post" title="">Post</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := doc.FirstChild.LastChild.FirstChild // html > body > a
+	if got := Attr(a, "href"); got != "/post" {
+		t.Errorf("expected href /post, got %q", got)
+	}
+	if got := Attr(a, "title") + Attr(a, "rel"); got != "" {
+		t.Errorf("expected empty and missing attributes to be \"\", got %q", got)
+	}
+}
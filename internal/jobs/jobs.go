@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// Handler runs one job. It returns a short summary of what it did, which the
+// queue stores as the job's result.
+type Handler func(ctx context.Context, payload json.RawMessage) (string, error)
+
+// RetryPolicy controls how often a failing job is attempted and how long the
+// queue waits between attempts. The wait doubles after every failed attempt,
+// starting at Backoff and capped at MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy suits work that talks to external services.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: time.Minute, MaxBackoff: 30 * time.Minute}
+
+// Delay returns the wait before the attempt after the given failed one
+// (1-based).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Permanent marks a handler error as one that retrying can't fix, such as
+// missing configuration: the job fails without further attempts. It returns
+// nil for a nil err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+type registration struct {
+	handler Handler
+	policy  RetryPolicy
+}
+
+// Queue runs jobs persisted in SQLite through registered handlers.
+type Queue struct {
+	db    *database.DB
+	kinds map[string]registration
+	now   func() time.Time
+
+	// observe, when set, gets the handler error of every attempt, which the
+	// queue only stores as text.
+	observe func(id int64, err error)
+}
+
+// NewQueue creates a job queue with no registered kinds.
+func NewQueue(db *database.DB) *Queue {
+	return &Queue{db: db, kinds: make(map[string]registration), now: time.Now}
+}
+
+// Register sets the handler and retry policy for a job kind.
+func (q *Queue) Register(kind string, policy RetryPolicy, h Handler) {
+	q.kinds[kind] = registration{handler: h, policy: policy}
+}
+
+// Observe makes the queue call fn after every job attempt with the job's ID
+// and the handler's error, nil when the attempt succeeded.
+func (q *Queue) Observe(fn func(id int64, err error)) {
+	q.observe = fn
+}
+
+// Enqueue adds a job of a registered kind. The payload is stored as JSON.
+func (q *Queue) Enqueue(kind string, payload any) (int64, error) {
+	reg, ok := q.kinds[kind]
+	if !ok {
+		return 0, fmt.Errorf("unknown job kind %q", kind)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("encoding %s payload: %w", kind, err)
+	}
+	return q.db.EnqueueJob(kind, string(data), reg.policy.MaxAttempts, q.now())
+}
+
+// RunNext claims and runs the next due job. It reports false when no job
+// was due.
+func (q *Queue) RunNext(ctx context.Context) (bool, error) {
+	job, err := q.db.ClaimNextJob(q.now())
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	reg, ok := q.kinds[job.Kind]
+	if !ok {
+		return true, q.db.FailJob(job.ID, "", fmt.Sprintf("unknown job kind %q", job.Kind), nil)
+	}
+
+	log.Printf("Running job %d (%s), attempt %d/%d", job.ID, job.Kind, job.Attempts, job.MaxAttempts)
+	summary, err := reg.handler(ctx, json.RawMessage(job.Payload))
+	if q.observe != nil {
+		q.observe(job.ID, err)
+	}
+	if err == nil {
+		log.Printf("Job %d (%s) done: %s", job.ID, job.Kind, summary)
+		return true, q.db.CompleteJob(job.ID, summary)
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		log.Printf("Job %d (%s) failed, not retrying: %v", job.ID, job.Kind, err)
+		return true, q.db.FailJob(job.ID, summary, err.Error(), nil)
+	}
+	if job.Attempts >= job.MaxAttempts {
+		log.Printf("Job %d (%s) failed after %d attempts: %v", job.ID, job.Kind, job.Attempts, err)
+		return true, q.db.FailJob(job.ID, summary, err.Error(), nil)
+	}
+	retryAt := q.now().Add(reg.policy.Delay(job.Attempts))
+	log.Printf("Job %d (%s) failed, retrying at %s: %v", job.ID, job.Kind, retryAt.Format(time.Kitchen), err)
+	return true, q.db.FailJob(job.ID, summary, err.Error(), &retryAt)
+}
+
+// RunPending runs jobs until none is due and returns how many ran. Retries
+// scheduled for later are left in the queue.
+func (q *Queue) RunPending(ctx context.Context) (int, error) {
+	n := 0
+	for ctx.Err() == nil {
+		ran, err := q.RunNext(ctx)
+		if err != nil {
+			return n, err
+		}
+		if !ran {
+			break
+		}
+		n++
+	}
+	return n, ctx.Err()
+}
+
+// Work runs due jobs, checking the queue every interval, until ctx is done.
+// Jobs left running by an earlier, interrupted worker are requeued first.
+func (q *Queue) Work(ctx context.Context, interval time.Duration) error {
+	if n, err := q.db.RequeueRunningJobs(); err != nil {
+		return err
+	} else if n > 0 {
+		log.Printf("Requeued %d interrupted jobs", n)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := q.RunPending(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Job queue error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRunPendingCompletesJobs(t *testing.T) {
+	db := openTestDB(t)
+	q := NewQueue(db)
+	var got []string
+	q.Register("echo", DefaultRetryPolicy, func(_ context.Context, payload json.RawMessage) (string, error) {
+		var p struct{ Text string }
+		json.Unmarshal(payload, &p)
+		got = append(got, p.Text)
+		return "echoed " + p.Text, nil
+	})
+
+	q.Enqueue("echo", map[string]string{"text": "a"})
+	id, _ := q.Enqueue("echo", map[string]string{"text": "b"})
+
+	n, err := q.RunPending(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 jobs run, got %d (%v)", n, err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("expected jobs in enqueue order, got %v", got)
+	}
+	job, _ := db.GetJob(id)
+	if job.Status != database.JobDone || job.Result == nil || *job.Result != "echoed b" || job.Attempts != 1 {
+		t.Errorf("unexpected job after run: %+v", job)
+	}
+}
+
+func TestFailingJobRetriesWithBackoff(t *testing.T) {
+	db := openTestDB(t)
+	q := NewQueue(db)
+	now := time.Date(2026, 2, 6, 9, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	calls := 0
+	q.Register("flaky", RetryPolicy{MaxAttempts: 2, Backoff: time.Minute}, func(context.Context, json.RawMessage) (string, error) {
+		calls++
+		return "", errors.New("upstream timeout")
+	})
+	id, _ := q.Enqueue("flaky", nil)
+
+	if n, _ := q.RunPending(context.Background()); n != 1 {
+		t.Fatalf("expected one attempt before the backoff, got %d", n)
+	}
+	job, _ := db.GetJob(id)
+	if job.Status != database.JobQueued || job.RunAfter != "2026-02-06 09:01:00" || *job.LastError != "upstream timeout" {
+		t.Errorf("expected job requeued a minute later, got %+v", job)
+	}
+
+	now = now.Add(time.Minute)
+	q.RunPending(context.Background())
+	job, _ = db.GetJob(id)
+	if job.Status != database.JobFailed || job.Attempts != 2 || calls != 2 {
+		t.Errorf("expected job failed after 2 attempts, got %+v (calls %d)", job, calls)
+	}
+
+	if ok, _ := db.RetryJob(id); !ok {
+		t.Fatal("expected failed job to be retryable")
+	}
+	job, _ = db.GetJob(id)
+	if job.Status != database.JobQueued || job.Attempts != 0 {
+		t.Errorf("expected retry to reset attempts, got %+v", job)
+	}
+}
+
+func TestPermanentErrorFailsWithoutRetry(t *testing.T) {
+	db := openTestDB(t)
+	q := NewQueue(db)
+	cause := errors.New("bad payload")
+	q.Register("broken", RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}, func(context.Context, json.RawMessage) (string, error) {
+		return "", Permanent(cause)
+	})
+	var observed error
+	q.Observe(func(_ int64, err error) { observed = err })
+	id, _ := q.Enqueue("broken", nil)
+
+	q.RunPending(context.Background())
+	job, _ := db.GetJob(id)
+	if job.Status != database.JobFailed || job.Attempts != 1 || *job.LastError != "bad payload" {
+		t.Errorf("expected job failed after one attempt, got %+v", job)
+	}
+	if !errors.Is(observed, cause) {
+		t.Errorf("expected the observer to get the handler error, got %v", observed)
+	}
+}
+
+func TestEnqueueUnknownKind(t *testing.T) {
+	if _, err := NewQueue(openTestDB(t)).Enqueue("missing", nil); err == nil {
+		t.Error("expected an error for an unregistered kind")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Minute, MaxBackoff: 5 * time.Minute}
+	for attempt, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 4: 5 * time.Minute, 10: 5 * time.Minute} {
+		if got := p.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
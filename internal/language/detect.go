@@ -0,0 +1,172 @@
+// Package language tells which language an article is written in and
+// translates articles into English, so triage and synthesis can work with
+// sources in other languages.
+package language
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// English is the language the briefing's prompts are written for.
+const English = "en"
+
+// names are the languages Detect tells apart, by ISO 639-1 code.
+var names = map[string]string{
+	"en": "English", "de": "German", "fr": "French", "es": "Spanish",
+	"it": "Italian", "pt": "Portuguese", "nl": "Dutch", "sv": "Swedish",
+	"pl": "Polish", "ru": "Russian", "uk": "Ukrainian", "el": "Greek",
+	"ar": "Arabic", "he": "Hebrew", "hi": "Hindi", "th": "Thai",
+	"zh": "Chinese", "ja": "Japanese", "ko": "Korean",
+}
+
+// Name returns the English name of a language, or its code when Detect
+// doesn't know it.
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// stopWords are frequent words of each language written in the Latin
+// alphabet that are rare in the others.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "for", "with", "this", "are", "was", "it", "on", "be", "have", "from", "which", "not"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "den", "ein", "eine", "auch", "sich", "auf", "für", "dem", "wird", "von", "werden"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "dans", "pour", "que", "qui", "pas", "sur", "avec", "du", "au", "sont", "cette"},
+	"es": {"el", "los", "las", "y", "es", "del", "una", "por", "para", "que", "con", "se", "su", "como", "más", "pero", "está", "son"},
+	"it": {"il", "di", "che", "della", "sono", "per", "una", "con", "del", "gli", "non", "anche", "nel", "alla", "questo", "come", "più", "è"},
+	"pt": {"o", "os", "as", "da", "do", "não", "uma", "para", "com", "que", "em", "são", "mais", "pelo", "pela", "dos", "das", "também"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "met", "voor", "zijn", "op", "ook", "die", "wordt", "aan", "naar", "worden"},
+	"sv": {"och", "att", "det", "som", "är", "för", "med", "inte", "av", "på", "till", "har", "den", "ett", "om", "kan", "men", "också"},
+	"pl": {"się", "nie", "na", "jest", "że", "jak", "ale", "oraz", "przez", "dla", "są", "jego", "który", "jako", "po", "tym", "lub", "być"},
+}
+
+// stopWordLanguage lists the languages of each stop word.
+var stopWordLanguage = func() map[string][]string {
+	m := make(map[string][]string)
+	for lang, words := range stopWords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// minStopWords is how many stop words a text needs for its language to be
+// told; titles alone rarely have enough.
+const minStopWords = 4
+
+// Detect returns the ISO 639-1 code of the language text is written in, or
+// "" when it can't tell. Texts in other alphabets are told by their script,
+// those in the Latin alphabet by their most frequent words.
+func Detect(text string) string {
+	scripts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if code := scriptLanguage(r); code != "" {
+			scripts[code]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana with Chinese characters.
+	if scripts["ja"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	for code, n := range scripts {
+		if n*2 > letters {
+			if code == "ru" && strings.ContainsAny(text, "іїєґІЇЄҐ") {
+				return "uk"
+			}
+			return code
+		}
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, lang := range stopWordLanguage[word] {
+			hits[lang]++
+		}
+	}
+	best, second := "", 0
+	for lang, n := range hits {
+		switch {
+		case n > hits[best] || n == hits[best] && lang < best:
+			second = hits[best]
+			best = lang
+		case n > second:
+			second = n
+		}
+	}
+	// The winner needs enough words and a clear lead, as languages share
+	// some ("die" is German and Dutch, "que" French, Spanish and Portuguese).
+	if hits[best] < minStopWords || hits[best]*2 < second*3 {
+		return ""
+	}
+	return best
+}
+
+// scriptLanguage returns the language a letter's script is mostly written
+// in, or "" for the Latin alphabet and scripts Detect doesn't know.
+func scriptLanguage(r rune) string {
+	switch {
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return "ja"
+	case unicode.Is(unicode.Han, r):
+		return "zh"
+	case unicode.Is(unicode.Hangul, r):
+		return "ko"
+	case unicode.Is(unicode.Cyrillic, r):
+		return "ru"
+	case unicode.Is(unicode.Greek, r):
+		return "el"
+	case unicode.Is(unicode.Arabic, r):
+		return "ar"
+	case unicode.Is(unicode.Hebrew, r):
+		return "he"
+	case unicode.Is(unicode.Devanagari, r):
+		return "hi"
+	case unicode.Is(unicode.Thai, r):
+		return "th"
+	}
+	return ""
+}
+
+// DetectArticles detects the language of the period's articles that don't
+// have one yet, from their title and content, and returns how many it
+// detected.
+func DetectArticles(db *database.DB, periodID string) (int, error) {
+	articles, err := db.GetArticlesWithoutLanguage(periodID)
+	if err != nil {
+		return 0, err
+	}
+	detected := 0
+	for _, a := range articles {
+		text := a.Title
+		if a.Content != nil {
+			text += "\n" + *a.Content
+		}
+		code := Detect(text)
+		if err := db.SetArticleLanguage(a.ID, code); err != nil {
+			log.Printf("Error storing the language of article %d: %v", a.ID, err)
+			continue
+		}
+		if code != "" {
+			detected++
+		}
+	}
+	return detected, nil
+}
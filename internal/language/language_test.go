@@ -0,0 +1,130 @@
+package language
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+type mockProvider struct {
+	response string
+	prompts  []string
+}
+
+func (m *mockProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return m.response, nil
+}
+
+func (m *mockProvider) IsConfigured() bool { return true }
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func ptr(s string) *string { return &s }
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The new model is faster than the old one, and it is available to everyone from today.", "en"},
+		{"Das neue Modell ist schneller als das alte und wird ab heute für alle verfügbar sein, sagt die Firma.", "de"},
+		{"Le nouveau modèle est plus rapide que l'ancien et il est disponible pour tous dans les prochains jours.", "fr"},
+		{"El nuevo modelo es más rápido que el anterior y está disponible para todos los usuarios desde hoy.", "es"},
+		{"Het nieuwe model is sneller dan het oude en is vanaf vandaag voor iedereen beschikbaar, zegt het bedrijf.", "nl"},
+		{"新しいモデルは以前のものより速く、今日から誰でも利用できます。", "ja"},
+		{"新模型比旧模型更快，从今天起所有人都可以使用。", "zh"},
+		{"Новая модель работает быстрее старой и доступна всем с сегодняшнего дня.", "ru"},
+		{"새 모델은 이전 모델보다 빠르며 오늘부터 누구나 사용할 수 있습니다.", "ko"},
+		{"GPT-5 released", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectArticles(t *testing.T) {
+	db := openTestDB(t)
+	period := ptr("2026-02-06")
+	en, _ := db.InsertArticle("https://a.com/1", "A new model", nil, nil,
+		ptr("The new model is faster than the old one, and it is available to everyone from today."), period)
+	de, _ := db.InsertArticle("https://a.com/2", "Ein neues Modell", nil, nil,
+		ptr("Das neue Modell ist schneller als das alte und wird ab heute für alle verfügbar sein."), period)
+	short, _ := db.InsertArticle("https://a.com/3", "GPT-5", nil, nil, nil, period)
+
+	detected, err := DetectArticles(db, "2026-02-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detected != 2 {
+		t.Errorf("expected 2 languages detected, got %d", detected)
+	}
+	for id, want := range map[int64]string{en: "en", de: "de", short: ""} {
+		a, _ := db.GetArticleByID(id)
+		if a.Language == nil || *a.Language != want {
+			t.Errorf("article %d: expected language %q, got %v", id, want, a.Language)
+		}
+	}
+
+	// Articles are detected once, until their content changes.
+	if detected, _ := DetectArticles(db, "2026-02-06"); detected != 0 {
+		t.Errorf("expected nothing left to detect, got %d", detected)
+	}
+	db.UpdateArticleContent(short, ptr("Das neue Modell ist schneller als das alte und wird ab heute für alle verfügbar sein."))
+	if detected, _ := DetectArticles(db, "2026-02-06"); detected != 1 {
+		t.Errorf("expected the refetched article detected again, got %d", detected)
+	}
+}
+
+func TestTranslateArticles(t *testing.T) {
+	db := openTestDB(t)
+	period := ptr("2026-02-06")
+	de, _ := db.InsertArticle("https://a.com/de", "Ein neues Modell", nil, nil, ptr("Das Modell ist schneller."), period)
+	fr, _ := db.InsertArticle("https://a.com/fr", "Un nouveau modèle", nil, nil, nil, period)
+	en, _ := db.InsertArticle("https://a.com/en", "A new model", nil, nil, nil, period)
+	triaged, _ := db.InsertArticle("https://a.com/de2", "Noch ein Modell", nil, nil, nil, period)
+	db.SetArticleLanguage(de, "de")
+	db.SetArticleLanguage(fr, "fr")
+	db.SetArticleLanguage(en, "en")
+	db.SetArticleLanguage(triaged, "de")
+	db.InsertTriage(triaged, "relevant", nil, nil, nil, 3)
+
+	resp, _ := json.Marshal(map[string]any{"translations": []string{"A new model", "The model is faster."}})
+	mock := &mockProvider{response: string(resp)}
+	result, err := NewTranslator(db, mock, Options{Accept: []string{"en", "de"}}).TranslateArticles(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Translated != 1 || result.Failed != 0 {
+		t.Errorf("expected only the untriaged German article translated, got %+v", result)
+	}
+	if len(mock.prompts) != 1 || !strings.Contains(mock.prompts[0], "into English") || !strings.Contains(mock.prompts[0], "Das Modell ist schneller.") {
+		t.Errorf("expected one prompt translating the German article into English, got %q", mock.prompts)
+	}
+	a, _ := db.GetArticleByID(de)
+	if a.Title != "A new model" || a.Content == nil || *a.Content != "The model is faster." || *a.Language != "de" {
+		t.Errorf("expected the translation stored in place of the German text, got %+v", a)
+	}
+
+	// Without a list of languages, the French article is translated too,
+	// and the German one isn't translated twice.
+	mock.response = `{"translations": ["A new model"]}`
+	if result, _ := NewTranslator(db, mock, Options{}).TranslateArticles(context.Background(), "2026-02-06"); result.Translated != 1 {
+		t.Errorf("expected only the French article left to translate, got %+v", result)
+	}
+}
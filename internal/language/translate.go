@@ -0,0 +1,104 @@
+package language
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+// maxTranslated is how much of an article's content is translated; triage
+// reads less and synthesis quotes excerpts, so the rest isn't worth the
+// tokens.
+const maxTranslated = 6000
+
+// Options configures translation.
+type Options struct {
+	// Accept are the languages worth translating; the others are skipped
+	// by triage anyway. Empty means all of them.
+	Accept []string
+
+	// Pool translates as many articles at once as it has slots; nil
+	// translates them one by one.
+	Pool *llm.Pool
+}
+
+// Result holds the results of a translation run.
+type Result struct {
+	Translated int
+	Failed     int // articles left in their language, tried again next run
+}
+
+// Translator translates articles written in other languages into English
+// before triage.
+type Translator struct {
+	db       *database.DB
+	provider llm.Provider
+	opts     Options
+}
+
+// NewTranslator creates a new article translator.
+func NewTranslator(db *database.DB, provider llm.Provider, opts Options) *Translator {
+	return &Translator{db: db, provider: provider, opts: opts}
+}
+
+// TranslateArticles translates the titles and content of the period's
+// untriaged articles into English, keeping the originals, and stores them
+// in their place. Articles that can't be translated stay as they are.
+func (t *Translator) TranslateArticles(ctx context.Context, periodID string) (*Result, error) {
+	if t.provider == nil {
+		return nil, llm.ErrNoProvider
+	}
+	articles, err := t.db.GetUntranslatedArticles(periodID)
+	if err != nil {
+		return nil, err
+	}
+	var pending []database.Article
+	for _, a := range articles {
+		if len(t.opts.Accept) == 0 || slices.Contains(t.opts.Accept, *a.Language) {
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) == 0 {
+		return &Result{}, nil
+	}
+
+	r := &Result{}
+	var mu sync.Mutex
+	t.opts.Pool.Run(ctx, len(pending), func(i int) {
+		err := t.translate(ctx, pending[i])
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			log.Printf("Error translating article %d from %s: %v", pending[i].ID, Name(*pending[i].Language), err)
+			r.Failed++
+			return
+		}
+		r.Translated++
+	})
+	log.Printf("Translation complete: %d translated, %d failed", r.Translated, r.Failed)
+	return r, nil
+}
+
+func (t *Translator) translate(ctx context.Context, a database.Article) error {
+	texts := []string{a.Title}
+	if a.Content != nil && *a.Content != "" {
+		content := *a.Content
+		if len(content) > maxTranslated {
+			content = content[:maxTranslated] + "..."
+		}
+		texts = append(texts, content)
+	}
+	translated, err := llm.Translate(ctx, t.provider, Name(English), texts)
+	if err != nil {
+		return err
+	}
+	var content *string
+	if len(translated) > 1 {
+		content = &translated[1]
+	}
+	return t.db.SetArticleTranslation(a.ID, translated[0], content)
+}
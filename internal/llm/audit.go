@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Exchange is one prompt sent through an AuditProvider and what came back.
+type Exchange struct {
+	Provider         string // empty when the response came from the cache
+	Model            string
+	Subject          Subject
+	Prompt           string
+	Response         string
+	Err              error
+	Latency          time.Duration
+	PromptTokens     int
+	CompletionTokens int
+	Cached           bool // answered by a CachingProvider without a call
+}
+
+// Subject identifies what a prompt is about, for the audit log.
+type Subject struct {
+	ArticleID   int64
+	StorylineID int64
+}
+
+type subjectKey struct{}
+
+// WithArticle returns a context whose LLM calls are about an article.
+func WithArticle(ctx context.Context, articleID int64) context.Context {
+	s := subjectFrom(ctx)
+	s.ArticleID = articleID
+	return context.WithValue(ctx, subjectKey{}, s)
+}
+
+// WithStoryline returns a context whose LLM calls are about a storyline.
+func WithStoryline(ctx context.Context, storylineID int64) context.Context {
+	s := subjectFrom(ctx)
+	s.StorylineID = storylineID
+	return context.WithValue(ctx, subjectKey{}, s)
+}
+
+func subjectFrom(ctx context.Context) Subject {
+	s, _ := ctx.Value(subjectKey{}).(Subject)
+	return s
+}
+
+// AuditProvider records every prompt, response, error and latency as an
+// Exchange in the meter attached to the context, so a bad briefing can be
+// traced back to the exchanges behind it. It wraps the whole provider
+// chain: the provider and model of an exchange are those that answered it,
+// after caching and failover.
+type AuditProvider struct {
+	Provider
+}
+
+// NewAuditProvider wraps p with an audit log. A nil p stays nil.
+func NewAuditProvider(p Provider) Provider {
+	if p == nil {
+		return nil
+	}
+	return &AuditProvider{Provider: p}
+}
+
+// Generate calls the wrapped provider and records the exchange.
+func (a *AuditProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	call := &Meter{}
+	start := time.Now()
+	text, err := a.Provider.Generate(WithMeter(ctx, call), prompt, maxTokens)
+	record(ctx, call, prompt, text, err, time.Since(start))
+	return text, err
+}
+
+// GenerateStream streams from the wrapped provider and records the exchange
+// once the stream ends.
+func (a *AuditProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	s, ok := a.Provider.(Streamer)
+	if !ok {
+		text, err := a.Generate(ctx, prompt, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan string, 1)
+		out <- text
+		close(out)
+		return out, nil
+	}
+
+	call := &Meter{}
+	start := time.Now()
+	chunks, err := s.GenerateStream(WithMeter(ctx, call), prompt, maxTokens)
+	if err != nil {
+		record(ctx, call, prompt, "", err, time.Since(start))
+		return nil, err
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		var text strings.Builder
+		for chunk := range chunks {
+			text.WriteString(chunk)
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+		record(ctx, call, prompt, text.String(), ctx.Err(), time.Since(start))
+	}()
+	return out, nil
+}
+
+// record adds an exchange to the meter attached to ctx, taking the provider,
+// model and tokens from the last call recorded in the call's own meter.
+func record(ctx context.Context, call *Meter, prompt, response string, err error, latency time.Duration) {
+	m := meterFrom(ctx)
+	if m == nil {
+		return
+	}
+	e := Exchange{
+		Subject:  subjectFrom(ctx),
+		Prompt:   prompt,
+		Response: response,
+		Err:      err,
+		Latency:  latency,
+	}
+	if calls := call.Calls(); len(calls) > 0 {
+		last := calls[len(calls)-1]
+		e.Provider, e.Model = last.Provider, last.Model
+		e.PromptTokens, e.CompletionTokens = last.PromptTokens, last.CompletionTokens
+	} else {
+		e.Cached = err == nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exchanges = append(m.exchanges, e)
+}
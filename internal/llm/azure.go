@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// AzureOpenAIProvider is an Azure OpenAI provider. Requests are routed to a
+// deployment rather than a model name.
+type AzureOpenAIProvider struct {
+	Endpoint   string // e.g. https://my-resource.openai.azure.com
+	Deployment string
+	APIVersion string
+	APIKey     string
+	client     *http.Client
+	schemas    schemaSupport
+}
+
+// NewAzureOpenAIProvider creates a new Azure OpenAI provider.
+func NewAzureOpenAIProvider(endpoint, deployment, apiVersion, apiKeyEnv string) *AzureOpenAIProvider {
+	return &AzureOpenAIProvider{
+		Endpoint:   endpoint,
+		Deployment: deployment,
+		APIVersion: apiVersion,
+		APIKey:     os.Getenv(apiKeyEnv),
+		client:     newClient(DefaultTimeout),
+	}
+}
+
+// IsConfigured checks if the endpoint, deployment and API key are set.
+func (a *AzureOpenAIProvider) IsConfigured() bool {
+	return a.Endpoint != "" && a.Deployment != "" && a.APIKey != ""
+}
+
+// Generate sends a prompt to the configured deployment and returns the response.
+func (a *AzureOpenAIProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	if !a.IsConfigured() {
+		return "", fmt.Errorf("Azure OpenAI endpoint, deployment or API key not configured")
+	}
+
+	return withSchema(ctx, &a.schemas, "Azure OpenAI", func(schema *Schema) (string, error) {
+		return chatCompletion(ctx, a.client, "azure", a.url(), "api-key", a.APIKey, chatBody("", systemFrom(ctx), prompt, maxTokens, temperatureFrom(ctx), schema))
+	})
+}
+
+// GenerateStream sends a prompt to the configured deployment and streams the
+// response.
+func (a *AzureOpenAIProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	if !a.IsConfigured() {
+		return nil, fmt.Errorf("Azure OpenAI endpoint, deployment or API key not configured")
+	}
+
+	return withSchema(ctx, &a.schemas, "Azure OpenAI", func(schema *Schema) (<-chan string, error) {
+		return streamChatCompletion(ctx, a.client, "azure", a.url(), map[string]string{"api-key": a.APIKey}, chatBody("", systemFrom(ctx), prompt, maxTokens, temperatureFrom(ctx), schema))
+	})
+}
+
+func (a *AzureOpenAIProvider) url() string {
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		strings.TrimRight(a.Endpoint, "/"), url.PathEscape(a.Deployment), url.QueryEscape(a.APIVersion))
+}
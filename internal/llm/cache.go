@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+// ResponseStore persists cached responses. *database.DB implements it.
+type ResponseStore interface {
+	// GetCachedResponse returns the response stored for model and promptHash
+	// within maxAge, or nil.
+	GetCachedResponse(model, promptHash string, maxAge time.Duration) (*string, error)
+	PutCachedResponse(model, promptHash, response string) error
+	// PruneCachedResponses deletes responses older than maxAge.
+	PruneCachedResponses(maxAge time.Duration) error
+}
+
+// CachingProvider answers repeated prompts from a ResponseStore instead of
+// calling the wrapped provider, so re-running a step whose input hasn't
+// changed costs nothing. Responses are keyed by the model that produced them
+// and a hash of the prompt, token limit and temperature; failed calls are not
+// cached.
+type CachingProvider struct {
+	Provider
+	Store ResponseStore
+	TTL   time.Duration
+	model string
+}
+
+// NewCachingProvider wraps p with a response cache in store, dropping entries
+// older than the configured TTL. When caching is disabled, p is returned
+// unwrapped.
+func NewCachingProvider(p Provider, store ResponseStore, cfg config.CacheConfig) Provider {
+	if p == nil || store == nil || !cfg.Enabled || cfg.TTLHours <= 0 {
+		return p
+	}
+	ttl := time.Duration(cfg.TTLHours * float64(time.Hour))
+	if err := store.PruneCachedResponses(ttl); err != nil {
+		log.Printf("Error pruning LLM cache: %v", err)
+	}
+	return &CachingProvider{Provider: p, Store: store, TTL: ttl, model: modelName(p)}
+}
+
+// Generate returns the cached response for prompt, or calls the wrapped
+// provider and caches its response.
+func (c *CachingProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	hash := promptHash(prompt, maxTokens, temperatureFrom(ctx), systemFrom(ctx))
+	if text, ok := c.lookup(hash); ok {
+		return text, nil
+	}
+	text, err := c.Provider.Generate(ctx, prompt, maxTokens)
+	if err == nil {
+		c.store(hash, text)
+	}
+	return text, err
+}
+
+// GenerateStream yields a cached response as a single chunk. Otherwise it
+// streams from the wrapped provider and caches the text once the stream
+// ends, unless ctx was cancelled first.
+func (c *CachingProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	hash := promptHash(prompt, maxTokens, temperatureFrom(ctx), systemFrom(ctx))
+	if text, ok := c.lookup(hash); ok {
+		out := make(chan string, 1)
+		out <- text
+		close(out)
+		return out, nil
+	}
+
+	s, ok := c.Provider.(Streamer)
+	if !ok {
+		text, err := c.Generate(ctx, prompt, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan string, 1)
+		out <- text
+		close(out)
+		return out, nil
+	}
+
+	chunks, err := s.GenerateStream(ctx, prompt, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		var text strings.Builder
+		for chunk := range chunks {
+			text.WriteString(chunk)
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() == nil {
+			c.store(hash, text.String())
+		}
+	}()
+	return out, nil
+}
+
+func (c *CachingProvider) lookup(hash string) (string, bool) {
+	text, err := c.Store.GetCachedResponse(c.model, hash, c.TTL)
+	if err != nil {
+		log.Printf("Error reading LLM cache: %v", err)
+		return "", false
+	}
+	if text == nil {
+		return "", false
+	}
+	return *text, true
+}
+
+func (c *CachingProvider) store(hash, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if err := c.Store.PutCachedResponse(c.model, hash, text); err != nil {
+		log.Printf("Error writing LLM cache: %v", err)
+	}
+}
+
+// promptHash hashes a request for the cache. The default temperature and an
+// empty system message are left out, so responses cached before either was
+// configurable still match.
+func promptHash(prompt string, maxTokens int, temperature float64, system string) string {
+	key := fmt.Sprintf("%d\x00%s", maxTokens, prompt)
+	if temperature != DefaultTemperature {
+		key = fmt.Sprintf("%d\x00%g\x00%s", maxTokens, temperature, prompt)
+	}
+	if system != "" {
+		key = system + "\x00" + key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// modelName identifies the model behind p for cache keys. OpenAI-compatible
+// and Ollama servers include their URL, since the same model name can be
+// served differently by different servers. A fallback chain is named by all
+// of its members.
+func modelName(p Provider) string {
+	if l, ok := p.(*LimitedProvider); ok {
+		p = l.Provider
+	}
+	if r, ok := p.(*RetryProvider); ok {
+		p = r.Provider
+	}
+	switch p := p.(type) {
+	case *OllamaProvider:
+		return "ollama:" + p.Model + "@" + p.BaseURL
+	case *OpenAIProvider:
+		return "openai:" + p.Model + "@" + p.BaseURL
+	case *ClaudeProvider:
+		return "claude:" + p.Model
+	case *GeminiProvider:
+		return "gemini:" + p.Model
+	case *AzureOpenAIProvider:
+		return "azure:" + p.Deployment + "@" + p.Endpoint
+	case *FallbackProvider:
+		return p.chainName()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// EmbedderName identifies the model behind e, for caching its embeddings.
+// It is empty for embedders whose vectors must not be cached: the TF-IDF
+// embedder weighs words by the batch it is given, and recording or replaying
+// embedders have to see every text.
+func EmbedderName(e Embedder) string {
+	switch e := e.(type) {
+	case *OllamaEmbedder:
+		return "ollama:" + e.Model + "@" + e.BaseURL
+	case *OpenAIEmbedder:
+		return "openai:" + e.Model + "@" + e.BaseURL
+	case *GeminiEmbedder:
+		return "gemini:" + e.Model
+	case *VoyageEmbedder:
+		return "voyage:" + e.Model
+	}
+	return ""
+}
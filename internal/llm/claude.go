@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	claudeBaseURL    = "https://api.anthropic.com"
+	claudeAPIVersion = "2023-06-01"
+)
+
+// ClaudeProvider is an Anthropic Messages API provider.
+type ClaudeProvider struct {
+	Model   string
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+}
+
+// NewClaudeProvider creates a new Claude provider.
+func NewClaudeProvider(model, apiKeyEnv string) *ClaudeProvider {
+	return &ClaudeProvider{
+		Model:   model,
+		APIKey:  os.Getenv(apiKeyEnv),
+		BaseURL: claudeBaseURL,
+		client:  newClient(DefaultTimeout),
+	}
+}
+
+// IsConfigured checks if the API key is set.
+func (c *ClaudeProvider) IsConfigured() bool {
+	return c.APIKey != ""
+}
+
+// Generate sends a prompt to the Messages API and returns the response text.
+func (c *ClaudeProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	if c.APIKey == "" {
+		return "", fmt.Errorf("Claude API key not configured")
+	}
+
+	body := map[string]any{
+		"model": c.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens":  maxTokens,
+		"temperature": temperatureFrom(ctx),
+	}
+	if system := systemFrom(ctx); system != "" {
+		body["system"] = system
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(c.BaseURL, "/")+"/v1/messages", bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", claudeAPIVersion)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Claude API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError("Claude", resp)
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage claudeUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text content in Claude response")
+	}
+
+	recordUsage(ctx, "claude", c.Model, result.Usage.InputTokens, result.Usage.OutputTokens)
+	return text.String(), nil
+}
+
+// claudeUsage is the token usage of a Messages API response.
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// GenerateStream sends a prompt to the Messages API and streams the response
+// text.
+func (c *ClaudeProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("Claude API key not configured")
+	}
+
+	body := map[string]any{
+		"model": c.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens":  maxTokens,
+		"temperature": temperatureFrom(ctx),
+		"stream":      true,
+	}
+	if system := systemFrom(ctx); system != "" {
+		body["system"] = system
+	}
+	headers := map[string]string{"x-api-key": c.APIKey, "anthropic-version": claudeAPIVersion}
+
+	stream, err := openStream(ctx, c.client, strings.TrimRight(c.BaseURL, "/")+"/v1/messages", headers, body, "Claude")
+	if err != nil {
+		return nil, err
+	}
+	// message_start reports the input tokens, the final message_delta the
+	// output tokens
+	var inputTokens int
+	return streamChunks(ctx, stream, true, "Claude", func(data []byte) (string, bool, error) {
+		var event struct {
+			Type  string `json:"type"`
+			Delta struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"delta"`
+			Message struct {
+				Usage claudeUsage `json:"usage"`
+			} `json:"message"`
+			Usage claudeUsage `json:"usage"`
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return "", false, fmt.Errorf("decoding event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			inputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			recordUsage(ctx, "claude", c.Model, inputTokens, event.Usage.OutputTokens)
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				return event.Delta.Text, false, nil
+			}
+		case "message_stop":
+			return "", true, nil
+		case "error":
+			return "", false, fmt.Errorf("%s", event.Error.Message)
+		}
+		return "", false, nil
+	}), nil
+}
@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Errors that callers can tell apart with errors.Is, for targeted messages.
+var (
+	// ErrNoProvider means no configured LLM provider is available: Ollama
+	// isn't running or the API key variable isn't set.
+	ErrNoProvider = errors.New("no LLM provider available")
+
+	// ErrEmbedderUnavailable means articles could not be embedded with the
+	// configured embedder.
+	ErrEmbedderUnavailable = errors.New("embedder unavailable")
+
+	// ErrRateLimited matches an APIError for a 429 response.
+	ErrRateLimited = errors.New("rate limited by the provider")
+
+	// ErrUnauthorized matches an APIError for a 401 or 403 response, which
+	// usually means a missing or revoked API key.
+	ErrUnauthorized = errors.New("API key rejected by the provider")
+)
+
+// Is makes an APIError match ErrRateLimited or ErrUnauthorized by its
+// status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
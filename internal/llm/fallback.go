@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fallbackCooldown is how long a provider that failed is passed over.
+const fallbackCooldown = 5 * time.Minute
+
+// FallbackProvider tries its providers in order on every call, so a provider
+// that starts failing mid-run hands the rest of the run to the next one. A
+// provider whose call fails is passed over for Cooldown; after that it must
+// pass a health check (IsConfigured, which pings Ollama) before it is tried
+// again. When every provider is cooling down, they are tried anyway rather
+// than failing the call untried.
+type FallbackProvider struct {
+	Providers []Provider
+	Cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	downUntil []time.Time
+}
+
+// NewFallbackProvider chains providers in order of preference. A single
+// provider is returned unwrapped.
+func NewFallbackProvider(providers []Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &FallbackProvider{
+		Providers: providers,
+		Cooldown:  fallbackCooldown,
+		now:       time.Now,
+		downUntil: make([]time.Time, len(providers)),
+	}
+}
+
+// IsConfigured reports whether any provider in the chain is.
+func (f *FallbackProvider) IsConfigured() bool {
+	for _, p := range f.Providers {
+		if p.IsConfigured() {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate returns the response of the first provider that succeeds.
+func (f *FallbackProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	var text string
+	err := f.failover(ctx, func(p Provider) error {
+		var err error
+		text, err = p.Generate(ctx, prompt, maxTokens)
+		return err
+	})
+	return text, err
+}
+
+// GenerateStream streams from the first provider that opens a stream. Errors
+// after the stream has started end it early like with any Streamer; they
+// don't fail over.
+func (f *FallbackProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	var chunks <-chan string
+	err := f.failover(ctx, func(p Provider) error {
+		s, ok := p.(Streamer)
+		if !ok {
+			text, err := p.Generate(ctx, prompt, maxTokens)
+			if err != nil {
+				return err
+			}
+			out := make(chan string, 1)
+			out <- text
+			close(out)
+			chunks = out
+			return nil
+		}
+		var err error
+		chunks, err = s.GenerateStream(ctx, prompt, maxTokens)
+		return err
+	})
+	return chunks, err
+}
+
+// failover runs call with each usable provider in turn until one succeeds,
+// returning the last error when none does.
+func (f *FallbackProvider) failover(ctx context.Context, call func(Provider) error) error {
+	var err error
+	for _, i := range f.order() {
+		p := f.Providers[i]
+		if err = call(p); err == nil {
+			f.setDownUntil(i, time.Time{})
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		log.Printf("LLM provider %s failed, passing it over for %s: %v", modelName(p), f.Cooldown, err)
+		f.setDownUntil(i, f.now().Add(f.Cooldown))
+	}
+	return err
+}
+
+// order lists the indexes of the providers to try: the healthy ones in
+// order of preference or, when none is, all of them. A provider whose
+// cooldown has passed is health-checked first and cools down again if the
+// check fails.
+func (f *FallbackProvider) order() []int {
+	now := f.now()
+	var healthy []int
+	for i, p := range f.Providers {
+		until := f.getDownUntil(i)
+		if until.IsZero() {
+			healthy = append(healthy, i)
+			continue
+		}
+		if now.Before(until) {
+			continue
+		}
+		if !p.IsConfigured() {
+			f.setDownUntil(i, now.Add(f.Cooldown))
+			continue
+		}
+		log.Printf("LLM provider %s is available again", modelName(p))
+		f.setDownUntil(i, time.Time{})
+		healthy = append(healthy, i)
+	}
+	if len(healthy) > 0 {
+		return healthy
+	}
+	all := make([]int, len(f.Providers))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+func (f *FallbackProvider) getDownUntil(i int) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.downUntil[i]
+}
+
+func (f *FallbackProvider) setDownUntil(i int, t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downUntil[i] = t
+}
+
+// chainName identifies a fallback chain for cache keys by its members.
+func (f *FallbackProvider) chainName() string {
+	names := make([]string, len(f.Providers))
+	for i, p := range f.Providers {
+		names[i] = modelName(p)
+	}
+	return "fallback:" + strings.Join(names, ",")
+}
@@ -0,0 +1,240 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiProvider is a Google Gemini API provider.
+type GeminiProvider struct {
+	Model   string
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+	schemas schemaSupport
+}
+
+// NewGeminiProvider creates a new Gemini provider.
+func NewGeminiProvider(model, apiKeyEnv string) *GeminiProvider {
+	return &GeminiProvider{
+		Model:   model,
+		APIKey:  os.Getenv(apiKeyEnv),
+		BaseURL: geminiBaseURL,
+		client:  newClient(DefaultTimeout),
+	}
+}
+
+// IsConfigured checks if the API key is set.
+func (g *GeminiProvider) IsConfigured() bool {
+	return g.APIKey != ""
+}
+
+// Generate sends a prompt to Gemini and returns the response text.
+func (g *GeminiProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	if g.APIKey == "" {
+		return "", fmt.Errorf("Gemini API key not configured")
+	}
+	return withSchema(ctx, &g.schemas, "Gemini", func(schema *Schema) (string, error) {
+		return g.generate(ctx, geminiBody(systemFrom(ctx), prompt, maxTokens, temperatureFrom(ctx), schema))
+	})
+}
+
+// geminiBody builds a generateContent request for a single user prompt,
+// with system as the system instruction unless it is empty.
+func geminiBody(system, prompt string, maxTokens int, temperature float64, schema *Schema) map[string]any {
+	generation := map[string]any{
+		"maxOutputTokens": maxTokens,
+		"temperature":     temperature,
+	}
+	if schema != nil {
+		generation["responseMimeType"] = "application/json"
+		generation["responseJsonSchema"] = schema.Definition
+	}
+	body := map[string]any{
+		"contents": []map[string]any{
+			{"role": "user", "parts": []map[string]string{{"text": prompt}}},
+		},
+		"generationConfig": generation,
+	}
+	if system != "" {
+		body["systemInstruction"] = map[string]any{"parts": []map[string]string{{"text": system}}}
+	}
+	return body
+}
+
+func (g *GeminiProvider) generate(ctx context.Context, body map[string]any) (string, error) {
+	var result struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		PromptFeedback struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
+		UsageMetadata geminiUsage `json:"usageMetadata"`
+	}
+	if err := geminiPost(ctx, g.client, g.BaseURL, g.Model, "generateContent", g.APIKey, body, &result); err != nil {
+		return "", err
+	}
+
+	if result.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("Gemini blocked the prompt: %s", result.PromptFeedback.BlockReason)
+	}
+	if len(result.Candidates) == 0 {
+		return "", fmt.Errorf("no candidates in Gemini response")
+	}
+
+	var text strings.Builder
+	for _, part := range result.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty Gemini response (finish reason %s)", result.Candidates[0].FinishReason)
+	}
+	recordUsage(ctx, "gemini", g.Model, result.UsageMetadata.PromptTokenCount, result.UsageMetadata.CandidatesTokenCount)
+	return text.String(), nil
+}
+
+// geminiUsage is the token usage of a generateContent response.
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+}
+
+// GenerateStream sends a prompt to Gemini and streams the response text.
+func (g *GeminiProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	if g.APIKey == "" {
+		return nil, fmt.Errorf("Gemini API key not configured")
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", strings.TrimRight(g.BaseURL, "/"), url.PathEscape(g.Model))
+	stream, err := withSchema(ctx, &g.schemas, "Gemini", func(schema *Schema) (io.ReadCloser, error) {
+		return openStream(ctx, g.client, endpoint, map[string]string{"x-goog-api-key": g.APIKey}, geminiBody(systemFrom(ctx), prompt, maxTokens, temperatureFrom(ctx), schema), "Gemini")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return streamChunks(ctx, stream, true, "Gemini", func(data []byte) (string, bool, error) {
+		var chunk struct {
+			Candidates []struct {
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+				FinishReason string `json:"finishReason"`
+			} `json:"candidates"`
+			UsageMetadata geminiUsage `json:"usageMetadata"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return "", false, fmt.Errorf("decoding chunk: %w", err)
+		}
+		var text strings.Builder
+		for _, c := range chunk.Candidates {
+			for _, part := range c.Content.Parts {
+				text.WriteString(part.Text)
+			}
+			// Usage is cumulative; the chunk with the finish reason has the total
+			if c.FinishReason != "" {
+				recordUsage(ctx, "gemini", g.Model, chunk.UsageMetadata.PromptTokenCount, chunk.UsageMetadata.CandidatesTokenCount)
+			}
+		}
+		return text.String(), false, nil
+	}), nil
+}
+
+// GeminiEmbedder generates embeddings via the Gemini API.
+type GeminiEmbedder struct {
+	Model   string
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+}
+
+// NewGeminiEmbedder creates a new Gemini embedder.
+func NewGeminiEmbedder(model, apiKeyEnv string) *GeminiEmbedder {
+	return &GeminiEmbedder{
+		Model:   model,
+		APIKey:  os.Getenv(apiKeyEnv),
+		BaseURL: geminiBaseURL,
+		client:  newClient(DefaultTimeout),
+	}
+}
+
+// Embed generates embeddings for the given texts in one batch request.
+func (e *GeminiEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.APIKey == "" {
+		return nil, fmt.Errorf("Gemini API key not configured")
+	}
+
+	requests := make([]map[string]any, len(texts))
+	for i, text := range texts {
+		requests[i] = map[string]any{
+			"model":   "models/" + e.Model,
+			"content": map[string]any{"parts": []map[string]string{{"text": text}}},
+		}
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := geminiPost(ctx, e.client, e.BaseURL, e.Model, "batchEmbedContents", e.APIKey, map[string]any{"requests": requests}, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("Gemini returned %d embeddings for %d texts", len(result.Embeddings), len(texts))
+	}
+
+	embeddings := make([][]float64, len(result.Embeddings))
+	for i, emb := range result.Embeddings {
+		embeddings[i] = emb.Values
+	}
+	return embeddings, nil
+}
+
+// geminiPost calls a model method such as generateContent and decodes the
+// JSON response into out.
+func geminiPost(ctx context.Context, client *http.Client, baseURL, model, method, apiKey string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:%s", strings.TrimRight(baseURL, "/"), url.PathEscape(model), method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Gemini API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("Gemini", resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
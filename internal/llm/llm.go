@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
 )
 
 // Provider is the interface for LLM providers.
@@ -19,6 +22,69 @@ type Provider interface {
 	IsConfigured() bool
 }
 
+// DefaultTemperature is the sampling temperature of calls whose context
+// doesn't set one.
+const DefaultTemperature = 0.3
+
+type temperatureKey struct{}
+
+// WithTemperature returns a context whose LLM calls sample at temperature t.
+func WithTemperature(ctx context.Context, t float64) context.Context {
+	return context.WithValue(ctx, temperatureKey{}, t)
+}
+
+// temperatureFrom returns the temperature attached to ctx, or
+// DefaultTemperature.
+func temperatureFrom(ctx context.Context) float64 {
+	if t, ok := ctx.Value(temperatureKey{}).(float64); ok {
+		return t
+	}
+	return DefaultTemperature
+}
+
+// DefaultAudience is who briefings are written for when no audience is
+// configured.
+const DefaultAudience = "software practitioners"
+
+type systemKey struct{}
+
+// WithSystem returns a context whose LLM calls send system as the system
+// message, ahead of the prompt.
+func WithSystem(ctx context.Context, system string) context.Context {
+	return context.WithValue(ctx, systemKey{}, system)
+}
+
+// systemFrom returns the system message attached to ctx, or "".
+func systemFrom(ctx context.Context) string {
+	s, _ := ctx.Value(systemKey{}).(string)
+	return s
+}
+
+// chatMessages builds the messages of a chat request: the system message,
+// when there is one, and the prompt as the user message.
+func chatMessages(system, prompt string) []map[string]string {
+	messages := []map[string]string{{"role": "user", "content": prompt}}
+	if system != "" {
+		messages = append([]map[string]string{{"role": "system", "content": system}}, messages...)
+	}
+	return messages
+}
+
+// DefaultTimeout is how long a request waits for an LLM API to start
+// responding when no timeout is configured.
+const DefaultTimeout = 120 * time.Second
+
+// newClient returns an HTTP client for an LLM API whose requests fail when
+// no response has started within timeout; 0 means DefaultTimeout. Only the
+// wait for the response headers is bounded, which non-streaming APIs send
+// once the text is generated, so a stream isn't cut off partway. The
+// request's context can end a call at any point.
+func newClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cmp.Or(timeout, DefaultTimeout)
+	return &http.Client{Transport: transport}
+}
+
 // Embedder is the interface for generating embeddings.
 type Embedder interface {
 	Embed(ctx context.Context, texts []string) ([][]float64, error)
@@ -29,6 +95,7 @@ type OllamaProvider struct {
 	Model   string
 	BaseURL string
 	client  *http.Client
+	schemas schemaSupport
 }
 
 // NewOllamaProvider creates a new Ollama provider.
@@ -36,7 +103,7 @@ func NewOllamaProvider(model, baseURL string) *OllamaProvider {
 	return &OllamaProvider{
 		Model:   model,
 		BaseURL: baseURL,
-		client:  &http.Client{Timeout: 120 * time.Second},
+		client:  newClient(DefaultTimeout),
 	}
 }
 
@@ -81,17 +148,24 @@ func (o *OllamaProvider) IsConfigured() bool {
 
 // Generate sends a prompt to Ollama and returns the response.
 func (o *OllamaProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	return withSchema(ctx, &o.schemas, "ollama", func(schema *Schema) (string, error) {
+		return o.generate(ctx, prompt, maxTokens, schema)
+	})
+}
+
+func (o *OllamaProvider) generate(ctx context.Context, prompt string, maxTokens int, schema *Schema) (string, error) {
 	body := map[string]any{
-		"model": o.Model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"stream": false,
+		"model":    o.Model,
+		"messages": chatMessages(systemFrom(ctx), prompt),
+		"stream":   false,
 		"options": map[string]any{
-			"num_predict":  maxTokens,
-			"temperature": 0.3,
+			"num_predict": maxTokens,
+			"temperature": temperatureFrom(ctx),
 		},
 	}
+	if schema != nil {
+		body["format"] = schema.Definition
+	}
 
 	data, err := json.Marshal(body)
 	if err != nil {
@@ -111,22 +185,67 @@ func (o *OllamaProvider) Generate(ctx context.Context, prompt string, maxTokens
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama API returned %d: %s", resp.StatusCode, string(respBody))
+		return "", newAPIError("ollama", resp)
 	}
 
 	var result struct {
 		Message struct {
 			Content string `json:"content"`
 		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", fmt.Errorf("decoding response: %w", err)
 	}
 
+	recordUsage(ctx, "ollama", o.Model, result.PromptEvalCount, result.EvalCount)
 	return result.Message.Content, nil
 }
 
+// GenerateStream sends a prompt to Ollama and streams the response.
+func (o *OllamaProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	stream, err := withSchema(ctx, &o.schemas, "ollama", func(schema *Schema) (io.ReadCloser, error) {
+		body := map[string]any{
+			"model":    o.Model,
+			"messages": chatMessages(systemFrom(ctx), prompt),
+			"stream":   true,
+			"options": map[string]any{
+				"num_predict": maxTokens,
+				"temperature": temperatureFrom(ctx),
+			},
+		}
+		if schema != nil {
+			body["format"] = schema.Definition
+		}
+		return openStream(ctx, o.client, o.BaseURL+"/api/chat", nil, body, "ollama")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return streamChunks(ctx, stream, false, "ollama", func(line []byte) (string, bool, error) {
+		var chunk struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Done            bool   `json:"done"`
+			Error           string `json:"error"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			EvalCount       int    `json:"eval_count"`
+		}
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return "", false, fmt.Errorf("decoding chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", false, fmt.Errorf("%s", chunk.Error)
+		}
+		if chunk.Done {
+			recordUsage(ctx, "ollama", o.Model, chunk.PromptEvalCount, chunk.EvalCount)
+		}
+		return chunk.Message.Content, chunk.Done, nil
+	}), nil
+}
+
 // OllamaEmbedder generates embeddings via the Ollama API.
 type OllamaEmbedder struct {
 	Model   string
@@ -139,7 +258,7 @@ func NewOllamaEmbedder(model, baseURL string) *OllamaEmbedder {
 	return &OllamaEmbedder{
 		Model:   model,
 		BaseURL: baseURL,
-		client:  &http.Client{Timeout: 120 * time.Second},
+		client:  newClient(DefaultTimeout),
 	}
 }
 
@@ -182,71 +301,136 @@ func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float64
 	return result.Embeddings, nil
 }
 
-// OpenAIProvider is an OpenAI API provider.
+// OpenAIBaseURL is the default endpoint of OpenAIProvider.
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIProvider is a provider for the OpenAI chat completions API, or any
+// compatible server (LM Studio, vLLM, llamafile, OpenRouter) via BaseURL.
 type OpenAIProvider struct {
-	Model  string
-	APIKey string
-	client *http.Client
+	Model   string
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+	schemas schemaSupport
 }
 
-// NewOpenAIProvider creates a new OpenAI provider.
-func NewOpenAIProvider(model, apiKeyEnv string) *OpenAIProvider {
+// NewOpenAIProvider creates a new OpenAI provider. An empty baseURL targets
+// api.openai.com.
+func NewOpenAIProvider(model, apiKeyEnv, baseURL string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = OpenAIBaseURL
+	}
 	return &OpenAIProvider{
-		Model:  model,
-		APIKey: os.Getenv(apiKeyEnv),
-		client: &http.Client{Timeout: 120 * time.Second},
+		Model:   model,
+		APIKey:  os.Getenv(apiKeyEnv),
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		client:  newClient(DefaultTimeout),
 	}
 }
 
-// IsConfigured checks if the API key is set.
+// IsConfigured checks if the API key is set. Custom endpoints may run
+// without one, as local servers usually do.
 func (o *OpenAIProvider) IsConfigured() bool {
-	return o.APIKey != ""
+	return o.APIKey != "" || o.isCustomEndpoint()
+}
+
+func (o *OpenAIProvider) isCustomEndpoint() bool {
+	return o.BaseURL != "" && o.BaseURL != OpenAIBaseURL
 }
 
 // Generate sends a prompt to OpenAI and returns the response.
 func (o *OpenAIProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
-	if o.APIKey == "" {
+	if !o.IsConfigured() {
 		return "", fmt.Errorf("OpenAI API key not configured")
 	}
 
+	baseURL := o.BaseURL
+	if baseURL == "" {
+		baseURL = OpenAIBaseURL
+	}
+	authHeader := ""
+	if o.APIKey != "" {
+		authHeader = "Authorization"
+	}
+	return withSchema(ctx, &o.schemas, "OpenAI", func(schema *Schema) (string, error) {
+		body := chatBody(o.Model, systemFrom(ctx), prompt, maxTokens, temperatureFrom(ctx), schema)
+		return chatCompletion(ctx, o.client, "openai", baseURL+"/chat/completions", authHeader, "Bearer "+o.APIKey, body)
+	})
+}
+
+// GenerateStream sends a prompt to OpenAI and streams the response.
+func (o *OpenAIProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	if !o.IsConfigured() {
+		return nil, fmt.Errorf("OpenAI API key not configured")
+	}
+
+	baseURL := o.BaseURL
+	if baseURL == "" {
+		baseURL = OpenAIBaseURL
+	}
+	var headers map[string]string
+	if o.APIKey != "" {
+		headers = map[string]string{"Authorization": "Bearer " + o.APIKey}
+	}
+	return withSchema(ctx, &o.schemas, "OpenAI", func(schema *Schema) (<-chan string, error) {
+		body := chatBody(o.Model, systemFrom(ctx), prompt, maxTokens, temperatureFrom(ctx), schema)
+		return streamChatCompletion(ctx, o.client, "openai", baseURL+"/chat/completions", headers, body)
+	})
+}
+
+// chatBody builds an OpenAI-style chat completion request for a single user
+// prompt, preceded by the system message unless it is empty. An empty model is left out, as Azure names it in the URL instead.
+func chatBody(model, system, prompt string, maxTokens int, temperature float64, schema *Schema) map[string]any {
 	body := map[string]any{
-		"model": o.Model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"messages":    chatMessages(system, prompt),
 		"max_tokens":  maxTokens,
-		"temperature": 0.3,
+		"temperature": temperature,
+	}
+	if model != "" {
+		body["model"] = model
+	}
+	if schema != nil {
+		body["response_format"] = schema.openAIResponseFormat()
 	}
+	return body
+}
 
+// chatCompletion posts an OpenAI-style chat completion request and returns
+// the first choice's content. authHeader and authValue carry the credential;
+// an empty authHeader sends none. provider names the service in usage records.
+func chatCompletion(ctx context.Context, client *http.Client, provider, url, authHeader, authValue string, body map[string]any) (string, error) {
 	data, err := json.Marshal(body)
 	if err != nil {
 		return "", fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
 	if err != nil {
 		return "", fmt.Errorf("creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	if authHeader != "" {
+		req.Header.Set(authHeader, authValue)
+	}
 
-	resp, err := o.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("OpenAI API error: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API returned %d: %s", resp.StatusCode, string(respBody))
+		return "", newAPIError("OpenAI", resp)
 	}
 
 	var result struct {
+		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", fmt.Errorf("decoding response: %w", err)
@@ -256,26 +440,338 @@ func (o *OpenAIProvider) Generate(ctx context.Context, prompt string, maxTokens
 		return "", fmt.Errorf("no choices in OpenAI response")
 	}
 
+	recordUsage(ctx, provider, chatModel(result.Model, body), result.Usage.PromptTokens, result.Usage.CompletionTokens)
 	return result.Choices[0].Message.Content, nil
 }
 
-// CreateProvider creates an LLM provider based on configuration.
-func CreateProvider(provider, model, ollamaURL, openaiModel, apiKeyEnv string) Provider {
-	if strings.ToLower(provider) == "ollama" {
-		p := NewOllamaProvider(model, ollamaURL)
+// chatUsage is the token usage of an OpenAI-style chat completion.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// chatModel returns the model a chat completion reports, falling back to
+// the requested one. Azure requests name a deployment instead of a model.
+func chatModel(reported string, body map[string]any) string {
+	if reported != "" {
+		return reported
+	}
+	model, _ := body["model"].(string)
+	return model
+}
+
+// streamChatCompletion is the streaming counterpart of chatCompletion: it
+// requests server-sent events and yields each choice delta. Usage arrives in
+// a final chunk without choices.
+func streamChatCompletion(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body map[string]any) (<-chan string, error) {
+	body["stream"] = true
+	body["stream_options"] = map[string]any{"include_usage": true}
+	stream, err := openStream(ctx, client, url, headers, body, "OpenAI")
+	if err != nil {
+		return nil, err
+	}
+	return streamChunks(ctx, stream, true, "OpenAI", func(data []byte) (string, bool, error) {
+		if string(data) == "[DONE]" {
+			return "", true, nil
+		}
+		var chunk struct {
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *chatUsage `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return "", false, fmt.Errorf("decoding chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", false, fmt.Errorf("%s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			recordUsage(ctx, provider, chatModel(chunk.Model, body), chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens)
+		}
+		if len(chunk.Choices) == 0 {
+			return "", false, nil
+		}
+		return chunk.Choices[0].Delta.Content, false, nil
+	}), nil
+}
+
+// openAIEmbedBatch is how many texts OpenAIEmbedder sends per request by
+// default; the API accepts up to 2048.
+const openAIEmbedBatch = 256
+
+// OpenAIEmbedder generates embeddings via the OpenAI embeddings API, or any
+// compatible server via BaseURL.
+type OpenAIEmbedder struct {
+	Model     string
+	APIKey    string
+	BaseURL   string
+	BatchSize int // texts per request; 0 means openAIEmbedBatch
+	client    *http.Client
+}
+
+// NewOpenAIEmbedder creates a new OpenAI embedder. An empty baseURL targets
+// api.openai.com.
+func NewOpenAIEmbedder(model, apiKeyEnv, baseURL string) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = OpenAIBaseURL
+	}
+	return &OpenAIEmbedder{
+		Model:   model,
+		APIKey:  os.Getenv(apiKeyEnv),
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		client:  newClient(DefaultTimeout),
+	}
+}
+
+// Embed generates embeddings for the given texts, in batches of BatchSize.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.APIKey == "" && e.BaseURL == OpenAIBaseURL {
+		return nil, fmt.Errorf("OpenAI API key not configured")
+	}
+
+	size := cmp.Or(e.BatchSize, openAIEmbedBatch)
+	embeddings := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		batch := texts[start:min(start+size, len(texts))]
+		vecs, err := e.embedBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, vecs...)
+	}
+	return embeddings, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	data, err := json.Marshal(map[string]any{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.BaseURL+"/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI embed error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("OpenAI", resp)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage chatUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding embeddings: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("OpenAI returned %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+
+	// The API documents data as ordered by index; place by index regardless.
+	embeddings := make([][]float64, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || embeddings[d.Index] != nil {
+			return nil, fmt.Errorf("OpenAI returned an embedding with unexpected index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	recordUsage(ctx, "openai", e.Model, result.Usage.PromptTokens, 0)
+	return embeddings, nil
+}
+
+// CreateProvider creates an LLM provider based on configuration. Ollama,
+// Claude, Gemini and Azure fall back to OpenAI when they are unavailable.
+// With cfg.Fallback set, the configured provider and the available fallbacks
+// instead form a FallbackProvider, which fails over on every call.
+// Transient API errors are retried as configured in cfg.Retry, by each
+// provider of a chain before the next one is tried.
+func CreateProvider(cfg config.Summarization) Provider {
+	if len(cfg.Fallback) == 0 {
+		return NewRetryProvider(createProvider(cfg), cfg.Retry)
+	}
+	candidates := []config.Summarization{cfg}
+	for _, f := range cfg.Fallback {
+		candidates = append(candidates, cfg.ForFallback(f))
+	}
+	var chain []Provider
+	for _, c := range candidates {
+		if p := configuredProvider(c); p != nil {
+			chain = append(chain, NewRetryProvider(p, cfg.Retry))
+		}
+	}
+	if len(chain) == 0 {
+		log.Println("No LLM provider in the fallback chain is available.")
+		return nil
+	}
+	return NewFallbackProvider(chain)
+}
+
+func createProvider(cfg config.Summarization) Provider {
+	if p := configuredProvider(cfg); p != nil {
+		return p
+	}
+	if !usesOpenAI(cfg.Provider) {
+		log.Println("Trying OpenAI fallback...")
+		cfg.Provider = "openai"
+		if p := configuredProvider(cfg); p != nil {
+			return p
+		}
+	}
+	log.Println("No LLM provider available. Check Ollama is running or set OPENAI_API_KEY.")
+	return nil
+}
+
+// configuredProvider returns the provider cfg.Provider names, or nil after
+// logging why when it is unavailable. Its requests time out as configured
+// in cfg.Timeouts for the provider.
+func configuredProvider(cfg config.Summarization) Provider {
+	client := newClient(cfg.Timeouts.For(cfg.Provider))
+	switch strings.ToLower(cfg.Provider) {
+	case "ollama":
+		p := NewOllamaProvider(cfg.Model, cfg.OllamaURL)
+		p.client = client
+		if p.IsConfigured() {
+			log.Printf("Using Ollama with model: %s", cfg.Model)
+			return p
+		}
+		log.Printf("Ollama not available at %s", cfg.OllamaURL)
+		return nil
+	case "claude", "anthropic":
+		p := NewClaudeProvider(cfg.Claude.Model, cfg.Claude.APIKeyEnv)
+		p.client = client
+		if p.IsConfigured() {
+			log.Printf("Using Claude with model: %s", cfg.Claude.Model)
+			return p
+		}
+		log.Printf("%s not set", cfg.Claude.APIKeyEnv)
+		return nil
+	case "gemini":
+		p := NewGeminiProvider(cfg.Gemini.Model, cfg.Gemini.APIKeyEnv)
+		p.client = client
+		if p.IsConfigured() {
+			log.Printf("Using Gemini with model: %s", cfg.Gemini.Model)
+			return p
+		}
+		log.Printf("%s not set", cfg.Gemini.APIKeyEnv)
+		return nil
+	case "azure":
+		az := cfg.Azure
+		p := NewAzureOpenAIProvider(az.Endpoint, az.Deployment, az.APIVersion, az.APIKeyEnv)
+		p.client = client
 		if p.IsConfigured() {
-			log.Printf("Using Ollama with model: %s", model)
+			log.Printf("Using Azure OpenAI with deployment: %s", az.Deployment)
 			return p
 		}
-		log.Println("Ollama not available, trying OpenAI fallback...")
+		log.Printf("Azure OpenAI needs endpoint, deployment and %s", az.APIKeyEnv)
+		return nil
 	}
 
-	p := NewOpenAIProvider(openaiModel, apiKeyEnv)
+	p := NewOpenAIProvider(cfg.OpenAIModel, cfg.APIKeyEnv, cfg.OpenAIBaseURL)
+	p.client = client
 	if p.IsConfigured() {
-		log.Printf("Using OpenAI with model: %s", openaiModel)
+		if p.isCustomEndpoint() {
+			log.Printf("Using OpenAI-compatible endpoint %s with model: %s", p.BaseURL, cfg.OpenAIModel)
+		} else {
+			log.Printf("Using OpenAI with model: %s", cfg.OpenAIModel)
+		}
 		return p
 	}
-
-	log.Println("No LLM provider available. Check Ollama is running or set OPENAI_API_KEY.")
+	log.Printf("%s not set", cfg.APIKeyEnv)
 	return nil
 }
+
+// usesOpenAI reports whether a provider name means the OpenAI API, which
+// any name other than the other providers' does.
+func usesOpenAI(provider string) bool {
+	switch strings.ToLower(provider) {
+	case "ollama", "claude", "anthropic", "gemini", "azure":
+		return false
+	}
+	return true
+}
+
+// CreateEmbedder creates the embedder used for clustering. embedding_provider
+// picks "ollama", "openai", "gemini", "voyage" or the built-in "tfidf"
+// explicitly; when it is empty, Gemini
+// users embed with Gemini when its API key is set and everyone else uses
+// Ollama. Requests time out as configured in cfg.Timeouts for the
+// embedding provider.
+func CreateEmbedder(cfg config.Summarization) Embedder {
+	switch strings.ToLower(cfg.EmbeddingProvider) {
+	case "openai":
+		log.Printf("Using OpenAI embeddings with model: %s", cfg.OpenAIEmbeddingModel)
+		e := NewOpenAIEmbedder(cfg.OpenAIEmbeddingModel, cfg.APIKeyEnv, cfg.OpenAIBaseURL)
+		e.client = newClient(cfg.Timeouts.For("openai"))
+		return e
+	case "gemini":
+		return newGeminiEmbedder(cfg)
+	case "voyage":
+		log.Printf("Using Voyage embeddings with model: %s", cfg.Voyage.EmbeddingModel)
+		e := NewVoyageEmbedder(cfg.Voyage.EmbeddingModel, cfg.Voyage.APIKeyEnv)
+		e.client = newClient(cfg.Timeouts.For("voyage"))
+		return e
+	case "tfidf":
+		log.Println("Using built-in TF-IDF embeddings")
+		return NewTFIDFEmbedder()
+	case "":
+		if strings.ToLower(cfg.Provider) == "gemini" && os.Getenv(cfg.Gemini.APIKeyEnv) != "" {
+			return newGeminiEmbedder(cfg)
+		}
+	case "ollama":
+	default:
+		log.Printf("Unknown embedding_provider %q, using Ollama embeddings", cfg.EmbeddingProvider)
+	}
+
+	model := cfg.EmbeddingModel
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	baseURL := cfg.OllamaURL
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	e := NewOllamaEmbedder(model, baseURL)
+	e.client = newClient(cfg.Timeouts.For("ollama"))
+	return e
+}
+
+// WithBatchSize makes e send up to size texts per request, for the
+// embedders that batch them (OpenAI and Voyage); 0 keeps their default.
+// It returns e.
+func WithBatchSize(e Embedder, size int) Embedder {
+	switch be := e.(type) {
+	case *OpenAIEmbedder:
+		be.BatchSize = size
+	case *VoyageEmbedder:
+		be.BatchSize = size
+	}
+	return e
+}
+
+func newGeminiEmbedder(cfg config.Summarization) *GeminiEmbedder {
+	log.Printf("Using Gemini embeddings with model: %s", cfg.Gemini.EmbeddingModel)
+	e := NewGeminiEmbedder(cfg.Gemini.EmbeddingModel, cfg.Gemini.APIKeyEnv)
+	e.client = newClient(cfg.Timeouts.For("gemini"))
+	return e
+}
@@ -1,7 +1,26 @@
 package llm
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
 )
 
 func TestParseJSONResponsePlain(t *testing.T) {
@@ -62,3 +81,1211 @@ func TestParseJSONResponseWhitespace(t *testing.T) {
 		t.Errorf("expected key='value', got %v", result["key"])
 	}
 }
+
+func TestClaudeGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("missing auth headers: %v", r.Header)
+		}
+		var body struct {
+			Model     string `json:"model"`
+			MaxTokens int    `json:"max_tokens"`
+			Messages  []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "claude-test" || body.MaxTokens != 256 || len(body.Messages) != 1 || body.Messages[0].Content != "Hello" {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Hi "}, {"type": "text", "text": "there"}], "stop_reason": "end_turn"}`))
+	}))
+	defer srv.Close()
+
+	p := &ClaudeProvider{Model: "claude-test", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	text, err := p.Generate(context.Background(), "Hello", 256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Hi there" {
+		t.Errorf("expected joined text blocks, got %q", text)
+	}
+}
+
+func TestClaudeGenerateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`))
+	}))
+	defer srv.Close()
+
+	p := &ClaudeProvider{Model: "claude-test", APIKey: "bad", BaseURL: srv.URL, client: srv.Client()}
+	_, err := p.Generate(context.Background(), "Hello", 256)
+	if err == nil {
+		t.Fatal("expected error for non-200 response")
+	}
+	if !errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected a 401 to match ErrUnauthorized only, got %v", err)
+	}
+	if (&ClaudeProvider{}).IsConfigured() {
+		t.Error("expected provider without API key to be unconfigured")
+	}
+}
+
+func TestGeminiGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing API key header")
+		}
+		var body struct {
+			Contents []struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"contents"`
+			GenerationConfig struct {
+				MaxOutputTokens int `json:"maxOutputTokens"`
+			} `json:"generationConfig"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Contents) != 1 || body.Contents[0].Parts[0].Text != "Hello" || body.GenerationConfig.MaxOutputTokens != 128 {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "Hi"}, {"text": " there"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer srv.Close()
+
+	p := &GeminiProvider{Model: "gemini-test", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	text, err := p.Generate(context.Background(), "Hello", 128)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Hi there" {
+		t.Errorf("expected joined parts, got %q", text)
+	}
+}
+
+func TestGeminiEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/embed-test:batchEmbedContents" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var body struct {
+			Requests []struct {
+				Model string `json:"model"`
+			} `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Requests) != 2 || body.Requests[0].Model != "models/embed-test" {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		w.Write([]byte(`{"embeddings": [{"values": [0.1, 0.2]}, {"values": [0.3, 0.4]}]}`))
+	}))
+	defer srv.Close()
+
+	e := &GeminiEmbedder{Model: "embed-test", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	vectors, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][1] != 0.4 {
+		t.Errorf("unexpected embeddings: %v", vectors)
+	}
+}
+
+func TestOpenAIEmbed(t *testing.T) {
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		batches = append(batches, len(body.Input))
+		// Answer in reverse order to check placement by index.
+		var data []string
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			data = append(data, fmt.Sprintf(`{"index": %d, "embedding": [%d]}`, i, len(body.Input[i])))
+		}
+		fmt.Fprintf(w, `{"data": [%s], "usage": {"prompt_tokens": %d}}`, strings.Join(data, ","), len(body.Input))
+	}))
+	defer srv.Close()
+
+	texts := make([]string, openAIEmbedBatch+1)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i%7)
+	}
+	e := &OpenAIEmbedder{Model: "text-embedding-3-small", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	meter := &Meter{}
+	vectors, err := e.Embed(WithMeter(context.Background(), meter), texts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 2 || batches[1] != 1 {
+		t.Errorf("expected two batches, got %v", batches)
+	}
+	for i, v := range vectors {
+		if v[0] != float64(i%7) {
+			t.Fatalf("embedding %d out of place: %v", i, v)
+		}
+	}
+	if calls := meter.Calls(); len(calls) != 2 || calls[0].PromptTokens != openAIEmbedBatch {
+		t.Errorf("expected usage per batch, got %+v", calls)
+	}
+
+	batches = nil
+	if _, err := WithBatchSize(e, 100).Embed(context.Background(), texts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 3 || batches[0] != 100 || batches[2] != openAIEmbedBatch+1-200 {
+		t.Errorf("expected batches of the configured size, got %v", batches)
+	}
+}
+
+func TestVoyageEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var body struct {
+			Model     string   `json:"model"`
+			Input     []string `json:"input"`
+			InputType string   `json:"input_type"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "voyage-3.5" || len(body.Input) != 2 || body.InputType != "document" {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		w.Write([]byte(`{"data": [{"index": 1, "embedding": [0.3, 0.4]}, {"index": 0, "embedding": [0.1, 0.2]}], "usage": {"total_tokens": 5}}`))
+	}))
+	defer srv.Close()
+
+	e := &VoyageEmbedder{Model: "voyage-3.5", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	meter := &Meter{}
+	vectors, err := e.Embed(WithMeter(context.Background(), meter), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 0.1 || vectors[1][1] != 0.4 {
+		t.Errorf("unexpected embeddings: %v", vectors)
+	}
+	if calls := meter.Calls(); len(calls) != 1 || calls[0].PromptTokens != 5 {
+		t.Errorf("expected usage of the call, got %+v", calls)
+	}
+	if _, err := (&VoyageEmbedder{Model: "voyage-3.5"}).Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+}
+
+func TestCreateEmbedder(t *testing.T) {
+	cfg := config.Summarization{EmbeddingProvider: "openai", OpenAIEmbeddingModel: "text-embedding-3-small"}
+	if e, ok := CreateEmbedder(cfg).(*OpenAIEmbedder); !ok || e.Model != "text-embedding-3-small" || e.BaseURL != OpenAIBaseURL {
+		t.Errorf("expected an OpenAI embedder, got %#v", CreateEmbedder(cfg))
+	}
+	cfg = config.Summarization{EmbeddingProvider: "voyage", Voyage: config.VoyageConfig{EmbeddingModel: "voyage-3.5"}}
+	if e, ok := CreateEmbedder(cfg).(*VoyageEmbedder); !ok || e.Model != "voyage-3.5" || EmbedderName(e) != "voyage:voyage-3.5" {
+		t.Errorf("expected a Voyage embedder, got %#v", CreateEmbedder(cfg))
+	}
+	cfg = config.Summarization{Provider: "openai", EmbeddingModel: "nomic-embed-text"}
+	if _, ok := CreateEmbedder(cfg).(*OllamaEmbedder); !ok {
+		t.Error("expected Ollama embeddings when embedding_provider is unset")
+	}
+}
+
+func TestAzureOpenAIGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/briefing-gpt/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if v := r.URL.Query().Get("api-version"); v != "2024-10-21" {
+			t.Errorf("expected api-version query, got %q", v)
+		}
+		if r.Header.Get("api-key") != "test-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("expected api-key header auth, got %v", r.Header)
+		}
+		w.Write([]byte(`{"choices": [{"message": {"content": "Hello from Azure"}}]}`))
+	}))
+	defer srv.Close()
+
+	p := &AzureOpenAIProvider{Endpoint: srv.URL + "/", Deployment: "briefing-gpt", APIVersion: "2024-10-21", APIKey: "test-key", client: srv.Client()}
+	text, err := p.Generate(context.Background(), "Hello", 64)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Hello from Azure" {
+		t.Errorf("unexpected response %q", text)
+	}
+	if (&AzureOpenAIProvider{Endpoint: srv.URL, APIKey: "k"}).IsConfigured() {
+		t.Error("expected provider without deployment to be unconfigured")
+	}
+}
+
+func TestOpenAICompatibleBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no auth header without API key")
+		}
+		w.Write([]byte(`{"choices": [{"message": {"content": "local answer"}}]}`))
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL+"/v1/")
+	if !p.IsConfigured() {
+		t.Fatal("expected custom endpoint to be usable without an API key")
+	}
+	text, err := p.Generate(context.Background(), "Hello", 64)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "local answer" {
+		t.Errorf("unexpected response %q", text)
+	}
+	if NewOpenAIProvider("gpt", "AICRAWLER_TEST_UNSET_KEY", "").IsConfigured() {
+		t.Error("expected api.openai.com without key to be unconfigured")
+	}
+}
+
+func TestOpenAIGenerateStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("expected stream request, got %v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\": [{\"delta\": {\"role\": \"assistant\"}}]}\n\n" +
+			"data: {\"choices\": [{\"delta\": {\"content\": \"Hel\"}}]}\n\n" +
+			"data: {\"choices\": [{\"delta\": {\"content\": \"lo\"}}]}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL)
+	var chunks []string
+	text, err := GenerateStreaming(context.Background(), p, "Hello", 64, func(c string) { chunks = append(chunks, c) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Hello" || len(chunks) != 2 {
+		t.Errorf("expected 2 chunks forming %q, got %q from %v", "Hello", text, chunks)
+	}
+}
+
+func TestClaudeGenerateStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: message_start\ndata: {\"type\": \"message_start\"}\n\n" +
+			"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \"Hi \"}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \"there\"}}\n\n" +
+			"event: message_stop\ndata: {\"type\": \"message_stop\"}\n\n"))
+	}))
+	defer srv.Close()
+
+	p := &ClaudeProvider{Model: "m", APIKey: "k", BaseURL: srv.URL, client: srv.Client()}
+	text, err := GenerateStreaming(context.Background(), p, "Hello", 64, nil)
+	if err != nil || text != "Hi there" {
+		t.Errorf("expected %q, got %q (%v)", "Hi there", text, err)
+	}
+}
+
+func TestOllamaGenerateStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"content": "one "}, "done": false}` + "\n" +
+			`{"message": {"content": "two"}, "done": false}` + "\n" +
+			`{"message": {"content": ""}, "done": true}` + "\n"))
+	}))
+	defer srv.Close()
+
+	text, err := GenerateStreaming(context.Background(), NewOllamaProvider("m", srv.URL), "Hello", 64, nil)
+	if err != nil || text != "one two" {
+		t.Errorf("expected %q, got %q (%v)", "one two", text, err)
+	}
+}
+
+type plainProvider struct{}
+
+func (plainProvider) Generate(context.Context, string, int) (string, error) { return "whole", nil }
+func (plainProvider) IsConfigured() bool                                    { return true }
+
+func TestGenerateStreamingFallsBackToGenerate(t *testing.T) {
+	var chunks []string
+	text, err := GenerateStreaming(context.Background(), plainProvider{}, "Hello", 64, func(c string) { chunks = append(chunks, c) })
+	if err != nil || text != "whole" || len(chunks) != 1 {
+		t.Errorf("expected the full response as one chunk, got %q %v (%v)", text, chunks, err)
+	}
+}
+
+func TestRetryProviderHonorsRetryAfter(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "rate limited"}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"content": "after retry"}}]}`))
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	p := &RetryProvider{
+		Provider:       NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL),
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		sleep:          func(_ context.Context, d time.Duration) error { waits = append(waits, d); return nil },
+	}
+	text, err := p.Generate(context.Background(), "Hello", 64)
+	if err != nil || text != "after retry" {
+		t.Fatalf("expected success after retry, got %q (%v)", text, err)
+	}
+	if calls != 2 || len(waits) != 1 || waits[0] != 7*time.Second {
+		t.Errorf("expected one 7s wait from Retry-After, got %d calls and waits %v", calls, waits)
+	}
+}
+
+func TestRetryProviderBacksOffAndGivesUp(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	p := &RetryProvider{
+		Provider:       NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL),
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+		sleep:          func(_ context.Context, d time.Duration) error { waits = append(waits, d); return nil },
+	}
+	_, err := p.Generate(context.Background(), "Hello", 64)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the last 503 APIError, got %v", err)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if calls != 4 || fmt.Sprint(waits) != fmt.Sprint(want) {
+		t.Errorf("expected 4 calls with waits %v, got %d calls and %v", want, calls, waits)
+	}
+}
+
+func TestRetryProviderSkipsPermanentErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	p := &RetryProvider{
+		Provider:    NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL),
+		MaxAttempts: 3,
+		sleep:       func(context.Context, time.Duration) error { return nil },
+	}
+	if _, err := p.Generate(context.Background(), "Hello", 64); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if calls != 1 {
+		t.Errorf("expected no retry for a 400, got %d calls", calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"soon":                          0,
+		"Fri, 02 Jan 2026 12:01:30 GMT": 90 * time.Second,
+		"Fri, 02 Jan 2026 11:00:00 GMT": 0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	urlErr := func(err error) error { return &url.Error{Op: "Post", URL: "https://api.example.com/v1", Err: err} }
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"overloaded", fmt.Errorf("claude: %w", &APIError{StatusCode: 529}), true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"client timeout", urlErr(timeoutError{}), true},
+		{"connection refused", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), true},
+		{"connection reset", urlErr(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), true},
+		{"closed before answering", urlErr(io.EOF), true},
+		{"temporary DNS failure", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Name: "api.example.com", IsTemporary: true}}), true},
+		{"unknown host", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Name: "api.example.com", IsNotFound: true}}), false},
+		{"canceled", urlErr(context.Canceled), false},
+		{"invalid URL", urlErr(errors.New("invalid control character in URL")), false},
+		{"unsupported protocol", urlErr(errors.New(`unsupported protocol scheme "htps"`)), false},
+		{"untrusted certificate", urlErr(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), false},
+		{"plain error", errors.New("no response"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("%s: expected transient %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// timeoutError is a net.Error that timed out, as an http.Client's is.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "Client.Timeout exceeded while awaiting headers" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestNewRetryProviderDisabled(t *testing.T) {
+	p := plainProvider{}
+	if _, ok := NewRetryProvider(p, config.RetryConfig{MaxAttempts: 1}).(*RetryProvider); ok {
+		t.Error("expected a single attempt to leave the provider unwrapped")
+	}
+	if NewRetryProvider(nil, config.RetryConfig{MaxAttempts: 3}) != nil {
+		t.Error("expected nil provider to stay nil")
+	}
+}
+
+func TestRetryProviderStopsBeforeDeadline(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	slept := false
+	p := &RetryProvider{
+		Provider:       NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL),
+		MaxAttempts:    3,
+		InitialBackoff: time.Minute,
+		sleep:          func(context.Context, time.Duration) error { slept = true; return nil },
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := p.Generate(ctx, "Hello", 64)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the 503 APIError, got %v", err)
+	}
+	if calls != 1 || slept {
+		t.Errorf("expected no retry past the deadline, got %d calls (slept %v)", calls, slept)
+	}
+}
+
+func TestClientTimesOutWaitingForResponse(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p := NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL)
+	p.client = newClient(50 * time.Millisecond)
+	if _, err := p.Generate(context.Background(), "Hello", 64); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestClientTimeoutDoesNotCutStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"content": "one "}, "done": false}` + "\n"))
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte(`{"message": {"content": "two"}, "done": true}` + "\n"))
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider("m", srv.URL)
+	p.client = newClient(50 * time.Millisecond)
+	text, err := GenerateStreaming(context.Background(), p, "Hello", 64, nil)
+	if err != nil || text != "one two" {
+		t.Errorf("expected the whole stream %q, got %q (%v)", "one two", text, err)
+	}
+}
+
+func TestConfiguredProviderUsesProviderTimeout(t *testing.T) {
+	cfg := config.Summarization{
+		Provider:      "openai",
+		OpenAIModel:   "local-model",
+		OpenAIBaseURL: "http://localhost:8080/v1",
+		Timeouts:      config.TimeoutsConfig{DefaultSeconds: 120, OllamaSeconds: 600, OpenAISeconds: 30},
+	}
+	p, ok := configuredProvider(cfg).(*OpenAIProvider)
+	if !ok {
+		t.Fatal("expected an OpenAI provider")
+	}
+	if got := p.client.Transport.(*http.Transport).ResponseHeaderTimeout; got != 30*time.Second {
+		t.Errorf("expected the 30s OpenAI timeout, got %s", got)
+	}
+}
+
+type cannedProvider struct{ response string }
+
+func (c cannedProvider) Generate(context.Context, string, int) (string, error) {
+	return c.response, nil
+}
+
+func (cannedProvider) IsConfigured() bool { return true }
+
+func TestTranslate(t *testing.T) {
+	texts := []string{"Briefly Noted", "Sources"}
+	out, err := Translate(context.Background(), cannedProvider{`{"translations": ["Kurz notiert", "Quellen"]}`}, "German", texts)
+	if err != nil || out[0] != "Kurz notiert" || out[1] != "Quellen" {
+		t.Errorf("unexpected translation %v (%v)", out, err)
+	}
+
+	out, err = Translate(context.Background(), cannedProvider{`{"translations": ["Kurz notiert"]}`}, "German", texts)
+	if err == nil || out[0] != "Briefly Noted" {
+		t.Errorf("expected originals and an error for a short response, got %v (%v)", out, err)
+	}
+}
+
+func TestMeterRecordsUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Hi"}], "usage": {"input_tokens": 12, "output_tokens": 3}}`))
+	}))
+	defer srv.Close()
+
+	p := &ClaudeProvider{Model: "claude-test", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	meter := &Meter{}
+	ctx := WithMeter(context.Background(), meter)
+	p.Generate(ctx, "Hello", 64)
+	p.Generate(context.Background(), "Unmetered", 64)
+
+	calls := meter.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 metered call, got %+v", calls)
+	}
+	if c := calls[0]; c.Provider != "claude" || c.Model != "claude-test" || c.PromptTokens != 12 || c.CompletionTokens != 3 {
+		t.Errorf("unexpected call: %+v", c)
+	}
+}
+
+func TestOpenAIStreamRecordsUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"model\": \"gpt-4o-mini-2024-07-18\", \"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\n" +
+			"data: {\"model\": \"gpt-4o-mini-2024-07-18\", \"choices\": [], \"usage\": {\"prompt_tokens\": 20, \"completion_tokens\": 2}}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider("gpt-4o-mini", "AICRAWLER_TEST_UNSET_KEY", srv.URL)
+	meter := &Meter{}
+	if _, err := GenerateStreaming(WithMeter(context.Background(), meter), p, "Hello", 64, func(string) {}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := meter.Calls()
+	if len(calls) != 1 || calls[0].Model != "gpt-4o-mini-2024-07-18" || calls[0].PromptTokens != 20 || calls[0].CompletionTokens != 2 {
+		t.Errorf("expected usage from the final chunk, got %+v", calls)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	prices := map[string]config.ModelPrice{
+		"gpt-4o":      {InputPerMillion: 2.5, OutputPerMillion: 10},
+		"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	}
+	got := EstimateCost(prices, Call{Model: "gpt-4o-mini-2024-07-18", PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	if math.Abs(got-0.45) > 1e-9 {
+		t.Errorf("expected the longest matching price (0.45), got %v", got)
+	}
+	if got := EstimateCost(prices, Call{Model: "llama3.2", PromptTokens: 1000}); got != 0 {
+		t.Errorf("expected unpriced model to cost 0, got %v", got)
+	}
+}
+
+// memoryStore is an in-memory ResponseStore.
+type memoryStore map[string]string
+
+func (m memoryStore) GetCachedResponse(model, hash string, _ time.Duration) (*string, error) {
+	if r, ok := m[model+"/"+hash]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (m memoryStore) PutCachedResponse(model, hash, response string) error {
+	m[model+"/"+hash] = response
+	return nil
+}
+
+func (m memoryStore) PruneCachedResponses(time.Duration) error { return nil }
+
+// countingProvider answers every prompt with its call count.
+type countingProvider struct{ calls int }
+
+func (c *countingProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	c.calls++
+	return fmt.Sprintf("%s #%d", prompt, c.calls), nil
+}
+
+func (c *countingProvider) IsConfigured() bool { return true }
+
+func TestCachingProviderReusesResponses(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewCachingProvider(inner, memoryStore{}, config.CacheConfig{Enabled: true, TTLHours: 1})
+	ctx := context.Background()
+
+	first, _ := p.Generate(ctx, "Hello", 64)
+	again, _ := p.Generate(ctx, "Hello", 64)
+	if first != "Hello #1" || again != first {
+		t.Errorf("expected the cached response, got %q then %q", first, again)
+	}
+	if other, _ := p.Generate(ctx, "Hello", 128); other != "Hello #2" {
+		t.Errorf("expected a different token limit to miss the cache, got %q", other)
+	}
+	if streamed, _ := GenerateStreaming(ctx, p, "Hello", 64, func(string) {}); streamed != first {
+		t.Errorf("expected streaming to hit the cache, got %q", streamed)
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", inner.calls)
+	}
+
+	if NewCachingProvider(inner, memoryStore{}, config.CacheConfig{}) != Provider(inner) {
+		t.Error("expected a disabled cache to leave the provider unwrapped")
+	}
+}
+
+func TestCachingProviderCachesStreams(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Hel\"}}]}\n\n" +
+			"data: {\"choices\": [{\"delta\": {\"content\": \"lo\"}}]}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	store := memoryStore{}
+	p := NewCachingProvider(NewOpenAIProvider("local-model", "AICRAWLER_TEST_UNSET_KEY", srv.URL), store, config.CacheConfig{Enabled: true, TTLHours: 1})
+	for range 2 {
+		text, err := GenerateStreaming(context.Background(), p, "Hi", 64, func(string) {})
+		if err != nil || text != "Hello" {
+			t.Fatalf("expected %q, got %q, %v", "Hello", text, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the second stream to come from the cache, got %d requests", requests)
+	}
+}
+
+var testSchema = Schema{Name: "answer", Definition: Object(map[string]any{
+	"answer": String(),
+	"score":  Nullable(Integer()),
+})}
+
+func TestTemperatureFromContext(t *testing.T) {
+	var temperatures []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		temperatures = append(temperatures, body["temperature"])
+		w.Write([]byte(`{"choices": [{"message": {"content": "Hi"}}]}`))
+	}))
+	defer srv.Close()
+
+	p := NewCachingProvider(NewOpenAIProvider("m", "AICRAWLER_TEST_UNSET_KEY", srv.URL), memoryStore{}, config.CacheConfig{Enabled: true, TTLHours: 1})
+	ctx := context.Background()
+	p.Generate(ctx, "Hello", 64)
+	p.Generate(WithTemperature(ctx, 0), "Hello", 64)
+	p.Generate(WithTemperature(ctx, 0), "Hello", 64)
+	if fmt.Sprint(temperatures) != "[0.3 0]" {
+		t.Errorf("expected the default and then the context's temperature, each cached once, got %v", temperatures)
+	}
+	if promptHash("Hello", 64, DefaultTemperature, "") != promptHash("Hello", 64, 0.3, "") || promptHash("Hello", 64, 0.3, "") == promptHash("Hello", 64, 0.7, "") {
+		t.Error("expected temperatures to tell cache entries apart")
+	}
+}
+
+func TestSystemMessageFromContext(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if strings.HasSuffix(r.URL.Path, "/v1/messages") {
+			w.Write([]byte(`{"content": [{"type": "text", "text": "Hi"}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"content": "Hi"}}]}`))
+	}))
+	defer srv.Close()
+
+	ctx := WithSystem(context.Background(), "You brief security engineers.")
+	openai := NewCachingProvider(NewOpenAIProvider("m", "AICRAWLER_TEST_UNSET_KEY", srv.URL), memoryStore{}, config.CacheConfig{Enabled: true, TTLHours: 1})
+	openai.Generate(context.Background(), "Hello", 64)
+	openai.Generate(ctx, "Hello", 64)
+	if len(bodies) != 2 {
+		t.Fatalf("expected the system message to tell cache entries apart, got %d calls", len(bodies))
+	}
+	if messages := fmt.Sprint(bodies[1]["messages"]); messages != "[map[content:You brief security engineers. role:system] map[content:Hello role:user]]" {
+		t.Errorf("expected a system message ahead of the prompt, got %s", messages)
+	}
+	if messages := fmt.Sprint(bodies[0]["messages"]); strings.Contains(messages, "system") {
+		t.Errorf("expected no system message without one in the context, got %s", messages)
+	}
+
+	claude := &ClaudeProvider{Model: "claude-test", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	claude.Generate(ctx, "Hello", 64)
+	if system := bodies[2]["system"]; system != "You brief security engineers." {
+		t.Errorf("expected Claude's system parameter, got %v", system)
+	}
+
+	gemini := geminiBody("You brief security engineers.", "Hello", 64, DefaultTemperature, nil)
+	if _, ok := gemini["systemInstruction"]; !ok {
+		t.Errorf("expected a Gemini system instruction, got %v", gemini)
+	}
+}
+
+func TestOpenAISendsResponseSchema(t *testing.T) {
+	var format map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		format, _ = body["response_format"].(map[string]any)
+		w.Write([]byte(`{"choices": [{"message": {"content": "{\"answer\": \"yes\"}"}}]}`))
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider("m", "AICRAWLER_TEST_UNSET_KEY", srv.URL)
+	if _, err := p.Generate(WithSchema(context.Background(), testSchema), "Hello", 64); err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || spec["name"] != "answer" {
+		t.Fatalf("expected json_schema response format, got %v", format)
+	}
+	schema := spec["schema"].(map[string]any)
+	if fmt.Sprint(schema["required"]) != "[answer score]" {
+		t.Errorf("expected every property required, got %v", schema["required"])
+	}
+
+	format = nil
+	p.Generate(context.Background(), "Hello", 64)
+	if format != nil {
+		t.Errorf("expected no response format without a schema, got %v", format)
+	}
+}
+
+func TestSchemaFallsBackWhenRejected(t *testing.T) {
+	var withFormat, without int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["format"]; ok {
+			withFormat++
+			http.Error(w, `{"error": "invalid format"}`, http.StatusBadRequest)
+			return
+		}
+		without++
+		w.Write([]byte(`{"message": {"content": "{\"answer\": \"yes\"}"}}`))
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider("m", srv.URL)
+	ctx := WithSchema(context.Background(), testSchema)
+	for i := 0; i < 2; i++ {
+		text, err := p.Generate(ctx, "Hello", 64)
+		if err != nil || text != `{"answer": "yes"}` {
+			t.Fatalf("expected the prompt-only response, got %q (%v)", text, err)
+		}
+	}
+	if withFormat != 1 || without != 2 {
+		t.Errorf("expected one rejected schema, then plain requests; got %d with, %d without", withFormat, without)
+	}
+}
+
+func TestGeminiSendsResponseSchema(t *testing.T) {
+	var generation map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		generation, _ = body["generationConfig"].(map[string]any)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{}"}]}}]}`))
+	}))
+	defer srv.Close()
+
+	p := &GeminiProvider{Model: "g", APIKey: "k", BaseURL: srv.URL, client: srv.Client()}
+	if _, err := p.Generate(WithSchema(context.Background(), testSchema), "Hello", 64); err != nil {
+		t.Fatal(err)
+	}
+	if generation["responseMimeType"] != "application/json" || generation["responseJsonSchema"] == nil {
+		t.Errorf("expected JSON response schema in generationConfig, got %v", generation)
+	}
+}
+
+// flakyProvider fails while failing is set and answers with its name otherwise.
+type flakyProvider struct {
+	name    string
+	failing bool
+	healthy bool
+	calls   int
+}
+
+func (f *flakyProvider) Generate(context.Context, string, int) (string, error) {
+	f.calls++
+	if f.failing {
+		return "", &APIError{API: f.name, StatusCode: http.StatusServiceUnavailable}
+	}
+	return f.name, nil
+}
+
+func (f *flakyProvider) IsConfigured() bool { return f.healthy }
+
+func TestFallbackProviderFailsOverPerCall(t *testing.T) {
+	primary := &flakyProvider{name: "primary", healthy: true}
+	backup := &flakyProvider{name: "backup", healthy: true}
+	f := NewFallbackProvider([]Provider{primary, backup}).(*FallbackProvider)
+	now := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if text, _ := f.Generate(ctx, "Hello", 64); text != "primary" {
+		t.Fatalf("expected the primary provider, got %q", text)
+	}
+
+	// The primary starts failing mid-run: this call and the next go to the
+	// backup, and the primary isn't asked again while it cools down.
+	primary.failing = true
+	if text, err := f.Generate(ctx, "Hello", 64); text != "backup" || err != nil {
+		t.Fatalf("expected failover to the backup, got %q (%v)", text, err)
+	}
+	if text, _ := f.Generate(ctx, "Hello", 64); text != "backup" || primary.calls != 2 {
+		t.Errorf("expected the primary to be passed over, got %q after %d primary calls", text, primary.calls)
+	}
+
+	// After the cooldown the primary needs to pass its health check.
+	primary.failing, primary.healthy = false, false
+	now = now.Add(fallbackCooldown + time.Second)
+	if text, _ := f.Generate(ctx, "Hello", 64); text != "backup" || primary.calls != 2 {
+		t.Errorf("expected an unhealthy primary to stay passed over, got %q", text)
+	}
+	primary.healthy = true
+	now = now.Add(fallbackCooldown + time.Second)
+	if text, _ := f.Generate(ctx, "Hello", 64); text != "primary" {
+		t.Errorf("expected the recovered primary, got %q", text)
+	}
+
+	// With every provider failing, the last error is returned.
+	primary.failing, backup.failing = true, true
+	var apiErr *APIError
+	if _, err := f.Generate(ctx, "Hello", 64); !errors.As(err, &apiErr) || apiErr.API != "backup" {
+		t.Errorf("expected the backup's error, got %v", err)
+	}
+	// Both are cooling down now, but are still tried rather than giving up.
+	backup.failing = false
+	if text, _ := f.Generate(ctx, "Hello", 64); text != "backup" {
+		t.Errorf("expected providers to be tried while all cool down, got %q", text)
+	}
+}
+
+func TestCreateProviderBuildsFallbackChain(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprintf(w, `{"choices": [{"message": {"content": "from %s"}}]}`, body["model"])
+	}))
+	defer up.Close()
+
+	cfg := config.Summarization{
+		Provider:      "openai",
+		OpenAIModel:   "primary-model",
+		OpenAIBaseURL: down.URL,
+		APIKeyEnv:     "AICRAWLER_TEST_UNSET_KEY",
+		Retry:         config.RetryConfig{MaxAttempts: 1},
+		Fallback: []config.FallbackConfig{
+			{Provider: "claude", APIKeyEnv: "AICRAWLER_TEST_UNSET_KEY"}, // unavailable, left out
+			{Provider: "openai", Model: "backup-model", BaseURL: up.URL},
+		},
+	}
+	p := CreateProvider(cfg)
+	f, ok := p.(*FallbackProvider)
+	if !ok || len(f.Providers) != 2 {
+		t.Fatalf("expected a chain of the two available providers, got %#v", p)
+	}
+	if text, err := p.Generate(context.Background(), "Hello", 64); text != "from backup-model" {
+		t.Errorf("expected the fallback's response, got %q (%v)", text, err)
+	}
+}
+
+func TestAuditProviderRecordsExchanges(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Hi"}], "usage": {"input_tokens": 12, "output_tokens": 3}}`))
+	}))
+	defer srv.Close()
+
+	claude := &ClaudeProvider{Model: "claude-test", APIKey: "test-key", BaseURL: srv.URL, client: srv.Client()}
+	p := NewAuditProvider(NewCachingProvider(claude, memoryStore{}, config.CacheConfig{Enabled: true, TTLHours: 1}))
+	meter := &Meter{}
+	ctx := WithArticle(WithMeter(context.Background(), meter), 42)
+	p.Generate(ctx, "Hello", 64)
+	p.Generate(ctx, "Hello", 64)
+
+	exchanges := meter.Exchanges()
+	if len(exchanges) != 2 {
+		t.Fatalf("expected 2 exchanges, got %+v", exchanges)
+	}
+	if e := exchanges[0]; e.Provider != "claude" || e.Model != "claude-test" || e.Prompt != "Hello" || e.Response != "Hi" ||
+		e.PromptTokens != 12 || e.Subject.ArticleID != 42 || e.Cached {
+		t.Errorf("unexpected exchange: %+v", e)
+	}
+	if e := exchanges[1]; !e.Cached || e.Response != "Hi" {
+		t.Errorf("expected the second exchange to come from the cache, got %+v", e)
+	}
+	if calls := meter.Calls(); len(calls) != 1 || calls[0].PromptTokens != 12 {
+		t.Errorf("expected the step meter to still count the provider call, got %+v", calls)
+	}
+
+	if NewAuditProvider(nil) != nil {
+		t.Error("expected a nil provider to stay nil")
+	}
+}
+
+type lengthEmbedder struct{}
+
+func (lengthEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float64{float64(len(text))}
+	}
+	return vectors, nil
+}
+
+func TestTFIDFEmbedder(t *testing.T) {
+	e := &TFIDFEmbedder{StopWords: map[string]bool{"the": true}}
+	vectors, err := e.Embed(context.Background(), []string{
+		"Gemini release brings longer context windows",
+		"The Gemini release: context windows grow",
+		"Robotics startup raises funding round",
+		"", // no counted words
+	})
+	if err != nil || len(vectors) != 4 {
+		t.Fatalf("expected 4 vectors, got %d (%v)", len(vectors), err)
+	}
+	distance := func(a, b []float64) float64 {
+		var sum float64
+		for i := range a {
+			sum += (a[i] - b[i]) * (a[i] - b[i])
+		}
+		return math.Sqrt(sum)
+	}
+	for i, v := range vectors {
+		if norm := distance(v, make([]float64, len(v))); math.Abs(norm-1) > 1e-9 {
+			t.Errorf("expected vector %d to have unit length, got %f", i, norm)
+		}
+	}
+	if related, unrelated := distance(vectors[0], vectors[1]), distance(vectors[0], vectors[2]); related >= unrelated {
+		t.Errorf("expected texts sharing words to be closer (%f) than unrelated ones (%f)", related, unrelated)
+	}
+	if unrelated := distance(vectors[0], vectors[2]); math.Abs(unrelated-1) > 1e-9 {
+		t.Errorf("expected texts without shared words at distance 1, got %f", unrelated)
+	}
+	if got := e.words("The Gemini API, v2"); fmt.Sprint(got) != "[gemini api]" {
+		t.Errorf("expected stop words and short words skipped, got %v", got)
+	}
+}
+
+func TestTapeRecordsAndReplays(t *testing.T) {
+	path := t.TempDir() + "/tapes/run.json"
+	tape, err := OpenTape(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	recorder := NewRecordingProvider(&countingProvider{}, tape)
+	recorded, _ := recorder.Generate(ctx, "Hello", 64)
+	NewRecordingEmbedder(lengthEmbedder{}, tape).Embed(ctx, []string{"abc"})
+	if err := tape.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tape, err = OpenTape(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if responses, embeddings := tape.Len(); responses != 1 || embeddings != 1 {
+		t.Fatalf("expected 1 response and 1 embedding, got %d and %d", responses, embeddings)
+	}
+	replay := NewReplayProvider(tape)
+	if text, err := replay.Generate(ctx, "Hello", 64); err != nil || text != recorded {
+		t.Errorf("expected the recorded %q, got %q (%v)", recorded, text, err)
+	}
+	if _, err := replay.Generate(ctx, "Goodbye", 64); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for another prompt, got %v", err)
+	}
+	if _, err := replay.Generate(WithTemperature(ctx, 0.9), "Hello", 64); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for another temperature, got %v", err)
+	}
+
+	embedder := NewReplayEmbedder(tape)
+	if vectors, err := embedder.Embed(ctx, []string{"abc"}); err != nil || len(vectors) != 1 || vectors[0][0] != 3 {
+		t.Errorf("expected the recorded vector, got %v (%v)", vectors, err)
+	}
+	if _, err := embedder.Embed(ctx, []string{"abc", "new"}); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for an unrecorded text, got %v", err)
+	}
+}
+
+// busyProvider takes a while per call and counts the calls in flight.
+type busyProvider struct {
+	inFlight, peak atomic.Int32
+}
+
+func (b *busyProvider) Generate(_ context.Context, _ string, _ int) (string, error) {
+	n := b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return "ok", nil
+}
+
+func (b *busyProvider) IsConfigured() bool { return true }
+
+func TestPoolLimitsCallsAcrossProviders(t *testing.T) {
+	busy := &busyProvider{}
+	pool := NewPool(2)
+	providers := []Provider{pool.Limit(busy), pool.Limit(busy)}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			if _, err := providers[i%2].Generate(context.Background(), "Hello", 64); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if peak := busy.peak.Load(); peak != 2 {
+		t.Errorf("expected at most and at some point 2 calls in flight, got %d", peak)
+	}
+	if name := modelName(providers[0]); name != modelName(busy) {
+		t.Errorf("expected the limit not to change the cache name, got %q", name)
+	}
+}
+
+func TestPoolRun(t *testing.T) {
+	var seen [20]atomic.Int32
+	NewPool(4).Run(context.Background(), len(seen), func(i int) { seen[i].Add(1) })
+	for i := range seen {
+		if n := seen[i].Load(); n != 1 {
+			t.Errorf("expected index %d to run once, got %d", i, n)
+		}
+	}
+
+	var order []int
+	var pool *Pool
+	pool.Run(context.Background(), 3, func(i int) { order = append(order, i) })
+	if fmt.Sprint(order) != "[0 1 2]" {
+		t.Errorf("expected a nil pool to run in order, got %v", order)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := 0
+	NewPool(1).Run(ctx, 5, func(int) { ran++ })
+	if ran != 0 {
+		t.Errorf("expected nothing to run once the context is done, got %d", ran)
+	}
+}
+
+// scriptedProvider answers with responses in turn, recording the prompts.
+type scriptedProvider struct {
+	responses []string
+	prompts   []string
+}
+
+func (s *scriptedProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return response, nil
+}
+
+func (s *scriptedProvider) IsConfigured() bool { return true }
+
+func TestGenerateWithToolsRunsCalls(t *testing.T) {
+	p := &scriptedProvider{responses: []string{
+		`{"tool_calls": [{"name": "shout", "arguments": {"text": "hi"}}, {"name": "missing", "arguments": {}}]}`,
+		`{"answer": "done"}`,
+	}}
+	shout := Tool{
+		Name:        "shout",
+		Description: "Returns text in upper case.",
+		Parameters:  Object(map[string]any{"text": String()}),
+		Call: func(_ context.Context, args map[string]any) (string, error) {
+			text, _ := args["text"].(string)
+			return strings.ToUpper(text), nil
+		},
+	}
+
+	answer, err := GenerateWithTools(context.Background(), p, "Question", 64, []Tool{shout}, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != `{"answer": "done"}` {
+		t.Errorf("expected the answer, got %q", answer)
+	}
+	if len(p.prompts) != 2 || !strings.Contains(p.prompts[0], "- shout: Returns text in upper case.") {
+		t.Fatalf("expected a prompt describing the tool, got %q", p.prompts)
+	}
+	second := p.prompts[1]
+	if !strings.Contains(second, "Result of shout({\"text\":\"hi\"}):\nHI") {
+		t.Errorf("expected the tool result in the next prompt, got %q", second)
+	}
+	if !strings.Contains(second, `Error: there is no tool named "missing"`) {
+		t.Errorf("expected an error for the unknown tool, got %q", second)
+	}
+}
+
+func TestGenerateWithToolsEndsWithAnswerRound(t *testing.T) {
+	call := `{"tool_calls": [{"name": "noop", "arguments": {}}]}`
+	p := &scriptedProvider{responses: []string{call, `{"answer": "forced"}`}}
+	noop := Tool{Name: "noop", Call: func(context.Context, map[string]any) (string, error) { return "ok", nil }}
+
+	answer, err := GenerateWithTools(context.Background(), p, "Question", 64, []Tool{noop}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != `{"answer": "forced"}` {
+		t.Errorf("expected the answer round's response, got %q", answer)
+	}
+	last := p.prompts[len(p.prompts)-1]
+	if strings.Contains(last, "tool_calls") || !strings.Contains(last, "You can't call tools any more") {
+		t.Errorf("expected the last round to ask for the answer without tools, got %q", last)
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool bounds how many LLM calls are in flight at once. Providers wrapped
+// with Limit share its slots, so pipeline steps with providers of their own
+// still stay within one limit and a local Ollama or a rate-limited API isn't
+// flooded. Run spreads a step's work over as many workers as there are
+// slots. A nil Pool limits nothing and runs work in order.
+type Pool struct {
+	slots chan struct{}
+}
+
+// NewPool creates a pool of size slots; fewer than one means one.
+func NewPool(size int) *Pool {
+	return &Pool{slots: make(chan struct{}, max(size, 1))}
+}
+
+// Size returns the number of calls the pool lets run at once.
+func (p *Pool) Size() int {
+	if p == nil {
+		return 1
+	}
+	return cap(p.slots)
+}
+
+// Limit wraps provider so each of its calls waits for a free slot. A nil
+// pool or provider returns provider unwrapped.
+func (p *Pool) Limit(provider Provider) Provider {
+	if p == nil || provider == nil {
+		return provider
+	}
+	return &LimitedProvider{Provider: provider, Pool: p}
+}
+
+// Run calls fn for every index below n on up to Size workers, and returns
+// once all calls have returned. Indices not started when ctx is done are
+// skipped. fn must be safe for concurrent use unless the pool has one slot.
+func (p *Pool) Run(ctx context.Context, n int, fn func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(p.Size(), n) {
+		wg.Go(func() {
+			for i := range next {
+				fn(i)
+			}
+		})
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+}
+
+func (p *Pool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	<-p.slots
+}
+
+// LimitedProvider holds a slot of its pool for the duration of every call
+// to the wrapped provider, until the end of the stream for streamed ones.
+type LimitedProvider struct {
+	Provider
+	Pool *Pool
+}
+
+// Generate waits for a slot and calls the wrapped provider.
+func (l *LimitedProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	if err := l.Pool.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer l.Pool.release()
+	return l.Provider.Generate(ctx, prompt, maxTokens)
+}
+
+// GenerateStream waits for a slot and streams from the wrapped provider,
+// releasing the slot when the stream ends. Without streaming support it
+// yields the Generate response as one chunk.
+func (l *LimitedProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	s, ok := l.Provider.(Streamer)
+	if !ok {
+		text, err := l.Generate(ctx, prompt, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan string, 1)
+		out <- text
+		close(out)
+		return out, nil
+	}
+
+	if err := l.Pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	chunks, err := s.GenerateStream(ctx, prompt, maxTokens)
+	if err != nil {
+		l.Pool.release()
+		return nil, err
+	}
+	out := make(chan string)
+	go func() {
+		defer l.Pool.release()
+		defer close(out)
+		for chunk := range chunks {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// tapeVersion is the format version written by Tape.Save.
+const tapeVersion = 1
+
+// ErrNotRecorded is returned when replaying a prompt or text the tape has no
+// recording for.
+var ErrNotRecorded = errors.New("not recorded on the tape")
+
+// Tape is a file of recorded LLM responses and embeddings. A run with a
+// recording provider fills it; a replaying provider answers from it without
+// calling any API, so later steps can be re-run deterministically.
+// Responses are keyed like the response cache, by prompt, token limit and
+// temperature; embeddings by text. It is safe for concurrent use.
+type Tape struct {
+	path string
+
+	mu         sync.Mutex
+	responses  map[string]tapeResponse
+	embeddings map[string][]float64
+}
+
+type tapeResponse struct {
+	Prompt   string `json:"prompt"`
+	Response string `json:"response"`
+}
+
+type tapeFile struct {
+	Version    int                     `json:"version"`
+	Responses  map[string]tapeResponse `json:"responses"`
+	Embeddings map[string][]float64    `json:"embeddings"`
+}
+
+// OpenTape loads the tape at path, or starts an empty one if the file
+// doesn't exist yet.
+func OpenTape(path string) (*Tape, error) {
+	t := &Tape{path: path, responses: map[string]tapeResponse{}, embeddings: map[string][]float64{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f tapeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading tape %s: %w", path, err)
+	}
+	if f.Version != tapeVersion {
+		return nil, fmt.Errorf("unsupported tape version %d in %s", f.Version, path)
+	}
+	if f.Responses != nil {
+		t.responses = f.Responses
+	}
+	if f.Embeddings != nil {
+		t.embeddings = f.Embeddings
+	}
+	return t, nil
+}
+
+// Path returns the file the tape is read from and saved to.
+func (t *Tape) Path() string { return t.path }
+
+// Len returns the number of recorded responses and embeddings.
+func (t *Tape) Len() (responses, embeddings int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.responses), len(t.embeddings)
+}
+
+// Save writes the tape to its file.
+func (t *Tape) Save() error {
+	t.mu.Lock()
+	data, err := json.Marshal(tapeFile{Version: tapeVersion, Responses: t.responses, Embeddings: t.embeddings})
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0o644)
+}
+
+func (t *Tape) response(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.responses[key]
+	return r.Response, ok
+}
+
+func (t *Tape) putResponse(key, prompt, response string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.responses[key] = tapeResponse{Prompt: prompt, Response: response}
+}
+
+func (t *Tape) embedding(key string) ([]float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v, ok := t.embeddings[key]
+	return v, ok
+}
+
+func (t *Tape) putEmbedding(key string, v []float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.embeddings[key] = v
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordingProvider records every successful response of the wrapped
+// provider on a Tape. It wraps the cache, so cached responses are recorded
+// too.
+type RecordingProvider struct {
+	Provider
+	Tape *Tape
+}
+
+// NewRecordingProvider wraps p to record its responses on tape. A nil p
+// stays nil.
+func NewRecordingProvider(p Provider, tape *Tape) Provider {
+	if p == nil {
+		return nil
+	}
+	return &RecordingProvider{Provider: p, Tape: tape}
+}
+
+// Generate calls the wrapped provider and records the response.
+func (r *RecordingProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	text, err := r.Provider.Generate(ctx, prompt, maxTokens)
+	if err == nil {
+		r.Tape.putResponse(promptHash(prompt, maxTokens, temperatureFrom(ctx), systemFrom(ctx)), prompt, text)
+	}
+	return text, err
+}
+
+// GenerateStream streams from the wrapped provider and records the response
+// once the stream ends.
+func (r *RecordingProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	s, ok := r.Provider.(Streamer)
+	if !ok {
+		text, err := r.Generate(ctx, prompt, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan string, 1)
+		out <- text
+		close(out)
+		return out, nil
+	}
+
+	chunks, err := s.GenerateStream(ctx, prompt, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	key := promptHash(prompt, maxTokens, temperatureFrom(ctx), systemFrom(ctx))
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		var text []byte
+		for chunk := range chunks {
+			text = append(text, chunk...)
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() == nil && len(text) > 0 {
+			r.Tape.putResponse(key, prompt, string(text))
+		}
+	}()
+	return out, nil
+}
+
+// ReplayProvider answers prompts from a Tape and never calls an API. A prompt
+// that wasn't recorded fails with ErrNotRecorded.
+type ReplayProvider struct {
+	Tape *Tape
+}
+
+// NewReplayProvider returns a provider that replays tape.
+func NewReplayProvider(tape *Tape) *ReplayProvider {
+	return &ReplayProvider{Tape: tape}
+}
+
+// IsConfigured always reports true; missing recordings fail per call.
+func (r *ReplayProvider) IsConfigured() bool { return true }
+
+// Generate returns the recorded response to prompt.
+func (r *ReplayProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	text, ok := r.Tape.response(promptHash(prompt, maxTokens, temperatureFrom(ctx), systemFrom(ctx)))
+	if !ok {
+		return "", fmt.Errorf("replaying %q: %w", excerpt(prompt), ErrNotRecorded)
+	}
+	recordUsage(ctx, "replay", "", 0, 0)
+	return text, nil
+}
+
+// excerpt shortens a prompt for error messages.
+func excerpt(prompt string) string {
+	if len(prompt) > 60 {
+		return prompt[:60] + "..."
+	}
+	return prompt
+}
+
+// RecordingEmbedder records the embeddings of the wrapped embedder on a Tape.
+type RecordingEmbedder struct {
+	Embedder
+	Tape *Tape
+}
+
+// NewRecordingEmbedder wraps e to record its embeddings on tape. A nil e
+// stays nil.
+func NewRecordingEmbedder(e Embedder, tape *Tape) Embedder {
+	if e == nil {
+		return nil
+	}
+	return &RecordingEmbedder{Embedder: e, Tape: tape}
+}
+
+// Embed calls the wrapped embedder and records a vector per text.
+func (r *RecordingEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors, err := r.Embedder.Embed(ctx, texts)
+	if err == nil && len(vectors) == len(texts) {
+		for i, text := range texts {
+			r.Tape.putEmbedding(textHash(text), vectors[i])
+		}
+	}
+	return vectors, err
+}
+
+// ReplayEmbedder embeds texts from a Tape. A text that wasn't recorded fails
+// the whole call with ErrNotRecorded, as a failing embedder would.
+type ReplayEmbedder struct {
+	Tape *Tape
+}
+
+// NewReplayEmbedder returns an embedder that replays tape.
+func NewReplayEmbedder(tape *Tape) *ReplayEmbedder {
+	return &ReplayEmbedder{Tape: tape}
+}
+
+// Embed returns the recorded vector of every text.
+func (r *ReplayEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		v, ok := r.Tape.embedding(textHash(text))
+		if !ok {
+			return nil, fmt.Errorf("replaying embedding of %q: %w", excerpt(text), ErrNotRecorded)
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+// APIError is an unsuccessful HTTP response from an LLM API.
+type APIError struct {
+	API        string // e.g. "OpenAI"
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header; 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API returned %d: %s", e.API, e.StatusCode, e.Body)
+}
+
+// newAPIError reads an error response into an APIError.
+func newAPIError(api string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	return &APIError{
+		API:        api,
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads a Retry-After value given in seconds or as an HTTP
+// date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// isTransient reports whether an error is worth retrying: rate limiting,
+// overloaded or failing servers, timeouts, and connections that couldn't
+// be made or were dropped. A canceled call, a bad URL or an untrusted
+// certificate fails the same way every time.
+func isTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529: // 529: Anthropic overloaded
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	// The server closed the connection before answering.
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+// RetryProvider retries transient failures of the wrapped provider with
+// exponential backoff, waiting as long as a Retry-After header asks when one
+// is sent.
+type RetryProvider struct {
+	Provider
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	sleep          func(context.Context, time.Duration) error
+}
+
+// NewRetryProvider wraps p with the retry settings from cfg. With fewer than
+// two attempts configured, p is returned unwrapped.
+func NewRetryProvider(p Provider, cfg config.RetryConfig) Provider {
+	if p == nil || cfg.MaxAttempts < 2 {
+		return p
+	}
+	return &RetryProvider{
+		Provider:       p,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoffSeconds * float64(time.Second)),
+		MaxBackoff:     time.Duration(cfg.MaxBackoffSeconds * float64(time.Second)),
+		sleep:          sleepContext,
+	}
+}
+
+// Generate calls the wrapped provider, retrying transient errors.
+func (r *RetryProvider) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	var text string
+	err := r.retry(ctx, func() error {
+		var err error
+		text, err = r.Provider.Generate(ctx, prompt, maxTokens)
+		return err
+	})
+	return text, err
+}
+
+// GenerateStream streams from the wrapped provider, retrying transient
+// errors while opening the stream. Without streaming support it yields the
+// Generate response as one chunk.
+func (r *RetryProvider) GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error) {
+	s, ok := r.Provider.(Streamer)
+	if !ok {
+		text, err := r.Generate(ctx, prompt, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan string, 1)
+		out <- text
+		close(out)
+		return out, nil
+	}
+
+	var chunks <-chan string
+	err := r.retry(ctx, func() error {
+		var err error
+		chunks, err = s.GenerateStream(ctx, prompt, maxTokens)
+		return err
+	})
+	return chunks, err
+}
+
+func (r *RetryProvider) retry(ctx context.Context, call func() error) error {
+	backoff := r.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= r.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := backoff
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		if r.MaxBackoff > 0 && wait > r.MaxBackoff {
+			wait = r.MaxBackoff
+		}
+		// A retry that can't start before the caller's deadline would only
+		// fail with a less telling error.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		log.Printf("LLM call failed (attempt %d/%d), retrying in %s: %v", attempt, r.MaxAttempts, wait, err)
+		if err := r.sleep(ctx, wait); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync/atomic"
+)
+
+// Schema is a JSON Schema for the object a prompt asks the LLM to respond
+// with. Attached to a context with WithSchema, it makes providers that
+// support structured output constrain their response to it: OpenAI and
+// Azure via response_format, Ollama via format and Gemini via
+// responseJsonSchema. Claude, and servers that reject the schema, rely on
+// the prompt alone, so callers still parse responses with ParseJSONResponse.
+type Schema struct {
+	Name       string // identifies the schema to the API, e.g. "triage"
+	Definition map[string]any
+}
+
+type schemaKey struct{}
+
+// WithSchema returns a context whose LLM calls ask for responses matching s.
+func WithSchema(ctx context.Context, s Schema) context.Context {
+	return context.WithValue(ctx, schemaKey{}, &s)
+}
+
+// schemaFrom returns the schema attached to ctx, or nil.
+func schemaFrom(ctx context.Context) *Schema {
+	s, _ := ctx.Value(schemaKey{}).(*Schema)
+	return s
+}
+
+// Object describes a JSON object with the given properties, all required.
+func Object(properties map[string]any) map[string]any {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// Array describes a JSON array of items.
+func Array(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// String describes a JSON string, limited to values when any are given.
+func String(values ...string) map[string]any {
+	if len(values) > 0 {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// Integer describes a JSON integer.
+func Integer() map[string]any { return map[string]any{"type": "integer"} }
+
+// Number describes a JSON number.
+func Number() map[string]any { return map[string]any{"type": "number"} }
+
+// Boolean describes a JSON boolean.
+func Boolean() map[string]any { return map[string]any{"type": "boolean"} }
+
+// Nullable allows null in addition to the scalar type t.
+func Nullable(t map[string]any) map[string]any {
+	out := make(map[string]any, len(t))
+	for k, v := range t {
+		out[k] = v
+	}
+	out["type"] = []any{t["type"], "null"}
+	return out
+}
+
+// openAIResponseFormat is the response_format of an OpenAI-style chat
+// completion asking for s. Strict mode is off: it would reject the optional
+// and nullable fields the prompts allow.
+func (s *Schema) openAIResponseFormat() map[string]any {
+	return map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   s.Name,
+			"schema": s.Definition,
+			"strict": false,
+		},
+	}
+}
+
+// schemaSupport remembers whether an API rejected a response schema, so that
+// later calls go straight to plain prompting.
+type schemaSupport struct {
+	rejected atomic.Bool
+}
+
+// withSchema runs call with the schema attached to ctx, or with nil when
+// there is none or the API rejected one before. A 400 response to a call
+// with a schema is taken to mean the API doesn't support structured output:
+// the call is repeated without it, and schemas aren't sent to the API again.
+func withSchema[T any](ctx context.Context, support *schemaSupport, api string, call func(*Schema) (T, error)) (T, error) {
+	schema := schemaFrom(ctx)
+	if schema == nil || support.rejected.Load() {
+		return call(nil)
+	}
+	out, err := call(schema)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		log.Printf("%s rejected the %s response schema; falling back to prompt-only JSON", api, schema.Name)
+		support.rejected.Store(true)
+		return call(nil)
+	}
+	return out, err
+}
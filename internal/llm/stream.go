@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Streamer is implemented by providers that can stream generated text as it
+// is produced. The channel delivers text chunks in order and is closed when
+// generation ends; an error partway through is logged and ends the stream
+// early.
+type Streamer interface {
+	GenerateStream(ctx context.Context, prompt string, maxTokens int) (<-chan string, error)
+}
+
+// GenerateStreaming generates a response, passing each chunk to onChunk as it
+// arrives, and returns the full text. Providers without streaming support
+// fall back to Generate and deliver the response as a single chunk.
+func GenerateStreaming(ctx context.Context, p Provider, prompt string, maxTokens int, onChunk func(string)) (string, error) {
+	s, ok := p.(Streamer)
+	if !ok {
+		text, err := p.Generate(ctx, prompt, maxTokens)
+		if err == nil && onChunk != nil {
+			onChunk(text)
+		}
+		return text, err
+	}
+
+	chunks, err := s.GenerateStream(ctx, prompt, maxTokens)
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for chunk := range chunks {
+		text.WriteString(chunk)
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty streamed response")
+	}
+	return text.String(), nil
+}
+
+// openStream posts a JSON request and returns the response body for
+// streaming. api names the service in errors.
+func openStream(ctx context.Context, client *http.Client, url string, headers map[string]string, body map[string]any, api string) (io.ReadCloser, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s API error: %w", api, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError(api, resp)
+	}
+	return resp.Body, nil
+}
+
+// streamChunks reads body line by line in the background and sends the text
+// that parse extracts from each event. With sse, only the payloads of
+// "data:" lines are parsed; otherwise every non-empty line is (NDJSON).
+// parse reports done to stop reading.
+func streamChunks(ctx context.Context, body io.ReadCloser, sse bool, api string, parse func([]byte) (text string, done bool, err error)) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer body.Close()
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if sse {
+				payload, ok := bytes.CutPrefix(line, []byte("data:"))
+				if !ok {
+					continue
+				}
+				line = bytes.TrimSpace(payload)
+			}
+			if len(line) == 0 {
+				continue
+			}
+
+			text, done, err := parse(line)
+			if err != nil {
+				log.Printf("%s stream error: %v", api, err)
+				return
+			}
+			if text != "" {
+				select {
+				case out <- text:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			log.Printf("%s stream error: %v", api, err)
+		}
+	}()
+	return out
+}
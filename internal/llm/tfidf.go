@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// defaultTFIDFDimensions is the vector size of a TFIDFEmbedder without one.
+const defaultTFIDFDimensions = 4096
+
+// tfidfShared is the weight of the component every TF-IDF vector shares.
+// Texts without a word in common are orthogonal in plain TF-IDF, while model
+// embeddings of unrelated texts are still somewhat similar; the shared
+// component brings distances into the range the clustering thresholds are
+// tuned for.
+const tfidfShared = 0.5
+
+// TFIDFEmbedder is a built-in embedder that needs no model or server. Words
+// are hashed into a fixed number of dimensions and weighted by TF-IDF, with
+// document frequencies taken from the texts of the same call, so texts
+// sharing distinctive words end up close together. It captures no meaning
+// beyond shared words, but it always works.
+type TFIDFEmbedder struct {
+	Dimensions int             // hashed word dimensions; 0 means defaultTFIDFDimensions
+	StopWords  map[string]bool // skipped in addition to words of 2 or fewer letters
+}
+
+// NewTFIDFEmbedder creates a TF-IDF embedder with the default dimensions.
+func NewTFIDFEmbedder() *TFIDFEmbedder {
+	return &TFIDFEmbedder{}
+}
+
+// Embed returns a unit-length vector per text: its TF-IDF weights in the
+// first dimensions plus the shared component in the last one. A text without
+// any counted word only has the shared component.
+func (e *TFIDFEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	dims := e.Dimensions
+	if dims <= 0 {
+		dims = defaultTFIDFDimensions
+	}
+
+	counts := make([]map[int]float64, len(texts))
+	df := make(map[int]int)
+	for i, text := range texts {
+		counts[i] = make(map[int]float64)
+		for _, word := range e.words(text) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			counts[i][int(h.Sum32()%uint32(dims))]++
+		}
+		for idx := range counts[i] {
+			df[idx]++
+		}
+	}
+
+	n := float64(len(texts))
+	vectors := make([][]float64, len(texts))
+	for i, c := range counts {
+		v := make([]float64, dims+1)
+		v[dims] = math.Sqrt(tfidfShared)
+		var norm float64
+		for idx, tf := range c {
+			// Sublinear term frequency and smoothed inverse document
+			// frequency, so a word repeated in one text doesn't dominate
+			// and a word in every text still counts a little.
+			w := (1 + math.Log(tf)) * (math.Log((1+n)/(1+float64(df[idx]))) + 1)
+			v[idx] = w
+			norm += w * w
+		}
+		if norm > 0 {
+			scale := math.Sqrt(1-tfidfShared) / math.Sqrt(norm)
+			for idx := range c {
+				v[idx] *= scale
+			}
+		} else {
+			v[dims] = 1
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+// words splits text into the lowercase words that are counted.
+func (e *TFIDFEmbedder) words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, w := range fields {
+		if len([]rune(w)) > 2 && !e.StopWords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
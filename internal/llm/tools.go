@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Tool is a function the LLM may call before it answers a prompt. Tools work
+// the same with every provider, including ones without native function
+// calling: the prompt describes them, the model responds with a JSON list of
+// calls instead of its answer, and the results are appended to the prompt of
+// the next round.
+type Tool struct {
+	Name        string
+	Description string // what the tool returns and what its arguments mean
+	Parameters  map[string]any
+	// Call runs the tool. An error is shown to the model as the result, so
+	// it can correct its arguments.
+	Call func(ctx context.Context, args map[string]any) (string, error)
+}
+
+// MaxToolCallsPerRound is how many tool calls of one response are run;
+// further ones are ignored.
+const MaxToolCallsPerRound = 4
+
+const toolsInstruction = `
+
+Before answering, you may call these tools to look things up:
+%s
+
+To call tools, respond with ONLY this JSON instead of your answer:
+{"tool_calls": [{"name": "tool name", "arguments": {"argument": "value"}}]}
+
+Make at most %d calls at a time. You can call tools %d more time(s); the results will be added below.`
+
+const lastRoundInstruction = `
+
+You can't call tools any more. Respond with your answer now.`
+
+// toolCallsDefinition describes a response calling tools.
+var toolCallsDefinition = Object(map[string]any{
+	"tool_calls": Array(Object(map[string]any{
+		"name":      String(),
+		"arguments": map[string]any{"type": "object"},
+	})),
+})
+
+// GenerateWithTools answers prompt like GenerateStreaming, letting the LLM
+// call tools for up to rounds rounds first. A response schema attached to
+// ctx is widened to allow tool calls until the last round, which must answer.
+// Without tools or rounds it is GenerateStreaming.
+func GenerateWithTools(ctx context.Context, p Provider, prompt string, maxTokens int, tools []Tool, rounds int, onChunk func(string)) (string, error) {
+	if len(tools) == 0 || rounds <= 0 {
+		return GenerateStreaming(ctx, p, prompt, maxTokens, onChunk)
+	}
+	byName := make(map[string]Tool, len(tools))
+	var described []string
+	for _, t := range tools {
+		byName[t.Name] = t
+		params, _ := json.Marshal(t.Parameters)
+		described = append(described, fmt.Sprintf("- %s: %s Arguments: %s", t.Name, t.Description, params))
+	}
+	toolCtx := ctx
+	if answer := schemaFrom(ctx); answer != nil {
+		toolCtx = WithSchema(ctx, Schema{Name: answer.Name, Definition: map[string]any{
+			"anyOf": []any{answer.Definition, toolCallsDefinition},
+		}})
+	}
+
+	var results strings.Builder
+	for round := 0; round < rounds; round++ {
+		text := prompt + fmt.Sprintf(toolsInstruction, strings.Join(described, "\n"), MaxToolCallsPerRound, rounds-round) + results.String()
+		response, err := GenerateStreaming(toolCtx, p, text, maxTokens, onChunk)
+		if err != nil {
+			return "", err
+		}
+		calls := parseToolCalls(response)
+		if len(calls) == 0 {
+			return response, nil
+		}
+		if len(calls) > MaxToolCallsPerRound {
+			log.Printf("Ignoring %d of %d tool calls", len(calls)-MaxToolCallsPerRound, len(calls))
+			calls = calls[:MaxToolCallsPerRound]
+		}
+		for _, c := range calls {
+			args, _ := json.Marshal(c.Arguments)
+			fmt.Fprintf(&results, "\n\nResult of %s(%s):\n%s", c.Name, args, runTool(ctx, byName, c))
+		}
+	}
+	return GenerateStreaming(ctx, p, prompt+results.String()+lastRoundInstruction, maxTokens, onChunk)
+}
+
+// toolCall is one call a response asks for.
+type toolCall struct {
+	Name      string
+	Arguments map[string]any
+}
+
+// parseToolCalls returns the tool calls of a response, or nil for an answer.
+func parseToolCalls(response string) []toolCall {
+	parsed := ParseJSONResponse(response)
+	list, _ := parsed["tool_calls"].([]any)
+	var calls []toolCall
+	for _, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := obj["name"].(string)
+		args, _ := obj["arguments"].(map[string]any)
+		calls = append(calls, toolCall{Name: name, Arguments: args})
+	}
+	return calls
+}
+
+func runTool(ctx context.Context, tools map[string]Tool, c toolCall) string {
+	t, ok := tools[c.Name]
+	if !ok {
+		return fmt.Sprintf("Error: there is no tool named %q", c.Name)
+	}
+	out, err := t.Call(ctx, c.Arguments)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const translatePrompt = `Translate each text in this JSON list into %s. Keep product, model and company names, code and markdown formatting unchanged. Translate short labels as labels, not sentences.
+
+%s
+
+Respond with ONLY this JSON, one translation per text in the same order:
+{
+    "translations": ["First translation", "Second translation"]
+}`
+
+// translateSchema describes the response translatePrompt asks for.
+var translateSchema = Schema{Name: "translations", Definition: Object(map[string]any{"translations": Array(String())})}
+
+// LanguageInstruction returns a prompt sentence asking for output in
+// language, or "" when language is empty.
+func LanguageInstruction(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf(" Write all text in your response in %s, keeping product, model and company names unchanged.", language)
+}
+
+// Translate translates texts into language with a single Generate call. If
+// the call fails or the response does not hold one translation per text, the
+// original texts are returned along with the error, so callers can carry on
+// in the source language.
+func Translate(ctx context.Context, p Provider, language string, texts []string) ([]string, error) {
+	if len(texts) == 0 {
+		return texts, nil
+	}
+
+	input, err := json.MarshalIndent(texts, "", "  ")
+	if err != nil {
+		return texts, err
+	}
+	// Translations run a little longer than the source; leave headroom for
+	// the JSON wrapper.
+	maxTokens := min(256+len(input)/2, 4096)
+
+	responseText, err := p.Generate(WithSchema(ctx, translateSchema), fmt.Sprintf(translatePrompt, language, input), maxTokens)
+	if err != nil {
+		return texts, err
+	}
+
+	parsed := ParseJSONResponse(responseText)
+	arr, _ := parsed["translations"].([]any)
+	if len(arr) != len(texts) {
+		return texts, fmt.Errorf("expected %d translations, got %d", len(texts), len(arr))
+	}
+	out := make([]string, len(texts))
+	for i, v := range arr {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			s = texts[i]
+		}
+		out[i] = s
+	}
+	return out, nil
+}
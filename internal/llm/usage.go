@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+)
+
+// Usage counts the tokens of one or more LLM calls.
+type Usage struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64 // estimated from the configured prices
+}
+
+// Add accumulates o into u.
+func (u *Usage) Add(o Usage) {
+	u.Calls += o.Calls
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.CostUSD += o.CostUSD
+}
+
+// Call is the token usage reported by one successful LLM call.
+type Call struct {
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Meter collects the calls made with a context returned by WithMeter, and
+// the exchanges an AuditProvider records. It is safe for concurrent use.
+type Meter struct {
+	mu        sync.Mutex
+	calls     []Call
+	exchanges []Exchange
+	parent    *Meter
+}
+
+type meterKey struct{}
+
+// WithMeter returns a context whose LLM calls are recorded in m. Calls are
+// also recorded in the meter ctx already carries, if any, so a meter for a
+// single call doesn't hide it from the step's meter.
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	m.parent = meterFrom(ctx)
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+func meterFrom(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
+
+// Calls returns the recorded calls in order.
+func (m *Meter) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Exchanges returns the recorded exchanges in order.
+func (m *Meter) Exchanges() []Exchange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Exchange(nil), m.exchanges...)
+}
+
+// recordUsage adds a call to the meter attached to ctx, if any. Providers
+// call it once per successful request with the token counts the API reports.
+func recordUsage(ctx context.Context, provider, model string, promptTokens, completionTokens int) {
+	c := Call{
+		Provider:         provider,
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}
+	for m := meterFrom(ctx); m != nil; m = m.parent {
+		m.mu.Lock()
+		m.calls = append(m.calls, c)
+		m.mu.Unlock()
+	}
+}
+
+// EstimateCost prices a call with the per-million-token rates in prices. A
+// model matches the longest configured name it starts with, so dated
+// snapshots such as "gpt-4o-mini-2024-07-18" use the "gpt-4o-mini" rate.
+// Unpriced models, such as local Ollama ones, cost nothing.
+func EstimateCost(prices map[string]config.ModelPrice, c Call) float64 {
+	var match string
+	for name := range prices {
+		if strings.HasPrefix(c.Model, name) && len(name) > len(match) {
+			match = name
+		}
+	}
+	if match == "" {
+		return 0
+	}
+	p := prices[match]
+	return (float64(c.PromptTokens)*p.InputPerMillion + float64(c.CompletionTokens)*p.OutputPerMillion) / 1e6
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VoyageBaseURL is the default endpoint of VoyageEmbedder.
+const VoyageBaseURL = "https://api.voyageai.com/v1"
+
+// voyageEmbedBatch caps the texts sent per Voyage embeddings request by
+// default; the API accepts up to 1000, within a token limit per request.
+const voyageEmbedBatch = 128
+
+// VoyageEmbedder generates embeddings via the Voyage AI embeddings API.
+type VoyageEmbedder struct {
+	Model     string
+	APIKey    string
+	BaseURL   string
+	BatchSize int // texts per request; 0 means voyageEmbedBatch
+	client    *http.Client
+}
+
+// NewVoyageEmbedder creates a new Voyage embedder.
+func NewVoyageEmbedder(model, apiKeyEnv string) *VoyageEmbedder {
+	return &VoyageEmbedder{
+		Model:   model,
+		APIKey:  os.Getenv(apiKeyEnv),
+		BaseURL: VoyageBaseURL,
+		client:  newClient(DefaultTimeout),
+	}
+}
+
+// Embed generates embeddings for the given texts, in batches of BatchSize.
+// Texts are embedded as documents, the input type Voyage recommends for
+// retrieval and clustering corpora.
+func (e *VoyageEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.APIKey == "" {
+		return nil, fmt.Errorf("Voyage API key not configured")
+	}
+
+	size := cmp.Or(e.BatchSize, voyageEmbedBatch)
+	embeddings := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		batch := texts[start:min(start+size, len(texts))]
+		vecs, err := e.embedBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, vecs...)
+	}
+	return embeddings, nil
+}
+
+func (e *VoyageEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	data, err := json.Marshal(map[string]any{"model": e.Model, "input": texts, "input_type": "document"})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(e.BaseURL, "/")+"/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Voyage embed error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("Voyage", resp)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding embeddings: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("Voyage returned %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || embeddings[d.Index] != nil {
+			return nil, fmt.Errorf("Voyage returned an embedding with unexpected index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	recordUsage(ctx, "voyage", e.Model, result.Usage.TotalTokens, 0)
+	return embeddings, nil
+}
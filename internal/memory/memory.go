@@ -0,0 +1,287 @@
+// Package memory keeps a rolling summary of recurring topics across
+// periods. After a period's narratives are written, its sections are
+// matched to the topics remembered so far and each topic's summary is
+// brought up to date; synthesis recalls the memories that match a storyline,
+// so a narrative can note "the third outage this month" without the reader
+// going back through old briefings.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+	"github.com/TobiSchelling/AICrawler/internal/textmatch"
+)
+
+const updatePrompt = `You keep the long-term memory of a daily AI news briefing: a short record of each recurring topic, so later briefings can point out continuity.
+
+Topics remembered so far:
+%s
+
+Sections of the briefing for %s:
+%s
+
+Decide for each section which remembered topic it continues, or whether it starts a recurring topic worth remembering. A topic is specific, such as one vendor's outages, a model family or a regulation, never just "AI" or "tools". Leave out sections that don't belong to a lasting topic.
+
+For every topic these sections touch, write its updated memory: a summary of at most 80 words recording what happened and when, with dates, keeping the earlier events of its summary that still matter, and 3-8 keywords: the product, company, project or technique names a later storyline on it would mention.
+
+Respond with ONLY this JSON:
+{
+    "topics": [
+        {"topic": "Name of the remembered topic, or a short new one", "summary": "...", "keywords": ["..."]}
+    ]
+}
+
+Use {"topics": []} when no section belongs to a lasting topic.`
+
+// updateSchema describes the response updatePrompt asks for.
+var updateSchema = llm.Schema{Name: "topic_memory", Definition: llm.Object(map[string]any{
+	"topics": llm.Array(llm.Object(map[string]any{
+		"topic":    llm.String(),
+		"summary":  llm.String(),
+		"keywords": llm.Array(llm.String()),
+	})),
+})}
+
+// brieflyNotedTitle is the title of the Briefly Noted section, whose
+// bullets are too thin to remember anything by.
+const brieflyNotedTitle = "Briefly Noted"
+
+// Limits that keep the update prompt to a predictable size.
+const (
+	maxPromptTopics  = 30  // remembered topics shown, matching ones first
+	maxSectionLength = 800 // characters of each narrative
+)
+
+// DefaultKeepDays is how long a topic is remembered after it last came up.
+const DefaultKeepDays = 90
+
+// Result holds the results of a memory update.
+type Result struct {
+	Updated int // remembered topics the period continued
+	Created int // topics the period started
+}
+
+// Options configures memory updates.
+type Options struct {
+	// KeepDays is how long a topic is remembered after it last came up;
+	// 0 means DefaultKeepDays.
+	KeepDays int
+
+	// MaxTokens limits the response to the update prompt; 0 means
+	// defaultMaxTokens.
+	MaxTokens int
+}
+
+// defaultMaxTokens fits the summaries of a period's topics.
+const defaultMaxTokens = 2048
+
+// Updater brings the topic memory up to date with a period's narratives.
+type Updater struct {
+	db       *database.DB
+	provider llm.Provider
+	opts     Options
+}
+
+// NewUpdater creates a new topic memory updater.
+func NewUpdater(db *database.DB, provider llm.Provider, opts Options) *Updater {
+	return &Updater{db: db, provider: provider, opts: opts}
+}
+
+// UpdatePeriod matches the period's narratives to the remembered topics and
+// stores an updated memory for every topic they touch. Memories are built
+// from those of earlier periods only, so updating a period again replaces
+// what the last update wrote for it instead of counting the period twice.
+func (u *Updater) UpdatePeriod(ctx context.Context, periodID string) (*Result, error) {
+	if u.provider == nil {
+		return nil, llm.ErrNoProvider
+	}
+	narratives, err := u.db.GetNarrativesForPeriod(periodID)
+	if err != nil {
+		return nil, err
+	}
+	var sections []database.StorylineNarrative
+	for _, n := range narratives {
+		if n.Title != brieflyNotedTitle {
+			sections = append(sections, n)
+		}
+	}
+	if len(sections) == 0 {
+		log.Printf("No narratives to remember for %s", periodID)
+		return &Result{}, nil
+	}
+
+	remembered, err := u.db.GetTopicMemories(periodID, Since(periodID, u.opts.KeepDays))
+	if err != nil {
+		return nil, err
+	}
+	shown := promptTopics(remembered, sections)
+
+	var sectionText []string
+	for i, n := range sections {
+		text := n.NarrativeText
+		if len(text) > maxSectionLength {
+			text = text[:maxSectionLength] + "..."
+		}
+		sectionText = append(sectionText, fmt.Sprintf("%d. %s\n%s", i+1, n.Title, text))
+	}
+	prompt := fmt.Sprintf(updatePrompt, formatTopics(shown), periodID, strings.Join(sectionText, "\n\n"))
+	responseText, err := u.provider.Generate(llm.WithSchema(ctx, updateSchema), prompt, cmp.Or(u.opts.MaxTokens, defaultMaxTokens))
+	if err != nil {
+		return nil, err
+	}
+	parsed := llm.ParseJSONResponse(responseText)
+	if parsed == nil {
+		return nil, fmt.Errorf("topic memory response could not be parsed")
+	}
+
+	byName := make(map[string]database.TopicMemory, len(remembered))
+	for _, m := range remembered {
+		byName[strings.ToLower(m.Topic)] = m
+	}
+	r := &Result{}
+	var memories []database.TopicMemory
+	seen := make(map[string]bool)
+	items, _ := parsed["topics"].([]any)
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		topic, _ := obj["topic"].(string)
+		summary, _ := obj["summary"].(string)
+		topic, summary = strings.TrimSpace(topic), strings.TrimSpace(summary)
+		if topic == "" || summary == "" || seen[strings.ToLower(topic)] {
+			continue
+		}
+		seen[strings.ToLower(topic)] = true
+
+		m := database.TopicMemory{Topic: topic, Summary: summary, Periods: 1}
+		for _, k := range stringList(obj["keywords"]) {
+			if k = strings.TrimSpace(k); k != "" {
+				m.Keywords = append(m.Keywords, k)
+			}
+		}
+		if prev, ok := byName[strings.ToLower(topic)]; ok {
+			m.Topic = prev.Topic
+			m.Periods = prev.Periods + 1
+			if len(m.Keywords) == 0 {
+				m.Keywords = prev.Keywords
+			}
+			r.Updated++
+		} else {
+			r.Created++
+		}
+		memories = append(memories, m)
+	}
+
+	if err := u.db.ReplaceTopicMemories(periodID, memories); err != nil {
+		return nil, err
+	}
+	log.Printf("Topic memory updated: %d topics continued, %d new", r.Updated, r.Created)
+	return r, nil
+}
+
+// Since returns the first period a memory recalled for periodID may have
+// been written for, keepDays (0 means DefaultKeepDays) before it starts.
+func Since(periodID string, keepDays int) string {
+	start, _, _ := strings.Cut(periodID, "..")
+	day, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return ""
+	}
+	return day.AddDate(0, 0, -cmp.Or(keepDays, DefaultKeepDays)).Format("2006-01-02")
+}
+
+// Relevant returns up to n memories whose topic name or keywords appear in
+// text, the best matching first; ties go to the most recently updated.
+func Relevant(memories []database.TopicMemory, text string, n int) []database.TopicMemory {
+	text = strings.ToLower(text)
+	type match struct {
+		memory database.TopicMemory
+		score  int
+	}
+	var matches []match
+	for _, m := range memories {
+		score := 0
+		for _, term := range append([]string{m.Topic}, m.Keywords...) {
+			if textmatch.ContainsWords(text, strings.ToLower(strings.TrimSpace(term))) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, match{m, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return cmp.Or(cmp.Compare(b.score, a.score), cmp.Compare(b.memory.PeriodID, a.memory.PeriodID))
+	})
+
+	var relevant []database.TopicMemory
+	for _, m := range matches[:min(n, len(matches))] {
+		relevant = append(relevant, m.memory)
+	}
+	return relevant
+}
+
+// Format lists memories for a prompt, one line each with when the topic
+// last came up and how often.
+func Format(memories []database.TopicMemory) string {
+	var lines []string
+	for _, m := range memories {
+		times := "once before"
+		if m.Periods > 1 {
+			times = fmt.Sprintf("%d times before", m.Periods)
+		}
+		lines = append(lines, fmt.Sprintf("- %s (came up %s, last on %s): %s", m.Topic, times, m.PeriodID, m.Summary))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// promptTopics picks the remembered topics shown in the update prompt:
+// those matching one of the sections first, then the most recent others.
+func promptTopics(remembered []database.TopicMemory, sections []database.StorylineNarrative) []database.TopicMemory {
+	var all []string
+	for _, n := range sections {
+		all = append(all, n.Title, n.NarrativeText)
+	}
+	shown := Relevant(remembered, strings.Join(all, "\n"), maxPromptTopics)
+	for _, m := range remembered {
+		if len(shown) == maxPromptTopics {
+			break
+		}
+		if !slices.ContainsFunc(shown, func(s database.TopicMemory) bool { return s.Topic == m.Topic }) {
+			shown = append(shown, m)
+		}
+	}
+	return shown
+}
+
+func formatTopics(memories []database.TopicMemory) string {
+	if len(memories) == 0 {
+		return "(none yet)"
+	}
+	var lines []string
+	for _, m := range memories {
+		lines = append(lines, fmt.Sprintf("- %s [keywords: %s]: %s", m.Topic, strings.Join(m.Keywords, ", "), m.Summary))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func stringList(v any) []string {
+	arr, _ := v.([]any)
+	var out []string
+	for _, item := range arr {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
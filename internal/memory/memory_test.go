@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+type mockProvider struct {
+	response string
+	prompts  []string
+}
+
+func (m *mockProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return m.response, nil
+}
+
+func (m *mockProvider) IsConfigured() bool { return true }
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func ptr(s string) *string { return &s }
+
+func TestUpdatePeriod(t *testing.T) {
+	db := openTestDB(t)
+	db.ReplaceTopicMemories("2026-02-01", []database.TopicMemory{
+		{Topic: "Vendor X outages", Summary: "Jan 28 and Feb 1: API down for hours", Keywords: []string{"Vendor X"}, Periods: 2},
+	})
+	db.ReplaceTopicMemories("2025-10-01", []database.TopicMemory{{Topic: "Forgotten", Summary: "Long ago"}})
+
+	aid, _ := db.InsertArticle("https://a.com/outage", "Vendor X is down again", nil, nil, nil, ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Vendor X outage", []int64{aid})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Vendor X Goes Down Again", "The API was unavailable all morning.", nil)
+	bid, _ := db.InsertStoryline("2026-02-06", brieflyNotedTitle, []int64{aid})
+	db.InsertStorylineNarrative(bid, "2026-02-06", brieflyNotedTitle, "- **Something else**", nil)
+
+	resp, _ := json.Marshal(map[string]any{"topics": []map[string]any{
+		{"topic": "vendor x outages", "summary": "Jan 28, Feb 1 and Feb 6: API down", "keywords": []string{}},
+		{"topic": "Uptime SLAs", "summary": "Feb 6: customers ask for credits", "keywords": []string{"SLA"}},
+		{"topic": "", "summary": "Dropped"},
+	}})
+	mock := &mockProvider{response: string(resp)}
+	result, err := NewUpdater(db, mock, Options{}).UpdatePeriod(context.Background(), "2026-02-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 || result.Created != 1 {
+		t.Errorf("expected one topic continued and one new, got %+v", result)
+	}
+	prompt := mock.prompts[0]
+	if !strings.Contains(prompt, "Vendor X outages [keywords: Vendor X]") || strings.Contains(prompt, "Forgotten") {
+		t.Errorf("expected the remembered topic and not the forgotten one in the prompt, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "1. Vendor X Goes Down Again") || strings.Contains(prompt, "Something else") {
+		t.Errorf("expected the narrative without Briefly Noted in the prompt, got %q", prompt)
+	}
+
+	// Updating the period again must not count it twice.
+	NewUpdater(db, mock, Options{}).UpdatePeriod(context.Background(), "2026-02-06")
+	memories, _ := db.GetTopicMemories("2026-02-07", "2026-01-01")
+	if len(memories) != 2 {
+		t.Fatalf("expected 2 topics remembered, got %+v", memories)
+	}
+	outages := memories[1]
+	if memories[0].Topic == "Vendor X outages" {
+		outages = memories[0]
+	}
+	if outages.Topic != "Vendor X outages" || outages.Periods != 3 || len(outages.Keywords) != 1 {
+		t.Errorf("expected the remembered name, the third period and the old keywords, got %+v", outages)
+	}
+}
+
+func TestRelevant(t *testing.T) {
+	memories := []database.TopicMemory{
+		{Topic: "Meta model releases", PeriodID: "2026-02-01", Keywords: []string{"Llama", "Meta"}},
+		{Topic: "Vendor X outages", PeriodID: "2026-02-03", Keywords: []string{"Vendor X"}},
+		{Topic: "Llama fine-tuning", PeriodID: "2026-02-04", Keywords: []string{"Llama", "LoRA"}},
+	}
+
+	got := Relevant(memories, "Meta ships Llama 5", 2)
+	if len(got) != 2 || got[0].Topic != "Meta model releases" || got[1].Topic != "Llama fine-tuning" {
+		t.Errorf("expected the best match first, got %+v", got)
+	}
+	if got := Relevant(memories, "Metadata for vendor xylophones", 2); len(got) != 0 {
+		t.Errorf("expected only whole-word matches, got %+v", got)
+	}
+}
+
+func TestSince(t *testing.T) {
+	if got := Since("2026-03-01..2026-03-05", 30); got != "2026-01-30" {
+		t.Errorf("expected 30 days before the period starts, got %q", got)
+	}
+	if got := Since("2026-03-01", 0); got != "2025-12-01" {
+		t.Errorf("expected DefaultKeepDays, got %q", got)
+	}
+}
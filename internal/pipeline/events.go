@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/TobiSchelling/AICrawler/internal/deliver"
+	"github.com/TobiSchelling/AICrawler/internal/events"
+)
+
+// Events returns the bus the pipeline publishes its events on, for
+// integrations to subscribe to before a run.
+func (p *Pipeline) Events() *events.Bus {
+	return p.bus
+}
+
+// emit publishes e and appends what its handlers reported to r as steps.
+func (p *Pipeline) emit(ctx context.Context, r *Result, e events.Event) {
+	for _, o := range p.bus.Publish(ctx, e) {
+		r.Steps = append(r.Steps, StepResult{Name: o.Name, Summary: o.Summary, Err: o.Err})
+	}
+}
+
+// subscribeIntegrations subscribes the configured delivery targets and
+// webhooks.
+func (p *Pipeline) subscribeIntegrations() {
+	if d := deliver.NewDeliverer(p.cfg.Delivery, p.db); d.HasTargets() {
+		p.bus.Subscribe(events.BriefingComposed, "Deliver", deliverHandler(d))
+	}
+
+	for _, kind := range events.Kinds {
+		var hooks []*events.Webhook
+		for _, w := range p.cfg.Delivery.Webhooks {
+			if w.URL == "" {
+				continue
+			}
+			if len(w.Events) == 0 || slices.Contains(w.Events, kind) {
+				hooks = append(hooks, events.NewWebhook(w.URL, p.cfg.Fetch.Agent()))
+			}
+		}
+		if len(hooks) > 0 {
+			p.bus.Subscribe(kind, "Webhooks", webhookHandler(hooks))
+		}
+	}
+	for _, w := range p.cfg.Delivery.Webhooks {
+		for _, kind := range w.Events {
+			if !slices.Contains(events.Kinds, kind) {
+				log.Printf("Unknown event %q for webhook %s; ignoring it", kind, w.URL)
+			}
+		}
+	}
+}
+
+// deliverHandler pushes each composed edition to the delivery targets.
+func deliverHandler(d *deliver.Deliverer) events.Handler {
+	return func(ctx context.Context, e events.Event) (string, error) {
+		log.Println("Delivering briefing...")
+		result, err := d.Deliver(ctx, e.PeriodID, e.Edition)
+		if err != nil {
+			return "", err
+		}
+		summary := fmt.Sprintf("Uploaded %d files, notified %d chats", result.Uploaded, result.Notified)
+		if result.Errors > 0 {
+			summary += fmt.Sprintf(" (%d failed)", result.Errors)
+		}
+		return summary, nil
+	}
+}
+
+// webhookHandler posts each event to hooks. Failed posts are counted in
+// the summary rather than failing the run.
+func webhookHandler(hooks []*events.Webhook) events.Handler {
+	return func(ctx context.Context, e events.Event) (string, error) {
+		failed := 0
+		for _, h := range hooks {
+			if err := h.Post(ctx, e); err != nil {
+				log.Printf("Error posting %s event: %v", e.Kind, err)
+				failed++
+			}
+		}
+		summary := fmt.Sprintf("Posted %s to %d webhooks", e.Kind, len(hooks)-failed)
+		if failed > 0 {
+			summary += fmt.Sprintf(" (%d failed)", failed)
+		}
+		return summary, nil
+	}
+}
@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/config"
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/deliver"
+	"github.com/TobiSchelling/AICrawler/internal/jobs"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+// Job kinds registered by RegisterJobs.
+const (
+	JobRun       = "run"       // full pipeline, intra-day update or evening edition
+	JobRefetch   = "refetch"   // fetch content still missing for a period
+	JobRecluster = "recluster" // re-embed, re-cluster, re-synthesize and re-compose a period
+	JobDeliver   = "deliver"   // export a composed edition to the delivery targets
+
+	JobResynthesize = "resynthesize" // rewrite a period's stale narratives and re-compose it
+)
+
+// JobPayload is the payload of every pipeline job kind.
+type JobPayload struct {
+	PeriodID string `json:"period_id"`
+	DaysBack int    `json:"days_back,omitempty"`
+	Edition  string `json:"edition,omitempty"`
+	PastDate bool   `json:"past_date,omitempty"` // run: PeriodID is a past day, see Pipeline.RunForDate
+	Update   bool   `json:"update,omitempty"`    // run: update the morning briefing, see Pipeline.RunUpdate
+	Tape     string `json:"tape,omitempty"`      // run: file to record LLM calls on or replay them from
+	TapeMode string `json:"tape_mode,omitempty"` // TapeRecord or TapeReplay, with Tape
+}
+
+// Retry policies per job kind. A failed run repeats the whole pipeline, so
+// it waits longer and tries less often than the single-step kinds.
+var (
+	runRetryPolicy  = jobs.RetryPolicy{MaxAttempts: 2, Backoff: 10 * time.Minute, MaxBackoff: time.Hour}
+	stepRetryPolicy = jobs.DefaultRetryPolicy
+)
+
+// RegisterJobs registers the pipeline's job kinds on q. Each job builds a
+// fresh Pipeline, so provider availability is checked when the job runs.
+func RegisterJobs(q *jobs.Queue, cfg *config.Config, db *database.DB) {
+	q.Register(JobRun, runRetryPolicy, func(ctx context.Context, raw json.RawMessage) (string, error) {
+		payload, err := decodePayload(raw)
+		if err != nil {
+			return "", jobs.Permanent(err)
+		}
+		var tape *llm.Tape
+		if payload.Tape != "" {
+			if tape, err = llm.OpenTape(payload.Tape); err != nil {
+				return "", jobs.Permanent(err)
+			}
+		}
+		p := NewWithTape(cfg, db, tape, payload.TapeMode)
+		var r *Result
+		switch {
+		case payload.Edition == database.EditionEvening:
+			r = p.RunEvening(ctx, payload.PeriodID)
+		case payload.Update:
+			r = p.RunUpdate(ctx, payload.PeriodID)
+		case payload.PastDate:
+			r = p.RunForDate(ctx, payload.PeriodID)
+		default:
+			r = p.Run(ctx, payload.PeriodID, max(payload.DaysBack, 1))
+		}
+		if payload.TapeMode == TapeRecord {
+			if err := tape.Save(); err != nil {
+				return r.Report(), fmt.Errorf("saving tape: %w", err)
+			}
+		}
+		return r.Report(), permanent(r.Err())
+	})
+
+	q.Register(JobRefetch, stepRetryPolicy, func(ctx context.Context, raw json.RawMessage) (string, error) {
+		payload, err := decodePayload(raw)
+		if err != nil {
+			return "", jobs.Permanent(err)
+		}
+		p := New(cfg, db)
+		fetched := p.timed("fetch", p.runFetch)(ctx, payload.PeriodID)
+		if !cfg.Synthesize.ResynthesizeStale {
+			return fetched.Summary, nil
+		}
+		if n, err := db.CountStaleNarratives(payload.PeriodID); err != nil || n == 0 {
+			return fetched.Summary, err
+		}
+		r := &Result{PeriodID: payload.PeriodID, Steps: []StepResult{fetched}}
+		p.resynthesize(ctx, r)
+		return r.Report(), permanent(r.Err())
+	})
+
+	q.Register(JobResynthesize, stepRetryPolicy, func(ctx context.Context, raw json.RawMessage) (string, error) {
+		payload, err := decodePayload(raw)
+		if err != nil {
+			return "", jobs.Permanent(err)
+		}
+		// An earlier job may have rewritten them already.
+		if n, err := db.CountStaleNarratives(payload.PeriodID); err != nil || n == 0 {
+			return "No stale narratives", err
+		}
+		r := &Result{PeriodID: payload.PeriodID}
+		New(cfg, db).resynthesize(ctx, r)
+		return r.Report(), permanent(r.Err())
+	})
+
+	q.Register(JobRecluster, stepRetryPolicy, func(ctx context.Context, raw json.RawMessage) (string, error) {
+		payload, err := decodePayload(raw)
+		if err != nil {
+			return "", jobs.Permanent(err)
+		}
+		p := New(cfg, db)
+		p.checkEmbeddingCache(ctx)
+		r := &Result{PeriodID: payload.PeriodID}
+		for _, step := range []func(context.Context, string) StepResult{p.timed("cluster", p.runCluster), p.timed("synthesize", p.runSynthesize)} {
+			s := p.measure(ctx, payload.PeriodID, step)
+			r.Steps = append(r.Steps, s)
+			if s.Err != nil {
+				return r.Report(), permanent(r.Err())
+			}
+		}
+		r.Steps = append(r.Steps, p.measure(ctx, payload.PeriodID, p.timed("compose", func(ctx context.Context, periodID string) StepResult {
+			return p.runCompose(ctx, periodID, r.Steps)
+		})))
+		if r.Err() == nil {
+			p.remember(ctx, r)
+		}
+		return r.Report(), permanent(r.Err())
+	})
+
+	q.Register(JobDeliver, stepRetryPolicy, func(ctx context.Context, raw json.RawMessage) (string, error) {
+		payload, err := decodePayload(raw)
+		if err != nil {
+			return "", jobs.Permanent(err)
+		}
+		edition := payload.Edition
+		if edition == "" {
+			edition = database.EditionMorning
+		}
+		d := deliver.NewDeliverer(cfg.Delivery, db)
+		if !d.HasTargets() {
+			return "No delivery targets configured", nil
+		}
+		result, err := d.Deliver(ctx, payload.PeriodID, edition)
+		if err != nil {
+			return "", permanent(err)
+		}
+		summary := fmt.Sprintf("Uploaded %d files, notified %d chats", result.Uploaded, result.Notified)
+		if result.Errors > 0 {
+			return summary, fmt.Errorf("%d deliveries failed", result.Errors)
+		}
+		return summary, nil
+	})
+}
+
+// resynthesize rewrites the stale narratives of r's period and, unless that
+// fails, re-composes its morning briefing and updates the topic memory,
+// appending the steps to r.
+func (p *Pipeline) resynthesize(ctx context.Context, r *Result) {
+	s := p.measure(ctx, r.PeriodID, p.timed("synthesize", p.runSynthesize))
+	r.Steps = append(r.Steps, s)
+	if s.Err != nil {
+		return
+	}
+	s = p.measure(ctx, r.PeriodID, p.timed("compose", func(ctx context.Context, periodID string) StepResult {
+		return p.runCompose(ctx, periodID, r.Steps)
+	}))
+	r.Steps = append(r.Steps, s)
+	if s.Err == nil {
+		p.remember(ctx, r)
+	}
+}
+
+// permanent marks the errors that retrying a job can't fix: no LLM
+// provider, a rejected API key and a missing briefing.
+func permanent(err error) error {
+	if errors.Is(err, llm.ErrNoProvider) || errors.Is(err, llm.ErrUnauthorized) || errors.Is(err, database.ErrPeriodNotFound) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+func decodePayload(raw json.RawMessage) (JobPayload, error) {
+	var p JobPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, fmt.Errorf("decoding job payload: %w", err)
+	}
+	if p.PeriodID == "" {
+		return p, errors.New("job payload has no period_id")
+	}
+	return p, nil
+}
+
+// Report formats the step results one step per line, as stored with a job,
+// followed by the run's LLM usage if it made any calls.
+func (r *Result) Report() string {
+	var lines []string
+	for i, step := range r.Steps {
+		line := fmt.Sprintf("Step %d/%d: %s: ", i+1, len(r.Steps), step.Name)
+		if step.Err != nil {
+			line += "Error: " + step.Err.Error()
+		} else {
+			line += step.Summary
+		}
+		if step.Usage.Calls > 0 {
+			line += " [" + FormatUsage(step.Usage) + "]"
+		}
+		lines = append(lines, line)
+	}
+	if total := r.Usage(); total.Calls > 0 {
+		lines = append(lines, "LLM usage: "+FormatUsage(total))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Usage returns the LLM usage summed over all steps.
+func (r *Result) Usage() llm.Usage {
+	var total llm.Usage
+	for _, step := range r.Steps {
+		total.Add(step.Usage)
+	}
+	return total
+}
+
+// FormatUsage renders usage as "N calls, P prompt + C completion tokens, ~$X".
+func FormatUsage(u llm.Usage) string {
+	return fmt.Sprintf("%d calls, %d prompt + %d completion tokens, ~$%.4f", u.Calls, u.PromptTokens, u.CompletionTokens, u.CostUSD)
+}
+
+// Err returns the first step error, if any.
+func (r *Result) Err() error {
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			return fmt.Errorf("%s: %w", step.Name, step.Err)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package pipeline
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/TobiSchelling/AICrawler/internal/cluster"
@@ -11,17 +14,25 @@ import (
 	"github.com/TobiSchelling/AICrawler/internal/compose"
 	"github.com/TobiSchelling/AICrawler/internal/config"
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/dedup"
+	"github.com/TobiSchelling/AICrawler/internal/events"
 	"github.com/TobiSchelling/AICrawler/internal/fetch"
+	"github.com/TobiSchelling/AICrawler/internal/language"
 	"github.com/TobiSchelling/AICrawler/internal/llm"
+	"github.com/TobiSchelling/AICrawler/internal/memory"
+	"github.com/TobiSchelling/AICrawler/internal/releases"
+	"github.com/TobiSchelling/AICrawler/internal/storage"
 	"github.com/TobiSchelling/AICrawler/internal/synthesize"
 	"github.com/TobiSchelling/AICrawler/internal/triage"
 )
 
 // StepResult holds the result of a single pipeline step.
 type StepResult struct {
-	Name    string
-	Summary string
-	Err     error
+	Name     string
+	Summary  string
+	Err      error
+	Degraded string    // partial failure the step worked around, noted in the briefing
+	Usage    llm.Usage // LLM calls made by the step
 }
 
 // Result holds the results of a full pipeline run.
@@ -34,77 +45,311 @@ type Result struct {
 type Pipeline struct {
 	cfg      *config.Config
 	db       *database.DB
-	provider llm.Provider
 	embedder llm.Embedder
+	runID    string // groups the LLM usage records of this run
+	replay   bool   // LLM calls are answered from a tape; see NewWithTape
+	pool     *llm.Pool
+	bus      *events.Bus // delivery and webhooks subscribe here; see Events
+
+	// Providers per LLM-backed step; steps without their own provider or
+	// model in summarization.steps share one. Temperatures and token limits
+	// are applied per call, so they don't need a provider of their own.
+	triageLLM     llm.Provider
+	releasesLLM   llm.Provider
+	synthesizeLLM llm.Provider
+	composeLLM    llm.Provider
 }
 
+// Tape modes of NewWithTape.
+const (
+	TapeRecord = "record" // call the providers and record their responses
+	TapeReplay = "replay" // answer every LLM call from the tape
+)
+
 // New creates a new pipeline.
 func New(cfg *config.Config, db *database.DB) *Pipeline {
+	return NewWithTape(cfg, db, nil, "")
+}
+
+// NewWithTape creates a pipeline whose LLM responses and embeddings are
+// recorded on tape (TapeRecord) or replayed from it (TapeReplay). A
+// replaying pipeline calls no API: its runs skip collection and fetching
+// and reprocess the period's stored articles, so a recorded run can be
+// repeated while clustering or composition logic changes. Prompts that
+// weren't recorded fail like provider errors. The caller saves the tape.
+func NewWithTape(cfg *config.Config, db *database.DB, tape *llm.Tape, mode string) *Pipeline {
 	summ := cfg.Summarization
-	provider := llm.CreateProvider(
-		summ.Provider,
-		summ.Model,
-		summ.OllamaURL,
-		summ.OpenAIModel,
-		summ.APIKeyEnv,
-	)
+	// One pool for every step's provider, so max_concurrency bounds the
+	// calls of the whole run. Cache hits and replayed responses don't take
+	// a slot.
+	pool := llm.NewPool(summ.MaxConcurrency)
+	build := func(s config.Summarization) llm.Provider {
+		var p llm.Provider
+		switch mode {
+		case TapeReplay:
+			p = llm.NewReplayProvider(tape)
+		case TapeRecord:
+			p = llm.NewRecordingProvider(llm.NewCachingProvider(pool.Limit(llm.CreateProvider(s)), db, summ.Cache), tape)
+		default:
+			p = llm.NewCachingProvider(pool.Limit(llm.CreateProvider(s)), db, summ.Cache)
+		}
+		if summ.Audit.Enabled {
+			p = llm.NewAuditProvider(p)
+		}
+		return p
+	}
+	var shared llm.Provider
+	provider := func(step config.StepConfig) llm.Provider {
+		if step.OwnProvider() {
+			return build(summ.ForStep(step))
+		}
+		if shared == nil {
+			shared = build(summ)
+		}
+		return shared
+	}
+	if summ.Audit.Enabled && summ.Audit.KeepDays > 0 {
+		if err := db.PruneLLMCalls(time.Duration(summ.Audit.KeepDays) * 24 * time.Hour); err != nil {
+			log.Printf("Error pruning LLM call log: %v", err)
+		}
+	}
 
 	var embedder llm.Embedder
-	embModel := summ.EmbeddingModel
-	if embModel == "" {
-		embModel = "nomic-embed-text"
-	}
-	baseURL := summ.OllamaURL
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
+	switch mode {
+	case TapeReplay:
+		embedder = llm.NewReplayEmbedder(tape)
+	case TapeRecord:
+		embedder = llm.NewRecordingEmbedder(llm.WithBatchSize(llm.CreateEmbedder(summ), cfg.Performance.EmbedBatchSize), tape)
+	default:
+		embedder = llm.WithBatchSize(llm.CreateEmbedder(summ), cfg.Performance.EmbedBatchSize)
 	}
-	embedder = llm.NewOllamaEmbedder(embModel, baseURL)
 
-	return &Pipeline{
-		cfg:      cfg,
-		db:       db,
-		provider: provider,
-		embedder: embedder,
+	p := &Pipeline{
+		cfg:           cfg,
+		db:            db,
+		embedder:      embedder,
+		runID:         time.Now().UTC().Format("20060102T150405"),
+		replay:        mode == TapeReplay,
+		pool:          pool,
+		triageLLM:     provider(summ.Steps.Triage),
+		releasesLLM:   provider(summ.Steps.Releases),
+		synthesizeLLM: provider(summ.Steps.Synthesize),
+		composeLLM:    provider(summ.Steps.Compose),
+		bus:           events.NewBus(),
+	}
+	// A replay must not deliver or notify anything.
+	if mode != TapeReplay {
+		p.subscribeIntegrations()
 	}
+	return p
 }
 
 // Run executes the full 6-step pipeline.
 func (p *Pipeline) Run(ctx context.Context, periodID string, daysBack int) *Result {
+	return p.run(ctx, periodID, func(r *Result) StepResult {
+		return p.runCollect(ctx, r, collect.NewCollector(p.cfg, p.db, daysBack), periodID)
+	})
+}
+
+// RunForDate executes the full pipeline for a past day. Collection is
+// limited to sources that can be searched by date; the articles already
+// collected for that day are processed along with them.
+func (p *Pipeline) RunForDate(ctx context.Context, date string) *Result {
+	return p.run(ctx, date, func(r *Result) StepResult {
+		return p.runCollect(ctx, r, collect.NewCollectorForDate(p.cfg, p.db, date), date)
+	})
+}
+
+func (p *Pipeline) run(ctx context.Context, periodID string, collectStep func(r *Result) StepResult) *Result {
 	r := &Result{PeriodID: periodID}
+	p.checkEmbeddingCache(ctx)
+
+	// Steps 1 and 2 would change the period's articles, and with them the
+	// prompts a replay expects.
+	if p.replay {
+		r.Steps = append(r.Steps,
+			StepResult{Name: "Collect", Summary: "Skipped while replaying"},
+			StepResult{Name: "Fetch", Summary: "Skipped while replaying"})
+	} else {
+		// Step 1: Collect
+		if step := collectStep(r); step.Err != nil {
+			return r
+		}
+
+		// Step 2: Fetch content
+		r.Steps = append(r.Steps, p.timed("fetch", p.runFetch)(ctx, periodID))
+	}
 
-	// Step 1: Collect
-	step := p.runCollect(periodID, daysBack)
+	// Step 3: Triage
+	step := p.measure(ctx, periodID, p.timed("triage", p.runTriage))
 	r.Steps = append(r.Steps, step)
 	if step.Err != nil {
 		return r
 	}
+	p.emit(ctx, r, events.Event{Kind: events.TriageCompleted, PeriodID: periodID, Summary: step.Summary})
+	r.Steps = append(r.Steps, p.measure(ctx, periodID, p.timed("releases", p.runReleases)))
 
-	// Step 2: Fetch content
-	step = p.runFetch(periodID)
+	// Step 4: Cluster
+	step = p.measure(ctx, periodID, p.timed("cluster", p.runCluster))
 	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
 
-	// Step 3: Triage
-	step = p.runTriage(ctx, periodID)
+	// Step 5: Synthesize
+	step = p.measure(ctx, periodID, p.timed("synthesize", p.runSynthesize))
 	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
 
-	// Step 4: Cluster
-	step = p.runCluster(ctx, periodID)
+	// Step 6: Compose
+	step = p.measure(ctx, periodID, p.timed("compose", func(ctx context.Context, periodID string) StepResult {
+		return p.runCompose(ctx, periodID, r.Steps)
+	}))
 	r.Steps = append(r.Steps, step)
 	if step.Err != nil {
 		return r
 	}
+	p.emit(ctx, r, events.Event{Kind: events.BriefingComposed, PeriodID: periodID, Edition: database.EditionMorning})
 
-	// Step 5: Synthesize
-	step = p.runSynthesize(ctx, periodID)
+	// Remember the recurring topics for later periods
+	p.remember(ctx, r)
+	return r
+}
+
+// RunEvening executes the evening delta edition: collect, fetch and triage
+// new articles, then compose an edition covering only what the morning
+// briefing doesn't. Clustering and synthesis are left to the morning run.
+func (p *Pipeline) RunEvening(ctx context.Context, periodID string) *Result {
+	r := &Result{PeriodID: periodID}
+
+	if step := p.runCollect(ctx, r, collect.NewCollector(p.cfg, p.db, 1), periodID); step.Err != nil {
+		return r
+	}
+
+	r.Steps = append(r.Steps, p.timed("fetch", p.runFetch)(ctx, periodID))
+	step := p.measure(ctx, periodID, p.timed("triage", p.runTriage))
 	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
+	p.emit(ctx, r, events.Event{Kind: events.TriageCompleted, PeriodID: periodID, Summary: step.Summary})
+	r.Steps = append(r.Steps, p.measure(ctx, periodID, p.timed("releases", p.runReleases)))
 
-	// Step 6: Compose
-	step = p.runCompose(ctx, periodID)
+	step = p.measure(ctx, periodID, p.timed("compose", func(ctx context.Context, periodID string) StepResult {
+		return p.runComposeEvening(ctx, periodID, r.Steps)
+	}))
+	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
+	p.emit(ctx, r, events.Event{Kind: events.BriefingComposed, PeriodID: periodID, Edition: database.EditionEvening})
+	return r
+}
+
+// RunUpdate brings today's morning briefing up to date during the day:
+// collect, fetch and triage new articles, add them to the storylines they
+// fit, rewrite the narratives that changed, and list the rest under "Later
+// today". Storylines and the TL;DR are not rebuilt; that is the morning
+// run's job, which must have composed the briefing first.
+func (p *Pipeline) RunUpdate(ctx context.Context, periodID string) *Result {
+	r := &Result{PeriodID: periodID}
+
+	if step := p.runCollect(ctx, r, collect.NewCollector(p.cfg, p.db, 1), periodID); step.Err != nil {
+		return r
+	}
+
+	r.Steps = append(r.Steps, p.timed("fetch", p.runFetch)(ctx, periodID))
+	step := p.measure(ctx, periodID, p.timed("triage", p.runTriage))
+	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
+	p.emit(ctx, r, events.Event{Kind: events.TriageCompleted, PeriodID: periodID, Summary: step.Summary})
+	r.Steps = append(r.Steps, p.measure(ctx, periodID, p.timed("releases", p.runReleases)))
+
+	step = p.measure(ctx, periodID, p.timed("cluster", p.runAssign))
+	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
+	step = p.measure(ctx, periodID, p.timed("synthesize", p.runSynthesize))
 	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
 
+	step = p.measure(ctx, periodID, p.timed("compose", func(ctx context.Context, periodID string) StepResult {
+		return p.runComposeUpdate(ctx, periodID, r.Steps)
+	}))
+	r.Steps = append(r.Steps, step)
+	if step.Err != nil {
+		return r
+	}
+	p.emit(ctx, r, events.Event{Kind: events.BriefingComposed, PeriodID: periodID, Edition: database.EditionMorning})
+	p.remember(ctx, r)
 	return r
 }
 
+func (p *Pipeline) composeOptions() compose.Options {
+	return compose.Options{
+		TeamDigest:    p.cfg.Compose.TeamDigest,
+		PolicyWatch:   p.cfg.Policy.Enabled,
+		Regulations:   p.cfg.Policy.Regulations,
+		Language:      p.cfg.Output.Language,
+		MaxTokens:     p.cfg.Summarization.Steps.Compose.MaxTokens,
+		MaxStorylines: p.cfg.Compose.MaxStorylines,
+		Audience:      p.cfg.Persona.Audience,
+	}
+}
+
+func (p *Pipeline) clusterOptions() cluster.Options {
+	opts := cluster.Options{MinClusterSize: p.cfg.Cluster.MinClusterSize}
+	switch p.cfg.Cluster.Singletons {
+	case "spotlight":
+		opts.SpotlightScore = p.cfg.Cluster.SpotlightMinScore
+	case "", "briefly_noted":
+	default:
+		log.Printf("Unknown cluster.singletons %q; briefly noting them", p.cfg.Cluster.Singletons)
+	}
+	return opts
+}
+
+func (p *Pipeline) triageOptions() triage.Options {
+	opts := triage.Options{
+		MaxTokens:    p.cfg.Summarization.Steps.Triage.MaxTokens,
+		RetryPasses:  p.cfg.Triage.RetryPasses,
+		RetryBackoff: time.Duration(p.cfg.Triage.RetryBackoffSeconds * float64(time.Second)),
+		ErrorBudget:  p.cfg.Triage.ErrorBudget,
+		Audience:     p.cfg.Persona.Audience,
+		Languages:    p.cfg.Triage.Languages,
+		Pool:         p.pool,
+	}
+	// Triage calls still take a slot of the shared pool, so a lower limit
+	// is the only one that changes anything.
+	if n := p.cfg.Performance.TriageConcurrency; n > 0 && n < p.pool.Size() {
+		opts.Pool = llm.NewPool(n)
+	}
+	if c := p.cfg.Triage.Classifier; c.Enabled {
+		opts.Classifier = &triage.ClassifierOptions{
+			Embedder:    p.embedder,
+			MinExamples: c.MinExamples,
+			AcceptAbove: c.AcceptAbove,
+			RejectBelow: c.RejectBelow,
+			MinAccuracy: c.MinAccuracy,
+		}
+	}
+	if !p.cfg.Policy.Enabled {
+		return opts
+	}
+	opts.Regulations = p.cfg.Policy.Regulations
+	for _, f := range p.cfg.Policy.Feeds {
+		opts.PolicySources = append(opts.PolicySources, collect.FeedConfig{URL: f.URL, Name: f.Name}.SourceName())
+	}
+	return opts
+}
+
 // DryRun shows what would be done without executing.
 func (p *Pipeline) DryRun(periodID string) *Result {
 	r := &Result{PeriodID: periodID}
@@ -152,71 +397,522 @@ func (p *Pipeline) DryRun(periodID string) *Result {
 		})
 	}
 
+	if p.cfg.Synthesize.Memory.Enabled {
+		remembered, _ := p.db.GetTopicMemories(periodID, memory.Since(periodID, p.recallDays()))
+		r.Steps = append(r.Steps, StepResult{
+			Name:    "Memory",
+			Summary: fmt.Sprintf("[dry-run] %d topics remembered from earlier periods", len(remembered)),
+		})
+	}
+
 	return r
 }
 
-func (p *Pipeline) runCollect(periodID string, daysBack int) StepResult {
+// runCollect collects into periodID, appends the step to r and announces
+// the new articles.
+func (p *Pipeline) runCollect(ctx context.Context, r *Result, collector *collect.Collector, periodID string) StepResult {
 	log.Println("Step 1/6: Collecting articles...")
-	collector := collect.NewCollector(p.cfg, p.db, daysBack)
 	result := collector.Collect(periodID)
-	return StepResult{
+	step := StepResult{
 		Name:    "Collect",
-		Summary: fmt.Sprintf("Found %d new articles (%d total, %d duplicates)", result.NewArticles, result.TotalFound, result.Duplicates),
+		Summary: fmt.Sprintf("Found %d new articles (%d total, %d duplicates, %d filtered)", result.NewArticles, result.TotalFound, result.Duplicates, result.Filtered),
+	}
+	if result.Updated > 0 {
+		step.Summary += fmt.Sprintf(", %d updated to fetch again", result.Updated)
+	}
+	r.Steps = append(r.Steps, step)
+	if len(result.ArticleIDs) > 0 {
+		p.emit(ctx, r, events.Event{Kind: events.ArticleCollected, PeriodID: periodID, ArticleIDs: result.ArticleIDs})
 	}
+	return step
 }
 
-func (p *Pipeline) runFetch(periodID string) StepResult {
+func (p *Pipeline) runFetch(ctx context.Context, periodID string) StepResult {
 	log.Println("Step 2/6: Fetching article content...")
-	fetcher := fetch.NewContentFetcher(p.db, 15*time.Second)
-	result := fetcher.FetchMissingContent(&periodID)
-	return StepResult{
-		Name:    "Fetch",
-		Summary: fmt.Sprintf("Fetched %d articles, %d failed", result.Fetched, result.Failed),
+	fetcher := NewFetcher(p.cfg, p.db)
+	result := fetcher.FetchMissingContent(ctx, &periodID)
+	step := StepResult{Name: "Fetch", Summary: FetchSummary(result)}
+	if p.cfg.Fetch.Refetch.Enabled {
+		// Updated articles of earlier periods are fetched again as well.
+		if r := fetcher.RefetchUpdatedContent(ctx); r.Fetched > 0 {
+			step.Summary += fmt.Sprintf(", %d updated articles fetched again (%d with new content)", r.Fetched, r.Refreshed)
+		}
+	}
+	if n := MarkNearDuplicates(p.cfg, p.db, periodID); n > 0 {
+		step.Summary += fmt.Sprintf(", %d near-duplicates", n)
+	}
+	return step
+}
+
+// MarkNearDuplicates marks the period's near-duplicate articles when
+// fetch.dedup is enabled, and returns how many it marked.
+func MarkNearDuplicates(cfg *config.Config, db *database.DB, periodID string) int {
+	d := cfg.Fetch.Dedup
+	if !d.Enabled {
+		return 0
+	}
+	result, err := dedup.MarkPeriod(db, periodID, dedup.Options{MaxDistance: d.MaxDistance, Days: d.Days})
+	if err != nil {
+		log.Printf("Error marking near-duplicates: %v", err)
+		return 0
 	}
+	return result.Duplicates
+}
+
+// NewFetcher creates the content fetcher of the fetch step, limited by the
+// performance settings, signed in with the secrets file, if any, and with
+// the page cache, the headless browser and the Wayback Machine fallback
+// when they are enabled. Pages cached longer than
+// the TTL ago are removed.
+func NewFetcher(cfg *config.Config, db *database.DB) *fetch.ContentFetcher {
+	perf := cfg.Performance
+	opts := fetch.Options{
+		Timeout:      perf.HTTP.FetchTimeout(),
+		MaxRedirects: perf.HTTP.MaxRedirects,
+		Workers:      perf.FetchWorkers,
+		PerHost:      perf.FetchesPerHost,
+		Retries:      perf.FetchRetries,
+		MaxBodyBytes: perf.HTTP.MaxPageBytes(),
+		Wayback:      cfg.Fetch.Wayback.Enabled,
+		UserAgent:    cfg.Fetch.Agent(),
+		Headers:      cfg.Fetch.Headers,
+		Proxy:        cfg.Network.ProxyURL(),
+		Extractors:   cfg.Fetch.Extractors,
+	}
+	if c := cfg.Fetch.Cache; c.Enabled && c.TTLHours > 0 {
+		store := storage.New(cfg.GetDataDir())
+		opts.Cache = store.Path(storage.Cache)
+		opts.CacheTTL = time.Duration(c.TTLHours * float64(time.Hour))
+		if _, _, err := store.Clean(storage.Cache, time.Now().Add(-opts.CacheTTL), false); err != nil {
+			log.Printf("Error pruning the page cache: %v", err)
+		}
+	}
+	if path := cfg.Fetch.SecretsFile; path != "" {
+		secrets, err := fetch.LoadSecrets(path)
+		if err != nil {
+			log.Printf("Error loading fetch.secrets_file, fetching without it: %v", err)
+		}
+		opts.Secrets = secrets
+	}
+	if b := cfg.Fetch.Browser; b.Enabled {
+		opts.Browser = cmp.Or(b.Path, fetch.FindBrowser())
+		opts.BrowserTimeout = b.Timeout()
+		if opts.Browser == "" {
+			log.Println("fetch.browser is enabled, but no Chrome or Chromium was found; set fetch.browser.path")
+		}
+	}
+	return fetch.NewContentFetcher(db, opts)
+}
+
+// FetchSummary describes the result of fetching content in one line.
+func FetchSummary(result *fetch.Result) string {
+	summary := fmt.Sprintf("Fetched %d articles, %d failed", result.Fetched, result.Failed)
+	if result.Retrying > 0 {
+		summary += fmt.Sprintf(" (%d to retry)", result.Retrying)
+	}
+	if result.Rendered > 0 {
+		summary += fmt.Sprintf(", %d rendered in the browser", result.Rendered)
+	}
+	if result.Archived > 0 {
+		summary += fmt.Sprintf(", %d from the Wayback Machine", result.Archived)
+	}
+	if result.Paywalled > 0 {
+		summary += fmt.Sprintf(", %d paywalled", result.Paywalled)
+	}
+	if result.Cached > 0 {
+		summary += fmt.Sprintf(", %d pages from the cache", result.Cached)
+	}
+	if result.Merged > 0 {
+		summary += fmt.Sprintf(", %d syndicated copies merged", result.Merged)
+	}
+	if result.Refreshed > 0 {
+		summary += fmt.Sprintf(", %d updated articles with new content", result.Refreshed)
+	}
+	return summary
 }
 
 func (p *Pipeline) runTriage(ctx context.Context, periodID string) StepResult {
 	log.Println("Step 3/6: Triaging articles...")
-	triager := triage.NewTriager(p.db, p.provider)
+	ctx = p.stepContext(ctx, p.cfg.Summarization.Steps.Triage)
+	translated := p.prepareLanguages(ctx, periodID)
+	triager := triage.NewTriager(p.db, p.triageLLM, p.triageOptions())
 	result := triager.TriageArticles(ctx, periodID)
-	return StepResult{
+	step := StepResult{
 		Name:    "Triage",
 		Summary: fmt.Sprintf("Triaged %d articles: %d relevant, %d skipped", result.Processed, result.Relevant, result.Skipped),
 	}
+	if translated > 0 {
+		step.Summary += fmt.Sprintf(" (%d translated)", translated)
+	}
+	if result.OtherLanguage > 0 {
+		step.Summary += fmt.Sprintf(" (%d in other languages)", result.OtherLanguage)
+	}
+	if result.Classified > 0 {
+		step.Summary += fmt.Sprintf(" (%d by the classifier)", result.Classified)
+	}
+	if result.Muted > 0 {
+		step.Summary += fmt.Sprintf(" (%d muted)", result.Muted)
+	}
+	if result.Recovered > 0 {
+		step.Summary += fmt.Sprintf(" (%d on retry)", result.Recovered)
+	}
+	if failed := result.Errors + result.Deferred; failed > 0 {
+		step.Degraded = fmt.Sprintf("%d articles could not be triaged", failed) + failureReason(result.LastErr)
+		// Without a single triaged article the briefing would be empty.
+		if result.Processed == 0 && providerFailure(result.LastErr) {
+			step.Err = fmt.Errorf("no article could be triaged: %w", result.LastErr)
+		}
+	}
+	return step
+}
+
+// prepareLanguages detects the language of the period's articles and, with
+// triage.translate, translates those to be triaged into English with the
+// triage provider. It returns how many it translated. Articles it fails on
+// are triaged in their own language.
+func (p *Pipeline) prepareLanguages(ctx context.Context, periodID string) int {
+	if _, err := language.DetectArticles(p.db, periodID); err != nil {
+		log.Printf("Error detecting article languages: %v", err)
+		return 0
+	}
+	if !p.cfg.Triage.Translate || p.triageLLM == nil {
+		return 0
+	}
+	translator := language.NewTranslator(p.db, p.triageLLM, language.Options{Accept: p.cfg.Triage.Languages, Pool: p.triageOptions().Pool})
+	result, err := translator.TranslateArticles(ctx, periodID)
+	if err != nil {
+		log.Printf("Error translating articles: %v", err)
+		return 0
+	}
+	return result.Translated
+}
+
+// runReleases registers model releases announced in newly triaged articles.
+func (p *Pipeline) runReleases(ctx context.Context, periodID string) StepResult {
+	log.Println("Extracting model releases...")
+	steps := p.cfg.Summarization.Steps
+	extractor := releases.NewExtractor(p.db, p.releasesLLM, releases.Options{MaxTokens: steps.Releases.MaxTokens})
+	result := extractor.ExtractPeriod(p.stepContext(ctx, steps.Releases), periodID)
+	return StepResult{
+		Name:    "Model releases",
+		Summary: fmt.Sprintf("Scanned %d articles, %d new models registered", result.Scanned, result.Releases),
+	}
 }
 
 func (p *Pipeline) runCluster(ctx context.Context, periodID string) StepResult {
 	log.Println("Step 4/6: Clustering into storylines...")
-	clusterer := cluster.NewClusterer(p.db, p.embedder, 0)
+	clusterer := cluster.NewClusterer(p.db, p.embedder, 0, p.clusterOptions())
 	result, err := clusterer.ClusterArticles(ctx, periodID)
 	if err != nil {
 		return StepResult{Name: "Cluster", Err: err}
 	}
-	return StepResult{
+	step := StepResult{
 		Name:    "Cluster",
 		Summary: fmt.Sprintf("Created %d storylines from %d articles", result.StorylineCount, result.ArticleCount),
 	}
+	if result.SpotlightCount > 0 {
+		step.Summary += fmt.Sprintf(" (%d spotlighted)", result.SpotlightCount)
+	}
+	if result.KeywordFallback {
+		step.Degraded = "clustering degraded: built-in TF-IDF embeddings used"
+	}
+	return step
+}
+
+// runAssign adds new articles to the period's existing storylines.
+func (p *Pipeline) runAssign(ctx context.Context, periodID string) StepResult {
+	log.Println("Assigning new articles to storylines...")
+	clusterer := cluster.NewClusterer(p.db, p.embedder, 0, p.clusterOptions())
+	result, err := clusterer.AssignArticles(ctx, periodID)
+	if err != nil {
+		return StepResult{Name: "Assign", Err: err}
+	}
+	return StepResult{
+		Name:    "Assign",
+		Summary: fmt.Sprintf("Added %d articles to %d storylines, %d left for later today", result.Assigned, result.Storylines, result.Unassigned),
+	}
+}
+
+// checkEmbeddingCache drops cached embeddings the embedder no longer
+// matches, before any step runs. A failing embedder only skips the check;
+// clustering copes with it on its own.
+func (p *Pipeline) checkEmbeddingCache(ctx context.Context) {
+	if _, err := cluster.NewClusterer(p.db, p.embedder, 0, p.clusterOptions()).CheckEmbeddingCache(ctx); err != nil {
+		log.Printf("Skipping the embedding cache check: %v", err)
+	}
 }
 
 func (p *Pipeline) runSynthesize(ctx context.Context, periodID string) StepResult {
 	log.Println("Step 5/6: Synthesizing narratives...")
-	synth := synthesize.NewSynthesizer(p.db, p.provider)
-	result := synth.SynthesizePeriod(ctx, periodID)
-	return StepResult{
+	steps := p.cfg.Summarization.Steps
+	synth := synthesize.NewSynthesizer(p.db, p.synthesizeLLM, synthesize.Options{
+		Language:       p.cfg.Output.Language,
+		MaxTokens:      steps.Synthesize.MaxTokens,
+		Audience:       p.cfg.Persona.Audience,
+		Pool:           p.pool,
+		ExcerptLookups: p.cfg.Synthesize.ExcerptLookups,
+		RecallDays:     p.recallDays(),
+	})
+	result := synth.SynthesizePeriod(p.stepContext(ctx, steps.Synthesize), periodID)
+	step := StepResult{
 		Name:    "Synthesize",
 		Summary: fmt.Sprintf("Synthesized %d narratives", result.NarrativesCreated),
 	}
+	if result.Refreshed > 0 {
+		step.Summary += fmt.Sprintf(", %d stale ones rewritten", result.Refreshed)
+	}
+	if result.Errors > 0 {
+		step.Degraded = fmt.Sprintf("%d narratives could not be synthesized", result.Errors) + failureReason(result.LastErr)
+		if result.NarrativesCreated == 0 && providerFailure(result.LastErr) {
+			step.Err = fmt.Errorf("no narrative could be synthesized: %w", result.LastErr)
+		}
+	}
+	return step
+}
+
+// recallDays is how far back synthesis recalls topic memories; 0 when the
+// memory is off.
+func (p *Pipeline) recallDays() int {
+	if mem := p.cfg.Synthesize.Memory; mem.Enabled {
+		return cmp.Or(mem.KeepDays, memory.DefaultKeepDays)
+	}
+	return 0
 }
 
-func (p *Pipeline) runCompose(ctx context.Context, periodID string) StepResult {
+// remember brings the topic memory up to date with the narratives of r's
+// period, appending the step to r unless the memory is off. It runs once
+// the briefing is composed, so a failure leaves the memory as it was and
+// the briefing untouched.
+func (p *Pipeline) remember(ctx context.Context, r *Result) {
+	if !p.cfg.Synthesize.Memory.Enabled {
+		return
+	}
+	r.Steps = append(r.Steps, p.measure(ctx, r.PeriodID, p.timed("memory", p.runMemory)))
+}
+
+func (p *Pipeline) runMemory(ctx context.Context, periodID string) StepResult {
+	log.Println("Updating topic memory...")
+	steps := p.cfg.Summarization.Steps
+	updater := memory.NewUpdater(p.db, p.synthesizeLLM, memory.Options{KeepDays: p.cfg.Synthesize.Memory.KeepDays})
+	result, err := updater.UpdatePeriod(p.stepContext(ctx, steps.Synthesize), periodID)
+	if err != nil {
+		log.Printf("Error updating topic memory: %v", err)
+		return StepResult{
+			Name:     "Memory",
+			Summary:  "Topic memory not updated" + failureReason(err),
+			Degraded: "topic memory not updated" + failureReason(err),
+		}
+	}
+	return StepResult{
+		Name:    "Memory",
+		Summary: fmt.Sprintf("Remembered %d topics, %d of them new", result.Updated+result.Created, result.Created),
+	}
+}
+
+// runCompose composes the morning briefing and annotates it with the
+// degradations reported by the earlier steps of this run.
+func (p *Pipeline) runCompose(ctx context.Context, periodID string, steps []StepResult) StepResult {
 	log.Println("Step 6/6: Composing briefing...")
-	comp := compose.NewComposer(p.db, p.provider)
-	briefing, err := comp.ComposeBriefing(ctx, periodID)
+	comp := compose.NewComposer(p.db, p.composeLLM, p.composeOptions())
+	briefing, err := comp.ComposeBriefing(p.stepContext(ctx, p.cfg.Summarization.Steps.Compose), periodID)
 	if err != nil {
 		return StepResult{Name: "Compose", Err: err}
 	}
-	return StepResult{
+	step := StepResult{
 		Name:    "Compose",
 		Summary: fmt.Sprintf("Briefing composed: %d storylines, %d articles", briefing.StorylineCount, briefing.ArticleCount),
 	}
+	articles, _ := p.db.GetRelevantArticles(periodID)
+	p.annotate(&step, periodID, database.EditionMorning, steps, articles)
+	return step
+}
+
+// runComposeEvening composes the evening edition and annotates it like
+// runCompose does the morning one.
+func (p *Pipeline) runComposeEvening(ctx context.Context, periodID string, steps []StepResult) StepResult {
+	log.Println("Composing evening edition...")
+	comp := compose.NewComposer(p.db, p.composeLLM, p.composeOptions())
+	briefing, err := comp.ComposeEveningEdition(p.stepContext(ctx, p.cfg.Summarization.Steps.Compose), periodID)
+	if err != nil {
+		return StepResult{Name: "Compose evening edition", Err: err}
+	}
+	step := StepResult{
+		Name:    "Compose evening edition",
+		Summary: fmt.Sprintf("Evening edition composed: %d new articles", briefing.ArticleCount),
+	}
+	articles, _ := comp.EveningArticles(periodID)
+	p.annotate(&step, periodID, database.EditionEvening, steps, articles)
+	return step
+}
+
+// runComposeUpdate updates the morning briefing with what arrived since it
+// was composed. The quality note stays unless this run has one of its own.
+func (p *Pipeline) runComposeUpdate(ctx context.Context, periodID string, steps []StepResult) StepResult {
+	log.Println("Updating briefing...")
+	comp := compose.NewComposer(p.db, p.composeLLM, p.composeOptions())
+	briefing, err := comp.ComposeUpdate(p.stepContext(ctx, p.cfg.Summarization.Steps.Compose), periodID)
+	if err != nil {
+		return StepResult{Name: "Compose update", Err: err}
+	}
+	step := StepResult{
+		Name:    "Compose update",
+		Summary: fmt.Sprintf("Briefing updated: %d storylines, %d articles", briefing.StorylineCount, briefing.ArticleCount),
+	}
+	articles, _ := p.db.GetRelevantArticles(periodID)
+	p.annotate(&step, periodID, database.EditionMorning, steps, articles)
+	return step
+}
+
+// providerFailure reports whether err means every LLM call of a step will
+// fail, so it is better to stop the run than to compose an empty briefing.
+func providerFailure(err error) bool {
+	return errors.Is(err, llm.ErrNoProvider) || errors.Is(err, llm.ErrUnauthorized) || errors.Is(err, llm.ErrRateLimited)
+}
+
+// failureReason names the cause of a degraded step's failures when err is
+// one of the provider errors, for its quality note.
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, llm.ErrNoProvider):
+		return " (no LLM provider available)"
+	case errors.Is(err, llm.ErrUnauthorized):
+		return " (API key rejected)"
+	case errors.Is(err, llm.ErrRateLimited):
+		return " (rate limited by the provider)"
+	}
+	return ""
+}
+
+// timed runs a step within its timeout from performance.step_timeouts_minutes,
+// if it has one, and reports the step as degraded when the timeout cut it
+// short.
+func (p *Pipeline) timed(step string, run func(context.Context, string) StepResult) func(context.Context, string) StepResult {
+	timeout := p.cfg.Performance.StepTimeout(step)
+	if timeout <= 0 {
+		return run
+	}
+	return func(ctx context.Context, periodID string) StepResult {
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		result := run(stepCtx, periodID)
+		if ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+			note := fmt.Sprintf("stopped after its %s timeout", timeout)
+			if result.Degraded != "" {
+				note = result.Degraded + "; " + note
+			}
+			result.Degraded = note
+		}
+		return result
+	}
+}
+
+// stepContext attaches a step's sampling temperature and the configured
+// system prompt to ctx.
+func (p *Pipeline) stepContext(ctx context.Context, step config.StepConfig) context.Context {
+	ctx = llm.WithTemperature(ctx, p.cfg.Summarization.StepTemperature(step))
+	if p.cfg.Persona.SystemPrompt != "" {
+		ctx = llm.WithSystem(ctx, p.cfg.Persona.SystemPrompt)
+	}
+	return ctx
+}
+
+// measure runs an LLM-backed step with a usage meter attached to ctx, stores
+// the calls it made under the run ID with their estimated cost, and reports
+// their totals in the step's Usage. Audited exchanges are stored in the call
+// log under the step's name.
+func (p *Pipeline) measure(ctx context.Context, periodID string, run func(context.Context, string) StepResult) StepResult {
+	meter := &llm.Meter{}
+	step := run(llm.WithMeter(ctx, meter), periodID)
+
+	calls := meter.Calls()
+	records := make([]database.LLMUsage, 0, len(calls))
+	for _, c := range calls {
+		cost := llm.EstimateCost(p.cfg.Summarization.Pricing, c)
+		step.Usage.Add(llm.Usage{
+			Calls:            1,
+			PromptTokens:     c.PromptTokens,
+			CompletionTokens: c.CompletionTokens,
+			CostUSD:          cost,
+		})
+		records = append(records, database.LLMUsage{
+			RunID:            p.runID,
+			PeriodID:         &periodID,
+			Step:             step.Name,
+			Provider:         c.Provider,
+			Model:            c.Model,
+			PromptTokens:     c.PromptTokens,
+			CompletionTokens: c.CompletionTokens,
+			CostUSD:          cost,
+		})
+	}
+	if err := p.db.InsertLLMUsage(records); err != nil {
+		log.Printf("Error storing LLM usage: %v", err)
+	}
+
+	exchanges := meter.Exchanges()
+	logged := make([]database.LLMCall, 0, len(exchanges))
+	for _, e := range exchanges {
+		c := database.LLMCall{
+			RunID:            p.runID,
+			PeriodID:         &periodID,
+			Step:             step.Name,
+			Provider:         e.Provider,
+			Model:            e.Model,
+			Prompt:           e.Prompt,
+			Response:         e.Response,
+			LatencyMS:        e.Latency.Milliseconds(),
+			PromptTokens:     e.PromptTokens,
+			CompletionTokens: e.CompletionTokens,
+			Cached:           e.Cached,
+		}
+		if e.Subject.ArticleID != 0 {
+			c.ArticleID = &e.Subject.ArticleID
+		}
+		if e.Subject.StorylineID != 0 {
+			c.StorylineID = &e.Subject.StorylineID
+		}
+		if e.Err != nil {
+			msg := e.Err.Error()
+			c.Error = &msg
+		}
+		logged = append(logged, c)
+	}
+	if err := p.db.InsertLLMCalls(logged); err != nil {
+		log.Printf("Error storing LLM call log: %v", err)
+	}
+	return step
+}
+
+// annotate stores the quality note of a freshly composed edition: the
+// degradations of steps, plus how many of the edition's articles lack full
+// text. The note is appended to the compose step's summary.
+func (p *Pipeline) annotate(step *StepResult, periodID, edition string, steps []StepResult, articles []database.Article) {
+	note := qualityNote(steps, articles)
+	if note == "" {
+		return
+	}
+	if err := p.db.SetBriefingQualityNote(periodID, edition, &note); err != nil {
+		log.Printf("Error storing quality note: %v", err)
+		return
+	}
+	step.Summary += " (" + note + ")"
+}
+
+func qualityNote(steps []StepResult, articles []database.Article) string {
+	var notes []string
+	for _, s := range steps {
+		if s.Degraded != "" {
+			notes = append(notes, s.Degraded)
+		}
+	}
+	missing := 0
+	for _, a := range articles {
+		if a.Content == nil || strings.TrimSpace(*a.Content) == "" {
+			missing++
+		}
+	}
+	if missing > 0 {
+		notes = append(notes, fmt.Sprintf("%d articles missing full text", missing))
+	}
+	return strings.Join(notes, "; ")
 }
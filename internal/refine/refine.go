@@ -0,0 +1,445 @@
+// Package refine helps a reader tune the research priorities. It looks at
+// what they rated useful or noisy lately and the storylines of recent
+// briefings, asks them a few questions about it, and turns their answers
+// into concrete changes: new priorities, keywords added to or removed from
+// existing ones, priorities to disable and topics or sources to mute. No
+// change is made until one is applied.
+package refine
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+const questionsPrompt = `You help the reader of a daily AI news briefing tune what it covers. The briefing triages articles against research priorities and skips those matching mute rules.
+
+%s
+
+Ask the reader %d short questions, one topic each, that will show what to change: which recurring themes they want more or less of, whether a source keeps being noise, whether a priority is still wanted. Refer to the concrete storylines, sources and priorities above rather than asking in general.
+
+Respond with ONLY this JSON:
+{"questions": ["..."]}`
+
+// questionsSchema describes the response questionsPrompt asks for.
+var questionsSchema = llm.Schema{Name: "refine_questions", Definition: llm.Object(map[string]any{
+	"questions": llm.Array(llm.String()),
+})}
+
+const proposePrompt = `You help the reader of a daily AI news briefing tune what it covers. The briefing triages articles against research priorities and skips those matching mute rules.
+
+%s
+
+You asked the reader, and they answered:
+%s
+
+Propose the changes their answers call for, and only those:
+- "add_priority": a new priority with a title, a one-sentence description and 3-8 keywords.
+- "keywords": keywords to add to and remove from an existing priority, named by its ID.
+- "disable_priority": an existing priority, named by its ID, they no longer want.
+- "mute": a term (mute_kind "term") whose mention in a title skips an article, or a source (mute_kind "source") whose articles are all skipped, given as mute_value. Mute a source only by its exact name above.
+
+Give each change a one-sentence reason quoting what the reader said. Leave fields a change doesn't use empty, and priority_id 0.
+
+Respond with ONLY this JSON:
+{
+    "changes": [
+        {"kind": "add_priority|keywords|disable_priority|mute", "priority_id": 0, "title": "", "description": "", "keywords": [], "remove_keywords": [], "mute_kind": "", "mute_value": "", "reason": "..."}
+    ]
+}
+
+Use {"changes": []} when the answers call for no change.`
+
+// proposeSchema describes the response proposePrompt asks for.
+var proposeSchema = llm.Schema{Name: "refine_changes", Definition: llm.Object(map[string]any{
+	"changes": llm.Array(llm.Object(map[string]any{
+		"kind":            llm.String(AddPriority, EditKeywords, DisablePriority, Mute),
+		"priority_id":     llm.Integer(),
+		"title":           llm.String(),
+		"description":     llm.String(),
+		"keywords":        llm.Array(llm.String()),
+		"remove_keywords": llm.Array(llm.String()),
+		"mute_kind":       llm.String(),
+		"mute_value":      llm.String(),
+		"reason":          llm.String(),
+	})),
+})}
+
+// Kinds of Change.
+const (
+	AddPriority     = "add_priority"
+	EditKeywords    = "keywords"
+	DisablePriority = "disable_priority"
+	Mute            = "mute"
+)
+
+// Defaults and limits of a refinement.
+const (
+	DefaultDays      = 14   // days of ratings and storylines looked at
+	defaultMaxTokens = 2048 // fits a handful of questions or changes
+	numQuestions     = 3
+	maxRatings       = 40 // of each of articles and storylines
+	maxStorylines    = 30
+)
+
+// Options configures a refinement.
+type Options struct {
+	// Days is how far back ratings and storylines are looked at; 0 means
+	// DefaultDays.
+	Days int
+
+	// MaxTokens limits the responses; 0 means defaultMaxTokens.
+	MaxTokens int
+}
+
+// Answer is the reader's answer to one of the questions.
+type Answer struct {
+	Question string
+	Answer   string
+}
+
+// Change is a proposed change to the priorities or mute rules.
+type Change struct {
+	Kind           string
+	PriorityID     int64    // of EditKeywords and DisablePriority
+	PriorityTitle  string   // of the priority PriorityID names
+	Title          string   // of AddPriority
+	Description    string   // of AddPriority
+	Keywords       []string // of AddPriority, or added by EditKeywords
+	RemoveKeywords []string // of EditKeywords
+	MuteKind       string   // database.MuteTerm or database.MuteSource
+	MuteValue      string
+	Reason         string
+}
+
+// Summary describes the change in one line.
+func (c Change) Summary() string {
+	switch c.Kind {
+	case AddPriority:
+		s := "Add priority " + quote(c.Title)
+		if len(c.Keywords) > 0 {
+			s += " (keywords: " + strings.Join(c.Keywords, ", ") + ")"
+		}
+		return s
+	case EditKeywords:
+		var parts []string
+		if len(c.Keywords) > 0 {
+			parts = append(parts, "add "+strings.Join(c.Keywords, ", "))
+		}
+		if len(c.RemoveKeywords) > 0 {
+			parts = append(parts, "remove "+strings.Join(c.RemoveKeywords, ", "))
+		}
+		return fmt.Sprintf("Keywords of [%d] %s: %s", c.PriorityID, c.PriorityTitle, strings.Join(parts, "; "))
+	case DisablePriority:
+		return fmt.Sprintf("Disable priority [%d] %s", c.PriorityID, c.PriorityTitle)
+	case Mute:
+		if c.MuteKind == database.MuteSource {
+			return "Mute source " + quote(c.MuteValue)
+		}
+		return "Mute titles mentioning " + quote(c.MuteValue)
+	}
+	return c.Kind
+}
+
+func quote(s string) string { return "“" + s + "”" }
+
+// Refiner asks the questions and proposes the changes.
+type Refiner struct {
+	db       *database.DB
+	provider llm.Provider
+	opts     Options
+}
+
+// NewRefiner creates a new priority refiner.
+func NewRefiner(db *database.DB, provider llm.Provider, opts Options) *Refiner {
+	return &Refiner{db: db, provider: provider, opts: opts}
+}
+
+// Questions returns the questions to ask the reader about their recent
+// briefings.
+func (r *Refiner) Questions(ctx context.Context) ([]string, error) {
+	if r.provider == nil {
+		return nil, llm.ErrNoProvider
+	}
+	state, err := r.currentState()
+	if err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(questionsPrompt, state.text, numQuestions)
+	responseText, err := r.provider.Generate(llm.WithSchema(ctx, questionsSchema), prompt, cmp.Or(r.opts.MaxTokens, defaultMaxTokens))
+	if err != nil {
+		return nil, err
+	}
+	parsed := llm.ParseJSONResponse(responseText)
+	if parsed == nil {
+		return nil, fmt.Errorf("refinement questions could not be parsed")
+	}
+	var questions []string
+	items, _ := parsed["questions"].([]any)
+	for _, item := range items {
+		if q, _ := item.(string); strings.TrimSpace(q) != "" && len(questions) < 2*numQuestions {
+			questions = append(questions, strings.TrimSpace(q))
+		}
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("no refinement questions in the response")
+	}
+	return questions, nil
+}
+
+// Propose returns the changes the reader's answers call for. Changes that
+// name no existing active priority, or would change nothing, are left out.
+func (r *Refiner) Propose(ctx context.Context, answers []Answer) ([]Change, error) {
+	if r.provider == nil {
+		return nil, llm.ErrNoProvider
+	}
+	var qa []string
+	for _, a := range answers {
+		if answer := strings.TrimSpace(a.Answer); answer != "" {
+			qa = append(qa, fmt.Sprintf("Q: %s\nA: %s", a.Question, answer))
+		}
+	}
+	if len(qa) == 0 {
+		return nil, nil
+	}
+	state, err := r.currentState()
+	if err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(proposePrompt, state.text, strings.Join(qa, "\n\n"))
+	responseText, err := r.provider.Generate(llm.WithSchema(ctx, proposeSchema), prompt, cmp.Or(r.opts.MaxTokens, defaultMaxTokens))
+	if err != nil {
+		return nil, err
+	}
+	parsed := llm.ParseJSONResponse(responseText)
+	if parsed == nil {
+		return nil, fmt.Errorf("refinement proposals could not be parsed")
+	}
+
+	var changes []Change
+	seen := make(map[string]bool)
+	items, _ := parsed["changes"].([]any)
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		c, ok := state.check(parseChange(obj))
+		if !ok || seen[c.Summary()] {
+			continue
+		}
+		seen[c.Summary()] = true
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+func parseChange(obj map[string]any) Change {
+	c := Change{
+		Kind:           str(obj["kind"]),
+		Title:          str(obj["title"]),
+		Description:    str(obj["description"]),
+		Keywords:       strs(obj["keywords"]),
+		RemoveKeywords: strs(obj["remove_keywords"]),
+		MuteKind:       str(obj["mute_kind"]),
+		MuteValue:      str(obj["mute_value"]),
+		Reason:         str(obj["reason"]),
+	}
+	if id, ok := obj["priority_id"].(float64); ok {
+		c.PriorityID = int64(id)
+	}
+	return c
+}
+
+// Apply makes a change, recording it in the audit log as user's edit.
+func Apply(db *database.DB, user string, c Change) error {
+	switch c.Kind {
+	case AddPriority:
+		id, err := db.InsertPriority(c.Title, c.Description, c.Keywords)
+		if err != nil {
+			return err
+		}
+		return db.RecordAudit(user, database.AuditPriorityAdd, id, c.Title)
+	case EditKeywords, DisablePriority:
+		p, err := db.GetPriority(c.PriorityID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return fmt.Errorf("priority %d not found", c.PriorityID)
+		}
+		if c.Kind == DisablePriority {
+			if !p.IsActive {
+				return nil
+			}
+			if err := db.TogglePriority(p.ID); err != nil {
+				return err
+			}
+			return db.RecordAudit(user, database.AuditPriorityToggle, p.ID, "disabled")
+		}
+		keywords := editKeywords(p.Keywords, c.Keywords, c.RemoveKeywords)
+		if err := db.UpdatePriority(p.ID, nil, nil, keywords); err != nil {
+			return err
+		}
+		return db.RecordAudit(user, database.AuditPriorityEdit, p.ID, p.Title)
+	case Mute:
+		id, err := db.InsertMuteRule(c.MuteKind, c.MuteValue)
+		if err != nil {
+			return err
+		}
+		return db.RecordAudit(user, database.AuditMuteAdd, id, c.MuteKind+" "+c.MuteValue)
+	}
+	return fmt.Errorf("unknown change %q", c.Kind)
+}
+
+// editKeywords returns keywords without remove and with add, both ignoring
+// case. The result is never nil, so UpdatePriority always stores it.
+func editKeywords(keywords, add, remove []string) []string {
+	out := []string{}
+	for _, k := range keywords {
+		if !containsFold(remove, k) {
+			out = append(out, k)
+		}
+	}
+	for _, k := range add {
+		if !containsFold(out, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(l string) bool { return strings.EqualFold(l, s) })
+}
+
+// state is what both prompts show of the reader's recent briefings and
+// current settings.
+type state struct {
+	text       string
+	priorities map[int64]database.ResearchPriority // active ones
+	mutes      []database.MuteRule
+}
+
+// currentState reads the ratings and storylines of the last Options.Days
+// and the active priorities and mute rules.
+func (r *Refiner) currentState() (*state, error) {
+	since := time.Now().AddDate(0, 0, -cmp.Or(r.opts.Days, DefaultDays)).Format("2006-01-02")
+	ratings, err := r.db.GetRecentRatings(since, maxRatings)
+	if err != nil {
+		return nil, err
+	}
+	storylines, err := r.db.GetStorylineTitlesSince(since, maxStorylines)
+	if err != nil {
+		return nil, err
+	}
+	priorities, err := r.db.GetActivePriorities()
+	if err != nil {
+		return nil, err
+	}
+	mutes, err := r.db.GetMuteRules()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &state{priorities: make(map[int64]database.ResearchPriority), mutes: mutes}
+	var b strings.Builder
+	b.WriteString("Research priorities:\n")
+	if len(priorities) == 0 {
+		b.WriteString("(none)\n")
+	}
+	for _, p := range priorities {
+		s.priorities[p.ID] = p
+		fmt.Fprintf(&b, "- [%d] %s", p.ID, p.Title)
+		if p.Description != nil && *p.Description != "" {
+			fmt.Fprintf(&b, ": %s", *p.Description)
+		}
+		if len(p.Keywords) > 0 {
+			fmt.Fprintf(&b, " [keywords: %s]", strings.Join(p.Keywords, ", "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nMute rules:\n")
+	if len(mutes) == 0 {
+		b.WriteString("(none)\n")
+	}
+	for _, m := range mutes {
+		fmt.Fprintf(&b, "- %s %q\n", m.Kind, m.Value)
+	}
+	fmt.Fprintf(&b, "\nRated in the last %d days:\n", cmp.Or(r.opts.Days, DefaultDays))
+	if len(ratings) == 0 {
+		b.WriteString("(nothing)\n")
+	}
+	for _, rating := range ratings {
+		verdict := "noisy"
+		if rating.Positive {
+			verdict = "useful"
+		}
+		if rating.Storyline {
+			fmt.Fprintf(&b, "- %s: storyline %q\n", verdict, rating.Title)
+		} else {
+			fmt.Fprintf(&b, "- %s: article %q from %s\n", verdict, rating.Title, cmp.Or(rating.Source, "an unknown source"))
+		}
+	}
+	b.WriteString("\nStorylines of recent briefings:\n")
+	if len(storylines) == 0 {
+		b.WriteString("(none)\n")
+	}
+	for _, title := range storylines {
+		fmt.Fprintf(&b, "- %s\n", title)
+	}
+	s.text = strings.TrimSpace(b.String())
+	return s, nil
+}
+
+// check validates a proposed change against the current settings, trimming
+// what it would not change, and reports whether anything is left.
+func (s *state) check(c Change) (Change, bool) {
+	switch c.Kind {
+	case AddPriority:
+		return c, c.Title != ""
+	case EditKeywords, DisablePriority:
+		p, ok := s.priorities[c.PriorityID]
+		if !ok {
+			return c, false
+		}
+		c.PriorityTitle = p.Title
+		if c.Kind == DisablePriority {
+			return c, true
+		}
+		c.Keywords = slices.DeleteFunc(c.Keywords, func(k string) bool { return containsFold(p.Keywords, k) })
+		c.RemoveKeywords = slices.DeleteFunc(c.RemoveKeywords, func(k string) bool { return !containsFold(p.Keywords, k) })
+		return c, len(c.Keywords)+len(c.RemoveKeywords) > 0
+	case Mute:
+		if c.MuteKind != database.MuteTerm && c.MuteKind != database.MuteSource || c.MuteValue == "" {
+			return c, false
+		}
+		muted := slices.ContainsFunc(s.mutes, func(m database.MuteRule) bool {
+			return m.Kind == c.MuteKind && strings.EqualFold(m.Value, c.MuteValue)
+		})
+		return c, !muted
+	}
+	return c, false
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+func strs(v any) []string {
+	arr, _ := v.([]any)
+	var out []string
+	for _, item := range arr {
+		if s := str(item); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
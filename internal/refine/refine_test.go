@@ -0,0 +1,144 @@
+package refine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+type mockProvider struct {
+	response string
+	prompts  []string
+}
+
+func (m *mockProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return m.response, nil
+}
+
+func (m *mockProvider) IsConfigured() bool { return true }
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func ptr(s string) *string { return &s }
+
+func TestQuestions(t *testing.T) {
+	db := openTestDB(t)
+	today := time.Now().Format("2006-01-02")
+	aid, _ := db.InsertArticle("https://a.com/1", "Yet another funding round", ptr("Hype Daily"), nil, nil, &today)
+	db.UpsertArticleFeedback(aid, "negative")
+	sid, _ := db.InsertStoryline(today, "agents", []int64{aid})
+	db.InsertStorylineNarrative(sid, today, "Coding Agents Get Tests", "...", nil)
+	db.UpsertStorylineFeedback(sid, today, "useful")
+	db.InsertPriority("Agents", "Coding agents in practice", []string{"agent"})
+	db.InsertMuteRule(database.MuteTerm, "crypto")
+
+	mock := &mockProvider{response: `{"questions": ["Is Hype Daily worth keeping?", " ", "More on coding agents?"]}`}
+	questions, err := NewRefiner(db, mock, Options{}).Questions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 || questions[0] != "Is Hype Daily worth keeping?" {
+		t.Errorf("expected the two non-empty questions, got %q", questions)
+	}
+	for _, want := range []string{
+		`noisy: article "Yet another funding round" from Hype Daily`,
+		`useful: storyline "Coding Agents Get Tests"`,
+		"Agents: Coding agents in practice [keywords: agent]",
+		`term "crypto"`,
+	} {
+		if !strings.Contains(mock.prompts[0], want) {
+			t.Errorf("expected %q in the prompt, got %q", want, mock.prompts[0])
+		}
+	}
+
+	if _, err := NewRefiner(db, nil, Options{}).Questions(context.Background()); !errors.Is(err, llm.ErrNoProvider) {
+		t.Errorf("expected ErrNoProvider without a provider, got %v", err)
+	}
+}
+
+func TestProposeAndApply(t *testing.T) {
+	db := openTestDB(t)
+	agents, _ := db.InsertPriority("Agents", "", []string{"agent", "MCP"})
+	evals, _ := db.InsertPriority("Evals", "", nil)
+	db.InsertMuteRule(database.MuteSource, "Hype Daily")
+
+	resp, _ := json.Marshal(map[string]any{"changes": []map[string]any{
+		{"kind": AddPriority, "title": "Local models", "description": "Running models on a laptop", "keywords": []string{"llama.cpp", "GGUF"}, "reason": "They said so"},
+		{"kind": EditKeywords, "priority_id": agents, "keywords": []string{"agent", "A2A"}, "remove_keywords": []string{"mcp", "unknown"}},
+		{"kind": DisablePriority, "priority_id": evals},
+		{"kind": DisablePriority, "priority_id": 999},
+		{"kind": Mute, "mute_kind": database.MuteTerm, "mute_value": "funding round"},
+		{"kind": Mute, "mute_kind": database.MuteSource, "mute_value": "hype daily"},
+		{"kind": Mute, "mute_kind": "domain", "mute_value": "example.com"},
+		{"kind": EditKeywords, "priority_id": agents, "keywords": []string{"Agent"}},
+	}})
+	mock := &mockProvider{response: string(resp)}
+	refiner := NewRefiner(db, mock, Options{})
+
+	if changes, err := refiner.Propose(context.Background(), []Answer{{Question: "Anything?", Answer: " "}}); err != nil || changes != nil || len(mock.prompts) != 0 {
+		t.Fatalf("expected no proposals and no LLM call without answers, got %+v, %v", changes, err)
+	}
+	changes, err := refiner.Propose(context.Background(), []Answer{{Question: "Funding news?", Answer: "Pure noise"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(mock.prompts[0], "Q: Funding news?\nA: Pure noise") {
+		t.Errorf("expected the answers in the prompt, got %q", mock.prompts[0])
+	}
+	var summaries []string
+	for _, c := range changes {
+		summaries = append(summaries, c.Summary())
+	}
+	want := []string{
+		"Add priority “Local models” (keywords: llama.cpp, GGUF)",
+		"Keywords of [1] Agents: add A2A; remove mcp",
+		"Disable priority [2] Evals",
+		"Mute titles mentioning “funding round”",
+	}
+	if strings.Join(summaries, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected the valid changes that change something, got %q", summaries)
+	}
+
+	for _, c := range changes {
+		if err := Apply(db, "ada", c); err != nil {
+			t.Fatalf("applying %q: %v", c.Summary(), err)
+		}
+	}
+	p, _ := db.GetPriority(agents)
+	if strings.Join(p.Keywords, ",") != "agent,A2A" {
+		t.Errorf("expected MCP replaced by A2A, got %q", p.Keywords)
+	}
+	if p, _ := db.GetPriority(evals); p.IsActive {
+		t.Error("expected Evals disabled")
+	}
+	active, _ := db.GetActivePriorities()
+	added := slices.IndexFunc(active, func(p database.ResearchPriority) bool { return p.Title == "Local models" })
+	if len(active) != 2 || added < 0 || len(active[added].Keywords) != 2 {
+		t.Errorf("expected the new priority with its keywords, got %+v", active)
+	}
+	rules, _ := db.GetMuteRules()
+	if len(rules) != 2 || rules[1].Value != "funding round" {
+		t.Errorf("expected the term muted, got %+v", rules)
+	}
+	entries, _, _ := db.ListAuditEntries(database.AuditFilter{}, database.ListOptions{})
+	if len(entries) != 4 || entries[0].User != "ada" {
+		t.Errorf("expected every change in the audit log, got %+v", entries)
+	}
+}
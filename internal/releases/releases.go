@@ -0,0 +1,197 @@
+package releases
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+const extractPrompt = `You are maintaining a registry of AI model releases.
+
+List every AI model this article announces or reports as newly released. Ignore models that are only mentioned, compared against, or released long ago.
+
+Article published: %s
+Article Title: %s
+Content:
+%s
+
+Respond with ONLY this JSON:
+{
+    "releases": [
+        {
+            "name": "Exact model name incl. version, e.g. Llama 4 Scout",
+            "vendor": "Company or lab, or null",
+            "release_date": "YYYY-MM-DD, or null if not stated",
+            "license": "e.g. Apache-2.0, MIT, proprietary, or null",
+            "context_window": number of tokens or null
+        }
+    ]
+}
+
+Use {"releases": []} when the article announces no model.`
+
+// extractSchema describes the response extractPrompt asks for.
+var extractSchema = llm.Schema{Name: "model_releases", Definition: llm.Object(map[string]any{
+	"releases": llm.Array(llm.Object(map[string]any{
+		"name":           llm.String(),
+		"vendor":         llm.Nullable(llm.String()),
+		"release_date":   llm.Nullable(llm.String()),
+		"license":        llm.Nullable(llm.String()),
+		"context_window": llm.Nullable(llm.Integer()),
+	})),
+})}
+
+// candidateTypes are the triage article types worth scanning for releases.
+var candidateTypes = []string{"model_update", "tool_release", "announcement"}
+
+// maxReleasesPerArticle caps how many releases one article can register.
+const maxReleasesPerArticle = 5
+
+// Result holds the results of an extraction run.
+type Result struct {
+	Scanned  int
+	Releases int // newly registered models
+	Errors   int
+}
+
+// Options configures release extraction.
+type Options struct {
+	// MaxTokens limits the response to each article's prompt; 0 means
+	// defaultMaxTokens.
+	MaxTokens int
+}
+
+// defaultMaxTokens fits the releases of a typical announcement.
+const defaultMaxTokens = 512
+
+// Extractor scans triaged articles for model releases.
+type Extractor struct {
+	db       *database.DB
+	provider llm.Provider
+	opts     Options
+}
+
+// NewExtractor creates a new model release extractor.
+func NewExtractor(db *database.DB, provider llm.Provider, opts Options) *Extractor {
+	return &Extractor{db: db, provider: provider, opts: opts}
+}
+
+// ExtractPeriod scans the period's relevant release-type articles that
+// haven't been scanned yet and registers the models they announce.
+func (e *Extractor) ExtractPeriod(ctx context.Context, periodID string) *Result {
+	if e.provider == nil {
+		log.Println("No LLM provider available for release extraction")
+		return &Result{Errors: 1}
+	}
+
+	articles, err := e.db.GetReleaseCandidates(periodID, candidateTypes)
+	if err != nil {
+		log.Printf("Error getting release candidates: %v", err)
+		return &Result{Errors: 1}
+	}
+
+	r := &Result{}
+	for _, article := range articles {
+		found, err := e.extractArticle(ctx, article)
+		if err != nil {
+			log.Printf("Error extracting releases from article %d: %v", article.ID, err)
+			r.Errors++
+			continue
+		}
+		e.db.MarkReleaseScanned(article.ID)
+		r.Scanned++
+		r.Releases += found
+	}
+
+	log.Printf("Release extraction complete: %d articles scanned, %d new models, %d errors",
+		r.Scanned, r.Releases, r.Errors)
+	return r
+}
+
+func (e *Extractor) extractArticle(ctx context.Context, article database.Article) (int, error) {
+	content := article.Title
+	if article.Content != nil && *article.Content != "" {
+		content = *article.Content
+	}
+	if len(content) > 4000 {
+		content = content[:4000] + "..."
+	}
+
+	published := database.GetToday()
+	if article.PublishedDate != nil && *article.PublishedDate != "" {
+		published = *article.PublishedDate
+	}
+
+	prompt := fmt.Sprintf(extractPrompt, published, article.Title, content)
+	responseText, err := e.provider.Generate(llm.WithSchema(llm.WithArticle(ctx, article.ID), extractSchema), prompt, cmp.Or(e.opts.MaxTokens, defaultMaxTokens))
+	if err != nil {
+		return 0, err
+	}
+
+	parsed := llm.ParseJSONResponse(responseText)
+	if parsed == nil {
+		// Retrying won't help a malformed answer; treat as no releases.
+		log.Printf("Release extraction response for article %d could not be parsed", article.ID)
+		return 0, nil
+	}
+	arr, _ := parsed["releases"].([]any)
+
+	found := 0
+	for i, item := range arr {
+		if i == maxReleasesPerArticle {
+			break
+		}
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(getString(obj, "name"))
+		if name == "" {
+			continue
+		}
+
+		date := strings.TrimSpace(getString(obj, "release_date"))
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			date = published
+		}
+
+		var contextWindow *int64
+		if n, ok := obj["context_window"].(float64); ok && n > 0 {
+			v := int64(n)
+			contextWindow = &v
+		}
+
+		isNew, err := e.db.UpsertModelRelease(article.ID, name, optional(getString(obj, "vendor")),
+			date, optional(getString(obj, "license")), contextWindow)
+		if err != nil {
+			return found, err
+		}
+		if isNew {
+			log.Printf("Registered model release: %s", name)
+			found++
+		}
+	}
+	return found, nil
+}
+
+func getString(m map[string]any, key string) string {
+	if s, ok := m[key].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// optional returns nil for empty strings so unknown details stay NULL.
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "null") || strings.EqualFold(s, "unknown") {
+		return nil
+	}
+	return &s
+}
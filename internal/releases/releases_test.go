@@ -0,0 +1,74 @@
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+type mockProvider struct {
+	response string
+	calls    int
+}
+
+func (m *mockProvider) Generate(_ context.Context, _ string, _ int) (string, error) {
+	m.calls++
+	return m.response, nil
+}
+
+func (m *mockProvider) IsConfigured() bool { return true }
+
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func ptr(s string) *string { return &s }
+
+func TestExtractPeriod(t *testing.T) {
+	db := openTestDB(t)
+	modelUpdate, tutorial := "model_update", "tutorial"
+	a1, _ := db.InsertArticle("https://a.com/launch", "Vendor ships Model X", nil, ptr("2026-02-05"), ptr("Model X is out."), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com/howto", "Prompting tips", nil, nil, ptr("Tips."), ptr("2026-02-06"))
+	db.InsertTriage(a1, "relevant", &modelUpdate, nil, nil, 4)
+	db.InsertTriage(a2, "relevant", &tutorial, nil, nil, 3)
+
+	resp, _ := json.Marshal(map[string]any{
+		"releases": []map[string]any{
+			{"name": "Model X", "vendor": "Vendor", "release_date": "2026-02-04", "license": "Apache-2.0", "context_window": 200000},
+			{"name": "Model X Mini", "vendor": nil, "release_date": nil, "license": "unknown", "context_window": nil},
+			{"name": ""},
+		},
+	})
+	mock := &mockProvider{response: string(resp)}
+	result := NewExtractor(db, mock, Options{}).ExtractPeriod(context.Background(), "2026-02-06")
+
+	if result.Scanned != 1 || result.Releases != 2 || mock.calls != 1 {
+		t.Fatalf("expected one release article scanned with 2 models, got %+v (calls %d)", result, mock.calls)
+	}
+
+	releases, _ := db.GetModelReleases("", "")
+	if len(releases) != 2 {
+		t.Fatalf("expected 2 releases, got %d", len(releases))
+	}
+	mini := releases[0]
+	if releases[0].Name != "Model X Mini" {
+		mini = releases[1]
+	}
+	if mini.ReleaseDate != "2026-02-05" || mini.License != nil || mini.Vendor != nil {
+		t.Errorf("expected publication date and unknown details left empty, got %+v", mini)
+	}
+
+	// A second run skips already scanned articles.
+	if again := NewExtractor(db, mock, Options{}).ExtractPeriod(context.Background(), "2026-02-06"); again.Scanned != 0 || mock.calls != 1 {
+		t.Errorf("expected no rescans, got %+v", again)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// maxIngestBody caps the size of an ingest request body unless
+// Options.MaxIngestBytes sets another limit.
+const maxIngestBody = 5 << 20
+
+// defaultIngestSource labels ingested articles that don't name a source.
+const defaultIngestSource = "Webhook"
+
+// IngestArticle is one article pushed through POST /api/v1/ingest.
+type IngestArticle struct {
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	Content       string `json:"content,omitempty"`
+	Source        string `json:"source,omitempty"`
+	PublishedDate string `json:"published_date,omitempty"`
+}
+
+// List endpoints page with ?limit= and ?offset= and sort with ?sort=key, or
+// ?sort=-key for descending order.
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// pageJSON is the pagination part of a list response. NextOffset is set
+// when there are more results.
+type pageJSON struct {
+	Total      int  `json:"total"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// parseListOptions reads the limit, offset and sort parameters of a list
+// request. It returns a message for the client when one is invalid.
+func parseListOptions(q url.Values) (database.ListOptions, string) {
+	opts := database.ListOptions{Limit: defaultPageLimit}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return opts, "limit must be between 1 and " + strconv.Itoa(maxPageLimit)
+		}
+		opts.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, "offset must be a non-negative integer"
+		}
+		opts.Offset = n
+	}
+	opts.Sort, opts.Desc = strings.CutPrefix(q.Get("sort"), "-")
+	return opts, ""
+}
+
+// newPage describes the page of opts in a result set of total items.
+func newPage(opts database.ListOptions, total int) pageJSON {
+	p := pageJSON{Total: total, Limit: opts.Limit, Offset: opts.Offset}
+	if next := opts.Offset + opts.Limit; next < total {
+		p.NextOffset = &next
+	}
+	return p
+}
+
+// writeListError answers a failed list query: a bad sort key is the
+// client's error, anything else is logged as the server's.
+func writeListError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, database.ErrUnknownSort) {
+		writeJSONError(w, http.StatusBadRequest, "sort: "+err.Error())
+		return
+	}
+	log.Printf("Error listing %s: %v", what, err)
+	writeJSONError(w, http.StatusInternalServerError, "failed to load "+what)
+}
+
+// ingestResponse reports the outcome of an ingest request.
+type ingestResponse struct {
+	Accepted   int     `json:"accepted"`
+	Duplicates int     `json:"duplicates"`
+	IDs        []int64 `json:"ids"`
+}
+
+// handleOpenAPI serves the OpenAPI document describing the JSON API.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// requireToken wraps an API handler with bearer-token authentication.
+// An empty token disables the endpoint entirely.
+func (s *Server) requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeJSONError(w, http.StatusServiceUnavailable, "endpoint disabled: no API token configured")
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleIngest accepts one article object or an array of them. Articles are
+// stored without a period and adopted by the next collection run, so they
+// go through the same dedup, fetch and triage path as collected articles.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	items, err := decodeIngest(http.MaxBytesReader(w, r.Body, cmp.Or(s.opts.MaxIngestBytes, maxIngestBody)))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON payload: "+err.Error())
+		return
+	}
+	if len(items) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "no articles in payload")
+		return
+	}
+	for _, item := range items {
+		if msg := validateIngest(item); msg != "" {
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	resp := ingestResponse{IDs: []int64{}}
+	for _, item := range items {
+		source := strings.TrimSpace(item.Source)
+		if source == "" {
+			source = defaultIngestSource
+		}
+		var content, pubDate *string
+		if item.Content != "" {
+			content = &item.Content
+		}
+		if item.PublishedDate != "" {
+			pubDate = &item.PublishedDate
+		}
+
+		id, err := s.db.InsertArticle(strings.TrimSpace(item.URL), strings.TrimSpace(item.Title), &source, pubDate, content, nil)
+		if err != nil {
+			log.Printf("Error ingesting %s: %v", item.URL, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to store article")
+			return
+		}
+		if id > 0 {
+			resp.Accepted++
+			resp.IDs = append(resp.IDs, id)
+		} else {
+			resp.Duplicates++
+		}
+	}
+
+	log.Printf("Ingested %d articles (%d duplicates)", resp.Accepted, resp.Duplicates)
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+func decodeIngest(body io.Reader) ([]IngestArticle, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []IngestArticle
+		err := json.Unmarshal(trimmed, &items)
+		return items, err
+	}
+	var item IngestArticle
+	if err := json.Unmarshal(trimmed, &item); err != nil {
+		return nil, err
+	}
+	return []IngestArticle{item}, nil
+}
+
+func validateIngest(item IngestArticle) string {
+	if strings.TrimSpace(item.Title) == "" {
+		return "title is required"
+	}
+	u, err := url.Parse(strings.TrimSpace(item.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url must be an absolute http(s) URL: " + item.URL
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
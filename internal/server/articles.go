@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// articleJSON is the API representation of a collected article.
+type articleJSON struct {
+	ID             int64   `json:"id"`
+	URL            string  `json:"url"`
+	Title          string  `json:"title"`
+	Source         *string `json:"source"`
+	PublishedDate  *string `json:"published_date"`
+	PeriodID       *string `json:"period_id"`
+	CollectedAt    *string `json:"collected_at"`
+	ContentFetched bool    `json:"content_fetched"`
+	Verdict        *string `json:"verdict"`
+	ArticleType    *string `json:"article_type"`
+	PracticalScore *int    `json:"practical_score"`
+}
+
+// handleArticlesAPI lists a page of articles as JSON, filtered by ?period=,
+// ?source= and ?verdict= (relevant, skip or untriaged).
+func (s *Server) handleArticlesAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	filter := database.ArticleFilter{PeriodID: q.Get("period"), Source: q.Get("source"), Verdict: q.Get("verdict")}
+	switch filter.Verdict {
+	case "", "relevant", "skip", database.VerdictUntriaged:
+	default:
+		writeJSONError(w, http.StatusBadRequest, "verdict must be relevant, skip or untriaged")
+		return
+	}
+	opts, msg := parseListOptions(q)
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	articles, total, err := s.db.ListArticles(filter, opts)
+	if err != nil {
+		writeListError(w, err, "articles")
+		return
+	}
+
+	out := make([]articleJSON, 0, len(articles))
+	for _, a := range articles {
+		out = append(out, articleJSON{
+			ID:             a.ID,
+			URL:            a.URL,
+			Title:          a.Title,
+			Source:         a.Source,
+			PublishedDate:  a.PublishedDate,
+			PeriodID:       a.PeriodID,
+			CollectedAt:    a.CollectedAt,
+			ContentFetched: a.ContentFetched,
+			Verdict:        a.Verdict,
+			ArticleType:    a.ArticleType,
+			PracticalScore: a.PracticalScore,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"articles": out, "page": newPage(opts, total)})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// anonymousUser is recorded for edits on a server without users.
+const anonymousUser = "anonymous"
+
+// auditEntryJSON is the API representation of an audit log entry.
+type auditEntryJSON struct {
+	ID        int64  `json:"id"`
+	User      string `json:"user"`
+	Action    string `json:"action"`
+	TargetID  *int64 `json:"target_id"`
+	Detail    string `json:"detail"`
+	CreatedAt string `json:"created_at"`
+}
+
+// audit records a manual edit made through r. Failing to record it doesn't
+// undo the edit, so errors are only logged.
+func (s *Server) audit(r *http.Request, action string, targetID int64, detail string) {
+	if err := s.db.RecordAudit(auditUser(r), action, targetID, detail); err != nil {
+		log.Printf("Recording audit entry %s: %v", action, err)
+	}
+}
+
+// auditUser returns the name edits made through r are recorded under.
+func auditUser(r *http.Request) string {
+	if u := userFrom(r.Context()); u != nil {
+		return u.Name
+	}
+	return anonymousUser
+}
+
+// handleAuditAPI lists a page of the audit log as JSON, filtered by ?user=
+// and ?action=.
+func (s *Server) handleAuditAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	opts, msg := parseListOptions(q)
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	entries, total, err := s.db.ListAuditEntries(database.AuditFilter{User: q.Get("user"), Action: q.Get("action")}, opts)
+	if err != nil {
+		writeListError(w, err, "audit log")
+		return
+	}
+
+	out := make([]auditEntryJSON, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, auditEntryJSON{
+			ID:        e.ID,
+			User:      e.User,
+			Action:    e.Action,
+			TargetID:  e.TargetID,
+			Detail:    e.Detail,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": out, "page": newPage(opts, total)})
+}
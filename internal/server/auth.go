@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Roles of server users. An admin can do everything a reader can, and also
+// edit priorities and retry jobs; a reader can view briefings and give
+// feedback.
+const (
+	RoleReader = "reader"
+	RoleAdmin  = "admin"
+)
+
+// User is someone allowed to use the server, identified by their token.
+type User struct {
+	Name  string
+	Role  string
+	Token string
+}
+
+type userKey struct{}
+
+// userFrom returns the user a request was authenticated as, or nil when the
+// server has no users.
+func userFrom(ctx context.Context) *User {
+	u, _ := ctx.Value(userKey{}).(*User)
+	return u
+}
+
+// validateUsers checks that every user has a name, a known role and a token.
+func validateUsers(users []User) error {
+	for _, u := range users {
+		if u.Name == "" {
+			return fmt.Errorf("server user without a name")
+		}
+		if u.Role != RoleReader && u.Role != RoleAdmin {
+			return fmt.Errorf("server user %q: unknown role %q (want %s or %s)", u.Name, u.Role, RoleReader, RoleAdmin)
+		}
+		if u.Token == "" {
+			return fmt.Errorf("server user %q has no token", u.Name)
+		}
+	}
+	return nil
+}
+
+// require wraps a handler so that only users with role, or admins, reach
+// it. Browsers log in with HTTP basic auth (user name and token), API clients
+// send the token as a bearer token. A server without users lets everyone
+// through, as the single-user local app it was before.
+func (s *Server) require(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.opts.Users) == 0 {
+			next(w, r)
+			return
+		}
+		api := strings.HasPrefix(r.URL.Path, "/api/")
+		u := s.authenticate(r)
+		if u == nil {
+			if api {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="AICrawler"`)
+				writeJSONError(w, http.StatusUnauthorized, "invalid or missing credentials")
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="AICrawler", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if role == RoleAdmin && u.Role != RoleAdmin {
+			if api {
+				writeJSONError(w, http.StatusForbidden, "admin role required")
+				return
+			}
+			http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+	}
+}
+
+// authenticate returns the user whose token the request carries, or nil.
+func (s *Server) authenticate(r *http.Request) *User {
+	name, token, basic := r.BasicAuth()
+	if !basic {
+		var ok bool
+		if token, ok = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); !ok {
+			return nil
+		}
+	}
+	for i := range s.opts.Users {
+		u := &s.opts.Users[i]
+		if basic && !strings.EqualFold(name, u.Name) {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(u.Token)) == 1 {
+			return u
+		}
+	}
+	return nil
+}
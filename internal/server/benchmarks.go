@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// BenchmarkView groups the recorded results of one benchmark for the
+// benchmarks page, oldest first.
+type BenchmarkView struct {
+	Name    string
+	Best    float64
+	Results []BenchmarkRow
+}
+
+// BenchmarkRow is one result with its bar width relative to the best score.
+type BenchmarkRow struct {
+	Result database.BenchmarkResult
+	Width  int // percent of the benchmark's best score
+}
+
+func (s *Server) handleBenchmarks(w http.ResponseWriter, r *http.Request) {
+	results, err := s.db.GetBenchmarkResults()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, "benchmarks.html", map[string]any{
+		"Benchmarks": groupBenchmarks(results),
+	})
+}
+
+// groupBenchmarks splits results, already ordered by benchmark, into one view
+// per benchmark name (case-insensitive).
+func groupBenchmarks(results []database.BenchmarkResult) []BenchmarkView {
+	var views []BenchmarkView
+	for _, res := range results {
+		if len(views) == 0 || !strings.EqualFold(views[len(views)-1].Name, res.Benchmark) {
+			views = append(views, BenchmarkView{Name: res.Benchmark})
+		}
+		v := &views[len(views)-1]
+		v.Results = append(v.Results, BenchmarkRow{Result: res})
+		if res.Score > v.Best {
+			v.Best = res.Score
+		}
+	}
+
+	for i := range views {
+		for j := range views[i].Results {
+			if views[i].Best > 0 {
+				views[i].Results[j].Width = int(views[i].Results[j].Result.Score / views[i].Best * 100)
+			}
+		}
+	}
+	return views
+}
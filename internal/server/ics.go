@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// The ICS feed covers recent and upcoming events so calendar clients keep
+// entries for a while after they pass.
+const (
+	icsPastDays   = 30
+	icsFutureDays = 365
+)
+
+func (s *Server) handleEventsICS(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	from := now.AddDate(0, 0, -icsPastDays).Format("2006-01-02")
+	to := now.AddDate(0, 0, icsFutureDays).Format("2006-01-02")
+
+	events, err := s.db.GetEventsBetween(from, to, 0)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="aicrawler-events.ics"`)
+	w.Write([]byte(renderICS(events, now))) //nolint: errcheck
+}
+
+// renderICS encodes events as an RFC 5545 calendar of all-day entries.
+func renderICS(events []database.Event, stamp time.Time) string {
+	var b strings.Builder
+	line := func(s string) { b.WriteString(foldICSLine(s) + "\r\n") }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//AICrawler//Upcoming AI events//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:AI Briefing: upcoming events")
+
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		day, err := time.Parse("2006-01-02", e.EventDate)
+		if err != nil {
+			continue
+		}
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:event-%d@aicrawler", e.ID))
+		line("DTSTAMP:" + dtstamp)
+		line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICSText(e.Title))
+		line("CATEGORIES:" + escapeICSText(e.Kind))
+		if e.ArticleURL != nil {
+			line("URL:" + *e.ArticleURL)
+			line("DESCRIPTION:" + escapeICSText("Mentioned in "+*e.ArticleURL))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits content lines longer than 75 octets, as RFC 5545
+// requires, without breaking UTF-8 sequences.
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// jobsPageLimit caps how many recent jobs /jobs lists.
+const jobsPageLimit = 50
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.db.GetJobs(jobsPageLimit)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "jobs.html", map[string]any{
+		"Jobs": jobs,
+	})
+}
+
+// handleJobAction handles POST /jobs/{id}/retry, queueing a failed job again.
+func (s *Server) handleJobAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/jobs", http.StatusFound)
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/", 2)
+	if len(parts) != 2 || parts[1] != "retry" {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if ok, _ := s.db.RetryJob(id); ok {
+		s.audit(r, database.AuditJobRetry, id, "")
+	}
+	http.Redirect(w, r, "/jobs", http.StatusFound)
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// Ratings accepted by the feedback API, per kind of item.
+var feedbackRatings = map[string][]string{
+	"storyline": {"useful", "not_useful"},
+	"article":   {"positive", "negative"},
+}
+
+// navJSON is what a briefing page needs to move between briefings and
+// through its storylines: the neighbouring periods and the storylines in
+// page order with their feedback.
+type navJSON struct {
+	PeriodID   string             `json:"period_id"`
+	Edition    string             `json:"edition"`
+	Previous   *string            `json:"previous"`
+	Next       *string            `json:"next"`
+	Storylines []navStorylineJSON `json:"storylines"`
+}
+
+type navStorylineJSON struct {
+	ID       int64            `json:"id"`
+	Title    string           `json:"title"`
+	Feedback *string          `json:"feedback"`
+	Stale    bool             `json:"stale"`
+	Articles []navArticleJSON `json:"articles"`
+}
+
+type navArticleJSON struct {
+	ID       int64   `json:"id"`
+	Title    string  `json:"title"`
+	URL      string  `json:"url"`
+	Feedback *string `json:"feedback"`
+}
+
+// feedbackJSON is the body and response of the feedback API. A null or
+// empty rating clears the feedback.
+type feedbackJSON struct {
+	ID     int64   `json:"id"`
+	Rating *string `json:"rating"`
+}
+
+// handleNavAPI serves GET /api/v1/briefings/{period_id}/nav, optionally for
+// ?edition=. Articles are in the order of ?sort= or the reader's remembered
+// one, as on the briefing page.
+func (s *Server) handleNavAPI(w http.ResponseWriter, r *http.Request) {
+	periodID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/briefings/"), "/nav")
+	if !ok || periodID == "" || strings.Contains(periodID, "/") {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	edition := r.URL.Query().Get("edition")
+	if edition == "" {
+		edition = database.EditionMorning
+	}
+
+	briefing, err := s.db.GetBriefingEdition(periodID, edition)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "could not load briefing")
+		return
+	}
+	if briefing == nil {
+		writeJSONError(w, http.StatusNotFound, "no such briefing")
+		return
+	}
+	previous, next, err := s.db.GetAdjacentBriefingPeriods(periodID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "could not load briefings")
+		return
+	}
+
+	nav := navJSON{PeriodID: periodID, Edition: edition, Storylines: []navStorylineJSON{}}
+	if previous != "" {
+		nav.Previous = &previous
+	}
+	if next != "" {
+		nav.Next = &next
+	}
+	// Storylines belong to the morning edition, as on the briefing page.
+	if edition == database.EditionMorning {
+		narratives, _ := s.db.GetNarrativesForPeriod(periodID)
+		sfMap, _ := s.db.GetStorylineFeedbackMap(periodID)
+		for _, n := range narratives {
+			articles, _ := s.db.GetStorylineArticles(n.StorylineID, articleOrder(r))
+			ids := make([]int64, len(articles))
+			for i, a := range articles {
+				ids[i] = a.ID
+			}
+			afMap, _ := s.db.GetArticleFeedbackMap(ids)
+
+			sv := navStorylineJSON{ID: n.StorylineID, Title: n.Title, Feedback: optional(sfMap[n.StorylineID]), Stale: n.Stale, Articles: []navArticleJSON{}}
+			for _, a := range articles {
+				sv.Articles = append(sv.Articles, navArticleJSON{ID: a.ID, Title: a.Title, URL: a.URL, Feedback: optional(afMap[a.ID])})
+			}
+			nav.Storylines = append(nav.Storylines, sv)
+		}
+	}
+	writeJSON(w, http.StatusOK, nav)
+}
+
+// handleFeedbackAPI serves POST /api/v1/feedback/{storyline|article}/{id}.
+// Unlike the toggling form buttons it sets the rating in the body, so
+// repeating a request doesn't undo it.
+func (s *Server) handleFeedbackAPI(w http.ResponseWriter, r *http.Request) {
+	kind, rawID, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/feedback/"), "/")
+	ratings, known := feedbackRatings[kind]
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if !known || err != nil {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body feedbackJSON
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	rating := ""
+	if body.Rating != nil {
+		rating = *body.Rating
+	}
+	if rating != "" && rating != ratings[0] && rating != ratings[1] {
+		writeJSONError(w, http.StatusBadRequest, "rating must be "+ratings[0]+", "+ratings[1]+" or null")
+		return
+	}
+
+	switch kind {
+	case "storyline":
+		narrative, err := s.db.GetNarrativeForStoryline(id)
+		if err != nil || narrative == nil {
+			writeJSONError(w, http.StatusNotFound, "no such storyline")
+			return
+		}
+		if rating == "" {
+			err = s.db.DeleteStorylineFeedback(id)
+		} else {
+			err = s.db.UpsertStorylineFeedback(id, narrative.PeriodID, rating)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "could not store feedback")
+			return
+		}
+		s.audit(r, database.AuditStorylineFeedback, id, cmp.Or(rating, "cleared"))
+	case "article":
+		article, err := s.db.GetArticleByID(id)
+		if err != nil || article == nil {
+			writeJSONError(w, http.StatusNotFound, "no such article")
+			return
+		}
+		if rating == "" {
+			err = s.db.DeleteArticleFeedback(id)
+		} else {
+			err = s.db.UpsertArticleFeedback(id, rating)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "could not store feedback")
+			return
+		}
+		s.audit(r, database.AuditArticleFeedback, id, cmp.Or(rating, "cleared"))
+	}
+	writeJSON(w, http.StatusOK, feedbackJSON{ID: id, Rating: optional(rating)})
+}
+
+// optional returns nil for an empty string, for JSON nulls.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/refine"
+)
+
+// refineView is the state of the refinement dialog on the priorities page:
+// the questions to answer, then the changes proposed.
+type refineView struct {
+	Questions []string
+	Proposals []proposalView
+	Error     string
+}
+
+// proposalView is a proposed change, with the change itself encoded as the
+// value of its checkbox, so applying it needs no state on the server.
+type proposalView struct {
+	Summary string
+	Reason  string
+	Value   string
+}
+
+// handleRefine runs the refinement dialog: GET asks the questions, POST
+// with step=answers proposes changes and POST with step=apply makes the
+// changes checked.
+func (s *Server) handleRefine(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Refiner == nil {
+		s.renderPriorities(w, &refineView{Error: "Refining priorities needs an LLM provider."})
+		return
+	}
+
+	switch {
+	case r.Method != http.MethodPost:
+		questions, err := s.opts.Refiner.Questions(r.Context())
+		if err != nil {
+			log.Printf("Asking refinement questions: %v", err)
+			s.renderPriorities(w, &refineView{Error: "The questions could not be generated: " + err.Error()})
+			return
+		}
+		s.renderPriorities(w, &refineView{Questions: questions})
+
+	case r.FormValue("step") == "apply":
+		for _, value := range r.Form["change"] {
+			var c refine.Change
+			if err := json.Unmarshal([]byte(value), &c); err != nil {
+				continue
+			}
+			if err := refine.Apply(s.db, auditUser(r), c); err != nil {
+				log.Printf("Applying %q: %v", c.Summary(), err)
+			}
+		}
+		http.Redirect(w, r, "/priorities", http.StatusFound)
+
+	default:
+		var answers []refine.Answer
+		for i := 0; r.FormValue(fmt.Sprintf("question_%d", i)) != ""; i++ {
+			answers = append(answers, refine.Answer{
+				Question: r.FormValue(fmt.Sprintf("question_%d", i)),
+				Answer:   r.FormValue(fmt.Sprintf("answer_%d", i)),
+			})
+		}
+		changes, err := s.opts.Refiner.Propose(r.Context(), answers)
+		if err != nil {
+			log.Printf("Proposing priority changes: %v", err)
+			s.renderPriorities(w, &refineView{Error: "No changes could be proposed: " + err.Error()})
+			return
+		}
+		view := &refineView{}
+		for _, c := range changes {
+			value, _ := json.Marshal(c)
+			view.Proposals = append(view.Proposals, proposalView{Summary: c.Summary(), Reason: c.Reason, Value: string(value)})
+		}
+		if len(view.Proposals) == 0 {
+			view.Error = "Your answers call for no changes."
+		}
+		s.renderPriorities(w, view)
+	}
+}
+
+// handleAddMute handles POST /priorities/mutes, adding the mute rule of the
+// form's kind and value.
+func (s *Server) handleAddMute(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		kind, value := r.FormValue("kind"), strings.TrimSpace(r.FormValue("value"))
+		if id, err := s.db.InsertMuteRule(kind, value); err == nil {
+			s.audit(r, database.AuditMuteAdd, id, kind+" "+value)
+		}
+	}
+	http.Redirect(w, r, "/priorities", http.StatusFound)
+}
+
+// handleMuteAction handles POST /priorities/mutes/{id}/delete.
+func (s *Server) handleMuteAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/priorities", http.StatusFound)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/priorities/mutes/"), "/", 2)
+	if len(parts) != 2 || parts[1] != "delete" {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if rule, _ := s.db.GetMuteRule(id); rule != nil && s.db.DeleteMuteRule(id) == nil {
+		s.audit(r, database.AuditMuteDelete, id, rule.Kind+" "+rule.Value)
+	}
+	http.Redirect(w, r, "/priorities", http.StatusFound)
+}
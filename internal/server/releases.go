@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// ReleaseMonth groups model releases of one calendar month for display.
+type ReleaseMonth struct {
+	Month    string // e.g. "February 2026"
+	Releases []database.ModelRelease
+}
+
+// modelReleaseJSON is the API representation of a model release.
+type modelReleaseJSON struct {
+	Name          string  `json:"name"`
+	Vendor        *string `json:"vendor"`
+	ReleaseDate   string  `json:"release_date"`
+	License       *string `json:"license"`
+	ContextWindow *int64  `json:"context_window"`
+	SourceURL     *string `json:"source_url"`
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	month := r.URL.Query().Get("month")
+	from, to, ok := monthRange(month)
+	if !ok {
+		month = ""
+	}
+
+	releases, err := s.db.GetModelReleases(from, to)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, "models.html", map[string]any{
+		"Month":  month,
+		"Months": groupReleasesByMonth(releases),
+	})
+}
+
+// handleModelsAPI lists a page of model releases as JSON, optionally for one
+// month (?month=YYYY-MM).
+func (s *Server) handleModelsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	month := r.URL.Query().Get("month")
+	from, to, ok := monthRange(month)
+	if month != "" && !ok {
+		writeJSONError(w, http.StatusBadRequest, "month must be YYYY-MM")
+		return
+	}
+
+	opts, msg := parseListOptions(r.URL.Query())
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	releases, total, err := s.db.ListModelReleases(from, to, opts)
+	if err != nil {
+		writeListError(w, err, "model releases")
+		return
+	}
+
+	out := make([]modelReleaseJSON, 0, len(releases))
+	for _, m := range releases {
+		out = append(out, modelReleaseJSON{
+			Name:          m.Name,
+			Vendor:        m.Vendor,
+			ReleaseDate:   m.ReleaseDate,
+			License:       m.License,
+			ContextWindow: m.ContextWindow,
+			SourceURL:     m.ArticleURL,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"releases": out, "page": newPage(opts, total)})
+}
+
+// monthRange converts YYYY-MM into its first and last day. An empty or
+// malformed month yields an unbounded range and ok=false.
+func monthRange(month string) (from, to string, ok bool) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", false
+	}
+	return start.Format("2006-01-02"), start.AddDate(0, 1, -1).Format("2006-01-02"), true
+}
+
+// groupReleasesByMonth splits releases, already ordered newest first, into
+// one group per calendar month.
+func groupReleasesByMonth(releases []database.ModelRelease) []ReleaseMonth {
+	var months []ReleaseMonth
+	for _, m := range releases {
+		label := m.ReleaseDate
+		if d, err := time.Parse("2006-01-02", m.ReleaseDate); err == nil {
+			label = d.Format("January 2006")
+		}
+		if len(months) == 0 || months[len(months)-1].Month != label {
+			months = append(months, ReleaseMonth{Month: label})
+		}
+		months[len(months)-1].Releases = append(months[len(months)-1].Releases, m)
+	}
+	return months
+}
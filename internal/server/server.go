@@ -6,19 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/yuin/goldmark"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/refine"
 )
 
 //go:embed templates/*.html
@@ -27,6 +31,9 @@ var templateFS embed.FS
 //go:embed static/*
 var staticFS embed.FS
 
+//go:embed openapi.json
+var openAPISpec []byte
+
 var md = goldmark.New()
 
 // StorylineView bundles a storyline narrative with its articles and feedback for template rendering.
@@ -34,6 +41,8 @@ type StorylineView struct {
 	Narrative database.StorylineNarrative
 	Articles  []ArticleView
 	Feedback  string // "useful", "not_useful", or ""
+	SOTA      bool   // an article reports a state-of-the-art benchmark result
+	Image     string // the lead image of its first source that has one, or ""
 }
 
 // ArticleView bundles an article with its triage and feedback for template rendering.
@@ -41,21 +50,66 @@ type ArticleView struct {
 	Article     database.Article
 	Triage      *database.ArticleTriage
 	Feedback    string // "positive", "negative", or ""
+	SourceType  string // one of database.SourceTypes, or "" if unknown
 	StorylineID int64
 }
 
+// Options configures optional server features.
+type Options struct {
+	// IngestToken enables POST /api/v1/ingest for clients presenting it as a
+	// bearer token. The endpoint is disabled when empty.
+	IngestToken string
+
+	// MaxIngestBytes caps the size of an ingest request body; 0 means 5 MB.
+	MaxIngestBytes int64
+
+	// Users, when set, must log in: every route but static files and ingest
+	// needs one of them, and editing priorities or retrying jobs needs an
+	// admin. Without users the server is open to everyone who can reach it.
+	Users []User
+
+	// OnStorylineChange, when set, is called with the period of storylines
+	// whose articles were changed through the API, whose narratives are
+	// stale.
+	OnStorylineChange func(periodID string)
+
+	// Refiner, when set, lets admins refine the priorities by answering a
+	// few questions on the priorities page.
+	Refiner *refine.Refiner
+}
+
 // Server is the HTTP server for serving briefings.
 type Server struct {
 	db    *database.DB
+	opts  Options
 	pages map[string]*template.Template
 	mux   *http.ServeMux
 }
 
 // New creates a new Server.
-func New(db *database.DB) (*Server, error) {
+func New(db *database.DB, opts Options) (*Server, error) {
+	if err := validateUsers(opts.Users); err != nil {
+		return nil, err
+	}
+
 	funcMap := template.FuncMap{
 		"markdown":     renderMarkdown,
 		"formatPeriod": database.FormatPeriodDisplay,
+		"formatDay": func(date string) string {
+			if d, err := time.Parse("2006-01-02", date); err == nil {
+				return d.Format("Jan 02")
+			}
+			return date
+		},
+		"formatTokens": func(n *int64) string {
+			switch {
+			case *n >= 1_000_000 && *n%1_000_000 == 0:
+				return fmt.Sprintf("%dM", *n/1_000_000)
+			case *n >= 1000:
+				return fmt.Sprintf("%dK", *n/1000)
+			}
+			return fmt.Sprintf("%d", *n)
+		},
 		"deref": func(s *string) string {
 			if s == nil {
 				return ""
@@ -72,7 +126,7 @@ func New(db *database.DB) (*Server, error) {
 
 	// For each page template, clone the base and parse the page into the clone.
 	// This gives each page its own {{define "content"}} and {{define "title"}}.
-	pageNames := []string{"index.html", "briefing.html", "priorities.html"}
+	pageNames := []string{"index.html", "briefing.html", "priorities.html", "benchmarks.html", "models.html", "jobs.html"}
 	pages := make(map[string]*template.Template, len(pageNames))
 	for _, name := range pageNames {
 		clone, err := base.Clone()
@@ -86,7 +140,7 @@ func New(db *database.DB) (*Server, error) {
 		pages[name] = clone
 	}
 
-	s := &Server{db: db, pages: pages, mux: http.NewServeMux()}
+	s := &Server{db: db, opts: opts, pages: pages, mux: http.NewServeMux()}
 	s.routes()
 	return s, nil
 }
@@ -102,13 +156,31 @@ func (s *Server) routes() {
 	s.mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(staticSub))))
 
 	// Routes
-	s.mux.HandleFunc("/", s.handleIndex)
-	s.mux.HandleFunc("/briefing/", s.handleBriefing)
-	s.mux.HandleFunc("/feedback/storyline/", s.handleStorylineFeedback)
-	s.mux.HandleFunc("/feedback/article/", s.handleArticleFeedback)
-	s.mux.HandleFunc("/priorities", s.handlePriorities)
-	s.mux.HandleFunc("/priorities/add", s.handleAddPriority)
-	s.mux.HandleFunc("/priorities/", s.handlePriorityAction)
+	s.mux.HandleFunc("/", s.require(RoleReader, s.handleIndex))
+	s.mux.HandleFunc("/briefing/", s.require(RoleReader, s.handleBriefing))
+	s.mux.HandleFunc("/feedback/storyline/", s.require(RoleReader, s.handleStorylineFeedback))
+	s.mux.HandleFunc("/feedback/article/", s.require(RoleReader, s.handleArticleFeedback))
+	s.mux.HandleFunc("/priorities", s.require(RoleReader, s.handlePriorities))
+	s.mux.HandleFunc("/priorities/add", s.require(RoleAdmin, s.handleAddPriority))
+	s.mux.HandleFunc("/priorities/", s.require(RoleAdmin, s.handlePriorityAction))
+	s.mux.HandleFunc("/priorities/refine", s.require(RoleAdmin, s.handleRefine))
+	s.mux.HandleFunc("/priorities/mutes", s.require(RoleAdmin, s.handleAddMute))
+	s.mux.HandleFunc("/priorities/mutes/", s.require(RoleAdmin, s.handleMuteAction))
+	s.mux.HandleFunc("/benchmarks", s.require(RoleReader, s.handleBenchmarks))
+	s.mux.HandleFunc("/models", s.require(RoleReader, s.handleModels))
+	s.mux.HandleFunc("/events.ics", s.require(RoleReader, s.handleEventsICS))
+	s.mux.HandleFunc("/jobs", s.require(RoleReader, s.handleJobs))
+	s.mux.HandleFunc("/jobs/", s.require(RoleAdmin, s.handleJobAction))
+
+	// JSON API; ingest keeps its own token for automations
+	s.mux.HandleFunc("/api/v1/ingest", s.requireToken(s.opts.IngestToken, s.handleIngest))
+	s.mux.HandleFunc("/api/v1/models", s.require(RoleReader, s.handleModelsAPI))
+	s.mux.HandleFunc("/api/v1/articles", s.require(RoleReader, s.handleArticlesAPI))
+	s.mux.HandleFunc("/api/v1/audit", s.require(RoleAdmin, s.handleAuditAPI))
+	s.mux.HandleFunc("/api/v1/briefings/", s.require(RoleReader, s.handleNavAPI))
+	s.mux.HandleFunc("/api/v1/feedback/", s.require(RoleReader, s.handleFeedbackAPI))
+	s.mux.HandleFunc("/api/v1/storylines/", s.require(RoleAdmin, s.handleStorylineArticlesAPI))
+	s.mux.HandleFunc("/api/v1/openapi.json", s.require(RoleReader, s.handleOpenAPI))
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -135,12 +207,54 @@ func (s *Server) handleBriefing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	briefing, _ := s.db.GetBriefing(periodID)
+	edition := r.URL.Query().Get("edition")
+	if edition == "" {
+		edition = database.EditionMorning
+	}
 
-	// Build structured storyline views
+	// ?source_type= narrows the source lists to one type of source, and the
+	// storylines to those with at least one such source.
+	sourceType := r.URL.Query().Get("source_type")
+	if !validSourceType(sourceType) {
+		sourceType = ""
+	}
+	order := articleOrder(r)
+	if r.URL.Query().Get("sort") == order {
+		rememberArticleOrder(w, order)
+	}
+
+	s.render(w, "briefing.html", s.briefingPage(periodID, edition, sourceType, order))
+}
+
+// RenderBriefing writes the briefing page of an edition as the web UI
+// serves it, unfiltered and with sources in collection order.
+func (s *Server) RenderBriefing(w io.Writer, periodID, edition string) error {
+	page := s.briefingPage(periodID, edition, "", database.ArticleOrderCollected)
+	return s.pages["briefing.html"].ExecuteTemplate(w, "base.html", page)
+}
+
+// briefingPage gathers the template data of the briefing page of an
+// edition, showing sourceType sources only unless it is empty.
+func (s *Server) briefingPage(periodID, edition, sourceType, order string) map[string]any {
+	briefing, _ := s.db.GetBriefingEdition(periodID, edition)
+	editions, _ := s.db.GetBriefingEditions(periodID)
+	typeMap, _ := s.db.GetSourceTypes()
+	typeOf := func(a database.Article) string {
+		if a.Source == nil {
+			return ""
+		}
+		return typeMap[*a.Source]
+	}
+
+	// Storylines belong to the morning edition; later editions are
+	// rendered from their markdown body only.
 	var storylines []StorylineView
-	narratives, _ := s.db.GetNarrativesForPeriod(periodID)
+	var narratives []database.StorylineNarrative
+	if edition == database.EditionMorning {
+		narratives, _ = s.db.GetNarrativesForPeriod(periodID)
+	}
 	sfMap, _ := s.db.GetStorylineFeedbackMap(periodID)
+	sotaMap, _ := s.db.GetSOTAStorylines(periodID)
 
 	// Collect all article IDs for batch feedback lookup
 	var allArticleIDs []int64
@@ -150,7 +264,7 @@ func (s *Server) handleBriefing(w http.ResponseWriter, r *http.Request) {
 	naArticles := make([]narrativeArticles, len(narratives))
 
 	for i, n := range narratives {
-		articles, _ := s.db.GetStorylineArticles(n.StorylineID)
+		articles, _ := s.db.GetStorylineArticles(n.StorylineID, order)
 		naArticles[i] = narrativeArticles{articles: articles}
 		for _, a := range articles {
 			allArticleIDs = append(allArticleIDs, a.ID)
@@ -163,22 +277,36 @@ func (s *Server) handleBriefing(w http.ResponseWriter, r *http.Request) {
 		sv := StorylineView{
 			Narrative: n,
 			Feedback:  sfMap[n.StorylineID],
+			SOTA:      sotaMap[n.StorylineID],
 		}
 		for _, a := range naArticles[i].articles {
+			if sourceType != "" && typeOf(a) != sourceType {
+				continue
+			}
 			triage, _ := s.db.GetTriage(a.ID)
 			sv.Articles = append(sv.Articles, ArticleView{
 				Article:     a,
 				Triage:      triage,
 				Feedback:    afMap[a.ID],
+				SourceType:  typeOf(a),
 				StorylineID: n.StorylineID,
 			})
 		}
+		if sourceType != "" && len(sv.Articles) == 0 {
+			continue
+		}
+		for _, a := range sv.Articles {
+			if a.Article.ImageURL != nil {
+				sv.Image = *a.Article.ImageURL
+				break
+			}
+		}
 		storylines = append(storylines, sv)
 	}
 
 	// Fallback: when no storylines exist, load articles directly for feedback
 	var articles []ArticleView
-	if len(storylines) == 0 && briefing != nil {
+	if len(narratives) == 0 && briefing != nil && edition == database.EditionMorning {
 		allArticles, err := s.db.GetArticlesForPeriod(periodID)
 		if err != nil {
 			log.Printf("error fetching articles for period %s: %v", periodID, err)
@@ -193,22 +321,94 @@ func (s *Server) handleBriefing(w http.ResponseWriter, r *http.Request) {
 			if triage == nil || triage.Verdict != "relevant" {
 				continue
 			}
+			if sourceType != "" && typeOf(a) != sourceType {
+				continue
+			}
 			articles = append(articles, ArticleView{
-				Article:  a,
-				Triage:   triage,
-				Feedback: afMap[a.ID],
+				Article:    a,
+				Triage:     triage,
+				Feedback:   afMap[a.ID],
+				SourceType: typeOf(a),
 			})
 		}
 	}
 
-	s.render(w, "briefing.html", map[string]any{
-		"Briefing":   briefing,
-		"PeriodID":   periodID,
-		"Storylines": storylines,
-		"Articles":   articles,
+	var highlights []database.BriefingHighlight
+	if edition == database.EditionMorning {
+		highlights, _ = s.db.GetBriefingHighlights(periodID)
+	}
+
+	// The markdown body already lists upcoming events; the structured
+	// storyline view doesn't show the body, so load them separately.
+	var upcoming []database.Event
+	if len(narratives) > 0 {
+		from, to := database.UpcomingWindow(periodID, database.UpcomingDays)
+		upcoming, _ = s.db.GetEventsBetween(from, to, database.MaxUpcomingEvents)
+	}
+
+	return map[string]any{
+		"Briefing":      briefing,
+		"PeriodID":      periodID,
+		"Edition":       edition,
+		"Editions":      editions,
+		"Highlights":    highlights,
+		"HasStorylines": len(narratives) > 0,
+		"Storylines":    storylines,
+		"Upcoming":      upcoming,
+		"Articles":      articles,
+		"SourceType":    sourceType,
+		"SourceTypes":   database.SourceTypes,
+		"ArticleOrder":  order,
+		"ArticleOrders": articleOrders,
+	}
+}
+
+// articleOrders are the orders a reader can pick for the sources of each
+// storyline, with their labels.
+var articleOrders = []struct{ Key, Label string }{
+	{database.ArticleOrderCollected, "As collected"},
+	{database.ArticleOrderScore, "Practical score"},
+	{database.ArticleOrderPublished, "Newest"},
+	{database.ArticleOrderReputation, "Source reputation"},
+}
+
+// articleOrderCookie remembers the article order a reader picked last.
+const articleOrderCookie = "article_order"
+
+// articleOrder returns the order of storyline articles for r: the one named
+// by ?sort=, else the remembered one, else collection order.
+func articleOrder(r *http.Request) string {
+	if o := r.URL.Query().Get("sort"); slices.Contains(database.ArticleOrders, o) {
+		return o
+	}
+	if c, err := r.Cookie(articleOrderCookie); err == nil && slices.Contains(database.ArticleOrders, c.Value) {
+		return c.Value
+	}
+	return database.ArticleOrderCollected
+}
+
+// rememberArticleOrder keeps a picked article order for a year, so it
+// applies to every briefing the reader opens.
+func rememberArticleOrder(w http.ResponseWriter, order string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     articleOrderCookie,
+		Value:    order,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
 
+func validSourceType(t string) bool {
+	for _, st := range database.SourceTypes {
+		if t == st {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) handleStorylineFeedback(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Redirect(w, r, "/", http.StatusFound)
@@ -240,8 +440,10 @@ func (s *Server) handleStorylineFeedback(w http.ResponseWriter, r *http.Request)
 	current, _ := s.db.GetStorylineFeedback(id)
 	if current != nil && current.Rating == rating {
 		s.db.DeleteStorylineFeedback(id)
+		s.audit(r, database.AuditStorylineFeedback, id, "cleared")
 	} else {
 		s.db.UpsertStorylineFeedback(id, periodID, rating)
+		s.audit(r, database.AuditStorylineFeedback, id, rating)
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/briefing/%s#storyline-%d", periodID, id), http.StatusFound)
@@ -279,8 +481,10 @@ func (s *Server) handleArticleFeedback(w http.ResponseWriter, r *http.Request) {
 	current, _ := s.db.GetArticleFeedback(id)
 	if current != nil && current.Rating == rating {
 		s.db.DeleteArticleFeedback(id)
+		s.audit(r, database.AuditArticleFeedback, id, "cleared")
 	} else {
 		s.db.UpsertArticleFeedback(id, rating)
+		s.audit(r, database.AuditArticleFeedback, id, rating)
 	}
 
 	anchor := ""
@@ -291,9 +495,19 @@ func (s *Server) handleArticleFeedback(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handlePriorities(w http.ResponseWriter, r *http.Request) {
+	s.renderPriorities(w, nil)
+}
+
+// renderPriorities renders the priorities page, with the refinement dialog
+// open when refinement is set.
+func (s *Server) renderPriorities(w http.ResponseWriter, refinement *refineView) {
 	priorities, _ := s.db.GetAllPriorities()
+	mutes, _ := s.db.GetMuteRules()
 	s.render(w, "priorities.html", map[string]any{
 		"Priorities": priorities,
+		"Mutes":      mutes,
+		"CanRefine":  s.opts.Refiner != nil,
+		"Refine":     refinement,
 	})
 }
 
@@ -307,7 +521,9 @@ func (s *Server) handleAddPriority(w http.ResponseWriter, r *http.Request) {
 	description := strings.TrimSpace(r.FormValue("description"))
 
 	if title != "" {
-		s.db.InsertPriority(title, description, nil)
+		if id, err := s.db.InsertPriority(title, description, nil); err == nil {
+			s.audit(r, database.AuditPriorityAdd, id, title)
+		}
 	}
 
 	http.Redirect(w, r, "/priorities", http.StatusFound)
@@ -332,16 +548,30 @@ func (s *Server) handlePriorityAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	priority, _ := s.db.GetPriority(id)
+	if priority == nil {
+		http.Redirect(w, r, "/priorities", http.StatusFound)
+		return
+	}
+
 	switch parts[1] {
 	case "toggle":
-		s.db.TogglePriority(id)
+		if s.db.TogglePriority(id) == nil {
+			state := "enabled"
+			if priority.IsActive {
+				state = "disabled"
+			}
+			s.audit(r, database.AuditPriorityToggle, id, state)
+		}
 	case "delete":
-		s.db.DeletePriority(id)
+		if s.db.DeletePriority(id) == nil {
+			s.audit(r, database.AuditPriorityDelete, id, priority.Title)
+		}
 	case "edit":
 		title := strings.TrimSpace(r.FormValue("title"))
 		description := strings.TrimSpace(r.FormValue("description"))
-		if title != "" {
-			s.db.UpdatePriority(id, &title, &description, nil)
+		if title != "" && s.db.UpdatePriority(id, &title, &description, nil) == nil {
+			s.audit(r, database.AuditPriorityEdit, id, title)
 		}
 	}
 
@@ -371,8 +601,8 @@ func renderMarkdown(text string) template.HTML {
 }
 
 // Serve starts the HTTP server on the given port.
-func Serve(db *database.DB, port int) error {
-	srv, err := New(db)
+func Serve(db *database.DB, port int, opts Options) error {
+	srv, err := New(db, opts)
 	if err != nil {
 		return err
 	}
@@ -1,14 +1,20 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/golden"
+	"github.com/TobiSchelling/AICrawler/internal/refine"
 )
 
 func openTestDB(t *testing.T) *database.DB {
@@ -25,7 +31,7 @@ func ptr(s string) *string { return &s }
 
 func TestIndexRoute(t *testing.T) {
 	db := openTestDB(t)
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -46,7 +52,7 @@ func TestBriefingRoute(t *testing.T) {
 	db := openTestDB(t)
 	db.InsertBriefing("2026-02-06", "- Key point", "## Section\nContent", 1, 5)
 
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -64,6 +70,30 @@ func TestBriefingRoute(t *testing.T) {
 	}
 }
 
+func TestBriefingPageGolden(t *testing.T) {
+	db := openTestDB(t)
+	if err := golden.Seed(db); err != nil {
+		t.Fatalf("seeding fixture: %v", err)
+	}
+	srv, err := New(db, Options{})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	var page strings.Builder
+	if err := srv.RenderBriefing(&page, golden.PeriodID, database.EditionMorning); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	golden.Assert(t, "briefing-page", []byte(page.String()))
+
+	// The web UI serves the same page.
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/briefing/"+golden.PeriodID, nil))
+	if rec.Body.String() != page.String() {
+		t.Error("expected the served page to match the rendered one")
+	}
+}
+
 func TestStorylineFeedbackRoute(t *testing.T) {
 	db := openTestDB(t)
 	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
@@ -71,7 +101,7 @@ func TestStorylineFeedbackRoute(t *testing.T) {
 	db.InsertStorylineNarrative(sid, "2026-02-06", "AI Testing", "Narrative text.", nil)
 	db.InsertBriefing("2026-02-06", "TL;DR", "Body", 1, 1)
 
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -117,7 +147,7 @@ func TestArticleFeedbackRoute(t *testing.T) {
 	db.InsertStorylineNarrative(sid, "2026-02-06", "Test", "Narrative.", nil)
 	db.InsertBriefing("2026-02-06", "TL;DR", "Body", 1, 1)
 
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -147,7 +177,7 @@ func TestBriefingStructured(t *testing.T) {
 	db.InsertTriage(a1, "relevant", &at, nil, nil, 4)
 	db.InsertBriefing("2026-02-06", "- Key point", "## Section\nContent", 1, 1)
 
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -183,7 +213,7 @@ func TestBriefingFallbackShowsArticleFeedback(t *testing.T) {
 	db.InsertTriage(aid, "relevant", &at, nil, nil, 3)
 	db.InsertBriefing("2026-02-06", "TL;DR", "## Body\nMarkdown content", 0, 1)
 
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -210,7 +240,7 @@ func TestBriefingFallbackShowsArticleFeedback(t *testing.T) {
 
 func TestStaticRoute(t *testing.T) {
 	db := openTestDB(t)
-	srv, err := New(db)
+	srv, err := New(db, Options{})
 	if err != nil {
 		t.Fatalf("failed to create server: %v", err)
 	}
@@ -226,3 +256,824 @@ func TestStaticRoute(t *testing.T) {
 		t.Error("expected CSS content")
 	}
 }
+
+func TestBriefingEditionNavigation(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertBriefing("2026-02-06", "- Morning point", "Morning body", 1, 5)
+	db.InsertBriefingEdition("2026-02-06", database.EditionEvening, "- Evening point", "## Since this morning", 0, 2)
+
+	srv, err := New(db, Options{})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/briefing/2026-02-06?edition=evening", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Evening point") {
+		t.Error("expected evening TL;DR in response")
+	}
+	if !strings.Contains(body, `href="/briefing/2026-02-06?edition=morning"`) {
+		t.Error("expected link to the morning edition")
+	}
+}
+
+func TestBriefingShowsQualityNote(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 1, 1)
+	note := "clustering degraded: keyword fallback used; 14 articles missing full text"
+	db.SetBriefingQualityNote("2026-02-06", database.EditionMorning, &note)
+
+	srv, err := New(db, Options{})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	for _, path := range []string{"/briefing/2026-02-06", "/"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if !strings.Contains(rec.Body.String(), "14 articles missing full text") {
+			t.Errorf("expected quality note on %s", path)
+		}
+	}
+}
+
+func TestBriefingShowsTeamHighlights(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 1, 1)
+	qa, _ := db.InsertProfile("qa", "For QA")
+	db.InsertBriefingHighlight("2026-02-06", qa, "For QA", "- Flaky tests got easier to triage")
+
+	srv, err := New(db, Options{})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/briefing/2026-02-06", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "For QA") || !strings.Contains(body, "Flaky tests got easier to triage") {
+		t.Error("expected QA highlight section in response")
+	}
+}
+
+func TestIngestRequiresToken(t *testing.T) {
+	db := openTestDB(t)
+
+	disabled, _ := New(db, Options{})
+	req := httptest.NewRequest("POST", "/api/v1/ingest", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	disabled.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without configured token, got %d", rec.Code)
+	}
+
+	srv, _ := New(db, Options{IngestToken: "secret"})
+	req = httptest.NewRequest("POST", "/api/v1/ingest", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for wrong token, got %d", rec.Code)
+	}
+}
+
+func TestIngestArticles(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertArticle("https://existing.com", "Existing", nil, nil, nil, ptr("2026-02-06"))
+	srv, _ := New(db, Options{IngestToken: "secret"})
+
+	payload := `[
+		{"url": "https://scraper.internal/post-1", "title": "Internal Post", "content": "Body", "source": "Scraper"},
+		{"url": "https://existing.com", "title": "Existing"}
+	]`
+	req := httptest.NewRequest("POST", "/api/v1/ingest", strings.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"accepted":1`) || !strings.Contains(rec.Body.String(), `"duplicates":1`) {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+
+	untriaged, _ := db.GetUntriagedArticles(nil)
+	found := false
+	for _, a := range untriaged {
+		if a.URL == "https://scraper.internal/post-1" {
+			found = a.PeriodID == nil && a.Source != nil && *a.Source == "Scraper"
+		}
+	}
+	if !found {
+		t.Error("expected ingested article pending triage with its source and no period")
+	}
+}
+
+func TestIngestRejectsInvalidArticle(t *testing.T) {
+	db := openTestDB(t)
+	srv, _ := New(db, Options{IngestToken: "secret"})
+
+	req := httptest.NewRequest("POST", "/api/v1/ingest", strings.NewReader(`{"url": "not-a-url", "title": "X"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestEventsICS(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com/post", "A", nil, nil, nil, nil)
+	future := time.Now().AddDate(0, 0, 10)
+	db.InsertEvent(aid, "DevConf, Berlin; day one", database.EventConference, future.Format("2006-01-02"))
+
+	srv, _ := New(db, Options{})
+	req := httptest.NewRequest("GET", "/events.ics", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("expected text/calendar, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		`SUMMARY:DevConf\, Berlin\; day one` + "\r\n",
+		"DTSTART;VALUE=DATE:" + future.Format("20060102") + "\r\n",
+		"URL:https://a.com/post\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in ICS output:\n%s", want, body)
+		}
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("é", 60)
+	folded := foldICSLine(long)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("line exceeds 75 octets: %d", len(part))
+		}
+	}
+	if strings.ReplaceAll(folded, "\r\n ", "") != long {
+		t.Error("unfolding should restore the original line")
+	}
+}
+
+func TestBriefingShowsUpcomingEvents(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Releases", []int64{aid})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Releases", "Text", nil)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 1, 1)
+	db.InsertEvent(aid, "Model v5 release", database.EventRelease, "2026-02-20")
+
+	srv, _ := New(db, Options{})
+	req := httptest.NewRequest("GET", "/briefing/2026-02-06", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Feb 20") || !strings.Contains(body, "Model v5 release") {
+		t.Error("expected upcoming event in structured briefing view")
+	}
+}
+
+func TestBriefingShowsHypeMeter(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Releases", []int64{aid})
+	nid, _ := db.InsertStorylineNarrative(sid, "2026-02-06", "Releases", "Text", nil)
+	db.SetNarrativeHypeScore(nid, 0.5)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 1, 1)
+
+	srv, _ := New(db, Options{})
+	req := httptest.NewRequest("GET", "/briefing/2026-02-06", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `class="hype-meter hype-mixed"`) {
+		t.Error("expected mixed hype indicator on storyline")
+	}
+}
+
+func TestBriefingShowsStorylineThumbnail(t *testing.T) {
+	db := openTestDB(t)
+	plain, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	pictured, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, ptr("2026-02-06"))
+	db.SetArticleImage(pictured, "https://b.com/lead.jpg")
+	sid, _ := db.InsertStoryline("2026-02-06", "Releases", []int64{plain, pictured})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Releases", "Text", nil)
+	other, _ := db.InsertStoryline("2026-02-06", "Other", []int64{plain})
+	db.InsertStorylineNarrative(other, "2026-02-06", "Other", "Text", nil)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 2, 2)
+
+	srv, _ := New(db, Options{})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/briefing/2026-02-06", nil))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `<img class="storyline-thumb" src="https://b.com/lead.jpg"`) {
+		t.Error("expected the storyline to show its source's lead image")
+	}
+	if strings.Count(body, `class="storyline-thumb"`) != 1 {
+		t.Error("expected no thumbnail for a storyline without images")
+	}
+}
+
+func TestBenchmarksPage(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com/model", "A", nil, nil, nil, ptr("2026-02-06"))
+	db.InsertBenchmarkResult(aid, "Model A", "SWE-bench Verified", 50, "2026-01-10", false)
+	db.InsertBenchmarkResult(aid, "Model B", "SWE-bench Verified", 62.5, "2026-02-06", false)
+	sid, _ := db.InsertStoryline("2026-02-06", "Coding agents", []int64{aid})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Coding agents", "Text", nil)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 1, 1)
+
+	srv, _ := New(db, Options{})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/benchmarks", nil))
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "SWE-bench Verified") {
+		t.Fatalf("expected benchmark listed, got %d", rec.Code)
+	}
+	if !strings.Contains(body, "width: 80%") || !strings.Contains(body, "62.5") {
+		t.Error("expected scores with bars relative to the best score")
+	}
+	if !strings.Contains(body, `class="sota-badge">SOTA`) {
+		t.Error("expected new best score marked SOTA")
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/briefing/2026-02-06", nil))
+	if !strings.Contains(rec.Body.String(), "New SOTA claim") {
+		t.Error("expected storyline flagged for SOTA claim")
+	}
+}
+
+func TestModelReleasesPageAndAPI(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com/launch", "A", nil, nil, nil, nil)
+	var ctxWindow int64 = 1_000_000
+	db.UpsertModelRelease(aid, "Model Z", nil, "2026-02-10", nil, &ctxWindow)
+	db.UpsertModelRelease(aid, "Older", nil, "2026-01-20", nil, nil)
+	srv, _ := New(db, Options{})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/models", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "February 2026") || !strings.Contains(body, "January 2026") || !strings.Contains(body, "1M") {
+		t.Error("expected releases grouped by month with context window")
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/models?month=2026-02", nil))
+	var resp struct {
+		Releases []struct {
+			Name      string  `json:"name"`
+			SourceURL *string `json:"source_url"`
+		} `json:"releases"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Releases) != 1 || resp.Releases[0].Name != "Model Z" || resp.Releases[0].SourceURL == nil {
+		t.Errorf("expected only February release with source, got %+v", resp.Releases)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/models?month=feb", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for malformed month, got %d", rec.Code)
+	}
+}
+
+func TestArticlesAPIPagination(t *testing.T) {
+	db := openTestDB(t)
+	for _, title := range []string{"A", "B", "C"} {
+		db.InsertArticle("https://example.com/"+title, title, ptr("Blog"), nil, nil, ptr("2026-02-06"))
+	}
+	srv, _ := New(db, Options{})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/articles?period=2026-02-06&sort=-title&limit=2", nil))
+	var resp struct {
+		Articles []struct {
+			Title string `json:"title"`
+		} `json:"articles"`
+		Page struct {
+			Total      int  `json:"total"`
+			NextOffset *int `json:"next_offset"`
+		} `json:"page"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Articles) != 2 || resp.Articles[0].Title != "C" || resp.Page.Total != 3 || resp.Page.NextOffset == nil || *resp.Page.NextOffset != 2 {
+		t.Fatalf("expected the first page sorted by title descending, got %d: %+v", rec.Code, resp)
+	}
+
+	for _, query := range []string{"sort=content", "limit=0", "offset=-1", "verdict=maybe"} {
+		rec = httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/articles?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestOpenAISpec(t *testing.T) {
+	srv, _ := New(openTestDB(t), Options{})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON document, got %d: %v", rec.Code, err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("expected OpenAPI 3, got %q", spec.OpenAPI)
+	}
+	for path, method := range map[string]string{"/articles": "get", "/models": "get", "/ingest": "post", "/openapi.json": "get"} {
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("expected %s %s to be documented", method, path)
+		}
+		if method != "get" {
+			continue
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1"+path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected documented %s to be served, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestJobsPageAndRetry(t *testing.T) {
+	db := openTestDB(t)
+	id, _ := db.EnqueueJob("refetch", `{"period_id":"2026-02-06"}`, 1, time.Now())
+	db.ClaimNextJob(time.Now())
+	db.FailJob(id, "", "connection refused", nil)
+	srv, _ := New(db, Options{})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/jobs", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "connection refused") || !strings.Contains(body, fmt.Sprintf(`action="/jobs/%d/retry"`, id)) {
+		t.Fatalf("expected failed job with retry button, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("POST", fmt.Sprintf("/jobs/%d/retry", id), nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("expected redirect, got %d", rec.Code)
+	}
+	if job, _ := db.GetJob(id); job.Status != database.JobQueued {
+		t.Errorf("expected job queued again, got %q", job.Status)
+	}
+}
+
+func TestBriefingFiltersBySourceType(t *testing.T) {
+	db := openTestDB(t)
+	db.SetSourceType("Lab", database.SourceVendor)
+	db.SetSourceType("Paper", database.SourceNews)
+	v, _ := db.InsertArticle("https://lab.example/a", "Launch post", ptr("Lab"), nil, nil, ptr("2026-02-06"))
+	n, _ := db.InsertArticle("https://paper.example/b", "Launch coverage", ptr("Paper"), nil, nil, ptr("2026-02-06"))
+	o, _ := db.InsertArticle("https://lab.example/c", "Other launch", ptr("Lab"), nil, nil, ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Launch", []int64{v, n})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "The Launch", "Text", nil)
+	s2, _ := db.InsertStoryline("2026-02-06", "Other", []int64{o})
+	db.InsertStorylineNarrative(s2, "2026-02-06", "Vendor Only", "Text", nil)
+	db.InsertBriefing("2026-02-06", "- Point", "Body", 2, 3)
+
+	srv, _ := New(db, Options{})
+	get := func(url string) string {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		return rec.Body.String()
+	}
+
+	body := get("/briefing/2026-02-06")
+	if !strings.Contains(body, `class="source-type source-vendor"`) {
+		t.Error("expected source type badge on vendor articles")
+	}
+	if !strings.Contains(body, "Vendor Only") || !strings.Contains(body, "?source_type=news") {
+		t.Error("expected all storylines and source type filter links")
+	}
+
+	body = get("/briefing/2026-02-06?source_type=news")
+	if !strings.Contains(body, "The Launch") || !strings.Contains(body, "Launch coverage") {
+		t.Error("expected the storyline with news coverage and its news article")
+	}
+	if strings.Contains(body, "Vendor Only") || strings.Contains(body, "Launch post") {
+		t.Error("expected vendor-only storylines and vendor articles to be filtered out")
+	}
+}
+
+func TestRoleBasedAccess(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := New(db, Options{Users: []User{{Name: "eve", Role: "owner", Token: "x"}}}); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+
+	srv, err := New(db, Options{Users: []User{
+		{Name: "ada", Role: RoleAdmin, Token: "admin-token"},
+		{Name: "bob", Role: RoleReader, Token: "reader-token"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader("title=Agents"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if auth != nil {
+			auth(req)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(token string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	basic := func(name, token string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(name, token) }
+	}
+
+	rec := do("GET", "/priorities", nil)
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic") {
+		t.Errorf("expected a basic auth challenge without credentials, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/v1/articles", bearer("wrong")); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"error"`) {
+		t.Errorf("expected a JSON 401 for a wrong API token, got %d", rec.Code)
+	}
+	if rec := do("GET", "/priorities", basic("ada", "reader-token")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for another user's token, got %d", rec.Code)
+	}
+	if rec := do("GET", "/static/style.css", nil); rec.Code != http.StatusOK {
+		t.Errorf("expected static files without login, got %d", rec.Code)
+	}
+
+	if rec := do("GET", "/priorities", basic("bob", "reader-token")); rec.Code != http.StatusOK {
+		t.Errorf("expected a reader to view priorities, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/v1/articles", bearer("reader-token")); rec.Code != http.StatusOK {
+		t.Errorf("expected a reader to use the API, got %d", rec.Code)
+	}
+	if rec := do("POST", "/priorities/add", basic("bob", "reader-token")); rec.Code != http.StatusForbidden {
+		t.Errorf("expected a reader to be refused editing priorities, got %d", rec.Code)
+	}
+	if rec := do("POST", "/jobs/1/retry", bearer("reader-token")); rec.Code != http.StatusForbidden {
+		t.Errorf("expected a reader to be refused retrying jobs, got %d", rec.Code)
+	}
+
+	if rec := do("POST", "/priorities/add", basic("ada", "admin-token")); rec.Code != http.StatusFound {
+		t.Errorf("expected an admin to add a priority, got %d", rec.Code)
+	}
+	if priorities, _ := db.GetActivePriorities(); len(priorities) != 1 {
+		t.Errorf("expected the admin's priority to be stored, got %d", len(priorities))
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	srv, _ := New(db, Options{Users: []User{
+		{Name: "ada", Role: RoleAdmin, Token: "admin-token"},
+		{Name: "bob", Role: RoleReader, Token: "reader-token"},
+	}})
+	do := func(method, url, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	do("POST", fmt.Sprintf("/feedback/article/%d/positive", aid), "period_id=2026-02-06", "reader-token")
+	do("POST", "/priorities/add", "title=Agents", "admin-token")
+
+	if rec := do("GET", "/api/v1/audit", "", "reader-token"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected readers to be refused the audit log, got %d", rec.Code)
+	}
+	rec := do("GET", "/api/v1/audit?sort=created", "", "admin-token")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Entries []auditEntryJSON `json:"entries"`
+		Page    pageJSON         `json:"page"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Page.Total != 2 || len(resp.Entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %+v", resp)
+	}
+	first, second := resp.Entries[0], resp.Entries[1]
+	if first.User != "bob" || first.Action != database.AuditArticleFeedback || first.Detail != "positive" || first.TargetID == nil || *first.TargetID != aid {
+		t.Errorf("expected bob's article feedback first, got %+v", first)
+	}
+	if second.User != "ada" || second.Action != database.AuditPriorityAdd || second.Detail != "Agents" {
+		t.Errorf("expected ada's priority addition, got %+v", second)
+	}
+}
+
+func TestBriefingNavAPI(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "AI Testing", []int64{aid})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "AI Testing", "Narrative text.", nil)
+	db.UpsertStorylineFeedback(sid, "2026-02-06", "useful")
+	db.InsertBriefing("2026-02-05", "TL;DR", "Body", 0, 0)
+	db.InsertBriefing("2026-02-06", "TL;DR", "Body", 1, 1)
+	db.InsertBriefing("2026-02-09", "TL;DR", "Body", 0, 0)
+	srv, _ := New(db, Options{})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/briefings/2026-02-06/nav", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var nav navJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &nav); err != nil {
+		t.Fatal(err)
+	}
+	if nav.Previous == nil || *nav.Previous != "2026-02-05" || nav.Next == nil || *nav.Next != "2026-02-09" {
+		t.Errorf("expected neighbours 2026-02-05 and 2026-02-09, got %v and %v", nav.Previous, nav.Next)
+	}
+	if len(nav.Storylines) != 1 || nav.Storylines[0].ID != sid || nav.Storylines[0].Feedback == nil || *nav.Storylines[0].Feedback != "useful" {
+		t.Fatalf("expected the rated storyline, got %+v", nav.Storylines)
+	}
+	if a := nav.Storylines[0].Articles; len(a) != 1 || a[0].ID != aid || a[0].Feedback != nil {
+		t.Errorf("expected the unrated article, got %+v", a)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/briefings/2026-02-05/nav", nil))
+	json.Unmarshal(rec.Body.Bytes(), &nav)
+	if nav.Previous != nil || nav.Next == nil || *nav.Next != "2026-02-06" {
+		t.Errorf("expected no previous briefing for the first one, got %v and %v", nav.Previous, nav.Next)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/briefings/2026-01-01/nav", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing briefing, got %d", rec.Code)
+	}
+
+	// The briefing page carries the IDs the shortcuts script uses.
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/briefing/2026-02-06", nil))
+	body := rec.Body.String()
+	for _, want := range []string{`src="/static/shortcuts.js"`, `data-period="2026-02-06"`, fmt.Sprintf(`data-storyline-id="%d"`, sid), fmt.Sprintf(`data-article-id="%d"`, aid)} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s on the briefing page", want)
+		}
+	}
+}
+
+func TestFeedbackAPI(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "AI Testing", []int64{aid})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "AI Testing", "Narrative text.", nil)
+	srv, _ := New(db, Options{})
+	post := func(url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Setting a rating twice keeps it, unlike the toggling form buttons.
+	for range 2 {
+		if rec := post(fmt.Sprintf("/api/v1/feedback/storyline/%d", sid), `{"rating":"useful"}`); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if fb, _ := db.GetStorylineFeedback(sid); fb == nil || fb.Rating != "useful" || fb.PeriodID != "2026-02-06" {
+		t.Errorf("expected useful storyline feedback, got %+v", fb)
+	}
+	post(fmt.Sprintf("/api/v1/feedback/storyline/%d", sid), `{"rating":null}`)
+	if fb, _ := db.GetStorylineFeedback(sid); fb != nil {
+		t.Errorf("expected a null rating to clear the feedback, got %+v", fb)
+	}
+
+	rec := post(fmt.Sprintf("/api/v1/feedback/article/%d", aid), `{"rating":"negative"}`)
+	var resp feedbackJSON
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.ID != aid || resp.Rating == nil || *resp.Rating != "negative" {
+		t.Errorf("expected the stored rating back, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if fb, _ := db.GetArticleFeedback(aid); fb == nil || fb.Rating != "negative" {
+		t.Errorf("expected negative article feedback, got %+v", fb)
+	}
+
+	if rec := post(fmt.Sprintf("/api/v1/feedback/article/%d", aid), `{"rating":"useful"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a storyline rating on an article, got %d", rec.Code)
+	}
+	if rec := post("/api/v1/feedback/storyline/999", `{"rating":"useful"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown storyline, got %d", rec.Code)
+	}
+	if rec := post("/api/v1/feedback/briefing/1", `{"rating":"useful"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown kind, got %d", rec.Code)
+	}
+
+	entries, _, _ := db.ListAuditEntries(database.AuditFilter{}, database.ListOptions{})
+	if len(entries) != 4 {
+		t.Errorf("expected 4 audited feedback changes, got %d", len(entries))
+	}
+}
+
+func TestStorylineArticlesAPI(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, nil, ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{a1, a2})
+	s2, _ := db.InsertStoryline("2026-02-06", "Evals", nil)
+	db.InsertStorylineNarrative(s1, "2026-02-06", "Agents Everywhere", "Narrative text.", nil)
+	db.InsertBriefing("2026-02-06", "TL;DR", "Body", 1, 2)
+	var changed []string
+	srv, _ := New(db, Options{
+		Users: []User{
+			{Name: "ada", Role: RoleAdmin, Token: "admin-token"},
+			{Name: "bob", Role: RoleReader, Token: "reader-token"},
+		},
+		OnStorylineChange: func(periodID string) { changed = append(changed, periodID) },
+	})
+	do := func(method, url, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	moveBody := fmt.Sprintf(`{"article_id":%d}`, a2)
+
+	if rec := do("POST", fmt.Sprintf("/api/v1/storylines/%d/articles", s2), moveBody, "reader-token"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected readers to be refused, got %d", rec.Code)
+	}
+	rec := do("POST", fmt.Sprintf("/api/v1/storylines/%d/articles", s2), moveBody, "admin-token")
+	var resp storylineArticlesJSON
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || len(resp.ArticleIDs) != 1 || resp.ArticleIDs[0] != a2 {
+		t.Fatalf("expected the article in its new storyline, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(changed) != 1 || changed[0] != "2026-02-06" {
+		t.Errorf("expected one change callback for the period, got %v", changed)
+	}
+
+	// Admins browse with basic auth.
+	req := httptest.NewRequest("GET", "/briefing/2026-02-06", nil)
+	req.SetBasicAuth("ada", "admin-token")
+	page := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(page, req)
+	if !strings.Contains(page.Body.String(), `class="stale-badge"`) {
+		t.Error("expected the stale narrative to be badged on the briefing page")
+	}
+
+	rec = do("DELETE", fmt.Sprintf("/api/v1/storylines/%d/articles/%d", s1, a2), "", "admin-token")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 removing an article that moved away, got %d", rec.Code)
+	}
+	rec = do("DELETE", fmt.Sprintf("/api/v1/storylines/%d/articles/%d", s1, a1), "", "admin-token")
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || len(resp.ArticleIDs) != 0 || !resp.Stale {
+		t.Errorf("expected an empty, stale storyline, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("POST", fmt.Sprintf("/api/v1/storylines/%d/articles", s1), `{"article_id":999}`, "admin-token"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown article, got %d", rec.Code)
+	}
+	if rec := do("GET", fmt.Sprintf("/api/v1/storylines/%d/articles", s1), "", "admin-token"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+
+	entries, _, _ := db.ListAuditEntries(database.AuditFilter{}, database.ListOptions{})
+	if len(entries) != 2 {
+		t.Errorf("expected the move and the removal to be audited, got %d entries", len(entries))
+	}
+}
+
+func TestBriefingArticleOrderIsRemembered(t *testing.T) {
+	db := openTestDB(t)
+	low, _ := db.InsertArticle("https://a.com/low", "Low Score Article", nil, nil, nil, ptr("2026-02-06"))
+	high, _ := db.InsertArticle("https://b.com/high", "High Score Article", nil, nil, nil, ptr("2026-02-06"))
+	db.InsertTriage(low, "relevant", nil, nil, nil, 1)
+	db.InsertTriage(high, "relevant", nil, nil, nil, 5)
+	sid, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{low, high})
+	db.InsertStorylineNarrative(sid, "2026-02-06", "Agents", "Narrative text.", nil)
+	db.InsertBriefing("2026-02-06", "TL;DR", "Body", 1, 2)
+	srv, _ := New(db, Options{})
+	highFirst := func(body string) bool {
+		return strings.Index(body, "High Score Article") < strings.Index(body, "Low Score Article")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/briefing/2026-02-06", nil))
+	if highFirst(rec.Body.String()) {
+		t.Error("expected collection order by default")
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/briefing/2026-02-06?sort=score", nil))
+	if !highFirst(rec.Body.String()) {
+		t.Error("expected the highest score first with ?sort=score")
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != articleOrderCookie || cookies[0].Value != "score" {
+		t.Fatalf("expected the order to be remembered in a cookie, got %v", cookies)
+	}
+
+	// A later page without ?sort= uses the remembered order, as does the nav API.
+	req := httptest.NewRequest("GET", "/briefing/2026-02-06", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if !highFirst(rec.Body.String()) {
+		t.Error("expected the remembered order to apply")
+	}
+	req = httptest.NewRequest("GET", "/api/v1/briefings/2026-02-06/nav", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	var nav navJSON
+	json.Unmarshal(rec.Body.Bytes(), &nav)
+	if len(nav.Storylines) != 1 || len(nav.Storylines[0].Articles) != 2 || nav.Storylines[0].Articles[0].ID != high {
+		t.Errorf("expected the nav API to list articles in the remembered order, got %+v", nav.Storylines)
+	}
+}
+
+// refineProvider answers the refinement questions and proposals.
+type refineProvider struct{}
+
+func (refineProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	if strings.Contains(prompt, "short questions") {
+		return `{"questions": ["Is crypto news useful to you?"]}`, nil
+	}
+	return `{"changes": [
+		{"kind": "mute", "mute_kind": "term", "mute_value": "crypto", "reason": "Pure noise, they said"},
+		{"kind": "add_priority", "title": "Local models", "keywords": ["GGUF"], "reason": "They run models locally"}
+	]}`, nil
+}
+
+func (refineProvider) IsConfigured() bool { return true }
+
+func TestRefinePriorities(t *testing.T) {
+	db := openTestDB(t)
+	srv, _ := New(db, Options{Refiner: refine.NewRefiner(db, refineProvider{}, refine.Options{})})
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("GET", "/priorities/refine", "")
+	if body := rec.Body.String(); !strings.Contains(body, "<dialog open") || !strings.Contains(body, `name="question_0" value="Is crypto news useful to you?"`) {
+		t.Fatalf("expected the dialog with the question, got %d: %s", rec.Code, body)
+	}
+
+	form := url.Values{"step": {"answers"}, "question_0": {"Is crypto news useful to you?"}, "answer_0": {"Pure noise"}}
+	body := do("POST", "/priorities/refine", form.Encode()).Body.String()
+	if !strings.Contains(body, "Mute titles mentioning “crypto”") || !strings.Contains(body, "Pure noise, they said") {
+		t.Fatalf("expected the proposals with their reasons, got %s", body)
+	}
+
+	// Apply only the mute rule, as checked.
+	changes, _ := refine.NewRefiner(db, refineProvider{}, refine.Options{}).Propose(context.Background(), []refine.Answer{{Question: "?", Answer: "Pure noise"}})
+	value, _ := json.Marshal(changes[0])
+	if rec := do("POST", "/priorities/refine", url.Values{"step": {"apply"}, "change": {string(value)}}.Encode()); rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect after applying, got %d", rec.Code)
+	}
+	rules, _ := db.GetMuteRules()
+	if len(rules) != 1 || rules[0].Value != "crypto" {
+		t.Fatalf("expected the term muted, got %+v", rules)
+	}
+	if priorities, _ := db.GetAllPriorities(); len(priorities) != 0 {
+		t.Errorf("expected the unchecked priority not added, got %+v", priorities)
+	}
+
+	body = do("GET", "/priorities", "").Body.String()
+	if !strings.Contains(body, "<strong>crypto</strong>") || !strings.Contains(body, fmt.Sprintf(`action="/priorities/mutes/%d/delete"`, rules[0].ID)) {
+		t.Fatalf("expected the mute rule listed with an unmute button, got %s", body)
+	}
+	do("POST", fmt.Sprintf("/priorities/mutes/%d/delete", rules[0].ID), "")
+	if rules, _ := db.GetMuteRules(); len(rules) != 0 {
+		t.Errorf("expected the rule deleted, got %+v", rules)
+	}
+}
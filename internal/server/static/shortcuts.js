@@ -0,0 +1,177 @@
+// Keyboard shortcuts for briefing pages. Moving between briefings and rating
+// go through the JSON API (/api/v1/briefings/{period}/nav and
+// /api/v1/feedback/...), so rating doesn't reload the page.
+(function () {
+    "use strict";
+
+    var briefing = document.querySelector("article.briefing[data-period]");
+    if (!briefing) {
+        return;
+    }
+    var period = briefing.dataset.period;
+    var edition = briefing.dataset.edition || "morning";
+    var nav = null;
+
+    var shortcuts = [
+        ["j / k", "Next / previous storyline"],
+        ["] / [", "Next / previous source of the storyline"],
+        ["u", "Rate the storyline useful"],
+        ["s", "Rate the storyline skip"],
+        ["+ / -", "Rate the source useful / not useful"],
+        ["o", "Show or hide the storyline's sources"],
+        ["Enter", "Open the source"],
+        ["n / p", "Next / previous briefing"],
+        ["?", "Show or hide this help"]
+    ];
+
+    fetch("/api/v1/briefings/" + encodeURIComponent(period) + "/nav?edition=" + encodeURIComponent(edition),
+        {credentials: "same-origin"})
+        .then(function (resp) { return resp.ok ? resp.json() : null; })
+        .then(function (data) { nav = data; })
+        .catch(function () {});
+
+    function storylines() {
+        return Array.prototype.slice.call(document.querySelectorAll(".storyline[data-storyline-id]"));
+    }
+
+    function current() {
+        return document.querySelector(".storyline.storyline-current");
+    }
+
+    function select(el, cls) {
+        var old = document.querySelector("." + cls);
+        if (old) {
+            old.classList.remove(cls);
+        }
+        if (el) {
+            el.classList.add(cls);
+            el.scrollIntoView({block: "nearest", behavior: "smooth"});
+        }
+    }
+
+    function moveStoryline(step) {
+        var list = storylines();
+        if (list.length === 0) {
+            return;
+        }
+        var i = list.indexOf(current());
+        i = i < 0 ? (step > 0 ? 0 : list.length - 1) : Math.min(Math.max(i + step, 0), list.length - 1);
+        select(list[i], "storyline-current");
+        select(null, "article-current");
+    }
+
+    function moveArticle(step) {
+        var storyline = current();
+        if (!storyline) {
+            return;
+        }
+        var details = storyline.querySelector("details.storyline-sources");
+        if (details) {
+            details.open = true;
+        }
+        var list = Array.prototype.slice.call(storyline.querySelectorAll(".article-item[data-article-id]"));
+        if (list.length === 0) {
+            return;
+        }
+        var i = list.indexOf(storyline.querySelector(".article-current"));
+        i = i < 0 ? (step > 0 ? 0 : list.length - 1) : Math.min(Math.max(i + step, 0), list.length - 1);
+        select(list[i], "article-current");
+    }
+
+    // rate sets the rating of an item, or clears it when it already has it,
+    // like the form buttons, and marks the buttons of the item accordingly.
+    function rate(kind, el, rating) {
+        var id = el.dataset[kind + "Id"];
+        var scope = kind === "storyline" ? el.querySelector(".storyline-feedback") : el;
+        var active = scope.querySelector("button.active-useful, button.active-not-useful");
+        var next = active && active.dataset.rating === rating ? null : rating;
+        fetch("/api/v1/feedback/" + kind + "/" + id, {
+            method: "POST",
+            credentials: "same-origin",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({rating: next})
+        }).then(function (resp) {
+            if (!resp.ok) {
+                return;
+            }
+            scope.querySelectorAll("button[data-rating]").forEach(function (button) {
+                var on = button.dataset.rating === next;
+                var cls = button.dataset.positive === "true" ? "active-useful" : "active-not-useful";
+                button.classList.toggle(cls, on);
+            });
+        });
+    }
+
+    function toggleHelp() {
+        var help = document.getElementById("shortcut-help");
+        if (help) {
+            help.remove();
+            return;
+        }
+        help = document.createElement("div");
+        help.id = "shortcut-help";
+        help.className = "shortcut-help";
+        help.setAttribute("role", "dialog");
+        help.setAttribute("aria-label", "Keyboard shortcuts");
+        var list = document.createElement("dl");
+        shortcuts.forEach(function (s) {
+            var key = document.createElement("dt");
+            key.textContent = s[0];
+            var what = document.createElement("dd");
+            what.textContent = s[1];
+            list.appendChild(key);
+            list.appendChild(what);
+        });
+        var title = document.createElement("h2");
+        title.textContent = "Keyboard shortcuts";
+        help.appendChild(title);
+        help.appendChild(list);
+        document.body.appendChild(help);
+    }
+
+    function goTo(target) {
+        if (target) {
+            window.location.href = "/briefing/" + encodeURIComponent(target);
+        }
+    }
+
+    document.addEventListener("keydown", function (e) {
+        if (e.ctrlKey || e.metaKey || e.altKey || e.defaultPrevented) {
+            return;
+        }
+        var tag = e.target.tagName;
+        if (tag === "INPUT" || tag === "TEXTAREA" || tag === "SELECT" || e.target.isContentEditable) {
+            return;
+        }
+        var storyline = current();
+        var article = document.querySelector(".article-current");
+        switch (e.key) {
+        case "j": moveStoryline(1); break;
+        case "k": moveStoryline(-1); break;
+        case "]": moveArticle(1); break;
+        case "[": moveArticle(-1); break;
+        case "u": if (storyline) { rate("storyline", storyline, "useful"); } break;
+        case "s": if (storyline) { rate("storyline", storyline, "not_useful"); } break;
+        case "+": if (article) { rate("article", article, "positive"); } break;
+        case "-": if (article) { rate("article", article, "negative"); } break;
+        case "o":
+            var details = storyline && storyline.querySelector("details.storyline-sources");
+            if (details) { details.open = !details.open; }
+            break;
+        case "Enter":
+            if (!article || tag === "A" || tag === "BUTTON" || tag === "SUMMARY") { return; }
+            window.open(article.querySelector("a.article-title").href, "_blank", "noopener");
+            break;
+        case "n": goTo(nav && nav.next); break;
+        case "p": goTo(nav && nav.previous); break;
+        case "?": toggleHelp(); break;
+        case "Escape":
+            var help = document.getElementById("shortcut-help");
+            if (help) { help.remove(); }
+            return;
+        default:
+            return;
+        }
+        e.preventDefault();
+    });
+})();
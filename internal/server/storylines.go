@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// storylineArticlesJSON is the body of POST /api/v1/storylines/{id}/articles
+// and, with the storyline's articles after the change, the response of both
+// storyline article endpoints.
+type storylineArticlesJSON struct {
+	StorylineID int64   `json:"storyline_id"`
+	ArticleID   int64   `json:"article_id"`
+	ArticleIDs  []int64 `json:"article_ids"`
+	Stale       bool    `json:"stale"`
+}
+
+// handleStorylineArticlesAPI serves POST /api/v1/storylines/{id}/articles,
+// which moves the article in the body into the storyline, and DELETE
+// /api/v1/storylines/{id}/articles/{article_id}, which takes one out. Both
+// leave the narratives of the storylines they change stale.
+func (s *Server) handleStorylineArticlesAPI(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/storylines/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "articles" {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	storylineID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var articleID int64
+	method := http.MethodPost
+	if len(parts) == 3 {
+		if articleID, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+			writeJSONError(w, http.StatusNotFound, "not found")
+			return
+		}
+		method = http.MethodDelete
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	storyline, err := s.db.GetStoryline(storylineID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "could not load storyline")
+		return
+	}
+	if storyline == nil {
+		writeJSONError(w, http.StatusNotFound, "no such storyline")
+		return
+	}
+
+	var changed bool
+	if method == http.MethodPost {
+		var body storylineArticlesJSON
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		articleID = body.ArticleID
+		if article, err := s.db.GetArticleByID(articleID); err != nil || article == nil {
+			writeJSONError(w, http.StatusBadRequest, "no such article")
+			return
+		}
+		moved, err := s.db.AddStorylineArticle(storylineID, articleID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "could not move article")
+			return
+		}
+		if changed = len(moved) > 0; changed {
+			s.audit(r, database.AuditStorylineAdd, storylineID, "article "+strconv.FormatInt(articleID, 10))
+		}
+	} else {
+		if changed, err = s.db.RemoveStorylineArticle(storylineID, articleID); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "could not remove article")
+			return
+		}
+		if !changed {
+			writeJSONError(w, http.StatusNotFound, "article is not in the storyline")
+			return
+		}
+		s.audit(r, database.AuditStorylineRemove, storylineID, "article "+strconv.FormatInt(articleID, 10))
+	}
+	if changed && s.opts.OnStorylineChange != nil {
+		s.opts.OnStorylineChange(storyline.PeriodID)
+	}
+
+	ids, err := s.db.GetStorylineArticleIDs(storylineID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "could not load storyline")
+		return
+	}
+	resp := storylineArticlesJSON{StorylineID: storylineID, ArticleID: articleID, ArticleIDs: append([]int64{}, ids...)}
+	if n, _ := s.db.GetNarrativeForStoryline(storylineID); n != nil {
+		resp.Stale = n.Stale
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,312 @@
+// Package storage lays out the data directory. Everything aicrawler keeps
+// on disk lives under it, in one subdirectory per kind of data ("area"):
+//
+//	aicrawler.db       the database (with its -wal and -shm files)
+//	tapes/             recorded runs (run --record)
+//	cache/html/        fetched pages
+//	exports/           rendered briefings and other exports
+//	audio/             spoken briefings
+//	backups/           database backups (storage backup)
+//	profiles/<name>/   exports/ and audio/ of each reader profile
+//
+// A Manager hands out the paths, reports how much each area uses and
+// removes old files from the areas that can be rebuilt or are kept for a
+// while only.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Areas of the data directory.
+const (
+	Database = "db"
+	Tapes    = "tapes"
+	Cache    = "cache"
+	Exports  = "exports"
+	Audio    = "audio"
+	Backups  = "backups"
+)
+
+// Areas lists every area, in the order usage is reported.
+var Areas = []string{Database, Tapes, Cache, Exports, Audio, Backups}
+
+// ProfileAreas are the areas a reader profile has its own directory of.
+var ProfileAreas = []string{Exports, Audio}
+
+// areaDirs are the directories of the areas other than Database, relative
+// to the data directory or a profile's directory.
+var areaDirs = map[string]string{
+	Tapes:   "tapes",
+	Cache:   filepath.Join("cache", "html"),
+	Exports: "exports",
+	Audio:   "audio",
+	Backups: "backups",
+}
+
+// databaseFile is the database's file name, kept where it always was.
+const databaseFile = "aicrawler.db"
+
+// profilesDir holds the per-profile directories.
+const profilesDir = "profiles"
+
+// Manager knows where each kind of data lives in a data directory.
+type Manager struct {
+	dir string
+}
+
+// New returns the manager of the data directory dir.
+func New(dir string) *Manager {
+	return &Manager{dir: dir}
+}
+
+// Dir returns the data directory.
+func (m *Manager) Dir() string { return m.dir }
+
+// DatabasePath returns the path of the database.
+func (m *Manager) DatabasePath() string {
+	return filepath.Join(m.dir, databaseFile)
+}
+
+// Path returns the path of elem within area, e.g. Path(Tapes, "2026-01-05.json").
+// It panics on Database or an unknown area, which are programming errors.
+func (m *Manager) Path(area string, elem ...string) string {
+	return filepath.Join(append([]string{m.dir, mustAreaDir(area)}, elem...)...)
+}
+
+// ProfilePath returns the path of elem within a reader profile's directory
+// of area, which must be one of ProfileAreas.
+func (m *Manager) ProfilePath(profile, area string, elem ...string) string {
+	if !slices.Contains(ProfileAreas, area) {
+		panic(fmt.Sprintf("storage: profiles have no %s area", area))
+	}
+	return m.profilePath(ProfileDirName(profile), area, elem...)
+}
+
+func (m *Manager) profilePath(dirName, area string, elem ...string) string {
+	return filepath.Join(append([]string{m.dir, profilesDir, dirName, mustAreaDir(area)}, elem...)...)
+}
+
+// BackupPath returns the path of a database backup taken at t.
+func (m *Manager) BackupPath(t time.Time) string {
+	return m.Path(Backups, "aicrawler-"+t.UTC().Format("20060102-150405")+".db")
+}
+
+// ProfileDirName returns the directory name of a reader profile: its name
+// in lower case, with anything but letters, digits, '-' and '_' replaced by
+// '-', so a name can't reach outside the profiles directory.
+func ProfileDirName(profile string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, strings.ToLower(strings.TrimSpace(profile)))
+	if strings.Trim(name, "-") == "" {
+		return "unnamed"
+	}
+	return name
+}
+
+func mustAreaDir(area string) string {
+	dir, ok := areaDirs[area]
+	if !ok {
+		panic(fmt.Sprintf("storage: no directory for area %q", area))
+	}
+	return dir
+}
+
+// Usage is the disk use of an area, or of a profile's directory of it.
+type Usage struct {
+	Area    string
+	Profile string // directory name of the profile; empty for the shared area
+	Files   int
+	Bytes   int64
+}
+
+// Usage reports the files and bytes of every area, followed by those of
+// each profile's directories; areas without any file are left out. The
+// Database area counts the database's -wal and -shm files too.
+func (m *Manager) Usage() ([]Usage, error) {
+	var usage []Usage
+	for _, area := range Areas {
+		u := Usage{Area: area}
+		var err error
+		if area == Database {
+			for _, suffix := range []string{"", "-wal", "-shm"} {
+				info, statErr := os.Stat(m.DatabasePath() + suffix)
+				if statErr == nil {
+					u.Files++
+					u.Bytes += info.Size()
+				} else if !errors.Is(statErr, fs.ErrNotExist) {
+					err = statErr
+				}
+			}
+		} else {
+			u.Files, u.Bytes, err = walk(m.Path(area), time.Time{}, false)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("measuring %s: %w", area, err)
+		}
+		if u.Files > 0 {
+			usage = append(usage, u)
+		}
+	}
+
+	profiles, err := m.profiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		for _, area := range ProfileAreas {
+			files, bytes, err := walk(m.profilePath(profile, area), time.Time{}, false)
+			if err != nil {
+				return nil, fmt.Errorf("measuring %s of profile %s: %w", area, profile, err)
+			}
+			if files > 0 {
+				usage = append(usage, Usage{Area: area, Profile: profile, Files: files, Bytes: bytes})
+			}
+		}
+	}
+	return usage, nil
+}
+
+// Clean removes the files of area, and of every profile's directory of it,
+// last modified before before (the zero time meaning now), along with the
+// directories that leaves empty, and returns how many files and bytes it
+// removed; with dryRun it only counts them. The database can't be cleaned,
+// and cleaning Backups always keeps the newest backup.
+func (m *Manager) Clean(area string, before time.Time, dryRun bool) (files int, bytes int64, err error) {
+	if area == Database {
+		return 0, 0, fmt.Errorf("the database can't be cleaned; take a backup and remove it by hand")
+	}
+	if _, ok := areaDirs[area]; !ok {
+		return 0, 0, fmt.Errorf("unknown storage area %q (one of %s)", area, strings.Join(Areas[1:], ", "))
+	}
+	if before.IsZero() {
+		before = time.Now()
+	}
+
+	if area == Backups {
+		newest, err := m.newestBackup()
+		if err != nil {
+			return 0, 0, err
+		}
+		if !newest.IsZero() && newest.Before(before) {
+			before = newest // removes only the backups older than the newest
+		}
+	}
+
+	dirs := []string{m.Path(area)}
+	if slices.Contains(ProfileAreas, area) {
+		profiles, err := m.profiles()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, profile := range profiles {
+			dirs = append(dirs, m.profilePath(profile, area))
+		}
+	}
+	for _, dir := range dirs {
+		f, b, err := walk(dir, before, !dryRun)
+		files += f
+		bytes += b
+		if err != nil {
+			return files, bytes, fmt.Errorf("cleaning %s: %w", dir, err)
+		}
+	}
+	return files, bytes, nil
+}
+
+// newestBackup returns the modification time of the newest backup, or the
+// zero time without any.
+func (m *Manager) newestBackup() (time.Time, error) {
+	entries, err := os.ReadDir(m.Path(Backups))
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	var newest time.Time
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// profiles returns the directory names of the profiles with a directory.
+func (m *Manager) profiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.dir, profilesDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing profile directories: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// walk counts the regular files under dir, only those modified before
+// before unless it is zero, and with remove deletes them and the
+// directories below dir left empty. A missing dir has no files.
+func walk(dir string, before time.Time, remove bool) (files int, bytes int64, err error) {
+	var emptied []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir {
+				emptied = append(emptied, path)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !before.IsZero() && !info.ModTime().Before(before) {
+			return nil
+		}
+		if remove {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		files++
+		bytes += info.Size()
+		return nil
+	})
+	if remove {
+		// Deepest first, so a parent is empty once its children are gone;
+		// a directory that still has files isn't removed.
+		for _, d := range slices.Backward(emptied) {
+			os.Remove(d)
+		}
+	}
+	return files, bytes, err
+}
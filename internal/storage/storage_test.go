@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	m := New("/data")
+	tests := []struct {
+		got, want string
+	}{
+		{m.DatabasePath(), "/data/aicrawler.db"},
+		{m.Path(Tapes, "2026-01-05.json"), "/data/tapes/2026-01-05.json"},
+		{m.Path(Cache), "/data/cache/html"},
+		{m.ProfilePath("QA Team", Audio, "2026-01-05.mp3"), "/data/profiles/qa-team/audio/2026-01-05.mp3"},
+		{m.ProfilePath("../..", Exports), "/data/profiles/unnamed/exports"},
+		{m.BackupPath(time.Date(2026, 1, 5, 6, 7, 8, 0, time.UTC)), "/data/backups/aicrawler-20260105-060708.db"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	m := New(t.TempDir())
+	writeFile(t, m.DatabasePath(), 100, 0)
+	writeFile(t, m.DatabasePath()+"-wal", 20, 0)
+	writeFile(t, m.Path(Tapes, "a.json"), 10, 0)
+	writeFile(t, m.Path(Cache, "ab", "page.html"), 5, 0)
+	writeFile(t, m.Path(Cache, "cd", "page.html"), 7, 0)
+	writeFile(t, m.ProfilePath("qa", Exports, "brief.md"), 3, 0)
+
+	usage, err := m.Usage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Usage{
+		{Area: Database, Files: 2, Bytes: 120},
+		{Area: Tapes, Files: 1, Bytes: 10},
+		{Area: Cache, Files: 2, Bytes: 12},
+		{Area: Exports, Profile: "qa", Files: 1, Bytes: 3},
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("got %+v, want %+v", usage, want)
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("usage[%d] = %+v, want %+v", i, usage[i], want[i])
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	m := New(t.TempDir())
+	day := 24 * time.Hour
+	writeFile(t, m.Path(Cache, "ab", "old.html"), 5, 40*day)
+	writeFile(t, m.Path(Cache, "cd", "new.html"), 7, day)
+	writeFile(t, m.Path(Exports, "old.md"), 2, 40*day)
+	writeFile(t, m.ProfilePath("qa", Exports, "old.md"), 3, 40*day)
+
+	cutoff := time.Now().Add(-30 * day)
+	files, bytes, err := m.Clean(Cache, cutoff, true)
+	if err != nil || files != 1 || bytes != 5 {
+		t.Fatalf("dry run = %d files, %d bytes, %v; want 1 file, 5 bytes", files, bytes, err)
+	}
+	if _, err := os.Stat(m.Path(Cache, "ab", "old.html")); err != nil {
+		t.Errorf("dry run removed a file: %v", err)
+	}
+
+	if files, bytes, err = m.Clean(Cache, cutoff, false); err != nil || files != 1 || bytes != 5 {
+		t.Fatalf("clean = %d files, %d bytes, %v; want 1 file, 5 bytes", files, bytes, err)
+	}
+	if _, err := os.Stat(m.Path(Cache, "ab")); !os.IsNotExist(err) {
+		t.Errorf("emptied directory left behind: %v", err)
+	}
+	if _, err := os.Stat(m.Path(Cache, "cd", "new.html")); err != nil {
+		t.Errorf("recent file removed: %v", err)
+	}
+
+	// A profile's directory of an area is cleaned with it.
+	if files, _, err = m.Clean(Exports, cutoff, false); err != nil || files != 2 {
+		t.Fatalf("clean exports = %d files, %v; want 2", files, err)
+	}
+
+	if _, _, err := m.Clean(Database, cutoff, false); err == nil {
+		t.Error("expected an error cleaning the database")
+	}
+	if _, _, err := m.Clean("videos", cutoff, false); err == nil {
+		t.Error("expected an error cleaning an unknown area")
+	}
+}
+
+func TestCleanKeepsNewestBackup(t *testing.T) {
+	m := New(t.TempDir())
+	day := 24 * time.Hour
+	writeFile(t, m.Path(Backups, "older.db"), 1, 60*day)
+	writeFile(t, m.Path(Backups, "newest.db"), 1, 50*day)
+
+	files, _, err := m.Clean(Backups, time.Time{}, false)
+	if err != nil || files != 1 {
+		t.Fatalf("clean = %d files, %v; want 1", files, err)
+	}
+	if _, err := os.Stat(m.Path(Backups, "newest.db")); err != nil {
+		t.Errorf("newest backup removed: %v", err)
+	}
+}
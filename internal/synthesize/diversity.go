@@ -0,0 +1,77 @@
+package synthesize
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+const (
+	// maxPerSource caps how many articles from one source a narrative is
+	// written from, so one outlet's repeated coverage doesn't crowd out others.
+	maxPerSource = 2
+	// maxNarrativeArticles caps how many articles a narrative is written
+	// from. The rest are listed as additional coverage.
+	maxNarrativeArticles = 8
+)
+
+// representativeArticles picks a diverse subset of a storyline's articles to
+// write its narrative from. Articles are ranked by score, then sources take
+// turns: each source's best article first, then each one's second best, up
+// to maxPerSource per source and maxNarrativeArticles in total. The articles
+// left over are returned as additional coverage, in rank order.
+func representativeArticles(articles []database.Article, score func(database.Article) int) (selected, additional []database.Article) {
+	ranked := append([]database.Article(nil), articles...)
+	scores := make(map[int64]int, len(ranked))
+	for _, a := range ranked {
+		scores[a.ID] = score(a)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] > scores[ranked[j].ID]
+	})
+
+	// Group by source, keeping sources in the order of their best article
+	var order []string
+	bySource := make(map[string][]database.Article)
+	for _, a := range ranked {
+		key := sourceKey(a)
+		if _, ok := bySource[key]; !ok {
+			order = append(order, key)
+		}
+		bySource[key] = append(bySource[key], a)
+	}
+
+	picked := make(map[int64]bool)
+	for round := 0; round < maxPerSource; round++ {
+		for _, key := range order {
+			if len(selected) == maxNarrativeArticles {
+				break
+			}
+			if round < len(bySource[key]) {
+				a := bySource[key][round]
+				selected = append(selected, a)
+				picked[a.ID] = true
+			}
+		}
+	}
+	for _, a := range ranked {
+		if !picked[a.ID] {
+			additional = append(additional, a)
+		}
+	}
+	return selected, additional
+}
+
+// sourceKey identifies an article's outlet: its source name, or the host of
+// its URL when it has none.
+func sourceKey(a database.Article) string {
+	if a.Source != nil && *a.Source != "" {
+		return strings.ToLower(*a.Source)
+	}
+	if u, err := url.Parse(a.URL); err == nil {
+		return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	return a.URL
+}
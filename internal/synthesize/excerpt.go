@@ -0,0 +1,141 @@
+package synthesize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+// excerptLength caps the text one excerpt lookup returns.
+const excerptLength = 1200
+
+// excerptTool lets the LLM look up passages in the full text of the articles
+// a narrative is written from, so it can quote them instead of guessing from
+// the previews. Articles are named by their number in the prompt, which also
+// keeps lookups to this storyline.
+func excerptTool(articles []database.Article) llm.Tool {
+	return llm.Tool{
+		Name: "fetch_article_excerpt",
+		Description: "Returns the passages of an article's full text that best match query, to quote or check details. " +
+			"article_id is the number of the article in brackets above.",
+		Parameters: llm.Object(map[string]any{"article_id": llm.Integer(), "query": llm.String()}),
+		Call: func(_ context.Context, args map[string]any) (string, error) {
+			n, ok := articleNumber(args["article_id"])
+			if !ok || n < 1 || n > len(articles) {
+				return "", fmt.Errorf("article_id must be a number from 1 to %d", len(articles))
+			}
+			a := articles[n-1]
+			if a.Content == nil || strings.TrimSpace(*a.Content) == "" {
+				return "No full text was fetched for this article.", nil
+			}
+			query, _ := args["query"].(string)
+			return excerpt(*a.Content, query, excerptLength), nil
+		},
+	}
+}
+
+// articleNumber reads an article_id argument: a JSON number, or a string
+// such as "2" or "[2]".
+func articleNumber(v any) (int, bool) {
+	switch v := v.(type) {
+	case float64:
+		return int(v), v == float64(int(v))
+	case string:
+		n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(v), "[]"))
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// excerpt returns the sentences of text that share the most words with
+// query, in text order and up to limit bytes, with " … " between sentences
+// that aren't adjacent. Without a match it returns the start of text.
+func excerpt(text, query string, limit int) string {
+	sentences := splitSentences(text)
+	terms := map[string]bool{}
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		if w = strings.Trim(w, ".,;:!?\"'()"); len(w) > 2 {
+			terms[w] = true
+		}
+	}
+
+	type scored struct{ index, score int }
+	var ranked []scored
+	for i, s := range sentences {
+		score := 0
+		lower := strings.ToLower(s)
+		for t := range terms {
+			if strings.Contains(lower, t) {
+				score++
+			}
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+	if len(ranked) == 0 {
+		return truncate(strings.Join(sentences, " "), limit)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	var picked []int
+	length := 0
+	for _, r := range ranked {
+		if length > 0 && length+len(sentences[r.index]) > limit {
+			continue
+		}
+		picked = append(picked, r.index)
+		length += len(sentences[r.index])
+	}
+	sort.Ints(picked)
+
+	var b strings.Builder
+	for i, idx := range picked {
+		if i > 0 {
+			if idx == picked[i-1]+1 {
+				b.WriteString(" ")
+			} else {
+				b.WriteString(" … ")
+			}
+		}
+		b.WriteString(sentences[idx])
+	}
+	return truncate(b.String(), limit)
+}
+
+// splitSentences splits text at line breaks and sentence ends.
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		start := 0
+		for i := 0; i < len(line); i++ {
+			if (line[i] == '.' || line[i] == '!' || line[i] == '?') && (i+1 == len(line) || line[i+1] == ' ') {
+				if s := strings.TrimSpace(line[start : i+1]); s != "" {
+					sentences = append(sentences, s)
+				}
+				start = i + 1
+			}
+		}
+		if s := strings.TrimSpace(line[start:]); s != "" {
+			sentences = append(sentences, s)
+		}
+	}
+	return sentences
+}
+
+// truncate cuts s to at most limit bytes at a word boundary.
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := s[:limit]
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + " …"
+}
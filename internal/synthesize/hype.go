@@ -0,0 +1,85 @@
+package synthesize
+
+import (
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// vendorDomains are hosts where AI companies announce their own products.
+// Coverage from them is first-party and usually promotional.
+var vendorDomains = []string{
+	"openai.com", "anthropic.com", "blog.google", "deepmind.google", "ai.google",
+	"ai.meta.com", "microsoft.com", "aws.amazon.com", "nvidia.com", "apple.com",
+	"huggingface.co", "mistral.ai", "cohere.com", "x.ai", "github.blog",
+}
+
+// marketingTerms are phrases typical of press releases rather than reporting.
+var marketingTerms = []string{
+	"revolutionary", "game-changer", "game changer", "groundbreaking", "cutting-edge",
+	"unprecedented", "seamless", "supercharge", "unlock the power", "next-generation",
+	"best-in-class", "world-class", "industry-leading", "state-of-the-art", "empower",
+	"effortless", "transformative", "disrupt", "10x", "magical", "unleash",
+}
+
+// marketingHits is how many distinct marketing terms make an article count as
+// promotional in tone.
+const marketingHits = 2
+
+// hypeScore estimates how promotional a storyline's coverage is, from 0
+// (independent, substantive) to 1 (vendor announcements in marketing
+// language). Vendor sources and promotional tone are weighted equally.
+func hypeScore(articles []database.Article) float64 {
+	if len(articles) == 0 {
+		return 0
+	}
+
+	var vendor, promotional int
+	for _, a := range articles {
+		if isVendorURL(a.URL) {
+			vendor++
+		}
+		text := a.Title
+		if a.Content != nil {
+			content := *a.Content
+			if len(content) > 4000 {
+				content = content[:4000]
+			}
+			text += " " + content
+		}
+		if countMarketingTerms(text) >= marketingHits {
+			promotional++
+		}
+	}
+
+	n := float64(len(articles))
+	score := 0.5*float64(vendor)/n + 0.5*float64(promotional)/n
+	return math.Round(score*100) / 100
+}
+
+func isVendorURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for _, d := range vendorDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func countMarketingTerms(text string) int {
+	text = strings.ToLower(text)
+	n := 0
+	for _, term := range marketingTerms {
+		if strings.Contains(text, term) {
+			n++
+		}
+	}
+	return n
+}
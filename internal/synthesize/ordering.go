@@ -0,0 +1,62 @@
+package synthesize
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+)
+
+// storyOrder sorts the articles a narrative is written from into the order
+// the story unfolded and decides each one's role, keyed by article ID. The
+// primary source is the earliest article; when several appeared the same
+// day, a vendor or academic source, announcing its own work or paper, comes
+// before the coverage of it. Undated articles go last, by collection time.
+func storyOrder(articles []database.Article, sourceTypes map[string]string) ([]database.Article, map[int64]string) {
+	originator := func(a database.Article) int {
+		if a.Source == nil {
+			return 1
+		}
+		switch sourceTypes[*a.Source] {
+		case database.SourceVendor, database.SourceAcademic:
+			return 0
+		}
+		return 1
+	}
+	day := func(a database.Article) string {
+		if a.PublishedDate == nil || *a.PublishedDate == "" {
+			return "9999-99-99"
+		}
+		return *a.PublishedDate
+	}
+	collected := func(a database.Article) string {
+		if a.CollectedAt == nil {
+			return ""
+		}
+		return *a.CollectedAt
+	}
+
+	ordered := slices.Clone(articles)
+	slices.SortStableFunc(ordered, func(a, b database.Article) int {
+		return cmp.Or(
+			cmp.Compare(day(a), day(b)),
+			cmp.Compare(originator(a), originator(b)),
+			cmp.Compare(collected(a), collected(b)),
+		)
+	})
+
+	roles := make(map[int64]string, len(ordered))
+	for i, a := range ordered {
+		roles[a.ID] = database.RoleFollowUp
+		if i == 0 {
+			roles[a.ID] = database.RolePrimary
+		}
+	}
+	return ordered, roles
+}
+
+// roleLabels describe the roles in the article list of a prompt.
+var roleLabels = map[string]string{
+	database.RolePrimary:  "primary source",
+	database.RoleFollowUp: "follow-up coverage",
+}
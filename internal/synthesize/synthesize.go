@@ -1,22 +1,29 @@
 package synthesize
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
 	"github.com/TobiSchelling/AICrawler/internal/llm"
+	"github.com/TobiSchelling/AICrawler/internal/memory"
 )
 
 const brieflyNotedLabel = "Briefly Noted"
 
-const synthesisPrompt = `You are writing one section of a daily AI news briefing for software practitioners.
+// progressInterval is how often a streamed narrative logs its progress.
+const progressInterval = 15 * time.Second
+
+const synthesisPrompt = `You are writing one section of a daily AI news briefing for %s.
 
 This section covers a storyline about: %s
 
-Write a cohesive 2-3 paragraph narrative that weaves these articles together. Write as if you're a well-informed colleague explaining what happened recently. Be specific about tools, techniques, and outcomes. Avoid marketing language.
+%s Write as if you're a well-informed colleague explaining what happened recently. Be specific about tools, techniques, and outcomes. Avoid marketing language.%s%s
 
 Articles in this storyline:
 %s
@@ -24,34 +31,111 @@ Articles in this storyline:
 Respond with ONLY this JSON:
 {
     "title": "A compelling 5-8 word section title",
-    "narrative": "Your 2-3 paragraph narrative here. Use markdown for emphasis.",
+    "narrative": "Your narrative here. Use markdown for emphasis.",
     "source_references": [
-        {"title": "Article Title", "url": "https://...", "contribution": "What this article added to the story"}
+        {"title": "Article Title", "url": "https://...", "contribution": "What this article added to the story", "role": "primary or follow_up"}
     ]
 }`
 
+// synthesisSchema describes the response synthesisPrompt asks for.
+var synthesisSchema = llm.Schema{Name: "storyline_narrative", Definition: llm.Object(map[string]any{
+	"title":     llm.String(),
+	"narrative": llm.String(),
+	"source_references": llm.Array(llm.Object(map[string]any{
+		"title":        llm.String(),
+		"url":          llm.String(),
+		"contribution": llm.String(),
+		"role":         llm.String(database.RolePrimary, database.RoleFollowUp),
+	})),
+})}
+
+// Length instructions of synthesisPrompt: a storyline weaves its articles
+// together, a spotlighted article gets a shorter section of its own.
+const (
+	weaveInstruction     = "Write a cohesive 2-3 paragraph narrative that weaves these articles together. They are listed in the order they were published, each marked as the primary source or as follow-up coverage: tell the story in that order, from what was announced to how others analyzed, tested or benchmarked it."
+	spotlightInstruction = "Write a focused 1-2 paragraph narrative about this one article: what it reports and why it matters in practice."
+)
+
+// recallInstruction introduces the remembered topics a storyline matches.
+const recallInstruction = "\n\nEarlier briefings covered related topics. Where this storyline continues one, such as a repeated incident or a follow-up to an earlier release, say so in a sentence without retelling it:\n"
+
+// maxRecalled is how many remembered topics a narrative prompt gets.
+const maxRecalled = 2
+
+const retitlePrompt = `Another section of today's AI news briefing is already titled "%s".
+
+Suggest a different, more specific 5-8 word title for this section about: %s
+
+Section narrative:
+%s
+
+Respond with ONLY the new title, without quotes.%s`
+
 // Result holds the results of a synthesis run.
 type Result struct {
 	NarrativesCreated int
+	Refreshed         int // of NarrativesCreated, stale ones rewritten
 	Errors            int
+
+	// LastErr is the error of the last storyline that failed, wrapping
+	// llm.ErrNoProvider when there was no provider to ask.
+	LastErr error
+}
+
+// Options controls optional synthesis behaviour.
+type Options struct {
+	// Language, when set, is the language narratives are written in.
+	// Storyline labels and Briefly Noted bullets, which come from English
+	// titles and key points, are translated into it.
+	Language string
+
+	// MaxTokens limits the response to each storyline's narrative prompt;
+	// 0 means defaultMaxTokens.
+	MaxTokens int
+
+	// Audience is who narratives are written for; empty means
+	// llm.DefaultAudience.
+	Audience string
+
+	// Pool synthesizes as many storylines at once as it has slots; nil
+	// synthesizes them one by one.
+	Pool *llm.Pool
+
+	// ExcerptLookups is how many rounds of fetch_article_excerpt calls the
+	// LLM may make before writing a narrative; 0 allows none.
+	ExcerptLookups int
+
+	// RecallDays is how far back topic memories matching a storyline are
+	// handed to its narrative prompt; 0 recalls none.
+	RecallDays int
 }
 
+// defaultMaxTokens fits a title and a narrative of a few paragraphs.
+const defaultMaxTokens = 1024
+
 // Synthesizer synthesizes narratives for each storyline using LLM.
 type Synthesizer struct {
 	db       *database.DB
 	provider llm.Provider
+	opts     Options
+
+	// titles is held from picking a section title until the narrative is
+	// stored, so storylines synthesized in parallel can't take the same one.
+	titles sync.Mutex
 }
 
 // NewSynthesizer creates a new storyline synthesizer.
-func NewSynthesizer(db *database.DB, provider llm.Provider) *Synthesizer {
-	return &Synthesizer{db: db, provider: provider}
+func NewSynthesizer(db *database.DB, provider llm.Provider, opts Options) *Synthesizer {
+	return &Synthesizer{db: db, provider: provider, opts: opts}
 }
 
-// SynthesizePeriod synthesizes narratives for all storylines in a period.
+// SynthesizePeriod synthesizes narratives for all storylines in a period
+// that have none or a stale one. A stale narrative is kept when rewriting it
+// fails.
 func (s *Synthesizer) SynthesizePeriod(ctx context.Context, periodID string) *Result {
 	if s.provider == nil {
 		log.Println("No LLM provider available for synthesis")
-		return &Result{Errors: 1}
+		return &Result{Errors: 1, LastErr: llm.ErrNoProvider}
 	}
 
 	storylines, err := s.db.GetStorylinesForPeriod(periodID)
@@ -64,43 +148,93 @@ func (s *Synthesizer) SynthesizePeriod(ctx context.Context, periodID string) *Re
 		return &Result{}
 	}
 
+	var memories []database.TopicMemory
+	if s.opts.RecallDays > 0 {
+		if memories, err = s.db.GetTopicMemories(periodID, memory.Since(periodID, s.opts.RecallDays)); err != nil {
+			log.Printf("Error getting topic memories: %v", err)
+		}
+	}
+
 	r := &Result{}
-	for _, storyline := range storylines {
+	var mu sync.Mutex // guards r
+	labels := s.translateLabels(ctx, storylines)
+	s.opts.Pool.Run(ctx, len(storylines), func(i int) {
+		storyline := storylines[i]
 		existing, _ := s.db.GetNarrativeForStoryline(storyline.ID)
-		if existing != nil {
+		if existing != nil && !existing.Stale {
+			mu.Lock()
 			r.NarrativesCreated++
-			continue
+			mu.Unlock()
+			return
+		}
+		if label, ok := labels[storyline.ID]; ok {
+			storyline.Label = label
 		}
 
-		articles, _ := s.db.GetStorylineArticles(storyline.ID)
+		articles, _ := s.db.GetStorylineArticles(storyline.ID, "")
 		if len(articles) == 0 {
-			continue
+			// Every article was moved out of the storyline.
+			if existing != nil {
+				s.db.DeleteStorylineNarrative(storyline.ID) //nolint: errcheck
+			}
+			return
 		}
 
 		var synthErr error
+		storylineCtx := llm.WithStoryline(ctx, storyline.ID)
 		if storyline.Label == brieflyNotedLabel {
-			synthErr = s.synthesizeBrieflyNoted(storyline, articles, periodID)
+			synthErr = s.synthesizeBrieflyNoted(storylineCtx, storyline, articles, periodID)
 		} else {
-			synthErr = s.synthesizeStoryline(ctx, storyline, articles, periodID)
+			synthErr = s.synthesizeStoryline(storylineCtx, storyline, articles, memories, periodID)
 		}
 
-		if synthErr != nil {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case synthErr != nil:
 			log.Printf("Error synthesizing storyline %d: %v", storyline.ID, synthErr)
 			r.Errors++
-		} else {
+			r.LastErr = synthErr
+		case existing != nil:
+			r.Refreshed++
+			r.NarrativesCreated++
+		default:
 			r.NarrativesCreated++
 		}
-	}
+	})
 
-	log.Printf("Synthesis complete: %d narratives created, %d errors", r.NarrativesCreated, r.Errors)
+	log.Printf("Synthesis complete: %d narratives created (%d stale ones rewritten), %d errors", r.NarrativesCreated, r.Refreshed, r.Errors)
 	return r
 }
 
-func (s *Synthesizer) synthesizeStoryline(ctx context.Context, storyline database.Storyline, articles []database.Article, periodID string) error {
-	articlesText := s.formatArticles(articles)
-	prompt := fmt.Sprintf(synthesisPrompt, storyline.Label, articlesText)
+func (s *Synthesizer) synthesizeStoryline(ctx context.Context, storyline database.Storyline, articles []database.Article, memories []database.TopicMemory, periodID string) error {
+	selected, additional := representativeArticles(articles, s.articleScore)
+	if len(additional) > 0 {
+		log.Printf("Writing %q from %d of %d articles", storyline.Label, len(selected), len(articles))
+	}
+	sourceTypes, _ := s.db.GetSourceTypes()
+	selected, roles := storyOrder(selected, sourceTypes)
+	articlesText := s.formatArticles(selected, roles)
+	instruction := weaveInstruction
+	if len(articles) == 1 {
+		instruction = spotlightInstruction
+	}
+	var recall string
+	if len(memories) > 0 {
+		text := []string{storyline.Label}
+		for _, a := range articles {
+			text = append(text, a.Title)
+		}
+		if relevant := memory.Relevant(memories, strings.Join(text, "\n"), maxRecalled); len(relevant) > 0 {
+			recall = recallInstruction + memory.Format(relevant)
+		}
+	}
+	prompt := fmt.Sprintf(synthesisPrompt, cmp.Or(s.opts.Audience, llm.DefaultAudience), storyline.Label, instruction,
+		llm.LanguageInstruction(s.opts.Language), recall, articlesText)
 
-	responseText, err := s.provider.Generate(ctx, prompt, 1024)
+	tools := []llm.Tool{excerptTool(selected)}
+	responseText, err := llm.GenerateWithTools(llm.WithSchema(ctx, synthesisSchema), s.provider, prompt,
+		cmp.Or(s.opts.MaxTokens, defaultMaxTokens), tools, s.opts.ExcerptLookups, progressLogger(storyline.Label))
 	if err != nil {
 		return err
 	}
@@ -114,34 +248,141 @@ func (s *Synthesizer) synthesizeStoryline(ctx context.Context, storyline databas
 		title = getStr(parsed, "title", storyline.Label)
 		narrative = getStr(parsed, "narrative", "")
 		refs = parseSourceRefs(parsed)
+		// Keep the LLM's role when it gave a valid one.
+		byURL := make(map[string]string, len(selected))
+		for _, a := range selected {
+			byURL[a.URL] = roles[a.ID]
+		}
+		for i := range refs {
+			if _, ok := roleLabels[refs[i].Role]; !ok {
+				refs[i].Role = byURL[refs[i].URL]
+			}
+		}
 	} else {
 		title = storyline.Label
 		narrative = strings.TrimSpace(responseText)
-		for _, a := range articles {
-			refs = append(refs, database.SourceReference{Title: a.Title, URL: a.URL})
+		for _, a := range selected {
+			refs = append(refs, database.SourceReference{Title: a.Title, URL: a.URL, Role: roles[a.ID]})
 		}
 	}
+	for _, a := range additional {
+		refs = append(refs, database.SourceReference{Title: a.Title, URL: a.URL, Additional: true})
+	}
 
-	_, err = s.db.InsertStorylineNarrative(storyline.ID, periodID, title, narrative, refs)
-	return err
+	s.titles.Lock()
+	title = s.distinctTitle(ctx, storyline.ID, title, storyline.Label, narrative, periodID)
+	id, err := s.db.InsertStorylineNarrative(storyline.ID, periodID, title, narrative, refs)
+	s.titles.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.db.SetNarrativeHypeScore(id, hypeScore(articles))
 }
 
-func (s *Synthesizer) synthesizeBrieflyNoted(storyline database.Storyline, articles []database.Article, periodID string) error {
-	var bullets []string
-	var refs []database.SourceReference
+// distinctTitle makes sure no two sections of a briefing share a title.
+// When the proposed title is taken, the LLM is asked once for an alternative;
+// if that fails too, a numeric disambiguator is appended. The title of the
+// stale narrative being replaced doesn't count as taken.
+func (s *Synthesizer) distinctTitle(ctx context.Context, storylineID int64, title, label, narrative, periodID string) string {
+	taken, err := s.db.GetNarrativeTitlesForPeriod(periodID)
+	if err != nil {
+		return title
+	}
+	if old, _ := s.db.GetNarrativeForStoryline(storylineID); old != nil {
+		delete(taken, database.FoldLabel(old.Title))
+	}
+	// Compose treats this title specially, so a storyline must never claim it.
+	taken[database.FoldLabel(brieflyNotedLabel)] = true
+	if !taken[database.FoldLabel(title)] {
+		return title
+	}
+
+	excerpt := narrative
+	if runes := []rune(excerpt); len(runes) > 600 {
+		excerpt = string(runes[:600]) + "..."
+	}
+	prompt := fmt.Sprintf(retitlePrompt, title, label, excerpt, llm.LanguageInstruction(s.opts.Language))
+	responseText, err := s.provider.Generate(ctx, prompt, 64)
+	if err == nil {
+		alt := strings.Trim(strings.TrimSpace(responseText), `"'`)
+		if alt != "" && !strings.Contains(alt, "\n") && !taken[database.FoldLabel(alt)] {
+			log.Printf("Retitled duplicate section %q as %q", title, alt)
+			return alt
+		}
+	}
+
+	return database.UniqueLabel(title, taken)
+}
+
+// translateLabels translates the labels of storylines still needing a
+// narrative into the configured language, keyed by storyline ID. Labels are
+// built from English title words, and they become section titles whenever
+// the LLM response can't be parsed. Without a language, or when translation
+// fails, it returns nil and the English labels are kept.
+func (s *Synthesizer) translateLabels(ctx context.Context, storylines []database.Storyline) map[int64]string {
+	if s.opts.Language == "" {
+		return nil
+	}
+	var pending []database.Storyline
+	var texts []string
+	for _, st := range storylines {
+		if st.Label == brieflyNotedLabel {
+			continue
+		}
+		if existing, _ := s.db.GetNarrativeForStoryline(st.ID); existing != nil && !existing.Stale {
+			continue
+		}
+		pending = append(pending, st)
+		texts = append(texts, st.Label)
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	translated, err := llm.Translate(ctx, s.provider, s.opts.Language, texts)
+	if err != nil {
+		log.Printf("Error translating storyline labels: %v", err)
+		return nil
+	}
+	labels := make(map[int64]string, len(pending))
+	for i, st := range pending {
+		labels[st.ID] = translated[i]
+	}
+	return labels
+}
 
+func (s *Synthesizer) synthesizeBrieflyNoted(ctx context.Context, storyline database.Storyline, articles []database.Article, periodID string) error {
+	var titles, points, texts []string
 	for _, article := range articles {
 		triage, _ := s.db.GetTriage(article.ID)
 		point := article.Title
 		if triage != nil && len(triage.KeyPoints) > 0 {
 			point = triage.KeyPoints[0]
 		}
+		titles = append(titles, article.Title)
+		points = append(points, point)
+		texts = append(texts, article.Title, point)
+	}
+
+	// Titles and key points are English; translate them pairwise in one call
+	if s.opts.Language != "" {
+		if translated, err := llm.Translate(ctx, s.provider, s.opts.Language, texts); err != nil {
+			log.Printf("Error translating Briefly Noted: %v", err)
+		} else {
+			for i := range titles {
+				titles[i], points[i] = translated[2*i], translated[2*i+1]
+			}
+		}
+	}
 
+	var bullets []string
+	var refs []database.SourceReference
+	for i, article := range articles {
 		source := "Unknown"
 		if article.Source != nil {
 			source = *article.Source
 		}
-		bullets = append(bullets, fmt.Sprintf("- **%s** (%s): %s", article.Title, source, point))
+		bullets = append(bullets, fmt.Sprintf("- **%s** (%s): %s", titles[i], source, points[i]))
 		refs = append(refs, database.SourceReference{Title: article.Title, URL: article.URL})
 	}
 
@@ -150,7 +391,22 @@ func (s *Synthesizer) synthesizeBrieflyNoted(storyline database.Storyline, artic
 	return err
 }
 
-func (s *Synthesizer) formatArticles(articles []database.Article) string {
+// articleScore ranks an article for narrative selection: triaged practical
+// value first, then whether its full text was fetched.
+func (s *Synthesizer) articleScore(a database.Article) int {
+	score := 0
+	if triage, _ := s.db.GetTriage(a.ID); triage != nil {
+		score = 2 * triage.PracticalScore
+	}
+	if a.Content != nil && len(*a.Content) > 500 {
+		score++
+	}
+	return score
+}
+
+// formatArticles lists articles for the synthesis prompt with their
+// publication date and role, numbered for fetch_article_excerpt.
+func (s *Synthesizer) formatArticles(articles []database.Article, roles map[int64]string) string {
 	var parts []string
 	for i, article := range articles {
 		triage, _ := s.db.GetTriage(article.ID)
@@ -172,9 +428,16 @@ func (s *Synthesizer) formatArticles(articles []database.Article) string {
 		if article.Source != nil {
 			source = *article.Source
 		}
+		published := "undated"
+		if article.PublishedDate != nil && *article.PublishedDate != "" {
+			published = *article.PublishedDate
+		}
+		if label := roleLabels[roles[article.ID]]; label != "" {
+			published += " (" + label + ")"
+		}
 
-		parts = append(parts, fmt.Sprintf("[%d] %s\n  Source: %s\n  URL: %s%s%s",
-			i+1, article.Title, source, article.URL, keyPoints, contentPreview))
+		parts = append(parts, fmt.Sprintf("[%d] %s\n  Source: %s\n  Published: %s\n  URL: %s%s%s",
+			i+1, article.Title, source, published, article.URL, keyPoints, contentPreview))
 	}
 	return strings.Join(parts, "\n\n")
 }
@@ -208,8 +471,23 @@ func parseSourceRefs(m map[string]any) []database.SourceReference {
 			Title:        getStr(obj, "title", ""),
 			URL:          getStr(obj, "url", ""),
 			Contribution: getStr(obj, "contribution", ""),
+			Role:         getStr(obj, "role", ""),
 		}
 		refs = append(refs, ref)
 	}
 	return refs
 }
+
+// progressLogger returns a chunk callback that logs how much of a storyline's
+// narrative has been generated, at most once per progressInterval.
+func progressLogger(label string) func(string) {
+	var received int
+	last := time.Now()
+	return func(chunk string) {
+		received += len(chunk)
+		if time.Since(last) >= progressInterval {
+			log.Printf("Synthesizing %q: %d characters so far", label, received)
+			last = time.Now()
+		}
+	}
+}
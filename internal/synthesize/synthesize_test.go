@@ -3,11 +3,14 @@ package synthesize
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
 )
 
 type mockProvider struct {
@@ -49,7 +52,7 @@ func TestSynthesizeStoryline(t *testing.T) {
 		},
 	})
 
-	synth := NewSynthesizer(db, &mockProvider{response: string(resp)})
+	synth := NewSynthesizer(db, &mockProvider{response: string(resp)}, Options{})
 	result := synth.SynthesizePeriod(context.Background(), "2026-02-06")
 
 	if result.NarrativesCreated != 1 {
@@ -69,7 +72,7 @@ func TestSynthesizeBrieflyNoted(t *testing.T) {
 	sid, _ := db.InsertStoryline("2026-02-06", brieflyNotedLabel, []int64{a1})
 
 	mock := &mockProvider{} // Should NOT be called for briefly noted
-	synth := NewSynthesizer(db, mock)
+	synth := NewSynthesizer(db, mock, Options{})
 	result := synth.SynthesizePeriod(context.Background(), "2026-02-06")
 
 	if result.NarrativesCreated != 1 {
@@ -95,10 +98,467 @@ func TestSynthesizeSkipsExisting(t *testing.T) {
 	db.InsertStorylineNarrative(sid, "2026-02-06", "Existing", "Already done", nil)
 
 	mock := &mockProvider{}
-	synth := NewSynthesizer(db, mock)
+	synth := NewSynthesizer(db, mock, Options{})
 	result := synth.SynthesizePeriod(context.Background(), "2026-02-06")
 
 	if result.NarrativesCreated != 1 {
 		t.Errorf("expected 1 (existing counted), got %d", result.NarrativesCreated)
 	}
 }
+
+func TestSynthesizeRewritesStaleNarrative(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "A", nil, nil, nil, ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents", []int64{a1})
+	s2, _ := db.InsertStoryline("2026-02-06", "Evals", []int64{a2})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "Agents Take Over CI", "Written without content", nil)
+	db.InsertStorylineNarrative(s2, "2026-02-06", "Evals", "Emptied", nil)
+	db.UpdateArticleContent(a1, ptr("Late content"))
+	db.RemoveStorylineArticle(s2, a2)
+
+	// The rewrite may keep its own title, which isn't a duplicate.
+	resp, _ := json.Marshal(map[string]any{"title": "Agents Take Over CI", "narrative": "With the full text"})
+	mock := &seqProvider{responses: []string{string(resp)}}
+	result := NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	if result.Refreshed != 1 || result.Errors != 0 {
+		t.Errorf("expected one stale narrative rewritten, got %+v", result)
+	}
+	narrative, _ := db.GetNarrativeForStoryline(s1)
+	if narrative == nil || narrative.Stale || narrative.NarrativeText != "With the full text" || narrative.Title != "Agents Take Over CI" {
+		t.Errorf("expected a fresh narrative under the same title, got %+v", narrative)
+	}
+	if mock.calls != 1 {
+		t.Errorf("expected no retitle call, got %d calls", mock.calls)
+	}
+	if n, _ := db.GetNarrativeForStoryline(s2); n != nil {
+		t.Errorf("expected the narrative of an emptied storyline to be dropped, got %+v", n)
+	}
+}
+
+// seqProvider returns canned responses in order, repeating the last one.
+type seqProvider struct {
+	responses []string
+	calls     int
+}
+
+func (m *seqProvider) Generate(_ context.Context, _ string, _ int) (string, error) {
+	i := min(m.calls, len(m.responses)-1)
+	m.calls++
+	return m.responses[i], nil
+}
+
+func (m *seqProvider) IsConfigured() bool { return true }
+
+func TestSynthesizeDuplicateTitleGetsAlternative(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "Agents A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "Agents B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents One", []int64{a1})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "AI Agents", "Existing section", nil)
+	s2, _ := db.InsertStoryline("2026-02-06", "Agents Two", []int64{a2})
+
+	resp, _ := json.Marshal(map[string]any{"title": "AI Agents", "narrative": "Another take"})
+	mock := &seqProvider{responses: []string{string(resp), "Agents Move Into CI Pipelines"}}
+	NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	narrative, _ := db.GetNarrativeForStoryline(s2)
+	if narrative == nil {
+		t.Fatal("expected narrative")
+	}
+	if narrative.Title != "Agents Move Into CI Pipelines" {
+		t.Errorf("expected alternative title, got %q", narrative.Title)
+	}
+}
+
+func TestSynthesizeDuplicateTitleFallsBackToSuffix(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "Agents A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "Agents B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents One", []int64{a1})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "AI Agents", "Existing section", nil)
+	s2, _ := db.InsertStoryline("2026-02-06", "Agents Two", []int64{a2})
+
+	resp, _ := json.Marshal(map[string]any{"title": "AI Agents", "narrative": "Another take"})
+	mock := &seqProvider{responses: []string{string(resp), "ai agents"}}
+	NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	narrative, _ := db.GetNarrativeForStoryline(s2)
+	if narrative == nil || narrative.Title != "AI Agents (2)" {
+		t.Errorf("expected 'AI Agents (2)', got %+v", narrative)
+	}
+}
+
+func TestDistinctTitleCutsExcerptOnRunes(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "Agents A", nil, nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "Agents B", nil, nil, ptr("C"), ptr("2026-02-06"))
+	s1, _ := db.InsertStoryline("2026-02-06", "Agents One", []int64{a1})
+	db.InsertStorylineNarrative(s1, "2026-02-06", "KI-Agenten", "Bestehender Abschnitt", nil)
+	s2, _ := db.InsertStoryline("2026-02-06", "Agents Two", []int64{a2})
+
+	// 599 ASCII bytes put the cut of a byte limit inside the first "ü".
+	narrative := strings.Repeat("a", 599) + strings.Repeat("ü", 100)
+	mock := &promptRecorder{response: "KI-Agenten übernehmen CI"}
+	synth := NewSynthesizer(db, mock, Options{})
+	if title := synth.distinctTitle(context.Background(), s2, "KI-Agenten", "Agents Two", narrative, "2026-02-06"); title != "KI-Agenten übernehmen CI" {
+		t.Errorf("expected the alternative title, got %q", title)
+	}
+	if len(mock.prompts) != 1 {
+		t.Fatalf("expected one retitle prompt, got %d", len(mock.prompts))
+	}
+	if !utf8.ValidString(mock.prompts[0]) {
+		t.Error("expected the retitle prompt to be valid UTF-8")
+	}
+	if want := strings.Repeat("a", 599) + "ü..."; !strings.Contains(mock.prompts[0], want) {
+		t.Errorf("expected the excerpt cut after 600 characters, got %q", mock.prompts[0])
+	}
+}
+
+// translatingProvider prefixes every text of a translation request with
+// "DE: " and answers anything else with plain (non-JSON) narrative text.
+type translatingProvider struct {
+	prompts []string
+}
+
+func (m *translatingProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	start, end := strings.Index(prompt, "["), strings.Index(prompt, "\n]")
+	if !strings.HasPrefix(prompt, "Translate") || start < 0 || end < start {
+		return "Ein Abschnitt auf Deutsch.", nil
+	}
+	var texts []string
+	json.Unmarshal([]byte(prompt[start:end+2]), &texts)
+	for i := range texts {
+		texts[i] = "DE: " + texts[i]
+	}
+	resp, _ := json.Marshal(map[string]any{"translations": texts})
+	return string(resp), nil
+}
+
+func (m *translatingProvider) IsConfigured() bool { return true }
+
+func TestSynthesizeTranslatesLabelsAndBrieflyNoted(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "Agents Ship", ptr("Source A"), nil, ptr("C"), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "Lone Release", ptr("Source B"), nil, ptr("C"), ptr("2026-02-06"))
+	db.InsertTriage(a2, "relevant", nil, []string{"A key point"}, nil, 3)
+	story, _ := db.InsertStoryline("2026-02-06", "Agents Ship Fast", []int64{a1})
+	noted, _ := db.InsertStoryline("2026-02-06", brieflyNotedLabel, []int64{a2})
+
+	mock := &translatingProvider{}
+	NewSynthesizer(db, mock, Options{Language: "German"}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	narrative, _ := db.GetNarrativeForStoryline(story)
+	if narrative == nil || narrative.Title != "DE: Agents Ship Fast" {
+		t.Errorf("expected translated label as fallback title, got %+v", narrative)
+	}
+	bn, _ := db.GetNarrativeForStoryline(noted)
+	if bn == nil || bn.Title != brieflyNotedLabel {
+		t.Fatalf("expected Briefly Noted narrative to keep its title, got %+v", bn)
+	}
+	if !strings.Contains(bn.NarrativeText, "**DE: Lone Release** (Source B): DE: A key point") {
+		t.Errorf("expected translated Briefly Noted bullet, got %q", bn.NarrativeText)
+	}
+	if bn.SourceReferences[0].Title != "Lone Release" {
+		t.Errorf("expected source reference to keep the original title, got %q", bn.SourceReferences[0].Title)
+	}
+
+	var sawInstruction bool
+	for _, p := range mock.prompts {
+		if strings.Contains(p, "storyline about") && strings.Contains(p, "in German") {
+			sawInstruction = true
+		}
+	}
+	if !sawInstruction {
+		t.Error("expected the synthesis prompt to ask for German")
+	}
+}
+
+func TestHypeScore(t *testing.T) {
+	independent := database.Article{URL: "https://simonwillison.net/post", Title: "Notes on the new model", Content: ptr("I tried it on three repos.")}
+	vendor := database.Article{URL: "https://www.openai.com/index/launch", Title: "Introducing our model", Content: ptr("Plain release notes.")}
+	hyped := database.Article{URL: "https://news.example.com/x", Title: "A revolutionary, game-changer launch", Content: ptr("It will unleash productivity.")}
+
+	if got := hypeScore([]database.Article{independent}); got != 0 {
+		t.Errorf("expected 0 for independent coverage, got %v", got)
+	}
+	if got := hypeScore([]database.Article{vendor, hyped}); got != 0.5 {
+		t.Errorf("expected 0.5 for one vendor and one hyped article, got %v", got)
+	}
+	if got := hypeScore([]database.Article{{URL: "https://blog.openai.com/launch", Title: hyped.Title}}); got != 1 {
+		t.Errorf("expected 1 for hyped vendor subdomain, got %v", got)
+	}
+	if isVendorURL("https://notopenai.com/") {
+		t.Error("expected lookalike domain not to count as vendor")
+	}
+}
+
+func TestSynthesizeStoresHypeScore(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://anthropic.com/news/launch", "Launch", nil, nil, ptr("Groundbreaking and seamless."), ptr("2026-02-06"))
+	sid, _ := db.InsertStoryline("2026-02-06", "Launch", []int64{a1})
+
+	resp, _ := json.Marshal(map[string]any{"title": "A Launch", "narrative": "Text"})
+	NewSynthesizer(db, &mockProvider{response: string(resp)}, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	narrative, _ := db.GetNarrativeForStoryline(sid)
+	if narrative == nil || narrative.HypeScore == nil || *narrative.HypeScore != 1 {
+		t.Fatalf("expected hype score 1, got %+v", narrative)
+	}
+	if narrative.HypeLevel() != database.HypePromotional {
+		t.Errorf("expected promotional level, got %q", narrative.HypeLevel())
+	}
+}
+
+func TestRepresentativeArticlesCapsEachSource(t *testing.T) {
+	var articles []database.Article
+	add := func(id int64, source string) {
+		articles = append(articles, database.Article{ID: id, URL: fmt.Sprintf("https://x.com/%d", id), Source: ptr(source)})
+	}
+	for id := int64(1); id <= 10; id++ {
+		add(id, "Wire")
+	}
+	add(11, "Blog")
+	add(12, "Paper")
+	score := func(a database.Article) int { return int(20 - a.ID) }
+
+	selected, additional := representativeArticles(articles, score)
+	var ids []int64
+	for _, a := range selected {
+		ids = append(ids, a.ID)
+	}
+	if fmt.Sprint(ids) != "[1 11 12 2]" {
+		t.Errorf("expected each source's best articles first, capped per source, got %v", ids)
+	}
+	if len(additional) != 8 || additional[0].ID != 3 {
+		t.Errorf("expected the other wire articles as additional coverage, got %d", len(additional))
+	}
+}
+
+// promptRecorder answers with a fixed response, recording the prompts it receives.
+type promptRecorder struct {
+	response string
+	prompts  []string
+}
+
+func (m *promptRecorder) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return m.response, nil
+}
+
+func (m *promptRecorder) IsConfigured() bool { return true }
+
+func TestSynthesizeListsAdditionalCoverage(t *testing.T) {
+	db := openTestDB(t)
+	var ids []int64
+	for i := 1; i <= 4; i++ {
+		id, _ := db.InsertArticle(fmt.Sprintf("https://wire.com/%d", i), fmt.Sprintf("Wire copy %d", i), ptr("Wire"), nil, nil, ptr("2026-02-06"))
+		db.InsertTriage(id, "relevant", nil, nil, nil, 5-i)
+		ids = append(ids, id)
+	}
+	sid, _ := db.InsertStoryline("2026-02-06", "Launch", ids)
+
+	mock := &promptRecorder{response: `{"title": "A Launch", "narrative": "Text", "source_references": [{"title": "Wire copy 1", "url": "https://wire.com/1"}]}`}
+	NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	if strings.Contains(mock.prompts[0], "Wire copy 3") || !strings.Contains(mock.prompts[0], "Wire copy 2") {
+		t.Errorf("expected only the two best wire copies in the prompt")
+	}
+	narrative, _ := db.GetNarrativeForStoryline(sid)
+	var extra []string
+	for _, ref := range narrative.SourceReferences {
+		if ref.Additional {
+			extra = append(extra, ref.URL)
+		}
+	}
+	if len(narrative.SourceReferences) != 3 || strings.Join(extra, " ") != "https://wire.com/3 https://wire.com/4" {
+		t.Errorf("expected the LLM's reference and two additional ones, got %+v", narrative.SourceReferences)
+	}
+}
+
+// sameTitleProvider proposes the same title for every section and repeats it
+// when asked for an alternative. It is safe for concurrent use.
+type sameTitleProvider struct{}
+
+func (sameTitleProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	if strings.HasPrefix(prompt, "Another section") {
+		return "AI Agents", nil
+	}
+	return `{"title": "AI Agents", "narrative": "A take on agents."}`, nil
+}
+
+func (sameTitleProvider) IsConfigured() bool { return true }
+
+func TestSynthesizeInParallelKeepsTitlesDistinct(t *testing.T) {
+	db := openTestDB(t)
+	var ids []int64
+	for i := range 6 {
+		aid, _ := db.InsertArticle(fmt.Sprintf("https://a.com/%d", i), fmt.Sprintf("Agents %d", i), nil, nil, ptr("C"), ptr("2026-02-06"))
+		sid, _ := db.InsertStoryline("2026-02-06", fmt.Sprintf("Agents %d", i), []int64{aid})
+		ids = append(ids, sid)
+	}
+
+	result := NewSynthesizer(db, sameTitleProvider{}, Options{Pool: llm.NewPool(3)}).SynthesizePeriod(context.Background(), "2026-02-06")
+	if result.NarrativesCreated != 6 || result.Errors != 0 {
+		t.Fatalf("expected 6 narratives, got %+v", result)
+	}
+	titles := map[string]bool{}
+	for _, id := range ids {
+		if n, _ := db.GetNarrativeForStoryline(id); n != nil {
+			titles[n.Title] = true
+		}
+	}
+	if len(titles) != 6 {
+		t.Errorf("expected 6 distinct titles, got %v", titles)
+	}
+}
+
+func TestExcerptPicksMatchingSentences(t *testing.T) {
+	text := "The launch was announced on Monday. Latency dropped to 40 ms in the benchmark.\n" +
+		"Pricing starts at $5 a month. The team thanked its users."
+	got := excerpt(text, "benchmark latency", 200)
+	if got != "Latency dropped to 40 ms in the benchmark." {
+		t.Errorf("expected the matching sentence, got %q", got)
+	}
+	got = excerpt(text, "latency pricing", 200)
+	if got != "Latency dropped to 40 ms in the benchmark. Pricing starts at $5 a month." {
+		t.Errorf("expected adjacent sentences joined, got %q", got)
+	}
+	if got := excerpt(text, "unrelated", 20); got != "The launch was …" {
+		t.Errorf("expected the truncated start without a match, got %q", got)
+	}
+}
+
+// lookupProvider asks for an excerpt once, then writes the narrative from
+// the result.
+type lookupProvider struct{ prompts []string }
+
+func (l *lookupProvider) Generate(_ context.Context, prompt string, _ int) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	if len(l.prompts) == 1 {
+		return `{"tool_calls": [{"name": "fetch_article_excerpt", "arguments": {"article_id": 2, "query": "latency"}}]}`, nil
+	}
+	resp, _ := json.Marshal(map[string]any{"title": "Faster Inference", "narrative": "Quoted.", "source_references": []any{}})
+	return string(resp), nil
+}
+
+func (l *lookupProvider) IsConfigured() bool { return true }
+
+func TestSynthesizeLooksUpExcerpts(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://a.com", "Inference, part 1", nil, nil, ptr("Nothing to see."), ptr("2026-02-06"))
+	a2, _ := db.InsertArticle("https://b.com", "Inference, part 2", nil, nil, ptr("Intro. Latency fell to 40 ms."), ptr("2026-02-06"))
+	db.InsertTriage(a1, "relevant", nil, nil, nil, 4)
+	db.InsertTriage(a2, "relevant", nil, nil, nil, 3)
+	db.InsertStoryline("2026-02-06", "Inference", []int64{a1, a2})
+
+	p := &lookupProvider{}
+	synth := NewSynthesizer(db, p, Options{ExcerptLookups: 1})
+	if r := synth.SynthesizePeriod(context.Background(), "2026-02-06"); r.NarrativesCreated != 1 {
+		t.Fatalf("expected 1 narrative, got %+v", r)
+	}
+	if len(p.prompts) != 2 {
+		t.Fatalf("expected a lookup round and an answer round, got %d prompts", len(p.prompts))
+	}
+	if !strings.Contains(p.prompts[1], "Latency fell to 40 ms.") {
+		t.Errorf("expected the excerpt in the answer prompt, got %q", p.prompts[1])
+	}
+	narratives, _ := db.GetNarrativesForPeriod("2026-02-06")
+	if len(narratives) != 1 || narratives[0].Title != "Faster Inference" {
+		t.Errorf("expected the narrative written after the lookup, got %+v", narratives)
+	}
+}
+
+func TestSynthesizeSpotlightsSingleArticle(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://a.com", "Our migration to agents", nil, nil, ptr("Report"), ptr("2026-02-06"))
+	db.InsertTriage(aid, "relevant", nil, nil, nil, 5)
+	db.InsertStoryline("2026-02-06", "Migration Agents", []int64{aid})
+
+	mock := &promptRecorder{response: `{"title": "One Team's Agent Migration", "narrative": "Text", "source_references": []}`}
+	NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	if len(mock.prompts) == 0 || !strings.Contains(mock.prompts[0], spotlightInstruction) || strings.Contains(mock.prompts[0], weaveInstruction) {
+		t.Errorf("expected the spotlight instruction for a single article, got %q", mock.prompts)
+	}
+}
+
+func TestSynthesizeTellsStoryInOrder(t *testing.T) {
+	db := openTestDB(t)
+	review, _ := db.InsertArticle("https://news.com/review", "We benchmarked the model", ptr("Tech News"), ptr("2026-02-06"), ptr("Scores"), ptr("2026-02-06"))
+	analysis, _ := db.InsertArticle("https://blog.com/notes", "Notes on the model", ptr("A Blog"), ptr("2026-02-05"), ptr("Notes"), ptr("2026-02-06"))
+	launch, _ := db.InsertArticle("https://vendor.com/launch", "Introducing the model", ptr("Vendor"), ptr("2026-02-05"), ptr("Launch"), ptr("2026-02-06"))
+	for _, id := range []int64{review, analysis, launch} {
+		db.InsertTriage(id, "relevant", nil, nil, nil, 3)
+	}
+	db.AddSource("Vendor", database.SourceVendor)
+	sid, _ := db.InsertStoryline("2026-02-06", "Model Launch", []int64{review, analysis, launch})
+
+	resp, _ := json.Marshal(map[string]any{
+		"title":     "A Model Launches",
+		"narrative": "Text",
+		"source_references": []map[string]string{
+			{"title": "Introducing the model", "url": "https://vendor.com/launch", "contribution": "Announcement"},
+			{"title": "We benchmarked the model", "url": "https://news.com/review", "contribution": "Scores", "role": "primary"},
+			{"title": "Notes on the model", "url": "https://blog.com/notes", "contribution": "Analysis", "role": "bystander"},
+		},
+	})
+	mock := &promptRecorder{response: string(resp)}
+	NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+
+	if len(mock.prompts) == 0 {
+		t.Fatal("expected a synthesis prompt")
+	}
+	prompt := mock.prompts[0]
+	first := strings.Index(prompt, "[1] Introducing the model")
+	second := strings.Index(prompt, "[2] Notes on the model")
+	third := strings.Index(prompt, "[3] We benchmarked the model")
+	if first < 0 || second < first || third < second {
+		t.Errorf("expected the launch, then the notes, then the benchmark, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "Published: 2026-02-05 (primary source)") || !strings.Contains(prompt, "Published: 2026-02-06 (follow-up coverage)") {
+		t.Errorf("expected dates and roles in the prompt, got %q", prompt)
+	}
+
+	narrative, _ := db.GetNarrativeForStoryline(sid)
+	roles := map[string]string{}
+	for _, ref := range narrative.SourceReferences {
+		roles[ref.URL] = ref.Role
+	}
+	// A missing or unknown role is filled in; a valid one from the LLM is kept.
+	if roles["https://vendor.com/launch"] != database.RolePrimary || roles["https://news.com/review"] != database.RolePrimary || roles["https://blog.com/notes"] != database.RoleFollowUp {
+		t.Errorf("unexpected roles %v", roles)
+	}
+}
+
+func TestSynthesizeRecallsMatchingTopics(t *testing.T) {
+	db := openTestDB(t)
+	db.ReplaceTopicMemories("2026-02-02", []database.TopicMemory{
+		{Topic: "Vendor X outages", Summary: "Feb 2: API down for hours", Keywords: []string{"Vendor X"}, Periods: 2},
+		{Topic: "Agent benchmarks", Summary: "Feb 2: a new leaderboard", Keywords: []string{"SWE-bench"}},
+	})
+	aid, _ := db.InsertArticle("https://a.com", "Vendor X is down again", nil, nil, ptr("Outage"), ptr("2026-02-06"))
+	db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
+	db.InsertStoryline("2026-02-06", "API outage", []int64{aid})
+
+	response := `{"title": "Another Outage", "narrative": "Text", "source_references": []}`
+	mock := &promptRecorder{response: response}
+	NewSynthesizer(db, mock, Options{RecallDays: 30}).SynthesizePeriod(context.Background(), "2026-02-06")
+	if len(mock.prompts) == 0 {
+		t.Fatal("expected a synthesis prompt")
+	}
+	if prompt := mock.prompts[0]; !strings.Contains(prompt, "- Vendor X outages (came up 2 times before, last on 2026-02-02): Feb 2: API down for hours") ||
+		strings.Contains(prompt, "Agent benchmarks") {
+		t.Errorf("expected only the matching topic recalled, got %q", prompt)
+	}
+
+	db.ClearStorylinesForPeriod("2026-02-06")
+	db.InsertStoryline("2026-02-06", "API outage", []int64{aid})
+	mock = &promptRecorder{response: response}
+	NewSynthesizer(db, mock, Options{}).SynthesizePeriod(context.Background(), "2026-02-06")
+	if len(mock.prompts) == 0 || strings.Contains(mock.prompts[0], recallInstruction) {
+		t.Errorf("expected no recall without RecallDays, got %q", mock.prompts)
+	}
+}
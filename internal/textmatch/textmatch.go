@@ -0,0 +1,35 @@
+// Package textmatch matches terms in text as whole words, for the keyword
+// filters of collection, the mute rules of triage and the keywords of
+// memories alike.
+package textmatch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ContainsWords reports whether term occurs in text as whole words, so
+// "meta" doesn't match "metadata". The match is case-sensitive; callers
+// lower both. An empty term matches nothing.
+func ContainsWords(text, term string) bool {
+	if term == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(text[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isWordByte reports whether b is part of a word: a letter or digit, or a
+// byte of a non-ASCII character, so terms in any script match.
+func isWordByte(b byte) bool {
+	return b >= 0x80 || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
@@ -0,0 +1,27 @@
+package textmatch
+
+import "testing"
+
+func TestContainsWords(t *testing.T) {
+	tests := []struct {
+		text, term string
+		want       bool
+	}{
+		{"meta releases a model", "meta", true},
+		{"new metadata standard", "meta", false},
+		{"a metadata and meta story", "meta", true},
+		{"gpt-5 is out", "gpt-5", true},
+		{"gpt-50 is out", "gpt-5", false},
+		{"open source models", "open source", true},
+		{"(ai) news", "ai", true},
+		{"über modelle", "modelle", true},
+		{"straße", "stra", false},
+		{"anything", "", false},
+		{"", "ai", false},
+	}
+	for _, tt := range tests {
+		if got := ContainsWords(tt.text, tt.term); got != tt.want {
+			t.Errorf("ContainsWords(%q, %q) = %v, want %v", tt.text, tt.term, got, tt.want)
+		}
+	}
+}
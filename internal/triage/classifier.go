@@ -0,0 +1,369 @@
+package triage
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+// ClassifierOptions configures the classifier that decides clear-cut
+// articles from their embeddings before any LLM call.
+type ClassifierOptions struct {
+	// Embedder embeds the articles. It must be one whose vectors are cached
+	// (see llm.EmbedderName): the classifier is trained on the embeddings
+	// of every earlier verdict.
+	Embedder llm.Embedder
+
+	// MinExamples is the fewest labelled articles to train on; 0 means
+	// defaultMinExamples.
+	MinExamples int
+
+	// An article is relevant without an LLM call when the classifier gives
+	// it a probability above AcceptAbove, and skipped below RejectBelow.
+	AcceptAbove float64
+	RejectBelow float64
+
+	// MinAccuracy is how often the classifier's confident decisions on the
+	// held-out examples must agree with their labels for it to be used.
+	MinAccuracy float64
+}
+
+// Defaults and limits of the classifier.
+const (
+	defaultMinExamples    = 300
+	maxClassifierExamples = 5000 // most recent labelled articles trained on
+	classifierChars       = 1000 // characters of content embedded
+)
+
+// classifierEmbeddings is appended to the embedder's cache key: the
+// classifier embeds an article's own text, not the clustering text with
+// its key points, which only exist after triage.
+const classifierEmbeddings = "#triage"
+
+// classifierReason starts the relevance reason of an article the classifier
+// decided, so its verdict is never learned from.
+const classifierReason = "Classifier: "
+
+// classify decides the articles the classifier is confident about and
+// returns the rest for the LLM. Articles of policy feeds always go to the
+// LLM. Without enough history, or when the classifier isn't accurate
+// enough on it, every article does.
+func (t *Triager) classify(ctx context.Context, articles []database.Article, r *Result) []database.Article {
+	opts := t.opts.Classifier
+	if opts == nil {
+		return articles
+	}
+	model := llm.EmbedderName(opts.Embedder)
+	if model == "" {
+		log.Println("The triage classifier needs a model embedder; triaging every article with the LLM")
+		return articles
+	}
+	model += classifierEmbeddings
+
+	examples, err := t.db.GetTrainingExamples("")
+	if err != nil {
+		log.Printf("Error reading triage history for the classifier: %v", err)
+		return articles
+	}
+	var training []database.Article
+	var labels []bool
+	for _, e := range examples {
+		if label, _, ok := trainingLabel(e, t.opts.PolicySources); ok {
+			training = append(training, e.Article)
+			labels = append(labels, label == "relevant")
+		}
+	}
+	if n := len(training) - maxClassifierExamples; n > 0 {
+		training, labels = training[n:], labels[n:]
+	}
+	if minExamples := cmp.Or(opts.MinExamples, defaultMinExamples); len(training) < minExamples {
+		log.Printf("The triage classifier needs %d labelled articles, %d so far; triaging every article with the LLM", minExamples, len(training))
+		return articles
+	}
+
+	var candidates, rest []database.Article
+	for _, a := range articles {
+		if t.isPolicySource(a) {
+			rest = append(rest, a)
+		} else {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return articles
+	}
+
+	vectors, err := t.embed(ctx, model, opts.Embedder, append(candidates, training...))
+	if err != nil {
+		log.Printf("Error embedding articles for the classifier: %v; triaging every article with the LLM", err)
+		return articles
+	}
+	var trainVectors [][]float64
+	var trainLabels []bool
+	for i, v := range vectors[len(candidates):] {
+		if v != nil {
+			trainVectors = append(trainVectors, v)
+			trainLabels = append(trainLabels, labels[i])
+		}
+	}
+
+	accuracy, decided := holdoutAccuracy(trainVectors, trainLabels, opts)
+	if decided == 0 || accuracy < opts.MinAccuracy {
+		log.Printf("The triage classifier was %.0f%% accurate on %d confident held-out decisions, below the %.0f%% required; triaging every article with the LLM",
+			100*accuracy, decided, 100*opts.MinAccuracy)
+		return articles
+	}
+	c := trainClassifier(trainVectors, trainLabels)
+	if c == nil {
+		return articles
+	}
+
+	classified := 0
+	for i, a := range candidates {
+		if vectors[i] == nil {
+			rest = append(rest, a)
+			continue
+		}
+		p := c.probability(vectors[i])
+		var result *triageResult
+		switch {
+		case p > opts.AcceptAbove:
+			at := "other"
+			reason := fmt.Sprintf("%s%.0f%% likely relevant", classifierReason, 100*p)
+			result = &triageResult{verdict: "relevant", articleType: &at, reason: &reason, practicalScore: 2}
+		case p < opts.RejectBelow:
+			reason := fmt.Sprintf("%s%.0f%% likely skipped", classifierReason, 100*(1-p))
+			result = &triageResult{verdict: "skip", reason: &reason}
+		default:
+			rest = append(rest, a)
+			continue
+		}
+		t.store(r, a, result)
+		classified++
+	}
+	r.Classified = classified
+	log.Printf("Classifier decided %d of %d articles (%.0f%% accurate on %d held-out decisions); %d left for the LLM",
+		classified, len(articles), 100*accuracy, decided, len(rest))
+	return rest
+}
+
+// holdoutAccuracy trains on all but the most recent fifth of the examples
+// and returns how often its confident decisions on that fifth agreed with
+// the labels, and how many it made.
+func holdoutAccuracy(vectors [][]float64, labels []bool, opts *ClassifierOptions) (float64, int) {
+	split := len(vectors) - len(vectors)/5
+	c := trainClassifier(vectors[:split], labels[:split])
+	if c == nil {
+		return 0, 0
+	}
+	decided, correct := 0, 0
+	for i, v := range vectors[split:] {
+		p := c.probability(v)
+		if p <= opts.AcceptAbove && p >= opts.RejectBelow {
+			continue
+		}
+		decided++
+		if (p > opts.AcceptAbove) == labels[split+i] {
+			correct++
+		}
+	}
+	if decided == 0 {
+		return 0, 0
+	}
+	return float64(correct) / float64(decided), decided
+}
+
+// embed returns an embedding per article of classifierText, reusing cached
+// vectors of an unchanged text and caching the new ones under model. Cached
+// vectors of another size than the embedder returns now are deleted and
+// left nil, so they are embedded again next run instead of being mixed
+// with new ones.
+func (t *Triager) embed(ctx context.Context, model string, embedder llm.Embedder, articles []database.Article) ([][]float64, error) {
+	ids := make([]int64, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+	cached, err := t.db.GetArticleEmbeddings(model, ids)
+	if err != nil {
+		log.Printf("Error reading cached embeddings: %v", err)
+	}
+
+	vectors := make([][]float64, len(articles))
+	hashes := make([]string, len(articles))
+	var missing []int
+	var texts []string
+	for i, a := range articles {
+		text := classifierText(a)
+		sum := sha256.Sum256([]byte(text))
+		hashes[i] = hex.EncodeToString(sum[:])
+		if e, ok := cached[a.ID]; ok && e.TextHash == hashes[i] {
+			vectors[i] = e.Vector
+		} else {
+			missing = append(missing, i)
+			texts = append(texts, text)
+		}
+	}
+	want := len(vectors[0])
+	if len(missing) > 0 {
+		fresh, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", llm.ErrEmbedderUnavailable, err)
+		}
+		if len(fresh) != len(texts) {
+			return nil, fmt.Errorf("got %d embeddings for %d texts", len(fresh), len(texts))
+		}
+		want = len(fresh[0])
+		var store []database.ArticleEmbedding
+		for j, i := range missing {
+			if len(fresh[j]) != want || want == 0 {
+				return nil, fmt.Errorf("embeddings of %d and %d dimensions", want, len(fresh[j]))
+			}
+			vectors[i] = fresh[j]
+			store = append(store, database.ArticleEmbedding{ArticleID: articles[i].ID, Model: model, TextHash: hashes[i], Vector: fresh[j]})
+		}
+		if err := t.db.PutArticleEmbeddings(store); err != nil {
+			log.Printf("Error caching embeddings: %v", err)
+		}
+	}
+
+	stale := 0
+	for i, v := range vectors {
+		if len(v) != want {
+			vectors[i] = nil
+			stale++
+		}
+	}
+	if stale > 0 {
+		log.Printf("Left out %d articles embedded with another size", stale)
+		if _, err := t.db.DeleteMismatchedEmbeddings(model, want); err != nil {
+			log.Printf("Error deleting mismatched embeddings: %v", err)
+		}
+	}
+	return vectors, nil
+}
+
+// classifierText is what the classifier sees of an article: the same as a
+// training record's prompt, without the instruction.
+func classifierText(a database.Article) string {
+	source := "Unknown"
+	if a.Source != nil {
+		source = *a.Source
+	}
+	content := ""
+	if a.Content != nil {
+		content = *a.Content
+	}
+	if len(content) > classifierChars {
+		content = content[:classifierChars]
+	}
+	return strings.TrimSpace(a.Title + "\nSource: " + source + "\n\n" + content)
+}
+
+// classifier is a logistic regression over standardized embeddings.
+type classifier struct {
+	mean, scale []float64
+	weights     []float64
+	bias        float64
+}
+
+// Training settings of trainClassifier.
+const (
+	trainEpochs  = 150
+	learningRate = 0.5
+	l2Penalty    = 1e-3
+)
+
+// trainClassifier fits a classifier to the vectors and their labels (true
+// for relevant) by gradient descent. Both classes are weighted equally, as
+// skips usually far outnumber relevant articles. It returns nil unless both
+// classes are present.
+func trainClassifier(vectors [][]float64, labels []bool) *classifier {
+	positive := 0
+	for _, l := range labels {
+		if l {
+			positive++
+		}
+	}
+	if len(vectors) == 0 || positive == 0 || positive == len(labels) {
+		return nil
+	}
+	n, dims := float64(len(vectors)), len(vectors[0])
+
+	c := &classifier{mean: make([]float64, dims), scale: make([]float64, dims), weights: make([]float64, dims)}
+	for _, v := range vectors {
+		for d, x := range v {
+			c.mean[d] += x / n
+		}
+	}
+	for _, v := range vectors {
+		for d, x := range v {
+			c.scale[d] += (x - c.mean[d]) * (x - c.mean[d]) / n
+		}
+	}
+	for d, variance := range c.scale {
+		c.scale[d] = 1
+		if variance > 0 {
+			c.scale[d] = 1 / math.Sqrt(variance)
+		}
+	}
+	xs := make([][]float64, len(vectors))
+	for i, v := range vectors {
+		xs[i] = c.standardize(v)
+	}
+
+	weightOf := map[bool]float64{true: n / (2 * float64(positive)), false: n / (2 * (n - float64(positive)))}
+	grad := make([]float64, dims)
+	for range trainEpochs {
+		clear(grad)
+		gradBias := 0.0
+		for i, x := range xs {
+			y := 0.0
+			if labels[i] {
+				y = 1
+			}
+			diff := (sigmoid(c.score(x)) - y) * weightOf[labels[i]] / n
+			for d, xd := range x {
+				grad[d] += diff * xd
+			}
+			gradBias += diff
+		}
+		for d := range c.weights {
+			c.weights[d] -= learningRate * (grad[d] + l2Penalty*c.weights[d])
+		}
+		c.bias -= learningRate * gradBias
+	}
+	return c
+}
+
+// probability returns how likely the article embedded as v is relevant.
+func (c *classifier) probability(v []float64) float64 {
+	return sigmoid(c.score(c.standardize(v)))
+}
+
+func (c *classifier) standardize(v []float64) []float64 {
+	x := make([]float64, len(v))
+	for d := range v {
+		x[d] = (v[d] - c.mean[d]) * c.scale[d]
+	}
+	return x
+}
+
+func (c *classifier) score(x []float64) float64 {
+	s := c.bias
+	for d, xd := range x {
+		s += c.weights[d] * xd
+	}
+	return s
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
@@ -0,0 +1,37 @@
+package triage
+
+import (
+	"log"
+	"slices"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/language"
+)
+
+// languageReason starts the relevance reason of an article skipped for the
+// language it is written in. Like mute rules, the setting decided, so these
+// are never learned from.
+const languageReason = "Language: "
+
+// skipLanguages stores a skip verdict for the articles written in a
+// language Options.Languages doesn't list, without an LLM call, and returns
+// the others. Articles whose language wasn't told are kept.
+func (t *Triager) skipLanguages(articles []database.Article, r *Result) []database.Article {
+	if len(t.opts.Languages) == 0 {
+		return articles
+	}
+	var rest []database.Article
+	for _, a := range articles {
+		if a.Language == nil || *a.Language == "" || slices.Contains(t.opts.Languages, *a.Language) {
+			rest = append(rest, a)
+			continue
+		}
+		reason := languageReason + language.Name(*a.Language)
+		t.store(r, a, &triageResult{verdict: "skip", reason: &reason})
+		r.OtherLanguage++
+	}
+	if r.OtherLanguage > 0 {
+		log.Printf("Skipped %d of %d articles in other languages", r.OtherLanguage, len(articles))
+	}
+	return rest
+}
@@ -0,0 +1,63 @@
+package triage
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/textmatch"
+)
+
+// mutedReason starts the relevance reason of an article a mute rule
+// skipped. Like the classifier's verdicts, these are never learned from:
+// the rule, not the article, decided.
+const mutedReason = "Muted: "
+
+// mute stores a skip verdict for the articles a mute rule matches, without
+// an LLM call, and returns the others.
+func (t *Triager) mute(articles []database.Article, r *Result) []database.Article {
+	rules, err := t.db.GetMuteRules()
+	if err != nil {
+		log.Printf("Error reading mute rules: %v", err)
+		return articles
+	}
+	if len(rules) == 0 {
+		return articles
+	}
+	var rest []database.Article
+	for _, a := range articles {
+		rule := MatchMute(rules, a)
+		if rule == nil {
+			rest = append(rest, a)
+			continue
+		}
+		reason := fmt.Sprintf("%s%s %q", mutedReason, rule.Kind, rule.Value)
+		t.store(r, a, &triageResult{verdict: "skip", reason: &reason})
+		r.Muted++
+	}
+	if r.Muted > 0 {
+		log.Printf("Mute rules skipped %d of %d articles", r.Muted, len(articles))
+	}
+	return rest
+}
+
+// MatchMute returns the first of rules that mutes the article, or nil: a
+// source rule naming its source, or a term rule whose term its title
+// mentions as whole words, both ignoring case.
+func MatchMute(rules []database.MuteRule, a database.Article) *database.MuteRule {
+	title := strings.ToLower(a.Title)
+	for i, rule := range rules {
+		switch rule.Kind {
+		case database.MuteSource:
+			if a.Source != nil && strings.EqualFold(*a.Source, rule.Value) {
+				return &rules[i]
+			}
+		case database.MuteTerm:
+			if textmatch.ContainsWords(title, strings.ToLower(rule.Value)) {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package triage
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
+)
+
+// classifierPrompt is the input of a training record: what a small
+// classifier sees of an article, without the priorities and feedback
+// patterns the LLM prompt carries, which change from run to run.
+const classifierPrompt = `Classify this AI news article for a daily briefing aimed at %s as "relevant" or "skip".
+
+Title: %s
+Source: %s
+Content:
+%s`
+
+// Where a training record's label came from.
+const (
+	LabelTriage   = "triage"   // the LLM's verdict
+	LabelFeedback = "feedback" // the reader's rating, which wins over the verdict
+)
+
+// TrainingRecord is a prompt/label pair of the training export, with what
+// is needed to weigh or audit it.
+type TrainingRecord struct {
+	Prompt         string `json:"prompt"`
+	Label          string `json:"label"` // "relevant" or "skip"
+	LabelSource    string `json:"label_source"`
+	TriageVerdict  string `json:"triage_verdict"`
+	ArticleType    string `json:"article_type,omitempty"`
+	PracticalScore int    `json:"practical_score"`
+	ArticleID      int64  `json:"article_id"`
+	URL            string `json:"url"`
+	PeriodID       string `json:"period_id"`
+}
+
+// TrainingOptions controls the training export.
+type TrainingOptions struct {
+	// Audience is named in the prompts; empty means llm.DefaultAudience.
+	Audience string
+
+	// MaxChars cuts article content in the prompts; 0 means
+	// defaultTrainingChars.
+	MaxChars int
+
+	// PolicySources are left out: their articles are triaged with the
+	// policy prompt, which a general classifier shouldn't learn from.
+	PolicySources []string
+}
+
+// defaultTrainingChars keeps prompts within a small model's context.
+const defaultTrainingChars = 2000
+
+// TrainingRecords turns triage history into training records, labelled as
+// trainingLabel says.
+func TrainingRecords(examples []database.TrainingExample, opts TrainingOptions) []TrainingRecord {
+	audience := cmp.Or(opts.Audience, llm.DefaultAudience)
+	maxChars := cmp.Or(opts.MaxChars, defaultTrainingChars)
+
+	var records []TrainingRecord
+	for _, e := range examples {
+		label, labelSource, ok := trainingLabel(e, opts.PolicySources)
+		if !ok {
+			continue
+		}
+		a, t := e.Article, e.Triage
+		source := "Unknown"
+		if a.Source != nil {
+			source = *a.Source
+		}
+
+		r := TrainingRecord{
+			Label:          label,
+			LabelSource:    labelSource,
+			TriageVerdict:  t.Verdict,
+			PracticalScore: t.PracticalScore,
+			ArticleID:      a.ID,
+			URL:            a.URL,
+		}
+		if t.ArticleType != nil {
+			r.ArticleType = *t.ArticleType
+		}
+		if a.PeriodID != nil {
+			r.PeriodID = *a.PeriodID
+		}
+
+		content := a.Title
+		if a.Content != nil && *a.Content != "" {
+			content = *a.Content
+		}
+		if len(content) > maxChars {
+			content = content[:maxChars] + "..."
+		}
+		r.Prompt = fmt.Sprintf(classifierPrompt, audience, a.Title, source, content)
+		records = append(records, r)
+	}
+	return records
+}
+
+// trainingLabel returns the label an example teaches and where it came from.
+// A reader's rating overrides the verdict: a positive one labels the article
+// relevant, a negative one skip. Unless rated, articles whose triage
+// response couldn't be parsed (kept as relevant by default) and articles
+// the classifier, a mute rule or their language decided are not learned
+// from; articles of policy sources never are. ok is false for those.
+func trainingLabel(e database.TrainingExample, policySources []string) (label, source string, ok bool) {
+	if e.Article.Source != nil && slices.Contains(policySources, *e.Article.Source) {
+		return "", "", false
+	}
+	switch {
+	case e.Rating != nil && *e.Rating == "positive":
+		return "relevant", LabelFeedback, true
+	case e.Rating != nil && *e.Rating == "negative":
+		return "skip", LabelFeedback, true
+	}
+	if reason := e.Triage.RelevanceReason; reason != nil && (*reason == unparsedReason || strings.HasPrefix(*reason, classifierReason) || strings.HasPrefix(*reason, mutedReason) || strings.HasPrefix(*reason, languageReason)) {
+		return "", "", false
+	}
+	return e.Triage.Verdict, LabelTriage, true
+}
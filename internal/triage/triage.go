@@ -1,21 +1,24 @@
 package triage
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
 	"github.com/TobiSchelling/AICrawler/internal/llm"
 )
 
-const triagePrompt = `You are triaging AI news articles for a daily briefing aimed at people who build software.
+const triagePrompt = `You are triaging AI news articles for a daily briefing aimed at %s.
 
 Decide whether this article is RELEVANT or should be SKIPPED.
 
-RELEVANT means: practical AI developments, experience reports from using AI tools, new techniques you can try, architecture patterns, tool releases, significant model updates, or insightful commentary on AI's impact on software development.
+RELEVANT means: practical AI developments, experience reports from using AI tools, new techniques you can try, architecture patterns, tool releases, significant model updates, or insightful commentary on AI's impact on these readers' work.
 
 SKIP means: pure academic research papers, funding/investment announcements, marketing fluff, product launches with no technical substance, celebrity AI opinions, or AI doom/hype pieces with no practical content.
 
@@ -25,6 +28,8 @@ Research priorities to give extra weight:
 Reader feedback patterns (use to calibrate relevance):
 %s
 
+Today's date: %s
+
 Article Title: %s
 Source: %s
 Content:
@@ -36,35 +41,184 @@ Respond with ONLY this JSON:
     "article_type": "experience_report" | "tool_release" | "technique" | "architecture" | "model_update" | "commentary" | "tutorial" | "announcement" | "other",
     "key_points": ["point 1", "point 2", "point 3"],
     "relevance_reason": "One sentence explaining your verdict",
-    "practical_score": 1-5
+    "practical_score": 1-5,
+    "upcoming_events": [
+        {"title": "Short event name", "date": "YYYY-MM-DD", "kind": "release" | "conference" | "deadline" | "other"}
+    ],
+    "benchmark_results": [
+        {"model": "Model name", "benchmark": "Benchmark name", "score": 88.7, "sota_claim": true or false}
+    ]
 }
 
-practical_score: 5 = immediately actionable, 1 = tangentially related. Skip articles get 0.`
+practical_score: 5 = immediately actionable, 1 = tangentially related. Skip articles get 0.
+upcoming_events: only concrete future dates stated in the article (release dates, conferences, deadlines); use [] when there are none. Never guess a date.
+benchmark_results: only numeric scores the article reports for a named model on a named benchmark where higher is better (e.g. accuracy in percent); sota_claim is true when the article calls the result state of the art. Use [] when there are none.`
+
+const policyTriagePrompt = `You are triaging articles from AI policy sources for the "Policy watch" section of a daily AI briefing aimed at %s.
+
+Decide whether this article is RELEVANT or should be SKIPPED.
+
+RELEVANT means: legislation, regulation, executive orders, court rulings, enforcement actions, standards or official guidance that affect how AI systems may be built, deployed or sold, and concrete changes in their status or obligations.
+
+SKIP means: general opinion with no legal development, event promotion, lobbying news with no effect on any rule, or articles that are not about AI.
+
+Regulations the reader tracks:
+%s
+
+Today's date: %s
+
+Article Title: %s
+Source: %s
+Content:
+%s
+
+Respond with ONLY this JSON:
+{
+    "verdict": "relevant" or "skip",
+    "article_type": "regulation" | "commentary" | "announcement" | "other",
+    "key_points": ["point 1", "point 2", "point 3"],
+    "relevance_reason": "One sentence explaining your verdict",
+    "practical_score": 1-5,
+    "upcoming_events": [
+        {"title": "Short event name", "date": "YYYY-MM-DD", "kind": "deadline" | "conference" | "other"}
+    ],
+    "policy_updates": [
+        {"regulation": "Regulation name", "status": "proposed" | "under_debate" | "adopted" | "in_force" | "amended" | "delayed" | "withdrawn" | "other", "summary": "One sentence on what changed"}
+    ]
+}
+
+practical_score: 5 = changes what builders must do soon, 1 = background reading. Skip articles get 0.
+upcoming_events: only concrete future dates stated in the article (compliance deadlines, votes, hearings); use [] when there are none. Never guess a date.
+policy_updates: the status the article reports for each regulation it covers, using a tracked name above when the article is about that regulation. Use [] when the article reports no status.`
+
+// triageSchema and policyTriageSchema describe the responses the prompts
+// above ask for.
+var (
+	triageSchema = llm.Schema{Name: "triage", Definition: llm.Object(map[string]any{
+		"verdict":          llm.String("relevant", "skip"),
+		"article_type":     llm.String("experience_report", "tool_release", "technique", "architecture", "model_update", "commentary", "tutorial", "announcement", "other"),
+		"key_points":       llm.Array(llm.String()),
+		"relevance_reason": llm.String(),
+		"practical_score":  llm.Integer(),
+		"upcoming_events":  llm.Array(eventSchema("release", "conference", "deadline", "other")),
+		"benchmark_results": llm.Array(llm.Object(map[string]any{
+			"model":      llm.String(),
+			"benchmark":  llm.String(),
+			"score":      llm.Number(),
+			"sota_claim": llm.Boolean(),
+		})),
+	})}
+
+	policyTriageSchema = llm.Schema{Name: "policy_triage", Definition: llm.Object(map[string]any{
+		"verdict":          llm.String("relevant", "skip"),
+		"article_type":     llm.String("regulation", "commentary", "announcement", "other"),
+		"key_points":       llm.Array(llm.String()),
+		"relevance_reason": llm.String(),
+		"practical_score":  llm.Integer(),
+		"upcoming_events":  llm.Array(eventSchema("deadline", "conference", "other")),
+		"policy_updates": llm.Array(llm.Object(map[string]any{
+			"regulation": llm.String(),
+			"status":     llm.String(database.PolicyProposed, database.PolicyDebated, database.PolicyAdopted, database.PolicyInForce, database.PolicyAmended, database.PolicyDelayed, database.PolicyWithdrawn, database.PolicyOther),
+			"summary":    llm.String(),
+		})),
+	})}
+)
+
+// unparsedReason is the relevance reason of an article whose triage
+// response could not be parsed and was kept as relevant by default.
+const unparsedReason = "LLM response could not be parsed"
+
+// paywalledContent stands in for the text of a paywalled article, whose
+// teaser would mislead triage more than the title and summary.
+const paywalledContent = "Content unavailable (paywalled). Judge from the title and any summary below."
+
+func eventSchema(kinds ...string) map[string]any {
+	return llm.Object(map[string]any{
+		"title": llm.String(),
+		"date":  llm.String(),
+		"kind":  llm.String(kinds...),
+	})
+}
+
+// Options controls optional triage behaviour.
+type Options struct {
+	// PolicySources are the source names of policy feeds; their articles are
+	// triaged for legal and regulatory relevance and may yield policy updates.
+	PolicySources []string
+
+	// Regulations are the tracked regulation names offered to the policy
+	// prompt, so updates are filed under a consistent name.
+	Regulations []string
+
+	// MaxTokens limits the response to each article's prompt; 0 means
+	// defaultMaxTokens.
+	MaxTokens int
+
+	// RetryPasses is how often articles whose triage call failed are tried
+	// again at the end of a run, waiting RetryBackoff before the first pass
+	// and doubling the wait before each further one.
+	RetryPasses  int
+	RetryBackoff time.Duration
+
+	// ErrorBudget stops triage after this many failed calls in a run, leaving
+	// the remaining articles for the next run; 0 means no limit.
+	ErrorBudget int
+
+	// Audience is who the briefing is for, as relevance is judged for them;
+	// empty means llm.DefaultAudience.
+	Audience string
+
+	// Pool triages as many articles at once as it has slots; nil triages
+	// them one by one.
+	Pool *llm.Pool
+
+	// Languages are the ISO 639-1 codes of the languages worth triaging;
+	// articles detected in any other are skipped. Empty means all of them.
+	Languages []string
+
+	// Classifier, when set, decides the articles a classifier trained on
+	// earlier verdicts is confident about, leaving the rest to the LLM.
+	Classifier *ClassifierOptions
+}
+
+// defaultMaxTokens fits key points, events and benchmark results.
+const defaultMaxTokens = 768
 
 // Result holds the results of a triage run.
 type Result struct {
-	Processed int
-	Relevant  int
-	Skipped   int
-	Errors    int
+	Processed  int
+	Relevant   int
+	Skipped    int
+	Errors     int // articles still failing at the end of the run
+	Recovered  int // articles triaged by a retry pass
+	Deferred   int // articles left untried once the error budget was spent
+	Classified int // articles the classifier decided without an LLM call
+	Muted      int // articles a mute rule skipped without an LLM call
+
+	OtherLanguage int // articles skipped for their language without an LLM call
+
+	// LastErr is the error of the last failed article, wrapping
+	// llm.ErrNoProvider when there was no provider to ask.
+	LastErr error
 }
 
 // Triager triages articles using LLM for relevance assessment.
 type Triager struct {
 	db       *database.DB
 	provider llm.Provider
+	opts     Options
 }
 
 // NewTriager creates a new article triager.
-func NewTriager(db *database.DB, provider llm.Provider) *Triager {
-	return &Triager{db: db, provider: provider}
+func NewTriager(db *database.DB, provider llm.Provider, opts Options) *Triager {
+	return &Triager{db: db, provider: provider, opts: opts}
 }
 
 // TriageArticles triages all untriaged articles for a period.
 func (t *Triager) TriageArticles(ctx context.Context, periodID string) *Result {
 	if t.provider == nil {
 		log.Println("No LLM provider available for triage")
-		return &Result{Errors: 1}
+		return &Result{Errors: 1, LastErr: llm.ErrNoProvider}
 	}
 
 	articles, err := t.db.GetUntriagedArticles(&periodID)
@@ -84,49 +238,173 @@ func (t *Triager) TriageArticles(ctx context.Context, periodID string) *Result {
 	feedbackSummary, _ := t.db.GetFeedbackSummary()
 	feedbackText := formatFeedbackSummary(feedbackSummary)
 
+	weights, err := t.db.GetSourceWeights()
+	if err != nil {
+		log.Printf("Error getting source weights: %v", err)
+	}
+
 	r := &Result{}
-	for _, article := range articles {
-		result, err := t.triageArticle(ctx, article, prioritiesText, feedbackText)
-		if err != nil {
-			log.Printf("Error triaging article %d: %v", article.ID, err)
-			r.Errors++
-			continue
+	failures := 0 // failed calls this run, retries included
+	pending := t.classify(ctx, t.mute(t.skipLanguages(articles, r), r), r)
+	for pass := 0; pass <= t.opts.RetryPasses && len(pending) > 0; pass++ {
+		if pass > 0 {
+			wait := t.opts.RetryBackoff << (pass - 1)
+			log.Printf("Retrying triage of %d articles in %s", len(pending), wait)
+			if err := sleepContext(ctx, wait); err != nil {
+				break
+			}
 		}
 
-		if result == nil {
-			r.Errors++
-			continue
-		}
+		// Results are stored under mu, so articles triaged in parallel
+		// don't interleave their writes.
+		var mu sync.Mutex
+		var failed []database.Article
+		deferred := 0
+		t.opts.Pool.Run(ctx, len(pending), func(i int) {
+			article := pending[i]
+			mu.Lock()
+			spent := t.opts.ErrorBudget > 0 && failures >= t.opts.ErrorBudget
+			if spent {
+				deferred++
+			}
+			mu.Unlock()
+			if spent {
+				return
+			}
 
-		t.db.InsertTriage(article.ID, result.verdict, result.articleType, result.keyPoints, result.reason, result.practicalScore)
-		r.Processed++
-		if result.verdict == "relevant" {
-			r.Relevant++
-		} else {
-			r.Skipped++
+			result, err := t.triageArticle(ctx, article, prioritiesText, feedbackText, weights)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("Error triaging article %d: %v", article.ID, err)
+				if err := t.db.RecordTriageFailure(article.ID, err.Error()); err != nil {
+					log.Printf("Error recording triage failure of article %d: %v", article.ID, err)
+				}
+				failures++
+				r.LastErr = err
+				failed = append(failed, article)
+				return
+			}
+			if pass > 0 {
+				r.Recovered++
+			}
+			t.store(r, article, result)
+		})
+		pending = failed
+		if deferred > 0 {
+			r.Deferred = deferred
+			log.Printf("Triage error budget of %d spent; leaving %d articles for the next run", t.opts.ErrorBudget, r.Deferred)
+			break
+		}
+		if ctx.Err() != nil {
+			break
 		}
-		log.Printf("Triaged [%s]: %s", result.verdict, article.Title)
 	}
+	r.Errors = len(pending)
 
 	log.Printf("Triage complete: %d processed (%d relevant, %d skipped), %d errors",
 		r.Processed, r.Relevant, r.Skipped, r.Errors)
 	return r
 }
 
+// store saves an article's triage and what it extracted, and counts it.
+func (t *Triager) store(r *Result, article database.Article, result *triageResult) {
+	t.db.InsertTriage(article.ID, result.verdict, result.articleType, result.keyPoints, result.reason, result.practicalScore)
+	if result.verdict == "relevant" {
+		for _, ev := range result.events {
+			if _, err := t.db.InsertEvent(article.ID, ev.title, ev.kind, ev.date); err != nil {
+				log.Printf("Error storing event %q: %v", ev.title, err)
+			}
+		}
+		for _, b := range result.benchmarks {
+			if _, err := t.db.InsertBenchmarkResult(article.ID, b.model, b.benchmark, b.score, b.reportedDate, b.sotaClaim); err != nil {
+				log.Printf("Error storing %s result for %s: %v", b.benchmark, b.model, err)
+			}
+		}
+		for _, u := range result.policyUpdates {
+			if err := t.db.InsertPolicyUpdate(article.ID, u.regulation, u.status, u.summary, u.reportedDate); err != nil {
+				log.Printf("Error storing policy update for %s: %v", u.regulation, err)
+			}
+		}
+	}
+	r.Processed++
+	if result.verdict == "relevant" {
+		r.Relevant++
+	} else {
+		r.Skipped++
+	}
+	log.Printf("Triaged [%s]: %s", result.verdict, article.Title)
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type triageResult struct {
 	verdict        string
 	articleType    *string
 	keyPoints      []string
 	reason         *string
 	practicalScore int
+	events         []event
+	benchmarks     []benchmark
+	policyUpdates  []policyUpdate
+}
+
+type event struct {
+	title string
+	date  string
+	kind  string
+}
+
+type benchmark struct {
+	model        string
+	benchmark    string
+	score        float64
+	reportedDate string
+	sotaClaim    bool
 }
 
-func (t *Triager) triageArticle(ctx context.Context, article database.Article, prioritiesText, feedbackText string) (*triageResult, error) {
+type policyUpdate struct {
+	regulation   string
+	status       string
+	summary      *string
+	reportedDate string
+}
+
+// isPolicySource reports whether an article came from a policy feed.
+func (t *Triager) isPolicySource(article database.Article) bool {
+	if article.Source == nil {
+		return false
+	}
+	for _, s := range t.opts.PolicySources {
+		if strings.EqualFold(s, *article.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Triager) triageArticle(ctx context.Context, article database.Article, prioritiesText, feedbackText string, weights map[string]database.SourceWeight) (*triageResult, error) {
 	content := ""
 	if article.Content != nil {
 		content = *article.Content
 	}
-	if content == "" {
+	switch {
+	case article.Paywalled:
+		content = strings.TrimSpace(paywalledContent + "\n\n" + content)
+	case content == "":
 		content = article.Title
 	}
 	if len(content) > 4000 {
@@ -135,12 +413,20 @@ func (t *Triager) triageArticle(ctx context.Context, article database.Article, p
 
 	source := "Unknown"
 	if article.Source != nil {
-		source = *article.Source
+		source = *article.Source + sourceStanding(database.WeightOf(weights, article.Source))
 	}
 
-	prompt := fmt.Sprintf(triagePrompt, prioritiesText, feedbackText, article.Title, source, content)
+	today := database.GetToday()
+	policy := t.isPolicySource(article)
+	audience := cmp.Or(t.opts.Audience, llm.DefaultAudience)
+	prompt := fmt.Sprintf(triagePrompt, audience, prioritiesText, feedbackText, today, article.Title, source, content)
+	schema := triageSchema
+	if policy {
+		prompt = fmt.Sprintf(policyTriagePrompt, audience, formatRegulations(t.opts.Regulations), today, article.Title, source, content)
+		schema = policyTriageSchema
+	}
 
-	responseText, err := t.provider.Generate(ctx, prompt, 512)
+	responseText, err := t.provider.Generate(llm.WithSchema(llm.WithArticle(ctx, article.ID), schema), prompt, cmp.Or(t.opts.MaxTokens, defaultMaxTokens))
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +435,7 @@ func (t *Triager) triageArticle(ctx context.Context, article database.Article, p
 	if parsed == nil {
 		// Default to relevant if we can't parse
 		at := "other"
-		reason := "LLM response could not be parsed"
+		reason := unparsedReason
 		return &triageResult{
 			verdict:        "relevant",
 			articleType:    &at,
@@ -190,13 +476,162 @@ func (t *Triager) triageArticle(ctx context.Context, article database.Article, p
 		score = 5
 	}
 
-	return &triageResult{
+	// Events must lie after the article was written and benchmark results
+	// are dated by it; today stands in when the publication date is unknown.
+	articleDate := today
+	if article.PublishedDate != nil && *article.PublishedDate != "" {
+		articleDate = *article.PublishedDate
+	}
+
+	result := &triageResult{
 		verdict:        verdict,
 		articleType:    &at,
 		keyPoints:      keyPoints,
 		reason:         &reason,
 		practicalScore: score,
-	}, nil
+		events:         parseEvents(parsed, articleDate),
+		benchmarks:     parseBenchmarks(parsed, articleDate),
+	}
+	if policy {
+		result.policyUpdates = parsePolicyUpdates(parsed, t.opts.Regulations, articleDate)
+	}
+	return result, nil
+}
+
+// parseEvents extracts well-formed events dated on or after notBefore.
+func parseEvents(parsed map[string]any, notBefore string) []event {
+	arr, ok := parsed["upcoming_events"].([]any)
+	if !ok {
+		return nil
+	}
+
+	var events []event
+	for _, item := range arr {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		title := strings.TrimSpace(getString(obj, "title", ""))
+		date := strings.TrimSpace(getString(obj, "date", ""))
+		if title == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil || date < notBefore {
+			continue
+		}
+
+		kind := strings.ToLower(getString(obj, "kind", database.EventOther))
+		switch kind {
+		case database.EventRelease, database.EventConference, database.EventDeadline:
+		default:
+			kind = database.EventOther
+		}
+		events = append(events, event{title: title, date: date, kind: kind})
+		if len(events) == 3 {
+			break
+		}
+	}
+	return events
+}
+
+// parseBenchmarks extracts named, numeric benchmark scores, dated by the
+// article's publication date.
+func parseBenchmarks(parsed map[string]any, reportedDate string) []benchmark {
+	arr, ok := parsed["benchmark_results"].([]any)
+	if !ok {
+		return nil
+	}
+
+	var results []benchmark
+	for _, item := range arr {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		model := strings.TrimSpace(getString(obj, "model", ""))
+		name := strings.TrimSpace(getString(obj, "benchmark", ""))
+		score, ok := obj["score"].(float64)
+		if model == "" || name == "" || !ok || score < 0 {
+			continue
+		}
+		sota, _ := obj["sota_claim"].(bool)
+		results = append(results, benchmark{model: model, benchmark: name, score: score, reportedDate: reportedDate, sotaClaim: sota})
+		if len(results) == 5 {
+			break
+		}
+	}
+	return results
+}
+
+// parsePolicyUpdates extracts regulation statuses, filing them under the
+// tracked name when one matches case-insensitively.
+func parsePolicyUpdates(parsed map[string]any, tracked []string, reportedDate string) []policyUpdate {
+	arr, ok := parsed["policy_updates"].([]any)
+	if !ok {
+		return nil
+	}
+
+	var updates []policyUpdate
+	for _, item := range arr {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(getString(obj, "regulation", ""))
+		if name == "" {
+			continue
+		}
+		for _, r := range tracked {
+			if strings.EqualFold(r, name) {
+				name = r
+				break
+			}
+		}
+
+		status := strings.ToLower(strings.TrimSpace(getString(obj, "status", database.PolicyOther)))
+		switch status {
+		case database.PolicyProposed, database.PolicyDebated, database.PolicyAdopted, database.PolicyInForce,
+			database.PolicyAmended, database.PolicyDelayed, database.PolicyWithdrawn:
+		default:
+			status = database.PolicyOther
+		}
+
+		u := policyUpdate{regulation: name, status: status, reportedDate: reportedDate}
+		if summary := strings.TrimSpace(getString(obj, "summary", "")); summary != "" {
+			u.summary = &summary
+		}
+		updates = append(updates, u)
+		if len(updates) == 3 {
+			break
+		}
+	}
+	return updates
+}
+
+// Sources weighted at least highSourceWeight, or at most lowSourceWeight,
+// are pointed out to triage next to the article's source name.
+const (
+	highSourceWeight = 1.25
+	lowSourceWeight  = 0.8
+)
+
+// sourceStanding describes a source's weight for the triage prompt, or
+// returns "" for a source of ordinary weight.
+func sourceStanding(weight float64) string {
+	switch {
+	case weight >= highSourceWeight:
+		return " (a source the reader values highly; lean towards relevant)"
+	case weight <= lowSourceWeight:
+		return " (a source the reader rarely values; relevant only when clearly so)"
+	}
+	return ""
+}
+
+func formatRegulations(regulations []string) string {
+	if len(regulations) == 0 {
+		return "None defined"
+	}
+	return "- " + strings.Join(regulations, "\n- ")
 }
 
 func formatPriorities(priorities []database.ResearchPriority) string {
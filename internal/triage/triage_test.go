@@ -3,10 +3,16 @@ package triage
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/TobiSchelling/AICrawler/internal/database"
+	"github.com/TobiSchelling/AICrawler/internal/llm"
 )
 
 // mockProvider implements llm.Provider for testing.
@@ -46,7 +52,7 @@ func TestTriageRelevantArticle(t *testing.T) {
 		"practical_score":  4,
 	})
 
-	triager := NewTriager(db, &mockProvider{response: string(resp)})
+	triager := NewTriager(db, &mockProvider{response: string(resp)}, Options{})
 	result := triager.TriageArticles(context.Background(), "2026-02-06")
 
 	if result.Processed != 1 {
@@ -78,7 +84,7 @@ func TestTriageSkipArticle(t *testing.T) {
 		"practical_score":  0,
 	})
 
-	triager := NewTriager(db, &mockProvider{response: string(resp)})
+	triager := NewTriager(db, &mockProvider{response: string(resp)}, Options{})
 	result := triager.TriageArticles(context.Background(), "2026-02-06")
 
 	if result.Processed != 1 || result.Skipped != 1 {
@@ -91,7 +97,7 @@ func TestTriageHandlesUnparseableResponse(t *testing.T) {
 	db.InsertArticle("https://example.com/test", "Test Article",
 		nil, nil, ptr("Some content"), ptr("2026-02-06"))
 
-	triager := NewTriager(db, &mockProvider{response: "This is not JSON at all"})
+	triager := NewTriager(db, &mockProvider{response: "This is not JSON at all"}, Options{})
 	result := triager.TriageArticles(context.Background(), "2026-02-06")
 
 	if result.Processed != 1 {
@@ -109,7 +115,7 @@ func TestTriageSkipsAlreadyTriaged(t *testing.T) {
 	db.InsertTriage(aid, "relevant", nil, nil, nil, 3)
 
 	mock := &mockProvider{}
-	triager := NewTriager(db, mock)
+	triager := NewTriager(db, mock, Options{})
 	result := triager.TriageArticles(context.Background(), "2026-02-06")
 
 	if result.Processed != 0 {
@@ -149,7 +155,7 @@ func TestTriageWithFeedbackSummary(t *testing.T) {
 	provider := &mockProvider{response: string(resp)}
 	captureProvider := &promptCapture{inner: provider}
 
-	triager := NewTriager(db, captureProvider)
+	triager := NewTriager(db, captureProvider, Options{})
 	result := triager.TriageArticles(context.Background(), "2026-02-06")
 
 	if result.Processed != 1 {
@@ -166,6 +172,37 @@ func TestTriageWithFeedbackSummary(t *testing.T) {
 	}
 }
 
+func TestTriagePromptNamesWeightedSources(t *testing.T) {
+	db := openTestDB(t)
+	db.SetSourceType("Trusted", database.SourceBlog)
+	db.SetSourceWeight("Trusted", 2)
+	db.InsertArticle("https://a.com", "From a trusted blog", ptr("Trusted"), nil, ptr("Content"), ptr("2026-02-06"))
+
+	capture := &promptCapture{inner: &mockProvider{response: `{"verdict": "relevant"}`}}
+	NewTriager(db, capture, Options{}).TriageArticles(context.Background(), "2026-02-06")
+	if !containsStr(capture.lastPrompt, "Source: Trusted (a source the reader values highly") {
+		t.Errorf("expected the source's standing in the prompt, got %q", capture.lastPrompt)
+	}
+
+	for weight, want := range map[float64]string{1: "", 1.1: "", 0.7: " (a source the reader rarely values; relevant only when clearly so)"} {
+		if got := sourceStanding(weight); got != want {
+			t.Errorf("sourceStanding(%g) = %q, want %q", weight, got, want)
+		}
+	}
+}
+
+func TestTriagePromptMarksPaywalledArticles(t *testing.T) {
+	db := openTestDB(t)
+	id, _ := db.InsertArticle("https://a.com", "Behind a paywall", ptr("News"), nil, nil, ptr("2026-02-06"))
+	db.MarkArticlePaywalled(id)
+
+	capture := &promptCapture{inner: &mockProvider{response: `{"verdict": "relevant"}`}}
+	NewTriager(db, capture, Options{}).TriageArticles(context.Background(), "2026-02-06")
+	if !containsStr(capture.lastPrompt, "Content:\nContent unavailable (paywalled).") {
+		t.Errorf("expected the prompt to say the content is paywalled, got %q", capture.lastPrompt)
+	}
+}
+
 // promptCapture wraps a provider and captures the last prompt.
 type promptCapture struct {
 	inner      *mockProvider
@@ -193,10 +230,347 @@ func TestTriageNoProvider(t *testing.T) {
 	db.InsertArticle("https://example.com/test", "Test",
 		nil, nil, ptr("C"), ptr("2026-02-06"))
 
-	triager := NewTriager(db, nil)
+	triager := NewTriager(db, nil, Options{})
 	result := triager.TriageArticles(context.Background(), "2026-02-06")
 
 	if result.Errors != 1 {
 		t.Errorf("expected 1 error, got %d", result.Errors)
 	}
+	if !errors.Is(result.LastErr, llm.ErrNoProvider) {
+		t.Errorf("expected LastErr to be ErrNoProvider, got %v", result.LastErr)
+	}
+}
+
+func TestTriageExtractsUpcomingEvents(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://example.com/release", "Model v5 ships in March",
+		nil, ptr("2026-02-06"), ptr("The release is planned for 2099-03-01."), ptr("2026-02-06"))
+
+	resp, _ := json.Marshal(map[string]any{
+		"verdict":         "relevant",
+		"practical_score": 3,
+		"upcoming_events": []map[string]string{
+			{"title": "Model v5 release", "date": "2099-03-01", "kind": "release"},
+			{"title": "Already happened", "date": "2020-01-01", "kind": "conference"},
+			{"title": "Vague", "date": "next spring"},
+			{"title": "Summit", "date": "2099-04-10", "kind": "meetup"},
+		},
+	})
+
+	NewTriager(db, &mockProvider{response: string(resp)}, Options{}).TriageArticles(context.Background(), "2026-02-06")
+
+	events, _ := db.GetEventsBetween("2000-01-01", "2100-01-01", 0)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 valid future events, got %+v", events)
+	}
+	if events[0].Title != "Model v5 release" || events[0].Kind != "release" || *events[0].ArticleID != aid {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Kind != "other" {
+		t.Errorf("expected unknown kind to become 'other', got %q", events[1].Kind)
+	}
+}
+
+func TestTriageExtractsBenchmarkResults(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://example.com/model", "New model tops coding benchmark",
+		nil, ptr("2026-02-06"), ptr("It scores 72.4% on SWE-bench Verified."), ptr("2026-02-06"))
+
+	resp, _ := json.Marshal(map[string]any{
+		"verdict": "relevant",
+		"benchmark_results": []map[string]any{
+			{"model": "Model X", "benchmark": "SWE-bench Verified", "score": 72.4, "sota_claim": true},
+			{"model": "Model X", "benchmark": "HumanEval", "score": "high"},
+			{"model": "", "benchmark": "MMLU", "score": 80},
+		},
+	})
+	NewTriager(db, &mockProvider{response: string(resp)}, Options{}).TriageArticles(context.Background(), "2026-02-06")
+
+	results, _ := db.GetBenchmarkResults()
+	if len(results) != 1 {
+		t.Fatalf("expected only the well-formed result, got %+v", results)
+	}
+	r := results[0]
+	if r.Model != "Model X" || r.Score != 72.4 || !r.SOTA || r.ReportedDate != "2026-02-06" || *r.ArticleID != aid {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestTriagePolicySourceRecordsUpdates(t *testing.T) {
+	db := openTestDB(t)
+	aid, _ := db.InsertArticle("https://example.com/ai-act", "AI Act obligations now apply",
+		ptr("EU AI Act Newsletter"), ptr("2026-02-05"), ptr("The general-purpose model rules are in force."), ptr("2026-02-06"))
+	db.InsertArticle("https://example.com/other", "Regular news",
+		ptr("Blog"), nil, ptr("Nothing about law."), ptr("2026-02-06"))
+
+	resp, _ := json.Marshal(map[string]any{
+		"verdict": "relevant",
+		"policy_updates": []map[string]string{
+			{"regulation": "eu ai act", "status": "in_force", "summary": "GPAI obligations apply"},
+			{"regulation": "Some Bill", "status": "stalled"},
+			{"regulation": "", "status": "adopted"},
+		},
+	})
+	capture := &promptCapture{inner: &mockProvider{response: string(resp)}}
+	opts := Options{PolicySources: []string{"EU AI Act Newsletter"}, Regulations: []string{"EU AI Act"}}
+	NewTriager(db, capture, opts).TriageArticles(context.Background(), "2026-02-06")
+
+	watch, _ := db.PolicyWatch(nil, "2026-02-06")
+	if len(watch) != 2 {
+		t.Fatalf("expected updates only from the policy source, got %+v", watch)
+	}
+	eu := watch[0]
+	if eu.Regulation != "EU AI Act" || eu.Current.Status != database.PolicyInForce ||
+		eu.Current.ArticleID != aid || eu.Current.ReportedDate != "2026-02-05" {
+		t.Errorf("expected update filed under the tracked name, got %+v", eu.Current)
+	}
+	if watch[1].Current.Status != database.PolicyOther {
+		t.Errorf("expected unknown status to become 'other', got %q", watch[1].Current.Status)
+	}
+}
+
+func TestTriagePolicyPrompt(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertArticle("https://example.com/ai-act", "AI Act obligations now apply",
+		ptr("EU AI Act Newsletter"), nil, ptr("Rules apply."), ptr("2026-02-06"))
+
+	capture := &promptCapture{inner: &mockProvider{response: `{"verdict": "relevant"}`}}
+	opts := Options{PolicySources: []string{"eu ai act newsletter"}, Regulations: []string{"EU AI Act", "UK AI Bill"}}
+	NewTriager(db, capture, opts).TriageArticles(context.Background(), "2026-02-06")
+
+	if !containsStr(capture.lastPrompt, "- EU AI Act\n- UK AI Bill") {
+		t.Errorf("expected tracked regulations in the policy prompt, got %q", capture.lastPrompt)
+	}
+	if containsStr(capture.lastPrompt, "Research priorities") {
+		t.Error("expected the policy prompt instead of the regular one")
+	}
+}
+
+// failingProvider fails its first failures calls, then answers relevant.
+type failingProvider struct {
+	failures int
+	calls    int
+}
+
+func (f *failingProvider) Generate(_ context.Context, _ string, _ int) (string, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return "", fmt.Errorf("HTTP 429 rate limited")
+	}
+	return `{"verdict": "relevant", "practical_score": 3}`, nil
+}
+
+func (f *failingProvider) IsConfigured() bool { return true }
+
+func TestTriageRetriesFailedArticles(t *testing.T) {
+	db := openTestDB(t)
+	a1, _ := db.InsertArticle("https://example.com/1", "One", nil, nil, nil, ptr("2026-02-06"))
+	db.InsertArticle("https://example.com/2", "Two", nil, nil, nil, ptr("2026-02-06"))
+
+	provider := &failingProvider{failures: 1}
+	result := NewTriager(db, provider, Options{RetryPasses: 1}).TriageArticles(context.Background(), "2026-02-06")
+	if result.Processed != 2 || result.Recovered != 1 || result.Errors != 0 {
+		t.Errorf("expected the failed article to be triaged on retry, got %+v", result)
+	}
+	if tr, _ := db.GetTriage(a1); tr == nil {
+		t.Error("expected the retried article to be triaged")
+	}
+	if failures, _ := db.GetTriageFailures(10); len(failures) != 0 {
+		t.Errorf("expected the failure to be cleared once triaged, got %+v", failures)
+	}
+}
+
+func TestTriageErrorBudget(t *testing.T) {
+	db := openTestDB(t)
+	for i := range 4 {
+		db.InsertArticle(fmt.Sprintf("https://example.com/%d", i), fmt.Sprintf("Article %d", i), nil, nil, nil, ptr("2026-02-06"))
+	}
+
+	provider := &failingProvider{failures: 100}
+	result := NewTriager(db, provider, Options{RetryPasses: 2, ErrorBudget: 3}).TriageArticles(context.Background(), "2026-02-06")
+	if provider.calls != 3 || result.Errors != 3 || result.Deferred != 1 {
+		t.Errorf("expected triage to stop after 3 failed calls, got %d calls and %+v", provider.calls, result)
+	}
+	failures, _ := db.GetTriageFailures(10)
+	if len(failures) != 3 || failures[0].Attempts != 1 || failures[0].LastError != "HTTP 429 rate limited" {
+		t.Errorf("expected 3 recorded failures with their reason, got %+v", failures)
+	}
+}
+
+func TestTriageInParallel(t *testing.T) {
+	db := openTestDB(t)
+	for i := range 12 {
+		db.InsertArticle(fmt.Sprintf("https://example.com/%d", i), fmt.Sprintf("Article %d", i), nil, nil, nil, ptr("2026-02-06"))
+	}
+
+	provider := &mockProvider{response: `{"verdict": "skip", "practical_score": 1}`}
+	result := NewTriager(db, provider, Options{Pool: llm.NewPool(4)}).TriageArticles(context.Background(), "2026-02-06")
+	if result.Processed != 12 || result.Skipped != 12 || result.Errors != 0 {
+		t.Errorf("expected all 12 articles triaged, got %+v", result)
+	}
+	if pending, _ := db.GetUntriagedArticles(ptr("2026-02-06")); len(pending) != 0 {
+		t.Errorf("expected no untriaged articles left, got %d", len(pending))
+	}
+}
+
+func TestTriageMuteRules(t *testing.T) {
+	db := openTestDB(t)
+	db.InsertMuteRule(database.MuteTerm, "crypto")
+	db.InsertMuteRule(database.MuteSource, "Hype Daily")
+	db.InsertArticle("https://a.com/1", "Crypto agents are here", ptr("A Blog"), nil, nil, ptr("2026-02-06"))
+	db.InsertArticle("https://b.com/1", "Agents write tests", ptr("hype daily"), nil, nil, ptr("2026-02-06"))
+	kept, _ := db.InsertArticle("https://c.com/1", "Cryptographic signing for models", ptr("A Blog"), nil, nil, ptr("2026-02-06"))
+
+	resp, _ := json.Marshal(map[string]any{"verdict": "relevant", "article_type": "other", "practical_score": 3})
+	mock := &promptCapture{inner: &mockProvider{response: string(resp)}}
+	result := NewTriager(db, mock, Options{}).TriageArticles(context.Background(), "2026-02-06")
+
+	if result.Processed != 3 || result.Muted != 2 || result.Skipped != 2 || result.Relevant != 1 {
+		t.Errorf("expected two muted skips and one relevant article, got %+v", result)
+	}
+	if !strings.Contains(mock.lastPrompt, "Cryptographic signing") {
+		t.Errorf("expected only the unmuted article sent to the LLM, got %q", mock.lastPrompt)
+	}
+	if triage, _ := db.GetTriage(kept); triage == nil || triage.Verdict != "relevant" {
+		t.Errorf("expected the whole-word term not to mute %q, got %+v", "Cryptographic", triage)
+	}
+
+	examples, _ := db.GetTrainingExamples("")
+	if records := TrainingRecords(examples, TrainingOptions{}); len(records) != 1 {
+		t.Errorf("expected muted verdicts not to be learned from, got %+v", records)
+	}
+}
+
+func TestTriageSkipsOtherLanguages(t *testing.T) {
+	db := openTestDB(t)
+	ja, _ := db.InsertArticle("https://a.com/ja", "新しいモデル", ptr("A Blog"), nil, nil, ptr("2026-02-06"))
+	de, _ := db.InsertArticle("https://b.com/de", "Ein neues Modell", ptr("A Blog"), nil, nil, ptr("2026-02-06"))
+	unknown, _ := db.InsertArticle("https://c.com/1", "GPT-5", ptr("A Blog"), nil, nil, ptr("2026-02-06"))
+	db.SetArticleLanguage(ja, "ja")
+	db.SetArticleLanguage(de, "de")
+	db.SetArticleLanguage(unknown, "")
+
+	resp, _ := json.Marshal(map[string]any{"verdict": "relevant", "article_type": "other", "practical_score": 3})
+	mock := &mockProvider{response: string(resp)}
+	result := NewTriager(db, mock, Options{Languages: []string{"en", "de"}}).TriageArticles(context.Background(), "2026-02-06")
+
+	if result.Processed != 3 || result.OtherLanguage != 1 || result.Skipped != 1 || result.Relevant != 2 {
+		t.Errorf("expected the Japanese article skipped and the others triaged, got %+v", result)
+	}
+	if triage, _ := db.GetTriage(ja); triage == nil || triage.RelevanceReason == nil || *triage.RelevanceReason != "Language: Japanese" {
+		t.Errorf("expected the skip to name the language, got %+v", triage)
+	}
+
+	examples, _ := db.GetTrainingExamples("")
+	if records := TrainingRecords(examples, TrainingOptions{}); len(records) != 2 {
+		t.Errorf("expected language skips not to be learned from, got %+v", records)
+	}
+}
+
+func TestTrainingRecords(t *testing.T) {
+	db := openTestDB(t)
+	tool, other := "tool_release", "other"
+	kept, _ := db.InsertArticle("https://a.com/tool", "A new agent", ptr("A Blog"), nil, ptr("Full text"), ptr("2026-02-05"))
+	overruled, _ := db.InsertArticle("https://b.com/fluff", "Funding round", ptr("News"), nil, nil, ptr("2026-02-06"))
+	unparsed, _ := db.InsertArticle("https://c.com/odd", "Odd page", ptr("News"), nil, nil, ptr("2026-02-06"))
+	policy, _ := db.InsertArticle("https://d.com/act", "The act passes", ptr("Policy Newsletter"), nil, nil, ptr("2026-02-06"))
+	old, _ := db.InsertArticle("https://e.com/old", "Old news", ptr("News"), nil, nil, ptr("2026-01-01"))
+	reason := unparsedReason
+	db.InsertTriage(kept, "relevant", &tool, nil, nil, 4)
+	db.InsertTriage(overruled, "relevant", &other, nil, nil, 2)
+	db.InsertTriage(unparsed, "relevant", &other, nil, &reason, 2)
+	db.InsertTriage(policy, "relevant", &other, nil, nil, 3)
+	db.InsertTriage(old, "skip", nil, nil, nil, 0)
+	db.UpsertArticleFeedback(overruled, "negative")
+
+	examples, err := db.GetTrainingExamples("2026-02-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := TrainingRecords(examples, TrainingOptions{Audience: "QA engineers", PolicySources: []string{"Policy Newsletter"}})
+	if len(records) != 2 {
+		t.Fatalf("expected the kept and the overruled article, got %+v", records)
+	}
+	if r := records[0]; r.Label != "relevant" || r.LabelSource != LabelTriage || r.ArticleType != tool || r.PeriodID != "2026-02-05" ||
+		r.Prompt != "Classify this AI news article for a daily briefing aimed at QA engineers as \"relevant\" or \"skip\".\n\nTitle: A new agent\nSource: A Blog\nContent:\nFull text" {
+		t.Errorf("unexpected record for the triaged article: %+v", r)
+	}
+	if r := records[1]; r.Label != "skip" || r.LabelSource != LabelFeedback || r.TriageVerdict != "relevant" {
+		t.Errorf("expected the rating to override the verdict, got %+v", r)
+	}
+}
+
+func TestTriageClassifier(t *testing.T) {
+	// The embedder puts articles about agents and about funding on two axes.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var vectors [][]float64
+		for i, text := range req.Input {
+			v := []float64{0, 0, float64(i%7) / 10}
+			if strings.Contains(text, "agent") {
+				v[0] = 1
+			}
+			if strings.Contains(text, "funding") {
+				v[1] = 1
+			}
+			vectors = append(vectors, v)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": vectors})
+	}))
+	defer srv.Close()
+
+	db := openTestDB(t)
+	for i := range 40 {
+		if i%4 == 0 {
+			id, _ := db.InsertArticle(fmt.Sprintf("https://a.com/%d", i), fmt.Sprintf("An agent that writes tests, part %d", i), ptr("Blog"), nil, nil, ptr("2026-02-05"))
+			db.InsertTriage(id, "relevant", nil, nil, nil, 4)
+		} else {
+			id, _ := db.InsertArticle(fmt.Sprintf("https://b.com/%d", i), fmt.Sprintf("Another funding round, number %d", i), ptr("News"), nil, nil, ptr("2026-02-05"))
+			db.InsertTriage(id, "skip", nil, nil, nil, 0)
+		}
+	}
+	db.InsertArticle("https://a.com/new", "A coding agent ships", ptr("Blog"), nil, nil, ptr("2026-02-06"))
+	db.InsertArticle("https://b.com/new", "A record funding round", ptr("News"), nil, nil, ptr("2026-02-06"))
+	db.InsertArticle("https://p.com/new", "An agent rule in the act", ptr("Policy Newsletter"), nil, nil, ptr("2026-02-06"))
+
+	resp, _ := json.Marshal(map[string]any{"verdict": "relevant", "article_type": "regulation", "practical_score": 3})
+	provider := &promptCapture{inner: &mockProvider{response: string(resp)}}
+	opts := Options{
+		PolicySources: []string{"Policy Newsletter"},
+		Classifier: &ClassifierOptions{
+			Embedder:    llm.NewOllamaEmbedder("test-embed", srv.URL),
+			MinExamples: 20,
+			AcceptAbove: 0.9,
+			RejectBelow: 0.1,
+			MinAccuracy: 0.9,
+		},
+	}
+	result := NewTriager(db, provider, opts).TriageArticles(context.Background(), "2026-02-06")
+
+	if result.Processed != 3 || result.Classified != 2 || result.Relevant != 2 || result.Skipped != 1 {
+		t.Errorf("expected 2 of 3 articles classified, got %+v", result)
+	}
+	if !containsStr(provider.lastPrompt, "An agent rule in the act") {
+		t.Errorf("expected the policy article to go to the LLM, last prompt: %q", provider.lastPrompt)
+	}
+	examples, _ := db.GetTrainingExamples("2026-02-06")
+	for _, e := range examples {
+		if e.Article.URL == "https://b.com/new" && (e.Triage.Verdict != "skip" || !strings.HasPrefix(*e.Triage.RelevanceReason, classifierReason)) {
+			t.Errorf("expected the funding article skipped by the classifier, got %+v", e.Triage)
+		}
+	}
+	if records := TrainingRecords(examples, TrainingOptions{}); len(records) != 1 {
+		t.Errorf("expected only the LLM's verdict to be learned from, got %+v", records)
+	}
+
+	// With too little history every article goes to the LLM.
+	db = openTestDB(t)
+	db.InsertArticle("https://a.com/new", "A coding agent ships", ptr("Blog"), nil, nil, ptr("2026-02-06"))
+	result = NewTriager(db, provider, opts).TriageArticles(context.Background(), "2026-02-06")
+	if result.Processed != 1 || result.Classified != 0 {
+		t.Errorf("expected the LLM to triage without history, got %+v", result)
+	}
 }